@@ -17,6 +17,8 @@ limitations under the License.
 package consensus
 
 import (
+	google_protobuf "google/protobuf"
+
 	pb "github.com/hyperledger/fabric/protos"
 )
 
@@ -35,6 +37,19 @@ type Consenter interface {
 	ExecutionConsumer
 }
 
+// Timestamper is implemented by the consensus plugins agreeing on the time of
+// the blocks they commit
+type Timestamper interface {
+	GetBlockTimestamp(metadata []byte) *google_protobuf.Timestamp // Returns the timestamp of the block committed with metadata, nil if none was agreed on
+}
+
+// Reconfigurer is implemented by the consensus plugins able to change the set
+// of validators, through CONSENSUS_RECONFIGURE transactions approved by the
+// current validators
+type Reconfigurer interface {
+	ApproveReconfiguration(validators []string, payload []byte) ([]byte, error) // Returns the payload with the approval of this validator added, a new payload to the validators if empty
+}
+
 // Inquirer is used to retrieve info about the validating network
 type Inquirer interface {
 	GetNetworkInfo() (self *pb.PeerEndpoint, network []*pb.PeerEndpoint, err error)
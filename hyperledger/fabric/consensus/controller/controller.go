@@ -25,6 +25,7 @@ import (
 	"github.com/hyperledger/fabric/consensus"
 	"github.com/hyperledger/fabric/consensus/noops"
 	"github.com/hyperledger/fabric/consensus/obcpbft"
+	"github.com/hyperledger/fabric/consensus/raft"
 )
 
 var logger *logging.Logger // package-level logger
@@ -42,7 +43,24 @@ func NewConsenter(stack consensus.Stack) consensus.Consenter {
 		logger.Infof("Creating consensus plugin %s", plugin)
 		return obcpbft.GetPlugin(stack)
 	}
+	if plugin == "raft" {
+		logger.Infof("Creating consensus plugin %s", plugin)
+		return raft.GetPlugin(stack)
+	}
 	logger.Info("Creating default consensus plugin (noops)")
 	return noops.GetNoops(stack)
 
 }
+
+// NewChainConsenter constructs a new Consenter object for a chain besides the
+// default chain, each chain running its own instance of the plugin
+func NewChainConsenter(stack consensus.Stack) consensus.Consenter {
+	plugin := strings.ToLower(viper.GetString("peer.validator.consensus.plugin"))
+	if plugin == "pbft" {
+		return obcpbft.New(stack)
+	}
+	if plugin == "raft" {
+		return raft.New(stack)
+	}
+	return noops.New(stack)
+}
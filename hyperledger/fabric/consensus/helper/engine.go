@@ -24,8 +24,10 @@ import (
 	"sync"
 
 	"github.com/hyperledger/fabric/consensus/controller"
+	"github.com/hyperledger/fabric/consensus/executor"
 	"github.com/hyperledger/fabric/consensus/util"
 	"github.com/hyperledger/fabric/core/chaincode"
+	"github.com/hyperledger/fabric/core/ledger"
 	pb "github.com/hyperledger/fabric/protos"
 	"golang.org/x/net/context"
 )
@@ -36,18 +38,46 @@ type EngineImpl struct {
 	helper       *Helper
 	peerEndpoint *pb.PeerEndpoint
 	consensusFan *util.MessageFan
+	chains       map[string]*chainEngine // by chain ID, besides the default chain
 }
 
-// GetHandlerFactory returns new NewConsensusHandler
+// chainEngine holds the consenter of a chain besides the default chain
+type chainEngine struct {
+	consenter consensus.Consenter
+	helper    *Helper
+}
+
+// getChain returns the consenter and helper of the chain, nil if the peer
+// does not host the chain
+func (eng *EngineImpl) getChain(chainID string) (consensus.Consenter, *Helper) {
+	if chainID == "" {
+		return eng.consenter, eng.helper
+	}
+	if chain, ok := eng.chains[chainID]; ok {
+		return chain.consenter, chain.helper
+	}
+	return nil, nil
+}
+
+// GetHandlerFactory returns a factory of consensus handlers passing the
+// messages to the engine, NewConsensusHandler for the engine of GetEngine
 func (eng *EngineImpl) GetHandlerFactory() peer.HandlerFactory {
-	return NewConsensusHandler
+	return func(coord peer.MessageHandlerCoordinator, stream peer.ChatStream, initiatedStream bool, next peer.MessageHandler) (peer.MessageHandler, error) {
+		return newConsensusHandler(eng, coord, stream, initiatedStream, next)
+	}
 }
 
 // ProcessTransactionMsg processes a Message in context of a Transaction
 func (eng *EngineImpl) ProcessTransactionMsg(msg *pb.Message, tx *pb.Transaction) (response *pb.Response) {
+	consenter, helper := eng.getChain(tx.ChainID)
+	if helper == nil {
+		return &pb.Response{Status: pb.Response_FAILURE, Msg: []byte(fmt.Sprintf("Error: chain %s is not hosted by this peer", tx.ChainID))}
+	}
+	msg.ChainID = tx.ChainID
+
 	//TODO: Do we always verify security, or can we supply a flag on the invoke ot this functions so to bypass check for locally generated transactions?
 	if tx.Type == pb.Transaction_CHAINCODE_QUERY {
-		if !engine.helper.valid {
+		if !helper.valid {
 			logger.Warning("Rejecting query because state is currently not valid")
 			return &pb.Response{Status: pb.Response_FAILURE,
 				Msg: []byte("Error: state may be inconsistent, cannot query")}
@@ -58,7 +88,7 @@ func (eng *EngineImpl) ProcessTransactionMsg(msg *pb.Message, tx *pb.Transaction
 		cxt := context.Background()
 		//query will ignore events as these are not stored on ledger (and query can report
 		//"event" data synchronously anyway)
-		result, _, err := chaincode.Execute(cxt, chaincode.GetChain(chaincode.DefaultChain), tx)
+		result, _, err := chaincode.Execute(cxt, helper.getChaincodeSupport(), tx)
 		if err != nil {
 			response = &pb.Response{Status: pb.Response_FAILURE,
 				Msg: []byte(fmt.Sprintf("Error:%s", err))}
@@ -76,13 +106,13 @@ func (eng *EngineImpl) ProcessTransactionMsg(msg *pb.Message, tx *pb.Transaction
 		}
 
 		// Pass the message to the consenter (eg. PBFT) NOTE: Make sure engine has been initialized
-		if eng.consenter == nil {
+		if consenter == nil {
 			return &pb.Response{Status: pb.Response_FAILURE, Msg: []byte("Engine not initialized")}
 		}
 		// TODO, do we want to put these requests into a queue? This will block until
 		// the consenter gets around to handling the message, but it also provides some
 		// natural feedback to the REST API to determine how long it takes to queue messages
-		err := eng.consenter.RecvMsg(msg, eng.peerEndpoint.ID)
+		err := consenter.RecvMsg(msg, eng.peerEndpoint.ID)
 		if err != nil {
 			response = &pb.Response{Status: pb.Response_FAILURE, Msg: []byte(err.Error())}
 		}
@@ -90,6 +120,20 @@ func (eng *EngineImpl) ProcessTransactionMsg(msg *pb.Message, tx *pb.Transaction
 	return response
 }
 
+// ApproveReconfiguration implements peer.ReconfigurationApprover for the
+// consensus plugins implementing consensus.Reconfigurer
+func (eng *EngineImpl) ApproveReconfiguration(chainID string, validators []string, payload []byte) ([]byte, error) {
+	consenter, _ := eng.getChain(chainID)
+	if consenter == nil {
+		return nil, fmt.Errorf("chain %s is not hosted by this peer", chainID)
+	}
+	reconfigurer, ok := consenter.(consensus.Reconfigurer)
+	if !ok {
+		return nil, fmt.Errorf("the consensus of chain %s cannot be reconfigured", chainID)
+	}
+	return reconfigurer.ApproveReconfiguration(validators, payload)
+}
+
 func (eng *EngineImpl) setConsenter(consenter consensus.Consenter) *EngineImpl {
 	eng.consenter = consenter
 	return eng
@@ -119,14 +163,71 @@ func GetEngine(coord peer.MessageHandlerCoordinator) (peer.Engine, error) {
 		engine.peerEndpoint, err = coord.GetPeerEndpoint()
 		engine.consensusFan = util.NewMessageFan()
 
-		go func() {
-			logger.Debug("Starting up message thread for consenter")
-
-			// The channel never closes, so this should never break
-			for msg := range engine.consensusFan.GetOutChannel() {
-				engine.consenter.RecvMsg(msg.Msg, msg.Sender)
+		engine.chains = make(map[string]*chainEngine)
+		for _, chainID := range peer.Chains() {
+			chainHelper, chainErr := NewChainHelper(coord, chainID)
+			if chainErr != nil {
+				err = fmt.Errorf("Error creating the consensus helper of chain %s: %s", chainID, chainErr)
+				return
 			}
-		}()
+			chain := &chainEngine{helper: chainHelper, consenter: controller.NewChainConsenter(chainHelper)}
+			chainHelper.setConsenter(chain.consenter)
+			engine.chains[chainID] = chain
+		}
+
+		go engine.recvConsensusMsgs()
 	})
 	return engine, err
 }
+
+// NewEngine returns a new peer.Engine running the consensus of the default
+// chain on the given ledger and chaincode support, instead of the ledger and
+// chaincode support of the process as the engine of GetEngine does. It lets a
+// process run several validating peers, each with an engine of its own. The
+// engine hosts no other chain.
+func NewEngine(coord peer.MessageHandlerCoordinator, chain *chaincode.ChaincodeSupport, lgr *ledger.Ledger) (peer.Engine, error) {
+	h := newHelper(coord, "")
+	h.lgr = lgr
+	h.chaincodeSupport = chain
+	h.Helper.DB = lgr.GetDB()
+	h.executor = executor.NewImpl(h, h, coord)
+	h.executor.Start()
+	h.repairState()
+
+	eng := &EngineImpl{helper: h, consensusFan: util.NewMessageFan(), chains: make(map[string]*chainEngine)}
+	eng.consenter = controller.NewChainConsenter(h)
+	h.setConsenter(eng.consenter)
+	var err error
+	if eng.peerEndpoint, err = coord.GetPeerEndpoint(); err != nil {
+		return nil, err
+	}
+	go eng.recvConsensusMsgs()
+	return eng, nil
+}
+
+// Close stops the consenter and the executor of an engine of NewEngine, once
+// its peer is stopped
+func (eng *EngineImpl) Close() {
+	if closer, ok := eng.consenter.(interface {
+		Close()
+	}); ok {
+		closer.Close()
+	}
+	eng.helper.executor.Halt()
+}
+
+// recvConsensusMsgs passes the consensus messages received by the handlers
+// to the consenters of their chains
+func (eng *EngineImpl) recvConsensusMsgs() {
+	logger.Debug("Starting up message thread for consenter")
+
+	// The channel never closes, so this should never break
+	for msg := range eng.consensusFan.GetOutChannel() {
+		consenter, _ := eng.getChain(msg.Msg.ChainID)
+		if consenter == nil {
+			logger.Warningf("Dropping consensus message for chain %s not hosted by this peer", msg.Msg.ChainID)
+			continue
+		}
+		consenter.RecvMsg(msg.Msg, msg.Sender)
+	}
+}
@@ -52,6 +52,12 @@ type ConsensusHandler struct {
 func NewConsensusHandler(coord peer.MessageHandlerCoordinator,
 	stream peer.ChatStream, initiatedStream bool,
 	next peer.MessageHandler) (peer.MessageHandler, error) {
+	return newConsensusHandler(getEngineImpl(), coord, stream, initiatedStream, next)
+}
+
+func newConsensusHandler(eng *EngineImpl, coord peer.MessageHandlerCoordinator,
+	stream peer.ChatStream, initiatedStream bool,
+	next peer.MessageHandler) (peer.MessageHandler, error) {
 
 	peerHandler, err := peer.NewPeerHandler(coord, stream, initiatedStream, nil)
 	if err != nil {
@@ -73,7 +79,7 @@ func NewConsensusHandler(coord peer.MessageHandlerCoordinator,
 	pe, _ := handler.To()
 
 	handler.consenterChan = make(chan *util.Message, consensusQueueSize)
-	getEngineImpl().consensusFan.RegisterChannel(pe.ID, handler.consenterChan)
+	eng.consensusFan.RegisterChannel(pe.ID, handler.consenterChan)
 
 	return handler, nil
 }
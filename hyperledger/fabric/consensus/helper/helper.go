@@ -22,6 +22,7 @@ import (
 	"github.com/golang/protobuf/proto"
 	"github.com/spf13/viper"
 	"golang.org/x/net/context"
+	google_protobuf "google/protobuf"
 
 	"github.com/hyperledger/fabric/consensus"
 	"github.com/hyperledger/fabric/consensus/executor"
@@ -35,6 +36,7 @@ import (
 
 // Helper contains the reference to the peer's MessageHandlerCoordinator
 type Helper struct {
+	chainID      string // empty for the default chain
 	consenter    consensus.Consenter
 	coordinator  peer.MessageHandlerCoordinator
 	secOn        bool
@@ -45,20 +47,94 @@ type Helper struct {
 	persist.Helper
 
 	executor consensus.Executor
+
+	// set by NewEngine, the ledger of the chain and the chaincode support of
+	// the peer otherwise
+	lgr              *ledger.Ledger
+	chaincodeSupport *chaincode.ChaincodeSupport
 }
 
 // NewHelper constructs the consensus helper object
 func NewHelper(mhc peer.MessageHandlerCoordinator) *Helper {
-	h := &Helper{
+	h := newHelper(mhc, "")
+	h.executor = executor.NewImpl(h, h, mhc)
+	h.executor.Start()
+	h.repairState()
+	return h
+}
+
+// NewChainHelper constructs the consensus helper object of the chain
+// identified by chainID, operating on the ledger of the chain
+func NewChainHelper(mhc peer.MessageHandlerCoordinator, chainID string) (*Helper, error) {
+	if chainID == "" {
+		return NewHelper(mhc), nil
+	}
+	stack, err := peer.NewChainStack(mhc, chainID)
+	if err != nil {
+		return nil, err
+	}
+	h := newHelper(mhc, chainID)
+	h.executor = executor.NewImpl(h, h, stack)
+	h.executor.Start()
+	h.repairState()
+	return h, nil
+}
+
+func newHelper(mhc peer.MessageHandlerCoordinator, chainID string) *Helper {
+	return &Helper{
+		chainID:     chainID,
 		coordinator: mhc,
 		secOn:       viper.GetBool("security.enabled"),
 		secHelper:   mhc.GetSecHelper(),
 		valid:       true, // Assume our state is consistent until we are told otherwise, TODO: revisit
+		Helper:      persist.Helper{ChainID: chainID},
 	}
+}
 
-	h.executor = executor.NewImpl(h, h, mhc)
-	h.executor.Start()
-	return h
+// GetChainID returns the chain of the helper, empty for the default chain
+func (h *Helper) GetChainID() string {
+	return h.chainID
+}
+
+// stateRepair is the tag of the state transfer repairing a damaged state
+type stateRepair struct{}
+
+// repairState transfers the state at the height of the chain from the other
+// peers if the verification of the ledger on startup found it damaged
+func (h *Helper) repairState() {
+	lgr, err := h.getLedger()
+	if err != nil || !lgr.IsStateDamaged() {
+		return
+	}
+	info, err := lgr.GetBlockchainInfo()
+	if err != nil {
+		logger.Errorf("Cannot repair the damaged state: %s", err)
+		return
+	}
+	logger.Warningf("The state is damaged, transferring the state at height %d", info.Height)
+	h.InvalidateState()
+	h.executor.UpdateState(stateRepair{}, info, nil)
+}
+
+// GetLedger returns the ledger of the chain of the helper
+func (h *Helper) GetLedger() (*ledger.Ledger, error) {
+	return h.getLedger()
+}
+
+func (h *Helper) getLedger() (*ledger.Ledger, error) {
+	if h.lgr != nil {
+		return h.lgr, nil
+	}
+	return ledger.GetChainLedger(h.chainID)
+}
+
+// getChaincodeSupport returns the chaincode support executing the
+// transactions of the helper
+func (h *Helper) getChaincodeSupport() *chaincode.ChaincodeSupport {
+	if h.chaincodeSupport != nil {
+		return h.chaincodeSupport
+	}
+	return chaincode.GetChain(chaincode.DefaultChain)
 }
 
 func (h *Helper) setConsenter(c consensus.Consenter) {
@@ -107,6 +183,7 @@ func (h *Helper) GetNetworkHandles() (self *pb.PeerID, network []*pb.PeerID, err
 
 // Broadcast sends a message to all validating peers
 func (h *Helper) Broadcast(msg *pb.Message, peerType pb.PeerEndpoint_Type) error {
+	msg.ChainID = h.chainID
 	errors := h.coordinator.Broadcast(msg, peerType)
 	if len(errors) > 0 {
 		return fmt.Errorf("Couldn't broadcast successfully")
@@ -116,6 +193,7 @@ func (h *Helper) Broadcast(msg *pb.Message, peerType pb.PeerEndpoint_Type) error
 
 // Unicast sends a message to a specified receiver
 func (h *Helper) Unicast(msg *pb.Message, receiverHandle *pb.PeerID) error {
+	msg.ChainID = h.chainID
 	return h.coordinator.Unicast(msg, receiverHandle)
 }
 
@@ -158,7 +236,7 @@ func (h *Helper) Verify(replicaID *pb.PeerID, signature []byte, message []byte)
 // BeginTxBatch gets invoked when the next round
 // of transaction-batch execution begins
 func (h *Helper) BeginTxBatch(id interface{}) error {
-	ledger, err := ledger.GetLedger()
+	ledger, err := h.getLedger()
 	if err != nil {
 		return fmt.Errorf("Failed to get the ledger: %v", err)
 	}
@@ -180,7 +258,15 @@ func (h *Helper) ExecTxs(id interface{}, txs []*pb.Transaction) ([]byte, error)
 	// cxt := context.WithValue(context.Background(), "security", h.coordinator.GetSecHelper())
 	// TODO return directly once underlying implementation no longer returns []error
 
-	res, ccevents, txerrs, err := chaincode.ExecuteTransactions(context.Background(), chaincode.DefaultChain, txs)
+	var res []byte
+	var ccevents []*pb.ChaincodeEvent
+	var txerrs []error
+	var err error
+	if h.chaincodeSupport != nil {
+		res, ccevents, txerrs, err = h.chaincodeSupport.ExecuteChainTransactions(context.Background(), h.chainID, txs)
+	} else {
+		res, ccevents, txerrs, err = chaincode.ExecuteChainTransactions(context.Background(), chaincode.DefaultChain, h.chainID, txs)
+	}
 	h.curBatch = append(h.curBatch, txs...) // TODO, remove after issue 579
 
 	//copy errs to results
@@ -206,15 +292,22 @@ func (h *Helper) ExecTxs(id interface{}, txs []*pb.Transaction) ([]byte, error)
 // during execution of this transaction-batch) have been committed to
 // permanent storage.
 func (h *Helper) CommitTxBatch(id interface{}, metadata []byte) (*pb.Block, error) {
-	ledger, err := ledger.GetLedger()
+	ledger, err := h.getLedger()
 	if err != nil {
 		return nil, fmt.Errorf("Failed to get the ledger: %v", err)
 	}
 	// TODO fix this one the ledger has been fixed to implement
-	if err := ledger.CommitTxBatch(id, h.curBatch, h.curBatchErrs, metadata); err != nil {
+	if err := ledger.CommitTxBatchAt(id, h.getBlockTimestamp(metadata), h.curBatch, h.curBatchErrs, metadata); err != nil {
 		return nil, fmt.Errorf("Failed to commit transaction to the ledger: %v", err)
 	}
 
+	// Now that their termination or upgrade is committed, stop the retired chaincodes
+	if h.chaincodeSupport != nil {
+		h.chaincodeSupport.StopRetiredChaincodes(context.Background(), h.curBatch, h.curBatchErrs)
+	} else {
+		chaincode.StopRetiredChaincodes(context.Background(), chaincode.DefaultChain, h.curBatch, h.curBatchErrs)
+	}
+
 	size := ledger.GetBlockchainSize()
 	defer func() {
 		h.curBatch = nil     // TODO, remove after issue 579
@@ -234,7 +327,7 @@ func (h *Helper) CommitTxBatch(id interface{}, metadata []byte) (*pb.Block, erro
 // RollbackTxBatch discards all the state changes that may have taken
 // place during the execution of current transaction-batch
 func (h *Helper) RollbackTxBatch(id interface{}) error {
-	ledger, err := ledger.GetLedger()
+	ledger, err := h.getLedger()
 	if err != nil {
 		return fmt.Errorf("Failed to get the ledger: %v", err)
 	}
@@ -246,17 +339,26 @@ func (h *Helper) RollbackTxBatch(id interface{}) error {
 	return nil
 }
 
+// getBlockTimestamp returns the timestamp the consenter agreed on for the block
+// committed with metadata, nil if it does not agree on any
+func (h *Helper) getBlockTimestamp(metadata []byte) *google_protobuf.Timestamp {
+	if timestamper, ok := h.consenter.(consensus.Timestamper); ok {
+		return timestamper.GetBlockTimestamp(metadata)
+	}
+	return nil
+}
+
 // PreviewCommitTxBatch retrieves a preview of the block info blob (as
 // returned by GetBlockchainInfoBlob) that would describe the
 // blockchain if CommitTxBatch were invoked.  The blockinfo will
 // change if additional ExecTXs calls are invoked.
 func (h *Helper) PreviewCommitTxBatch(id interface{}, metadata []byte) ([]byte, error) {
-	ledger, err := ledger.GetLedger()
+	ledger, err := h.getLedger()
 	if err != nil {
 		return nil, fmt.Errorf("Failed to get the ledger: %v", err)
 	}
 	// TODO fix this once the underlying API is fixed
-	blockInfo, err := ledger.GetTXBatchPreviewBlockInfo(id, h.curBatch, metadata)
+	blockInfo, err := ledger.GetTXBatchPreviewBlockInfoAt(id, h.getBlockTimestamp(metadata), h.curBatch, metadata)
 	if err != nil {
 		return nil, fmt.Errorf("Failed to preview commit: %v", err)
 	}
@@ -266,7 +368,7 @@ func (h *Helper) PreviewCommitTxBatch(id interface{}, metadata []byte) ([]byte,
 
 // GetBlock returns a block from the chain
 func (h *Helper) GetBlock(blockNumber uint64) (block *pb.Block, err error) {
-	ledger, err := ledger.GetLedger()
+	ledger, err := h.getLedger()
 	if err != nil {
 		return nil, fmt.Errorf("Failed to get the ledger :%v", err)
 	}
@@ -275,7 +377,7 @@ func (h *Helper) GetBlock(blockNumber uint64) (block *pb.Block, err error) {
 
 // GetCurrentStateHash returns the current/temporary state hash
 func (h *Helper) GetCurrentStateHash() (stateHash []byte, err error) {
-	ledger, err := ledger.GetLedger()
+	ledger, err := h.getLedger()
 	if err != nil {
 		return nil, fmt.Errorf("Failed to get the ledger :%v", err)
 	}
@@ -284,19 +386,26 @@ func (h *Helper) GetCurrentStateHash() (stateHash []byte, err error) {
 
 // GetBlockchainSize returns the current size of the blockchain
 func (h *Helper) GetBlockchainSize() uint64 {
-	return h.coordinator.GetBlockchainSize()
+	if h.chainID == "" {
+		return h.coordinator.GetBlockchainSize()
+	}
+	ledger, err := h.getLedger()
+	if err != nil {
+		return 0
+	}
+	return ledger.GetBlockchainSize()
 }
 
 // GetBlockchainInfo gets the ledger's BlockchainInfo
 func (h *Helper) GetBlockchainInfo() *pb.BlockchainInfo {
-	ledger, _ := ledger.GetLedger()
+	ledger, _ := h.getLedger()
 	info, _ := ledger.GetBlockchainInfo()
 	return info
 }
 
 // GetBlockchainInfoBlob marshals a ledger's BlockchainInfo into a protobuf
 func (h *Helper) GetBlockchainInfoBlob() []byte {
-	ledger, _ := ledger.GetLedger()
+	ledger, _ := h.getLedger()
 	info, _ := ledger.GetBlockchainInfo()
 	rawInfo, _ := proto.Marshal(info)
 	return rawInfo
@@ -304,7 +413,7 @@ func (h *Helper) GetBlockchainInfoBlob() []byte {
 
 // GetBlockHeadMetadata returns metadata from block at the head of the blockchain
 func (h *Helper) GetBlockHeadMetadata() ([]byte, error) {
-	ledger, err := ledger.GetLedger()
+	ledger, err := h.getLedger()
 	if err != nil {
 		return nil, err
 	}
@@ -375,6 +484,15 @@ func (h *Helper) RolledBack(tag interface{}) {
 
 // StateUpdated is called when state transfer completes, if target is nil, this indicates a failure and a new target should be supplied
 func (h *Helper) StateUpdated(tag interface{}, target *pb.BlockchainInfo) {
+	if _, ok := tag.(stateRepair); ok {
+		if target == nil {
+			logger.Error("The transfer of the damaged state failed")
+			return
+		}
+		logger.Infof("The damaged state has been transferred at height %d", target.Height)
+		h.ValidateState()
+		return
+	}
 	if h.consenter != nil {
 		h.consenter.StateUpdated(tag, target)
 	}
@@ -23,6 +23,7 @@ import (
 
 	"github.com/golang/protobuf/proto"
 	"github.com/op/go-logging"
+	google_protobuf "google/protobuf"
 
 	"github.com/hyperledger/fabric/consensus"
 	"github.com/hyperledger/fabric/core/ledger"
@@ -44,6 +45,8 @@ type Noops struct {
 	timer    *time.Timer
 	duration time.Duration
 	channel  chan *pb.Transaction
+
+	batchTimestamp *google_protobuf.Timestamp // timestamp of the batch being processed
 }
 
 // Setting up a singleton NOOPS consenter
@@ -57,6 +60,11 @@ func GetNoops(c consensus.Stack) consensus.Consenter {
 	return iNoops
 }
 
+// New returns a new Noops instance, GetNoops returning the shared one
+func New(c consensus.Stack) consensus.Consenter {
+	return newNoops(c)
+}
+
 // newNoops is a constructor returning a consensus.Consenter object.
 func newNoops(c consensus.Stack) consensus.Consenter {
 	var err error
@@ -201,7 +209,24 @@ func (i *Noops) processBlock() error {
 }
 
 func (i *Noops) processTransactions() error {
-	timestamp := util.CreateUtcTimestamp()
+	// Grab all transactions from the FIFO queue and run them in order
+	txarr := i.txQ.getTXs()
+
+	// The batch is stamped with the latest timestamp of its transactions, so
+	// that the validators running the same batch commit the same block. The
+	// timestamps are set by the clients, which are not trusted: a timestamp
+	// is never later than the time of the validator, so that a client cannot
+	// activate a chaincode before its effective date.
+	var timestamp *google_protobuf.Timestamp
+	for _, tx := range txarr {
+		if pb.TimestampBefore(timestamp, tx.Timestamp) {
+			timestamp = tx.Timestamp
+		}
+	}
+	if now := util.CreateUtcTimestamp(); timestamp == nil || pb.TimestampBefore(now, timestamp) {
+		timestamp = now
+	}
+	i.batchTimestamp = timestamp
 	if logger.IsEnabledFor(logging.DEBUG) {
 		logger.Debugf("Starting TX batch with timestamp: %v", timestamp)
 	}
@@ -209,8 +234,6 @@ func (i *Noops) processTransactions() error {
 		return err
 	}
 
-	// Grab all transactions from the FIFO queue and run them in order
-	txarr := i.txQ.getTXs()
 	if logger.IsEnabledFor(logging.DEBUG) {
 		logger.Debugf("Executing batch of %d transactions with timestamp %v", len(txarr), timestamp)
 	}
@@ -234,6 +257,12 @@ func (i *Noops) processTransactions() error {
 	return nil
 }
 
+// GetBlockTimestamp returns the timestamp of the batch being committed, the
+// validator being its own consensus
+func (i *Noops) GetBlockTimestamp(metadata []byte) *google_protobuf.Timestamp {
+	return i.batchTimestamp
+}
+
 func (i *Noops) getTxFromMsg(msg *pb.Message) (*pb.Transaction, error) {
 	txs := &pb.TransactionBlock{}
 	if err := proto.Unmarshal(msg.Payload, txs); err != nil {
@@ -242,22 +271,34 @@ func (i *Noops) getTxFromMsg(msg *pb.Message) (*pb.Transaction, error) {
 	return txs.GetTransactions()[0], nil
 }
 
+// ledgerStack is implemented by the stacks operating on a ledger other than
+// the ledger of the default chain of the process
+type ledgerStack interface {
+	GetLedger() (*ledger.Ledger, error)
+}
+
 func (i *Noops) getBlockData() (*pb.Block, *statemgmt.StateDelta, error) {
-	ledger, err := ledger.GetLedger()
+	var lgr *ledger.Ledger
+	var err error
+	if ls, ok := i.stack.(ledgerStack); ok {
+		lgr, err = ls.GetLedger()
+	} else {
+		lgr, err = ledger.GetLedger()
+	}
 	if err != nil {
 		return nil, nil, fmt.Errorf("Fail to get the ledger: %v", err)
 	}
 
-	blockHeight := ledger.GetBlockchainSize()
+	blockHeight := lgr.GetBlockchainSize()
 	if logger.IsEnabledFor(logging.DEBUG) {
 		logger.Debugf("Preparing to broadcast with block number %v", blockHeight)
 	}
-	block, err := ledger.GetBlockByNumber(blockHeight - 1)
+	block, err := lgr.GetBlockByNumber(blockHeight - 1)
 	if nil != err {
 		return nil, nil, err
 	}
-	//delta, err := ledger.GetStateDeltaBytes(blockHeight)
-	delta, err := ledger.GetStateDelta(blockHeight - 1)
+	//delta, err := lgr.GetStateDeltaBytes(blockHeight)
+	delta, err := lgr.GetStateDelta(blockHeight - 1)
 	if nil != err {
 		return nil, nil, err
 	}
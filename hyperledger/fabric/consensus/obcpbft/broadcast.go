@@ -33,10 +33,12 @@ type communicator interface {
 type broadcaster struct {
 	comm communicator
 
-	f        int
-	msgChans map[uint64]chan *sendRequest
-	closed   sync.WaitGroup
-	closedCh chan struct{}
+	self      uint64
+	f         int
+	msgChans  map[uint64]chan *sendRequest
+	stopChans map[uint64]chan struct{}
+	closed    sync.WaitGroup
+	closedCh  chan struct{}
 }
 
 type sendRequest struct {
@@ -45,25 +47,49 @@ type sendRequest struct {
 }
 
 func newBroadcaster(self uint64, N int, f int, c communicator) *broadcaster {
-	queueSize := 10 // XXX increase after testing
-
-	chans := make(map[uint64]chan *sendRequest)
 	b := &broadcaster{
-		comm:     c,
-		f:        f,
-		msgChans: chans,
-		closedCh: make(chan struct{}),
+		comm:      c,
+		self:      self,
+		f:         f,
+		msgChans:  make(map[uint64]chan *sendRequest),
+		stopChans: make(map[uint64]chan struct{}),
+		closedCh:  make(chan struct{}),
 	}
 	for i := 0; i < N; i++ {
-		if uint64(i) == self {
-			continue
-		}
-		chans[uint64(i)] = make(chan *sendRequest, queueSize)
-		go b.drainer(uint64(i))
+		b.addReplica(uint64(i))
 	}
 	return b
 }
 
+func (b *broadcaster) addReplica(dest uint64) {
+	queueSize := 10 // XXX increase after testing
+
+	if _, ok := b.msgChans[dest]; ok || dest == b.self {
+		return
+	}
+	b.msgChans[dest] = make(chan *sendRequest, queueSize)
+	b.stopChans[dest] = make(chan struct{})
+	go b.drainer(dest, b.msgChans[dest], b.stopChans[dest])
+}
+
+// setReplicas changes the replicas the messages are sent to, once the
+// membership was reconfigured. It must be called from the thread sending.
+func (b *broadcaster) setReplicas(replicas []uint64, f int) {
+	b.f = f
+	members := make(map[uint64]bool)
+	for _, id := range replicas {
+		members[id] = true
+		b.addReplica(id)
+	}
+	for dest, stop := range b.stopChans {
+		if !members[dest] {
+			close(stop)
+			delete(b.msgChans, dest)
+			delete(b.stopChans, dest)
+		}
+	}
+}
+
 func (b *broadcaster) Close() {
 	close(b.closedCh)
 	b.closed.Wait()
@@ -101,24 +127,32 @@ func (b *broadcaster) drainerSend(dest uint64, send *sendRequest, successLastTim
 
 }
 
-func (b *broadcaster) drainer(dest uint64) {
+func (b *broadcaster) drainer(dest uint64, msgChan chan *sendRequest, stop chan struct{}) {
 	successLastTime := false
 
 	for {
 		select {
-		case send := <-b.msgChans[dest]:
+		case send := <-msgChan:
 			successLastTime = b.drainerSend(dest, send, successLastTime)
 		case <-b.closedCh:
-			for {
-				// Drain the message channel to free calling waiters before we shut down
-				select {
-				case send := <-b.msgChans[dest]:
-					send.done <- false
-					b.closed.Done()
-				default:
-					return
-				}
-			}
+			b.drain(msgChan)
+			return
+		case <-stop:
+			b.drain(msgChan)
+			return
+		}
+	}
+}
+
+// drain empties the message channel to free calling waiters before a drainer shuts down
+func (b *broadcaster) drain(msgChan chan *sendRequest) {
+	for {
+		select {
+		case send := <-msgChan:
+			send.done <- false
+			b.closed.Done()
+		default:
+			return
 		}
 	}
 }
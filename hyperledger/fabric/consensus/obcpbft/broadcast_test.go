@@ -272,3 +272,36 @@ func TestBroadcastIndefinitelyStuck(t *testing.T) {
 	close(m.done)
 	b.Close()
 }
+
+func TestBroadcastSetReplicas(t *testing.T) {
+	m := &mockComm{
+		self:  1,
+		n:     5,
+		msgCh: make(chan mockMsg, 8),
+	}
+	b := newBroadcaster(1, 4, 1, m)
+	defer b.Close()
+
+	b.setReplicas([]uint64{1, 2, 3, 4}, 1)
+	b.Broadcast(&pb.Message{Payload: []byte("hi")})
+
+	sent := make(map[string]bool)
+	for i := 0; i < 3; i++ {
+		msg := <-m.msgCh
+		sent[msg.dest.Name] = true
+	}
+	for _, name := range []string{"vp2", "vp3", "vp4"} {
+		if !sent[name] {
+			t.Errorf("broadcast did not send to %s: %v", name, sent)
+		}
+	}
+
+	if err := b.Unicast(&pb.Message{Payload: []byte("hi")}, 0); err != nil {
+		t.Fatalf("unicast failed: %v", err)
+	}
+	select {
+	case msg := <-m.msgCh:
+		t.Errorf("unicast sent to %s, which is not a replica anymore", msg.dest.Name)
+	default:
+	}
+}
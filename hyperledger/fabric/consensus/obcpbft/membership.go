@@ -0,0 +1,271 @@
+/*
+Copyright IBM Corp. 2016 All Rights Reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+		 http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package obcpbft
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+
+	"github.com/golang/protobuf/proto"
+
+	"github.com/hyperledger/fabric/core/util"
+	pb "github.com/hyperledger/fabric/protos"
+)
+
+// membershipListener is implemented by the consumers which must know the
+// replica set, to be told when it changes
+type membershipListener interface {
+	membershipChanged(replicas []uint64, f int)
+}
+
+// NewReconfigurationTransaction creates the transaction replacing the replica
+// set by the validators of the reconfiguration, named like vp0, vp1... It is
+// submitted as any other transaction once a quorum of the current replicas
+// approved it, and is only processed in batch mode.
+func NewReconfigurationTransaction(rc *Reconfiguration) (*pb.Transaction, error) {
+	payload, err := proto.Marshal(rc)
+	if err != nil {
+		return nil, fmt.Errorf("Could not marshal reconfiguration: %s", err)
+	}
+	return &pb.Transaction{
+		Type:      pb.Transaction_CONSENSUS_RECONFIGURE,
+		Payload:   payload,
+		Uuid:      util.GenerateUUID(),
+		Timestamp: util.CreateUtcTimestamp(),
+	}, nil
+}
+
+// parseReconfiguration returns a reconfiguration and the sorted replica IDs of
+// its validators
+func parseReconfiguration(payload []byte) (*Reconfiguration, []uint64, error) {
+	rc := &Reconfiguration{}
+	if err := proto.Unmarshal(payload, rc); err != nil {
+		return nil, nil, fmt.Errorf("could not unmarshal reconfiguration: %s", err)
+	}
+	replicas, err := getReconfigurationReplicas(rc.Validators)
+	if err != nil {
+		return nil, nil, err
+	}
+	return rc, replicas, nil
+}
+
+// getReconfigurationReplicas returns the sorted replica IDs of the validators
+func getReconfigurationReplicas(validators []string) ([]uint64, error) {
+	if len(validators) == 0 {
+		return nil, fmt.Errorf("reconfiguration to an empty replica set")
+	}
+	replicas := make([]uint64, 0, len(validators))
+	seen := make(map[uint64]bool)
+	for _, name := range validators {
+		id, err := getValidatorID(&pb.PeerID{Name: name})
+		if err != nil {
+			return nil, err
+		}
+		if seen[id] {
+			return nil, fmt.Errorf("validator %s appears twice in reconfiguration", name)
+		}
+		seen[id] = true
+		replicas = append(replicas, id)
+	}
+	sort.Sort(sortableUint64Slice(replicas))
+	return replicas, nil
+}
+
+// getApprovedMessage returns what the replicas approving the reconfiguration
+// sign: the reconfiguration without its approvals
+func getApprovedMessage(rc *Reconfiguration) []byte {
+	raw, _ := proto.Marshal(&Reconfiguration{Validators: rc.Validators, Replicas: rc.Replicas})
+	return raw
+}
+
+// approveReconfiguration adds the approval of the replica to the
+// reconfiguration, replacing its previous one if any
+func approveReconfiguration(rc *Reconfiguration, id uint64, sign func(msg []byte) ([]byte, error)) error {
+	signature, err := sign(getApprovedMessage(rc))
+	if err != nil {
+		return fmt.Errorf("could not sign reconfiguration: %s", err)
+	}
+	approvals := []*ReconfigurationApproval{}
+	for _, approval := range rc.Approvals {
+		if approval.ReplicaId != id {
+			approvals = append(approvals, approval)
+		}
+	}
+	rc.Approvals = append(approvals, &ReconfigurationApproval{ReplicaId: id, Signature: signature})
+	return nil
+}
+
+// checkApprovals returns an error unless a quorum of the current replicas
+// approved the reconfiguration, for the current replica set so that approvals
+// cannot be replayed once the replica set changed
+func (instance *pbftCore) checkApprovals(rc *Reconfiguration, verify func(senderID uint64, signature []byte, message []byte) error) error {
+	if !reflect.DeepEqual(rc.Replicas, instance.getReplicas()) {
+		return fmt.Errorf("reconfiguration approved for the replica set %v, not the current one %v", rc.Replicas, instance.getReplicas())
+	}
+	msg := getApprovedMessage(rc)
+	approved := make(map[uint64]bool)
+	for _, approval := range rc.Approvals {
+		if !instance.isReplica(approval.ReplicaId) || approved[approval.ReplicaId] {
+			continue
+		}
+		if err := verify(approval.ReplicaId, approval.Signature, msg); err != nil {
+			logger.Warningf("Replica %d ignoring invalid approval of reconfiguration from replica %d: %s", instance.id, approval.ReplicaId, err)
+			continue
+		}
+		approved[approval.ReplicaId] = true
+	}
+	if len(approved) < instance.intersectionQuorum() {
+		return fmt.Errorf("reconfiguration approved by %d replicas, %d required", len(approved), instance.intersectionQuorum())
+	}
+	return nil
+}
+
+// getReplicas returns the current replica set
+func (instance *pbftCore) getReplicas() []uint64 {
+	if instance.replicas != nil {
+		return instance.replicas
+	}
+	replicas := make([]uint64, instance.N)
+	for i := range replicas {
+		replicas[i] = uint64(i)
+	}
+	return replicas
+}
+
+// isReplica returns whether the replica is part of the current replica set
+func (instance *pbftCore) isReplica(id uint64) bool {
+	if instance.replicas == nil {
+		return id < uint64(instance.N)
+	}
+	for _, r := range instance.replicas {
+		if r == id {
+			return true
+		}
+	}
+	return false
+}
+
+// latestReplicas returns the last replica set decided, nil if the replica set
+// was never reconfigured
+func (instance *pbftCore) latestReplicas() []uint64 {
+	if len(instance.memberships) == 0 {
+		return nil
+	}
+	return instance.memberships[len(instance.memberships)-1].Replicas
+}
+
+// reconfigurationSeqNo returns the checkpoint at which a reconfiguration
+// executed at seqNo takes effect
+func (instance *pbftCore) reconfigurationSeqNo(seqNo uint64) uint64 {
+	return (seqNo + instance.K - 1) / instance.K * instance.K
+}
+
+// reconfigure records the replica set decided by a reconfiguration executed
+// at seqNo. The replica set takes over after the next checkpoint, the primary
+// does not assign the sequence numbers beyond it in the meantime.
+func (instance *pbftCore) reconfigure(seqNo uint64, replicas []uint64) {
+	instance.recordMembership(instance.reconfigurationSeqNo(seqNo), replicas)
+	logger.Infof("Replica %d reconfigured at seqNo %d to replica set %v, taking effect after checkpoint %d",
+		instance.id, seqNo, replicas, instance.reconfigSeqNo)
+}
+
+// adoptMembership adopts the latest replica set decided as of seqNo, which a
+// replica bootstrapping via state transfer did not execute
+func (instance *pbftCore) adoptMembership(seqNo uint64, replicas []uint64) {
+	if reflect.DeepEqual(instance.latestReplicas(), replicas) {
+		return
+	}
+	logger.Infof("Replica %d adopting replica set %v decided as of seqNo %d", instance.id, replicas, seqNo)
+	instance.recordMembership(instance.reconfigurationSeqNo(seqNo), replicas)
+	instance.applyPendingMembership()
+}
+
+func (instance *pbftCore) recordMembership(seqNo uint64, replicas []uint64) {
+	instance.memberships = append(instance.memberships, &Membership{SeqNo: seqNo, Replicas: replicas})
+	instance.persistMemberships()
+	instance.pendingReplicas = replicas
+	instance.reconfigSeqNo = seqNo
+}
+
+// applyPendingMembership switches to the pending replica set once its
+// checkpoint was executed, and returns whether it did
+func (instance *pbftCore) applyPendingMembership() bool {
+	if instance.pendingReplicas == nil || instance.lastExec < instance.reconfigSeqNo {
+		return false
+	}
+	instance.setReplicas(instance.pendingReplicas)
+	instance.pendingReplicas = nil
+	instance.reconfigSeqNo = ^uint64(0) // infinity
+	if l, ok := instance.consumer.(membershipListener); ok {
+		l.membershipChanged(instance.replicas, instance.f)
+	}
+	return true
+}
+
+// setReplicas makes the replica set current, recomputing N and f
+func (instance *pbftCore) setReplicas(replicas []uint64) {
+	instance.replicas = replicas
+	instance.N = len(replicas)
+	instance.f = (instance.N - 1) / 3
+	instance.replicaCount = instance.N
+	logger.Infof("Replica %d replica set is now %v, N=%d, f=%d", instance.id, replicas, instance.N, instance.f)
+}
+
+// advanceToReconfiguration is called after each execution while a replica
+// set is pending: the primary orders null requests when idle, not to wait for
+// further requests to reach the checkpoint at which the replica set takes over
+func (instance *pbftCore) advanceToReconfiguration() {
+	if instance.primary(instance.view) == instance.id && instance.activeView && instance.seqNo == instance.lastExec {
+		instance.sendPrePrepare(nil, "")
+	}
+}
+
+func (instance *pbftCore) persistMemberships() {
+	raw, err := proto.Marshal(&MembershipHistory{instance.memberships})
+	if err != nil {
+		logger.Warningf("Replica %d could not persist membership history: %s", instance.id, err)
+		return
+	}
+	instance.consumer.StoreState("membership", raw)
+}
+
+// restoreMemberships restores the replica set in effect after lastExec, and
+// the pending one if any
+func (instance *pbftCore) restoreMemberships() {
+	raw, err := instance.consumer.ReadState("membership")
+	if err != nil {
+		logger.Debugf("Replica %d could not restore membership history: %s", instance.id, err)
+		return
+	}
+	history := &MembershipHistory{}
+	if err = proto.Unmarshal(raw, history); err != nil {
+		logger.Errorf("Replica %d could not unmarshal membership history - local state is damaged: %s", instance.id, err)
+		return
+	}
+	instance.memberships = history.GetMemberships()
+	for _, m := range instance.memberships {
+		if m.SeqNo <= instance.lastExec {
+			instance.setReplicas(m.Replicas)
+			instance.pendingReplicas = nil
+			instance.reconfigSeqNo = ^uint64(0)
+		} else {
+			instance.pendingReplicas = m.Replicas
+			instance.reconfigSeqNo = m.SeqNo
+		}
+	}
+}
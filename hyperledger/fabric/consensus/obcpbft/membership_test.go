@@ -0,0 +1,247 @@
+/*
+Copyright IBM Corp. 2016 All Rights Reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+		 http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package obcpbft
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+
+	pb "github.com/hyperledger/fabric/protos"
+
+	"github.com/golang/protobuf/proto"
+)
+
+// createOcMsgWithReconfiguration creates the reconfiguration to the
+// validators, approved by the replicas of the network in turn
+func createOcMsgWithReconfiguration(t *testing.T, net *consumerNetwork, approvers []uint64, validators ...string) *pb.Message {
+	var payload []byte
+	for _, id := range approvers {
+		var err error
+		payload, err = net.endpoints[id].(*consumerEndpoint).consumer.(*obcBatch).ApproveReconfiguration(validators, payload)
+		if err != nil {
+			t.Fatalf("Replica %d failed to approve reconfiguration: %s", id, err)
+		}
+	}
+	rc := &Reconfiguration{}
+	proto.Unmarshal(payload, rc)
+	tx, err := NewReconfigurationTransaction(rc)
+	if err != nil {
+		t.Fatalf("Failed to create reconfiguration transaction: %s", err)
+	}
+	txPacked, _ := proto.Marshal(tx)
+	return &pb.Message{
+		Type:    pb.Message_CHAIN_TRANSACTION,
+		Payload: txPacked,
+	}
+}
+
+func TestParseReconfiguration(t *testing.T) {
+	tx, _ := NewReconfigurationTransaction(&Reconfiguration{Validators: []string{"vp3", "vp1", "vp4"}})
+	_, replicas, err := parseReconfiguration(tx.Payload)
+	if err != nil {
+		t.Fatalf("Failed to parse reconfiguration: %s", err)
+	}
+	if !reflect.DeepEqual(replicas, []uint64{1, 3, 4}) {
+		t.Errorf("Expected replicas [1 3 4], got %v", replicas)
+	}
+
+	for _, validators := range [][]string{nil, {"vp1", "vp1"}, {"vp1", "nvp0"}} {
+		tx, _ = NewReconfigurationTransaction(&Reconfiguration{Validators: validators})
+		if _, _, err = parseReconfiguration(tx.Payload); err == nil {
+			t.Errorf("Expected an error parsing reconfiguration to %v", validators)
+		}
+	}
+}
+
+func TestCheckApprovals(t *testing.T) {
+	instance := newPbftCore(0, loadConfig(), &omniProto{}, &inertTimerFactory{})
+	defer instance.close()
+	instance.N = 4
+	instance.f = 1
+
+	// Replicas sign with their ID
+	sign := func(id uint64) func([]byte) ([]byte, error) {
+		return func(msg []byte) ([]byte, error) {
+			return append(msg, byte(id)), nil
+		}
+	}
+	verify := func(id uint64, signature []byte, msg []byte) error {
+		if !reflect.DeepEqual(signature, append(msg, byte(id))) {
+			return fmt.Errorf("invalid signature")
+		}
+		return nil
+	}
+
+	rc := &Reconfiguration{Validators: []string{"vp1", "vp2", "vp3", "vp4"}, Replicas: []uint64{0, 1, 2, 3}}
+	approveReconfiguration(rc, 0, sign(0))
+	approveReconfiguration(rc, 1, sign(1))
+	approveReconfiguration(rc, 1, sign(1))
+	if err := instance.checkApprovals(rc, verify); err == nil {
+		t.Errorf("Expected the approvals of 2 replicas to be rejected")
+	}
+
+	// Neither the replicas out of the replica set nor invalid signatures count
+	approveReconfiguration(rc, 4, sign(4))
+	approveReconfiguration(rc, 2, sign(3))
+	if err := instance.checkApprovals(rc, verify); err == nil {
+		t.Errorf("Expected the approvals with an invalid signature to be rejected")
+	}
+
+	approveReconfiguration(rc, 2, sign(2))
+	if err := instance.checkApprovals(rc, verify); err != nil {
+		t.Errorf("Expected the approvals of 3 replicas to be accepted: %s", err)
+	}
+
+	// The approvals of another replica set cannot be replayed
+	instance.setReplicas([]uint64{0, 1, 2, 4})
+	if err := instance.checkApprovals(rc, verify); err == nil {
+		t.Errorf("Expected the approvals of another replica set to be rejected")
+	}
+}
+
+func TestReconfigurationSeqNo(t *testing.T) {
+	instance := newPbftCore(0, loadConfig(), &omniProto{}, &inertTimerFactory{})
+	defer instance.close()
+	instance.K = 10
+
+	for seqNo, expected := range map[uint64]uint64{1: 10, 9: 10, 10: 10, 11: 20} {
+		if n := instance.reconfigurationSeqNo(seqNo); n != expected {
+			t.Errorf("Expected a reconfiguration executed at %d to take effect at %d, got %d", seqNo, expected, n)
+		}
+	}
+}
+
+func TestBatchReconfiguration(t *testing.T) {
+	// vp4 starts out of the replica set, vp0 is removed by the reconfiguration
+	net := makeConsumerNetwork(5, obcBatchHelper, func(ce *consumerEndpoint) {
+		ce.consumer.(*obcBatch).batchSize = 1
+		ce.consumer.getPBFTCore().N = 4
+		ce.consumer.getPBFTCore().f = 1
+	})
+	defer net.stop()
+
+	// The reconfiguration is ignored until a quorum of the current replicas approved it
+	err := net.endpoints[1].(*consumerEndpoint).consumer.RecvMsg(createOcMsgWithReconfiguration(t, net, []uint64{1, 2}, "vp1", "vp2", "vp3", "vp4"), net.endpoints[1].getHandle())
+	if err != nil {
+		t.Fatalf("Reconfiguration was not processed: %s", err)
+	}
+	net.process()
+	for _, ep := range net.endpoints {
+		if instance := ep.(*consumerEndpoint).consumer.getPBFTCore(); instance.pendingReplicas != nil || instance.replicas != nil {
+			t.Fatalf("Replica %d expected to ignore the reconfiguration approved by 2 replicas", instance.id)
+		}
+	}
+
+	err = net.endpoints[1].(*consumerEndpoint).consumer.RecvMsg(createOcMsgWithReconfiguration(t, net, []uint64{1, 2, 3}, "vp1", "vp2", "vp3", "vp4"), net.endpoints[1].getHandle())
+	if err != nil {
+		t.Fatalf("Reconfiguration was not processed: %s", err)
+	}
+	net.process()
+
+	expected := []uint64{1, 2, 3, 4}
+	for _, ep := range net.endpoints {
+		ce := ep.(*consumerEndpoint)
+		instance := ce.consumer.getPBFTCore()
+		if !reflect.DeepEqual(instance.replicas, expected) || instance.N != 4 || instance.f != 1 {
+			t.Errorf("Replica %d expected replica set %v with N=4 and f=1, got %v with N=%d and f=%d", ce.id, expected, instance.replicas, instance.N, instance.f)
+		}
+		if ce.id != 0 && (instance.view != 1 || !instance.activeView) {
+			t.Errorf("Replica %d expected to be active in view 1, got view %d (active %v)", ce.id, instance.view, instance.activeView)
+		}
+		if ce.id == 0 && instance.isReplica(0) {
+			t.Errorf("Replica 0 expected to be out of the replica set")
+		}
+	}
+
+	err = net.endpoints[4].(*consumerEndpoint).consumer.RecvMsg(createOcMsgWithChainTx(1), net.endpoints[4].getHandle())
+	if err != nil {
+		t.Fatalf("Request was not processed: %s", err)
+	}
+	net.process()
+
+	// The reconfigurations are committed in empty blocks, which record the replica set
+	for _, ep := range net.endpoints {
+		ce := ep.(*consumerEndpoint)
+		ledger := net.mockLedgers[ce.id]
+		expectedSize := uint64(4)
+		if ce.id == 0 {
+			expectedSize = 3
+		}
+		if size := ledger.GetBlockchainSize(); size != expectedSize {
+			t.Errorf("Replica %d expected to have %d blocks, got %d", ce.id, expectedSize, size)
+			continue
+		}
+		block, _ := ledger.GetBlock(1)
+		meta := &Metadata{}
+		proto.Unmarshal(block.ConsensusMetadata, meta)
+		if meta.Replicas != nil {
+			t.Errorf("Replica %d expected no replica set in the metadata of the ignored reconfiguration, got %v", ce.id, meta.Replicas)
+		}
+		block, _ = ledger.GetBlock(2)
+		meta = &Metadata{}
+		proto.Unmarshal(block.ConsensusMetadata, meta)
+		if !reflect.DeepEqual(meta.Replicas, expected) {
+			t.Errorf("Replica %d expected the replica set %v in the block metadata, got %v", ce.id, expected, meta.Replicas)
+		}
+		if ce.id != 0 {
+			block, _ = ledger.GetBlock(3)
+			if len(block.Transactions) != 1 {
+				t.Errorf("Replica %d expected 1 transaction in block 3, got %d", ce.id, len(block.Transactions))
+			}
+		}
+	}
+}
+
+func TestRestoreMembership(t *testing.T) {
+	store := make(map[string][]byte)
+	omni := &omniProto{
+		StoreStateImpl: func(key string, value []byte) error {
+			store[key] = value
+			return nil
+		},
+		ReadStateImpl: func(key string) ([]byte, error) {
+			if val, ok := store[key]; ok {
+				return val, nil
+			}
+			return nil, fmt.Errorf("no such key %s", key)
+		},
+		ReadStateSetImpl: func(prefix string) (map[string][]byte, error) {
+			return nil, fmt.Errorf("unimplemented")
+		},
+		getLastSeqNoImpl: func() (uint64, error) {
+			return 15, nil
+		},
+	}
+	instance := newPbftCore(0, loadConfig(), omni, &inertTimerFactory{})
+	instance.K = 10
+	instance.reconfigure(5, []uint64{0, 1, 2, 4})
+	instance.reconfigure(12, []uint64{0, 1, 2, 4, 5})
+	instance.close()
+
+	instance = newPbftCore(0, loadConfig(), omni, &inertTimerFactory{})
+	defer instance.close()
+	if !reflect.DeepEqual(instance.replicas, []uint64{0, 1, 2, 4}) || instance.N != 4 {
+		t.Errorf("Expected restored replica set [0 1 2 4], got %v with N=%d", instance.replicas, instance.N)
+	}
+	if !reflect.DeepEqual(instance.pendingReplicas, []uint64{0, 1, 2, 4, 5}) || instance.reconfigSeqNo != 20 {
+		t.Errorf("Expected pending replica set [0 1 2 4 5] at 20, got %v at %d", instance.pendingReplicas, instance.reconfigSeqNo)
+	}
+	if instance.primary(3) != 4 {
+		t.Errorf("Expected replica 4 to be the primary of view 3, got %d", instance.primary(3))
+	}
+}
@@ -29,6 +29,10 @@ It has these top-level messages:
 	VerifySet
 	Flush
 	Metadata
+	Reconfiguration
+	ReconfigurationApproval
+	Membership
+	MembershipHistory
 */
 package obcpbft
 
@@ -977,9 +981,68 @@ func (m *Flush) String() string { return proto.CompactTextString(m) }
 func (*Flush) ProtoMessage()    {}
 
 type Metadata struct {
-	SeqNo uint64 `protobuf:"varint,1,opt,name=seqNo" json:"seqNo,omitempty"`
+	SeqNo     uint64                     `protobuf:"varint,1,opt,name=seqNo" json:"seqNo,omitempty"`
+	Replicas  []uint64                   `protobuf:"varint,2,rep,name=replicas" json:"replicas,omitempty"`
+	Timestamp *google_protobuf.Timestamp `protobuf:"bytes,3,opt,name=timestamp" json:"timestamp,omitempty"`
 }
 
 func (m *Metadata) Reset()         { *m = Metadata{} }
 func (m *Metadata) String() string { return proto.CompactTextString(m) }
 func (*Metadata) ProtoMessage()    {}
+
+func (m *Metadata) GetTimestamp() *google_protobuf.Timestamp {
+	if m != nil {
+		return m.Timestamp
+	}
+	return nil
+}
+
+type Reconfiguration struct {
+	Validators []string                   `protobuf:"bytes,1,rep,name=validators" json:"validators,omitempty"`
+	Replicas   []uint64                   `protobuf:"varint,2,rep,name=replicas" json:"replicas,omitempty"`
+	Approvals  []*ReconfigurationApproval `protobuf:"bytes,3,rep,name=approvals" json:"approvals,omitempty"`
+}
+
+func (m *Reconfiguration) Reset()         { *m = Reconfiguration{} }
+func (m *Reconfiguration) String() string { return proto.CompactTextString(m) }
+func (*Reconfiguration) ProtoMessage()    {}
+
+func (m *Reconfiguration) GetApprovals() []*ReconfigurationApproval {
+	if m != nil {
+		return m.Approvals
+	}
+	return nil
+}
+
+type ReconfigurationApproval struct {
+	ReplicaId uint64 `protobuf:"varint,1,opt,name=replica_id" json:"replica_id,omitempty"`
+	Signature []byte `protobuf:"bytes,2,opt,name=signature,proto3" json:"signature,omitempty"`
+}
+
+func (m *ReconfigurationApproval) Reset()         { *m = ReconfigurationApproval{} }
+func (m *ReconfigurationApproval) String() string { return proto.CompactTextString(m) }
+func (*ReconfigurationApproval) ProtoMessage()    {}
+
+type Membership struct {
+	SeqNo    uint64   `protobuf:"varint,1,opt,name=seqNo" json:"seqNo,omitempty"`
+	Replicas []uint64 `protobuf:"varint,2,rep,name=replicas" json:"replicas,omitempty"`
+}
+
+func (m *Membership) Reset()         { *m = Membership{} }
+func (m *Membership) String() string { return proto.CompactTextString(m) }
+func (*Membership) ProtoMessage()    {}
+
+type MembershipHistory struct {
+	Memberships []*Membership `protobuf:"bytes,1,rep,name=memberships" json:"memberships,omitempty"`
+}
+
+func (m *MembershipHistory) Reset()         { *m = MembershipHistory{} }
+func (m *MembershipHistory) String() string { return proto.CompactTextString(m) }
+func (*MembershipHistory) ProtoMessage()    {}
+
+func (m *MembershipHistory) GetMemberships() []*Membership {
+	if m != nil {
+		return m.Memberships
+	}
+	return nil
+}
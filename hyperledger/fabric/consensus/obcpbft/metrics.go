@@ -0,0 +1,33 @@
+/*
+Copyright IBM Corp. 2016 All Rights Reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+		 http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package obcpbft
+
+import (
+	"github.com/hyperledger/fabric/core/metrics"
+)
+
+var (
+	viewGauge = metrics.NewGauge("pbft_view",
+		"Current view of the replica.")
+	viewChanges = metrics.NewCounter("pbft_view_changes_total",
+		"Number of view changes sent by the replica.")
+	batchSizes = metrics.NewHistogram("pbft_batch_size",
+		"Number of requests in the batches sent for ordering.",
+		[]float64{1, 2, 5, 10, 20, 50, 100, 200, 500, 1000})
+	outstandingRequests = metrics.NewGauge("pbft_outstanding_requests",
+		"Number of requests received by the replica and not executed yet.")
+)
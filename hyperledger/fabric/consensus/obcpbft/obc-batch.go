@@ -18,6 +18,7 @@ package obcpbft
 
 import (
 	"fmt"
+	"reflect"
 	"time"
 
 	"github.com/hyperledger/fabric/consensus"
@@ -91,6 +92,7 @@ func newObcBatch(id uint64, config *viper.Viper, stack consensus.Stack) *obcBatc
 	op.manager.Start()
 	op.externalEventReceiver.manager = op.manager
 	op.broadcaster = newBroadcaster(id, op.pbft.N, op.pbft.f, stack)
+	op.broadcaster.setReplicas(op.pbft.getReplicas(), op.pbft.f) // the replica set may have been reconfigured
 
 	op.batchSize = config.GetInt("general.batchsize")
 	op.batchStore = nil
@@ -201,8 +203,13 @@ func (op *obcBatch) execute(seqNo uint64, raw []byte) {
 	}
 
 	var txs []*pb.Transaction
+	var timestamp *google_protobuf.Timestamp
 
 	for _, req := range reqs.Requests {
+		// The requests are timestamped by the replicas, not by the clients
+		if pb.TimestampBefore(timestamp, req.Timestamp) {
+			timestamp = req.Timestamp
+		}
 
 		tx := &pb.Transaction{}
 		if err := proto.Unmarshal(req.Payload, tx); err != nil {
@@ -216,22 +223,106 @@ func (op *obcBatch) execute(seqNo uint64, raw []byte) {
 		if outstanding, pending := op.reqStore.remove(req); !outstanding || !pending {
 			logger.Debugf("Batch replica %d missing transaction %s outstanding=%v, pending=%v", op.pbft.id, tx.Uuid, outstanding, pending)
 		}
-		txs = append(txs, tx)
 
 		op.deduplicator.Execute(req)
+
+		if tx.Type == pb.Transaction_CONSENSUS_RECONFIGURE {
+			rc, replicas, err := parseReconfiguration(tx.Payload)
+			if err == nil {
+				err = op.pbft.checkApprovals(rc, op.verify)
+			}
+			if err != nil {
+				logger.Warningf("Batch replica %d ignoring reconfiguration transaction %s: %s", op.pbft.id, tx.Uuid, err)
+			} else {
+				op.pbft.reconfigure(seqNo, replicas)
+			}
+			continue
+		}
+		txs = append(txs, tx)
 	}
 
-	meta, _ := proto.Marshal(&Metadata{seqNo})
+	// The replica set is recorded in the blocks, for replicas bootstrapping via state transfer
+	meta, _ := proto.Marshal(&Metadata{SeqNo: seqNo, Replicas: op.pbft.latestReplicas(), Timestamp: timestamp})
 
 	logger.Debugf("Batch replica %d received exec for seqNo %d containing %d transactions", op.pbft.id, seqNo, len(txs))
 
 	op.stack.Execute(meta, txs) // This executes in the background, we will receive an executedEvent once it completes
 }
 
+// membershipChanged updates the replicas the messages are sent to
+func (op *obcBatch) membershipChanged(replicas []uint64, f int) {
+	op.broadcaster.setReplicas(replicas, f)
+}
+
+// ApproveReconfiguration implements consensus.Reconfigurer: this replica signs
+// the reconfiguration of the payload, or a new one to the validators if the
+// payload is empty, for the current replica set
+func (op *obcBatch) ApproveReconfiguration(validators []string, payload []byte) ([]byte, error) {
+	rc := &Reconfiguration{Validators: validators}
+	if len(payload) > 0 {
+		if err := proto.Unmarshal(payload, rc); err != nil {
+			return nil, fmt.Errorf("could not unmarshal reconfiguration: %s", err)
+		}
+		if len(validators) > 0 && !reflect.DeepEqual(validators, rc.Validators) {
+			return nil, fmt.Errorf("reconfiguration to the validators %v, not %v", rc.Validators, validators)
+		}
+	}
+	if _, err := getReconfigurationReplicas(rc.Validators); err != nil {
+		return nil, err
+	}
+
+	var err error
+	done := make(chan struct{})
+	op.manager.Queue() <- workEvent(func() {
+		defer close(done)
+		replicas := op.pbft.getReplicas()
+		if !op.pbft.isReplica(op.pbft.id) {
+			err = fmt.Errorf("replica %d is not part of the replica set %v", op.pbft.id, replicas)
+			return
+		}
+		if rc.Replicas == nil {
+			rc.Replicas = append([]uint64(nil), replicas...)
+		} else if !reflect.DeepEqual(rc.Replicas, replicas) {
+			err = fmt.Errorf("reconfiguration of the replica set %v, not the current one %v", rc.Replicas, replicas)
+			return
+		}
+		err = approveReconfiguration(rc, op.pbft.id, op.sign)
+	})
+	<-done
+	if err != nil {
+		return nil, err
+	}
+	return proto.Marshal(rc)
+}
+
+// GetBlockTimestamp returns the latest timestamp of the requests ordered in
+// the batch committed with metadata
+func (op *obcBatch) GetBlockTimestamp(metadata []byte) *google_protobuf.Timestamp {
+	meta := &Metadata{}
+	if err := proto.Unmarshal(metadata, meta); err != nil {
+		return nil
+	}
+	return meta.Timestamp
+}
+
 // =============================================================================
 // functions specific to batch mode
 // =============================================================================
 
+// adoptLedgerMembership adopts the replica set recorded in the last block,
+// which may have been reconfigured while this replica was not executing
+func (op *obcBatch) adoptLedgerMembership() {
+	raw, err := op.stack.GetBlockHeadMetadata()
+	if err != nil || raw == nil {
+		return
+	}
+	meta := &Metadata{}
+	if err = proto.Unmarshal(raw, meta); err != nil || meta.Replicas == nil {
+		return
+	}
+	op.pbft.adoptMembership(meta.SeqNo, meta.Replicas)
+}
+
 func (op *obcBatch) leaderProcReq(req *Request) events.Event {
 	// XXX check req sig
 
@@ -273,6 +364,7 @@ func (op *obcBatch) sendBatch() events.Event {
 
 	// process internally
 	logger.Infof("Creating batch with %d requests", len(reqBlock.Requests))
+	batchSizes.Observe(float64(len(reqBlock.Requests)))
 	return pbftMessageEvent{
 		msg: &Message{&Message_Request{&Request{
 			Payload:   reqsPacked,
@@ -389,6 +481,9 @@ func (op *obcBatch) resubmitOutstandingReqs() events.Event {
 // allow the primary to send a batch when the timer expires
 func (op *obcBatch) ProcessEvent(event events.Event) events.Event {
 	logger.Debugf("Replica %d batch main thread looping", op.pbft.id)
+	defer func() {
+		outstandingRequests.Set(float64(op.reqStore.outstandingRequests.Len()))
+	}()
 	switch et := event.(type) {
 	case batchMessageEvent:
 		ocMsg := et
@@ -464,7 +559,11 @@ func (op *obcBatch) ProcessEvent(event events.Event) events.Event {
 	case stateUpdatedEvent:
 		// When the state is updated, clear any outstanding requests, they may have been processed while we were gone
 		op.reqStore = newRequestStore()
-		return op.pbft.ProcessEvent(event)
+		res := op.pbft.ProcessEvent(event)
+		if et.target != nil && !op.pbft.skipInProgress {
+			op.adoptLedgerMembership()
+		}
+		return res
 	default:
 		return op.pbft.ProcessEvent(event)
 	}
@@ -20,6 +20,8 @@ import (
 	"testing"
 	"time"
 
+	google_protobuf "google/protobuf"
+
 	"github.com/hyperledger/fabric/consensus"
 	"github.com/hyperledger/fabric/consensus/obcpbft/events"
 	pb "github.com/hyperledger/fabric/protos"
@@ -80,6 +82,40 @@ func TestNetworkBatch(t *testing.T) {
 	}
 }
 
+func TestBatchBlockTimestamp(t *testing.T) {
+	validatorCount := 4
+	net := makeConsumerNetwork(validatorCount, obcBatchHelper, func(ce *consumerEndpoint) {
+		ce.consumer.(*obcBatch).batchSize = 1
+	})
+	defer net.stop()
+
+	start := time.Now().Unix()
+	err := net.endpoints[1].(*consumerEndpoint).consumer.RecvMsg(createOcMsgWithChainTx(1), net.endpoints[1].getHandle())
+	if err != nil {
+		t.Fatalf("External request was not processed by backup: %v", err)
+	}
+	net.process()
+
+	// The block timestamp is the one of the request, the same on all the replicas
+	var expected *google_protobuf.Timestamp
+	for _, ep := range net.endpoints {
+		ce := ep.(*consumerEndpoint)
+		block, err := ce.consumer.(*obcBatch).stack.GetBlock(1)
+		if err != nil {
+			t.Fatalf("Replica %d could not retrieve the new block: %s", ce.id, err)
+		}
+		timestamp := ce.consumer.(*obcBatch).GetBlockTimestamp(block.ConsensusMetadata)
+		if timestamp == nil || timestamp.Seconds < start || timestamp.Seconds > time.Now().Unix() {
+			t.Fatalf("Replica %d expected a block timestamp since %d, got %v", ce.id, start, timestamp)
+		}
+		if expected == nil {
+			expected = timestamp
+		} else if !proto.Equal(timestamp, expected) {
+			t.Errorf("Replica %d expected block timestamp %v, got %v", ce.id, expected, timestamp)
+		}
+	}
+}
+
 func TestClearOustandingReqsOnStateRecovery(t *testing.T) {
 	b := newObcBatch(0, loadConfig(), &omniProto{})
 	defer b.Close()
@@ -426,7 +426,7 @@ func (op *obcSieve) processExecute() {
 
 	logger.Debugf("Sieve replica %d results=%x err=%v using lastPbftExec of %d", op.id, results, err, op.lastExecPbftSeqNo)
 
-	meta, _ := proto.Marshal(&Metadata{op.lastExecPbftSeqNo})
+	meta, _ := proto.Marshal(&Metadata{SeqNo: op.lastExecPbftSeqNo, Timestamp: op.currentReqFull.Timestamp})
 	op.currentResult, err = op.stack.PreviewCommitTxBatch(op.currentReq, meta)
 	if err != nil {
 		logger.Errorf("could not preview next block: %s", err)
@@ -828,11 +828,21 @@ func (op *obcSieve) rollback() {
 }
 
 func (op *obcSieve) commit() {
-	meta, _ := proto.Marshal(&Metadata{op.lastExecPbftSeqNo})
+	meta, _ := proto.Marshal(&Metadata{SeqNo: op.lastExecPbftSeqNo, Timestamp: op.currentReqFull.Timestamp})
 	op.stack.CommitTxBatch(op.currentReq, meta)
 	op.currentReq = ""
 }
 
+// GetBlockTimestamp returns the timestamp the submitting replica set on the
+// request executed in the block committed with metadata
+func (op *obcSieve) GetBlockTimestamp(metadata []byte) *google_protobuf.Timestamp {
+	meta := &Metadata{}
+	if err := proto.Unmarshal(metadata, meta); err != nil {
+		return nil
+	}
+	return meta.Timestamp
+}
+
 func (op *obcSieve) restoreBlockNumber() {
 	var err error
 	op.blockNumber = op.stack.GetBlockchainSize() - 1 // The highest block number is one less than the size
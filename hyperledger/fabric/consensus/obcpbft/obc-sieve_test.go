@@ -22,6 +22,8 @@ import (
 	"testing"
 	"time"
 
+	google_protobuf "google/protobuf"
+
 	"github.com/hyperledger/fabric/consensus"
 
 	"github.com/golang/protobuf/proto"
@@ -90,6 +92,35 @@ func TestSieveNetwork(t *testing.T) {
 	}
 }
 
+func TestSieveBlockTimestamp(t *testing.T) {
+	validatorCount := 4
+	net := makeConsumerNetwork(validatorCount, obcSieveHelper)
+	defer net.stop()
+
+	start := time.Now().Unix()
+	net.endpoints[1].(*consumerEndpoint).consumer.RecvMsg(createOcMsgWithChainTx(1), net.endpoints[generateBroadcaster(validatorCount)].getHandle())
+	net.process()
+
+	// The block timestamp is the one of the request, the same on all the replicas
+	var expected *google_protobuf.Timestamp
+	for _, ep := range net.endpoints {
+		ce := ep.(*consumerEndpoint)
+		block, err := ce.consumer.(*obcSieve).stack.GetBlock(1)
+		if err != nil {
+			t.Fatalf("Replica %d could not retrieve the new block: %s", ce.id, err)
+		}
+		timestamp := ce.consumer.(*obcSieve).GetBlockTimestamp(block.ConsensusMetadata)
+		if timestamp == nil || timestamp.Seconds < start || timestamp.Seconds > time.Now().Unix() {
+			t.Fatalf("Replica %d expected a block timestamp since %d, got %v", ce.id, start, timestamp)
+		}
+		if expected == nil {
+			expected = timestamp
+		} else if !proto.Equal(timestamp, expected) {
+			t.Errorf("Replica %d expected block timestamp %v, got %v", ce.id, expected, timestamp)
+		}
+	}
+}
+
 // TestSieveNoDecision disables PFBT messages from replica 0 to
 // simulate the sieve leader being byzantine.  Execute and verify
 // replies will make it to replica 0, but the replicas will time out
@@ -136,6 +136,7 @@ type pbftCore struct {
 	L             uint64            // log size
 	lastExec      uint64            // last request we executed
 	replicaCount  int               // number of replicas; PBFT `|R|`
+	replicas      []uint64          // replica set once reconfigured, the N first replicas until then
 	seqNo         uint64            // PBFT "n", strictly monotonic increasing sequence number
 	view          uint64            // current view
 	chkpts        map[uint64]string // state checkpoints; map lastExec to global hash
@@ -165,6 +166,11 @@ type pbftCore struct {
 
 	missingReqs map[string]bool // for all the assigned, non-checkpointed requests we might be missing during view-change
 
+	memberships     []*Membership // history of the replica sets decided
+	pendingReplicas []uint64      // replica set decided, taking over after reconfigSeqNo
+	reconfigSeqNo   uint64        // checkpoint after which the pending replica set takes over
+	deferredNewView *NewView      // new-view received during state transfer, whose primary may belong to a replica set being transferred
+
 	// implementation of PBFT `in`
 	reqStore        map[string]*Request   // track requests
 	certStore       map[msgID]*msgCert    // track quorum certificates for requests
@@ -303,6 +309,7 @@ func newPbftCore(id uint64, config *viper.Viper, consumer innerStack, etf events
 	instance.outstandingReqs = make(map[string]*Request)
 	instance.missingReqs = make(map[string]bool)
 
+	instance.reconfigSeqNo = ^uint64(0) // infinity
 	instance.restoreState()
 
 	instance.viewChangeSeqNo = ^uint64(0) // infinity
@@ -380,9 +387,15 @@ func (instance *pbftCore) ProcessEvent(e events.Event) events.Event {
 		// XXX create checkpoint
 		instance.lastExec = update.seqNo
 		instance.moveWatermarks(instance.lastExec) // The watermark movement handles moving this to a checkpoint boundary
+		instance.applyPendingMembership()
 		instance.skipInProgress = false
 		instance.consumer.validateState()
 		instance.executeOutstanding()
+		if nv := instance.deferredNewView; nv != nil {
+			// Returned as the next event, so the consumer adopts the transferred replica set first
+			instance.deferredNewView = nil
+			return nv
+		}
 	case execDoneEvent:
 		instance.execDoneSync()
 		if instance.skipInProgress {
@@ -428,6 +441,9 @@ func (instance *pbftCore) ProcessEvent(e events.Event) events.Event {
 
 // Given a certain view n, what is the expected primary?
 func (instance *pbftCore) primary(n uint64) uint64 {
+	if instance.replicas != nil {
+		return instance.replicas[n%uint64(len(instance.replicas))]
+	}
 	return n % uint64(instance.replicaCount)
 }
 
@@ -567,6 +583,11 @@ func (instance *pbftCore) nullRequestHandler() {
 
 func (instance *pbftCore) recvMsg(msg *Message, senderID uint64) (interface{}, error) {
 
+	// Replicas out of the replica set cannot tell the members, they follow the network to bootstrap when joining
+	if instance.isReplica(instance.id) && !instance.isReplica(senderID) {
+		return nil, fmt.Errorf("Replica %d ignoring message from replica %d which is not part of the replica set", instance.id, senderID)
+	}
+
 	if req := msg.GetRequest(); req != nil {
 		if senderID != req.ReplicaId {
 			return nil, fmt.Errorf("Sender ID included in request message (%v) doesn't match ID corresponding to the receiving stream (%v)", req.ReplicaId, senderID)
@@ -664,6 +685,11 @@ func (instance *pbftCore) sendPrePrepare(req *Request, digest string) {
 		return
 	}
 
+	if n > instance.reconfigSeqNo {
+		logger.Infof("Primary %d about to switch to the replica set %v, not sending pre-prepare with seqno=%d", instance.id, instance.pendingReplicas, n)
+		return
+	}
+
 	logger.Debugf("Primary %d broadcasting pre-prepare for view=%d/seqNo=%d and digest %s",
 		instance.id, instance.view, n, digest)
 	instance.seqNo = n
@@ -749,6 +775,11 @@ func (instance *pbftCore) recvPrePrepare(preprep *PrePrepare) error {
 		return nil
 	}
 
+	if preprep.SequenceNumber > instance.reconfigSeqNo {
+		logger.Debugf("Replica %d ignoring pre-prepare for %d, which follows the switch to the replica set %v", instance.id, preprep.SequenceNumber, instance.pendingReplicas)
+		return nil
+	}
+
 	cert := instance.getCert(preprep.View, preprep.SequenceNumber)
 	if cert.digest != "" && cert.digest != preprep.RequestDigest {
 		logger.Warningf("Pre-prepare found for same view/seqNo but different digest: received %s, stored %s", preprep.RequestDigest, cert.digest)
@@ -1028,7 +1059,12 @@ func (instance *pbftCore) execDoneSync() {
 		logger.Infof("Replica %d finished execution %d, trying next", instance.id, *instance.currentExec)
 		instance.lastExec = *instance.currentExec
 		if instance.lastExec%instance.K == 0 {
+			// A pending replica set takes over at its checkpoint, which is sent to the new replicas
+			reconfigured := instance.applyPendingMembership()
 			instance.Checkpoint(instance.lastExec, instance.consumer.getState())
+			if reconfigured {
+				instance.sendViewChange()
+			}
 		}
 
 	} else {
@@ -1038,6 +1074,10 @@ func (instance *pbftCore) execDoneSync() {
 	}
 	instance.currentExec = nil
 
+	if instance.pendingReplicas != nil {
+		instance.advanceToReconfiguration()
+	}
+
 	instance.executeOutstanding()
 }
 
@@ -1205,6 +1245,11 @@ func (instance *pbftCore) recvCheckpoint(chkpt *Checkpoint) events.Event {
 		instance.id, matching, chkpt.SequenceNumber, chkpt.Id)
 
 	if matching == instance.f+1 {
+		if !instance.isReplica(instance.id) && chkpt.SequenceNumber > instance.lastExec && !instance.skipInProgress {
+			logger.Infof("Replica %d is not part of the replica set, catching up to checkpoint %d", instance.id, chkpt.SequenceNumber)
+			instance.skipInProgress = true
+			instance.consumer.invalidateState()
+		}
 		// We do have a weak cert
 		instance.witnessCheckpointWeakCert(chkpt)
 	}
@@ -1309,6 +1354,11 @@ func (instance *pbftCore) innerBroadcast(msg *Message) error {
 		return fmt.Errorf("[innerBroadcast] Cannot marshal message: %s", err)
 	}
 
+	if !instance.isReplica(instance.id) {
+		logger.Debugf("Replica %d is not part of the replica set, not broadcasting", instance.id)
+		return nil
+	}
+
 	doByzantine := false
 	if instance.byzantine {
 		rand1 := rand.New(rand.NewSource(time.Now().UnixNano()))
@@ -1321,12 +1371,13 @@ func (instance *pbftCore) innerBroadcast(msg *Message) error {
 	// testing byzantine fault.
 	if doByzantine {
 		rand2 := rand.New(rand.NewSource(time.Now().UnixNano()))
-		ignoreidx := rand2.Intn(instance.N)
-		for i := 0; i < instance.N; i++ {
-			if i != ignoreidx && uint64(i) != instance.id { //Pick a random replica and do not send message
-				instance.consumer.unicast(msgRaw, uint64(i))
+		replicas := instance.getReplicas()
+		ignoreidx := rand2.Intn(len(replicas))
+		for i, id := range replicas {
+			if i != ignoreidx && id != instance.id { //Pick a random replica and do not send message
+				instance.consumer.unicast(msgRaw, id)
 			} else {
-				logger.Debugf("PBFT byzantine: not broadcasting to replica %v", i)
+				logger.Debugf("PBFT byzantine: not broadcasting to replica %v", id)
 			}
 		}
 	} else {
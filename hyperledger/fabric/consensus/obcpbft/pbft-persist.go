@@ -161,6 +161,8 @@ func (instance *pbftCore) restoreState() {
 	}
 
 	instance.restoreLastSeqNo()
+	instance.restoreMemberships()
+	viewGauge.Set(float64(instance.view))
 
 	logger.Infof("Replica %d restored state: view: %d, seqNo: %d, pset: %d, qset: %d, reqs: %d, chkpts: %d",
 		instance.id, instance.view, instance.seqNo, len(instance.pset), len(instance.qset), len(instance.reqStore), len(instance.chkpts))
@@ -128,6 +128,8 @@ func (instance *pbftCore) sendViewChange() events.Event {
 	delete(instance.newViewStore, instance.view)
 	instance.view++
 	instance.activeView = false
+	viewChanges.Inc()
+	viewGauge.Set(float64(instance.view))
 
 	instance.pset = instance.calcPSet()
 	instance.qset = instance.calcQSet()
@@ -297,6 +299,13 @@ func (instance *pbftCore) recvNewView(nv *NewView) events.Event {
 	logger.Infof("Replica %d received new-view %d",
 		instance.id, nv.View)
 
+	if instance.skipInProgress && nv.View > 0 && nv.View >= instance.view && instance.primary(nv.View) != nv.ReplicaId {
+		logger.Infof("Replica %d deferring new-view from %d, v:%d until it caught up with the replica set",
+			instance.id, nv.ReplicaId, nv.View)
+		instance.deferredNewView = nv
+		return nil
+	}
+
 	if !(nv.View > 0 && nv.View >= instance.view && instance.primary(nv.View) == nv.ReplicaId && instance.newViewStore[nv.View] == nil) {
 		logger.Infof("Replica %d rejecting invalid new-view from %d, v:%d",
 			instance.id, nv.ReplicaId, nv.View)
@@ -0,0 +1,88 @@
+/*
+Copyright IBM Corp. 2016 All Rights Reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+		 http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package raft
+
+import (
+	"github.com/hyperledger/fabric/consensus/obcpbft/events"
+	pb "github.com/hyperledger/fabric/protos"
+)
+
+// Event types
+
+// messageEvent is sent when a message is received from the network
+type messageEvent struct {
+	msg    *pb.Message
+	sender *pb.PeerID
+}
+
+// executedEvent is sent when the execution of an entry completes
+type executedEvent struct {
+	tag interface{}
+}
+
+// committedEvent is sent when the commit of an entry completes
+type committedEvent struct {
+	tag    interface{}
+	target *pb.BlockchainInfo
+}
+
+// stateUpdatedEvent is sent when state transfer completes
+type stateUpdatedEvent struct {
+	tag    interface{}
+	target *pb.BlockchainInfo
+}
+
+// electionTimerEvent is sent when a follower has not heard from the leader in time
+type electionTimerEvent struct{}
+
+// heartbeatTimerEvent is sent when the leader must send its heartbeats
+type heartbeatTimerEvent struct{}
+
+// batchTimerEvent is sent when the batch timer expires
+type batchTimerEvent struct{}
+
+type externalEventReceiver struct {
+	manager events.Manager
+}
+
+// RecvMsg is called by the stack when a new message is received
+func (eer *externalEventReceiver) RecvMsg(ocMsg *pb.Message, senderHandle *pb.PeerID) error {
+	eer.manager.Queue() <- messageEvent{
+		msg:    ocMsg,
+		sender: senderHandle,
+	}
+	return nil
+}
+
+// Executed is called whenever Execute completes
+func (eer *externalEventReceiver) Executed(tag interface{}) {
+	eer.manager.Queue() <- executedEvent{tag}
+}
+
+// Committed is called whenever Commit completes
+func (eer *externalEventReceiver) Committed(tag interface{}, target *pb.BlockchainInfo) {
+	eer.manager.Queue() <- committedEvent{tag, target}
+}
+
+// RolledBack is called whenever a Rollback completes, raft never rolls back
+func (eer *externalEventReceiver) RolledBack(tag interface{}) {
+}
+
+// StateUpdated is a signal from the stack that it has fast-forwarded its state
+func (eer *externalEventReceiver) StateUpdated(tag interface{}, target *pb.BlockchainInfo) {
+	eer.manager.Queue() <- stateUpdatedEvent{tag, target}
+}
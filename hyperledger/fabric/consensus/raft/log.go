@@ -0,0 +1,190 @@
+/*
+Copyright IBM Corp. 2016 All Rights Reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+		 http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package raft
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/golang/protobuf/proto"
+
+	"github.com/hyperledger/fabric/consensus"
+)
+
+const (
+	termKey     = "raft.term"
+	voteKey     = "raft.vote"
+	snapshotKey = "raft.snapshot"
+	entryPrefix = "raft.entry."
+)
+
+func entryKey(index uint64) string {
+	return fmt.Sprintf("%s%d", entryPrefix, index)
+}
+
+// raftLog holds the entries following the snapshot, that is the last entry
+// applied to the ledger and compacted away. Every change is persisted so that
+// the log survives a crash.
+type raftLog struct {
+	persistor consensus.StatePersistor
+	entries   []*Entry
+	snapIndex uint64
+	snapTerm  uint64
+}
+
+type entrySorter []*Entry
+
+func (a entrySorter) Len() int           { return len(a) }
+func (a entrySorter) Swap(i, j int)      { a[i], a[j] = a[j], a[i] }
+func (a entrySorter) Less(i, j int) bool { return a[i].Index < a[j].Index }
+
+func newRaftLog(persistor consensus.StatePersistor) *raftLog {
+	l := &raftLog{persistor: persistor}
+	l.restore()
+	return l
+}
+
+// restore reads the snapshot and the entries persisted before a crash
+func (l *raftLog) restore() {
+	if raw, err := l.persistor.ReadState(snapshotKey); err == nil && raw != nil {
+		snapshot := &Metadata{}
+		if err = proto.Unmarshal(raw, snapshot); err != nil {
+			logger.Errorf("Could not unmarshal the raft snapshot - local state is damaged: %s", err)
+		} else {
+			l.snapIndex, l.snapTerm = snapshot.Index, snapshot.Term
+		}
+	}
+
+	stored, err := l.persistor.ReadStateSet(entryPrefix)
+	if err != nil {
+		logger.Debugf("Could not restore raft log: %s", err)
+		return
+	}
+	var entries []*Entry
+	for key, raw := range stored {
+		entry := &Entry{}
+		if err = proto.Unmarshal(raw, entry); err != nil {
+			logger.Errorf("Could not unmarshal raft log entry %s - local state is damaged: %s", key, err)
+			continue
+		}
+		entries = append(entries, entry)
+	}
+	sort.Sort(entrySorter(entries))
+	for _, entry := range entries {
+		if entry.Index <= l.snapIndex {
+			l.persistor.DelState(entryKey(entry.Index))
+			continue
+		}
+		if entry.Index != l.lastIndex()+1 {
+			logger.Errorf("Raft log is missing entry %d - local state is damaged", l.lastIndex()+1)
+			break
+		}
+		l.entries = append(l.entries, entry)
+	}
+}
+
+func (l *raftLog) lastIndex() uint64 {
+	return l.snapIndex + uint64(len(l.entries))
+}
+
+func (l *raftLog) lastTerm() uint64 {
+	term, _ := l.term(l.lastIndex())
+	return term
+}
+
+// term returns the term of the entry at index, false if the entry was
+// compacted away or is not in the log yet
+func (l *raftLog) term(index uint64) (uint64, bool) {
+	if index == l.snapIndex {
+		return l.snapTerm, true
+	}
+	if index < l.snapIndex || index > l.lastIndex() {
+		return 0, false
+	}
+	return l.entries[index-l.snapIndex-1].Term, true
+}
+
+// entry returns the entry at index, nil if it is not in the log
+func (l *raftLog) entry(index uint64) *Entry {
+	if index <= l.snapIndex || index > l.lastIndex() {
+		return nil
+	}
+	return l.entries[index-l.snapIndex-1]
+}
+
+// from returns the entries from index on
+func (l *raftLog) from(index uint64) []*Entry {
+	if index <= l.snapIndex || index > l.lastIndex() {
+		return nil
+	}
+	return l.entries[index-l.snapIndex-1:]
+}
+
+func (l *raftLog) append(entries ...*Entry) {
+	for _, entry := range entries {
+		raw, err := proto.Marshal(entry)
+		if err != nil {
+			logger.Errorf("Could not marshal raft log entry %d: %s", entry.Index, err)
+			continue
+		}
+		if err = l.persistor.StoreState(entryKey(entry.Index), raw); err != nil {
+			logger.Errorf("Could not persist raft log entry %d: %s", entry.Index, err)
+		}
+		l.entries = append(l.entries, entry)
+	}
+}
+
+// truncate removes the entries from index on, which conflict with the log of
+// the leader
+func (l *raftLog) truncate(index uint64) {
+	for i := index; i <= l.lastIndex(); i++ {
+		l.persistor.DelState(entryKey(i))
+	}
+	l.entries = l.entries[:index-l.snapIndex-1]
+}
+
+// compact removes the entries up to index, which is applied to the ledger
+func (l *raftLog) compact(index uint64) {
+	term, ok := l.term(index)
+	if !ok || index == l.snapIndex {
+		return
+	}
+	l.storeSnapshot(index, term)
+	for i := l.snapIndex + 1; i <= index; i++ {
+		l.persistor.DelState(entryKey(i))
+	}
+	l.entries = append([]*Entry(nil), l.entries[index-l.snapIndex:]...)
+	l.snapIndex, l.snapTerm = index, term
+}
+
+// reset removes all the entries, the ledger being brought to the entry at
+// index by state transfer
+func (l *raftLog) reset(index, term uint64) {
+	l.storeSnapshot(index, term)
+	for i := l.snapIndex + 1; i <= l.lastIndex(); i++ {
+		l.persistor.DelState(entryKey(i))
+	}
+	l.entries = nil
+	l.snapIndex, l.snapTerm = index, term
+}
+
+func (l *raftLog) storeSnapshot(index, term uint64) {
+	raw, _ := proto.Marshal(&Metadata{Index: index, Term: term})
+	if err := l.persistor.StoreState(snapshotKey, raw); err != nil {
+		logger.Errorf("Could not persist raft snapshot: %s", err)
+	}
+}
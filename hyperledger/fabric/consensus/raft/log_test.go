@@ -0,0 +1,71 @@
+/*
+Copyright IBM Corp. 2016 All Rights Reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+		 http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package raft
+
+import (
+	"testing"
+)
+
+func checkLog(t *testing.T, l *raftLog, snapIndex, lastIndex, lastTerm uint64) {
+	if l.snapIndex != snapIndex || l.lastIndex() != lastIndex || l.lastTerm() != lastTerm {
+		t.Fatalf("Expected log from %d to %d in term %d, got log from %d to %d in term %d",
+			snapIndex, lastIndex, lastTerm, l.snapIndex, l.lastIndex(), l.lastTerm())
+	}
+}
+
+func TestRaftLog(t *testing.T) {
+	persist := &mockPersist{}
+	l := newRaftLog(persist)
+	checkLog(t, l, 0, 0, 0)
+
+	l.append(&Entry{Term: 1, Index: 1}, &Entry{Term: 1, Index: 2}, &Entry{Term: 2, Index: 3}, &Entry{Term: 2, Index: 4})
+	checkLog(t, l, 0, 4, 2)
+	if term, ok := l.term(2); !ok || term != 1 {
+		t.Fatalf("Expected entry 2 in term 1, got %d", term)
+	}
+	if _, ok := l.term(5); ok {
+		t.Fatalf("Expected no entry 5")
+	}
+	if entries := l.from(3); len(entries) != 2 || entries[0].Index != 3 {
+		t.Fatalf("Expected entries 3 and 4, got %v", entries)
+	}
+
+	l.truncate(4)
+	checkLog(t, l, 0, 3, 2)
+	l.compact(2)
+	checkLog(t, l, 2, 3, 2)
+	if term, ok := l.term(2); !ok || term != 1 || l.entry(2) != nil {
+		t.Fatalf("Expected entry 2 to be compacted away in term 1, got %d", term)
+	}
+	if _, ok := l.term(1); ok {
+		t.Fatalf("Expected entry 1 to be compacted away")
+	}
+
+	// the persisted log is restored
+	restored := newRaftLog(persist)
+	checkLog(t, restored, 2, 3, 2)
+	if entry := restored.entry(3); entry == nil || entry.Term != 2 {
+		t.Fatalf("Expected entry 3 to be restored, got %v", entry)
+	}
+
+	restored.reset(10, 3)
+	checkLog(t, restored, 10, 10, 3)
+	checkLog(t, newRaftLog(persist), 10, 10, 3)
+	if len(persist.store) != 1 {
+		t.Fatalf("Expected only the snapshot to be persisted, got %v", persist.store)
+	}
+}
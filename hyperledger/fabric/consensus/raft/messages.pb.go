@@ -0,0 +1,123 @@
+// Code generated by protoc-gen-go.
+// source: raft/messages.proto
+// DO NOT EDIT!
+
+/*
+Package raft is a generated protocol buffer package.
+
+It is generated from these files:
+	raft/messages.proto
+
+It has these top-level messages:
+	Message
+	Entry
+	Metadata
+*/
+package raft
+
+import proto "github.com/golang/protobuf/proto"
+import fmt "fmt"
+import math "math"
+import google_protobuf "google/protobuf"
+
+// Reference imports to suppress errors if they are not otherwise used.
+var _ = proto.Marshal
+var _ = fmt.Errorf
+var _ = math.Inf
+
+type Message_Type int32
+
+const (
+	Message_UNDEFINED               Message_Type = 0
+	Message_REQUEST                 Message_Type = 1
+	Message_REQUEST_VOTE            Message_Type = 2
+	Message_VOTE                    Message_Type = 3
+	Message_APPEND_ENTRIES          Message_Type = 4
+	Message_APPEND_ENTRIES_RESPONSE Message_Type = 5
+	Message_SNAPSHOT                Message_Type = 6
+)
+
+var Message_Type_name = map[int32]string{
+	0: "UNDEFINED",
+	1: "REQUEST",
+	2: "REQUEST_VOTE",
+	3: "VOTE",
+	4: "APPEND_ENTRIES",
+	5: "APPEND_ENTRIES_RESPONSE",
+	6: "SNAPSHOT",
+}
+var Message_Type_value = map[string]int32{
+	"UNDEFINED":               0,
+	"REQUEST":                 1,
+	"REQUEST_VOTE":            2,
+	"VOTE":                    3,
+	"APPEND_ENTRIES":          4,
+	"APPEND_ENTRIES_RESPONSE": 5,
+	"SNAPSHOT":                6,
+}
+
+func (x Message_Type) String() string {
+	return proto.EnumName(Message_Type_name, int32(x))
+}
+
+type Message struct {
+	Type        Message_Type `protobuf:"varint,1,opt,name=type,enum=raft.Message_Type" json:"type,omitempty"`
+	Term        uint64       `protobuf:"varint,2,opt,name=term" json:"term,omitempty"`
+	Index       uint64       `protobuf:"varint,3,opt,name=index" json:"index,omitempty"`
+	LogTerm     uint64       `protobuf:"varint,4,opt,name=log_term" json:"log_term,omitempty"`
+	Entries     []*Entry     `protobuf:"bytes,5,rep,name=entries" json:"entries,omitempty"`
+	Commit      uint64       `protobuf:"varint,6,opt,name=commit" json:"commit,omitempty"`
+	Success     bool         `protobuf:"varint,7,opt,name=success" json:"success,omitempty"`
+	Transaction []byte       `protobuf:"bytes,8,opt,name=transaction,proto3" json:"transaction,omitempty"`
+	State       []byte       `protobuf:"bytes,9,opt,name=state,proto3" json:"state,omitempty"`
+}
+
+func (m *Message) Reset()         { *m = Message{} }
+func (m *Message) String() string { return proto.CompactTextString(m) }
+func (*Message) ProtoMessage()    {}
+
+func (m *Message) GetEntries() []*Entry {
+	if m != nil {
+		return m.Entries
+	}
+	return nil
+}
+
+type Entry struct {
+	Term         uint64                     `protobuf:"varint,1,opt,name=term" json:"term,omitempty"`
+	Index        uint64                     `protobuf:"varint,2,opt,name=index" json:"index,omitempty"`
+	Transactions [][]byte                   `protobuf:"bytes,3,rep,name=transactions,proto3" json:"transactions,omitempty"`
+	Timestamp    *google_protobuf.Timestamp `protobuf:"bytes,4,opt,name=timestamp" json:"timestamp,omitempty"`
+}
+
+func (m *Entry) Reset()         { *m = Entry{} }
+func (m *Entry) String() string { return proto.CompactTextString(m) }
+func (*Entry) ProtoMessage()    {}
+
+func (m *Entry) GetTimestamp() *google_protobuf.Timestamp {
+	if m != nil {
+		return m.Timestamp
+	}
+	return nil
+}
+
+type Metadata struct {
+	Index     uint64                     `protobuf:"varint,1,opt,name=index" json:"index,omitempty"`
+	Term      uint64                     `protobuf:"varint,2,opt,name=term" json:"term,omitempty"`
+	Timestamp *google_protobuf.Timestamp `protobuf:"bytes,3,opt,name=timestamp" json:"timestamp,omitempty"`
+}
+
+func (m *Metadata) Reset()         { *m = Metadata{} }
+func (m *Metadata) String() string { return proto.CompactTextString(m) }
+func (*Metadata) ProtoMessage()    {}
+
+func (m *Metadata) GetTimestamp() *google_protobuf.Timestamp {
+	if m != nil {
+		return m.Timestamp
+	}
+	return nil
+}
+
+func init() {
+	proto.RegisterEnum("raft.Message_Type", Message_Type_name, Message_Type_value)
+}
@@ -0,0 +1,195 @@
+/*
+Copyright IBM Corp. 2016 All Rights Reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+		 http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package raft
+
+import (
+	"fmt"
+	"sync/atomic"
+	"time"
+
+	"github.com/spf13/viper"
+
+	"github.com/hyperledger/fabric/consensus"
+	pb "github.com/hyperledger/fabric/protos"
+)
+
+type noopSecurity struct{}
+
+func (ns *noopSecurity) Sign(msg []byte) ([]byte, error) {
+	return nil, nil
+}
+
+func (ns *noopSecurity) Verify(peerID *pb.PeerID, signature []byte, message []byte) error {
+	return nil
+}
+
+type mockPersist struct {
+	store map[string][]byte
+}
+
+func (p *mockPersist) initialize() {
+	if p.store == nil {
+		p.store = make(map[string][]byte)
+	}
+}
+
+func (p *mockPersist) ReadState(key string) ([]byte, error) {
+	p.initialize()
+	if val, ok := p.store[key]; ok {
+		return val, nil
+	}
+	return nil, fmt.Errorf("cannot find key %s", key)
+}
+
+func (p *mockPersist) ReadStateSet(prefix string) (map[string][]byte, error) {
+	if p.store == nil {
+		return nil, fmt.Errorf("no state yet")
+	}
+	ret := make(map[string][]byte)
+	for k, v := range p.store {
+		if len(k) >= len(prefix) && k[0:len(prefix)] == prefix {
+			ret[k] = v
+		}
+	}
+	return ret, nil
+}
+
+func (p *mockPersist) StoreState(key string, value []byte) error {
+	p.initialize()
+	p.store[key] = value
+	return nil
+}
+
+func (p *mockPersist) DelState(key string) {
+	p.initialize()
+	delete(p.store, key)
+}
+
+type consumerEndpoint struct {
+	*testEndpoint
+	consumer *raft
+}
+
+func (ce *consumerEndpoint) stop() {
+	ce.consumer.Close()
+}
+
+func (ce *consumerEndpoint) deliver(msg []byte, senderHandle *pb.PeerID) {
+	ce.consumer.RecvMsg(&pb.Message{Type: pb.Message_CONSENSUS, Payload: msg}, senderHandle)
+}
+
+type completeStack struct {
+	*consumerEndpoint
+	*noopSecurity
+	*MockLedger
+	*mockPersist
+	skipTarget     chan struct{}
+	stateTransfers int32
+}
+
+func (cs *completeStack) ValidateState()   {}
+func (cs *completeStack) InvalidateState() {}
+func (cs *completeStack) Start()           {}
+func (cs *completeStack) Halt()            {}
+
+func (cs *completeStack) UpdateState(tag interface{}, target *pb.BlockchainInfo, peers []*pb.PeerID) {
+	select {
+	// This guarantees the first UpdateState call is the one that's queued
+	case cs.skipTarget <- struct{}{}:
+		go func() {
+			// State transfer takes time, not simulating this hides bugs
+			time.Sleep(100 * time.Millisecond)
+			cs.simulateStateTransfer(target, peers)
+			atomic.AddInt32(&cs.stateTransfers, 1)
+			cs.consumer.StateUpdated(tag, cs.GetBlockchainInfo())
+			<-cs.skipTarget
+		}()
+	default:
+		logger.Debugf("Replica %d ignoring UpdateState because one is already in progress", cs.id)
+	}
+}
+
+type consumerNetwork struct {
+	*testnet
+	mockLedgers  []*MockLedger
+	mockPersists []*mockPersist
+	stacks       []*completeStack
+}
+
+func (cnet *consumerNetwork) GetLedgerByPeerID(peerID *pb.PeerID) (consensus.ReadOnlyLedger, bool) {
+	id, err := getValidatorID(peerID)
+	if nil != err {
+		return nil, false
+	}
+	return cnet.mockLedgers[id], true
+}
+
+func (cnet *consumerNetwork) consumer(id int) *raft {
+	return cnet.endpoints[id].(*consumerEndpoint).consumer
+}
+
+// loadTestConfig loads the plugin config with timeouts short enough for the
+// tests to run quickly
+func loadTestConfig(N int) *viper.Viper {
+	config := loadConfig()
+	config.Set("general.N", N)
+	config.Set("general.timeout.batch", "100ms")
+	config.Set("general.timeout.election", "300ms")
+	config.Set("general.timeout.heartbeat", "50ms")
+	return config
+}
+
+func makeConsumerNetwork(N int, initFNs ...func(*viper.Viper)) *consumerNetwork {
+	cnet := &consumerNetwork{
+		mockLedgers:  make([]*MockLedger, N),
+		mockPersists: make([]*mockPersist, N),
+		stacks:       make([]*completeStack, N),
+	}
+
+	endpointFunc := func(id uint64, net *testnet) endpoint {
+		tep := makeTestEndpoint(id, net)
+		ce := &consumerEndpoint{
+			testEndpoint: tep,
+		}
+
+		ml := NewMockLedger(cnet)
+		ml.ce = ce
+		cnet.mockLedgers[id] = ml
+		mp := &mockPersist{}
+		cnet.mockPersists[id] = mp
+
+		cs := &completeStack{
+			consumerEndpoint: ce,
+			noopSecurity:     &noopSecurity{},
+			MockLedger:       ml,
+			mockPersist:      mp,
+			skipTarget:       make(chan struct{}, 1),
+		}
+		cnet.stacks[id] = cs
+
+		config := loadTestConfig(N)
+		for _, fn := range initFNs {
+			fn(config)
+		}
+		ce.consumer = newRaft(id, config, cs)
+
+		return ce
+	}
+
+	cnet.testnet = makeTestnet(N, endpointFunc)
+	return cnet
+}
@@ -0,0 +1,245 @@
+/*
+Copyright IBM Corp. 2016 All Rights Reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+		 http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package raft
+
+import (
+	"fmt"
+	"reflect"
+	"sync"
+
+	"github.com/golang/protobuf/proto"
+
+	"github.com/hyperledger/fabric/consensus"
+	"github.com/hyperledger/fabric/protos"
+)
+
+type LedgerDirectory interface {
+	GetLedgerByPeerID(peerID *protos.PeerID) (consensus.ReadOnlyLedger, bool)
+}
+
+type MockLedger struct {
+	blocks        map[uint64]*protos.Block
+	blockHeight   uint64
+	remoteLedgers LedgerDirectory
+
+	mutex *sync.Mutex
+
+	txID       interface{}
+	curBatch   []*protos.Transaction
+	curResults []byte
+
+	ce *consumerEndpoint // To support the ExecTx stuff
+}
+
+func NewMockLedger(remoteLedgers LedgerDirectory) *MockLedger {
+	mock := &MockLedger{}
+	mock.mutex = &sync.Mutex{}
+	mock.blocks = make(map[uint64]*protos.Block)
+	mock.blockHeight = 1
+	mock.blocks[0] = &protos.Block{}
+	mock.remoteLedgers = remoteLedgers
+
+	return mock
+}
+
+func (mock *MockLedger) BeginTxBatch(id interface{}) error {
+	if mock.txID != nil {
+		return fmt.Errorf("Tx batch is already active")
+	}
+	mock.txID = id
+	mock.curBatch = nil
+	mock.curResults = nil
+	return nil
+}
+
+func (mock *MockLedger) Execute(tag interface{}, txs []*protos.Transaction) {
+	go func() {
+		if mock.txID == nil {
+			mock.BeginTxBatch(mock)
+		}
+
+		_, err := mock.ExecTxs(mock, txs)
+		if err != nil {
+			panic(err)
+		}
+		mock.ce.consumer.Executed(tag)
+	}()
+}
+
+func (mock *MockLedger) Commit(tag interface{}, meta []byte) {
+	go func() {
+		_, err := mock.CommitTxBatch(mock, meta)
+		if err != nil {
+			panic(err)
+		}
+		mock.ce.consumer.Committed(tag, mock.GetBlockchainInfo())
+	}()
+}
+
+func (mock *MockLedger) Rollback(tag interface{}) {
+	go func() {
+		mock.RollbackTxBatch(mock)
+		mock.ce.consumer.RolledBack(tag)
+	}()
+}
+
+func (mock *MockLedger) ExecTxs(id interface{}, txs []*protos.Transaction) ([]byte, error) {
+	if !reflect.DeepEqual(mock.txID, id) {
+		return nil, fmt.Errorf("Invalid batch ID")
+	}
+
+	mock.curBatch = append(mock.curBatch, txs...)
+	var txResult []byte
+	for _, transaction := range txs {
+		txResult = append(txResult, transaction.Payload...)
+	}
+	mock.curResults = append(mock.curResults, txResult...)
+
+	return txResult, nil
+}
+
+func (mock *MockLedger) CommitTxBatch(id interface{}, metadata []byte) (*protos.Block, error) {
+	block, err := mock.commonCommitTx(id, metadata, false)
+	if nil == err {
+		mock.txID = nil
+		mock.curBatch = nil
+		mock.curResults = nil
+	}
+	return block, err
+}
+
+func (mock *MockLedger) commonCommitTx(id interface{}, metadata []byte, preview bool) (*protos.Block, error) {
+	if !reflect.DeepEqual(mock.txID, id) {
+		return nil, fmt.Errorf("Invalid batch ID")
+	}
+
+	previousBlock, _ := mock.GetBlock(mock.GetBlockchainSize() - 1)
+	previousBlockHash, _ := mock.HashBlock(previousBlock)
+
+	block := &protos.Block{
+		ConsensusMetadata: metadata,
+		PreviousBlockHash: previousBlockHash,
+		StateHash:         mock.curResults, // Use the current result output in the hash
+		Transactions:      mock.curBatch,
+	}
+
+	if !preview {
+		mock.mutex.Lock()
+		defer mock.mutex.Unlock()
+		mock.blocks[mock.blockHeight] = block
+		mock.blockHeight++
+	}
+
+	return block, nil
+}
+
+func (mock *MockLedger) PreviewCommitTxBatch(id interface{}, metadata []byte) ([]byte, error) {
+	b, err := mock.commonCommitTx(id, metadata, true)
+	if err != nil {
+		return nil, err
+	}
+	return mock.getBlockInfoBlob(mock.GetBlockchainSize()+1, b), nil
+}
+
+func (mock *MockLedger) RollbackTxBatch(id interface{}) error {
+	if !reflect.DeepEqual(mock.txID, id) {
+		return fmt.Errorf("Invalid batch ID")
+	}
+	mock.curBatch = nil
+	mock.curResults = nil
+	mock.txID = nil
+	return nil
+}
+
+func (mock *MockLedger) GetBlockchainSize() uint64 {
+	mock.mutex.Lock()
+	defer mock.mutex.Unlock()
+	return mock.blockHeight
+}
+
+func (mock *MockLedger) GetBlock(id uint64) (*protos.Block, error) {
+	mock.mutex.Lock()
+	defer mock.mutex.Unlock()
+	block, ok := mock.blocks[id]
+	if !ok {
+		return nil, fmt.Errorf("Block not found")
+	}
+	return block, nil
+}
+
+func (mock *MockLedger) HashBlock(block *protos.Block) ([]byte, error) {
+	return block.GetHash()
+}
+
+func (mock *MockLedger) GetBlockchainInfo() *protos.BlockchainInfo {
+	height := mock.GetBlockchainSize()
+	b, _ := mock.GetBlock(height - 1)
+	return mock.getBlockInfo(height, b)
+}
+
+func (mock *MockLedger) GetBlockchainInfoBlob() []byte {
+	h, _ := proto.Marshal(mock.GetBlockchainInfo())
+	return h
+}
+
+func (mock *MockLedger) getBlockInfoBlob(height uint64, block *protos.Block) []byte {
+	h, _ := proto.Marshal(mock.getBlockInfo(height, block))
+	return h
+}
+
+func (mock *MockLedger) getBlockInfo(height uint64, block *protos.Block) *protos.BlockchainInfo {
+	info := &protos.BlockchainInfo{Height: height}
+	info.CurrentBlockHash, _ = mock.HashBlock(block)
+	return info
+}
+
+func (mock *MockLedger) GetBlockHeadMetadata() ([]byte, error) {
+	b, err := mock.GetBlock(mock.GetBlockchainSize() - 1)
+	if err != nil {
+		return nil, fmt.Errorf("could not retrieve block from mock ledger")
+	}
+	return b.ConsensusMetadata, nil
+}
+
+// simulateStateTransfer copies the blocks up to the target from the first of
+// the peers reaching it
+func (mock *MockLedger) simulateStateTransfer(info *protos.BlockchainInfo, peers []*protos.PeerID) {
+	var remoteLedger consensus.ReadOnlyLedger
+	for _, peer := range peers {
+		if ledger, ok := mock.remoteLedgers.GetLedgerByPeerID(peer); ok && ledger.GetBlockchainSize() >= info.Height {
+			remoteLedger = ledger
+			break
+		}
+	}
+	if remoteLedger == nil {
+		panic(fmt.Sprintf("Asked to skip to a block (%d) none of the peers %v has", info.Height, peers))
+	}
+	height := mock.GetBlockchainSize()
+	if height >= info.Height {
+		panic(fmt.Sprintf("Asked to skip to a block (%d) which is lower than our current height of %d", info.Height, height))
+	}
+	mock.mutex.Lock()
+	defer mock.mutex.Unlock()
+	for n := height; n < info.Height; n++ {
+		block, err := remoteLedger.GetBlock(n)
+		if err != nil {
+			panic(fmt.Sprintf("Asked to skip to a block (%d) the peer does not have", n))
+		}
+		mock.blocks[n] = block
+	}
+	mock.blockHeight = info.Height
+}
@@ -0,0 +1,201 @@
+/*
+Copyright IBM Corp. 2016 All Rights Reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+		 http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package raft
+
+import (
+	"fmt"
+	"sync"
+
+	pb "github.com/hyperledger/fabric/protos"
+)
+
+type endpoint interface {
+	stop()
+	deliver([]byte, *pb.PeerID)
+	getHandle() *pb.PeerID
+	getID() uint64
+}
+
+type taggedMsg struct {
+	src int
+	dst int
+	msg []byte
+}
+
+type testnet struct {
+	N         int
+	closed    chan struct{}
+	endpoints []endpoint
+	msgs      chan taggedMsg
+
+	filterLock sync.Mutex
+	filterFn   func(int, int, []byte) []byte
+}
+
+type testEndpoint struct {
+	id  uint64
+	net *testnet
+}
+
+func makeTestEndpoint(id uint64, net *testnet) *testEndpoint {
+	ep := &testEndpoint{}
+	ep.id = id
+	ep.net = net
+	return ep
+}
+
+func (ep *testEndpoint) getID() uint64 {
+	return ep.id
+}
+
+func (ep *testEndpoint) getHandle() *pb.PeerID {
+	return &pb.PeerID{Name: fmt.Sprintf("vp%d", ep.id)}
+}
+
+func (ep *testEndpoint) GetNetworkInfo() (self *pb.PeerEndpoint, network []*pb.PeerEndpoint, err error) {
+	oSelf, oNetwork, _ := ep.GetNetworkHandles()
+	self = &pb.PeerEndpoint{
+		ID:   oSelf,
+		Type: pb.PeerEndpoint_VALIDATOR,
+	}
+
+	network = make([]*pb.PeerEndpoint, len(oNetwork))
+	for i, id := range oNetwork {
+		network[i] = &pb.PeerEndpoint{
+			ID:   id,
+			Type: pb.PeerEndpoint_VALIDATOR,
+		}
+	}
+	return
+}
+
+func (ep *testEndpoint) GetNetworkHandles() (self *pb.PeerID, network []*pb.PeerID, err error) {
+	if nil == ep.net {
+		err = fmt.Errorf("Network not initialized")
+		return
+	}
+	self = ep.getHandle()
+	network = make([]*pb.PeerID, len(ep.net.endpoints))
+	for i, oep := range ep.net.endpoints {
+		if nil != oep {
+			// In case this is invoked before all endpoints are initialized, this emulates a real network as well
+			network[i] = oep.getHandle()
+		}
+	}
+	return
+}
+
+// Broadcast delivers to all endpoints but the sender
+func (ep *testEndpoint) Broadcast(msg *pb.Message, peerType pb.PeerEndpoint_Type) error {
+	ep.net.queueMessage(taggedMsg{int(ep.id), -1, msg.Payload})
+	return nil
+}
+
+func (ep *testEndpoint) Unicast(msg *pb.Message, receiverHandle *pb.PeerID) error {
+	receiverID, err := getValidatorID(receiverHandle)
+	if err != nil {
+		return fmt.Errorf("Couldn't unicast message to %s: %v", receiverHandle.Name, err)
+	}
+	ep.net.queueMessage(taggedMsg{int(ep.id), int(receiverID), msg.Payload})
+	return nil
+}
+
+func (net *testnet) queueMessage(tm taggedMsg) {
+	select {
+	case <-net.closed:
+		return
+	default:
+	}
+	net.msgs <- tm
+}
+
+// setFilter sets the function filtering the messages from src to dst,
+// returning nil drops the message
+func (net *testnet) setFilter(filterFn func(int, int, []byte) []byte) {
+	net.filterLock.Lock()
+	defer net.filterLock.Unlock()
+	net.filterFn = filterFn
+}
+
+func (net *testnet) filter(src, dst int, payload []byte) []byte {
+	net.filterLock.Lock()
+	defer net.filterLock.Unlock()
+	if net.filterFn == nil {
+		return payload
+	}
+	return net.filterFn(src, dst, payload)
+}
+
+func (net *testnet) deliverFilter(msg taggedMsg) {
+	senderHandle := net.endpoints[msg.src].getHandle()
+	if msg.dst == -1 {
+		logger.Debugf("Test network delivering broadcast from %d", msg.src)
+		wg := &sync.WaitGroup{}
+		for id, ep := range net.endpoints {
+			if msg.src == id {
+				// do not deliver to local replica
+				continue
+			}
+			if payload := net.filter(msg.src, id, msg.msg); payload != nil {
+				wg.Add(1)
+				go func(ep endpoint) {
+					defer wg.Done()
+					ep.deliver(payload, senderHandle)
+				}(ep)
+			}
+		}
+		wg.Wait()
+	} else {
+		logger.Debugf("Test network delivering unicast from %d to %d", msg.src, msg.dst)
+		if payload := net.filter(msg.src, msg.dst, msg.msg); payload != nil {
+			net.endpoints[msg.dst].deliver(payload, senderHandle)
+		}
+	}
+}
+
+// processContinually delivers the messages until the network is stopped, the
+// replicas keep exchanging heartbeats so the network is never idle
+func (net *testnet) processContinually() {
+	for {
+		select {
+		case msg := <-net.msgs:
+			net.deliverFilter(msg)
+		case <-net.closed:
+			return
+		}
+	}
+}
+
+func makeTestnet(N int, initFn func(id uint64, network *testnet) endpoint) *testnet {
+	net := &testnet{N: N}
+	net.msgs = make(chan taggedMsg, 1000)
+	net.closed = make(chan struct{})
+	net.endpoints = make([]endpoint, N)
+
+	for i := range net.endpoints {
+		net.endpoints[i] = initFn(uint64(i), net)
+	}
+
+	return net
+}
+
+func (net *testnet) stop() {
+	close(net.closed)
+	for _, ep := range net.endpoints {
+		ep.stop()
+	}
+}
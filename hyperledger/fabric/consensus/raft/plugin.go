@@ -0,0 +1,108 @@
+/*
+Copyright IBM Corp. 2016 All Rights Reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+		 http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package raft
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/op/go-logging"
+	"github.com/spf13/viper"
+
+	"github.com/hyperledger/fabric/consensus"
+	pb "github.com/hyperledger/fabric/protos"
+)
+
+const configPrefix = "CORE_RAFT"
+
+var logger *logging.Logger             // package-level logger
+var pluginInstance consensus.Consenter // singleton service
+var config *viper.Viper
+
+func init() {
+	logger = logging.MustGetLogger("consensus/raft")
+	config = loadConfig()
+}
+
+// GetPlugin returns the handle to the Consenter singleton
+func GetPlugin(c consensus.Stack) consensus.Consenter {
+	if pluginInstance == nil {
+		pluginInstance = New(c)
+	}
+	return pluginInstance
+}
+
+// New creates a new raft instance that provides the Consenter interface
+func New(stack consensus.Stack) consensus.Consenter {
+	handle, _, _ := stack.GetNetworkHandles()
+	id, err := getValidatorID(handle)
+	if err != nil {
+		panic(err)
+	}
+	return newRaft(id, config, stack)
+}
+
+func loadConfig() (config *viper.Viper) {
+	config = viper.New()
+
+	// for environment variables
+	config.SetEnvPrefix(configPrefix)
+	config.AutomaticEnv()
+	replacer := strings.NewReplacer(".", "_")
+	config.SetEnvKeyReplacer(replacer)
+
+	config.SetConfigName("config")
+	config.AddConfigPath("./")
+	config.AddConfigPath("../consensus/raft/")
+	config.AddConfigPath("../../consensus/raft")
+	// Path to look for the config file in based on GOPATH
+	gopath := os.Getenv("GOPATH")
+	for _, p := range filepath.SplitList(gopath) {
+		raftpath := filepath.Join(p, "src/github.com/hyperledger/fabric/consensus/raft")
+		config.AddConfigPath(raftpath)
+	}
+
+	err := config.ReadInConfig()
+	if err != nil {
+		panic(fmt.Errorf("Error reading %s plugin config: %s", configPrefix, err))
+	}
+	return
+}
+
+// Returns the uint64 ID corresponding to a peer handle, validators are
+// named vpX where X is a unique integer between 0 and N-1
+func getValidatorID(handle *pb.PeerID) (id uint64, err error) {
+	if strings.HasPrefix(handle.Name, "vp") {
+		id, err = strconv.ParseUint(handle.Name[2:], 10, 64)
+		if err != nil {
+			return id, fmt.Errorf("Error extracting ID from \"%s\" handle: %v", handle.Name, err)
+		}
+		return
+	}
+	err = fmt.Errorf(`Set the VP's peer.id to vpX,
+		where X is a unique integer between 0 and N-1
+		(N being the number of VPs in the network)`)
+	return
+}
+
+// Returns the peer handle that corresponds to a validator ID
+func getValidatorHandle(id uint64) *pb.PeerID {
+	return &pb.PeerID{Name: "vp" + strconv.FormatUint(id, 10)}
+}
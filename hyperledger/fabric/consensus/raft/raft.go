@@ -0,0 +1,677 @@
+/*
+Copyright IBM Corp. 2016 All Rights Reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+		 http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package raft
+
+import (
+	"fmt"
+	"math/rand"
+	"strconv"
+	"time"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/spf13/viper"
+	google_protobuf "google/protobuf"
+
+	"github.com/hyperledger/fabric/consensus"
+	"github.com/hyperledger/fabric/consensus/obcpbft/events"
+	"github.com/hyperledger/fabric/core/util"
+	pb "github.com/hyperledger/fabric/protos"
+)
+
+type role int
+
+const (
+	follower role = iota
+	candidate
+	leader
+)
+
+// raft orders the transactions with the Raft algorithm: the leader appends
+// batches of transactions to its log and replicates it to the followers, an
+// entry stored by a majority of the replicas is committed and executed by all
+// of them as one block. Raft tolerates the crash of a minority of the
+// replicas, not byzantine faults.
+type raft struct {
+	externalEventReceiver
+	stack   consensus.Stack
+	manager events.Manager
+
+	id uint64
+	N  int
+
+	batchSize        int
+	logSize          uint64
+	batchTimeout     time.Duration
+	electionTimeout  time.Duration
+	heartbeatTimeout time.Duration
+
+	// persisted state
+	term     uint64
+	votedFor int64 // -1 if no vote was cast in this term
+	log      *raftLog
+
+	role        role
+	leader      int64 // -1 if unknown
+	votes       map[uint64]bool
+	nextIndex   []uint64 // for each replica, the index of the next entry to send, leader only
+	matchIndex  []uint64 // for each replica, the index of the last entry known to match, leader only
+	commitIndex uint64
+	lastApplied uint64
+	appliedInfo *pb.BlockchainInfo // the blockchain info as of lastApplied
+
+	batchStore     [][]byte // transactions for the next entry, leader only
+	pending        [][]byte // transactions received while the leader is unknown
+	executing      bool     // an entry is being executed and committed
+	skipInProgress bool     // the ledger is being brought up to date by state transfer
+
+	electionTimer    events.Timer
+	heartbeatTimer   events.Timer
+	batchTimer       events.Timer
+	batchTimerActive bool
+}
+
+func newRaft(id uint64, config *viper.Viper, stack consensus.Stack) *raft {
+	var err error
+
+	r := &raft{
+		stack:    stack,
+		id:       id,
+		N:        config.GetInt("general.N"),
+		votedFor: -1,
+		leader:   -1,
+	}
+
+	r.batchSize = config.GetInt("general.batchsize")
+	r.logSize = uint64(config.GetInt("general.logsize"))
+	if r.batchTimeout, err = time.ParseDuration(config.GetString("general.timeout.batch")); err != nil {
+		panic(fmt.Errorf("Cannot parse batch timeout: %s", err))
+	}
+	if r.electionTimeout, err = time.ParseDuration(config.GetString("general.timeout.election")); err != nil {
+		panic(fmt.Errorf("Cannot parse election timeout: %s", err))
+	}
+	if r.heartbeatTimeout, err = time.ParseDuration(config.GetString("general.timeout.heartbeat")); err != nil {
+		panic(fmt.Errorf("Cannot parse heartbeat timeout: %s", err))
+	}
+	if r.heartbeatTimeout >= r.electionTimeout {
+		panic(fmt.Errorf("Heartbeat timeout %v must be below the election timeout %v", r.heartbeatTimeout, r.electionTimeout))
+	}
+	logger.Infof("Raft replica %d of N = %d", r.id, r.N)
+	logger.Infof("Raft Batch size = %d, log size = %d", r.batchSize, r.logSize)
+	logger.Infof("Raft timeouts: batch = %v, election = %v, heartbeat = %v", r.batchTimeout, r.electionTimeout, r.heartbeatTimeout)
+
+	r.manager = events.NewManagerImpl()
+	r.manager.SetReceiver(r)
+	r.externalEventReceiver.manager = r.manager
+	etf := events.NewTimerFactoryImpl(r.manager)
+	r.electionTimer = etf.CreateTimer()
+	r.heartbeatTimer = etf.CreateTimer()
+	r.batchTimer = etf.CreateTimer()
+
+	r.restoreState()
+
+	r.manager.Start()
+	r.resetElectionTimer()
+
+	return r
+}
+
+// Close tells us to release resources we are holding
+func (r *raft) Close() {
+	r.electionTimer.Halt()
+	r.heartbeatTimer.Halt()
+	r.batchTimer.Halt()
+	r.manager.Halt()
+}
+
+// GetBlockTimestamp returns the timestamp the leader set on the entry
+// committed with metadata
+func (r *raft) GetBlockTimestamp(metadata []byte) *google_protobuf.Timestamp {
+	meta := &Metadata{}
+	if err := proto.Unmarshal(metadata, meta); err != nil {
+		return nil
+	}
+	return meta.Timestamp
+}
+
+// restoreState reads the term, vote and log persisted before a crash. The
+// entries up to the one recorded in the metadata of the last block are
+// applied already.
+func (r *raft) restoreState() {
+	if raw, err := r.stack.ReadState(termKey); err == nil && raw != nil {
+		if r.term, err = strconv.ParseUint(string(raw), 10, 64); err != nil {
+			logger.Errorf("Replica %d could not restore its term - local state is damaged: %s", r.id, err)
+		}
+	}
+	if raw, err := r.stack.ReadState(voteKey); err == nil && raw != nil {
+		if r.votedFor, err = strconv.ParseInt(string(raw), 10, 64); err != nil {
+			logger.Errorf("Replica %d could not restore its vote - local state is damaged: %s", r.id, err)
+			r.votedFor = -1
+		}
+	}
+	r.log = newRaftLog(r.stack)
+
+	r.lastApplied = r.log.snapIndex
+	meta := &Metadata{}
+	if raw, err := r.stack.GetBlockHeadMetadata(); err == nil && raw != nil {
+		if err = proto.Unmarshal(raw, meta); err != nil {
+			logger.Warningf("Replica %d could not unmarshal the metadata of the last block: %s", r.id, err)
+			meta = &Metadata{}
+		}
+	}
+	if meta.Index > r.lastApplied {
+		if term, ok := r.log.term(meta.Index); !ok || term != meta.Term {
+			logger.Warningf("Replica %d log does not hold the last block entry %d, discarding it", r.id, meta.Index)
+			r.log.reset(meta.Index, meta.Term)
+		}
+		r.lastApplied = meta.Index
+	}
+	r.commitIndex = r.lastApplied
+	r.appliedInfo = r.stack.GetBlockchainInfo()
+
+	logger.Infof("Replica %d restored term %d, log up to %d, applied up to %d", r.id, r.term, r.log.lastIndex(), r.lastApplied)
+}
+
+func (r *raft) persistTermAndVote() {
+	if err := r.stack.StoreState(termKey, []byte(strconv.FormatUint(r.term, 10))); err != nil {
+		logger.Errorf("Replica %d could not persist its term: %s", r.id, err)
+	}
+	if err := r.stack.StoreState(voteKey, []byte(strconv.FormatInt(r.votedFor, 10))); err != nil {
+		logger.Errorf("Replica %d could not persist its vote: %s", r.id, err)
+	}
+}
+
+// ProcessEvent is the main event loop of the replica
+func (r *raft) ProcessEvent(event events.Event) events.Event {
+	switch et := event.(type) {
+	case messageEvent:
+		r.processMessage(et.msg, et.sender)
+	case electionTimerEvent:
+		r.startElection()
+	case heartbeatTimerEvent:
+		if r.role == leader {
+			r.broadcastAppend()
+			r.heartbeatTimer.Reset(r.heartbeatTimeout, heartbeatTimerEvent{})
+		}
+	case batchTimerEvent:
+		r.batchTimerActive = false
+		if r.role == leader && len(r.batchStore) > 0 {
+			logger.Debugf("Leader %d batch timer expired", r.id)
+			r.appendBatch()
+		}
+	case executedEvent:
+		meta, _ := proto.Marshal(et.tag.(*Metadata))
+		r.stack.Commit(et.tag, meta)
+	case committedEvent:
+		meta := et.tag.(*Metadata)
+		logger.Debugf("Replica %d committed entry %d", r.id, meta.Index)
+		r.executing = false
+		r.lastApplied = meta.Index
+		r.appliedInfo = et.target
+		r.apply()
+	case stateUpdatedEvent:
+		r.stateUpdated(et.tag.(*Metadata), et.target)
+	default:
+		logger.Errorf("Replica %d received an unknown event type %T", r.id, event)
+	}
+	return nil
+}
+
+func (r *raft) processMessage(ocMsg *pb.Message, senderHandle *pb.PeerID) {
+	if ocMsg.Type == pb.Message_CHAIN_TRANSACTION {
+		r.submit(ocMsg.Payload)
+		return
+	}
+	if ocMsg.Type != pb.Message_CONSENSUS {
+		logger.Errorf("Unexpected message type: %s", ocMsg.Type)
+		return
+	}
+
+	msg := &Message{}
+	if err := proto.Unmarshal(ocMsg.Payload, msg); err != nil {
+		logger.Errorf("Error unmarshaling message: %s", err)
+		return
+	}
+	senderID, err := getValidatorID(senderHandle)
+	if err != nil {
+		logger.Errorf("Replica %d received a message from an unknown replica: %s", r.id, err)
+		return
+	}
+
+	if msg.Type == Message_REQUEST {
+		r.submit(msg.Transaction)
+		return
+	}
+
+	if msg.Term > r.term {
+		logger.Infof("Replica %d moving to term %d of replica %d", r.id, msg.Term, senderID)
+		r.becomeFollower(msg.Term, -1)
+	}
+
+	switch msg.Type {
+	case Message_REQUEST_VOTE:
+		r.recvRequestVote(msg, senderID)
+	case Message_VOTE:
+		r.recvVote(msg, senderID)
+	case Message_APPEND_ENTRIES:
+		r.recvAppendEntries(msg, senderID)
+	case Message_APPEND_ENTRIES_RESPONSE:
+		r.recvAppendEntriesResponse(msg, senderID)
+	case Message_SNAPSHOT:
+		r.recvSnapshot(msg, senderID)
+	default:
+		logger.Errorf("Replica %d received an unknown message type %s", r.id, msg.Type)
+	}
+}
+
+// submit hands a transaction to the leader
+func (r *raft) submit(tx []byte) {
+	switch {
+	case r.role == leader:
+		r.batchStore = append(r.batchStore, tx)
+		if len(r.batchStore) >= r.batchSize {
+			r.appendBatch()
+		} else if !r.batchTimerActive {
+			r.batchTimer.Reset(r.batchTimeout, batchTimerEvent{})
+			r.batchTimerActive = true
+		}
+	case r.leader >= 0:
+		r.unicast(&Message{Type: Message_REQUEST, Transaction: tx}, uint64(r.leader))
+	default:
+		logger.Debugf("Replica %d holding a transaction until a leader is known", r.id)
+		r.pending = append(r.pending, tx)
+	}
+}
+
+func (r *raft) submitPending() {
+	pending := r.pending
+	r.pending = nil
+	for _, tx := range pending {
+		r.submit(tx)
+	}
+}
+
+// =============================================================================
+// Leader election
+// =============================================================================
+
+func (r *raft) resetElectionTimer() {
+	timeout := r.electionTimeout + time.Duration(rand.Int63n(int64(r.electionTimeout)))
+	r.electionTimer.Reset(timeout, electionTimerEvent{})
+}
+
+func (r *raft) hasQuorum(count int) bool {
+	return count > r.N/2
+}
+
+// becomeFollower moves to the term if it is newer and follows the leader, -1
+// if it is unknown
+func (r *raft) becomeFollower(term uint64, leaderID int64) {
+	if term > r.term {
+		r.term = term
+		r.votedFor = -1
+		r.persistTermAndVote()
+	}
+	if r.role == leader {
+		logger.Infof("Replica %d stepping down as leader", r.id)
+		r.heartbeatTimer.Stop()
+		r.batchTimer.Stop()
+		r.batchTimerActive = false
+		r.pending = append(r.pending, r.batchStore...)
+		r.batchStore = nil
+	}
+	r.role = follower
+	r.leader = leaderID
+	r.resetElectionTimer()
+	if leaderID >= 0 {
+		r.submitPending()
+	}
+}
+
+func (r *raft) startElection() {
+	if r.role == leader {
+		return
+	}
+	r.role = candidate
+	r.term++
+	r.votedFor = int64(r.id)
+	r.leader = -1
+	r.persistTermAndVote()
+	r.votes = map[uint64]bool{r.id: true}
+	logger.Infof("Replica %d starting election for term %d", r.id, r.term)
+
+	r.resetElectionTimer()
+	if r.hasQuorum(len(r.votes)) {
+		r.becomeLeader()
+		return
+	}
+	r.broadcast(&Message{Type: Message_REQUEST_VOTE, Term: r.term, Index: r.log.lastIndex(), LogTerm: r.log.lastTerm()})
+}
+
+func (r *raft) recvRequestVote(msg *Message, senderID uint64) {
+	upToDate := msg.LogTerm > r.log.lastTerm() || msg.LogTerm == r.log.lastTerm() && msg.Index >= r.log.lastIndex()
+	grant := msg.Term == r.term && (r.votedFor == -1 || r.votedFor == int64(senderID)) && upToDate
+	if grant {
+		logger.Debugf("Replica %d voting for replica %d in term %d", r.id, senderID, r.term)
+		r.votedFor = int64(senderID)
+		r.persistTermAndVote()
+		r.resetElectionTimer()
+	}
+	r.unicast(&Message{Type: Message_VOTE, Term: r.term, Success: grant}, senderID)
+}
+
+func (r *raft) recvVote(msg *Message, senderID uint64) {
+	if r.role != candidate || msg.Term != r.term || !msg.Success {
+		return
+	}
+	r.votes[senderID] = true
+	if r.hasQuorum(len(r.votes)) {
+		r.becomeLeader()
+	}
+}
+
+// becomeLeader starts replicating the log, with a new empty entry to commit
+// the entries of the previous terms
+func (r *raft) becomeLeader() {
+	logger.Infof("Replica %d is the leader of term %d", r.id, r.term)
+	r.role = leader
+	r.leader = int64(r.id)
+	r.electionTimer.Stop()
+
+	r.nextIndex = make([]uint64, r.N)
+	r.matchIndex = make([]uint64, r.N)
+	for i := range r.nextIndex {
+		r.nextIndex[i] = r.log.lastIndex() + 1
+	}
+	r.log.append(&Entry{Term: r.term, Index: r.log.lastIndex() + 1})
+
+	r.broadcastAppend()
+	r.heartbeatTimer.Reset(r.heartbeatTimeout, heartbeatTimerEvent{})
+	r.advanceCommit()
+	r.submitPending()
+}
+
+// =============================================================================
+// Log replication
+// =============================================================================
+
+func (r *raft) appendBatch() {
+	r.batchTimer.Stop()
+	r.batchTimerActive = false
+
+	entry := &Entry{Term: r.term, Index: r.log.lastIndex() + 1, Transactions: r.batchStore, Timestamp: util.CreateUtcTimestamp()}
+	r.batchStore = nil
+	logger.Infof("Leader %d appending entry %d with %d transactions", r.id, entry.Index, len(entry.Transactions))
+	r.log.append(entry)
+
+	r.broadcastAppend()
+	r.advanceCommit()
+}
+
+func (r *raft) broadcastAppend() {
+	for i := 0; i < r.N; i++ {
+		if uint64(i) != r.id {
+			r.sendAppend(uint64(i))
+		}
+	}
+}
+
+// sendAppend sends the entries the replica misses, or a snapshot if they
+// were compacted away
+func (r *raft) sendAppend(replica uint64) {
+	next := r.nextIndex[replica]
+	prevTerm, ok := r.log.term(next - 1)
+	if !ok {
+		r.sendSnapshot(replica)
+		return
+	}
+	r.unicast(&Message{
+		Type:    Message_APPEND_ENTRIES,
+		Term:    r.term,
+		Index:   next - 1,
+		LogTerm: prevTerm,
+		Entries: r.log.from(next),
+		Commit:  r.commitIndex,
+	}, replica)
+}
+
+func (r *raft) sendSnapshot(replica uint64) {
+	term, _ := r.log.term(r.lastApplied)
+	state, err := proto.Marshal(r.appliedInfo)
+	if err != nil {
+		logger.Errorf("Leader %d could not marshal its blockchain info: %s", r.id, err)
+		return
+	}
+	logger.Debugf("Leader %d sending snapshot at %d to replica %d", r.id, r.lastApplied, replica)
+	r.unicast(&Message{Type: Message_SNAPSHOT, Term: r.term, Index: r.lastApplied, LogTerm: term, State: state}, replica)
+}
+
+func (r *raft) recvAppendEntries(msg *Message, senderID uint64) {
+	if msg.Term < r.term {
+		r.unicast(&Message{Type: Message_APPEND_ENTRIES_RESPONSE, Term: r.term, Index: r.log.lastIndex()}, senderID)
+		return
+	}
+	if r.role != follower || r.leader != int64(senderID) {
+		r.becomeFollower(msg.Term, int64(senderID))
+	} else {
+		r.resetElectionTimer()
+	}
+	if r.skipInProgress {
+		return
+	}
+
+	prevIndex, prevTerm, entries := msg.Index, msg.LogTerm, msg.Entries
+	if prevIndex < r.log.snapIndex {
+		// the entries up to the snapshot are committed, they match
+		for len(entries) > 0 && entries[0].Index <= r.log.snapIndex {
+			entries = entries[1:]
+		}
+		prevIndex, prevTerm = r.log.snapIndex, r.log.snapTerm
+	}
+	if term, ok := r.log.term(prevIndex); !ok || term != prevTerm {
+		hint := r.log.lastIndex()
+		if ok {
+			hint = prevIndex - 1
+		}
+		logger.Debugf("Replica %d log does not match entry %d of the leader", r.id, prevIndex)
+		r.unicast(&Message{Type: Message_APPEND_ENTRIES_RESPONSE, Term: r.term, Index: hint}, senderID)
+		return
+	}
+
+	last := prevIndex
+	for _, entry := range entries {
+		last = entry.Index
+		if term, ok := r.log.term(entry.Index); ok {
+			if term == entry.Term {
+				continue
+			}
+			logger.Infof("Replica %d discarding its log from entry %d", r.id, entry.Index)
+			r.log.truncate(entry.Index)
+		}
+		r.log.append(entry)
+	}
+
+	commit := msg.Commit
+	if commit > last {
+		commit = last
+	}
+	if commit > r.commitIndex {
+		r.commitIndex = commit
+	}
+	r.unicast(&Message{Type: Message_APPEND_ENTRIES_RESPONSE, Term: r.term, Index: last, Success: true}, senderID)
+	r.apply()
+}
+
+func (r *raft) recvAppendEntriesResponse(msg *Message, senderID uint64) {
+	if r.role != leader || msg.Term != r.term || senderID >= uint64(r.N) {
+		return
+	}
+	if msg.Success {
+		if msg.Index > r.matchIndex[senderID] {
+			r.matchIndex[senderID] = msg.Index
+		}
+		if msg.Index+1 > r.nextIndex[senderID] {
+			r.nextIndex[senderID] = msg.Index + 1
+		}
+		r.advanceCommit()
+		if r.nextIndex[senderID] <= r.log.lastIndex() {
+			r.sendAppend(senderID)
+		}
+		return
+	}
+
+	next := msg.Index + 1
+	if next >= r.nextIndex[senderID] {
+		next = r.nextIndex[senderID] - 1
+	}
+	if next <= r.matchIndex[senderID] {
+		next = r.matchIndex[senderID] + 1
+	}
+	r.nextIndex[senderID] = next
+	r.sendAppend(senderID)
+}
+
+// advanceCommit commits the last entry of the term stored by a majority of
+// the replicas, and thereby all the entries preceding it
+func (r *raft) advanceCommit() {
+	for index := r.log.lastIndex(); index > r.commitIndex; index-- {
+		if term, _ := r.log.term(index); term != r.term {
+			return
+		}
+		count := 1 // the leader stores all the entries
+		for i, match := range r.matchIndex {
+			if uint64(i) != r.id && match >= index {
+				count++
+			}
+		}
+		if r.hasQuorum(count) {
+			logger.Debugf("Leader %d committing up to entry %d", r.id, index)
+			r.commitIndex = index
+			r.apply()
+			return
+		}
+	}
+}
+
+// apply executes the next committed entry, one at a time, skipping the empty
+// entries as they would produce empty blocks
+func (r *raft) apply() {
+	for !r.executing && !r.skipInProgress && r.lastApplied < r.commitIndex {
+		entry := r.log.entry(r.lastApplied + 1)
+		if entry == nil {
+			logger.Debugf("Replica %d waiting for entry %d", r.id, r.lastApplied+1)
+			break
+		}
+		if len(entry.Transactions) == 0 {
+			r.lastApplied = entry.Index
+			continue
+		}
+
+		var txs []*pb.Transaction
+		for _, raw := range entry.Transactions {
+			tx := &pb.Transaction{}
+			if err := proto.Unmarshal(raw, tx); err != nil {
+				logger.Warningf("Replica %d could not unmarshal transaction: %s", r.id, err)
+				continue
+			}
+			txs = append(txs, tx)
+		}
+		logger.Debugf("Replica %d executing entry %d with %d transactions", r.id, entry.Index, len(txs))
+		r.executing = true
+		r.stack.Execute(&Metadata{Index: entry.Index, Term: entry.Term, Timestamp: entry.Timestamp}, txs) // we will receive an executedEvent once it completes
+	}
+
+	if r.lastApplied > r.log.snapIndex+r.logSize {
+		r.log.compact(r.lastApplied - r.logSize)
+	}
+}
+
+// =============================================================================
+// State transfer
+// =============================================================================
+
+func (r *raft) recvSnapshot(msg *Message, senderID uint64) {
+	if msg.Term < r.term {
+		r.unicast(&Message{Type: Message_APPEND_ENTRIES_RESPONSE, Term: r.term, Index: r.log.lastIndex()}, senderID)
+		return
+	}
+	if r.role != follower || r.leader != int64(senderID) {
+		r.becomeFollower(msg.Term, int64(senderID))
+	} else {
+		r.resetElectionTimer()
+	}
+	if r.skipInProgress || r.executing {
+		// the leader sends the snapshot again with its next heartbeat
+		return
+	}
+	if msg.Index <= r.lastApplied {
+		r.unicast(&Message{Type: Message_APPEND_ENTRIES_RESPONSE, Term: r.term, Index: r.lastApplied, Success: true}, senderID)
+		return
+	}
+
+	info := &pb.BlockchainInfo{}
+	if err := proto.Unmarshal(msg.State, info); err != nil {
+		logger.Errorf("Replica %d could not unmarshal the snapshot state: %s", r.id, err)
+		return
+	}
+	logger.Infof("Replica %d is behind, transferring state up to entry %d, block %d", r.id, msg.Index, info.Height-1)
+	r.skipInProgress = true
+	r.stack.InvalidateState()
+	r.stack.UpdateState(&Metadata{Index: msg.Index, Term: msg.LogTerm}, info, []*pb.PeerID{getValidatorHandle(senderID)})
+}
+
+// stateUpdated resumes the replication from the snapshot the ledger was
+// brought to
+func (r *raft) stateUpdated(snapshot *Metadata, target *pb.BlockchainInfo) {
+	r.skipInProgress = false
+	if target == nil {
+		logger.Warningf("Replica %d state transfer failed, waiting for a new snapshot", r.id)
+		return
+	}
+	logger.Infof("Replica %d transferred state up to entry %d", r.id, snapshot.Index)
+	r.stack.ValidateState()
+
+	r.log.reset(snapshot.Index, snapshot.Term)
+	r.lastApplied = snapshot.Index
+	if r.commitIndex < snapshot.Index {
+		r.commitIndex = snapshot.Index
+	}
+	r.appliedInfo = target
+	if r.leader >= 0 {
+		r.unicast(&Message{Type: Message_APPEND_ENTRIES_RESPONSE, Term: r.term, Index: snapshot.Index, Success: true}, uint64(r.leader))
+	}
+}
+
+// =============================================================================
+// Messaging
+// =============================================================================
+
+func (r *raft) wrapMessage(msg *Message) *pb.Message {
+	payload, _ := proto.Marshal(msg)
+	return &pb.Message{Type: pb.Message_CONSENSUS, Payload: payload}
+}
+
+func (r *raft) broadcast(msg *Message) {
+	if err := r.stack.Broadcast(r.wrapMessage(msg), pb.PeerEndpoint_VALIDATOR); err != nil {
+		logger.Warningf("Replica %d could not broadcast %s: %s", r.id, msg.Type, err)
+	}
+}
+
+func (r *raft) unicast(msg *Message, receiverID uint64) {
+	if err := r.stack.Unicast(r.wrapMessage(msg), getValidatorHandle(receiverID)); err != nil {
+		logger.Debugf("Replica %d could not send %s to replica %d: %s", r.id, msg.Type, receiverID, err)
+	}
+}
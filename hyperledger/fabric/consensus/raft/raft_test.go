@@ -0,0 +1,244 @@
+/*
+Copyright IBM Corp. 2016 All Rights Reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+		 http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package raft
+
+import (
+	"bytes"
+	"fmt"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/spf13/viper"
+
+	pb "github.com/hyperledger/fabric/protos"
+)
+
+func waitFor(t *testing.T, what string, cond func() bool) {
+	deadline := time.Now().Add(10 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatalf("Timed out waiting for %s", what)
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+}
+
+// waitForLeader returns the leader of the latest term among the replicas
+func waitForLeader(t *testing.T, cnet *consumerNetwork, replicas ...int) int {
+	leaderID := -1
+	waitFor(t, "a leader", func() bool {
+		var term uint64
+		leaderID = -1
+		for _, id := range replicas {
+			if r := cnet.consumer(id); r.role == leader && r.term >= term {
+				leaderID, term = id, r.term
+			}
+		}
+		return leaderID >= 0
+	})
+	return leaderID
+}
+
+func waitForHeight(t *testing.T, cnet *consumerNetwork, height uint64, replicas ...int) {
+	for _, id := range replicas {
+		waitFor(t, fmt.Sprintf("replica %d to reach height %d", id, height), func() bool {
+			return cnet.mockLedgers[id].GetBlockchainSize() >= height
+		})
+	}
+}
+
+func checkSameBlocks(t *testing.T, cnet *consumerNetwork, height uint64, replicas ...int) {
+	for n := uint64(1); n < height; n++ {
+		expected, err := cnet.mockLedgers[replicas[0]].GetBlock(n)
+		if err != nil {
+			t.Fatalf("Replica %d has no block %d", replicas[0], n)
+		}
+		for _, id := range replicas[1:] {
+			block, err := cnet.mockLedgers[id].GetBlock(n)
+			if err != nil || !proto.Equal(block, expected) {
+				t.Fatalf("Replica %d block %d differs from replica %d: %v", id, n, replicas[0], block)
+			}
+		}
+	}
+}
+
+func sendTx(cnet *consumerNetwork, id int, iter int64) {
+	tx, _ := proto.Marshal(&pb.Transaction{Type: pb.Transaction_CHAINCODE_INVOKE, Payload: []byte(fmt.Sprint(iter))})
+	cnet.consumer(id).RecvMsg(&pb.Message{Type: pb.Message_CHAIN_TRANSACTION, Payload: tx}, cnet.endpoints[id].getHandle())
+}
+
+// isolate drops all the messages from and to the replica, nil heals the network
+func isolate(cnet *consumerNetwork, isolated int) {
+	if isolated < 0 {
+		cnet.setFilter(nil)
+		return
+	}
+	cnet.setFilter(func(src, dst int, payload []byte) []byte {
+		if src == isolated || dst == isolated {
+			return nil
+		}
+		return payload
+	})
+}
+
+func TestRaftElectionAndExecution(t *testing.T) {
+	cnet := makeConsumerNetwork(4)
+	defer cnet.stop()
+	go cnet.processContinually()
+
+	leaderID := waitForLeader(t, cnet, 0, 1, 2, 3)
+	for id := 0; id < 4; id++ {
+		if r := cnet.consumer(id); id != leaderID && r.role == leader && r.term == cnet.consumer(leaderID).term {
+			t.Fatalf("Expected a single leader, replicas %d and %d lead term %d", leaderID, id, r.term)
+		}
+	}
+
+	// the transactions sent to a follower are forwarded to the leader
+	follower := (leaderID + 1) % 4
+	for i := int64(1); i <= 4; i++ {
+		sendTx(cnet, follower, i)
+	}
+	waitForHeight(t, cnet, 3, 0, 1, 2, 3)
+	checkSameBlocks(t, cnet, 3, 0, 1, 2, 3)
+
+	var payloads []string
+	for n := uint64(1); n < 3; n++ {
+		block, _ := cnet.mockLedgers[0].GetBlock(n)
+		for _, tx := range block.Transactions {
+			payloads = append(payloads, string(tx.Payload))
+		}
+		meta := &Metadata{}
+		if err := proto.Unmarshal(block.ConsensusMetadata, meta); err != nil || meta.Index == 0 {
+			t.Fatalf("Expected block %d metadata to hold the log entry, got %v (%v)", n, meta, err)
+		}
+	}
+	if fmt.Sprint(payloads) != "[1 2 3 4]" {
+		t.Fatalf("Expected the transactions to be executed in order, got %v", payloads)
+	}
+}
+
+func TestRaftLeaderFailover(t *testing.T) {
+	cnet := makeConsumerNetwork(3)
+	defer cnet.stop()
+	go cnet.processContinually()
+
+	oldLeader := waitForLeader(t, cnet, 0, 1, 2)
+	oldTerm := cnet.consumer(oldLeader).term
+	isolate(cnet, oldLeader)
+
+	var others []int
+	for id := 0; id < 3; id++ {
+		if id != oldLeader {
+			others = append(others, id)
+		}
+	}
+	newLeader := waitForLeader(t, cnet, others...)
+	if term := cnet.consumer(newLeader).term; term <= oldTerm {
+		t.Fatalf("Expected the new leader to lead a term after %d, got %d", oldTerm, term)
+	}
+
+	sendTx(cnet, others[0], 1)
+	sendTx(cnet, others[1], 2)
+	waitForHeight(t, cnet, 2, others...)
+	if height := cnet.mockLedgers[oldLeader].GetBlockchainSize(); height != 1 {
+		t.Fatalf("Expected the isolated leader to commit nothing, got height %d", height)
+	}
+
+	// once the network heals, the old leader follows and catches up
+	isolate(cnet, -1)
+	waitForHeight(t, cnet, 2, 0, 1, 2)
+	checkSameBlocks(t, cnet, 2, 0, 1, 2)
+	waitFor(t, "the old leader to step down", func() bool {
+		return cnet.consumer(oldLeader).role == follower
+	})
+}
+
+func TestRaftStateTransfer(t *testing.T) {
+	cnet := makeConsumerNetwork(3, func(config *viper.Viper) {
+		config.Set("general.batchsize", 1)
+		config.Set("general.logsize", 0)
+	})
+	defer cnet.stop()
+	go cnet.processContinually()
+
+	leaderID := waitForLeader(t, cnet, 0, 1, 2)
+	lagging := (leaderID + 1) % 3
+	others := []int{leaderID, (leaderID + 2) % 3}
+	waitFor(t, "replicas to follow the leader", func() bool {
+		return cnet.consumer(lagging).leader == int64(leaderID) && cnet.consumer(others[1]).leader == int64(leaderID)
+	})
+	isolate(cnet, lagging)
+
+	for i := int64(1); i <= 3; i++ {
+		sendTx(cnet, leaderID, i)
+	}
+	waitForHeight(t, cnet, 4, others...)
+
+	// the entries are compacted away, the lagging replica is brought up to
+	// date by state transfer
+	isolate(cnet, -1)
+	waitForHeight(t, cnet, 4, lagging)
+	checkSameBlocks(t, cnet, 4, 0, 1, 2)
+	if transfers := atomic.LoadInt32(&cnet.stacks[lagging].stateTransfers); transfers == 0 {
+		t.Fatalf("Expected replica %d to transfer state", lagging)
+	}
+
+	// and replicates the new entries from there
+	sendTx(cnet, lagging, 4)
+	waitForHeight(t, cnet, 5, 0, 1, 2)
+	checkSameBlocks(t, cnet, 5, 0, 1, 2)
+}
+
+func TestRaftRestoreState(t *testing.T) {
+	cnet := makeConsumerNetwork(1)
+	defer cnet.stop()
+	go cnet.processContinually()
+
+	waitForLeader(t, cnet, 0)
+	sendTx(cnet, 0, 1)
+	sendTx(cnet, 0, 2)
+	waitForHeight(t, cnet, 2, 0)
+
+	// a crashed replica restores its term and log, the entries up to the last
+	// block being applied
+	old := cnet.consumer(0)
+	old.Close()
+	time.Sleep(50 * time.Millisecond)
+	term, lastIndex, lastApplied := old.term, old.log.lastIndex(), old.lastApplied
+
+	ce := cnet.endpoints[0].(*consumerEndpoint)
+	ce.consumer = newRaft(0, loadTestConfig(1), cnet.stacks[0])
+	r := ce.consumer
+	if r.term != term || r.votedFor != 0 {
+		t.Fatalf("Expected term %d and vote for 0 to be restored, got term %d and vote for %d", term, r.term, r.votedFor)
+	}
+	if r.log.lastIndex() != lastIndex || r.lastApplied != lastApplied || r.commitIndex != lastApplied {
+		t.Fatalf("Expected log up to %d applied up to %d, got log up to %d applied up to %d committed up to %d",
+			lastIndex, lastApplied, r.log.lastIndex(), r.lastApplied, r.commitIndex)
+	}
+
+	waitFor(t, "a new term", func() bool { return r.role == leader && r.term > term })
+	sendTx(cnet, 0, 3)
+	sendTx(cnet, 0, 4)
+	waitForHeight(t, cnet, 3, 0)
+	block, _ := cnet.mockLedgers[0].GetBlock(2)
+	if len(block.Transactions) != 2 || !bytes.Equal(block.Transactions[0].Payload, []byte("3")) {
+		t.Fatalf("Expected block 2 to hold the new transactions, got %v", block.Transactions)
+	}
+}
@@ -17,7 +17,9 @@ limitations under the License.
 package core
 
 import (
+	"fmt"
 	"os"
+	"path/filepath"
 	"runtime"
 
 	"github.com/op/go-logging"
@@ -26,6 +28,7 @@ import (
 
 	"google/protobuf"
 
+	"github.com/hyperledger/fabric/core/ledger"
 	pb "github.com/hyperledger/fabric/protos"
 )
 
@@ -79,3 +82,23 @@ func (*ServerAdmin) StopServer(context.Context, *google_protobuf.Empty) (*pb.Ser
 	defer os.Exit(0)
 	return status, nil
 }
+
+// ExportLedger exports the ledger to an archive file on the file system of the
+// peer. The archive is taken from a point-in-time view of the ledger, so that
+// the peer keeps processing transactions during the export.
+func (*ServerAdmin) ExportLedger(ctx context.Context, request *pb.ExportLedgerRequest) (*pb.ExportLedgerResponse, error) {
+	if !filepath.IsAbs(request.Path) {
+		return nil, fmt.Errorf("Path of the archive must be absolute, got [%s]", request.Path)
+	}
+	ledger, err := ledger.GetLedger()
+	if err != nil {
+		return nil, err
+	}
+	log.Infof("Exporting ledger to %s", request.Path)
+	info, err := ledger.ExportToFile(request.Path)
+	if err != nil {
+		log.Errorf("Error exporting ledger to %s: %s", request.Path, err)
+		return nil, err
+	}
+	return &pb.ExportLedgerResponse{Blocks: info.Blocks, StateEntries: info.StateEntries, HistoryEntries: info.HistoryEntries}, nil
+}
@@ -0,0 +1,129 @@
+/*
+Copyright IBM Corp. 2016 All Rights Reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+		 http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package chaincode
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/golang/protobuf/proto"
+	google_protobuf "google/protobuf"
+
+	"github.com/hyperledger/fabric/core/ledger"
+	pb "github.com/hyperledger/fabric/protos"
+)
+
+// effectiveDatesNamespace is the state namespace recording the effective date
+// of the deployed version of the chaincodes: the key is the chaincode name and
+// the value the marshalled timestamp. Chaincodes deployed without an effective
+// date have no key.
+const effectiveDatesNamespace = "#effectivedates"
+
+// setEffectiveDate records the effective date of the deployed version of the
+// chaincode, a nil date meaning the chaincode is executable right away
+func setEffectiveDate(ledger *ledger.Ledger, chaincode string, date *google_protobuf.Timestamp) error {
+	if date == nil {
+		return ledger.DeleteState(effectiveDatesNamespace, chaincode)
+	}
+	rawDate, err := proto.Marshal(date)
+	if err != nil {
+		return err
+	}
+	return ledger.SetState(effectiveDatesNamespace, chaincode, rawDate)
+}
+
+// recordEffectiveDate records the effective date set by a deploy transaction
+func recordEffectiveDate(ledger *ledger.Ledger, t *pb.Transaction) error {
+	cds := &pb.ChaincodeDeploymentSpec{}
+	if err := proto.Unmarshal(t.Payload, cds); err != nil {
+		return err
+	}
+	if cds.EffectiveDate == nil {
+		return nil
+	}
+	return setEffectiveDate(ledger, cds.ChaincodeSpec.ChaincodeID.Name, cds.EffectiveDate)
+}
+
+// getEffectiveDate returns the effective date of the deployed version of the
+// chaincode, nil if it has none
+func getEffectiveDate(ledger *ledger.Ledger, chaincode string, committed bool) (*google_protobuf.Timestamp, error) {
+	rawDate, err := ledger.GetState(effectiveDatesNamespace, chaincode, committed)
+	if err != nil || rawDate == nil {
+		return nil, err
+	}
+	date := &google_protobuf.Timestamp{}
+	if err = proto.Unmarshal(rawDate, date); err != nil {
+		return nil, err
+	}
+	return date, nil
+}
+
+// GetActivation returns the effective date of the deployed version of the
+// chaincode, nil if it has none, and whether the chaincode is active as of the
+// last committed block
+func GetActivation(ledger *ledger.Ledger, chaincode string) (*google_protobuf.Timestamp, bool, error) {
+	date, err := getEffectiveDate(ledger, chaincode, true)
+	if err != nil || date == nil {
+		return nil, err == nil, err
+	}
+	consensusTime, err := getLastBlockTimestamp(ledger)
+	if err != nil {
+		return nil, false, err
+	}
+	return date, !pb.TimestampBefore(consensusTime, date), nil
+}
+
+// getLastBlockTimestamp returns the timestamp of the last committed block, nil
+// if there is none
+func getLastBlockTimestamp(ledger *ledger.Ledger) (*google_protobuf.Timestamp, error) {
+	size := ledger.GetBlockchainSize()
+	if size == 0 {
+		return nil, nil
+	}
+	block, err := ledger.GetBlockByNumber(size - 1)
+	if err != nil {
+		return nil, err
+	}
+	return block.Timestamp, nil
+}
+
+// checkActive returns an error if the chaincode cannot be invoked or queried
+// yet, that is until a block whose timestamp, agreed on by the consensus, is
+// at or after the effective date is committed. The timestamps of the
+// transactions are set by the clients, they are not trusted.
+func checkActive(ledger *ledger.Ledger, chaincode string) error {
+	date, err := getEffectiveDate(ledger, chaincode, false)
+	if err != nil {
+		return fmt.Errorf("Failed to get the effective date of chaincode %s (%s)", chaincode, err)
+	}
+	if date == nil {
+		return nil
+	}
+	consensusTime, err := getLastBlockTimestamp(ledger)
+	if err != nil {
+		return fmt.Errorf("Failed to get the timestamp of the last block (%s)", err)
+	}
+	if pb.TimestampBefore(consensusTime, date) {
+		return fmt.Errorf("chaincode %s is not active until %s", chaincode, FormatEffectiveDate(date))
+	}
+	return nil
+}
+
+// FormatEffectiveDate formats an effective date in RFC 3339 format
+func FormatEffectiveDate(date *google_protobuf.Timestamp) string {
+	return time.Unix(date.Seconds, int64(date.Nanos)).UTC().Format(time.RFC3339Nano)
+}
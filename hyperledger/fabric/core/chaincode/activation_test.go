@@ -0,0 +1,100 @@
+/*
+Copyright IBM Corp. 2016 All Rights Reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+		 http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package chaincode
+
+import (
+	"testing"
+
+	google_protobuf "google/protobuf"
+
+	"github.com/hyperledger/fabric/core/ledger"
+	pb "github.com/hyperledger/fabric/protos"
+)
+
+func TestChaincodeActivation(t *testing.T) {
+	lgr := ledger.InitTestLedger(t)
+	effectiveDate := &google_protobuf.Timestamp{Seconds: 1000}
+
+	// Commit a deploy transaction taking effect at the effective date
+	cds := &pb.ChaincodeDeploymentSpec{ChaincodeSpec: &pb.ChaincodeSpec{Type: pb.ChaincodeSpec_GOLANG, ChaincodeID: &pb.ChaincodeID{Name: "mycc"}}, EffectiveDate: effectiveDate}
+	depTx, err := pb.NewChaincodeDeployTransaction(cds, "mycc")
+	if err != nil {
+		t.Fatalf("Error creating deploy transaction: %s", err)
+	}
+	lgr.BeginTxBatch(1)
+	lgr.TxBegin("mycc")
+	if err = recordEffectiveDate(lgr, depTx); err != nil {
+		t.Fatalf("Error recording effective date: %s", err)
+	}
+	lgr.TxFinished("mycc", true)
+	if err = lgr.CommitTxBatchAt(1, &google_protobuf.Timestamp{Seconds: 500}, []*pb.Transaction{depTx}, nil, nil); err != nil {
+		t.Fatalf("Error committing deploy transaction: %s", err)
+	}
+
+	date, active, err := GetActivation(lgr, "mycc")
+	if err != nil || active || date == nil || date.Seconds != 1000 {
+		t.Fatalf("Expected a pending activation at 1000 but got %v, %t, %v", date, active, err)
+	}
+	if date, active, err := GetActivation(lgr, "other"); err != nil || !active || date != nil {
+		t.Fatalf("Expected a chaincode without effective date to be active but got %v, %t, %v", date, active, err)
+	}
+
+	// Invokes and queries are rejected before the effective date, whatever
+	// the timestamp of the transactions
+	if err = checkActive(lgr, "mycc"); err == nil {
+		t.Fatalf("Expected an error invoking the chaincode before its effective date")
+	}
+
+	// A block timestamped before the effective date leaves the chaincode inactive
+	invokeTx, err := pb.NewChaincodeExecute(&pb.ChaincodeInvocationSpec{ChaincodeSpec: cds.ChaincodeSpec}, "tx1", pb.Transaction_CHAINCODE_INVOKE)
+	if err != nil {
+		t.Fatalf("Error creating transaction: %s", err)
+	}
+	invokeTx.Timestamp = &google_protobuf.Timestamp{Seconds: 2000}
+	lgr.BeginTxBatch(2)
+	if err = lgr.CommitTxBatchAt(2, &google_protobuf.Timestamp{Seconds: 999}, []*pb.Transaction{invokeTx}, nil, nil); err != nil {
+		t.Fatalf("Error committing invoke transaction: %s", err)
+	}
+	if err = checkActive(lgr, "mycc"); err == nil {
+		t.Fatalf("Expected an error invoking the chaincode before a block reached its effective date")
+	}
+
+	// Once a block timestamped at the effective date is committed the
+	// chaincode is active
+	lgr.BeginTxBatch(3)
+	if err = lgr.CommitTxBatchAt(3, effectiveDate, nil, nil, nil); err != nil {
+		t.Fatalf("Error committing block: %s", err)
+	}
+	if err = checkActive(lgr, "mycc"); err != nil {
+		t.Fatalf("Expected the chaincode to be active at its effective date: %s", err)
+	}
+	if date, active, err := GetActivation(lgr, "mycc"); err != nil || !active || date.Seconds != 1000 {
+		t.Fatalf("Expected the chaincode to be active but got %v, %t, %v", date, active, err)
+	}
+
+	// Clearing the effective date, as an upgrade without one does
+	lgr.BeginTxBatch(4)
+	lgr.TxBegin("tx2")
+	if err = setEffectiveDate(lgr, "mycc", nil); err != nil {
+		t.Fatalf("Error clearing effective date: %s", err)
+	}
+	lgr.TxFinished("tx2", true)
+	if date, _ := getEffectiveDate(lgr, "mycc", false); date != nil {
+		t.Fatalf("Expected no effective date but got %v", date)
+	}
+	lgr.RollbackTxBatch(4)
+}
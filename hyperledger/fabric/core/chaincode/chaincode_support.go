@@ -21,6 +21,7 @@ import (
 	"fmt"
 	"io"
 	"strconv"
+	"strings"
 	"sync"
 	"time"
 
@@ -28,6 +29,7 @@ import (
 	"github.com/spf13/viper"
 	"golang.org/x/net/context"
 
+	"github.com/hyperledger/fabric/core/comm"
 	"github.com/hyperledger/fabric/core/container"
 	"github.com/hyperledger/fabric/core/container/ccintf"
 	"github.com/hyperledger/fabric/core/crypto"
@@ -46,6 +48,14 @@ const (
 	chaincodeStartupTimeoutDefault int    = 5000
 	chaincodeInstallPathDefault    string = "/opt/gopath/bin/"
 	peerAddressDefault             string = "0.0.0.0:30303"
+
+	// VMTypeDocker is the vm.type property running chaincodes in docker containers
+	VMTypeDocker string = "docker"
+	// VMTypeProcess is the vm.type property running chaincodes as processes of the peer
+	VMTypeProcess string = "process"
+	// VMTypeInproc is the vm.type property running chaincodes registered with
+	// the inproc container within the peer, as system chaincodes are
+	VMTypeInproc string = "inproc"
 )
 
 // chains is a map between different blockchains and their ChaincodeSupport.
@@ -60,6 +70,8 @@ func init() {
 //This is where the VM that's running the chaincode would hook in
 type chaincodeRTEnv struct {
 	handler *Handler
+	//version of the chaincode run by the handler
+	version uint64
 }
 
 // runningChaincodes contains maps of chaincodeIDs to their chaincodeRTEs
@@ -69,18 +81,48 @@ type runningChaincodes struct {
 	chaincodeMap map[string]*chaincodeRTEnv
 }
 
+// getChaincodeKey returns the key of the chaincode of the chain among the
+// running chaincodes, which is also the name the chaincode registers with, as
+// every chain runs its own instance of a chaincode
+func getChaincodeKey(chainID string, chaincode string) string {
+	if chainID == "" {
+		return chaincode
+	}
+	return chainID + ":" + chaincode
+}
+
+// getChaincodeName returns the name of the chaincode registered with the key
+func getChaincodeName(key string) string {
+	if i := strings.Index(key, ":"); i >= 0 {
+		return key[i+1:]
+	}
+	return key
+}
+
 // GetChain returns the chaincode support for a given chain
 func GetChain(name ChainName) *ChaincodeSupport {
 	return chains[name]
 }
 
+// getLedger returns the ledger of the chain identified by chainID, the
+// default chain if empty
+func (chaincodeSupport *ChaincodeSupport) getLedger(chainID string) (*ledger.Ledger, error) {
+	if chaincodeSupport.ledger == nil {
+		return ledger.GetChainLedger(chainID)
+	}
+	if chainID != "" {
+		return nil, fmt.Errorf("chain %s is not hosted, only the default chain is", chainID)
+	}
+	return chaincodeSupport.ledger, nil
+}
+
 //call this under lock
-func (chaincodeSupport *ChaincodeSupport) preLaunchSetup(chaincode string) chan bool {
+func (chaincodeSupport *ChaincodeSupport) preLaunchSetup(chaincode string, version uint64) chan bool {
 	//register placeholder Handler. This will be transferred in registerHandler
 	//NOTE: from this point, existence of handler for this chaincode means the chaincode
 	//is in the process of getting started (or has been started)
 	notfy := make(chan bool, 1)
-	chaincodeSupport.runningChaincodes.chaincodeMap[chaincode] = &chaincodeRTEnv{handler: &Handler{readyNotify: notfy}}
+	chaincodeSupport.runningChaincodes.chaincodeMap[chaincode] = &chaincodeRTEnv{handler: &Handler{readyNotify: notfy}, version: version}
 	return notfy
 }
 
@@ -92,14 +134,33 @@ func (chaincodeSupport *ChaincodeSupport) chaincodeHasBeenLaunched(chaincode str
 
 // NewChaincodeSupport creates a new ChaincodeSupport instance
 func NewChaincodeSupport(chainname ChainName, getPeerEndpoint func() (*pb.PeerEndpoint, error), userrunsCC bool, ccstartuptimeout time.Duration, secHelper crypto.Peer) *ChaincodeSupport {
+	s := newChaincodeSupport(chainname, getPeerEndpoint, userrunsCC, ccstartuptimeout, secHelper)
+
+	//initialize global chain
+	chains[chainname] = s
+	return s
+}
+
+// NewLedgerChaincodeSupport creates a ChaincodeSupport executing the
+// transactions of the default chain on the given ledger, apart from the
+// chains of GetChain. The chaincodes it launches are named after the ID of
+// the peer endpoint, so that a process can run several peers each with
+// chaincodes of its own.
+func NewLedgerChaincodeSupport(lgr *ledger.Ledger, getPeerEndpoint func() (*pb.PeerEndpoint, error), userrunsCC bool, ccstartuptimeout time.Duration, secHelper crypto.Peer) *ChaincodeSupport {
+	s := newChaincodeSupport(DefaultChain, getPeerEndpoint, userrunsCC, ccstartuptimeout, secHelper)
+	s.ledger = lgr
+	if peerEndpoint, err := getPeerEndpoint(); err == nil && peerEndpoint.ID != nil {
+		s.peerID = peerEndpoint.ID.Name
+	}
+	return s
+}
+
+func newChaincodeSupport(chainname ChainName, getPeerEndpoint func() (*pb.PeerEndpoint, error), userrunsCC bool, ccstartuptimeout time.Duration, secHelper crypto.Peer) *ChaincodeSupport {
 	pnid := viper.GetString("peer.networkId")
 	pid := viper.GetString("peer.id")
 
 	s := &ChaincodeSupport{name: chainname, runningChaincodes: &runningChaincodes{chaincodeMap: make(map[string]*chaincodeRTEnv)}, secHelper: secHelper, peerNetworkID: pnid, peerID: pid}
 
-	//initialize global chain
-	chains[chainname] = s
-
 	peerEndpoint, err := getPeerEndpoint()
 	if err != nil {
 		chaincodeLogger.Errorf("Error getting PeerEndpoint, using peer.address: %s", err)
@@ -123,6 +184,23 @@ func NewChaincodeSupport(chainname ChainName, getPeerEndpoint func() (*pb.PeerEn
 		s.chaincodeInstallPath = chaincodeInstallPathDefault
 	}
 
+	//user chaincodes run in docker containers unless configured to run as
+	//processes of the peer or within the peer
+	switch vmType := viper.GetString("vm.type"); vmType {
+	case VMTypeProcess:
+		s.vmType = container.PROCESS
+	case VMTypeInproc:
+		s.vmType = container.SYSTEM
+	case "", VMTypeDocker:
+		s.vmType = container.DOCKER
+	default:
+		chaincodeLogger.Errorf("Invalid vm type %s defaulting to %s", vmType, VMTypeDocker)
+		s.vmType = container.DOCKER
+	}
+
+	//invokes of different chaincodes in a batch run in parallel, see ExecuteTransactions
+	s.parallelExecution = viper.GetBool("chaincode.parallelexecution")
+
 	s.peerTLS = viper.GetBool("peer.tls.enabled")
 	if s.peerTLS {
 		s.peerTLSCertFile = viper.GetString("peer.tls.cert.file")
@@ -170,6 +248,9 @@ type ChaincodeSupport struct {
 	peerTLSKeyFile       string
 	peerTLSSvrHostOrd    string
 	keepalive            time.Duration
+	vmType               string
+	parallelExecution    bool
+	ledger               *ledger.Ledger // set by NewLedgerChaincodeSupport
 }
 
 // DuplicateChaincodeHandlerError returned if attempt to register same chaincodeID while a stream already exists.
@@ -265,6 +346,7 @@ func (chaincodeSupport *ChaincodeSupport) sendInitOrReady(context context.Contex
 				err = fmt.Errorf("Error initializing container %s: %s", chaincode, string(ccMsg.Payload))
 			}
 		case <-time.After(timeout):
+			timeouts.With("init").Inc()
 			err = fmt.Errorf("Timeout expired while executing send init message")
 		}
 	}
@@ -275,9 +357,9 @@ func (chaincodeSupport *ChaincodeSupport) sendInitOrReady(context context.Contex
 	return err
 }
 
-//get args and env given chaincodeID
-func (chaincodeSupport *ChaincodeSupport) getArgsAndEnv(cID *pb.ChaincodeID) (args []string, envs []string, err error) {
-	envs = []string{"CORE_CHAINCODE_ID_NAME=" + cID.Name}
+//get args and env given the chaincode key and the name of its executable
+func (chaincodeSupport *ChaincodeSupport) getArgsAndEnv(key string, executable string) (args []string, envs []string, err error) {
+	envs = []string{"CORE_CHAINCODE_ID_NAME=" + key}
 
 	//if TLS is enabled, pass TLS material to chaincode
 	if chaincodeSupport.peerTLS {
@@ -290,8 +372,7 @@ func (chaincodeSupport *ChaincodeSupport) getArgsAndEnv(cID *pb.ChaincodeID) (ar
 		envs = append(envs, "CORE_PEER_TLS_ENABLED=false")
 	}
 
-	//chaincode executable will be same as the name of the chaincode
-	args = []string{chaincodeSupport.chaincodeInstallPath + cID.Name, fmt.Sprintf("-peer.address=%s", chaincodeSupport.peerAddress)}
+	args = []string{chaincodeSupport.chaincodeInstallPath + executable, fmt.Sprintf("-peer.address=%s", chaincodeSupport.peerAddress)}
 
 	chaincodeLogger.Debugf("Executable is %s", args[0])
 
@@ -299,11 +380,11 @@ func (chaincodeSupport *ChaincodeSupport) getArgsAndEnv(cID *pb.ChaincodeID) (ar
 }
 
 // launchAndWaitForRegister will launch container if not already running. Use the targz to create the image if not found
-func (chaincodeSupport *ChaincodeSupport) launchAndWaitForRegister(ctxt context.Context, cds *pb.ChaincodeDeploymentSpec, cID *pb.ChaincodeID, uuid string, targz io.Reader) (bool, error) {
-	chaincode := cID.Name
-	if chaincode == "" {
+func (chaincodeSupport *ChaincodeSupport) launchAndWaitForRegister(ctxt context.Context, chainID string, cds *pb.ChaincodeDeploymentSpec, cID *pb.ChaincodeID, uuid string, executable string, targz io.Reader) (bool, error) {
+	if cID.Name == "" {
 		return false, fmt.Errorf("chaincode name not set")
 	}
+	chaincode := getChaincodeKey(chainID, cID.Name)
 
 	chaincodeSupport.runningChaincodes.Lock()
 	var ok bool
@@ -314,12 +395,13 @@ func (chaincodeSupport *ChaincodeSupport) launchAndWaitForRegister(ctxt context.
 		return true, nil
 	}
 	alreadyRunning := false
-	notfy := chaincodeSupport.preLaunchSetup(chaincode)
+	defer launchDuration.ObserveSince(time.Now())
+	notfy := chaincodeSupport.preLaunchSetup(chaincode, cds.ChaincodeSpec.ChaincodeID.Version)
 	chaincodeSupport.runningChaincodes.Unlock()
 
 	//launch the chaincode
 
-	args, env, err := chaincodeSupport.getArgsAndEnv(cID)
+	args, env, err := chaincodeSupport.getArgsAndEnv(chaincode, executable)
 	if err != nil {
 		return alreadyRunning, err
 	}
@@ -328,7 +410,7 @@ func (chaincodeSupport *ChaincodeSupport) launchAndWaitForRegister(ctxt context.
 
 	vmtype, _ := chaincodeSupport.getVMType(cds)
 
-	sir := container.StartImageReq{CCID: ccintf.CCID{ChaincodeSpec: cds.ChaincodeSpec, NetworkID: chaincodeSupport.peerNetworkID, PeerID: chaincodeSupport.peerID}, Reader: targz, Args: args, Env: env}
+	sir := container.StartImageReq{CCID: ccintf.CCID{ChaincodeSpec: cds.ChaincodeSpec, NetworkID: chaincodeSupport.peerNetworkID, PeerID: chaincodeSupport.peerID, ChainID: chainID}, Reader: targz, Args: args, Env: env}
 
 	ipcCtxt := context.WithValue(ctxt, ccintf.GetCCHandlerKey(), chaincodeSupport)
 
@@ -351,11 +433,12 @@ func (chaincodeSupport *ChaincodeSupport) launchAndWaitForRegister(ctxt context.
 			err = fmt.Errorf("registration failed for %s(networkid:%s,peerid:%s,tx:%s)", chaincode, chaincodeSupport.peerNetworkID, chaincodeSupport.peerID, uuid)
 		}
 	case <-time.After(chaincodeSupport.ccStartupTimeout):
+		timeouts.With("launch").Inc()
 		err = fmt.Errorf("Timeout expired while starting chaincode %s(networkid:%s,peerid:%s,tx:%s)", chaincode, chaincodeSupport.peerNetworkID, chaincodeSupport.peerID, uuid)
 	}
 	if err != nil {
 		chaincodeLogger.Debugf("stopping due to error while launching %s", err)
-		errIgnore := chaincodeSupport.Stop(ctxt, cds)
+		errIgnore := chaincodeSupport.Stop(ctxt, chainID, cds)
 		if errIgnore != nil {
 			chaincodeLogger.Debugf("error on stop %s(%s)", errIgnore, err)
 		}
@@ -363,15 +446,15 @@ func (chaincodeSupport *ChaincodeSupport) launchAndWaitForRegister(ctxt context.
 	return alreadyRunning, err
 }
 
-//Stop stops a chaincode if running
-func (chaincodeSupport *ChaincodeSupport) Stop(context context.Context, cds *pb.ChaincodeDeploymentSpec) error {
-	chaincode := cds.ChaincodeSpec.ChaincodeID.Name
-	if chaincode == "" {
+//Stop stops a chaincode of the chain if running
+func (chaincodeSupport *ChaincodeSupport) Stop(context context.Context, chainID string, cds *pb.ChaincodeDeploymentSpec) error {
+	if cds.ChaincodeSpec.ChaincodeID.Name == "" {
 		return fmt.Errorf("chaincode name not set")
 	}
+	chaincode := getChaincodeKey(chainID, cds.ChaincodeSpec.ChaincodeID.Name)
 
 	//stop the chaincode
-	sir := container.StopImageReq{CCID: ccintf.CCID{ChaincodeSpec: cds.ChaincodeSpec, NetworkID: chaincodeSupport.peerNetworkID, PeerID: chaincodeSupport.peerID}, Timeout: 0}
+	sir := container.StopImageReq{CCID: ccintf.CCID{ChaincodeSpec: cds.ChaincodeSpec, NetworkID: chaincodeSupport.peerNetworkID, PeerID: chaincodeSupport.peerID, ChainID: chainID}, Timeout: 0}
 
 	vmtype, _ := chaincodeSupport.getVMType(cds)
 
@@ -404,7 +487,7 @@ func (chaincodeSupport *ChaincodeSupport) Launch(context context.Context, t *pb.
 	var initargs []string
 
 	cds := &pb.ChaincodeDeploymentSpec{}
-	if t.Type == pb.Transaction_CHAINCODE_DEPLOY {
+	if t.Type == pb.Transaction_CHAINCODE_DEPLOY || t.Type == pb.Transaction_CHAINCODE_UPGRADE {
 		err := proto.Unmarshal(t.Payload, cds)
 		if err != nil {
 			return nil, nil, err
@@ -426,28 +509,74 @@ func (chaincodeSupport *ChaincodeSupport) Launch(context context.Context, t *pb.
 		return nil, nil, fmt.Errorf("invalid transaction type: %d", t.Type)
 	}
 	chaincode := cID.Name
+
+	//terminated chaincodes can neither be redeployed nor invoked
+	if err := chaincodeSupport.checkNotTerminated(t.ChainID, chaincode); err != nil {
+		return cID, cMsg, err
+	}
+
+	//the version to run is carried by deploy and upgrade transactions, and
+	//recorded in the ledger for the others
+	var version uint64
+	if t.Type == pb.Transaction_CHAINCODE_DEPLOY || t.Type == pb.Transaction_CHAINCODE_UPGRADE {
+		version = cID.Version
+	} else {
+		ledger, ledgerErr := chaincodeSupport.getLedger(t.ChainID)
+		if ledgerErr != nil {
+			return cID, cMsg, fmt.Errorf("Failed to get handle to ledger (%s)", ledgerErr)
+		}
+		var versionErr error
+		if version, versionErr = getChaincodeVersion(ledger, chaincode, false); versionErr != nil {
+			return cID, cMsg, fmt.Errorf("Could not get version of chaincode %s - %s", chaincode, versionErr)
+		}
+		//chaincodes deployed ahead of their effective date cannot be invoked yet
+		if activeErr := checkActive(ledger, chaincode); activeErr != nil {
+			return cID, cMsg, activeErr
+		}
+	}
+
+	key := getChaincodeKey(t.ChainID, chaincode)
 	chaincodeSupport.runningChaincodes.Lock()
 	var chrte *chaincodeRTEnv
 	var ok bool
 	var err error
 	//if its in the map, there must be a connected stream...nothing to do
-	if chrte, ok = chaincodeSupport.chaincodeHasBeenLaunched(chaincode); ok {
+	if chrte, ok = chaincodeSupport.chaincodeHasBeenLaunched(key); ok {
 		if !chrte.handler.registered {
 			chaincodeSupport.runningChaincodes.Unlock()
 			chaincodeLogger.Debugf("premature execution - chaincode (%s) is being launched", chaincode)
 			err = fmt.Errorf("premature execution - chaincode (%s) is being launched", chaincode)
 			return cID, cMsg, err
 		}
-		if chrte.handler.isRunning() {
+		if chrte.version != version {
+			if chaincodeSupport.userRunsCC {
+				//the user is responsible for running the right version
+				chrte.version = version
+			} else {
+				//the running version is outdated, e.g. after an upgrade was rolled back
+				chaincodeSupport.runningChaincodes.Unlock()
+				chaincodeLogger.Debugf("stopping version %d of chaincode %s to run version %d", chrte.version, chaincode, version)
+				outdated := &pb.ChaincodeDeploymentSpec{ChaincodeSpec: &pb.ChaincodeSpec{ChaincodeID: &pb.ChaincodeID{Name: chaincode, Version: chrte.version}}}
+				if errIgnore := chaincodeSupport.Stop(context, t.ChainID, outdated); errIgnore != nil {
+					chaincodeLogger.Debugf("stop failed %s", errIgnore)
+				}
+				chaincodeSupport.runningChaincodes.Lock()
+				chrte = nil
+			}
+		}
+		if chrte != nil && chrte.handler.isRunning() {
 			chaincodeLogger.Debugf("chaincode is running(no need to launch) : %s", chaincode)
 			chaincodeSupport.runningChaincodes.Unlock()
 			return cID, cMsg, nil
 		}
-		chaincodeLogger.Debugf("Container not in READY state(%s)...send init/ready", chrte.handler.FSM.Current())
+		if chrte != nil {
+			chaincodeLogger.Debugf("Container not in READY state(%s)...send init/ready", chrte.handler.FSM.Current())
+		}
 	}
 	chaincodeSupport.runningChaincodes.Unlock()
 
 	var depTx *pb.Transaction
+	depUUID := t.Uuid
 
 	//extract depTx so we can initialize hander.deployTXSecContext
 	//we need it only after container is launched and only if this is not a deploy tx
@@ -461,14 +590,19 @@ func (chaincodeSupport *ChaincodeSupport) Launch(context context.Context, t *pb.
 	//         5) query successfully retrives committed tx and calls sendInitOrReady
 	// See issue #710
 
-	if t.Type != pb.Transaction_CHAINCODE_DEPLOY {
-		ledger, ledgerErr := ledger.GetLedger()
+	if t.Type != pb.Transaction_CHAINCODE_DEPLOY && t.Type != pb.Transaction_CHAINCODE_UPGRADE {
+		ledger, ledgerErr := chaincodeSupport.getLedger(t.ChainID)
 		if ledgerErr != nil {
 			return cID, cMsg, fmt.Errorf("Failed to get handle to ledger (%s)", ledgerErr)
 		}
 
+		//the deployed transaction of an upgraded chaincode is the upgrade transaction of its version
+		if depUUID, ledgerErr = getVersionTxUUID(ledger, chaincode, version, false); ledgerErr != nil {
+			return cID, cMsg, ledgerErr
+		}
+
 		//hopefully we are restarting from existing image and the deployed transaction exists
-		depTx, ledgerErr = ledger.GetTransactionByUUID(chaincode)
+		depTx, ledgerErr = ledger.GetTransactionByUUID(depUUID)
 		if ledgerErr != nil {
 			return cID, cMsg, fmt.Errorf("Could not get deployment transaction for %s - %s", chaincode, ledgerErr)
 		}
@@ -494,7 +628,7 @@ func (chaincodeSupport *ChaincodeSupport) Launch(context context.Context, t *pb.
 	//launch container if it is a System container or not in dev mode
 	if (!chaincodeSupport.userRunsCC || cds.ExecEnv == pb.ChaincodeDeploymentSpec_SYSTEM) && (chrte == nil || chrte.handler == nil) {
 		var targz io.Reader = bytes.NewBuffer(cds.CodePackage)
-		_, err = chaincodeSupport.launchAndWaitForRegister(context, cds, cID, t.Uuid, targz)
+		_, err = chaincodeSupport.launchAndWaitForRegister(context, t.ChainID, cds, cID, t.Uuid, getExecutable(cds, depUUID), targz)
 		if err != nil {
 			chaincodeLogger.Debugf("launchAndWaitForRegister failed %s", err)
 			return cID, cMsg, err
@@ -503,11 +637,11 @@ func (chaincodeSupport *ChaincodeSupport) Launch(context context.Context, t *pb.
 
 	if err == nil {
 		//send init (if (f,args)) and wait for ready state
-		err = chaincodeSupport.sendInitOrReady(context, t.Uuid, chaincode, f, initargs, chaincodeSupport.ccStartupTimeout, t, depTx)
+		err = chaincodeSupport.sendInitOrReady(context, t.Uuid, key, f, initargs, chaincodeSupport.ccStartupTimeout, t, depTx)
 		if err != nil {
 			chaincodeLogger.Debugf("sending init failed(%s)", err)
 			err = fmt.Errorf("Failed to init chaincode(%s)", err)
-			errIgnore := chaincodeSupport.Stop(context, cds)
+			errIgnore := chaincodeSupport.Stop(context, t.ChainID, cds)
 			if errIgnore != nil {
 				chaincodeLogger.Debugf("stop failed %s(%s)", errIgnore, err)
 			}
@@ -531,7 +665,10 @@ func (chaincodeSupport *ChaincodeSupport) getVMType(cds *pb.ChaincodeDeploymentS
 	if cds.ExecEnv == pb.ChaincodeDeploymentSpec_SYSTEM {
 		return container.SYSTEM, nil
 	}
-	return container.DOCKER, nil
+	if chaincodeSupport.vmType == "" {
+		return container.DOCKER, nil
+	}
+	return chaincodeSupport.vmType, nil
 }
 
 // Deploy deploys the chaincode if not in development mode where user is running the chaincode.
@@ -548,27 +685,33 @@ func (chaincodeSupport *ChaincodeSupport) Deploy(context context.Context, t *pb.
 		return cds, err
 	}
 
+	//versions other than the first are deployed by upgrade transactions
+	if t.Type == pb.Transaction_CHAINCODE_DEPLOY && cID.Version != 0 {
+		return cds, fmt.Errorf("deploy of chaincode %s cannot set version %d", chaincode, cID.Version)
+	}
+
 	if chaincodeSupport.userRunsCC {
 		chaincodeLogger.Debug("user runs chaincode, not deploying chaincode")
 		return nil, nil
 	}
 
+	key := getChaincodeKey(t.ChainID, chaincode)
 	chaincodeSupport.runningChaincodes.Lock()
 	//if its in the map, there must be a connected stream...and we are trying to build the code ?!
-	if _, ok := chaincodeSupport.chaincodeHasBeenLaunched(chaincode); ok {
+	if _, ok := chaincodeSupport.chaincodeHasBeenLaunched(key); ok {
 		chaincodeLogger.Debugf("deploy ?!! there's a chaincode with that name running: %s", chaincode)
 		chaincodeSupport.runningChaincodes.Unlock()
 		return cds, fmt.Errorf("deploy attempted but a chaincode with same name running %s", chaincode)
 	}
 	chaincodeSupport.runningChaincodes.Unlock()
 
-	args, envs, err := chaincodeSupport.getArgsAndEnv(cID)
+	args, envs, err := chaincodeSupport.getArgsAndEnv(key, getExecutable(cds, t.Uuid))
 	if err != nil {
 		return cds, fmt.Errorf("error getting args for chaincode %s", err)
 	}
 
 	var targz io.Reader = bytes.NewBuffer(cds.CodePackage)
-	cir := &container.CreateImageReq{CCID: ccintf.CCID{ChaincodeSpec: cds.ChaincodeSpec, NetworkID: chaincodeSupport.peerNetworkID, PeerID: chaincodeSupport.peerID}, Args: args, Reader: targz, Env: envs}
+	cir := &container.CreateImageReq{CCID: ccintf.CCID{ChaincodeSpec: cds.ChaincodeSpec, NetworkID: chaincodeSupport.peerNetworkID, PeerID: chaincodeSupport.peerID, ChainID: t.ChainID}, Args: args, Reader: targz, Env: envs}
 
 	vmtype, _ := chaincodeSupport.getVMType(cds)
 
@@ -590,6 +733,7 @@ func (chaincodeSupport *ChaincodeSupport) HandleChaincodeStream(ctxt context.Con
 
 // Register the bidi stream entry point called by chaincode to register with the Peer.
 func (chaincodeSupport *ChaincodeSupport) Register(stream pb.ChaincodeSupport_RegisterServer) error {
+	defer comm.TrackStream("chaincode")()
 	return chaincodeSupport.HandleChaincodeStream(stream.Context(), stream)
 }
 
@@ -613,10 +757,11 @@ func createQueryMessage(uuid string, cMsg *pb.ChaincodeInput) (*pb.ChaincodeMess
 }
 
 // Execute executes a transaction and waits for it to complete until a timeout value.
+// The chaincode runs on the chain of the transaction.
 func (chaincodeSupport *ChaincodeSupport) Execute(ctxt context.Context, chaincode string, msg *pb.ChaincodeMessage, timeout time.Duration, tx *pb.Transaction) (*pb.ChaincodeMessage, error) {
 	chaincodeSupport.runningChaincodes.Lock()
 	//we expect the chaincode to be running... sanity check
-	chrte, ok := chaincodeSupport.chaincodeHasBeenLaunched(chaincode)
+	chrte, ok := chaincodeSupport.chaincodeHasBeenLaunched(getChaincodeKey(tx.ChainID, chaincode))
 	if !ok {
 		chaincodeSupport.runningChaincodes.Unlock()
 		chaincodeLogger.Debugf("cannot execute-chaincode is not running: %s", chaincode)
@@ -629,12 +774,14 @@ func (chaincodeSupport *ChaincodeSupport) Execute(ctxt context.Context, chaincod
 	if notfy, err = chrte.handler.sendExecuteMessage(msg, tx); err != nil {
 		return nil, fmt.Errorf("Error sending %s: %s", msg.Type.String(), err)
 	}
+	defer executeDuration.With(msg.Type.String()).ObserveSince(time.Now())
 	var ccresp *pb.ChaincodeMessage
 	select {
 	case ccresp = <-notfy:
 		//response is sent to user or calling chaincode. ChaincodeMessage_ERROR and ChaincodeMessage_QUERY_ERROR
 		//are typically treated as error
 	case <-time.After(timeout):
+		timeouts.With("execute").Inc()
 		err = fmt.Errorf("Timeout expired while executing transaction")
 	}
 
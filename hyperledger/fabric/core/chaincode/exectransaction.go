@@ -19,6 +19,7 @@ package chaincode
 import (
 	"errors"
 	"fmt"
+	"sync"
 	"time"
 
 	"github.com/golang/protobuf/proto"
@@ -33,7 +34,7 @@ func Execute(ctxt context.Context, chain *ChaincodeSupport, t *pb.Transaction) (
 	var err error
 
 	// get a handle to ledger to mark the begin/finish of a tx
-	ledger, ledgerErr := ledger.GetLedger()
+	ledger, ledgerErr := chain.getLedger(t.ChainID)
 	if ledgerErr != nil {
 		return nil, nil, fmt.Errorf("Failed to get handle to ledger (%s)", ledgerErr)
 	}
@@ -48,6 +49,11 @@ func Execute(ctxt context.Context, chain *ChaincodeSupport, t *pb.Transaction) (
 	}
 
 	if t.Type == pb.Transaction_CHAINCODE_DEPLOY {
+		//do not build the image of a chaincode that cannot be launched anyway
+		if err := chain.checkNotTerminated(t.ChainID, t.Uuid); err != nil {
+			return nil, nil, fmt.Errorf("Failed to deploy chaincode spec(%s)", err)
+		}
+
 		_, err := chain.Deploy(ctxt, t)
 		if err != nil {
 			return nil, nil, fmt.Errorf("Failed to deploy chaincode spec(%s)", err)
@@ -56,6 +62,9 @@ func Execute(ctxt context.Context, chain *ChaincodeSupport, t *pb.Transaction) (
 		//launch and wait for ready
 		markTxBegin(ledger, t)
 		_, _, err = chain.Launch(ctxt, t)
+		if err == nil {
+			err = recordEffectiveDate(ledger, t)
+		}
 		if err != nil {
 			markTxFinish(ledger, t, false)
 			return nil, nil, fmt.Errorf("%s", err)
@@ -125,6 +134,24 @@ func Execute(ctxt context.Context, chain *ChaincodeSupport, t *pb.Transaction) (
 			return resp.Payload, nil, fmt.Errorf("receive a response for (%s) but in invalid state(%d)", t.Uuid, resp.Type)
 		}
 
+	} else if t.Type == pb.Transaction_CHAINCODE_UPGRADE {
+		//copy the state, deploy and launch the new version and call its Upgrade function
+		markTxBegin(ledger, t)
+		err = chain.executeUpgrade(ctxt, ledger, t)
+		if err != nil {
+			markTxFinish(ledger, t, false)
+			return nil, nil, fmt.Errorf("Failed to upgrade chaincode(%s)", err)
+		}
+		markTxFinish(ledger, t, true)
+	} else if t.Type == pb.Transaction_CHAINCODE_TERMINATE {
+		//the chaincode is stopped once the transaction is committed
+		markTxBegin(ledger, t)
+		err = chain.executeTerminate(ledger, t)
+		if err != nil {
+			markTxFinish(ledger, t, false)
+			return nil, nil, fmt.Errorf("Failed to terminate chaincode(%s)", err)
+		}
+		markTxFinish(ledger, t, true)
 	} else {
 		err = fmt.Errorf("Invalid transaction type %s", t.Type.String())
 	}
@@ -134,25 +161,107 @@ func Execute(ctxt context.Context, chain *ChaincodeSupport, t *pb.Transaction) (
 //ExecuteTransactions - will execute transactions on the array one by one
 //will return an array of errors one for each transaction. If the execution
 //succeeded, array element will be nil. returns []byte of state hash or
-//error
+//error. If parallel execution is enabled, the consecutive invokes of public
+//chaincodes are executed in parallel, see executeInParallel
 func ExecuteTransactions(ctxt context.Context, cname ChainName, xacts []*pb.Transaction) (stateHash []byte, ccevents []*pb.ChaincodeEvent, txerrs []error, err error) {
+	return ExecuteChainTransactions(ctxt, cname, "", xacts)
+}
+
+//ExecuteChainTransactions - ExecuteTransactions on the ledger of the chain
+//identified by chainID, the default chain if empty. All the transactions must
+//belong to the chain.
+func ExecuteChainTransactions(ctxt context.Context, cname ChainName, chainID string, xacts []*pb.Transaction) (stateHash []byte, ccevents []*pb.ChaincodeEvent, txerrs []error, err error) {
 	var chain = GetChain(cname)
 	if chain == nil {
 		// TODO: We should never get here, but otherwise a good reminder to better handle
 		panic(fmt.Sprintf("[ExecuteTransactions]Chain %s not found\n", cname))
 	}
+	return chain.ExecuteChainTransactions(ctxt, chainID, xacts)
+}
+
+//ExecuteChainTransactions - executes the transactions of the chain identified
+//by chainID with this chaincode support, see the function ExecuteChainTransactions
+func (chaincodeSupport *ChaincodeSupport) ExecuteChainTransactions(ctxt context.Context, chainID string, xacts []*pb.Transaction) (stateHash []byte, ccevents []*pb.ChaincodeEvent, txerrs []error, err error) {
+	for _, t := range xacts {
+		if t.ChainID != chainID {
+			return nil, nil, nil, fmt.Errorf("transaction %s of chain %q in a batch of chain %q", t.Uuid, t.ChainID, chainID)
+		}
+	}
+	var lgr *ledger.Ledger
+	lgr, err = chaincodeSupport.getLedger(chainID)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
 	txerrs = make([]error, len(xacts))
 	ccevents = make([]*pb.ChaincodeEvent, len(xacts))
+	for i := 0; i < len(xacts); {
+		j := i
+		uuids := make(map[string]bool)
+		for chaincodeSupport.parallelExecution && j < len(xacts) && canExecuteInParallel(xacts[j]) && !uuids[xacts[j].Uuid] {
+			uuids[xacts[j].Uuid] = true
+			j++
+		}
+		if j-i > 1 {
+			executeInParallel(ctxt, chaincodeSupport, lgr, xacts[i:j], ccevents[i:j], txerrs[i:j])
+			i = j
+			continue
+		}
+		_, ccevents[i], txerrs[i] = Execute(ctxt, chaincodeSupport, xacts[i])
+		i++
+	}
+
+	stateHash, err = lgr.GetTempStateHash()
+	return stateHash, ccevents, txerrs, err
+}
+
+// canExecuteInParallel returns true for the transactions whose chaincode is
+// known before executing them, public invokes
+func canExecuteInParallel(t *pb.Transaction) bool {
+	return t.Type == pb.Transaction_CHAINCODE_INVOKE && t.ConfidentialityLevel == pb.ConfidentialityLevel_PUBLIC
+}
+
+// executeInParallel executes the invokes with the result of their sequential
+// execution. A chaincode executes a transaction at a time, so the invokes of
+// each chaincode are executed in order while the chaincodes run in parallel.
+// Each invoke sees the state as of the beginning of the execution, then the
+// invokes finish in order: an invoke which read state changed by a previous
+// one, or which invokes another chaincode, is executed again.
+func executeInParallel(ctxt context.Context, chain *ChaincodeSupport, lgr *ledger.Ledger, xacts []*pb.Transaction, ccevents []*pb.ChaincodeEvent, txerrs []error) {
+	groups := make(map[string][]int)
+	var names []string
 	for i, t := range xacts {
-		_, ccevents[i], txerrs[i] = Execute(ctxt, chain, t)
+		cID := &pb.ChaincodeID{}
+		//a transaction without a valid chaincode ID fails anyway
+		proto.Unmarshal(t.ChaincodeID, cID)
+		if _, ok := groups[cID.Name]; !ok {
+			names = append(names, cID.Name)
+		}
+		groups[cID.Name] = append(groups[cID.Name], i)
 	}
+	chaincodeLogger.Debugf("Executing %d transactions of %d chaincodes in parallel", len(xacts), len(names))
 
-	var lgr *ledger.Ledger
-	lgr, err = ledger.GetLedger()
-	if err == nil {
-		stateHash, err = lgr.GetTempStateHash()
+	for _, t := range xacts {
+		lgr.TxBeginParallel(t.Uuid)
+	}
+	var wg sync.WaitGroup
+	for _, name := range names {
+		wg.Add(1)
+		go func(indexes []int) {
+			defer wg.Done()
+			for _, i := range indexes {
+				_, ccevents[i], txerrs[i] = Execute(ctxt, chain, xacts[i])
+			}
+		}(groups[name])
+	}
+	wg.Wait()
+
+	for i, t := range xacts {
+		if !lgr.TxFinishParallel(t.Uuid) {
+			chaincodeLogger.Debugf("[%s]Executing transaction again in order", shortuuid(t.Uuid))
+			_, ccevents[i], txerrs[i] = Execute(ctxt, chain, t)
+		}
 	}
-	return stateHash, ccevents, txerrs, err
 }
 
 // GetSecureContext returns the security context from the context object or error
@@ -17,6 +17,7 @@ limitations under the License.
 package chaincode
 
 import (
+	"bytes"
 	"fmt"
 	"io/ioutil"
 	"net"
@@ -58,8 +59,8 @@ func initMemSrvc() (net.Listener, error) {
 
 	ca.LogInit(ioutil.Discard, os.Stdout, os.Stdout, os.Stderr, os.Stdout)
 
-	aca := ca.NewACA()
 	eca := ca.NewECA()
+	aca := ca.NewACA(eca)
 	tca := ca.NewTCA(eca)
 	tlsca := ca.NewTLSCA(eca)
 
@@ -341,14 +342,14 @@ func executeDeployTransaction(t *testing.T, url string) {
 	_, err = deploy(ctxt, spec)
 	chaincodeID := spec.ChaincodeID.Name
 	if err != nil {
-		GetChain(DefaultChain).Stop(ctxt, &pb.ChaincodeDeploymentSpec{ChaincodeSpec: spec})
+		GetChain(DefaultChain).Stop(ctxt, "", &pb.ChaincodeDeploymentSpec{ChaincodeSpec: spec})
 		closeListenerAndSleep(lis)
 		t.Fail()
 		t.Logf("Error deploying <%s>: %s", chaincodeID, err)
 		return
 	}
 
-	GetChain(DefaultChain).Stop(ctxt, &pb.ChaincodeDeploymentSpec{ChaincodeSpec: spec})
+	GetChain(DefaultChain).Stop(ctxt, "", &pb.ChaincodeDeploymentSpec{ChaincodeSpec: spec})
 	closeListenerAndSleep(lis)
 }
 
@@ -429,10 +430,11 @@ func invokeExample02Transaction(ctxt context.Context, cID *pb.ChaincodeID, args
 	time.Sleep(time.Second)
 
 	if destroyImage {
-		GetChain(DefaultChain).Stop(ctxt, &pb.ChaincodeDeploymentSpec{ChaincodeSpec: spec})
+		GetChain(DefaultChain).Stop(ctxt, "", &pb.ChaincodeDeploymentSpec{ChaincodeSpec: spec})
 		dir := container.DestroyImageReq{CCID: ccintf.CCID{ChaincodeSpec: spec, NetworkID: GetChain(DefaultChain).peerNetworkID, PeerID: GetChain(DefaultChain).peerID}, Force: true, NoPrune: true}
 
-		_, err = container.VMCProcess(ctxt, container.DOCKER, dir)
+		vmtype, _ := GetChain(DefaultChain).getVMType(&pb.ChaincodeDeploymentSpec{ChaincodeSpec: spec})
+		_, err = container.VMCProcess(ctxt, vmtype, dir)
 		if err != nil {
 			err = fmt.Errorf("Error destroying image: %s", err)
 			return err
@@ -517,7 +519,7 @@ func TestExecuteInvokeTransaction(t *testing.T) {
 		t.Logf("Invoke test passed")
 	}
 
-	GetChain(DefaultChain).Stop(ctxt, &pb.ChaincodeDeploymentSpec{ChaincodeSpec: &pb.ChaincodeSpec{ChaincodeID: chaincodeID}})
+	GetChain(DefaultChain).Stop(ctxt, "", &pb.ChaincodeDeploymentSpec{ChaincodeSpec: &pb.ChaincodeSpec{ChaincodeID: chaincodeID}})
 
 	closeListenerAndSleep(lis)
 }
@@ -615,7 +617,7 @@ func TestExecuteQuery(t *testing.T) {
 	if err != nil {
 		t.Fail()
 		t.Logf("Error initializing chaincode %s(%s)", chaincodeID, err)
-		GetChain(DefaultChain).Stop(ctxt, &pb.ChaincodeDeploymentSpec{ChaincodeSpec: spec})
+		GetChain(DefaultChain).Stop(ctxt, "", &pb.ChaincodeDeploymentSpec{ChaincodeSpec: spec})
 		closeListenerAndSleep(lis)
 		return
 	}
@@ -646,7 +648,7 @@ func TestExecuteQuery(t *testing.T) {
 	//end := getNowMillis()
 	//fmt.Fprintf(os.Stderr, "Ending: %d\n", end)
 	//fmt.Fprintf(os.Stderr, "Elapsed : %d millis\n", end-start)
-	GetChain(DefaultChain).Stop(ctxt, &pb.ChaincodeDeploymentSpec{ChaincodeSpec: spec})
+	GetChain(DefaultChain).Stop(ctxt, "", &pb.ChaincodeDeploymentSpec{ChaincodeSpec: spec})
 	closeListenerAndSleep(lis)
 }
 
@@ -697,7 +699,7 @@ func TestExecuteInvokeInvalidTransaction(t *testing.T) {
 		errStr := err.Error()
 		t.Logf("Got error %s\n", errStr)
 		t.Logf("InvalidInvoke test passed")
-		GetChain(DefaultChain).Stop(ctxt, &pb.ChaincodeDeploymentSpec{ChaincodeSpec: &pb.ChaincodeSpec{ChaincodeID: chaincodeID}})
+		GetChain(DefaultChain).Stop(ctxt, "", &pb.ChaincodeDeploymentSpec{ChaincodeSpec: &pb.ChaincodeSpec{ChaincodeID: chaincodeID}})
 
 		closeListenerAndSleep(lis)
 		return
@@ -706,7 +708,7 @@ func TestExecuteInvokeInvalidTransaction(t *testing.T) {
 	t.Fail()
 	t.Logf("Error invoking transaction %s", err)
 
-	GetChain(DefaultChain).Stop(ctxt, &pb.ChaincodeDeploymentSpec{ChaincodeSpec: &pb.ChaincodeSpec{ChaincodeID: chaincodeID}})
+	GetChain(DefaultChain).Stop(ctxt, "", &pb.ChaincodeDeploymentSpec{ChaincodeSpec: &pb.ChaincodeSpec{ChaincodeID: chaincodeID}})
 
 	closeListenerAndSleep(lis)
 }
@@ -759,7 +761,7 @@ func TestExecuteInvalidQuery(t *testing.T) {
 	if err != nil {
 		t.Fail()
 		t.Logf("Error initializing chaincode %s(%s)", chaincodeID, err)
-		GetChain(DefaultChain).Stop(ctxt, &pb.ChaincodeDeploymentSpec{ChaincodeSpec: spec})
+		GetChain(DefaultChain).Stop(ctxt, "", &pb.ChaincodeDeploymentSpec{ChaincodeSpec: spec})
 		closeListenerAndSleep(lis)
 		return
 	}
@@ -778,7 +780,7 @@ func TestExecuteInvalidQuery(t *testing.T) {
 		t.Logf("This query should not have succeeded as it attempts to put state")
 	}
 
-	GetChain(DefaultChain).Stop(ctxt, &pb.ChaincodeDeploymentSpec{ChaincodeSpec: spec})
+	GetChain(DefaultChain).Stop(ctxt, "", &pb.ChaincodeDeploymentSpec{ChaincodeSpec: spec})
 	closeListenerAndSleep(lis)
 }
 
@@ -831,7 +833,7 @@ func TestChaincodeInvokeChaincode(t *testing.T) {
 	if err != nil {
 		t.Fail()
 		t.Logf("Error initializing chaincode %s(%s)", chaincodeID1, err)
-		GetChain(DefaultChain).Stop(ctxt, &pb.ChaincodeDeploymentSpec{ChaincodeSpec: spec1})
+		GetChain(DefaultChain).Stop(ctxt, "", &pb.ChaincodeDeploymentSpec{ChaincodeSpec: spec1})
 		closeListenerAndSleep(lis)
 		return
 	}
@@ -852,8 +854,8 @@ func TestChaincodeInvokeChaincode(t *testing.T) {
 	if err != nil {
 		t.Fail()
 		t.Logf("Error initializing chaincode %s(%s)", chaincodeID2, err)
-		GetChain(DefaultChain).Stop(ctxt, &pb.ChaincodeDeploymentSpec{ChaincodeSpec: spec1})
-		GetChain(DefaultChain).Stop(ctxt, &pb.ChaincodeDeploymentSpec{ChaincodeSpec: spec2})
+		GetChain(DefaultChain).Stop(ctxt, "", &pb.ChaincodeDeploymentSpec{ChaincodeSpec: spec1})
+		GetChain(DefaultChain).Stop(ctxt, "", &pb.ChaincodeDeploymentSpec{ChaincodeSpec: spec2})
 		closeListenerAndSleep(lis)
 		return
 	}
@@ -872,8 +874,8 @@ func TestChaincodeInvokeChaincode(t *testing.T) {
 	if err != nil {
 		t.Fail()
 		t.Logf("Error invoking <%s>: %s", chaincodeID2, err)
-		GetChain(DefaultChain).Stop(ctxt, &pb.ChaincodeDeploymentSpec{ChaincodeSpec: spec1})
-		GetChain(DefaultChain).Stop(ctxt, &pb.ChaincodeDeploymentSpec{ChaincodeSpec: spec2})
+		GetChain(DefaultChain).Stop(ctxt, "", &pb.ChaincodeDeploymentSpec{ChaincodeSpec: spec1})
+		GetChain(DefaultChain).Stop(ctxt, "", &pb.ChaincodeDeploymentSpec{ChaincodeSpec: spec2})
 		closeListenerAndSleep(lis)
 		return
 	}
@@ -883,14 +885,14 @@ func TestChaincodeInvokeChaincode(t *testing.T) {
 	if err != nil {
 		t.Fail()
 		t.Logf("Incorrect final state after transaction for <%s>: %s", chaincodeID1, err)
-		GetChain(DefaultChain).Stop(ctxt, &pb.ChaincodeDeploymentSpec{ChaincodeSpec: spec1})
-		GetChain(DefaultChain).Stop(ctxt, &pb.ChaincodeDeploymentSpec{ChaincodeSpec: spec2})
+		GetChain(DefaultChain).Stop(ctxt, "", &pb.ChaincodeDeploymentSpec{ChaincodeSpec: spec1})
+		GetChain(DefaultChain).Stop(ctxt, "", &pb.ChaincodeDeploymentSpec{ChaincodeSpec: spec2})
 		closeListenerAndSleep(lis)
 		return
 	}
 
-	GetChain(DefaultChain).Stop(ctxt, &pb.ChaincodeDeploymentSpec{ChaincodeSpec: spec1})
-	GetChain(DefaultChain).Stop(ctxt, &pb.ChaincodeDeploymentSpec{ChaincodeSpec: spec2})
+	GetChain(DefaultChain).Stop(ctxt, "", &pb.ChaincodeDeploymentSpec{ChaincodeSpec: spec1})
+	GetChain(DefaultChain).Stop(ctxt, "", &pb.ChaincodeDeploymentSpec{ChaincodeSpec: spec2})
 	closeListenerAndSleep(lis)
 }
 
@@ -944,7 +946,7 @@ func TestChaincodeInvokeChaincodeErrorCase(t *testing.T) {
 	if err != nil {
 		t.Fail()
 		t.Logf("Error initializing chaincode %s(%s)", chaincodeID1, err)
-		GetChain(DefaultChain).Stop(ctxt, &pb.ChaincodeDeploymentSpec{ChaincodeSpec: spec1})
+		GetChain(DefaultChain).Stop(ctxt, "", &pb.ChaincodeDeploymentSpec{ChaincodeSpec: spec1})
 		closeListenerAndSleep(lis)
 		return
 	}
@@ -965,8 +967,8 @@ func TestChaincodeInvokeChaincodeErrorCase(t *testing.T) {
 	if err != nil {
 		t.Fail()
 		t.Logf("Error initializing chaincode %s(%s)", chaincodeID2, err)
-		GetChain(DefaultChain).Stop(ctxt, &pb.ChaincodeDeploymentSpec{ChaincodeSpec: spec1})
-		GetChain(DefaultChain).Stop(ctxt, &pb.ChaincodeDeploymentSpec{ChaincodeSpec: spec2})
+		GetChain(DefaultChain).Stop(ctxt, "", &pb.ChaincodeDeploymentSpec{ChaincodeSpec: spec1})
+		GetChain(DefaultChain).Stop(ctxt, "", &pb.ChaincodeDeploymentSpec{ChaincodeSpec: spec2})
 		closeListenerAndSleep(lis)
 		return
 	}
@@ -984,8 +986,8 @@ func TestChaincodeInvokeChaincodeErrorCase(t *testing.T) {
 	if err == nil {
 		t.Fail()
 		t.Logf("Error invoking <%s>: %s", chaincodeID2, err)
-		GetChain(DefaultChain).Stop(ctxt, &pb.ChaincodeDeploymentSpec{ChaincodeSpec: spec1})
-		GetChain(DefaultChain).Stop(ctxt, &pb.ChaincodeDeploymentSpec{ChaincodeSpec: spec2})
+		GetChain(DefaultChain).Stop(ctxt, "", &pb.ChaincodeDeploymentSpec{ChaincodeSpec: spec1})
+		GetChain(DefaultChain).Stop(ctxt, "", &pb.ChaincodeDeploymentSpec{ChaincodeSpec: spec2})
 		closeListenerAndSleep(lis)
 		return
 	}
@@ -993,14 +995,14 @@ func TestChaincodeInvokeChaincodeErrorCase(t *testing.T) {
 	if strings.Index(err.Error(), "Incorrect number of arguments. Expecting 3") < 0 {
 		t.Fail()
 		t.Logf("Unexpected error %s", err)
-		GetChain(DefaultChain).Stop(ctxt, &pb.ChaincodeDeploymentSpec{ChaincodeSpec: spec1})
-		GetChain(DefaultChain).Stop(ctxt, &pb.ChaincodeDeploymentSpec{ChaincodeSpec: spec2})
+		GetChain(DefaultChain).Stop(ctxt, "", &pb.ChaincodeDeploymentSpec{ChaincodeSpec: spec1})
+		GetChain(DefaultChain).Stop(ctxt, "", &pb.ChaincodeDeploymentSpec{ChaincodeSpec: spec2})
 		closeListenerAndSleep(lis)
 		return
 	}
 
-	GetChain(DefaultChain).Stop(ctxt, &pb.ChaincodeDeploymentSpec{ChaincodeSpec: spec1})
-	GetChain(DefaultChain).Stop(ctxt, &pb.ChaincodeDeploymentSpec{ChaincodeSpec: spec2})
+	GetChain(DefaultChain).Stop(ctxt, "", &pb.ChaincodeDeploymentSpec{ChaincodeSpec: spec1})
+	GetChain(DefaultChain).Stop(ctxt, "", &pb.ChaincodeDeploymentSpec{ChaincodeSpec: spec2})
 	closeListenerAndSleep(lis)
 }
 
@@ -1019,7 +1021,7 @@ func chaincodeQueryChaincode(user string) error {
 	_, err := deploy(ctxt, spec1)
 	chaincodeID1 := spec1.ChaincodeID.Name
 	if err != nil {
-		GetChain(DefaultChain).Stop(ctxt, &pb.ChaincodeDeploymentSpec{ChaincodeSpec: spec1})
+		GetChain(DefaultChain).Stop(ctxt, "", &pb.ChaincodeDeploymentSpec{ChaincodeSpec: spec1})
 		return fmt.Errorf("Error initializing chaincode %s(%s)", chaincodeID1, err)
 	}
 
@@ -1037,8 +1039,8 @@ func chaincodeQueryChaincode(user string) error {
 	_, err = deploy(ctxt, spec2)
 	chaincodeID2 := spec2.ChaincodeID.Name
 	if err != nil {
-		GetChain(DefaultChain).Stop(ctxt, &pb.ChaincodeDeploymentSpec{ChaincodeSpec: spec1})
-		GetChain(DefaultChain).Stop(ctxt, &pb.ChaincodeDeploymentSpec{ChaincodeSpec: spec2})
+		GetChain(DefaultChain).Stop(ctxt, "", &pb.ChaincodeDeploymentSpec{ChaincodeSpec: spec1})
+		GetChain(DefaultChain).Stop(ctxt, "", &pb.ChaincodeDeploymentSpec{ChaincodeSpec: spec2})
 		return fmt.Errorf("Error initializing chaincode %s(%s)", chaincodeID2, err)
 	}
 
@@ -1054,16 +1056,16 @@ func chaincodeQueryChaincode(user string) error {
 	_, _, retVal, err = invoke(ctxt, spec2, pb.Transaction_CHAINCODE_INVOKE)
 
 	if err != nil {
-		GetChain(DefaultChain).Stop(ctxt, &pb.ChaincodeDeploymentSpec{ChaincodeSpec: spec1})
-		GetChain(DefaultChain).Stop(ctxt, &pb.ChaincodeDeploymentSpec{ChaincodeSpec: spec2})
+		GetChain(DefaultChain).Stop(ctxt, "", &pb.ChaincodeDeploymentSpec{ChaincodeSpec: spec1})
+		GetChain(DefaultChain).Stop(ctxt, "", &pb.ChaincodeDeploymentSpec{ChaincodeSpec: spec2})
 		return fmt.Errorf("Error invoking <%s>: %s", chaincodeID2, err)
 	}
 
 	// Check the return value
 	result, err := strconv.Atoi(string(retVal))
 	if err != nil || result != 300 {
-		GetChain(DefaultChain).Stop(ctxt, &pb.ChaincodeDeploymentSpec{ChaincodeSpec: spec1})
-		GetChain(DefaultChain).Stop(ctxt, &pb.ChaincodeDeploymentSpec{ChaincodeSpec: spec2})
+		GetChain(DefaultChain).Stop(ctxt, "", &pb.ChaincodeDeploymentSpec{ChaincodeSpec: spec1})
+		GetChain(DefaultChain).Stop(ctxt, "", &pb.ChaincodeDeploymentSpec{ChaincodeSpec: spec2})
 		return fmt.Errorf("Incorrect final state after transaction for <%s>: %s", chaincodeID1, err)
 	}
 
@@ -1076,21 +1078,21 @@ func chaincodeQueryChaincode(user string) error {
 	_, _, retVal, err = invoke(ctxt, spec2, pb.Transaction_CHAINCODE_QUERY)
 
 	if err != nil {
-		GetChain(DefaultChain).Stop(ctxt, &pb.ChaincodeDeploymentSpec{ChaincodeSpec: spec1})
-		GetChain(DefaultChain).Stop(ctxt, &pb.ChaincodeDeploymentSpec{ChaincodeSpec: spec2})
+		GetChain(DefaultChain).Stop(ctxt, "", &pb.ChaincodeDeploymentSpec{ChaincodeSpec: spec1})
+		GetChain(DefaultChain).Stop(ctxt, "", &pb.ChaincodeDeploymentSpec{ChaincodeSpec: spec2})
 		return fmt.Errorf("Error querying <%s>: %s", chaincodeID2, err)
 	}
 
 	// Check the return value
 	result, err = strconv.Atoi(string(retVal))
 	if err != nil || result != 300 {
-		GetChain(DefaultChain).Stop(ctxt, &pb.ChaincodeDeploymentSpec{ChaincodeSpec: spec1})
-		GetChain(DefaultChain).Stop(ctxt, &pb.ChaincodeDeploymentSpec{ChaincodeSpec: spec2})
+		GetChain(DefaultChain).Stop(ctxt, "", &pb.ChaincodeDeploymentSpec{ChaincodeSpec: spec1})
+		GetChain(DefaultChain).Stop(ctxt, "", &pb.ChaincodeDeploymentSpec{ChaincodeSpec: spec2})
 		return fmt.Errorf("Incorrect final value after query for <%s>: %s", chaincodeID1, err)
 	}
 
-	GetChain(DefaultChain).Stop(ctxt, &pb.ChaincodeDeploymentSpec{ChaincodeSpec: spec1})
-	GetChain(DefaultChain).Stop(ctxt, &pb.ChaincodeDeploymentSpec{ChaincodeSpec: spec2})
+	GetChain(DefaultChain).Stop(ctxt, "", &pb.ChaincodeDeploymentSpec{ChaincodeSpec: spec1})
+	GetChain(DefaultChain).Stop(ctxt, "", &pb.ChaincodeDeploymentSpec{ChaincodeSpec: spec2})
 
 	return nil
 }
@@ -1165,7 +1167,7 @@ func TestChaincodeQueryChaincodeErrorCase(t *testing.T) {
 	if err != nil {
 		t.Fail()
 		t.Logf("Error initializing chaincode %s(%s)", chaincodeID1, err)
-		GetChain(DefaultChain).Stop(ctxt, &pb.ChaincodeDeploymentSpec{ChaincodeSpec: spec1})
+		GetChain(DefaultChain).Stop(ctxt, "", &pb.ChaincodeDeploymentSpec{ChaincodeSpec: spec1})
 		closeListenerAndSleep(lis)
 		return
 	}
@@ -1186,8 +1188,8 @@ func TestChaincodeQueryChaincodeErrorCase(t *testing.T) {
 	if err != nil {
 		t.Fail()
 		t.Logf("Error initializing chaincode %s(%s)", chaincodeID2, err)
-		GetChain(DefaultChain).Stop(ctxt, &pb.ChaincodeDeploymentSpec{ChaincodeSpec: spec1})
-		GetChain(DefaultChain).Stop(ctxt, &pb.ChaincodeDeploymentSpec{ChaincodeSpec: spec2})
+		GetChain(DefaultChain).Stop(ctxt, "", &pb.ChaincodeDeploymentSpec{ChaincodeSpec: spec1})
+		GetChain(DefaultChain).Stop(ctxt, "", &pb.ChaincodeDeploymentSpec{ChaincodeSpec: spec2})
 		closeListenerAndSleep(lis)
 		return
 	}
@@ -1205,8 +1207,8 @@ func TestChaincodeQueryChaincodeErrorCase(t *testing.T) {
 	if err == nil {
 		t.Fail()
 		t.Logf("Error invoking <%s>: %s", chaincodeID2, err)
-		GetChain(DefaultChain).Stop(ctxt, &pb.ChaincodeDeploymentSpec{ChaincodeSpec: spec1})
-		GetChain(DefaultChain).Stop(ctxt, &pb.ChaincodeDeploymentSpec{ChaincodeSpec: spec2})
+		GetChain(DefaultChain).Stop(ctxt, "", &pb.ChaincodeDeploymentSpec{ChaincodeSpec: spec1})
+		GetChain(DefaultChain).Stop(ctxt, "", &pb.ChaincodeDeploymentSpec{ChaincodeSpec: spec2})
 		closeListenerAndSleep(lis)
 		return
 	}
@@ -1214,14 +1216,14 @@ func TestChaincodeQueryChaincodeErrorCase(t *testing.T) {
 	if strings.Index(err.Error(), "Nil amount for c") < 0 {
 		t.Fail()
 		t.Logf("Unexpected error %s", err)
-		GetChain(DefaultChain).Stop(ctxt, &pb.ChaincodeDeploymentSpec{ChaincodeSpec: spec1})
-		GetChain(DefaultChain).Stop(ctxt, &pb.ChaincodeDeploymentSpec{ChaincodeSpec: spec2})
+		GetChain(DefaultChain).Stop(ctxt, "", &pb.ChaincodeDeploymentSpec{ChaincodeSpec: spec1})
+		GetChain(DefaultChain).Stop(ctxt, "", &pb.ChaincodeDeploymentSpec{ChaincodeSpec: spec2})
 		closeListenerAndSleep(lis)
 		return
 	}
 
-	GetChain(DefaultChain).Stop(ctxt, &pb.ChaincodeDeploymentSpec{ChaincodeSpec: spec1})
-	GetChain(DefaultChain).Stop(ctxt, &pb.ChaincodeDeploymentSpec{ChaincodeSpec: spec2})
+	GetChain(DefaultChain).Stop(ctxt, "", &pb.ChaincodeDeploymentSpec{ChaincodeSpec: spec1})
+	GetChain(DefaultChain).Stop(ctxt, "", &pb.ChaincodeDeploymentSpec{ChaincodeSpec: spec2})
 	closeListenerAndSleep(lis)
 }
 
@@ -1327,7 +1329,7 @@ func TestRangeQuery(t *testing.T) {
 	if err != nil {
 		t.Fail()
 		t.Logf("Error initializing chaincode %s(%s)", chaincodeID, err)
-		GetChain(DefaultChain).Stop(ctxt, &pb.ChaincodeDeploymentSpec{ChaincodeSpec: spec})
+		GetChain(DefaultChain).Stop(ctxt, "", &pb.ChaincodeDeploymentSpec{ChaincodeSpec: spec})
 		closeListenerAndSleep(lis)
 		return
 	}
@@ -1342,14 +1344,101 @@ func TestRangeQuery(t *testing.T) {
 	if err != nil {
 		t.Fail()
 		t.Logf("Error invoking <%s>: %s", chaincodeID, err)
-		GetChain(DefaultChain).Stop(ctxt, &pb.ChaincodeDeploymentSpec{ChaincodeSpec: spec})
+		GetChain(DefaultChain).Stop(ctxt, "", &pb.ChaincodeDeploymentSpec{ChaincodeSpec: spec})
 		closeListenerAndSleep(lis)
 		return
 	}
-	GetChain(DefaultChain).Stop(ctxt, &pb.ChaincodeDeploymentSpec{ChaincodeSpec: spec})
+	GetChain(DefaultChain).Stop(ctxt, "", &pb.ChaincodeDeploymentSpec{ChaincodeSpec: spec})
 	closeListenerAndSleep(lis)
 }
 
+func getExample02Value(chaincodeID string, key string) (int, error) {
+	ledgerObj, err := ledger.GetLedger()
+	if err != nil {
+		return 0, err
+	}
+	resbytes, err := ledgerObj.GetState(chaincodeID, key, true)
+	if err != nil {
+		return 0, err
+	}
+	return strconv.Atoi(string(resbytes))
+}
+
+func TestExecuteTransactionsInParallel(t *testing.T) {
+	lis, err := initPeer()
+	if err != nil {
+		t.Fail()
+		t.Logf("Error creating peer: %s", err)
+	}
+
+	defer finitPeer(lis)
+
+	var ctxt = context.Background()
+	chain := GetChain(DefaultChain)
+
+	url := "github.com/hyperledger/fabric/examples/chaincode/go/chaincode_example02"
+	var specs []*pb.ChaincodeSpec
+	for _, args := range [][]string{{"a", "100", "b", "200"}, {"a", "1000", "b", "2000"}} {
+		spec := &pb.ChaincodeSpec{Type: 1, ChaincodeID: &pb.ChaincodeID{Path: url}, CtorMsg: &pb.ChaincodeInput{Function: "init", Args: args}}
+		if _, err = deploy(ctxt, spec); err != nil {
+			t.Fail()
+			t.Logf("Error deploying <%s>: %s", spec.ChaincodeID.Name, err)
+		}
+		defer chain.Stop(ctxt, "", &pb.ChaincodeDeploymentSpec{ChaincodeSpec: spec})
+		specs = append(specs, spec)
+	}
+	if t.Failed() {
+		return
+	}
+
+	// the second invoke of each chaincode reads state changed by the first one
+	var xacts []*pb.Transaction
+	for _, invoke := range []struct {
+		spec *pb.ChaincodeSpec
+		args []string
+	}{{specs[0], []string{"a", "b", "10"}}, {specs[1], []string{"a", "b", "10"}}, {specs[0], []string{"a", "b", "10"}}, {specs[1], []string{"b", "a", "100"}}} {
+		spec := &pb.ChaincodeSpec{Type: 1, ChaincodeID: invoke.spec.ChaincodeID, CtorMsg: &pb.ChaincodeInput{Function: "invoke", Args: invoke.args}}
+		tx, err := createTransaction(true, &pb.ChaincodeInvocationSpec{ChaincodeSpec: spec}, util.GenerateUUID())
+		if err != nil {
+			t.Fatalf("Error creating transaction: %s", err)
+		}
+		xacts = append(xacts, tx)
+	}
+
+	ledgerObj, _ := ledger.GetLedger()
+	defer func() { chain.parallelExecution = viper.GetBool("chaincode.parallelexecution") }()
+	var stateHashes [][]byte
+	for _, parallel := range []bool{false, true} {
+		chain.parallelExecution = parallel
+		ledgerObj.BeginTxBatch("1")
+		stateHash, _, txerrs, err := ExecuteTransactions(ctxt, DefaultChain, xacts)
+		if err != nil {
+			t.Fatalf("Error executing transactions (parallel=%t): %s", parallel, err)
+		}
+		for i, txerr := range txerrs {
+			if txerr != nil {
+				t.Fatalf("Error executing transaction %d (parallel=%t): %s", i, parallel, txerr)
+			}
+		}
+		stateHashes = append(stateHashes, stateHash)
+		if !parallel {
+			ledgerObj.RollbackTxBatch("1")
+		}
+	}
+	if !bytes.Equal(stateHashes[0], stateHashes[1]) {
+		t.Fatalf("Expected the state hash of the parallel execution to be the same as the sequential one")
+	}
+	ledgerObj.CommitTxBatch("1", xacts, nil, nil)
+
+	for i, expected := range []map[string]int{{"a": 80, "b": 220}, {"a": 1090, "b": 1910}} {
+		for key, value := range expected {
+			if actual, err := getExample02Value(specs[i].ChaincodeID.Name, key); err != nil || actual != value {
+				t.Fatalf("Expected %s=%d for chaincode %d but got %d(%v)", key, value, i, actual, err)
+			}
+		}
+	}
+}
+
 func TestGetEvent(t *testing.T) {
 	var opts []grpc.ServerOption
 	if viper.GetBool("peer.tls.enabled") {
@@ -1394,7 +1483,7 @@ func TestGetEvent(t *testing.T) {
 	if err != nil {
 		t.Fail()
 		t.Logf("Error initializing chaincode %s(%s)", chaincodeID, err)
-		GetChain(DefaultChain).Stop(ctxt, &pb.ChaincodeDeploymentSpec{ChaincodeSpec: spec})
+		GetChain(DefaultChain).Stop(ctxt, "", &pb.ChaincodeDeploymentSpec{ChaincodeSpec: spec})
 		closeListenerAndSleep(lis)
 		return
 	}
@@ -1428,7 +1517,7 @@ func TestGetEvent(t *testing.T) {
 		t.Fail()
 	}
 
-	GetChain(DefaultChain).Stop(ctxt, &pb.ChaincodeDeploymentSpec{ChaincodeSpec: spec})
+	GetChain(DefaultChain).Stop(ctxt, "", &pb.ChaincodeDeploymentSpec{ChaincodeSpec: spec})
 	closeListenerAndSleep(lis)
 }
 
@@ -134,6 +134,20 @@ func (handler *Handler) getTxContext(uuid string) *transactionContext {
 	return handler.txCtxs[uuid]
 }
 
+// getChainID returns the chain of the transaction, empty for the default chain
+func (handler *Handler) getChainID(uuid string) string {
+	txctx := handler.getTxContext(uuid)
+	if txctx == nil || txctx.transactionSecContext == nil {
+		return ""
+	}
+	return txctx.transactionSecContext.ChainID
+}
+
+// getLedger returns the ledger of the chain of the transaction
+func (handler *Handler) getLedger(uuid string) (*ledger.Ledger, error) {
+	return handler.chaincodeSupport.getLedger(handler.getChainID(uuid))
+}
+
 func (handler *Handler) deleteTxContext(uuid string) {
 	handler.Lock()
 	defer handler.Unlock()
@@ -236,6 +250,17 @@ func (handler *Handler) encryptOrDecrypt(encrypt bool, uuid string, payload []by
 	return payload, err
 }
 
+// isConfidential returns true if the state of the chaincode is encrypted for
+// the transaction
+func (handler *Handler) isConfidential(uuid string) bool {
+	if handler.chaincodeSupport.getSecHelper() == nil {
+		return false
+	}
+	txctx := handler.getTxContext(uuid)
+	return txctx != nil && txctx.transactionSecContext != nil &&
+		txctx.transactionSecContext.ConfidentialityLevel == pb.ConfidentialityLevel_CONFIDENTIAL
+}
+
 func (handler *Handler) decrypt(uuid string, payload []byte) ([]byte, error) {
 	return handler.encryptOrDecrypt(false, uuid, payload)
 }
@@ -387,6 +412,7 @@ func newChaincodeSupportHandler(chaincodeSupport *ChaincodeSupport, peerChatStre
 			//Send REGISTERED, then, if deploy { trigger INIT(via INIT) } else { trigger READY(via COMPLETED) }
 			{Name: pb.ChaincodeMessage_REGISTER.String(), Src: []string{createdstate}, Dst: establishedstate},
 			{Name: pb.ChaincodeMessage_INIT.String(), Src: []string{establishedstate}, Dst: initstate},
+			{Name: pb.ChaincodeMessage_UPGRADE.String(), Src: []string{establishedstate}, Dst: initstate},
 			{Name: pb.ChaincodeMessage_READY.String(), Src: []string{establishedstate}, Dst: readystate},
 			{Name: pb.ChaincodeMessage_TRANSACTION.String(), Src: []string{readystate}, Dst: transactionstate},
 			{Name: pb.ChaincodeMessage_PUT_STATE.String(), Src: []string{transactionstate}, Dst: busyxactstate},
@@ -416,6 +442,16 @@ func newChaincodeSupportHandler(chaincodeSupport *ChaincodeSupport, peerChatStre
 			{Name: pb.ChaincodeMessage_RANGE_QUERY_STATE_CLOSE.String(), Src: []string{busyinitstate}, Dst: busyinitstate},
 			{Name: pb.ChaincodeMessage_RANGE_QUERY_STATE_CLOSE.String(), Src: []string{transactionstate}, Dst: transactionstate},
 			{Name: pb.ChaincodeMessage_RANGE_QUERY_STATE_CLOSE.String(), Src: []string{busyxactstate}, Dst: busyxactstate},
+			{Name: pb.ChaincodeMessage_GET_HISTORY_FOR_KEY.String(), Src: []string{readystate}, Dst: readystate},
+			{Name: pb.ChaincodeMessage_GET_HISTORY_FOR_KEY.String(), Src: []string{initstate}, Dst: initstate},
+			{Name: pb.ChaincodeMessage_GET_HISTORY_FOR_KEY.String(), Src: []string{busyinitstate}, Dst: busyinitstate},
+			{Name: pb.ChaincodeMessage_GET_HISTORY_FOR_KEY.String(), Src: []string{transactionstate}, Dst: transactionstate},
+			{Name: pb.ChaincodeMessage_GET_HISTORY_FOR_KEY.String(), Src: []string{busyxactstate}, Dst: busyxactstate},
+			{Name: pb.ChaincodeMessage_GET_QUERY_RESULT.String(), Src: []string{readystate}, Dst: readystate},
+			{Name: pb.ChaincodeMessage_GET_QUERY_RESULT.String(), Src: []string{initstate}, Dst: initstate},
+			{Name: pb.ChaincodeMessage_GET_QUERY_RESULT.String(), Src: []string{busyinitstate}, Dst: busyinitstate},
+			{Name: pb.ChaincodeMessage_GET_QUERY_RESULT.String(), Src: []string{transactionstate}, Dst: transactionstate},
+			{Name: pb.ChaincodeMessage_GET_QUERY_RESULT.String(), Src: []string{busyxactstate}, Dst: busyxactstate},
 			{Name: pb.ChaincodeMessage_ERROR.String(), Src: []string{initstate}, Dst: endstate},
 			{Name: pb.ChaincodeMessage_ERROR.String(), Src: []string{transactionstate}, Dst: readystate},
 			{Name: pb.ChaincodeMessage_ERROR.String(), Src: []string{busyinitstate}, Dst: initstate},
@@ -427,10 +463,13 @@ func newChaincodeSupportHandler(chaincodeSupport *ChaincodeSupport, peerChatStre
 			"before_" + pb.ChaincodeMessage_REGISTER.String():               func(e *fsm.Event) { v.beforeRegisterEvent(e, v.FSM.Current()) },
 			"before_" + pb.ChaincodeMessage_COMPLETED.String():              func(e *fsm.Event) { v.beforeCompletedEvent(e, v.FSM.Current()) },
 			"before_" + pb.ChaincodeMessage_INIT.String():                   func(e *fsm.Event) { v.beforeInitState(e, v.FSM.Current()) },
+			"before_" + pb.ChaincodeMessage_UPGRADE.String():                func(e *fsm.Event) { v.beforeInitState(e, v.FSM.Current()) },
 			"after_" + pb.ChaincodeMessage_GET_STATE.String():               func(e *fsm.Event) { v.afterGetState(e, v.FSM.Current()) },
 			"after_" + pb.ChaincodeMessage_RANGE_QUERY_STATE.String():       func(e *fsm.Event) { v.afterRangeQueryState(e, v.FSM.Current()) },
 			"after_" + pb.ChaincodeMessage_RANGE_QUERY_STATE_NEXT.String():  func(e *fsm.Event) { v.afterRangeQueryStateNext(e, v.FSM.Current()) },
 			"after_" + pb.ChaincodeMessage_RANGE_QUERY_STATE_CLOSE.String(): func(e *fsm.Event) { v.afterRangeQueryStateClose(e, v.FSM.Current()) },
+			"after_" + pb.ChaincodeMessage_GET_HISTORY_FOR_KEY.String():     func(e *fsm.Event) { v.afterGetHistoryForKey(e, v.FSM.Current()) },
+			"after_" + pb.ChaincodeMessage_GET_QUERY_RESULT.String():        func(e *fsm.Event) { v.afterGetQueryResult(e, v.FSM.Current()) },
 			"after_" + pb.ChaincodeMessage_PUT_STATE.String():               func(e *fsm.Event) { v.afterPutState(e, v.FSM.Current()) },
 			"after_" + pb.ChaincodeMessage_DEL_STATE.String():               func(e *fsm.Event) { v.afterDelState(e, v.FSM.Current()) },
 			"after_" + pb.ChaincodeMessage_INVOKE_CHAINCODE.String():        func(e *fsm.Event) { v.afterInvokeChaincode(e, v.FSM.Current()) },
@@ -568,6 +607,18 @@ func (handler *Handler) beforeCompletedEvent(e *fsm.Event, state string) {
 	return
 }
 
+// getStateNamespace returns the state namespace of the chaincode version run by
+// the handler
+func (handler *Handler) getStateNamespace() string {
+	var version uint64
+	handler.chaincodeSupport.runningChaincodes.Lock()
+	if chrte, ok := handler.chaincodeSupport.chaincodeHasBeenLaunched(handler.ChaincodeID.Name); ok {
+		version = chrte.version
+	}
+	handler.chaincodeSupport.runningChaincodes.Unlock()
+	return getStateNamespace(getChaincodeName(handler.ChaincodeID.Name), version)
+}
+
 // beforeInitState is invoked before an init message is sent to the chaincode.
 func (handler *Handler) beforeInitState(e *fsm.Event, state string) {
 	chaincodeLogger.Debugf("Before state %s.. notifying waiter that we are up", state)
@@ -610,7 +661,7 @@ func (handler *Handler) handleGetState(msg *pb.ChaincodeMessage) {
 		}()
 
 		key := string(msg.Payload)
-		ledgerObj, ledgerErr := ledger.GetLedger()
+		ledgerObj, ledgerErr := handler.getLedger(msg.Uuid)
 		if ledgerErr != nil {
 			// Send error msg back to chaincode. GetState will not trigger event
 			payload := []byte(ledgerErr.Error())
@@ -621,10 +672,10 @@ func (handler *Handler) handleGetState(msg *pb.ChaincodeMessage) {
 		}
 
 		// Invoke ledger to get state
-		chaincodeID := handler.ChaincodeID.Name
+		namespace := handler.getStateNamespace()
 
 		readCommittedState := !handler.getIsTransaction(msg.Uuid)
-		res, err := ledgerObj.GetState(chaincodeID, key, readCommittedState)
+		res, err := ledgerObj.GetTxState(msg.Uuid, namespace, key, readCommittedState)
 		if err != nil {
 			// Send error msg back to chaincode. GetState will not trigger event
 			payload := []byte(err.Error())
@@ -701,7 +752,7 @@ func (handler *Handler) handleRangeQueryState(msg *pb.ChaincodeMessage) {
 
 		hasNext := true
 
-		ledger, ledgerErr := ledger.GetLedger()
+		ledger, ledgerErr := handler.getLedger(msg.Uuid)
 		if ledgerErr != nil {
 			// Send error msg back to chaincode. GetState will not trigger event
 			payload := []byte(ledgerErr.Error())
@@ -710,10 +761,10 @@ func (handler *Handler) handleRangeQueryState(msg *pb.ChaincodeMessage) {
 			return
 		}
 
-		chaincodeID := handler.ChaincodeID.Name
+		namespace := handler.getStateNamespace()
 
 		readCommittedState := !handler.getIsTransaction(msg.Uuid)
-		rangeIter, err := ledger.GetStateRangeScanIterator(chaincodeID, rangeQueryState.StartKey, rangeQueryState.EndKey, readCommittedState)
+		rangeIter, err := ledger.GetTxStateRangeScanIterator(msg.Uuid, namespace, rangeQueryState.StartKey, rangeQueryState.EndKey, readCommittedState)
 		if err != nil {
 			// Send error msg back to chaincode. GetState will not trigger event
 			payload := []byte(err.Error())
@@ -945,6 +996,238 @@ func (handler *Handler) handleRangeQueryStateClose(msg *pb.ChaincodeMessage) {
 	}()
 }
 
+// afterGetHistoryForKey handles a GET_HISTORY_FOR_KEY request from the chaincode.
+func (handler *Handler) afterGetHistoryForKey(e *fsm.Event, state string) {
+	msg, ok := e.Args[0].(*pb.ChaincodeMessage)
+	if !ok {
+		e.Cancel(fmt.Errorf("Received unexpected message type"))
+		return
+	}
+	chaincodeLogger.Debugf("[%s]Received %s, invoking get history from ledger", shortuuid(msg.Uuid), pb.ChaincodeMessage_GET_HISTORY_FOR_KEY)
+
+	// Query ledger for history
+	handler.handleGetHistoryForKey(msg)
+	chaincodeLogger.Debug("Exiting GET_HISTORY_FOR_KEY")
+}
+
+// Handles query to ledger to get the committed changes of a key. The history
+// only contains committed changes, so the whole history is sent at once. Like
+// rich queries, history queries are not allowed in transactions.
+func (handler *Handler) handleGetHistoryForKey(msg *pb.ChaincodeMessage) {
+	// The defer followed by triggering a go routine dance is needed to ensure that the previous state transition
+	// is completed before the next one is triggered. The previous state transition is deemed complete only when
+	// the afterGetHistoryForKey function is exited. Interesting bug fix!!
+	go func() {
+		// Check if this is the unique state request from this chaincode uuid
+		uniqueReq := handler.createUUIDEntry(msg.Uuid)
+		if !uniqueReq {
+			// Drop this request
+			chaincodeLogger.Debug("Another state request pending for this Uuid. Cannot process.")
+			return
+		}
+
+		var serialSendMsg *pb.ChaincodeMessage
+
+		defer func() {
+			handler.deleteUUIDEntry(msg.Uuid)
+			chaincodeLogger.Debugf("[%s]handleGetHistoryForKey serial send %s", shortuuid(serialSendMsg.Uuid), serialSendMsg.Type)
+			handler.serialSend(serialSendMsg)
+		}()
+
+		getHistoryForKey := &pb.GetHistoryForKey{}
+		unmarshalErr := proto.Unmarshal(msg.Payload, getHistoryForKey)
+		if unmarshalErr != nil {
+			payload := []byte(unmarshalErr.Error())
+			chaincodeLogger.Debugf("Failed to unmarshall history query request. Sending %s", pb.ChaincodeMessage_ERROR)
+			serialSendMsg = &pb.ChaincodeMessage{Type: pb.ChaincodeMessage_ERROR, Payload: payload, Uuid: msg.Uuid}
+			return
+		}
+
+		if handler.getIsTransaction(msg.Uuid) {
+			payload := []byte("GetHistoryForKey is only supported in queries")
+			chaincodeLogger.Debugf("History query requested by a transaction. Sending %s", pb.ChaincodeMessage_ERROR)
+			serialSendMsg = &pb.ChaincodeMessage{Type: pb.ChaincodeMessage_ERROR, Payload: payload, Uuid: msg.Uuid}
+			return
+		}
+
+		ledger, ledgerErr := handler.getLedger(msg.Uuid)
+		if ledgerErr != nil {
+			payload := []byte(ledgerErr.Error())
+			chaincodeLogger.Debugf("Failed to get ledger. Sending %s", pb.ChaincodeMessage_ERROR)
+			serialSendMsg = &pb.ChaincodeMessage{Type: pb.ChaincodeMessage_ERROR, Payload: payload, Uuid: msg.Uuid}
+			return
+		}
+
+		namespace := handler.getStateNamespace()
+
+		historyIter, err := ledger.GetHistoryForKey(namespace, getHistoryForKey.Key)
+		if err != nil {
+			payload := []byte(err.Error())
+			chaincodeLogger.Debugf("Failed to get ledger history iterator. Sending %s", pb.ChaincodeMessage_ERROR)
+			serialSendMsg = &pb.ChaincodeMessage{Type: pb.ChaincodeMessage_ERROR, Payload: payload, Uuid: msg.Uuid}
+			return
+		}
+		defer historyIter.Close()
+
+		var modifications []*pb.KeyModification
+		for historyIter.Next() {
+			modification := historyIter.GetKeyModification()
+			if !modification.IsDelete {
+				// Decrypt the data if the confidential is enabled
+				decryptedValue, decryptErr := handler.decrypt(msg.Uuid, modification.Value)
+				if decryptErr != nil {
+					payload := []byte(decryptErr.Error())
+					chaincodeLogger.Debugf("Failed decrypt value. Sending %s", pb.ChaincodeMessage_ERROR)
+					serialSendMsg = &pb.ChaincodeMessage{Type: pb.ChaincodeMessage_ERROR, Payload: payload, Uuid: msg.Uuid}
+					return
+				}
+				modification.Value = decryptedValue
+			}
+			modifications = append(modifications, modification)
+		}
+		if err = historyIter.Err(); err != nil {
+			payload := []byte(err.Error())
+			chaincodeLogger.Debugf("Failed to read history. Sending %s", pb.ChaincodeMessage_ERROR)
+			serialSendMsg = &pb.ChaincodeMessage{Type: pb.ChaincodeMessage_ERROR, Payload: payload, Uuid: msg.Uuid}
+			return
+		}
+
+		payload := &pb.HistoryQueryResponse{Modifications: modifications}
+		payloadBytes, err := proto.Marshal(payload)
+		if err != nil {
+			payload := []byte(err.Error())
+			chaincodeLogger.Debugf("Failed marshall resopnse. Sending %s", pb.ChaincodeMessage_ERROR)
+			serialSendMsg = &pb.ChaincodeMessage{Type: pb.ChaincodeMessage_ERROR, Payload: payload, Uuid: msg.Uuid}
+			return
+		}
+
+		chaincodeLogger.Debugf("Got key history. Sending %s", pb.ChaincodeMessage_RESPONSE)
+		serialSendMsg = &pb.ChaincodeMessage{Type: pb.ChaincodeMessage_RESPONSE, Payload: payloadBytes, Uuid: msg.Uuid}
+
+	}()
+}
+
+// afterGetQueryResult handles a GET_QUERY_RESULT request from the chaincode.
+func (handler *Handler) afterGetQueryResult(e *fsm.Event, state string) {
+	msg, ok := e.Args[0].(*pb.ChaincodeMessage)
+	if !ok {
+		e.Cancel(fmt.Errorf("Received unexpected message type"))
+		return
+	}
+	chaincodeLogger.Debugf("[%s]Received %s, invoking rich query on ledger", shortuuid(msg.Uuid), pb.ChaincodeMessage_GET_QUERY_RESULT)
+
+	// Query ledger for the documents matching the query
+	handler.handleGetQueryResult(msg)
+	chaincodeLogger.Debug("Exiting GET_QUERY_RESULT")
+}
+
+// Handles rich query to ledger. The query is evaluated over the committed
+// state, so it is only allowed in queries: the matching key-values are not
+// tracked by the transactions and the results of an invoke would depend on the
+// timing of the commits on each validating peer. The values of confidential
+// chaincodes are encrypted and cannot be matched.
+func (handler *Handler) handleGetQueryResult(msg *pb.ChaincodeMessage) {
+	// The defer followed by triggering a go routine dance is needed to ensure that the previous state transition
+	// is completed before the next one is triggered. The previous state transition is deemed complete only when
+	// the afterGetQueryResult function is exited. Interesting bug fix!!
+	go func() {
+		// Check if this is the unique state request from this chaincode uuid
+		uniqueReq := handler.createUUIDEntry(msg.Uuid)
+		if !uniqueReq {
+			// Drop this request
+			chaincodeLogger.Debug("Another state request pending for this Uuid. Cannot process.")
+			return
+		}
+
+		var serialSendMsg *pb.ChaincodeMessage
+
+		defer func() {
+			handler.deleteUUIDEntry(msg.Uuid)
+			chaincodeLogger.Debugf("[%s]handleGetQueryResult serial send %s", shortuuid(serialSendMsg.Uuid), serialSendMsg.Type)
+			handler.serialSend(serialSendMsg)
+		}()
+
+		getQueryResult := &pb.GetQueryResult{}
+		unmarshalErr := proto.Unmarshal(msg.Payload, getQueryResult)
+		if unmarshalErr != nil {
+			payload := []byte(unmarshalErr.Error())
+			chaincodeLogger.Debugf("Failed to unmarshall rich query request. Sending %s", pb.ChaincodeMessage_ERROR)
+			serialSendMsg = &pb.ChaincodeMessage{Type: pb.ChaincodeMessage_ERROR, Payload: payload, Uuid: msg.Uuid}
+			return
+		}
+
+		if handler.getIsTransaction(msg.Uuid) {
+			payload := []byte("GetQueryResult is only supported in queries")
+			chaincodeLogger.Debugf("Rich query requested by a transaction. Sending %s", pb.ChaincodeMessage_ERROR)
+			serialSendMsg = &pb.ChaincodeMessage{Type: pb.ChaincodeMessage_ERROR, Payload: payload, Uuid: msg.Uuid}
+			return
+		}
+
+		if handler.isConfidential(msg.Uuid) {
+			payload := []byte("GetQueryResult is not supported by confidential chaincodes")
+			chaincodeLogger.Debugf("Rich query requested by a confidential chaincode. Sending %s", pb.ChaincodeMessage_ERROR)
+			serialSendMsg = &pb.ChaincodeMessage{Type: pb.ChaincodeMessage_ERROR, Payload: payload, Uuid: msg.Uuid}
+			return
+		}
+
+		ledger, ledgerErr := handler.getLedger(msg.Uuid)
+		if ledgerErr != nil {
+			payload := []byte(ledgerErr.Error())
+			chaincodeLogger.Debugf("Failed to get ledger. Sending %s", pb.ChaincodeMessage_ERROR)
+			serialSendMsg = &pb.ChaincodeMessage{Type: pb.ChaincodeMessage_ERROR, Payload: payload, Uuid: msg.Uuid}
+			return
+		}
+
+		namespace := handler.getStateNamespace()
+
+		queryIter, err := ledger.GetQueryResult(namespace, getQueryResult.Query)
+		if err != nil {
+			payload := []byte(err.Error())
+			chaincodeLogger.Debugf("Failed to get rich query result. Sending %s", pb.ChaincodeMessage_ERROR)
+			serialSendMsg = &pb.ChaincodeMessage{Type: pb.ChaincodeMessage_ERROR, Payload: payload, Uuid: msg.Uuid}
+			return
+		}
+
+		// The remaining results are fetched with RANGE_QUERY_STATE_NEXT
+		iterID := util.GenerateUUID()
+		txContext := handler.getTxContext(msg.Uuid)
+		handler.putRangeQueryIterator(txContext, iterID, queryIter)
+
+		hasNext := queryIter.Next()
+
+		var keysAndValues []*pb.RangeQueryStateKeyValue
+		var i = uint32(0)
+		for ; hasNext && i < maxRangeQueryStateLimit; i++ {
+			key, value := queryIter.GetKeyValue()
+			keyAndValue := pb.RangeQueryStateKeyValue{Key: key, Value: value}
+			keysAndValues = append(keysAndValues, &keyAndValue)
+
+			hasNext = queryIter.Next()
+		}
+
+		if !hasNext {
+			queryIter.Close()
+			handler.deleteRangeQueryIterator(txContext, iterID)
+		}
+
+		payload := &pb.RangeQueryStateResponse{KeysAndValues: keysAndValues, HasMore: hasNext, ID: iterID}
+		payloadBytes, err := proto.Marshal(payload)
+		if err != nil {
+			queryIter.Close()
+			handler.deleteRangeQueryIterator(txContext, iterID)
+
+			payload := []byte(err.Error())
+			chaincodeLogger.Debugf("Failed marshall resopnse. Sending %s", pb.ChaincodeMessage_ERROR)
+			serialSendMsg = &pb.ChaincodeMessage{Type: pb.ChaincodeMessage_ERROR, Payload: payload, Uuid: msg.Uuid}
+			return
+		}
+
+		chaincodeLogger.Debugf("Got rich query result. Sending %s", pb.ChaincodeMessage_RESPONSE)
+		serialSendMsg = &pb.ChaincodeMessage{Type: pb.ChaincodeMessage_RESPONSE, Payload: payloadBytes, Uuid: msg.Uuid}
+
+	}()
+}
+
 // afterPutState handles a PUT_STATE request from the chaincode.
 func (handler *Handler) afterPutState(e *fsm.Event, state string) {
 	_, ok := e.Args[0].(*pb.ChaincodeMessage)
@@ -1011,7 +1294,7 @@ func (handler *Handler) enterBusyState(e *fsm.Event, state string) {
 			handler.triggerNextState(triggerNextStateMsg, true)
 		}()
 
-		ledgerObj, ledgerErr := ledger.GetLedger()
+		ledgerObj, ledgerErr := handler.getLedger(msg.Uuid)
 		if ledgerErr != nil {
 			// Send error msg back to chaincode and trigger event
 			payload := []byte(ledgerErr.Error())
@@ -1020,7 +1303,7 @@ func (handler *Handler) enterBusyState(e *fsm.Event, state string) {
 			return
 		}
 
-		chaincodeID := handler.ChaincodeID.Name
+		namespace := handler.getStateNamespace()
 		var err error
 		var res []byte
 
@@ -1038,17 +1321,25 @@ func (handler *Handler) enterBusyState(e *fsm.Event, state string) {
 			// Encrypt the data if the confidential is enabled
 			if pVal, err = handler.encrypt(msg.Uuid, putStateInfo.Value); err == nil {
 				// Invoke ledger to put state
-				err = ledgerObj.SetState(chaincodeID, putStateInfo.Key, pVal)
+				err = ledgerObj.SetTxState(msg.Uuid, namespace, putStateInfo.Key, pVal)
 			}
 		} else if msg.Type.String() == pb.ChaincodeMessage_DEL_STATE.String() {
 			// Invoke ledger to delete state
 			key := string(msg.Payload)
-			err = ledgerObj.DeleteState(chaincodeID, key)
+			err = ledgerObj.DeleteTxState(msg.Uuid, namespace, key)
 		} else if msg.Type.String() == pb.ChaincodeMessage_INVOKE_CHAINCODE.String() {
 			//check and prohibit C-call-C for CONFIDENTIAL txs
 			if triggerNextStateMsg = handler.canCallChaincode(msg.Uuid); triggerNextStateMsg != nil {
 				return
 			}
+			//the called chaincode may be busy with another transaction executed in
+			//parallel: the transaction is executed again in order, see ExecuteTransactions
+			if ledgerObj.AbortParallelTx(msg.Uuid) {
+				payload := []byte("Cannot invoke a chaincode from a transaction executed in parallel")
+				chaincodeLogger.Debugf("[%s]Transaction executed in parallel invokes a chaincode. Sending %s", shortuuid(msg.Uuid), pb.ChaincodeMessage_ERROR)
+				triggerNextStateMsg = &pb.ChaincodeMessage{Type: pb.ChaincodeMessage_ERROR, Payload: payload, Uuid: msg.Uuid}
+				return
+			}
 			chaincodeSpec := &pb.ChaincodeSpec{}
 			unmarshalErr := proto.Unmarshal(msg.Payload, chaincodeSpec)
 			if unmarshalErr != nil {
@@ -1064,6 +1355,8 @@ func (handler *Handler) enterBusyState(e *fsm.Event, state string) {
 			// Create the transaction object
 			chaincodeInvocationSpec := &pb.ChaincodeInvocationSpec{ChaincodeSpec: chaincodeSpec}
 			transaction, _ := pb.NewChaincodeExecute(chaincodeInvocationSpec, msg.Uuid, pb.Transaction_CHAINCODE_INVOKE)
+			// The invoked chaincode runs on the chain of the calling transaction
+			transaction.ChainID = handler.getChainID(msg.Uuid)
 
 			// Launch the new chaincode if not already running
 			_, chaincodeInput, launchErr := handler.chaincodeSupport.Launch(context.Background(), transaction)
@@ -1119,11 +1412,11 @@ func (handler *Handler) enterInitState(e *fsm.Event, state string) {
 	}
 	chaincodeLogger.Debugf("[%s]Entered state %s", shortuuid(ccMsg.Uuid), state)
 	//very first time entering init state from established, send message to chaincode
-	if ccMsg.Type == pb.ChaincodeMessage_INIT {
+	if ccMsg.Type == pb.ChaincodeMessage_INIT || ccMsg.Type == pb.ChaincodeMessage_UPGRADE {
 		// Mark isTransaction to allow put/del state and invoke other chaincodes
 		handler.markIsTransaction(ccMsg.Uuid, true)
 		if err := handler.serialSend(ccMsg); err != nil {
-			errMsg := &pb.ChaincodeMessage{Type: pb.ChaincodeMessage_ERROR, Payload: []byte(fmt.Sprintf("Error sending %s: %s", pb.ChaincodeMessage_INIT, err)), Uuid: ccMsg.Uuid}
+			errMsg := &pb.ChaincodeMessage{Type: pb.ChaincodeMessage_ERROR, Payload: []byte(fmt.Sprintf("Error sending %s: %s", ccMsg.Type, err)), Uuid: ccMsg.Uuid}
 			handler.notify(errMsg)
 		}
 	}
@@ -1199,7 +1492,7 @@ func (handler *Handler) initializeSecContext(tx, depTx *pb.Transaction) error {
 	//don't need the payload which is not useful and rather large
 	handler.deployTXSecContext.Payload = nil
 
-	//we need to null out path from depTx as invoke or queries don't have it
+	//we need to null out path and version from depTx as invoke or queries don't have them
 	cID := &pb.ChaincodeID{}
 	err := proto.Unmarshal(handler.deployTXSecContext.ChaincodeID, cID)
 	if err != nil {
@@ -1207,6 +1500,7 @@ func (handler *Handler) initializeSecContext(tx, depTx *pb.Transaction) error {
 	}
 
 	cID.Path = ""
+	cID.Version = 0
 	data, err := proto.Marshal(cID)
 	if err != nil {
 		return fmt.Errorf("Failed to marshall : %s\n", err)
@@ -1270,7 +1564,12 @@ func (handler *Handler) initOrReady(uuid string, f *string, initArgs []string, t
 	notfy := txctx.responseNotifier
 
 	if f != nil || initArgs != nil {
-		chaincodeLogger.Debug("sending INIT")
+		//upgrade transactions call the Upgrade function of the new version instead of Init
+		msgType := pb.ChaincodeMessage_INIT
+		if tx != nil && tx.Type == pb.Transaction_CHAINCODE_UPGRADE {
+			msgType = pb.ChaincodeMessage_UPGRADE
+		}
+		chaincodeLogger.Debugf("sending %s", msgType)
 		var f2 string
 		if f != nil {
 			f2 = *f
@@ -1279,9 +1578,9 @@ func (handler *Handler) initOrReady(uuid string, f *string, initArgs []string, t
 		var payload []byte
 		if payload, funcErr = proto.Marshal(funcArgsMsg); funcErr != nil {
 			handler.deleteTxContext(uuid)
-			return nil, fmt.Errorf("Failed to marshall %s : %s\n", ccMsg.Type.String(), funcErr)
+			return nil, fmt.Errorf("Failed to marshall %s : %s\n", msgType, funcErr)
 		}
-		ccMsg = &pb.ChaincodeMessage{Type: pb.ChaincodeMessage_INIT, Payload: payload, Uuid: uuid}
+		ccMsg = &pb.ChaincodeMessage{Type: msgType, Payload: payload, Uuid: uuid}
 		send = false
 	} else {
 		chaincodeLogger.Debug("sending READY")
@@ -1342,6 +1641,8 @@ func (handler *Handler) handleQueryChaincode(msg *pb.ChaincodeMessage) {
 		// Create the transaction object
 		chaincodeInvocationSpec := &pb.ChaincodeInvocationSpec{ChaincodeSpec: chaincodeSpec}
 		transaction, _ := pb.NewChaincodeExecute(chaincodeInvocationSpec, msg.Uuid, pb.Transaction_CHAINCODE_QUERY)
+		// The queried chaincode runs on the chain of the calling transaction
+		transaction.ChainID = handler.getChainID(msg.Uuid)
 
 		// Launch the new chaincode if not already running
 		_, chaincodeInput, launchErr := handler.chaincodeSupport.Launch(context.Background(), transaction)
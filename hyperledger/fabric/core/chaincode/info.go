@@ -0,0 +1,73 @@
+/*
+Copyright IBM Corp. 2016 All Rights Reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+		 http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package chaincode
+
+import (
+	"fmt"
+
+	"github.com/golang/protobuf/proto"
+
+	"github.com/hyperledger/fabric/core/ledger"
+	pb "github.com/hyperledger/fabric/protos"
+)
+
+// GetChaincodeInfo returns the committed current version of the chaincode.
+// The path and type of a confidential chaincode, whose deploy transaction is
+// encrypted, are not disclosed. ledger.ErrResourceNotFound is returned if the
+// chaincode has not been deployed.
+func GetChaincodeInfo(ledger *ledger.Ledger, chaincode string) (*pb.ChaincodeInfo, error) {
+	version, err := getChaincodeVersion(ledger, chaincode, true)
+	if err != nil {
+		return nil, err
+	}
+	txUUID, err := getVersionTxUUID(ledger, chaincode, version, true)
+	if err != nil {
+		return nil, err
+	}
+	depTx, err := ledger.GetTransactionByUUID(txUUID)
+	if err != nil {
+		return nil, err
+	}
+	if depTx.Type != pb.Transaction_CHAINCODE_DEPLOY && depTx.Type != pb.Transaction_CHAINCODE_UPGRADE {
+		return nil, fmt.Errorf("transaction %s of chaincode %s is not a deploy transaction", txUUID, chaincode)
+	}
+
+	info := &pb.ChaincodeInfo{ChaincodeID: &pb.ChaincodeID{Name: chaincode, Version: version}, TransactionUUID: txUUID}
+	if depTx.ConfidentialityLevel == pb.ConfidentialityLevel_PUBLIC {
+		cds := &pb.ChaincodeDeploymentSpec{}
+		if err = proto.Unmarshal(depTx.Payload, cds); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal deployment transaction %s (%s)", txUUID, err)
+		}
+		if spec := cds.GetChaincodeSpec(); spec != nil {
+			info.Type = spec.Type
+			if spec.ChaincodeID != nil {
+				info.ChaincodeID.Path = spec.ChaincodeID.Path
+			}
+		}
+	}
+
+	terminationTxUUID, err := ledger.GetState(terminatedChaincodesNamespace, chaincode, true)
+	if err != nil {
+		return nil, err
+	}
+	info.Terminated = terminationTxUUID != nil
+	if info.EffectiveDate, info.Active, err = GetActivation(ledger, chaincode); err != nil {
+		return nil, err
+	}
+	info.Active = info.Active && !info.Terminated
+	return info, nil
+}
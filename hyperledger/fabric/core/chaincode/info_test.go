@@ -0,0 +1,84 @@
+/*
+Copyright IBM Corp. 2016 All Rights Reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+		 http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package chaincode
+
+import (
+	"testing"
+
+	"github.com/hyperledger/fabric/core/ledger"
+	pb "github.com/hyperledger/fabric/protos"
+)
+
+func TestGetChaincodeInfo(t *testing.T) {
+	lgr := ledger.InitTestLedger(t)
+
+	if _, err := GetChaincodeInfo(lgr, "mycc"); err != ledger.ErrResourceNotFound {
+		t.Fatalf("Expected ErrResourceNotFound for a chaincode not deployed but got %v", err)
+	}
+
+	cds := &pb.ChaincodeDeploymentSpec{ChaincodeSpec: &pb.ChaincodeSpec{Type: pb.ChaincodeSpec_GOLANG,
+		ChaincodeID: &pb.ChaincodeID{Path: "example.com/mycc", Name: "mycc"}}, CodePackage: []byte("code")}
+	depTx, err := pb.NewChaincodeDeployTransaction(cds, "mycc")
+	if err != nil {
+		t.Fatalf("Error creating deploy transaction: %s", err)
+	}
+	secretCds := &pb.ChaincodeDeploymentSpec{ChaincodeSpec: &pb.ChaincodeSpec{Type: pb.ChaincodeSpec_GOLANG,
+		ChaincodeID: &pb.ChaincodeID{Path: "example.com/secretcc", Name: "secretcc"}}}
+	secretTx, err := pb.NewChaincodeDeployTransaction(secretCds, "secretcc")
+	if err != nil {
+		t.Fatalf("Error creating deploy transaction: %s", err)
+	}
+	secretTx.ConfidentialityLevel = pb.ConfidentialityLevel_CONFIDENTIAL
+	lgr.BeginTxBatch(1)
+	if err = lgr.CommitTxBatch(1, []*pb.Transaction{depTx, secretTx}, nil, nil); err != nil {
+		t.Fatalf("Error committing deploy transactions: %s", err)
+	}
+
+	info, err := GetChaincodeInfo(lgr, "mycc")
+	if err != nil {
+		t.Fatalf("Error getting chaincode info: %s", err)
+	}
+	if info.ChaincodeID.Name != "mycc" || info.ChaincodeID.Path != "example.com/mycc" || info.ChaincodeID.Version != 0 ||
+		info.Type != pb.ChaincodeSpec_GOLANG || info.TransactionUUID != "mycc" || !info.Active || info.Terminated {
+		t.Fatalf("Unexpected chaincode info %v", info)
+	}
+
+	// The path of a confidential chaincode is not disclosed
+	info, err = GetChaincodeInfo(lgr, "secretcc")
+	if err != nil {
+		t.Fatalf("Error getting chaincode info: %s", err)
+	}
+	if info.ChaincodeID.Path != "" || info.TransactionUUID != "secretcc" {
+		t.Fatalf("Expected the path of a confidential chaincode to be left out but got %v", info)
+	}
+
+	// A terminated chaincode is not active
+	lgr.BeginTxBatch(2)
+	lgr.TxBegin("tx2")
+	lgr.SetState(terminatedChaincodesNamespace, "mycc", []byte("tx2"))
+	lgr.TxFinished("tx2", true)
+	if err = lgr.CommitTxBatch(2, nil, nil, nil); err != nil {
+		t.Fatalf("Error committing termination: %s", err)
+	}
+	info, err = GetChaincodeInfo(lgr, "mycc")
+	if err != nil {
+		t.Fatalf("Error getting chaincode info: %s", err)
+	}
+	if !info.Terminated || info.Active {
+		t.Fatalf("Expected a terminated chaincode but got %v", info)
+	}
+}
@@ -0,0 +1,31 @@
+/*
+Copyright IBM Corp. 2016 All Rights Reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+		 http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package chaincode
+
+import (
+	"github.com/hyperledger/fabric/core/metrics"
+)
+
+var (
+	launchDuration = metrics.NewHistogram("chaincode_launch_duration_seconds",
+		"Time taken to start a chaincode and wait for its registration.",
+		[]float64{.1, .25, .5, 1, 2.5, 5, 10, 30, 60, 120, 300})
+	executeDuration = metrics.NewHistogramVec("chaincode_execute_duration_seconds",
+		"Time taken by a chaincode to execute a transaction or a query.", nil, "type")
+	timeouts = metrics.NewCounterVec("chaincode_timeouts_total",
+		"Number of chaincode operations which timed out, by operation: launch, init or execute.", "operation")
+)
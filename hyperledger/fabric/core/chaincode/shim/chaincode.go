@@ -27,6 +27,7 @@ import (
 	"os"
 	"strconv"
 	"strings"
+	"unicode/utf8"
 
 	gp "google/protobuf"
 
@@ -44,31 +45,13 @@ import (
 // Logger for the shim package.
 var chaincodeLogger = logging.MustGetLogger("shim")
 
-// Handler to shim that handles all control logic.
-var handler *Handler
-
-// Chaincode interface must be implemented by all chaincodes. The fabric runs
-// the transactions by calling these functions as specified.
-type Chaincode interface {
-	// Init is called during Deploy transaction after the container has been
-	// established, allowing the chaincode to initialize its internal data
-	Init(stub *ChaincodeStub, function string, args []string) ([]byte, error)
-
-	// Invoke is called for every Invoke transactions. The chaincode may change
-	// its state variables
-	Invoke(stub *ChaincodeStub, function string, args []string) ([]byte, error)
-
-	// Query is called for Query transactions. The chaincode may only read
-	// (but not modify) its state variables and return the result
-	Query(stub *ChaincodeStub, function string, args []string) ([]byte, error)
-}
-
 // ChaincodeStub is an object passed to chaincode for shim side handling of
 // APIs.
 type ChaincodeStub struct {
 	UUID            string
 	securityContext *pb.ChaincodeSecurityContext
 	chaincodeEvent  *pb.ChaincodeEvent
+	handler         *Handler
 }
 
 // Peer address derived from command line or env var
@@ -164,7 +147,7 @@ func newPeerClientConnection() (*grpc.ClientConn, error) {
 func chatWithPeer(chaincodename string, stream PeerChaincodeStream, cc Chaincode) error {
 
 	// Create the shim handler responsible for all control logic
-	handler = newChaincodeHandler(stream, cc)
+	handler := newChaincodeHandler(stream, cc)
 
 	defer stream.CloseSend()
 	// Send the ChaincodeID during register.
@@ -244,11 +227,17 @@ func chatWithPeer(chaincodename string, stream PeerChaincodeStream, cc Chaincode
 }
 
 // -- init stub ---
-func (stub *ChaincodeStub) init(uuid string, secContext *pb.ChaincodeSecurityContext) {
+func (stub *ChaincodeStub) init(handler *Handler, uuid string, secContext *pb.ChaincodeSecurityContext) {
+	stub.handler = handler
 	stub.UUID = uuid
 	stub.securityContext = secContext
 }
 
+// GetTxID returns the transaction ID
+func (stub *ChaincodeStub) GetTxID() string {
+	return stub.UUID
+}
+
 // --------- Security functions ----------
 //CHAINCODE SEC INTERFACE FUNCS TOBE IMPLEMENTED BY ANGELO
 
@@ -258,31 +247,31 @@ func (stub *ChaincodeStub) init(uuid string, secContext *pb.ChaincodeSecurityCon
 // same transaction context; that is, chaincode calling chaincode doesn't
 // create a new transaction message.
 func (stub *ChaincodeStub) InvokeChaincode(chaincodeName string, function string, args []string) ([]byte, error) {
-	return handler.handleInvokeChaincode(chaincodeName, function, args, stub.UUID)
+	return stub.handler.handleInvokeChaincode(chaincodeName, function, args, stub.UUID)
 }
 
 // QueryChaincode locally calls the specified chaincode `Query` using the
 // same transaction context; that is, chaincode calling chaincode doesn't
 // create a new transaction message.
 func (stub *ChaincodeStub) QueryChaincode(chaincodeName string, function string, args []string) ([]byte, error) {
-	return handler.handleQueryChaincode(chaincodeName, function, args, stub.UUID)
+	return stub.handler.handleQueryChaincode(chaincodeName, function, args, stub.UUID)
 }
 
 // --------- State functions ----------
 
 // GetState returns the byte array value specified by the `key`.
 func (stub *ChaincodeStub) GetState(key string) ([]byte, error) {
-	return handler.handleGetState(key, stub.UUID)
+	return stub.handler.handleGetState(key, stub.UUID)
 }
 
 // PutState writes the specified `value` and `key` into the ledger.
 func (stub *ChaincodeStub) PutState(key string, value []byte) error {
-	return handler.handlePutState(key, value, stub.UUID)
+	return stub.handler.handlePutState(key, value, stub.UUID)
 }
 
 // DelState removes the specified `key` and its value from the ledger.
 func (stub *ChaincodeStub) DelState(key string) error {
-	return handler.handleDelState(key, stub.UUID)
+	return stub.handler.handleDelState(key, stub.UUID)
 }
 
 //ReadCertAttribute is used to read an specific attribute from the transaction certificate, *attributeName* is passed as input parameter to this function.
@@ -332,12 +321,12 @@ type StateRangeQueryIterator struct {
 // an iterator will be returned that can be used to iterate over all keys
 // between the startKey and endKey, inclusive. The order in which keys are
 // returned by the iterator is random.
-func (stub *ChaincodeStub) RangeQueryState(startKey, endKey string) (*StateRangeQueryIterator, error) {
-	response, err := handler.handleRangeQueryState(startKey, endKey, stub.UUID)
+func (stub *ChaincodeStub) RangeQueryState(startKey, endKey string) (StateRangeQueryIteratorInterface, error) {
+	response, err := stub.handler.handleRangeQueryState(startKey, endKey, stub.UUID)
 	if err != nil {
 		return nil, err
 	}
-	return &StateRangeQueryIterator{handler, stub.UUID, response, 0}, nil
+	return &StateRangeQueryIterator{stub.handler, stub.UUID, response, 0}, nil
 }
 
 // HasNext returns true if the range query iterator contains additional keys
@@ -380,6 +369,148 @@ func (iter *StateRangeQueryIterator) Close() error {
 	return err
 }
 
+// GetQueryResult function can be invoked by a chaincode to run a rich query
+// over the JSON documents of its committed state, for instance
+//
+//	{"selector": {"docType": "Member", "age": {"$gt": 30}}, "sort": ["name"], "limit": 10}
+//
+// See package github.com/hyperledger/fabric/core/ledger/query for the syntax
+// of the queries. An iterator over the key-values of the matching documents is
+// returned. Rich queries are only supported in queries, not in transactions,
+// and not by confidential chaincodes.
+func (stub *ChaincodeStub) GetQueryResult(query string) (StateRangeQueryIteratorInterface, error) {
+	response, err := stub.handler.handleGetQueryResult(query, stub.UUID)
+	if err != nil {
+		return nil, err
+	}
+	return &StateRangeQueryIterator{stub.handler, stub.UUID, response, 0}, nil
+}
+
+// HistoryQueryIterator allows a chaincode to iterate over the committed
+// changes of a key.
+type HistoryQueryIterator struct {
+	response   *pb.HistoryQueryResponse
+	currentLoc int
+}
+
+// GetHistoryForKey function can be invoked by a chaincode to fetch the changes
+// of a key committed to the ledger, oldest first. Changes made by the current
+// transaction are not included. The peer must run with ledger.history.enabled.
+// It is only supported in queries.
+func (stub *ChaincodeStub) GetHistoryForKey(key string) (HistoryQueryIteratorInterface, error) {
+	response, err := stub.handler.handleGetHistoryForKey(key, stub.UUID)
+	if err != nil {
+		return nil, err
+	}
+	return &HistoryQueryIterator{response, 0}, nil
+}
+
+// HasNext returns true if the history query iterator contains additional
+// changes.
+func (iter *HistoryQueryIterator) HasNext() bool {
+	return iter.currentLoc < len(iter.response.Modifications)
+}
+
+// Next returns the next change in the history query iterator.
+func (iter *HistoryQueryIterator) Next() (*pb.KeyModification, error) {
+	if !iter.HasNext() {
+		return nil, errors.New("No such modification")
+	}
+	modification := iter.response.Modifications[iter.currentLoc]
+	iter.currentLoc++
+	return modification, nil
+}
+
+// Close closes the history query iterator.
+func (iter *HistoryQueryIterator) Close() error {
+	return nil
+}
+
+// COMPOSITE KEY FUNCTIONALITY
+
+const (
+	// compositeKeyNamespace starts every composite key, so that composite keys
+	// never mix with the simple keys of the chaincode, which must not start
+	// with U+0000
+	compositeKeyNamespace = "\x00"
+	// minUnicodeRuneValue separates the components of a composite key
+	minUnicodeRuneValue = rune(0) //U+0000
+	// maxUnicodeRuneValue sorts after every composite key sharing a prefix
+	maxUnicodeRuneValue = utf8.MaxRune //U+10FFFF - maximum (and unallocated) code point
+)
+
+// CreateCompositeKey combines the objectType and the attributes into a single
+// key. The composite keys of an objectType sort by their attributes, in
+// order, so that GetStateByPartialCompositeKey can return the keys that share
+// their first attributes. The objectType and attributes must be valid UTF-8
+// strings and must not contain U+0000 or U+10FFFF.
+func (stub *ChaincodeStub) CreateCompositeKey(objectType string, attributes []string) (string, error) {
+	return createCompositeKey(objectType, attributes)
+}
+
+// SplitCompositeKey splits a key created by CreateCompositeKey back into its
+// objectType and attributes.
+func (stub *ChaincodeStub) SplitCompositeKey(compositeKey string) (string, []string, error) {
+	return splitCompositeKey(compositeKey)
+}
+
+// GetStateByPartialCompositeKey returns an iterator over the composite keys of
+// the objectType starting with the given attributes. The attributes may be
+// empty to iterate over all the keys of the objectType. As with
+// RangeQueryState, the order in which keys are returned is random.
+func (stub *ChaincodeStub) GetStateByPartialCompositeKey(objectType string, attributes []string) (StateRangeQueryIteratorInterface, error) {
+	return getStateByPartialCompositeKeyInternal(stub, objectType, attributes)
+}
+
+func createCompositeKey(objectType string, attributes []string) (string, error) {
+	if objectType == "" {
+		return "", errors.New("Invalid composite key. The object type must be 1 or more characters.")
+	}
+	if err := validateCompositeKeyAttribute(objectType); err != nil {
+		return "", err
+	}
+	ck := compositeKeyNamespace + objectType + string(minUnicodeRuneValue)
+	for _, att := range attributes {
+		if err := validateCompositeKeyAttribute(att); err != nil {
+			return "", err
+		}
+		ck += att + string(minUnicodeRuneValue)
+	}
+	return ck, nil
+}
+
+func splitCompositeKey(compositeKey string) (string, []string, error) {
+	if !strings.HasPrefix(compositeKey, compositeKeyNamespace) || len(compositeKey) < 3 ||
+		!strings.HasSuffix(compositeKey, string(minUnicodeRuneValue)) {
+		return "", nil, fmt.Errorf("Invalid composite key %q.", compositeKey)
+	}
+	components := strings.Split(compositeKey[1:len(compositeKey)-1], string(minUnicodeRuneValue))
+	return components[0], components[1:], nil
+}
+
+func validateCompositeKeyAttribute(str string) error {
+	if !utf8.ValidString(str) {
+		return fmt.Errorf("Invalid composite key component %q. It must be a valid UTF-8 string.", str)
+	}
+	for _, runeValue := range str {
+		if runeValue == minUnicodeRuneValue || runeValue == maxUnicodeRuneValue {
+			return fmt.Errorf("Invalid composite key component %q. It must not contain U+%04X.", str, runeValue)
+		}
+	}
+	return nil
+}
+
+func getStateByPartialCompositeKeyInternal(stub ChaincodeStubInterface, objectType string, attributes []string) (StateRangeQueryIteratorInterface, error) {
+	startKey, err := createCompositeKey(objectType, attributes)
+	if err != nil {
+		return nil, err
+	}
+	// The composite keys starting with startKey sort between startKey and
+	// startKey followed by the largest code point, which no key may contain
+	endKey := startKey + string(maxUnicodeRuneValue)
+	return stub.RangeQueryState(startKey, endKey)
+}
+
 // TABLE FUNCTIONALITY
 // TODO More comments here with documentation
 
@@ -391,8 +522,12 @@ var (
 
 // CreateTable creates a new table given the table name and column definitions
 func (stub *ChaincodeStub) CreateTable(name string, columnDefinitions []*ColumnDefinition) error {
+	return createTableInternal(stub, name, columnDefinitions)
+}
 
-	_, err := stub.getTable(name)
+func createTableInternal(stub ChaincodeStubInterface, name string, columnDefinitions []*ColumnDefinition) error {
+
+	_, err := getTable(stub, name)
 	if err == nil {
 		return fmt.Errorf("CreateTable operation failed. Table %s already exists.", name)
 	}
@@ -400,6 +535,25 @@ func (stub *ChaincodeStub) CreateTable(name string, columnDefinitions []*ColumnD
 		return fmt.Errorf("CreateTable operation failed. %s", err)
 	}
 
+	if err = validateColumnDefinitions(columnDefinitions); err != nil {
+		return err
+	}
+
+	tableNameKey, err := getTableNameKey(name)
+	if err != nil {
+		return fmt.Errorf("Error creating table key: %s", err)
+	}
+	if err = putTable(stub, &Table{Name: name, ColumnDefinitions: columnDefinitions}); err != nil {
+		return err
+	}
+	err = stub.PutState(getRowCountKey(tableNameKey), []byte("0"))
+	if err != nil {
+		return fmt.Errorf("Error inserting table in state: %s", err)
+	}
+	return nil
+}
+
+func validateColumnDefinitions(columnDefinitions []*ColumnDefinition) error {
 	if columnDefinitions == nil || len(columnDefinitions) == 0 {
 		return errors.New("Invalid column definitions. Tables must contain at least one column.")
 	}
@@ -432,6 +586,9 @@ func (stub *ChaincodeStub) CreateTable(name string, columnDefinitions []*ColumnD
 		default:
 			return fmt.Errorf("Column definition %s does not have a valid type.", definition.Name)
 		}
+		if definition.Default != nil && !columnHasType(definition.Default, definition.Type) {
+			return fmt.Errorf("Column definition %s has a default value of the wrong type.", definition.Name)
+		}
 
 		if definition.Key {
 			hasKey = true
@@ -441,13 +598,15 @@ func (stub *ChaincodeStub) CreateTable(name string, columnDefinitions []*ColumnD
 	if !hasKey {
 		return errors.New("Inavlid table. One or more columns must be a key.")
 	}
+	return nil
+}
 
-	table := &Table{name, columnDefinitions}
+func putTable(stub ChaincodeStubInterface, table *Table) error {
 	tableBytes, err := proto.Marshal(table)
 	if err != nil {
 		return fmt.Errorf("Error marshalling table: %s", err)
 	}
-	tableNameKey, err := getTableNameKey(name)
+	tableNameKey, err := getTableNameKey(table.Name)
 	if err != nil {
 		return fmt.Errorf("Error creating table key: %s", err)
 	}
@@ -458,14 +617,91 @@ func (stub *ChaincodeStub) CreateTable(name string, columnDefinitions []*ColumnD
 	return nil
 }
 
+// AddColumn adds a column to an existing table. The column cannot be a key:
+// the rows already in the table get the default value of the column, the
+// zero value of its type if the definition has none.
+func (stub *ChaincodeStub) AddColumn(tableName string, definition *ColumnDefinition) error {
+	return addColumnInternal(stub, tableName, definition)
+}
+
+func addColumnInternal(stub ChaincodeStubInterface, tableName string, definition *ColumnDefinition) error {
+	table, err := getTable(stub, tableName)
+	if err != nil {
+		return err
+	}
+	if definition != nil && definition.Key {
+		return fmt.Errorf("AddColumn operation failed. Column %s cannot be a key.", definition.Name)
+	}
+	columnDefinitions := append(table.ColumnDefinitions[:len(table.ColumnDefinitions):len(table.ColumnDefinitions)], definition)
+	if err = validateColumnDefinitions(columnDefinitions); err != nil {
+		return fmt.Errorf("AddColumn operation failed. %s", err)
+	}
+	table.ColumnDefinitions = columnDefinitions
+	return putTable(stub, table)
+}
+
+// CreateIndex creates an index of the table on the given columns, in order.
+// The rows already in the table are indexed, and the index is kept up to date
+// by InsertRow, ReplaceRow and DeleteRow.
+func (stub *ChaincodeStub) CreateIndex(tableName string, columns []string) error {
+	return createIndexInternal(stub, tableName, columns)
+}
+
+func createIndexInternal(stub ChaincodeStubInterface, tableName string, columns []string) error {
+	table, err := getTable(stub, tableName)
+	if err != nil {
+		return err
+	}
+	if len(columns) == 0 {
+		return errors.New("CreateIndex operation failed. Indexes must contain at least one column.")
+	}
+	if _, err = getColumnPositions(table, columns); err != nil {
+		return fmt.Errorf("CreateIndex operation failed. %s", err)
+	}
+	if getIndex(table, columns) != nil {
+		return fmt.Errorf("CreateIndex operation failed. Table %s already has an index on %v.", tableName, columns)
+	}
+	index := &TableIndex{Columns: columns}
+
+	tableNameKey, err := getTableNameKey(tableName)
+	if err != nil {
+		return err
+	}
+	iter, err := stub.RangeQueryState(tableNameKey+"1", tableNameKey+":")
+	if err != nil {
+		return fmt.Errorf("Error indexing rows: %s", err)
+	}
+	defer iter.Close()
+	for iter.HasNext() {
+		keyString, rowBytes, err := iter.Next()
+		if err != nil {
+			return fmt.Errorf("Error indexing rows: %s", err)
+		}
+		row, err := unmarshalRow(table, rowBytes)
+		if err != nil {
+			return err
+		}
+		if err = updateIndex(stub, tableNameKey, table, index, row, keyString, false); err != nil {
+			return err
+		}
+	}
+
+	table.Indexes = append(table.Indexes, index)
+	return putTable(stub, table)
+}
+
 // GetTable returns the table for the specified table name or ErrTableNotFound
 // if the table does not exist.
 func (stub *ChaincodeStub) GetTable(tableName string) (*Table, error) {
-	return stub.getTable(tableName)
+	return getTable(stub, tableName)
 }
 
 // DeleteTable deletes an entire table and all associated rows.
 func (stub *ChaincodeStub) DeleteTable(tableName string) error {
+	return deleteTableInternal(stub, tableName)
+}
+
+func deleteTableInternal(stub ChaincodeStubInterface, tableName string) error {
 	tableNameKey, err := getTableNameKey(tableName)
 	if err != nil {
 		return err
@@ -488,6 +724,23 @@ func (stub *ChaincodeStub) DeleteTable(tableName string) error {
 		}
 	}
 
+	// Delete the row count and the indexes
+	iter, err = stub.RangeQueryState(tableNameKey+"#", tableNameKey+"$")
+	if err != nil {
+		return fmt.Errorf("Error deleting table: %s", err)
+	}
+	defer iter.Close()
+	for iter.HasNext() {
+		key, _, err := iter.Next()
+		if err != nil {
+			return fmt.Errorf("Error deleting table: %s", err)
+		}
+		err = stub.DelState(key)
+		if err != nil {
+			return fmt.Errorf("Error deleting table: %s", err)
+		}
+	}
+
 	return stub.DelState(tableNameKey)
 }
 
@@ -498,7 +751,7 @@ func (stub *ChaincodeStub) DeleteTable(tableName string) error {
 // false and a TableNotFoundError if the specified table name does not exist.
 // false and an error if there is an unexpected error condition.
 func (stub *ChaincodeStub) InsertRow(tableName string, row Row) (bool, error) {
-	return stub.insertRowInternal(tableName, row, false)
+	return insertRowInternal(stub, tableName, row, false)
 }
 
 // ReplaceRow updates the row in the specified table.
@@ -508,11 +761,15 @@ func (stub *ChaincodeStub) InsertRow(tableName string, row Row) (bool, error) {
 // flase and a TableNotFoundError if the specified table name does not exist.
 // false and an error if there is an unexpected error condition.
 func (stub *ChaincodeStub) ReplaceRow(tableName string, row Row) (bool, error) {
-	return stub.insertRowInternal(tableName, row, true)
+	return insertRowInternal(stub, tableName, row, true)
 }
 
 // GetRow fetches a row from the specified table for the given key.
 func (stub *ChaincodeStub) GetRow(tableName string, key []Column) (Row, error) {
+	return getRowInternal(stub, tableName, key)
+}
+
+func getRowInternal(stub ChaincodeStubInterface, tableName string, key []Column) (Row, error) {
 
 	var row Row
 
@@ -525,14 +782,15 @@ func (stub *ChaincodeStub) GetRow(tableName string, key []Column) (Row, error) {
 	if err != nil {
 		return row, fmt.Errorf("Error fetching row from DB: %s", err)
 	}
+	if rowBytes == nil {
+		return row, nil
+	}
 
-	err = proto.Unmarshal(rowBytes, &row)
+	table, err := getTable(stub, tableName)
 	if err != nil {
-		return row, fmt.Errorf("Error unmarshalling row: %s", err)
+		return row, err
 	}
-
-	return row, nil
-
+	return unmarshalRow(table, rowBytes)
 }
 
 // GetRows returns multiple rows based on a partial key. For example, given table
@@ -542,77 +800,231 @@ func (stub *ChaincodeStub) GetRow(tableName string, key []Column) (Row, error) {
 // also be called with A only to return all rows that have A and any value
 // for C and D as their key.
 func (stub *ChaincodeStub) GetRows(tableName string, key []Column) (<-chan Row, error) {
+	return getRowsInternal(stub, tableName, key)
+}
 
-	keyString, err := buildKeyString(tableName, key)
+func getRowsInternal(stub ChaincodeStubInterface, tableName string, key []Column) (<-chan Row, error) {
+
+	iter, err := getRowsIteratorInternal(stub, tableName, key)
 	if err != nil {
 		return nil, err
 	}
 
-	table, err := stub.getTable(tableName)
+	rows := make(chan Row)
+
+	go func() {
+		defer close(rows)
+		defer iter.Close()
+		for iter.HasNext() {
+			row, err := iter.Next()
+			if err != nil {
+				chaincodeLogger.Errorf("Error fetching rows of table %s: %s", tableName, err)
+				return
+			}
+			rows <- row
+		}
+	}()
+
+	return rows, nil
+
+}
+
+// RowIterator allows a chaincode to iterate over the rows of a table returned
+// by GetRowsIterator or GetRowsByIndex
+type RowIterator struct {
+	stub  ChaincodeStubInterface
+	table *Table
+	iter  StateRangeQueryIteratorInterface
+	// byIndex is set when iterating over the entries of an index, whose values
+	// are the keys of the rows
+	byIndex bool
+}
+
+// HasNext returns true if the range query iterator contains additional rows
+func (iter *RowIterator) HasNext() bool {
+	return iter.iter.HasNext()
+}
+
+// Next returns the next row of the iterator
+func (iter *RowIterator) Next() (Row, error) {
+	_, rowBytes, err := iter.iter.Next()
+	if err != nil {
+		return Row{}, fmt.Errorf("Error fetching row: %s", err)
+	}
+	if iter.byIndex {
+		keyString := string(rowBytes)
+		rowBytes, err = iter.stub.GetState(keyString)
+		if err != nil {
+			return Row{}, fmt.Errorf("Error fetching row for key %s: %s", keyString, err)
+		}
+		if rowBytes == nil {
+			return Row{}, fmt.Errorf("Index of table %s refers to missing row %s", iter.table.Name, keyString)
+		}
+	}
+	return unmarshalRow(iter.table, rowBytes)
+}
+
+// Close closes the iterator. This should be called when done
+// reading from the iterator to free up resources.
+func (iter *RowIterator) Close() error {
+	return iter.iter.Close()
+}
+
+// GetRowsIterator is GetRows returning an iterator, which reports the errors
+// fetching the rows instead of closing a channel.
+func (stub *ChaincodeStub) GetRowsIterator(tableName string, key []Column) (RowIteratorInterface, error) {
+	return getRowsIteratorInternal(stub, tableName, key)
+}
+
+func getRowsIteratorInternal(stub ChaincodeStubInterface, tableName string, key []Column) (RowIteratorInterface, error) {
+
+	keyString, err := buildKeyString(tableName, key)
 	if err != nil {
 		return nil, err
 	}
 
-	// Need to check for special case where table has a single column
-	if len(table.GetColumnDefinitions()) < 2 && len(key) > 0 {
+	table, err := getTable(stub, tableName)
+	if err != nil {
+		return nil, err
+	}
 
-		row, err := stub.GetRow(tableName, key)
-		if err != nil {
-			return nil, err
-		}
-		rows := make(chan Row)
-		go func() {
-			rows <- row
-			close(rows)
-		}()
-		return rows, nil
+	// A complete key designates a single row, whose key has no suffix
+	startKey, endKey := keyString+"1", keyString+":"
+	if len(key) == len(getKeyColumnPositions(table)) {
+		startKey, endKey = keyString, keyString
 	}
 
-	iter, err := stub.RangeQueryState(keyString+"1", keyString+":")
+	iter, err := stub.RangeQueryState(startKey, endKey)
 	if err != nil {
 		return nil, fmt.Errorf("Error fetching rows: %s", err)
 	}
-	defer iter.Close()
+	return &RowIterator{stub: stub, table: table, iter: iter}, nil
+}
 
-	rows := make(chan Row)
+// GetRowsByIndex returns an iterator over the rows of the table matching the
+// key in the index on the given columns, created by CreateIndex. As for
+// GetRows, the key can be partial: given an index on [A, B], it can be called
+// with values for A and B or for A only.
+func (stub *ChaincodeStub) GetRowsByIndex(tableName string, columns []string, key []Column) (RowIteratorInterface, error) {
+	return getRowsByIndexInternal(stub, tableName, columns, key)
+}
 
-	go func() {
-		for iter.HasNext() {
-			_, rowBytes, err := iter.Next()
-			if err != nil {
-				close(rows)
-			}
+func getRowsByIndexInternal(stub ChaincodeStubInterface, tableName string, columns []string, key []Column) (RowIteratorInterface, error) {
 
-			var row Row
-			err = proto.Unmarshal(rowBytes, &row)
-			if err != nil {
-				close(rows)
-			}
+	table, err := getTable(stub, tableName)
+	if err != nil {
+		return nil, err
+	}
+	index := getIndex(table, columns)
+	if index == nil {
+		return nil, fmt.Errorf("Table %s has no index on %v.", tableName, columns)
+	}
+	if len(key) > len(columns) {
+		return nil, fmt.Errorf("Index on %v has %d columns, but key has %d columns.", columns, len(columns), len(key))
+	}
+	positions, err := getColumnPositions(table, columns)
+	if err != nil {
+		return nil, err
+	}
+	for i := range key {
+		definition := table.ColumnDefinitions[positions[i]]
+		if !columnHasType(&key[i], definition.Type) {
+			return nil, fmt.Errorf("The type for table '%s', column '%s' is '%s', but the column in the key does not match.",
+				tableName, definition.Name, definition.Type)
+		}
+	}
 
-			rows <- row
+	tableNameKey, err := getTableNameKey(tableName)
+	if err != nil {
+		return nil, err
+	}
+	var prefix bytes.Buffer
+	prefix.WriteString(getIndexKey(tableNameKey, index))
+	encodeColumns(&prefix, key)
 
-		}
-		close(rows)
-	}()
+	iter, err := stub.RangeQueryState(prefix.String()+"0", prefix.String()+":")
+	if err != nil {
+		return nil, fmt.Errorf("Error fetching rows: %s", err)
+	}
+	return &RowIterator{stub: stub, table: table, iter: iter, byIndex: true}, nil
+}
 
-	return rows, nil
+// GetRowCount returns the number of rows in the table
+func (stub *ChaincodeStub) GetRowCount(tableName string) (uint64, error) {
+	return getRowCountInternal(stub, tableName)
+}
+
+func getRowCountInternal(stub ChaincodeStubInterface, tableName string) (uint64, error) {
+
+	if _, err := getTable(stub, tableName); err != nil {
+		return 0, err
+	}
+	tableNameKey, err := getTableNameKey(tableName)
+	if err != nil {
+		return 0, err
+	}
+	countBytes, err := stub.GetState(getRowCountKey(tableNameKey))
+	if err != nil {
+		return 0, fmt.Errorf("Error fetching row count: %s", err)
+	}
+	if countBytes != nil {
+		return strconv.ParseUint(string(countBytes), 10, 64)
+	}
 
+	// Tables created before row counts were kept are counted
+	iter, err := stub.RangeQueryState(tableNameKey+"1", tableNameKey+":")
+	if err != nil {
+		return 0, fmt.Errorf("Error counting rows: %s", err)
+	}
+	defer iter.Close()
+	var count uint64
+	for iter.HasNext() {
+		if _, _, err = iter.Next(); err != nil {
+			return 0, fmt.Errorf("Error counting rows: %s", err)
+		}
+		count++
+	}
+	return count, nil
 }
 
 // DeleteRow deletes the row for the given key from the specified table.
 func (stub *ChaincodeStub) DeleteRow(tableName string, key []Column) error {
+	return deleteRowInternal(stub, tableName, key)
+}
+
+func deleteRowInternal(stub ChaincodeStubInterface, tableName string, key []Column) error {
 
 	keyString, err := buildKeyString(tableName, key)
 	if err != nil {
 		return err
 	}
 
+	table, err := getTable(stub, tableName)
+	if err != nil {
+		return err
+	}
+	rowBytes, err := stub.GetState(keyString)
+	if err != nil {
+		return fmt.Errorf("DeleteRow operation error. Error fetching row: %s", err)
+	}
+	if rowBytes == nil {
+		return nil
+	}
+	row, err := unmarshalRow(table, rowBytes)
+	if err != nil {
+		return err
+	}
+
 	err = stub.DelState(keyString)
 	if err != nil {
 		return fmt.Errorf("DeleteRow operation error. Error deleting row: %s", err)
 	}
 
-	return nil
+	tableNameKey, _ := getTableNameKey(tableName)
+	if err = updateIndexes(stub, tableNameKey, table, row, keyString, true); err != nil {
+		return err
+	}
+	return updateRowCount(stub, tableNameKey, -1)
 }
 
 // VerifySignature verifies the transaction signature and returns `true` if
@@ -653,7 +1065,7 @@ func (stub *ChaincodeStub) GetTxTimestamp() (*gp.Timestamp, error) {
 	return stub.securityContext.TxTimestamp, nil
 }
 
-func (stub *ChaincodeStub) getTable(tableName string) (*Table, error) {
+func getTable(stub ChaincodeStubInterface, tableName string) (*Table, error) {
 
 	tableName, err := getTableNameKey(tableName)
 	if err != nil {
@@ -703,6 +1115,14 @@ func buildKeyString(tableName string, keys []Column) (string, error) {
 	}
 
 	keyBuffer.WriteString(tableNameKey)
+	encodeColumns(&keyBuffer, keys)
+
+	return keyBuffer.String(), nil
+}
+
+// encodeColumns writes the values of the columns to the buffer, each prefixed
+// with its length
+func encodeColumns(keyBuffer *bytes.Buffer, keys []Column) {
 
 	for _, key := range keys {
 
@@ -730,8 +1150,193 @@ func buildKeyString(tableName string, keys []Column) (string, error) {
 		keyBuffer.WriteString(strconv.Itoa(len(keyString)))
 		keyBuffer.WriteString(keyString)
 	}
+}
 
-	return keyBuffer.String(), nil
+// The row count and the indexes of a table are stored under the table key
+// followed by '#', which sorts before the lengths starting the row keys. The
+// row count is a decimal string. An index entry is keyed by the values of the
+// indexed columns then the key of the row, and holds the key string of the
+// row.
+
+func getRowCountKey(tableNameKey string) string {
+	return tableNameKey + "#c"
+}
+
+func getIndexKey(tableNameKey string, index *TableIndex) string {
+	var id bytes.Buffer
+	for _, column := range index.Columns {
+		id.WriteString(strconv.Itoa(len(column)))
+		id.WriteString(column)
+	}
+	return tableNameKey + "#i" + strconv.Itoa(id.Len()) + id.String()
+}
+
+// getIndex returns the index of the table on the columns, nil if there is none
+func getIndex(table *Table, columns []string) *TableIndex {
+	for _, index := range table.Indexes {
+		if len(index.Columns) != len(columns) {
+			continue
+		}
+		match := true
+		for i := range columns {
+			if index.Columns[i] != columns[i] {
+				match = false
+				break
+			}
+		}
+		if match {
+			return index
+		}
+	}
+	return nil
+}
+
+// getColumnPositions returns the positions of the named columns in the table
+func getColumnPositions(table *Table, columns []string) ([]int, error) {
+	positions := make([]int, len(columns))
+	for i, column := range columns {
+		positions[i] = -1
+		for j, definition := range table.ColumnDefinitions {
+			if definition.Name == column {
+				positions[i] = j
+				break
+			}
+		}
+		if positions[i] < 0 {
+			return nil, fmt.Errorf("Table %s has no column '%s'.", table.Name, column)
+		}
+		for _, previous := range columns[:i] {
+			if previous == column {
+				return nil, fmt.Errorf("Duplicate column name '%s'.", column)
+			}
+		}
+	}
+	return positions, nil
+}
+
+func getKeyColumnPositions(table *Table) []int {
+	var positions []int
+	for i, definition := range table.ColumnDefinitions {
+		if definition.Key {
+			positions = append(positions, i)
+		}
+	}
+	return positions
+}
+
+// updateIndexes adds the entries of the row to the indexes of the table, or
+// removes them
+func updateIndexes(stub ChaincodeStubInterface, tableNameKey string, table *Table, row Row, keyString string, remove bool) error {
+	for _, index := range table.Indexes {
+		if err := updateIndex(stub, tableNameKey, table, index, row, keyString, remove); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func updateIndex(stub ChaincodeStubInterface, tableNameKey string, table *Table, index *TableIndex, row Row, keyString string, remove bool) error {
+	positions, err := getColumnPositions(table, index.Columns)
+	if err != nil {
+		return err
+	}
+	var entryKey bytes.Buffer
+	entryKey.WriteString(getIndexKey(tableNameKey, index))
+	for _, i := range append(positions, getKeyColumnPositions(table)...) {
+		encodeColumns(&entryKey, []Column{*row.Columns[i]})
+	}
+
+	if remove {
+		err = stub.DelState(entryKey.String())
+	} else {
+		err = stub.PutState(entryKey.String(), []byte(keyString))
+	}
+	if err != nil {
+		return fmt.Errorf("Error updating index of table %s: %s", table.Name, err)
+	}
+	return nil
+}
+
+// updateRowCount adds delta to the row count of the table, unless the table
+// was created before row counts were kept
+func updateRowCount(stub ChaincodeStubInterface, tableNameKey string, delta int64) error {
+	countKey := getRowCountKey(tableNameKey)
+	countBytes, err := stub.GetState(countKey)
+	if err != nil {
+		return fmt.Errorf("Error fetching row count: %s", err)
+	}
+	if countBytes == nil {
+		return nil
+	}
+	count, err := strconv.ParseUint(string(countBytes), 10, 64)
+	if err != nil {
+		return fmt.Errorf("Invalid row count %q: %s", countBytes, err)
+	}
+	count = uint64(int64(count) + delta)
+	err = stub.PutState(countKey, []byte(strconv.FormatUint(count, 10)))
+	if err != nil {
+		return fmt.Errorf("Error updating row count: %s", err)
+	}
+	return nil
+}
+
+// unmarshalRow unmarshals a row of the table. The rows inserted before columns
+// were added to the table get the default values of those columns.
+func unmarshalRow(table *Table, rowBytes []byte) (Row, error) {
+	var row Row
+	err := proto.Unmarshal(rowBytes, &row)
+	if err != nil {
+		return row, fmt.Errorf("Error unmarshalling row: %s", err)
+	}
+	for i := len(row.Columns); i < len(table.ColumnDefinitions); i++ {
+		row.Columns = append(row.Columns, getDefault(table.ColumnDefinitions[i]))
+	}
+	return row, nil
+}
+
+// getDefault returns the default value of the column, the zero value of its
+// type if the definition has none
+func getDefault(definition *ColumnDefinition) *Column {
+	if definition.Default != nil {
+		return definition.Default
+	}
+	switch definition.Type {
+	case ColumnDefinition_STRING:
+		return &Column{Value: &Column_String_{}}
+	case ColumnDefinition_INT32:
+		return &Column{Value: &Column_Int32{}}
+	case ColumnDefinition_INT64:
+		return &Column{Value: &Column_Int64{}}
+	case ColumnDefinition_UINT32:
+		return &Column{Value: &Column_Uint32{}}
+	case ColumnDefinition_UINT64:
+		return &Column{Value: &Column_Uint64{}}
+	case ColumnDefinition_BYTES:
+		return &Column{Value: &Column_Bytes{}}
+	default:
+		return &Column{Value: &Column_Bool{}}
+	}
+}
+
+func columnHasType(column *Column, columnType ColumnDefinition_Type) bool {
+	switch column.Value.(type) {
+	case *Column_String_:
+		return columnType == ColumnDefinition_STRING
+	case *Column_Int32:
+		return columnType == ColumnDefinition_INT32
+	case *Column_Int64:
+		return columnType == ColumnDefinition_INT64
+	case *Column_Uint32:
+		return columnType == ColumnDefinition_UINT32
+	case *Column_Uint64:
+		return columnType == ColumnDefinition_UINT64
+	case *Column_Bytes:
+		return columnType == ColumnDefinition_BYTES
+	case *Column_Bool:
+		return columnType == ColumnDefinition_BOOL
+	default:
+		return false
+	}
 }
 
 func getKeyAndVerifyRow(table Table, row Row) ([]Column, error) {
@@ -746,26 +1351,7 @@ func getKeyAndVerifyRow(table Table, row Row) ([]Column, error) {
 	for i, column := range row.Columns {
 
 		// Check types
-		var expectedType bool
-		switch column.Value.(type) {
-		case *Column_String_:
-			expectedType = table.ColumnDefinitions[i].Type == ColumnDefinition_STRING
-		case *Column_Int32:
-			expectedType = table.ColumnDefinitions[i].Type == ColumnDefinition_INT32
-		case *Column_Int64:
-			expectedType = table.ColumnDefinitions[i].Type == ColumnDefinition_INT64
-		case *Column_Uint32:
-			expectedType = table.ColumnDefinitions[i].Type == ColumnDefinition_UINT32
-		case *Column_Uint64:
-			expectedType = table.ColumnDefinitions[i].Type == ColumnDefinition_UINT64
-		case *Column_Bytes:
-			expectedType = table.ColumnDefinitions[i].Type == ColumnDefinition_BYTES
-		case *Column_Bool:
-			expectedType = table.ColumnDefinitions[i].Type == ColumnDefinition_BOOL
-		default:
-			expectedType = false
-		}
-		if !expectedType {
+		if !columnHasType(column, table.ColumnDefinitions[i].Type) {
 			return keys, fmt.Errorf("The type for table '%s', column '%s' is '%s', but the column in the row does not match.",
 				table.Name, table.ColumnDefinitions[i].Name, table.ColumnDefinitions[i].Type)
 		}
@@ -779,30 +1365,15 @@ func getKeyAndVerifyRow(table Table, row Row) ([]Column, error) {
 	return keys, nil
 }
 
-func (stub *ChaincodeStub) isRowPrsent(tableName string, key []Column) (bool, error) {
-	keyString, err := buildKeyString(tableName, key)
-	if err != nil {
-		return false, err
-	}
-	rowBytes, err := stub.GetState(keyString)
-	if err != nil {
-		return false, fmt.Errorf("Error fetching row for key %s: %s", keyString, err)
-	}
-	if rowBytes != nil {
-		return true, nil
-	}
-	return false, nil
-}
-
 // insertRowInternal inserts a new row into the specified table.
 // Returns -
 // true and no error if the row is successfully inserted.
 // false and no error if a row already exists for the given key.
 // flase and a TableNotFoundError if the specified table name does not exist.
 // false and an error if there is an unexpected error condition.
-func (stub *ChaincodeStub) insertRowInternal(tableName string, row Row, update bool) (bool, error) {
+func insertRowInternal(stub ChaincodeStubInterface, tableName string, row Row, update bool) (bool, error) {
 
-	table, err := stub.getTable(tableName)
+	table, err := getTable(stub, tableName)
 	if err != nil {
 		return false, err
 	}
@@ -812,10 +1383,15 @@ func (stub *ChaincodeStub) insertRowInternal(tableName string, row Row, update b
 		return false, err
 	}
 
-	present, err := stub.isRowPrsent(tableName, key)
+	keyString, err := buildKeyString(tableName, key)
 	if err != nil {
 		return false, err
 	}
+	oldRowBytes, err := stub.GetState(keyString)
+	if err != nil {
+		return false, fmt.Errorf("Error fetching row for key %s: %s", keyString, err)
+	}
+	present := oldRowBytes != nil
 	if (present && !update) || (!present && update) {
 		return false, nil
 	}
@@ -825,15 +1401,31 @@ func (stub *ChaincodeStub) insertRowInternal(tableName string, row Row, update b
 		return false, fmt.Errorf("Error marshalling row: %s", err)
 	}
 
-	keyString, err := buildKeyString(tableName, key)
-	if err != nil {
-		return false, err
+	tableNameKey, _ := getTableNameKey(tableName)
+	if present && len(table.Indexes) > 0 {
+		oldRow, err := unmarshalRow(table, oldRowBytes)
+		if err != nil {
+			return false, err
+		}
+		if err = updateIndexes(stub, tableNameKey, table, oldRow, keyString, true); err != nil {
+			return false, err
+		}
 	}
+
 	err = stub.PutState(keyString, rowBytes)
 	if err != nil {
 		return false, fmt.Errorf("Error inserting row in table %s: %s", tableName, err)
 	}
 
+	if err = updateIndexes(stub, tableNameKey, table, row, keyString, false); err != nil {
+		return false, err
+	}
+	if !present {
+		if err = updateRowCount(stub, tableNameKey, 1); err != nil {
+			return false, err
+		}
+	}
+
 	return true, nil
 }
 
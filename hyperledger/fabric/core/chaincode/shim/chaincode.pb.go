@@ -10,6 +10,7 @@ It is generated from these files:
 
 It has these top-level messages:
 	ColumnDefinition
+	TableIndex
 	Table
 	Column
 	Row
@@ -64,15 +65,33 @@ type ColumnDefinition struct {
 	Name string                `protobuf:"bytes,1,opt,name=name" json:"name,omitempty"`
 	Type ColumnDefinition_Type `protobuf:"varint,2,opt,name=type,enum=shim.ColumnDefinition_Type" json:"type,omitempty"`
 	Key  bool                  `protobuf:"varint,3,opt,name=key" json:"key,omitempty"`
+	// value of the column in the rows inserted before it was added
+	Default *Column `protobuf:"bytes,4,opt,name=default" json:"default,omitempty"`
 }
 
 func (m *ColumnDefinition) Reset()         { *m = ColumnDefinition{} }
 func (m *ColumnDefinition) String() string { return proto.CompactTextString(m) }
 func (*ColumnDefinition) ProtoMessage()    {}
 
+func (m *ColumnDefinition) GetDefault() *Column {
+	if m != nil {
+		return m.Default
+	}
+	return nil
+}
+
+type TableIndex struct {
+	Columns []string `protobuf:"bytes,1,rep,name=columns" json:"columns,omitempty"`
+}
+
+func (m *TableIndex) Reset()         { *m = TableIndex{} }
+func (m *TableIndex) String() string { return proto.CompactTextString(m) }
+func (*TableIndex) ProtoMessage()    {}
+
 type Table struct {
 	Name              string              `protobuf:"bytes,1,opt,name=name" json:"name,omitempty"`
 	ColumnDefinitions []*ColumnDefinition `protobuf:"bytes,2,rep,name=columnDefinitions" json:"columnDefinitions,omitempty"`
+	Indexes           []*TableIndex       `protobuf:"bytes,3,rep,name=indexes" json:"indexes,omitempty"`
 }
 
 func (m *Table) Reset()         { *m = Table{} }
@@ -86,6 +105,13 @@ func (m *Table) GetColumnDefinitions() []*ColumnDefinition {
 	return nil
 }
 
+func (m *Table) GetIndexes() []*TableIndex {
+	if m != nil {
+		return m.Indexes
+	}
+	return nil
+}
+
 type Column struct {
 	// Types that are valid to be assigned to Value:
 	//	*Column_String_
@@ -155,6 +155,7 @@ func newChaincodeHandler(peerChatStream PeerChaincodeStream, chaincode Chaincode
 		fsm.Events{
 			{Name: pb.ChaincodeMessage_REGISTERED.String(), Src: []string{"created"}, Dst: "established"},
 			{Name: pb.ChaincodeMessage_INIT.String(), Src: []string{"established"}, Dst: "init"},
+			{Name: pb.ChaincodeMessage_UPGRADE.String(), Src: []string{"established"}, Dst: "init"},
 			{Name: pb.ChaincodeMessage_READY.String(), Src: []string{"established"}, Dst: "ready"},
 			{Name: pb.ChaincodeMessage_ERROR.String(), Src: []string{"init"}, Dst: "established"},
 			{Name: pb.ChaincodeMessage_RESPONSE.String(), Src: []string{"init"}, Dst: "init"},
@@ -191,7 +192,8 @@ func (handler *Handler) beforeRegistered(e *fsm.Event) {
 	chaincodeLogger.Debugf("Received %s, ready for invocations", pb.ChaincodeMessage_REGISTERED)
 }
 
-// handleInit handles request to initialize chaincode.
+// handleInit handles request to initialize chaincode, or to upgrade the state
+// copied from its previous version.
 func (handler *Handler) handleInit(msg *pb.ChaincodeMessage) {
 	// The defer followed by triggering a go routine dance is needed to ensure that the previous state transition
 	// is completed before the next one is triggered. The previous state transition is deemed complete only when
@@ -222,8 +224,17 @@ func (handler *Handler) handleInit(msg *pb.ChaincodeMessage) {
 		// Call chaincode's Run
 		// Create the ChaincodeStub which the chaincode can use to callback
 		stub := new(ChaincodeStub)
-		stub.init(msg.Uuid, msg.SecurityContext)
-		res, err := handler.cc.Init(stub, input.Function, input.Args)
+		stub.init(handler, msg.Uuid, msg.SecurityContext)
+		var res []byte
+		var err error
+		if msg.Type == pb.ChaincodeMessage_UPGRADE {
+			// Chaincodes without an Upgrade function keep the state as is
+			if upgrader, ok := handler.cc.(ChaincodeUpgrader); ok {
+				res, err = upgrader.Upgrade(stub, input.Function, input.Args)
+			}
+		} else {
+			res, err = handler.cc.Init(stub, input.Function, input.Args)
+		}
 
 		// delete isTransaction entry
 		handler.deleteIsTransaction(msg.Uuid)
@@ -251,7 +262,7 @@ func (handler *Handler) enterInitState(e *fsm.Event) {
 		return
 	}
 	chaincodeLogger.Debugf("[%s]Received %s, initializing chaincode", shortuuid(msg.Uuid), msg.Type.String())
-	if msg.Type.String() == pb.ChaincodeMessage_INIT.String() {
+	if msg.Type.String() == pb.ChaincodeMessage_INIT.String() || msg.Type.String() == pb.ChaincodeMessage_UPGRADE.String() {
 		// Call the chaincode's Run function to initialize
 		handler.handleInit(msg)
 	}
@@ -289,7 +300,7 @@ func (handler *Handler) handleTransaction(msg *pb.ChaincodeMessage) {
 		// Call chaincode's Run
 		// Create the ChaincodeStub which the chaincode can use to callback
 		stub := new(ChaincodeStub)
-		stub.init(msg.Uuid, msg.SecurityContext)
+		stub.init(handler, msg.Uuid, msg.SecurityContext)
 		res, err := handler.cc.Invoke(stub, input.Function, input.Args)
 
 		// delete isTransaction entry
@@ -336,7 +347,7 @@ func (handler *Handler) handleQuery(msg *pb.ChaincodeMessage) {
 		// Call chaincode's Query
 		// Create the ChaincodeStub which the chaincode can use to callback
 		stub := new(ChaincodeStub)
-		stub.init(msg.Uuid, msg.SecurityContext)
+		stub.init(handler, msg.Uuid, msg.SecurityContext)
 		res, err := handler.cc.Query(stub, input.Function, input.Args)
 
 		// delete isTransaction entry
@@ -631,6 +642,62 @@ func (handler *Handler) handleRangeQueryState(startKey, endKey string, uuid stri
 	return nil, errors.New("Incorrect chaincode message received")
 }
 
+// handleGetQueryResult communicates with the validator to run a rich query
+// over the committed state.
+func (handler *Handler) handleGetQueryResult(query string, uuid string) (*pb.RangeQueryStateResponse, error) {
+	// Create the channel on which to communicate the response from validating peer
+	respChan, uniqueReqErr := handler.createChannel(uuid)
+	if uniqueReqErr != nil {
+		chaincodeLogger.Debugf("[%s]Another state request pending for this Uuid. Cannot process.", shortuuid(uuid))
+		return nil, uniqueReqErr
+	}
+
+	defer handler.deleteChannel(uuid)
+
+	// Send GET_QUERY_RESULT message to validator chaincode support
+	payload := &pb.GetQueryResult{Query: query}
+	payloadBytes, err := proto.Marshal(payload)
+	if err != nil {
+		return nil, errors.New("Failed to process rich query request")
+	}
+	msg := &pb.ChaincodeMessage{Type: pb.ChaincodeMessage_GET_QUERY_RESULT, Payload: payloadBytes, Uuid: uuid}
+	chaincodeLogger.Debugf("[%s]Sending %s", shortuuid(msg.Uuid), pb.ChaincodeMessage_GET_QUERY_RESULT)
+	if err = handler.serialSend(msg); err != nil {
+		chaincodeLogger.Errorf("[%s]error sending %s", shortuuid(msg.Uuid), pb.ChaincodeMessage_GET_QUERY_RESULT)
+		return nil, errors.New("could not send msg")
+	}
+
+	// Wait on responseChannel for response
+	responseMsg, ok := handler.receiveChannel(respChan)
+	if !ok {
+		chaincodeLogger.Errorf("[%s]Received unexpected message type", uuid)
+		return nil, errors.New("Received unexpected message type")
+	}
+
+	if responseMsg.Type.String() == pb.ChaincodeMessage_RESPONSE.String() {
+		// Success response
+		chaincodeLogger.Debugf("[%s]Received %s. Successfully got query result", shortuuid(responseMsg.Uuid), pb.ChaincodeMessage_RESPONSE)
+
+		queryResponse := &pb.RangeQueryStateResponse{}
+		unmarshalErr := proto.Unmarshal(responseMsg.Payload, queryResponse)
+		if unmarshalErr != nil {
+			chaincodeLogger.Errorf("[%s]unmarshall error", shortuuid(responseMsg.Uuid))
+			return nil, errors.New("Error unmarshalling RangeQueryStateResponse.")
+		}
+
+		return queryResponse, nil
+	}
+	if responseMsg.Type.String() == pb.ChaincodeMessage_ERROR.String() {
+		// Error response
+		chaincodeLogger.Errorf("[%s]Received %s", shortuuid(responseMsg.Uuid), pb.ChaincodeMessage_ERROR)
+		return nil, errors.New(string(responseMsg.Payload[:]))
+	}
+
+	// Incorrect chaincode message received
+	chaincodeLogger.Errorf("Incorrect chaincode message %s recieved. Expecting %s or %s", responseMsg.Type, pb.ChaincodeMessage_RESPONSE, pb.ChaincodeMessage_ERROR)
+	return nil, errors.New("Incorrect chaincode message received")
+}
+
 func (handler *Handler) handleRangeQueryStateNext(id, uuid string) (*pb.RangeQueryStateResponse, error) {
 	// Create the channel on which to communicate the response from validating peer
 	respChan, uniqueReqErr := handler.createChannel(uuid)
@@ -739,6 +806,62 @@ func (handler *Handler) handleRangeQueryStateClose(id, uuid string) (*pb.RangeQu
 	return nil, errors.New("Incorrect chaincode message received")
 }
 
+// handleGetHistoryForKey communicates with the validator to fetch the committed
+// changes of a key.
+func (handler *Handler) handleGetHistoryForKey(key string, uuid string) (*pb.HistoryQueryResponse, error) {
+	// Create the channel on which to communicate the response from validating peer
+	respChan, uniqueReqErr := handler.createChannel(uuid)
+	if uniqueReqErr != nil {
+		chaincodeLogger.Debugf("[%s]Another state request pending for this Uuid. Cannot process.", shortuuid(uuid))
+		return nil, uniqueReqErr
+	}
+
+	defer handler.deleteChannel(uuid)
+
+	// Send GET_HISTORY_FOR_KEY message to validator chaincode support
+	payload := &pb.GetHistoryForKey{Key: key}
+	payloadBytes, err := proto.Marshal(payload)
+	if err != nil {
+		return nil, errors.New("Failed to process get history for key request")
+	}
+	msg := &pb.ChaincodeMessage{Type: pb.ChaincodeMessage_GET_HISTORY_FOR_KEY, Payload: payloadBytes, Uuid: uuid}
+	chaincodeLogger.Debugf("[%s]Sending %s", shortuuid(msg.Uuid), pb.ChaincodeMessage_GET_HISTORY_FOR_KEY)
+	if err = handler.serialSend(msg); err != nil {
+		chaincodeLogger.Errorf("[%s]error sending %s", shortuuid(msg.Uuid), pb.ChaincodeMessage_GET_HISTORY_FOR_KEY)
+		return nil, errors.New("could not send msg")
+	}
+
+	// Wait on responseChannel for response
+	responseMsg, ok := handler.receiveChannel(respChan)
+	if !ok {
+		chaincodeLogger.Errorf("[%s]Received unexpected message type", uuid)
+		return nil, errors.New("Received unexpected message type")
+	}
+
+	if responseMsg.Type.String() == pb.ChaincodeMessage_RESPONSE.String() {
+		// Success response
+		chaincodeLogger.Debugf("[%s]Received %s. Successfully got history", shortuuid(responseMsg.Uuid), pb.ChaincodeMessage_RESPONSE)
+
+		historyResponse := &pb.HistoryQueryResponse{}
+		unmarshalErr := proto.Unmarshal(responseMsg.Payload, historyResponse)
+		if unmarshalErr != nil {
+			chaincodeLogger.Errorf("[%s]unmarshall error", shortuuid(responseMsg.Uuid))
+			return nil, errors.New("Error unmarshalling HistoryQueryResponse.")
+		}
+
+		return historyResponse, nil
+	}
+	if responseMsg.Type.String() == pb.ChaincodeMessage_ERROR.String() {
+		// Error response
+		chaincodeLogger.Errorf("[%s]Received %s", shortuuid(responseMsg.Uuid), pb.ChaincodeMessage_ERROR)
+		return nil, errors.New(string(responseMsg.Payload[:]))
+	}
+
+	// Incorrect chaincode message received
+	chaincodeLogger.Errorf("Incorrect chaincode message %s recieved. Expecting %s or %s", responseMsg.Type, pb.ChaincodeMessage_RESPONSE, pb.ChaincodeMessage_ERROR)
+	return nil, errors.New("Incorrect chaincode message received")
+}
+
 // handleInvokeChaincode communicates with the validator to invoke another chaincode.
 func (handler *Handler) handleInvokeChaincode(chaincodeName string, function string, args []string, uuid string) ([]byte, error) {
 	// Check if this is a transaction
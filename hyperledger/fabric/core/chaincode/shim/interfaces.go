@@ -0,0 +1,254 @@
+/*
+Copyright IBM Corp. 2016 All Rights Reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+		 http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package shim
+
+import (
+	gp "google/protobuf"
+
+	"github.com/hyperledger/fabric/core/chaincode/shim/crypto/attr"
+	pb "github.com/hyperledger/fabric/protos"
+)
+
+// Chaincode interface must be implemented by all chaincodes. The fabric runs
+// the transactions by calling these functions as specified.
+type Chaincode interface {
+	// Init is called during Deploy transaction after the container has been
+	// established, allowing the chaincode to initialize its internal data
+	Init(stub ChaincodeStubInterface, function string, args []string) ([]byte, error)
+
+	// Invoke is called for every Invoke transactions. The chaincode may change
+	// its state variables
+	Invoke(stub ChaincodeStubInterface, function string, args []string) ([]byte, error)
+
+	// Query is called for Query transactions. The chaincode may only read
+	// (but not modify) its state variables and return the result
+	Query(stub ChaincodeStubInterface, function string, args []string) ([]byte, error)
+}
+
+// ChaincodeUpgrader may be implemented by chaincodes that need to migrate their
+// state when they are upgraded.
+type ChaincodeUpgrader interface {
+	// Upgrade is called during Upgrade transaction after the container of the
+	// new version has been established, in place of Init. The state of the
+	// previous version has already been copied and may be migrated here
+	Upgrade(stub ChaincodeStubInterface, function string, args []string) ([]byte, error)
+}
+
+// ChaincodeStubInterface is used by deployable chaincode apps to access and
+// modify their ledgers. ChaincodeStub is the implementation used when the
+// chaincode runs against a peer; MockStub is an in-memory implementation for
+// unit testing chaincode.
+type ChaincodeStubInterface interface {
+	// GetTxID returns the UUID of the transaction being executed
+	GetTxID() string
+
+	// InvokeChaincode locally calls the specified chaincode `Invoke` using the
+	// same transaction context; that is, chaincode calling chaincode doesn't
+	// create a new transaction message.
+	InvokeChaincode(chaincodeName string, function string, args []string) ([]byte, error)
+
+	// QueryChaincode locally calls the specified chaincode `Query` using the
+	// same transaction context; that is, chaincode calling chaincode doesn't
+	// create a new transaction message.
+	QueryChaincode(chaincodeName string, function string, args []string) ([]byte, error)
+
+	// GetState returns the byte array value specified by the `key`.
+	GetState(key string) ([]byte, error)
+
+	// PutState writes the specified `value` and `key` into the ledger.
+	PutState(key string, value []byte) error
+
+	// DelState removes the specified `key` and its value from the ledger.
+	DelState(key string) error
+
+	// RangeQueryState function can be invoked by a chaincode to query of a range
+	// of keys in the state. Assuming the startKey and endKey are in lexical
+	// order, an iterator will be returned that can be used to iterate over all
+	// keys between the startKey and endKey, inclusive.
+	RangeQueryState(startKey, endKey string) (StateRangeQueryIteratorInterface, error)
+
+	// GetHistoryForKey returns an iterator over the changes of the `key`
+	// committed to the ledger, oldest first. It is only supported in queries.
+	GetHistoryForKey(key string) (HistoryQueryIteratorInterface, error)
+
+	// GetQueryResult runs a rich query over the JSON documents of the
+	// committed state and returns an iterator over the matching key-values.
+	// It is only supported in queries.
+	GetQueryResult(query string) (StateRangeQueryIteratorInterface, error)
+
+	// CreateCompositeKey combines the `objectType` and the `attributes` into a
+	// single key, which can be used with GetState, PutState and DelState.
+	CreateCompositeKey(objectType string, attributes []string) (string, error)
+
+	// SplitCompositeKey splits a key created by CreateCompositeKey back into
+	// its objectType and attributes.
+	SplitCompositeKey(compositeKey string) (string, []string, error)
+
+	// GetStateByPartialCompositeKey returns an iterator over the composite keys
+	// of the `objectType` whose first attributes are the given `attributes`.
+	// The order in which keys are returned by the iterator is random.
+	GetStateByPartialCompositeKey(objectType string, attributes []string) (StateRangeQueryIteratorInterface, error)
+
+	// CreateTable creates a new table given the table name and column definitions
+	CreateTable(name string, columnDefinitions []*ColumnDefinition) error
+
+	// GetTable returns the table for the specified table name or ErrTableNotFound
+	// if the table does not exist.
+	GetTable(tableName string) (*Table, error)
+
+	// DeleteTable deletes an entire table and all associated rows.
+	DeleteTable(tableName string) error
+
+	// InsertRow inserts a new row into the specified table.
+	// Returns -
+	// true and no error if the row is successfully inserted.
+	// false and no error if a row already exists for the given key.
+	// false and a TableNotFoundError if the specified table name does not exist.
+	// false and an error if there is an unexpected error condition.
+	InsertRow(tableName string, row Row) (bool, error)
+
+	// ReplaceRow updates the row in the specified table.
+	// Returns -
+	// true and no error if the row is successfully updated.
+	// false and no error if a row does not exist the given key.
+	// flase and a TableNotFoundError if the specified table name does not exist.
+	// false and an error if there is an unexpected error condition.
+	ReplaceRow(tableName string, row Row) (bool, error)
+
+	// GetRow fetches a row from the specified table for the given key.
+	GetRow(tableName string, key []Column) (Row, error)
+
+	// GetRows returns multiple rows based on a partial key. For example, given table
+	// | A | B | C | D |
+	// where A, C and D are keys, GetRows can be called with [A, C] to return
+	// all rows that have A, C and any value for D as their key. GetRows could
+	// also be called with A only to return all rows that have A and any value
+	// for C and D as their key.
+	GetRows(tableName string, key []Column) (<-chan Row, error)
+
+	// DeleteRow deletes the row for the given key from the specified table.
+	DeleteRow(tableName string, key []Column) error
+
+	// AddColumn adds a column to an existing table. The column cannot be a key:
+	// the rows already in the table get the default value of the column, the
+	// zero value of its type if the definition has none.
+	AddColumn(tableName string, definition *ColumnDefinition) error
+
+	// CreateIndex creates an index of the table on the given columns, in order.
+	// The rows already in the table are indexed, and the index is kept up to
+	// date by InsertRow, ReplaceRow and DeleteRow.
+	CreateIndex(tableName string, columns []string) error
+
+	// GetRowsIterator is GetRows returning an iterator, which reports the errors
+	// fetching the rows instead of closing a channel.
+	GetRowsIterator(tableName string, key []Column) (RowIteratorInterface, error)
+
+	// GetRowsByIndex returns an iterator over the rows of the table matching the
+	// key in the index on the given columns, created by CreateIndex. As for
+	// GetRows, the key can be partial: given an index on [A, B], it can be
+	// called with values for A and B or for A only.
+	GetRowsByIndex(tableName string, columns []string, key []Column) (RowIteratorInterface, error)
+
+	// GetRowCount returns the number of rows in the table
+	GetRowCount(tableName string) (uint64, error)
+
+	// ReadCertAttribute is used to read an specific attribute from the
+	// transaction certificate, *attributeName* is passed as input parameter to
+	// this function.
+	ReadCertAttribute(attributeName string) ([]byte, error)
+
+	// VerifyAttribute is used to verify if the transaction certificate has an
+	// attribute with name *attributeName* and value *attributeValue* which are
+	// the input parameters received by this function.
+	VerifyAttribute(attributeName string, attributeValue []byte) (bool, error)
+
+	// VerifyAttributes does the same as VerifyAttribute but it checks for a
+	// list of attributes and their respective values instead of a single
+	// attribute/value pair
+	VerifyAttributes(attrs ...*attr.Attribute) (bool, error)
+
+	// VerifySignature verifies the transaction signature and returns `true` if
+	// correct and `false` otherwise
+	VerifySignature(certificate, signature, message []byte) (bool, error)
+
+	// GetCallerCertificate returns caller certificate
+	GetCallerCertificate() ([]byte, error)
+
+	// GetCallerMetadata returns caller metadata
+	GetCallerMetadata() ([]byte, error)
+
+	// GetBinding returns the transaction binding
+	GetBinding() ([]byte, error)
+
+	// GetPayload returns transaction payload, which is a `ChaincodeSpec` defined
+	// in fabric/protos/chaincode.proto
+	GetPayload() ([]byte, error)
+
+	// GetTxTimestamp returns transaction created timestamp, which is currently
+	// taken from the peer receiving the transaction. Note that this timestamp
+	// may not be the same with the other peers' time.
+	GetTxTimestamp() (*gp.Timestamp, error)
+
+	// SetEvent saves the event to be sent when a transaction is made part of a block
+	SetEvent(name string, payload []byte) error
+}
+
+// StateRangeQueryIteratorInterface allows a chaincode to iterate over a range of
+// key/value pairs in the state.
+type StateRangeQueryIteratorInterface interface {
+
+	// HasNext returns true if the range query iterator contains additional keys
+	// and values.
+	HasNext() bool
+
+	// Next returns the next key and value in the range query iterator.
+	Next() (string, []byte, error)
+
+	// Close closes the range query iterator. This should be called when done
+	// reading from the iterator to free up resources.
+	Close() error
+}
+
+// RowIteratorInterface allows a chaincode to iterate over the rows of a table
+type RowIteratorInterface interface {
+
+	// HasNext returns true if the iterator contains additional rows.
+	HasNext() bool
+
+	// Next returns the next row of the iterator.
+	Next() (Row, error)
+
+	// Close closes the iterator. This should be called when done reading from
+	// the iterator to free up resources.
+	Close() error
+}
+
+// HistoryQueryIteratorInterface allows a chaincode to iterate over the
+// committed changes of a key.
+type HistoryQueryIteratorInterface interface {
+
+	// HasNext returns true if the history query iterator contains additional
+	// changes.
+	HasNext() bool
+
+	// Next returns the next change in the history query iterator.
+	Next() (*pb.KeyModification, error)
+
+	// Close closes the history query iterator. This should be called when done
+	// reading from the iterator to free up resources.
+	Close() error
+}
@@ -0,0 +1,596 @@
+/*
+Copyright IBM Corp. 2016 All Rights Reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+		 http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package shim
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	gp "google/protobuf"
+
+	"github.com/hyperledger/fabric/core/chaincode/shim/crypto/attr"
+	"github.com/hyperledger/fabric/core/chaincode/shim/crypto/ecdsa"
+	"github.com/hyperledger/fabric/core/ledger/query"
+	pb "github.com/hyperledger/fabric/protos"
+	"github.com/op/go-logging"
+)
+
+// Logger for the MockStub.
+var mockLogger = logging.MustGetLogger("mock")
+
+// mockEventsBufferSize is the number of chaincode events the MockStub keeps
+// before it starts dropping them.
+const mockEventsBufferSize = 100
+
+// MockStub is an implementation of ChaincodeStubInterface for unit testing
+// chaincode. Use this instead of ChaincodeStub in your chaincode's unit test
+// calls to Init, Query or Invoke. State is kept in an in-memory map whose keys
+// are also maintained in lexical order so that range queries behave like they
+// do against the ledger.
+type MockStub struct {
+	// A pointer back to the chaincode that will invoke this, set by constructor.
+	// If a peer calls this stub, the chaincode will be invoked from here.
+	cc Chaincode
+
+	// A nice name that can be used for logging
+	Name string
+
+	// State keeps name value pairs
+	State map[string][]byte
+
+	// Keys stores the list of mapped values in lexical order
+	Keys []string
+
+	// Invokables is the registered list of other MockStub chaincodes that can
+	// be called from this MockStub through InvokeChaincode or QueryChaincode
+	Invokables map[string]*MockStub
+
+	// TxID stores a transaction uuid while being Invoked / Deployed
+	TxID string
+
+	// SecurityContext is returned to the chaincode through GetCallerCertificate,
+	// GetCallerMetadata, GetBinding, GetPayload and the attribute functions.
+	// Tests may set it before calling MockInit, MockInvoke or MockQuery.
+	SecurityContext *pb.ChaincodeSecurityContext
+
+	// ChaincodeEventsChannel receives the event set with SetEvent by every
+	// successfully completed transaction
+	ChaincodeEventsChannel chan *pb.ChaincodeEvent
+
+	// History keeps the committed changes of every key, oldest first. Each
+	// successfully completed transaction is recorded as its own block.
+	History map[string][]*pb.KeyModification
+
+	// blockNumber is the number of the block recording the next transaction
+	blockNumber uint64
+
+	// transaction context, only valid between MockTransactionStart and
+	// MockTransactionEnd
+	isTransaction  bool
+	txTimestamp    *gp.Timestamp
+	chaincodeEvent *pb.ChaincodeEvent
+	savedState     map[string][]byte
+	savedKeys      []string
+	rollbacks      []func()
+	txWrites       []*mockWrite
+}
+
+// mockWrite is a state change done by the current transaction
+type mockWrite struct {
+	key          string
+	modification *pb.KeyModification
+}
+
+// NewMockStub constructs a MockStub with the given name for the chaincode cc
+func NewMockStub(name string, cc Chaincode) *MockStub {
+	mockLogger.Debugf("MockStub(%s, %v)", name, cc)
+	s := new(MockStub)
+	s.Name = name
+	s.cc = cc
+	s.State = make(map[string][]byte)
+	s.Invokables = make(map[string]*MockStub)
+	s.ChaincodeEventsChannel = make(chan *pb.ChaincodeEvent, mockEventsBufferSize)
+	s.History = make(map[string][]*pb.KeyModification)
+	return s
+}
+
+// GetTxID returns the UUID of the transaction currently being executed
+func (stub *MockStub) GetTxID() string {
+	return stub.TxID
+}
+
+// MockTransactionStart is used to indicate to a chaincode that it is part of
+// a transaction. This is important when chaincodes invoke each other. The
+// current state is remembered so that it can be rolled back if the
+// transaction fails.
+func (stub *MockStub) MockTransactionStart(uuid string) {
+	stub.TxID = uuid
+	stub.isTransaction = true
+	stub.txTimestamp = &gp.Timestamp{Seconds: time.Now().Unix(), Nanos: 0}
+	stub.chaincodeEvent = nil
+	stub.rollbacks = nil
+	stub.txWrites = nil
+	stub.savedState = make(map[string][]byte, len(stub.State))
+	for k, v := range stub.State {
+		stub.savedState[k] = v
+	}
+	stub.savedKeys = append([]string(nil), stub.Keys...)
+}
+
+// MockTransactionEnd ends a mocked transaction, clearing the UUID. If err is
+// not nil all state changes done by the transaction, including the ones done
+// by chaincodes it invoked, are rolled back. Otherwise the chaincode event set
+// by the transaction, if any, is published on ChaincodeEventsChannel and its
+// state changes are added to History.
+func (stub *MockStub) MockTransactionEnd(uuid string, err error) {
+	if err != nil {
+		mockLogger.Debugf("MockStub %s rolling back transaction %s: %s", stub.Name, uuid, err)
+		for i := len(stub.rollbacks) - 1; i >= 0; i-- {
+			stub.rollbacks[i]()
+		}
+		stub.State = stub.savedState
+		stub.Keys = stub.savedKeys
+	} else {
+		for _, write := range stub.txWrites {
+			write.modification.BlockNumber = stub.blockNumber
+			stub.History[write.key] = append(stub.History[write.key], write.modification)
+		}
+		stub.blockNumber++
+		if stub.chaincodeEvent != nil {
+			select {
+			case stub.ChaincodeEventsChannel <- stub.chaincodeEvent:
+			default:
+				mockLogger.Warningf("MockStub %s dropping chaincode event %s, channel is full", stub.Name, stub.chaincodeEvent.EventName)
+			}
+		}
+	}
+	stub.TxID = ""
+	stub.isTransaction = false
+	stub.txTimestamp = nil
+	stub.chaincodeEvent = nil
+	stub.savedState = nil
+	stub.savedKeys = nil
+	stub.rollbacks = nil
+	stub.txWrites = nil
+}
+
+// MockPeerChaincode registers a MockStub chaincode that can be called by
+// this MockStub through InvokeChaincode and QueryChaincode
+func (stub *MockStub) MockPeerChaincode(invokableChaincodeName string, otherStub *MockStub) {
+	stub.Invokables[invokableChaincodeName] = otherStub
+}
+
+// MockInit initializes this chaincode, also starts and ends a transaction.
+func (stub *MockStub) MockInit(uuid string, function string, args []string) ([]byte, error) {
+	stub.MockTransactionStart(uuid)
+	bytes, err := stub.cc.Init(stub, function, args)
+	stub.MockTransactionEnd(uuid, err)
+	return bytes, err
+}
+
+// MockUpgrade replaces this chaincode with its new version cc, keeping the
+// state, and calls its Upgrade function if it implements ChaincodeUpgrader.
+// The previous version is kept if the upgrade fails.
+func (stub *MockStub) MockUpgrade(uuid string, cc Chaincode, function string, args []string) ([]byte, error) {
+	previous := stub.cc
+	stub.cc = cc
+	stub.MockTransactionStart(uuid)
+	var bytes []byte
+	var err error
+	if upgrader, ok := cc.(ChaincodeUpgrader); ok {
+		bytes, err = upgrader.Upgrade(stub, function, args)
+	}
+	stub.MockTransactionEnd(uuid, err)
+	if err != nil {
+		stub.cc = previous
+	}
+	return bytes, err
+}
+
+// MockInvoke invokes this chaincode, also starts and ends a transaction.
+func (stub *MockStub) MockInvoke(uuid string, function string, args []string) ([]byte, error) {
+	stub.MockTransactionStart(uuid)
+	bytes, err := stub.cc.Invoke(stub, function, args)
+	stub.MockTransactionEnd(uuid, err)
+	return bytes, err
+}
+
+// MockQuery queries this chaincode. State may be read but not modified.
+func (stub *MockStub) MockQuery(function string, args []string) ([]byte, error) {
+	stub.isTransaction = false
+	stub.txTimestamp = &gp.Timestamp{Seconds: time.Now().Unix(), Nanos: 0}
+	bytes, err := stub.cc.Query(stub, function, args)
+	stub.txTimestamp = nil
+	return bytes, err
+}
+
+// InvokeChaincode calls the registered MockStub chaincode `Invoke` using the
+// same transaction UUID. If the calling transaction later fails, the changes
+// done by the called chaincode are rolled back as well.
+func (stub *MockStub) InvokeChaincode(chaincodeName string, function string, args []string) ([]byte, error) {
+	if !stub.isTransaction {
+		return nil, errors.New("Cannot invoke chaincode in query context")
+	}
+	otherStub, ok := stub.Invokables[chaincodeName]
+	if !ok {
+		return nil, fmt.Errorf("Chaincode %s is not registered with MockStub %s", chaincodeName, stub.Name)
+	}
+	mockLogger.Debugf("MockStub %s invoking peered chaincode %s", stub.Name, otherStub.Name)
+
+	restore := otherStub.snapshot()
+	otherStub.MockTransactionStart(stub.TxID)
+	bytes, err := otherStub.cc.Invoke(otherStub, function, args)
+	nested := otherStub.rollbacks
+	otherStub.MockTransactionEnd(stub.TxID, err)
+	if err == nil {
+		// the chaincodes the callee invoked are rolled back along with it
+		stub.rollbacks = append(stub.rollbacks, func() {
+			for i := len(nested) - 1; i >= 0; i-- {
+				nested[i]()
+			}
+			restore()
+		})
+	}
+	return bytes, err
+}
+
+// QueryChaincode calls the registered MockStub chaincode `Query`.
+func (stub *MockStub) QueryChaincode(chaincodeName string, function string, args []string) ([]byte, error) {
+	otherStub, ok := stub.Invokables[chaincodeName]
+	if !ok {
+		return nil, fmt.Errorf("Chaincode %s is not registered with MockStub %s", chaincodeName, stub.Name)
+	}
+	mockLogger.Debugf("MockStub %s querying peered chaincode %s", stub.Name, otherStub.Name)
+	return otherStub.MockQuery(function, args)
+}
+
+// GetState retrieves the value for a given key from the in-memory state
+func (stub *MockStub) GetState(key string) ([]byte, error) {
+	value := stub.State[key]
+	mockLogger.Debugf("MockStub %s getting %s = %s", stub.Name, key, value)
+	return value, nil
+}
+
+// PutState writes the specified `value` and `key` into the in-memory state.
+func (stub *MockStub) PutState(key string, value []byte) error {
+	if !stub.isTransaction {
+		return errors.New("Cannot put state in query context")
+	}
+	mockLogger.Debugf("MockStub %s putting %s = %s", stub.Name, key, value)
+
+	if _, ok := stub.State[key]; !ok {
+		i := sort.SearchStrings(stub.Keys, key)
+		stub.Keys = append(stub.Keys, "")
+		copy(stub.Keys[i+1:], stub.Keys[i:])
+		stub.Keys[i] = key
+	}
+	stub.State[key] = value
+	stub.addTxWrite(key, &pb.KeyModification{TxID: stub.TxID, Value: value})
+	return nil
+}
+
+// DelState removes the specified `key` and its value from the in-memory state.
+func (stub *MockStub) DelState(key string) error {
+	if !stub.isTransaction {
+		return errors.New("Cannot del state in query context")
+	}
+	mockLogger.Debugf("MockStub %s deleting %s = %s", stub.Name, key, stub.State[key])
+
+	if _, ok := stub.State[key]; ok {
+		i := sort.SearchStrings(stub.Keys, key)
+		stub.Keys = append(stub.Keys[:i], stub.Keys[i+1:]...)
+		delete(stub.State, key)
+		stub.addTxWrite(key, &pb.KeyModification{TxID: stub.TxID, IsDelete: true})
+	}
+	return nil
+}
+
+// RangeQueryState returns an iterator over all keys between the startKey and
+// endKey, inclusive, in lexical order. An empty endKey has no upper bound.
+func (stub *MockStub) RangeQueryState(startKey, endKey string) (StateRangeQueryIteratorInterface, error) {
+	iter := &MockStateRangeQueryIterator{stub: stub}
+	for i := sort.SearchStrings(stub.Keys, startKey); i < len(stub.Keys); i++ {
+		key := stub.Keys[i]
+		if endKey != "" && key > endKey {
+			break
+		}
+		iter.keys = append(iter.keys, key)
+	}
+	return iter, nil
+}
+
+// GetQueryResult returns an iterator over the key-values of the in-memory
+// state whose values are JSON documents matching the rich query. Like the
+// ledger, rich queries are only supported in queries.
+func (stub *MockStub) GetQueryResult(queryString string) (StateRangeQueryIteratorInterface, error) {
+	if stub.isTransaction {
+		return nil, errors.New("GetQueryResult is only supported in queries")
+	}
+	q, err := query.Parse(queryString)
+	if err != nil {
+		return nil, err
+	}
+	i := 0
+	kvs := q.Execute(func() (string, []byte, bool) {
+		if i == len(stub.Keys) {
+			return "", nil, false
+		}
+		i++
+		return stub.Keys[i-1], stub.State[stub.Keys[i-1]], true
+	})
+	iter := &MockStateRangeQueryIterator{stub: stub}
+	for _, kv := range kvs {
+		iter.keys = append(iter.keys, kv.Key)
+	}
+	return iter, nil
+}
+
+// addTxWrite records a state change of the current transaction. Like the
+// ledger, only the last change of a key is kept.
+func (stub *MockStub) addTxWrite(key string, modification *pb.KeyModification) {
+	for _, write := range stub.txWrites {
+		if write.key == key {
+			write.modification = modification
+			return
+		}
+	}
+	stub.txWrites = append(stub.txWrites, &mockWrite{key, modification})
+}
+
+// GetHistoryForKey returns an iterator over the changes of the key done by the
+// transactions completed so far, oldest first. It is only supported in queries.
+func (stub *MockStub) GetHistoryForKey(key string) (HistoryQueryIteratorInterface, error) {
+	if stub.isTransaction {
+		return nil, errors.New("GetHistoryForKey is only supported in queries")
+	}
+	modifications := stub.History[key]
+	return &HistoryQueryIterator{&pb.HistoryQueryResponse{Modifications: modifications[:len(modifications):len(modifications)]}, 0}, nil
+}
+
+// snapshot returns a function restoring the state and history of the stub as
+// they are now
+func (stub *MockStub) snapshot() func() {
+	state := make(map[string][]byte, len(stub.State))
+	for k, v := range stub.State {
+		state[k] = v
+	}
+	keys := append([]string(nil), stub.Keys...)
+	history := make(map[string][]*pb.KeyModification, len(stub.History))
+	for k, v := range stub.History {
+		history[k] = v
+	}
+	blockNumber := stub.blockNumber
+	return func() {
+		stub.State = state
+		stub.Keys = keys
+		stub.History = history
+		stub.blockNumber = blockNumber
+	}
+}
+
+// CreateCompositeKey combines the objectType and the attributes into a single
+// key, as ChaincodeStub does.
+func (stub *MockStub) CreateCompositeKey(objectType string, attributes []string) (string, error) {
+	return createCompositeKey(objectType, attributes)
+}
+
+// SplitCompositeKey splits a key created by CreateCompositeKey back into its
+// objectType and attributes.
+func (stub *MockStub) SplitCompositeKey(compositeKey string) (string, []string, error) {
+	return splitCompositeKey(compositeKey)
+}
+
+// GetStateByPartialCompositeKey returns an iterator over the composite keys of
+// the objectType starting with the given attributes. The MockStub returns them
+// in lexical order, a peer in random order.
+func (stub *MockStub) GetStateByPartialCompositeKey(objectType string, attributes []string) (StateRangeQueryIteratorInterface, error) {
+	return getStateByPartialCompositeKeyInternal(stub, objectType, attributes)
+}
+
+// CreateTable creates a new table given the table name and column definitions
+func (stub *MockStub) CreateTable(name string, columnDefinitions []*ColumnDefinition) error {
+	return createTableInternal(stub, name, columnDefinitions)
+}
+
+// GetTable returns the table for the specified table name or ErrTableNotFound
+// if the table does not exist.
+func (stub *MockStub) GetTable(tableName string) (*Table, error) {
+	return getTable(stub, tableName)
+}
+
+// DeleteTable deletes an entire table and all associated rows.
+func (stub *MockStub) DeleteTable(tableName string) error {
+	return deleteTableInternal(stub, tableName)
+}
+
+// InsertRow inserts a new row into the specified table.
+func (stub *MockStub) InsertRow(tableName string, row Row) (bool, error) {
+	return insertRowInternal(stub, tableName, row, false)
+}
+
+// ReplaceRow updates the row in the specified table.
+func (stub *MockStub) ReplaceRow(tableName string, row Row) (bool, error) {
+	return insertRowInternal(stub, tableName, row, true)
+}
+
+// GetRow fetches a row from the specified table for the given key.
+func (stub *MockStub) GetRow(tableName string, key []Column) (Row, error) {
+	return getRowInternal(stub, tableName, key)
+}
+
+// GetRows returns multiple rows based on a partial key.
+func (stub *MockStub) GetRows(tableName string, key []Column) (<-chan Row, error) {
+	return getRowsInternal(stub, tableName, key)
+}
+
+// DeleteRow deletes the row for the given key from the specified table.
+func (stub *MockStub) DeleteRow(tableName string, key []Column) error {
+	return deleteRowInternal(stub, tableName, key)
+}
+
+// AddColumn adds a non-key column to an existing table.
+func (stub *MockStub) AddColumn(tableName string, definition *ColumnDefinition) error {
+	return addColumnInternal(stub, tableName, definition)
+}
+
+// CreateIndex creates an index of the table on the given columns.
+func (stub *MockStub) CreateIndex(tableName string, columns []string) error {
+	return createIndexInternal(stub, tableName, columns)
+}
+
+// GetRowsIterator returns an iterator over the rows matching a partial key.
+func (stub *MockStub) GetRowsIterator(tableName string, key []Column) (RowIteratorInterface, error) {
+	return getRowsIteratorInternal(stub, tableName, key)
+}
+
+// GetRowsByIndex returns an iterator over the rows matching a partial key in
+// the index on the given columns.
+func (stub *MockStub) GetRowsByIndex(tableName string, columns []string, key []Column) (RowIteratorInterface, error) {
+	return getRowsByIndexInternal(stub, tableName, columns, key)
+}
+
+// GetRowCount returns the number of rows in the table.
+func (stub *MockStub) GetRowCount(tableName string) (uint64, error) {
+	return getRowCountInternal(stub, tableName)
+}
+
+// ReadCertAttribute reads an attribute from the caller certificate set in
+// SecurityContext.
+func (stub *MockStub) ReadCertAttribute(attributeName string) ([]byte, error) {
+	attributesHandler, err := attr.NewAttributesHandlerImpl(stub)
+	if err != nil {
+		return nil, err
+	}
+	return attributesHandler.GetValue(attributeName)
+}
+
+// VerifyAttribute verifies an attribute of the caller certificate set in
+// SecurityContext.
+func (stub *MockStub) VerifyAttribute(attributeName string, attributeValue []byte) (bool, error) {
+	attributesHandler, err := attr.NewAttributesHandlerImpl(stub)
+	if err != nil {
+		return false, err
+	}
+	return attributesHandler.VerifyAttribute(attributeName, attributeValue)
+}
+
+// VerifyAttributes verifies a list of attributes of the caller certificate set
+// in SecurityContext.
+func (stub *MockStub) VerifyAttributes(attrs ...*attr.Attribute) (bool, error) {
+	attributesHandler, err := attr.NewAttributesHandlerImpl(stub)
+	if err != nil {
+		return false, err
+	}
+	return attributesHandler.VerifyAttributes(attrs...)
+}
+
+// VerifySignature verifies the signature and returns `true` if correct and
+// `false` otherwise
+func (stub *MockStub) VerifySignature(certificate, signature, message []byte) (bool, error) {
+	return ecdsa.NewX509ECDSASignatureVerifier().Verify(certificate, signature, message)
+}
+
+// GetCallerCertificate returns the caller certificate set in SecurityContext
+func (stub *MockStub) GetCallerCertificate() ([]byte, error) {
+	if stub.SecurityContext == nil {
+		return nil, nil
+	}
+	return stub.SecurityContext.CallerCert, nil
+}
+
+// GetCallerMetadata returns the caller metadata set in SecurityContext
+func (stub *MockStub) GetCallerMetadata() ([]byte, error) {
+	if stub.SecurityContext == nil {
+		return nil, nil
+	}
+	return stub.SecurityContext.Metadata, nil
+}
+
+// GetBinding returns the transaction binding set in SecurityContext
+func (stub *MockStub) GetBinding() ([]byte, error) {
+	if stub.SecurityContext == nil {
+		return nil, nil
+	}
+	return stub.SecurityContext.Binding, nil
+}
+
+// GetPayload returns the transaction payload set in SecurityContext
+func (stub *MockStub) GetPayload() ([]byte, error) {
+	if stub.SecurityContext == nil {
+		return nil, nil
+	}
+	return stub.SecurityContext.Payload, nil
+}
+
+// GetTxTimestamp returns the timestamp set in SecurityContext or, if none was
+// set, the time the current mocked transaction or query started.
+func (stub *MockStub) GetTxTimestamp() (*gp.Timestamp, error) {
+	if stub.SecurityContext != nil && stub.SecurityContext.TxTimestamp != nil {
+		return stub.SecurityContext.TxTimestamp, nil
+	}
+	if stub.txTimestamp == nil {
+		return nil, errors.New("TxTimestamp not set")
+	}
+	return stub.txTimestamp, nil
+}
+
+// SetEvent saves the event to be published on ChaincodeEventsChannel when the
+// current transaction completes successfully
+func (stub *MockStub) SetEvent(name string, payload []byte) error {
+	stub.chaincodeEvent = &pb.ChaincodeEvent{EventName: name, Payload: payload}
+	return nil
+}
+
+// MockStateRangeQueryIterator iterates over the keys of a MockStub that
+// matched a RangeQueryState call.
+type MockStateRangeQueryIterator struct {
+	Closed     bool
+	stub       *MockStub
+	keys       []string
+	currentLoc int
+}
+
+// HasNext returns true if the range query iterator contains additional keys
+// and values.
+func (iter *MockStateRangeQueryIterator) HasNext() bool {
+	if iter.Closed {
+		return false
+	}
+	return iter.currentLoc < len(iter.keys)
+}
+
+// Next returns the next key and value in the range query iterator.
+func (iter *MockStateRangeQueryIterator) Next() (string, []byte, error) {
+	if iter.Closed {
+		return "", nil, errors.New("MockStateRangeQueryIterator.Next() called after Close()")
+	}
+	if iter.currentLoc >= len(iter.keys) {
+		return "", nil, errors.New("No such key")
+	}
+	key := iter.keys[iter.currentLoc]
+	iter.currentLoc++
+	return key, iter.stub.State[key], nil
+}
+
+// Close closes the range query iterator. This should be called when done
+// reading from the iterator to free up resources.
+func (iter *MockStateRangeQueryIterator) Close() error {
+	iter.Closed = true
+	return nil
+}
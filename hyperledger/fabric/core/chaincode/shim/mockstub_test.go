@@ -0,0 +1,565 @@
+/*
+Copyright IBM Corp. 2016 All Rights Reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package shim
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+// mockTestChaincode is a minimal chaincode used to exercise the MockStub.
+// Invoke "put" stores args[0]=args[1], "putfail" stores and then fails,
+// "event" sets a chaincode event, and "call" invokes args[0] with "put"
+// args[1:] and then fails if args[1] is "fail".
+type mockTestChaincode struct {
+}
+
+func (t *mockTestChaincode) Init(stub ChaincodeStubInterface, function string, args []string) ([]byte, error) {
+	return nil, stub.PutState("init", []byte(function))
+}
+
+func (t *mockTestChaincode) Invoke(stub ChaincodeStubInterface, function string, args []string) ([]byte, error) {
+	switch function {
+	case "put":
+		return nil, stub.PutState(args[0], []byte(args[1]))
+	case "del":
+		return nil, stub.DelState(args[0])
+	case "putfail":
+		if err := stub.PutState(args[0], []byte(args[1])); err != nil {
+			return nil, err
+		}
+		return nil, errors.New("putfail")
+	case "event":
+		return nil, stub.SetEvent(args[0], []byte(args[1]))
+	case "call":
+		if _, err := stub.InvokeChaincode(args[0], "put", args[1:]); err != nil {
+			return nil, err
+		}
+		if args[1] == "fail" {
+			return nil, errors.New("call failed")
+		}
+		return nil, nil
+	case "relay":
+		if err := stub.PutState(args[1], []byte(args[2])); err != nil {
+			return nil, err
+		}
+		_, err := stub.InvokeChaincode(args[0], "put", args[1:])
+		return nil, err
+	case "relayfail":
+		if _, err := stub.InvokeChaincode(args[0], "relay", args[1:]); err != nil {
+			return nil, err
+		}
+		return nil, errors.New("relay failed")
+	case "history":
+		_, err := stub.GetHistoryForKey(args[0])
+		return nil, err
+	}
+	return nil, errors.New("Unknown function " + function)
+}
+
+func (t *mockTestChaincode) Query(stub ChaincodeStubInterface, function string, args []string) ([]byte, error) {
+	if function == "put" {
+		return nil, stub.PutState(args[0], []byte(args[1]))
+	}
+	return stub.GetState(args[0])
+}
+
+// mockUpgradedChaincode is the new version of mockTestChaincode. Its Upgrade
+// function renames the key args[0] to args[1], and fails if args[0] is unset.
+type mockUpgradedChaincode struct {
+	mockTestChaincode
+}
+
+func (t *mockUpgradedChaincode) Upgrade(stub ChaincodeStubInterface, function string, args []string) ([]byte, error) {
+	value, err := stub.GetState(args[0])
+	if err != nil {
+		return nil, err
+	}
+	if value == nil {
+		return nil, errors.New("missing key " + args[0])
+	}
+	if err = stub.DelState(args[0]); err != nil {
+		return nil, err
+	}
+	return nil, stub.PutState(args[1], value)
+}
+
+func checkMockState(t *testing.T, stub *MockStub, key string, expected string) {
+	value, err := stub.GetState(key)
+	if err != nil {
+		t.Fatalf("GetState(%s) failed: %s", key, err)
+	}
+	if string(value) != expected {
+		t.Fatalf("Expected %s=[%s], got [%s]", key, expected, value)
+	}
+}
+
+func TestMockStubInitInvokeQuery(t *testing.T) {
+	stub := NewMockStub("test", new(mockTestChaincode))
+
+	if _, err := stub.MockInit("1", "hello", nil); err != nil {
+		t.Fatalf("MockInit failed: %s", err)
+	}
+	checkMockState(t, stub, "init", "hello")
+
+	if _, err := stub.MockInvoke("2", "put", []string{"a", "1"}); err != nil {
+		t.Fatalf("MockInvoke failed: %s", err)
+	}
+	value, err := stub.MockQuery("get", []string{"a"})
+	if err != nil {
+		t.Fatalf("MockQuery failed: %s", err)
+	}
+	if string(value) != "1" {
+		t.Fatalf("Expected query to return 1, got %s", value)
+	}
+
+	if _, err := stub.MockQuery("put", []string{"a", "2"}); err == nil {
+		t.Fatalf("Expected PutState to fail in query context")
+	}
+	checkMockState(t, stub, "a", "1")
+	if stub.GetTxID() != "" {
+		t.Fatalf("Expected TxID to be cleared after the transaction, got %s", stub.GetTxID())
+	}
+}
+
+func TestMockStubUpgrade(t *testing.T) {
+	stub := NewMockStub("test", new(mockTestChaincode))
+	stub.MockInvoke("1", "put", []string{"a", "1"})
+
+	// a failed upgrade keeps the previous version and its state
+	previous := stub.cc
+	upgraded := new(mockUpgradedChaincode)
+	if _, err := stub.MockUpgrade("2", upgraded, "upgrade", []string{"b", "c"}); err == nil {
+		t.Fatalf("Expected MockUpgrade to fail")
+	}
+	if stub.cc != previous {
+		t.Fatalf("Expected the previous version to be kept")
+	}
+	checkMockState(t, stub, "a", "1")
+
+	if _, err := stub.MockUpgrade("3", upgraded, "upgrade", []string{"a", "b"}); err != nil {
+		t.Fatalf("MockUpgrade failed: %s", err)
+	}
+	checkMockState(t, stub, "a", "")
+	checkMockState(t, stub, "b", "1")
+
+	// chaincodes without an Upgrade function keep the state as is
+	if _, err := stub.MockUpgrade("4", new(mockTestChaincode), "upgrade", nil); err != nil {
+		t.Fatalf("MockUpgrade failed: %s", err)
+	}
+	checkMockState(t, stub, "b", "1")
+}
+
+func TestMockStubRollback(t *testing.T) {
+	stub := NewMockStub("test", new(mockTestChaincode))
+	stub.MockInvoke("1", "put", []string{"a", "1"})
+
+	if _, err := stub.MockInvoke("2", "putfail", []string{"a", "2"}); err == nil {
+		t.Fatalf("Expected MockInvoke to fail")
+	}
+	checkMockState(t, stub, "a", "1")
+
+	if _, err := stub.MockInvoke("3", "putfail", []string{"b", "2"}); err == nil {
+		t.Fatalf("Expected MockInvoke to fail")
+	}
+	checkMockState(t, stub, "b", "")
+	if len(stub.Keys) != 1 || stub.Keys[0] != "a" {
+		t.Fatalf("Expected keys [a] after rollback, got %v", stub.Keys)
+	}
+}
+
+func TestMockStubRangeQuery(t *testing.T) {
+	stub := NewMockStub("test", new(mockTestChaincode))
+	for _, k := range []string{"d", "b", "e", "a", "c"} {
+		stub.MockInvoke("1", "put", []string{k, k})
+	}
+	stub.MockInvoke("2", "del", []string{"c"})
+
+	iter, err := stub.RangeQueryState("b", "d")
+	if err != nil {
+		t.Fatalf("RangeQueryState failed: %s", err)
+	}
+	defer iter.Close()
+	var keys []string
+	for iter.HasNext() {
+		key, value, err := iter.Next()
+		if err != nil {
+			t.Fatalf("Next failed: %s", err)
+		}
+		if key != string(value) {
+			t.Fatalf("Expected value %s for key %s, got %s", key, key, value)
+		}
+		keys = append(keys, key)
+	}
+	if len(keys) != 2 || keys[0] != "b" || keys[1] != "d" {
+		t.Fatalf("Expected keys [b d], got %v", keys)
+	}
+}
+
+func TestMockStubPartialCompositeKey(t *testing.T) {
+	stub := NewMockStub("test", new(mockTestChaincode))
+	stub.MockTransactionStart("1")
+	for _, attributes := range [][]string{{"a", "p1"}, {"a", "p2"}, {"ab", "p1"}, {"b", "p1"}} {
+		key, err := stub.CreateCompositeKey("member~project", attributes)
+		if err != nil {
+			t.Fatalf("CreateCompositeKey failed: %s", err)
+		}
+		stub.PutState(key, []byte{})
+	}
+	otherKey, _ := stub.CreateCompositeKey("project~member", []string{"a", "p1"})
+	stub.PutState(otherKey, []byte{})
+	stub.PutState("a", []byte{})
+	stub.MockTransactionEnd("1", nil)
+
+	iter, err := stub.GetStateByPartialCompositeKey("member~project", []string{"a"})
+	if err != nil {
+		t.Fatalf("GetStateByPartialCompositeKey failed: %s", err)
+	}
+	defer iter.Close()
+	var projects []string
+	for iter.HasNext() {
+		key, _, err := iter.Next()
+		if err != nil {
+			t.Fatalf("Next failed: %s", err)
+		}
+		objectType, attributes, err := stub.SplitCompositeKey(key)
+		if err != nil {
+			t.Fatalf("SplitCompositeKey failed: %s", err)
+		}
+		if objectType != "member~project" || attributes[0] != "a" {
+			t.Fatalf("Unexpected key [%s %v]", objectType, attributes)
+		}
+		projects = append(projects, attributes[1])
+	}
+	if len(projects) != 2 || projects[0] != "p1" || projects[1] != "p2" {
+		t.Fatalf("Expected projects [p1 p2] of member a, got %v", projects)
+	}
+
+	iter, _ = stub.GetStateByPartialCompositeKey("member~project", nil)
+	count := 0
+	for iter.HasNext() {
+		iter.Next()
+		count++
+	}
+	if count != 4 {
+		t.Fatalf("Expected 4 keys of member~project, got %d", count)
+	}
+}
+
+func TestMockStubGetQueryResult(t *testing.T) {
+	stub := NewMockStub("test", new(mockTestChaincode))
+	stub.MockTransactionStart("1")
+	stub.PutState("member1", []byte(`{"docType":"Member","name":"bob","age":40}`))
+	stub.PutState("member2", []byte(`{"docType":"Member","name":"alice","age":30}`))
+	stub.PutState("project1", []byte(`{"docType":"Project","name":"ledger"}`))
+	stub.PutState("raw", []byte("not a document"))
+	if _, err := stub.GetQueryResult(`{"selector":{"docType":"Member"}}`); err == nil {
+		t.Fatalf("Expected GetQueryResult to fail in a transaction")
+	}
+	stub.MockTransactionEnd("1", nil)
+
+	iter, err := stub.GetQueryResult(`{"selector":{"docType":"Member","age":{"$lte":40}},"sort":["name"]}`)
+	if err != nil {
+		t.Fatalf("GetQueryResult failed: %s", err)
+	}
+	defer iter.Close()
+	var keys []string
+	for iter.HasNext() {
+		key, value, err := iter.Next()
+		if err != nil {
+			t.Fatalf("Next failed: %s", err)
+		}
+		if string(value) != string(stub.State[key]) {
+			t.Fatalf("Unexpected value %s of key %s", value, key)
+		}
+		keys = append(keys, key)
+	}
+	if len(keys) != 2 || keys[0] != "member2" || keys[1] != "member1" {
+		t.Fatalf("Expected the members sorted by name, got %v", keys)
+	}
+
+	if _, err = stub.GetQueryResult(`{"docType":"Member"}`); err == nil {
+		t.Fatalf("Expected GetQueryResult to fail for a query without selector")
+	}
+}
+
+func TestMockStubEvents(t *testing.T) {
+	stub := NewMockStub("test", new(mockTestChaincode))
+	if _, err := stub.MockInvoke("1", "event", []string{"evt", "payload"}); err != nil {
+		t.Fatalf("MockInvoke failed: %s", err)
+	}
+	select {
+	case event := <-stub.ChaincodeEventsChannel:
+		if event.EventName != "evt" || string(event.Payload) != "payload" {
+			t.Fatalf("Unexpected event %v", event)
+		}
+	default:
+		t.Fatalf("Expected a chaincode event")
+	}
+}
+
+func TestMockStubInvokeChaincode(t *testing.T) {
+	caller := NewMockStub("caller", new(mockTestChaincode))
+	callee := NewMockStub("callee", new(mockTestChaincode))
+	caller.MockPeerChaincode("callee", callee)
+
+	if _, err := caller.MockInvoke("1", "call", []string{"callee", "a", "1"}); err != nil {
+		t.Fatalf("MockInvoke failed: %s", err)
+	}
+	checkMockState(t, callee, "a", "1")
+
+	if _, err := caller.MockInvoke("2", "call", []string{"callee", "fail", "1"}); err == nil {
+		t.Fatalf("Expected MockInvoke to fail")
+	}
+	checkMockState(t, callee, "fail", "")
+
+	value, err := caller.QueryChaincode("callee", "get", []string{"a"})
+	if err != nil || string(value) != "1" {
+		t.Fatalf("Expected QueryChaincode to return 1, got %s, %v", value, err)
+	}
+
+	if _, err := caller.MockInvoke("3", "call", []string{"unknown", "a", "1"}); err == nil {
+		t.Fatalf("Expected invoking an unregistered chaincode to fail")
+	}
+}
+
+func TestMockStubInvokeChaincodeNestedRollback(t *testing.T) {
+	caller := NewMockStub("caller", new(mockTestChaincode))
+	relay := NewMockStub("relay", new(mockTestChaincode))
+	callee := NewMockStub("callee", new(mockTestChaincode))
+	caller.MockPeerChaincode("relay", relay)
+	relay.MockPeerChaincode("callee", callee)
+
+	if _, err := caller.MockInvoke("1", "relayfail", []string{"relay", "callee", "a", "1"}); err == nil {
+		t.Fatalf("Expected MockInvoke to fail")
+	}
+	// both the relay and the chaincode it invoked are rolled back
+	checkMockState(t, relay, "a", "")
+	checkMockState(t, callee, "a", "")
+	if len(callee.History["a"]) != 0 {
+		t.Fatalf("Expected no history for a, got %v", callee.History["a"])
+	}
+
+	if _, err := relay.MockInvoke("2", "relay", []string{"callee", "a", "2"}); err != nil {
+		t.Fatalf("MockInvoke failed: %s", err)
+	}
+	checkMockState(t, callee, "a", "2")
+}
+
+func TestMockStubTables(t *testing.T) {
+	stub := NewMockStub("test", new(mockTestChaincode))
+	stub.MockTransactionStart("1")
+	defer stub.MockTransactionEnd("1", nil)
+
+	err := stub.CreateTable("T", []*ColumnDefinition{
+		&ColumnDefinition{Name: "K", Type: ColumnDefinition_STRING, Key: true},
+		&ColumnDefinition{Name: "V", Type: ColumnDefinition_INT32, Key: false},
+	})
+	if err != nil {
+		t.Fatalf("CreateTable failed: %s", err)
+	}
+	for i, k := range []string{"x", "y"} {
+		ok, err := stub.InsertRow("T", Row{Columns: []*Column{
+			&Column{Value: &Column_String_{String_: k}},
+			&Column{Value: &Column_Int32{Int32: int32(i)}},
+		}})
+		if !ok || err != nil {
+			t.Fatalf("InsertRow failed: %v, %s", ok, err)
+		}
+	}
+	row, err := stub.GetRow("T", []Column{Column{Value: &Column_String_{String_: "y"}}})
+	if err != nil || row.Columns[1].GetInt32() != 1 {
+		t.Fatalf("GetRow returned %v, %s", row, err)
+	}
+	rows, err := stub.GetRows("T", nil)
+	if err != nil {
+		t.Fatalf("GetRows failed: %s", err)
+	}
+	count := 0
+	for range rows {
+		count++
+	}
+	if count != 2 {
+		t.Fatalf("Expected 2 rows, got %d", count)
+	}
+	if err := stub.DeleteTable("T"); err != nil {
+		t.Fatalf("DeleteTable failed: %s", err)
+	}
+	if len(stub.Keys) != 0 {
+		t.Fatalf("Expected empty state after DeleteTable, got %v", stub.Keys)
+	}
+}
+
+func TestMockStubTableIndexes(t *testing.T) {
+	stub := NewMockStub("test", new(mockTestChaincode))
+	// rowKeys returns the first columns of the rows of the iterator
+	rowKeys := func(iter RowIteratorInterface, err error) string {
+		if err != nil {
+			t.Fatalf("Error getting rows: %s", err)
+		}
+		defer iter.Close()
+		var keys []string
+		for iter.HasNext() {
+			row, err := iter.Next()
+			if err != nil {
+				t.Fatalf("Error iterating over rows: %s", err)
+			}
+			keys = append(keys, row.Columns[0].GetString_())
+		}
+		return strings.Join(keys, ",")
+	}
+	stub.MockTransactionStart("1")
+	defer stub.MockTransactionEnd("1", nil)
+
+	err := stub.CreateTable("T", []*ColumnDefinition{
+		&ColumnDefinition{Name: "K", Type: ColumnDefinition_STRING, Key: true},
+		&ColumnDefinition{Name: "Owner", Type: ColumnDefinition_STRING, Key: false},
+		&ColumnDefinition{Name: "V", Type: ColumnDefinition_INT32, Key: false},
+	})
+	if err != nil {
+		t.Fatalf("CreateTable failed: %s", err)
+	}
+	newRow := func(k, owner string, v int32) Row {
+		return Row{Columns: []*Column{
+			&Column{Value: &Column_String_{String_: k}},
+			&Column{Value: &Column_String_{String_: owner}},
+			&Column{Value: &Column_Int32{Int32: v}},
+		}}
+	}
+	owner := func(name string) []Column {
+		return []Column{Column{Value: &Column_String_{String_: name}}}
+	}
+	stub.InsertRow("T", newRow("x", "alice", 1))
+	stub.InsertRow("T", newRow("y", "bob", 2))
+
+	// Existing rows are indexed, new ones as they are inserted
+	if err = stub.CreateIndex("T", []string{"Owner"}); err != nil {
+		t.Fatalf("CreateIndex failed: %s", err)
+	}
+	if err = stub.CreateIndex("T", []string{"Owner"}); err == nil {
+		t.Fatalf("Expected an error creating an index twice")
+	}
+	if err = stub.CreateIndex("T", []string{"Missing"}); err == nil {
+		t.Fatalf("Expected an error creating an index on a missing column")
+	}
+	stub.InsertRow("T", newRow("z", "alice", 3))
+	if keys := rowKeys(stub.GetRowsByIndex("T", []string{"Owner"}, owner("alice"))); keys != "x,z" {
+		t.Fatalf("Expected rows x,z for alice, got %v", keys)
+	}
+	if keys := rowKeys(stub.GetRowsByIndex("T", []string{"Owner"}, nil)); keys != "y,x,z" {
+		t.Fatalf("Expected all rows in index order y,x,z, got %v", keys)
+	}
+	if _, err = stub.GetRowsByIndex("T", []string{"V"}, nil); err == nil {
+		t.Fatalf("Expected an error querying a missing index")
+	}
+	if _, err = stub.GetRowsByIndex("T", []string{"Owner"}, []Column{Column{Value: &Column_Int32{Int32: 1}}}); err == nil {
+		t.Fatalf("Expected an error querying an index with a key of the wrong type")
+	}
+
+	// Replacing and deleting rows updates the index and the row count
+	if ok, err := stub.ReplaceRow("T", newRow("y", "alice", 2)); !ok || err != nil {
+		t.Fatalf("ReplaceRow failed: %v, %s", ok, err)
+	}
+	if err = stub.DeleteRow("T", owner("x")); err != nil {
+		t.Fatalf("DeleteRow failed: %s", err)
+	}
+	if keys := rowKeys(stub.GetRowsByIndex("T", []string{"Owner"}, owner("alice"))); keys != "y,z" {
+		t.Fatalf("Expected rows y,z for alice, got %v", keys)
+	}
+	if keys := rowKeys(stub.GetRowsByIndex("T", []string{"Owner"}, owner("bob"))); keys != "" {
+		t.Fatalf("Expected no rows for bob, got %v", keys)
+	}
+	if count, err := stub.GetRowCount("T"); count != 2 || err != nil {
+		t.Fatalf("Expected 2 rows, got %d, %v", count, err)
+	}
+
+	// Rows inserted before a column was added get its default value
+	if err = stub.AddColumn("T", &ColumnDefinition{Name: "K2", Type: ColumnDefinition_STRING, Key: true}); err == nil {
+		t.Fatalf("Expected an error adding a key column")
+	}
+	if err = stub.AddColumn("T", &ColumnDefinition{Name: "V", Type: ColumnDefinition_STRING}); err == nil {
+		t.Fatalf("Expected an error adding a duplicate column")
+	}
+	err = stub.AddColumn("T", &ColumnDefinition{Name: "Note", Type: ColumnDefinition_STRING, Default: &Column{Value: &Column_String_{String_: "none"}}})
+	if err != nil {
+		t.Fatalf("AddColumn failed: %s", err)
+	}
+	if err = stub.AddColumn("T", &ColumnDefinition{Name: "Flag", Type: ColumnDefinition_BOOL}); err != nil {
+		t.Fatalf("AddColumn failed: %s", err)
+	}
+	row, err := stub.GetRow("T", owner("z"))
+	if err != nil || len(row.Columns) != 5 || row.Columns[3].GetString_() != "none" || row.Columns[4].GetBool() {
+		t.Fatalf("Expected the default values in the added columns, got %v, %v", row, err)
+	}
+	if ok, _ := stub.InsertRow("T", newRow("w", "bob", 4)); ok {
+		t.Fatalf("Expected InsertRow to require the added columns")
+	}
+
+	// The iterator over a complete key returns the single row
+	if keys := rowKeys(stub.GetRowsIterator("T", owner("y"))); keys != "y" {
+		t.Fatalf("Expected row y, got %v", keys)
+	}
+	if keys := rowKeys(stub.GetRowsIterator("T", nil)); keys != "y,z" {
+		t.Fatalf("Expected rows y,z, got %v", keys)
+	}
+
+	if err := stub.DeleteTable("T"); err != nil {
+		t.Fatalf("DeleteTable failed: %s", err)
+	}
+	if len(stub.Keys) != 0 {
+		t.Fatalf("Expected empty state after DeleteTable, got %v", stub.Keys)
+	}
+}
+
+func TestMockStubHistory(t *testing.T) {
+	stub := NewMockStub("test", new(mockTestChaincode))
+	stub.MockInvoke("1", "put", []string{"a", "1"})
+	stub.MockInvoke("2", "putfail", []string{"a", "2"})
+	stub.MockInvoke("3", "put", []string{"a", "3"})
+	stub.MockInvoke("4", "del", []string{"a"})
+
+	iter, err := stub.GetHistoryForKey("a")
+	if err != nil {
+		t.Fatalf("GetHistoryForKey failed: %s", err)
+	}
+	defer iter.Close()
+	expected := []struct {
+		txID     string
+		value    string
+		isDelete bool
+	}{{"1", "1", false}, {"3", "3", false}, {"4", "", true}}
+	for i, e := range expected {
+		if !iter.HasNext() {
+			t.Fatalf("Expected %d modifications, got %d", len(expected), i)
+		}
+		modification, err := iter.Next()
+		if err != nil {
+			t.Fatalf("Next failed: %s", err)
+		}
+		if modification.TxID != e.txID || string(modification.Value) != e.value || modification.IsDelete != e.isDelete {
+			t.Fatalf("Unexpected modification %d: %v", i, modification)
+		}
+	}
+	if iter.HasNext() {
+		t.Fatalf("Expected %d modifications only", len(expected))
+	}
+
+	if _, err = stub.MockInvoke("5", "history", []string{"a"}); err == nil {
+		t.Fatalf("Expected GetHistoryForKey to fail in a transaction")
+	}
+}
@@ -17,6 +17,7 @@ limitations under the License.
 package shim
 
 import (
+	"sort"
 	"testing"
 
 	"github.com/op/go-logging"
@@ -117,3 +118,88 @@ func TestChaincodeLogging(t *testing.T) {
 		t.Errorf("'bar' should be enabled for LogCritical")
 	}
 }
+
+func TestCompositeKeys(t *testing.T) {
+	key, err := createCompositeKey("member~project", []string{"émployé 1", "proj/1"})
+	if err != nil {
+		t.Fatalf("createCompositeKey failed: %s", err)
+	}
+	objectType, attributes, err := splitCompositeKey(key)
+	if err != nil {
+		t.Fatalf("splitCompositeKey failed: %s", err)
+	}
+	if objectType != "member~project" || len(attributes) != 2 || attributes[0] != "émployé 1" || attributes[1] != "proj/1" {
+		t.Fatalf("Expected [member~project émployé 1 proj/1] but got [%s %v]", objectType, attributes)
+	}
+
+	// Keys without attributes split into the object type only
+	key, _ = createCompositeKey("member~project", nil)
+	if objectType, attributes, err = splitCompositeKey(key); err != nil || objectType != "member~project" || len(attributes) != 0 {
+		t.Fatalf("Expected [member~project] but got [%s %v] (%v)", objectType, attributes, err)
+	}
+
+	for _, invalid := range [][]string{{"", "a"}, {"type", "a\x00b"}, {"type", "a\U0010FFFF"}, {"type", "\xff"}, {"a\x00", "b"}} {
+		if _, err = createCompositeKey(invalid[0], invalid[1:]); err == nil {
+			t.Fatalf("Expected an error creating a composite key from %q", invalid)
+		}
+	}
+	for _, invalid := range []string{"", "simple", "\x00", "\x00type"} {
+		if _, _, err = splitCompositeKey(invalid); err == nil {
+			t.Fatalf("Expected an error splitting %q", invalid)
+		}
+	}
+}
+
+// reversedRangeStub returns the keys of range queries in reverse order, as a
+// peer may return them in any order.
+type reversedRangeStub struct {
+	*MockStub
+}
+
+func (stub reversedRangeStub) RangeQueryState(startKey, endKey string) (StateRangeQueryIteratorInterface, error) {
+	iter, err := stub.MockStub.RangeQueryState(startKey, endKey)
+	if err != nil {
+		return nil, err
+	}
+	keys := iter.(*MockStateRangeQueryIterator).keys
+	reversed := make([]string, len(keys))
+	for i, key := range keys {
+		reversed[len(keys)-1-i] = key
+	}
+	return &MockStateRangeQueryIterator{stub: stub.MockStub, keys: reversed}, nil
+}
+
+func TestGetStateByPartialCompositeKeyAnyOrder(t *testing.T) {
+	stub := reversedRangeStub{NewMockStub("test", nil)}
+	stub.MockTransactionStart("1")
+	for _, attributes := range [][]string{{"a", "p1"}, {"a", "p2"}, {"a", "p3"}, {"ab", "p1"}, {"b", "p1"}} {
+		key, _ := createCompositeKey("member~project", attributes)
+		stub.PutState(key, []byte{})
+	}
+	stub.MockTransactionEnd("1", nil)
+
+	iter, err := getStateByPartialCompositeKeyInternal(stub, "member~project", []string{"a"})
+	if err != nil {
+		t.Fatalf("getStateByPartialCompositeKeyInternal failed: %s", err)
+	}
+	defer iter.Close()
+	var projects []string
+	for iter.HasNext() {
+		key, _, err := iter.Next()
+		if err != nil {
+			t.Fatalf("Next failed: %s", err)
+		}
+		_, attributes, err := splitCompositeKey(key)
+		if err != nil {
+			t.Fatalf("splitCompositeKey failed: %s", err)
+		}
+		if attributes[0] != "a" {
+			t.Fatalf("Unexpected key of member %s", attributes[0])
+		}
+		projects = append(projects, attributes[1])
+	}
+	sort.Strings(projects)
+	if len(projects) != 3 || projects[0] != "p1" || projects[1] != "p2" || projects[2] != "p3" {
+		t.Fatalf("Expected projects [p1 p2 p3] of member a in any order, got %v", projects)
+	}
+}
@@ -0,0 +1,271 @@
+/*
+Copyright IBM Corp. 2016 All Rights Reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+		 http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package chaincode
+
+import (
+	"bytes"
+	"fmt"
+
+	"github.com/golang/protobuf/proto"
+	"golang.org/x/net/context"
+
+	"github.com/hyperledger/fabric/core/container"
+	"github.com/hyperledger/fabric/core/container/ccintf"
+	"github.com/hyperledger/fabric/core/ledger"
+	pb "github.com/hyperledger/fabric/protos"
+)
+
+// terminatedChaincodesNamespace is the state namespace recording the
+// terminated chaincodes: the key is the chaincode name and the value the UUID
+// of the terminate transaction. No chaincode can own it, as '#' is not valid in
+// the name of a chaincode container.
+const terminatedChaincodesNamespace = "#terminated"
+
+// isTerminated returns true if the chaincode has been terminated, including by
+// a transaction of the current batch.
+func isTerminated(ledger *ledger.Ledger, chaincode string) (bool, error) {
+	txUUID, err := ledger.GetState(terminatedChaincodesNamespace, chaincode, false)
+	if err != nil {
+		return false, err
+	}
+	return txUUID != nil, nil
+}
+
+// checkNotTerminated returns an error if the chaincode has been terminated on
+// the chain
+func (chaincodeSupport *ChaincodeSupport) checkNotTerminated(chainID string, chaincode string) error {
+	ledger, err := chaincodeSupport.getLedger(chainID)
+	if err != nil {
+		return fmt.Errorf("Failed to get handle to ledger (%s)", err)
+	}
+	terminated, err := isTerminated(ledger, chaincode)
+	if err != nil {
+		return fmt.Errorf("Failed to check whether chaincode %s is terminated (%s)", chaincode, err)
+	}
+	if terminated {
+		return fmt.Errorf("chaincode %s has been terminated", chaincode)
+	}
+	return nil
+}
+
+// checkDeployerOrAdmin returns an error unless the transaction was signed by the
+// deployer of the chaincode, with the certificate of its deploy transaction, or
+// by an administrator, with its enrollment certificate. The signature is
+// verified again as the transaction may have been submitted to another peer,
+// but not the revocation of the certificate: it depends on the CRLs each peer
+// last fetched, and the outcome must be the same on every validator. Revoked
+// certificates are rejected when the transaction is submitted. Without
+// security there are no identities to check.
+func (chaincodeSupport *ChaincodeSupport) checkDeployerOrAdmin(ledger *ledger.Ledger, chaincode string, t *pb.Transaction) error {
+	secHelper := chaincodeSupport.secHelper
+	if secHelper == nil {
+		return nil
+	}
+	if err := secHelper.VerifyTransactionSignature(t); err != nil {
+		return fmt.Errorf("invalid signature of transaction %s (%s)", t.Uuid, err)
+	}
+	//the deploy transaction is named after the chaincode
+	depTx, err := ledger.GetTransactionByUUID(chaincode)
+	if err != nil {
+		return fmt.Errorf("Could not get deployment transaction for %s - %s", chaincode, err)
+	}
+	if depTx != nil && len(depTx.Cert) > 0 && bytes.Equal(depTx.Cert, t.Cert) {
+		return nil
+	}
+	if secHelper.IsAdmin(t.Cert) {
+		chaincodeLogger.Debugf("transaction %s submitted by an administrator", t.Uuid)
+		return nil
+	}
+	return fmt.Errorf("transaction %s was submitted neither by the deployer of chaincode %s nor by an administrator", t.Uuid, chaincode)
+}
+
+// getDeploymentSpec returns the deployment spec carried by a deploy or upgrade
+// transaction
+func (chaincodeSupport *ChaincodeSupport) getDeploymentSpec(ledger *ledger.Ledger, txUUID string) (*pb.ChaincodeDeploymentSpec, error) {
+	depTx, err := ledger.GetTransactionByUUID(txUUID)
+	if err != nil {
+		return nil, fmt.Errorf("Could not get deployment transaction for %s - %s", txUUID, err)
+	}
+	if depTx == nil {
+		return nil, fmt.Errorf("deployment transaction does not exist for %s", txUUID)
+	}
+	if nil != chaincodeSupport.secHelper {
+		depTx, err = chaincodeSupport.secHelper.TransactionPreExecution(depTx)
+		if nil != err {
+			return nil, fmt.Errorf("failed tx preexecution%s - %s", txUUID, err)
+		}
+	}
+	cds := &pb.ChaincodeDeploymentSpec{}
+	if err = proto.Unmarshal(depTx.Payload, cds); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal deployment transactions for %s - %s", txUUID, err)
+	}
+	return cds, nil
+}
+
+// executeTerminate records the effects of a terminate transaction in the
+// state: the chaincode is marked as terminated and, if requested, the keys of
+// all its versions are deleted. Only the deployer of the chaincode or an
+// administrator may terminate it. The chaincode itself is stopped only once the
+// transaction is committed, see StopRetiredChaincodes.
+func (chaincodeSupport *ChaincodeSupport) executeTerminate(ledger *ledger.Ledger, t *pb.Transaction) error {
+	cts := &pb.ChaincodeTerminationSpec{}
+	if err := proto.Unmarshal(t.Payload, cts); err != nil {
+		return err
+	}
+	cID := cts.ChaincodeSpec.GetChaincodeID()
+	if cID == nil || cID.Name == "" {
+		return fmt.Errorf("chaincode name not set")
+	}
+	chaincode := cID.Name
+
+	terminated, err := isTerminated(ledger, chaincode)
+	if err != nil {
+		return err
+	}
+	if terminated {
+		return fmt.Errorf("chaincode %s has already been terminated", chaincode)
+	}
+	version, err := getChaincodeVersion(ledger, chaincode, false)
+	if err != nil {
+		return err
+	}
+	cds, err := chaincodeSupport.getVersionDeploymentSpec(ledger, chaincode, version)
+	if err != nil {
+		return err
+	}
+	if cds.ExecEnv == pb.ChaincodeDeploymentSpec_SYSTEM {
+		return fmt.Errorf("system chaincode %s cannot be terminated", chaincode)
+	}
+	if err = chaincodeSupport.checkDeployerOrAdmin(ledger, chaincode, t); err != nil {
+		return err
+	}
+
+	if cts.PurgeState {
+		for v := uint64(0); v <= version; v++ {
+			if err = purgeState(ledger, getStateNamespace(chaincode, v)); err != nil {
+				return fmt.Errorf("Failed to purge the state of chaincode %s (%s)", chaincode, err)
+			}
+		}
+	}
+
+	chaincodeLogger.Debugf("marking chaincode %s as terminated by %s", chaincode, t.Uuid)
+	return ledger.SetState(terminatedChaincodesNamespace, chaincode, []byte(t.Uuid))
+}
+
+// purgeState deletes all the keys of a state namespace
+func purgeState(ledger *ledger.Ledger, namespace string) error {
+	itr, err := ledger.GetStateRangeScanIterator(namespace, "", "", false)
+	if err != nil {
+		return err
+	}
+	var keys []string
+	for itr.Next() {
+		key, _ := itr.GetKeyValue()
+		keys = append(keys, key)
+	}
+	itr.Close()
+
+	chaincodeLogger.Debugf("purging %d keys of namespace %s", len(keys), namespace)
+	for _, key := range keys {
+		if err = ledger.DeleteState(namespace, key); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Terminate stops the chaincode of the chain, if running, and destroys its image
+func (chaincodeSupport *ChaincodeSupport) Terminate(context context.Context, chainID string, cds *pb.ChaincodeDeploymentSpec) error {
+	if err := chaincodeSupport.Stop(context, chainID, cds); err != nil {
+		//the container may not be running on this peer, proceed to destroy the image
+		chaincodeLogger.Debugf("stop failed %s", err)
+	}
+	return chaincodeSupport.destroyImage(context, chainID, cds)
+}
+
+// destroyImage destroys the image of the chaincode version of the chain
+func (chaincodeSupport *ChaincodeSupport) destroyImage(context context.Context, chainID string, cds *pb.ChaincodeDeploymentSpec) error {
+	if chaincodeSupport.userRunsCC {
+		chaincodeLogger.Debug("user runs chaincode, no image to destroy")
+		return nil
+	}
+
+	dir := container.DestroyImageReq{CCID: ccintf.CCID{ChaincodeSpec: cds.ChaincodeSpec, NetworkID: chaincodeSupport.peerNetworkID, PeerID: chaincodeSupport.peerID, ChainID: chainID}, Force: true}
+
+	vmtype, _ := chaincodeSupport.getVMType(cds)
+
+	_, err := container.VMCProcess(context, vmtype, dir)
+	if err != nil {
+		err = fmt.Errorf("Error destroying image: %s", err)
+	}
+	return err
+}
+
+// StopRetiredChaincodes stops and destroys the chaincodes terminated by the
+// successful transactions of a committed batch, and destroys the images of
+// the versions they upgraded. Failures are logged: the ledger records the
+// terminations and upgrades regardless, so those versions will never be
+// launched again.
+func StopRetiredChaincodes(ctxt context.Context, cname ChainName, txs []*pb.Transaction, results []*pb.TransactionResult) {
+	var chain = GetChain(cname)
+	if chain == nil {
+		chaincodeLogger.Errorf("Chain %s not found", cname)
+		return
+	}
+	chain.StopRetiredChaincodes(ctxt, txs, results)
+}
+
+// StopRetiredChaincodes stops the chaincodes retired by a committed batch,
+// see the function StopRetiredChaincodes
+func (chaincodeSupport *ChaincodeSupport) StopRetiredChaincodes(ctxt context.Context, txs []*pb.Transaction, results []*pb.TransactionResult) {
+	for i, t := range txs {
+		if i < len(results) && results[i].ErrorCode != 0 {
+			continue
+		}
+		ledger, err := chaincodeSupport.getLedger(t.ChainID)
+		if err != nil {
+			chaincodeLogger.Errorf("Failed to get handle to ledger (%s)", err)
+			return
+		}
+		if t.Type == pb.Transaction_CHAINCODE_UPGRADE {
+			chaincodeSupport.destroyUpgradedVersion(ctxt, ledger, t)
+			continue
+		}
+		if t.Type != pb.Transaction_CHAINCODE_TERMINATE {
+			continue
+		}
+		cts := &pb.ChaincodeTerminationSpec{}
+		if err = proto.Unmarshal(t.Payload, cts); err != nil {
+			chaincodeLogger.Errorf("Failed to unmarshal terminate transaction %s (%s)", t.Uuid, err)
+			continue
+		}
+		cID := cts.ChaincodeSpec.GetChaincodeID()
+		if cID == nil {
+			continue
+		}
+		chaincode := cID.Name
+		cds, err := chaincodeSupport.getCurrentDeploymentSpec(ledger, chaincode)
+		if err != nil {
+			chaincodeLogger.Errorf("Failed to stop terminated chaincode %s (%s)", chaincode, err)
+			continue
+		}
+		chaincodeLogger.Infof("Stopping terminated chaincode %s", chaincode)
+		if err = chaincodeSupport.Terminate(ctxt, t.ChainID, cds); err != nil {
+			chaincodeLogger.Errorf("Failed to stop terminated chaincode %s (%s)", chaincode, err)
+		}
+	}
+}
@@ -0,0 +1,190 @@
+/*
+Copyright IBM Corp. 2016 All Rights Reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+		 http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package chaincode
+
+import (
+	"bytes"
+	"fmt"
+	"testing"
+
+	"github.com/hyperledger/fabric/core/crypto"
+	"github.com/hyperledger/fabric/core/ledger"
+	pb "github.com/hyperledger/fabric/protos"
+)
+
+func TestExecuteTerminate(t *testing.T) {
+	lgr := ledger.InitTestLedger(t)
+	chaincodeSupport := &ChaincodeSupport{}
+
+	// Commit the deploy transaction and some state of the chaincode
+	cds := &pb.ChaincodeDeploymentSpec{ChaincodeSpec: &pb.ChaincodeSpec{Type: pb.ChaincodeSpec_GOLANG, ChaincodeID: &pb.ChaincodeID{Name: "mycc"}}}
+	depTx, err := pb.NewChaincodeDeployTransaction(cds, "mycc")
+	if err != nil {
+		t.Fatalf("Error creating deploy transaction: %s", err)
+	}
+	lgr.BeginTxBatch(1)
+	lgr.TxBegin("mycc")
+	lgr.SetState("mycc", "a", []byte("100"))
+	lgr.SetState("mycc", "b", []byte("200"))
+	lgr.SetState("othercc", "a", []byte("300"))
+	lgr.TxFinished("mycc", true)
+	if err = lgr.CommitTxBatch(1, []*pb.Transaction{depTx}, nil, nil); err != nil {
+		t.Fatalf("Error committing deploy transaction: %s", err)
+	}
+
+	if err = chaincodeSupport.checkNotTerminated("", "mycc"); err != nil {
+		t.Fatalf("Expected chaincode not to be terminated but got: %s", err)
+	}
+
+	// Terminating an unknown chaincode must fail
+	unknownTx, _ := pb.NewChaincodeTerminateTransaction(&pb.ChaincodeTerminationSpec{ChaincodeSpec: &pb.ChaincodeSpec{ChaincodeID: &pb.ChaincodeID{Name: "unknown"}}}, "terminate0")
+	lgr.BeginTxBatch(2)
+	lgr.TxBegin("terminate0")
+	if err = chaincodeSupport.executeTerminate(lgr, unknownTx); err == nil {
+		t.Fatalf("Expected an error terminating an undeployed chaincode")
+	}
+	lgr.TxFinished("terminate0", false)
+
+	// Terminate the chaincode, purging its state
+	termTx, _ := pb.NewChaincodeTerminateTransaction(&pb.ChaincodeTerminationSpec{ChaincodeSpec: &pb.ChaincodeSpec{ChaincodeID: &pb.ChaincodeID{Name: "mycc"}}, PurgeState: true}, "terminate1")
+	lgr.TxBegin("terminate1")
+	if err = chaincodeSupport.executeTerminate(lgr, termTx); err != nil {
+		t.Fatalf("Error terminating chaincode: %s", err)
+	}
+	lgr.TxFinished("terminate1", true)
+	if err = lgr.CommitTxBatch(2, []*pb.Transaction{unknownTx, termTx}, nil, nil); err != nil {
+		t.Fatalf("Error committing terminate transaction: %s", err)
+	}
+
+	if err = chaincodeSupport.checkNotTerminated("", "mycc"); err == nil {
+		t.Fatalf("Expected chaincode to be terminated")
+	}
+	for _, key := range []string{"a", "b"} {
+		if value, _ := lgr.GetState("mycc", key, true); value != nil {
+			t.Fatalf("Expected key %s to be purged but got %s", key, value)
+		}
+	}
+	if value, _ := lgr.GetState("othercc", "a", true); string(value) != "300" {
+		t.Fatalf("Expected the state of other chaincodes to be kept but got %s", value)
+	}
+
+	// Terminating the chaincode again must fail
+	againTx, _ := pb.NewChaincodeTerminateTransaction(&pb.ChaincodeTerminationSpec{ChaincodeSpec: &pb.ChaincodeSpec{ChaincodeID: &pb.ChaincodeID{Name: "mycc"}}}, "terminate2")
+	lgr.BeginTxBatch(3)
+	lgr.TxBegin("terminate2")
+	if err = chaincodeSupport.executeTerminate(lgr, againTx); err == nil {
+		t.Fatalf("Expected an error terminating a terminated chaincode")
+	}
+	lgr.TxFinished("terminate2", false)
+	lgr.RollbackTxBatch(3)
+}
+
+// mockSecHelper accepts the signature of every transaction and recognizes an
+// administrator by its certificate. The certificates in its CRL cache are
+// rejected by TransactionPreValidation only.
+type mockSecHelper struct {
+	crypto.Peer
+	admin   []byte
+	revoked [][]byte
+}
+
+func (m *mockSecHelper) TransactionPreValidation(tx *pb.Transaction) (*pb.Transaction, error) {
+	for _, cert := range m.revoked {
+		if bytes.Equal(cert, tx.Cert) {
+			return tx, fmt.Errorf("certificate %s has been revoked", cert)
+		}
+	}
+	return tx, nil
+}
+
+func (m *mockSecHelper) VerifyTransactionSignature(tx *pb.Transaction) error {
+	return nil
+}
+
+func (m *mockSecHelper) TransactionPreExecution(tx *pb.Transaction) (*pb.Transaction, error) {
+	return tx, nil
+}
+
+func (m *mockSecHelper) IsAdmin(cert []byte) bool {
+	return bytes.Equal(cert, m.admin)
+}
+
+func TestExecuteTerminateAuthorization(t *testing.T) {
+	lgr := ledger.InitTestLedger(t)
+	chaincodeSupport := &ChaincodeSupport{secHelper: &mockSecHelper{admin: []byte("admin")}}
+
+	cds := &pb.ChaincodeDeploymentSpec{ChaincodeSpec: &pb.ChaincodeSpec{Type: pb.ChaincodeSpec_GOLANG, ChaincodeID: &pb.ChaincodeID{Name: "mycc"}}}
+	depTx, err := pb.NewChaincodeDeployTransaction(cds, "mycc")
+	if err != nil {
+		t.Fatalf("Error creating deploy transaction: %s", err)
+	}
+	depTx.Cert = []byte("deployer")
+	lgr.BeginTxBatch(1)
+	if err = lgr.CommitTxBatch(1, []*pb.Transaction{depTx}, nil, nil); err != nil {
+		t.Fatalf("Error committing deploy transaction: %s", err)
+	}
+
+	for _, test := range []struct {
+		cert       string
+		authorized bool
+	}{{"other", false}, {"", false}, {"admin", true}, {"deployer", true}} {
+		termTx, _ := pb.NewChaincodeTerminateTransaction(&pb.ChaincodeTerminationSpec{ChaincodeSpec: &pb.ChaincodeSpec{ChaincodeID: &pb.ChaincodeID{Name: "mycc"}}}, "terminate")
+		termTx.Cert = []byte(test.cert)
+		lgr.BeginTxBatch(2)
+		lgr.TxBegin("terminate")
+		err = chaincodeSupport.executeTerminate(lgr, termTx)
+		lgr.TxFinished("terminate", false)
+		lgr.RollbackTxBatch(2)
+		if test.authorized && err != nil {
+			t.Fatalf("Expected the certificate %s to be allowed to terminate but got: %s", test.cert, err)
+		}
+		if !test.authorized && err == nil {
+			t.Fatalf("Expected the certificate %s not to be allowed to terminate", test.cert)
+		}
+	}
+}
+
+func TestExecuteTerminateIgnoresCRLs(t *testing.T) {
+	lgr := ledger.InitTestLedger(t)
+
+	cds := &pb.ChaincodeDeploymentSpec{ChaincodeSpec: &pb.ChaincodeSpec{Type: pb.ChaincodeSpec_GOLANG, ChaincodeID: &pb.ChaincodeID{Name: "mycc"}}}
+	depTx, err := pb.NewChaincodeDeployTransaction(cds, "mycc")
+	if err != nil {
+		t.Fatalf("Error creating deploy transaction: %s", err)
+	}
+	depTx.Cert = []byte("deployer")
+	lgr.BeginTxBatch(1)
+	if err = lgr.CommitTxBatch(1, []*pb.Transaction{depTx}, nil, nil); err != nil {
+		t.Fatalf("Error committing deploy transaction: %s", err)
+	}
+
+	// One validator fetched the CRL revoking the deployer, the other did not
+	// yet: both must execute the terminate transaction the same way
+	termTx, _ := pb.NewChaincodeTerminateTransaction(&pb.ChaincodeTerminationSpec{ChaincodeSpec: &pb.ChaincodeSpec{ChaincodeID: &pb.ChaincodeID{Name: "mycc"}}}, "terminate")
+	termTx.Cert = []byte("deployer")
+	for _, secHelper := range []*mockSecHelper{{revoked: [][]byte{[]byte("deployer")}}, {}} {
+		chaincodeSupport := &ChaincodeSupport{secHelper: secHelper}
+		lgr.BeginTxBatch(2)
+		lgr.TxBegin("terminate")
+		err = chaincodeSupport.executeTerminate(lgr, termTx)
+		lgr.TxFinished("terminate", false)
+		lgr.RollbackTxBatch(2)
+		if err != nil {
+			t.Fatalf("Expected the terminate transaction to execute whatever the CRLs of the validator but got: %s", err)
+		}
+	}
+}
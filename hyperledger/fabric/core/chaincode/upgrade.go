@@ -0,0 +1,236 @@
+/*
+Copyright IBM Corp. 2016 All Rights Reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+		 http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package chaincode
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/golang/protobuf/proto"
+	"golang.org/x/net/context"
+
+	"github.com/hyperledger/fabric/core/ledger"
+	pb "github.com/hyperledger/fabric/protos"
+)
+
+// chaincodeVersionsNamespace is the state namespace recording the versions of
+// the upgraded chaincodes: the key of a chaincode name holds its current
+// version, and the key of the state namespace of each version holds the UUID
+// of the upgrade transaction that deployed it.
+const chaincodeVersionsNamespace = "#versions"
+
+// getStateNamespace returns the state namespace of a version of the chaincode.
+// The deployed version keeps the chaincode name, so that the state of the
+// chaincodes that were never upgraded does not move.
+func getStateNamespace(chaincode string, version uint64) string {
+	if version == 0 {
+		return chaincode
+	}
+	return fmt.Sprintf("%s:v%d", chaincode, version)
+}
+
+// getExecutable returns the name of the chaincode executable in the container
+// of the version deployed by the transaction. The platforms name it after the
+// hash of the code package, which is the chaincode name for the deployed
+// version and the upgrade transaction UUID for the upgraded ones.
+func getExecutable(cds *pb.ChaincodeDeploymentSpec, txUUID string) string {
+	cID := cds.ChaincodeSpec.ChaincodeID
+	if cID.Version == 0 {
+		return cID.Name
+	}
+	return txUUID
+}
+
+// getChaincodeVersion returns the current version of the chaincode
+func getChaincodeVersion(ledger *ledger.Ledger, chaincode string, committed bool) (uint64, error) {
+	rawVersion, err := ledger.GetState(chaincodeVersionsNamespace, chaincode, committed)
+	if err != nil || rawVersion == nil {
+		return 0, err
+	}
+	version, err := strconv.ParseUint(string(rawVersion), 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid version of chaincode %s (%s)", chaincode, err)
+	}
+	return version, nil
+}
+
+// getVersionTxUUID returns the UUID of the transaction that deployed the
+// version of the chaincode
+func getVersionTxUUID(ledger *ledger.Ledger, chaincode string, version uint64, committed bool) (string, error) {
+	if version == 0 {
+		return chaincode, nil
+	}
+	txUUID, err := ledger.GetState(chaincodeVersionsNamespace, getStateNamespace(chaincode, version), committed)
+	if err != nil {
+		return "", err
+	}
+	if txUUID == nil {
+		return "", fmt.Errorf("version %d of chaincode %s does not exist", version, chaincode)
+	}
+	return string(txUUID), nil
+}
+
+// GetChaincodeVersion returns the committed version of the chaincode
+func GetChaincodeVersion(ledger *ledger.Ledger, chaincode string) (uint64, error) {
+	return getChaincodeVersion(ledger, chaincode, true)
+}
+
+// GetStateNamespace returns the state namespace of the committed version of
+// the chaincode
+func GetStateNamespace(ledger *ledger.Ledger, chaincode string) (string, error) {
+	version, err := getChaincodeVersion(ledger, chaincode, true)
+	if err != nil {
+		return "", err
+	}
+	return getStateNamespace(chaincode, version), nil
+}
+
+// getVersionDeploymentSpec returns the deployment spec of a version of the
+// chaincode
+func (chaincodeSupport *ChaincodeSupport) getVersionDeploymentSpec(ledger *ledger.Ledger, chaincode string, version uint64) (*pb.ChaincodeDeploymentSpec, error) {
+	txUUID, err := getVersionTxUUID(ledger, chaincode, version, false)
+	if err != nil {
+		return nil, err
+	}
+	return chaincodeSupport.getDeploymentSpec(ledger, txUUID)
+}
+
+// getCurrentDeploymentSpec returns the deployment spec of the current version
+// of the chaincode
+func (chaincodeSupport *ChaincodeSupport) getCurrentDeploymentSpec(ledger *ledger.Ledger, chaincode string) (*pb.ChaincodeDeploymentSpec, error) {
+	version, err := getChaincodeVersion(ledger, chaincode, false)
+	if err != nil {
+		return nil, err
+	}
+	return chaincodeSupport.getVersionDeploymentSpec(ledger, chaincode, version)
+}
+
+// moveState moves the state of a version of the chaincode to another version
+func moveState(ledger *ledger.Ledger, chaincode string, from uint64, to uint64) error {
+	if err := ledger.CopyState(getStateNamespace(chaincode, from), getStateNamespace(chaincode, to)); err != nil {
+		return err
+	}
+	return purgeState(ledger, getStateNamespace(chaincode, from))
+}
+
+// executeUpgrade deploys the new version of the chaincode carried by an upgrade
+// transaction. Only the deployer of the chaincode or an administrator may
+// upgrade it. The state of the current version is moved to the new version
+// before its Upgrade function is called, all within the transaction, so that a
+// failed upgrade leaves the current version untouched. The image of the
+// previous version is destroyed only once the transaction is committed, see
+// StopRetiredChaincodes.
+func (chaincodeSupport *ChaincodeSupport) executeUpgrade(ctxt context.Context, ledger *ledger.Ledger, t *pb.Transaction) error {
+	cds := &pb.ChaincodeDeploymentSpec{}
+	if err := proto.Unmarshal(t.Payload, cds); err != nil {
+		return err
+	}
+	cID := cds.ChaincodeSpec.GetChaincodeID()
+	if cID == nil || cID.Name == "" {
+		return fmt.Errorf("chaincode name not set")
+	}
+	chaincode := cID.Name
+
+	if err := chaincodeSupport.checkNotTerminated(t.ChainID, chaincode); err != nil {
+		return err
+	}
+	version, err := getChaincodeVersion(ledger, chaincode, false)
+	if err != nil {
+		return err
+	}
+	if cID.Version != version+1 {
+		return fmt.Errorf("chaincode %s is at version %d and cannot be upgraded to version %d", chaincode, version, cID.Version)
+	}
+	current, err := chaincodeSupport.getVersionDeploymentSpec(ledger, chaincode, version)
+	if err != nil {
+		return err
+	}
+	if current.ExecEnv == pb.ChaincodeDeploymentSpec_SYSTEM || cds.ExecEnv == pb.ChaincodeDeploymentSpec_SYSTEM {
+		return fmt.Errorf("system chaincode %s cannot be upgraded", chaincode)
+	}
+	if current.ChaincodeSpec.ConfidentialityLevel == pb.ConfidentialityLevel_CONFIDENTIAL {
+		return fmt.Errorf("confidential chaincode %s cannot be upgraded", chaincode)
+	}
+	if err = chaincodeSupport.checkDeployerOrAdmin(ledger, chaincode, t); err != nil {
+		return err
+	}
+	//the executable of the new version is named after the transaction
+	if tx, _ := ledger.GetTransactionByUUID(t.Uuid); tx != nil {
+		return fmt.Errorf("transaction %s already exists", t.Uuid)
+	}
+
+	//record the new version and move the state of the current version into it
+	namespace := getStateNamespace(chaincode, cID.Version)
+	if err = ledger.SetState(chaincodeVersionsNamespace, chaincode, []byte(strconv.FormatUint(cID.Version, 10))); err != nil {
+		return err
+	}
+	if err = ledger.SetState(chaincodeVersionsNamespace, namespace, []byte(t.Uuid)); err != nil {
+		return err
+	}
+	if err = setEffectiveDate(ledger, chaincode, cds.EffectiveDate); err != nil {
+		return err
+	}
+	chaincodeLogger.Debugf("moving the state of chaincode %s from version %d to version %d", chaincode, version, cID.Version)
+	if err = moveState(ledger, chaincode, version, cID.Version); err != nil {
+		return fmt.Errorf("Failed to move the state of chaincode %s (%s)", chaincode, err)
+	}
+
+	//the user runs the new version in development mode
+	if !chaincodeSupport.userRunsCC {
+		if err = chaincodeSupport.Stop(ctxt, t.ChainID, current); err != nil {
+			chaincodeLogger.Debugf("stop failed %s", err)
+		}
+	}
+
+	if _, err = chaincodeSupport.Deploy(ctxt, t); err != nil {
+		return fmt.Errorf("Failed to deploy chaincode spec(%s)", err)
+	}
+
+	//launch the new version and call its Upgrade function
+	if _, _, err = chaincodeSupport.Launch(ctxt, t); err != nil {
+		//the current version is launched again on its next invocation
+		if errIgnore := chaincodeSupport.destroyImage(ctxt, t.ChainID, cds); errIgnore != nil {
+			chaincodeLogger.Debugf("destroy failed %s(%s)", errIgnore, err)
+		}
+		return err
+	}
+	return nil
+}
+
+// destroyUpgradedVersion destroys the image of the version of the chaincode
+// upgraded by a committed transaction. Its container was already stopped when
+// the transaction was executed.
+func (chaincodeSupport *ChaincodeSupport) destroyUpgradedVersion(ctxt context.Context, ledger *ledger.Ledger, t *pb.Transaction) {
+	cds := &pb.ChaincodeDeploymentSpec{}
+	if err := proto.Unmarshal(t.Payload, cds); err != nil {
+		chaincodeLogger.Errorf("Failed to unmarshal upgrade transaction %s (%s)", t.Uuid, err)
+		return
+	}
+	cID := cds.ChaincodeSpec.GetChaincodeID()
+	if cID == nil || cID.Version == 0 {
+		return
+	}
+	previous, err := chaincodeSupport.getVersionDeploymentSpec(ledger, cID.Name, cID.Version-1)
+	if err != nil {
+		chaincodeLogger.Errorf("Failed to destroy upgraded chaincode %s (%s)", cID.Name, err)
+		return
+	}
+	chaincodeLogger.Infof("Destroying version %d of upgraded chaincode %s", cID.Version-1, cID.Name)
+	if err = chaincodeSupport.destroyImage(ctxt, t.ChainID, previous); err != nil {
+		chaincodeLogger.Errorf("Failed to destroy upgraded chaincode %s (%s)", cID.Name, err)
+	}
+}
@@ -0,0 +1,199 @@
+/*
+Copyright IBM Corp. 2016 All Rights Reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+		 http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package chaincode
+
+import (
+	"testing"
+
+	"golang.org/x/net/context"
+
+	"github.com/hyperledger/fabric/core/ledger"
+	pb "github.com/hyperledger/fabric/protos"
+)
+
+func TestChaincodeVersions(t *testing.T) {
+	lgr := ledger.InitTestLedger(t)
+	chaincodeSupport := &ChaincodeSupport{}
+
+	// Commit the deploy transaction and some state of the chaincode
+	cds := &pb.ChaincodeDeploymentSpec{ChaincodeSpec: &pb.ChaincodeSpec{Type: pb.ChaincodeSpec_GOLANG, ChaincodeID: &pb.ChaincodeID{Name: "mycc"}}}
+	depTx, err := pb.NewChaincodeDeployTransaction(cds, "mycc")
+	if err != nil {
+		t.Fatalf("Error creating deploy transaction: %s", err)
+	}
+	lgr.BeginTxBatch(1)
+	lgr.TxBegin("mycc")
+	lgr.SetState("mycc", "a", []byte("100"))
+	lgr.TxFinished("mycc", true)
+	if err = lgr.CommitTxBatch(1, []*pb.Transaction{depTx}, nil, nil); err != nil {
+		t.Fatalf("Error committing deploy transaction: %s", err)
+	}
+
+	if version, _ := GetChaincodeVersion(lgr, "mycc"); version != 0 {
+		t.Fatalf("Expected version 0 but got %d", version)
+	}
+	if namespace, _ := GetStateNamespace(lgr, "mycc"); namespace != "mycc" {
+		t.Fatalf("Expected namespace mycc but got %s", namespace)
+	}
+	if executable := getExecutable(cds, depTx.Uuid); executable != "mycc" {
+		t.Fatalf("Expected executable mycc but got %s", executable)
+	}
+
+	// Upgrading to any version but the next one must fail, leaving the state untouched
+	v2 := &pb.ChaincodeDeploymentSpec{ChaincodeSpec: &pb.ChaincodeSpec{Type: pb.ChaincodeSpec_GOLANG, ChaincodeID: &pb.ChaincodeID{Name: "mycc", Version: 2}}}
+	skipTx, _ := pb.NewChaincodeUpgradeTransaction(v2, "hash2")
+	lgr.BeginTxBatch(2)
+	lgr.TxBegin("hash2")
+	if err = chaincodeSupport.executeUpgrade(context.Background(), lgr, skipTx); err == nil {
+		t.Fatalf("Expected an error upgrading to version 2")
+	}
+	lgr.TxFinished("hash2", false)
+	lgr.RollbackTxBatch(2)
+
+	// Upgrading an unknown chaincode must fail
+	unknown := &pb.ChaincodeDeploymentSpec{ChaincodeSpec: &pb.ChaincodeSpec{Type: pb.ChaincodeSpec_GOLANG, ChaincodeID: &pb.ChaincodeID{Name: "unknown", Version: 1}}}
+	unknownTx, _ := pb.NewChaincodeUpgradeTransaction(unknown, "hash0")
+	lgr.BeginTxBatch(2)
+	lgr.TxBegin("hash0")
+	if err = chaincodeSupport.executeUpgrade(context.Background(), lgr, unknownTx); err == nil {
+		t.Fatalf("Expected an error upgrading an undeployed chaincode")
+	}
+	lgr.TxFinished("hash0", false)
+	lgr.RollbackTxBatch(2)
+
+	// Commit the bookkeeping and state move of an upgrade to version 1
+	v1 := &pb.ChaincodeDeploymentSpec{ChaincodeSpec: &pb.ChaincodeSpec{Type: pb.ChaincodeSpec_GOLANG, ChaincodeID: &pb.ChaincodeID{Name: "mycc", Version: 1}}}
+	upgradeTx, _ := pb.NewChaincodeUpgradeTransaction(v1, "hash1")
+	lgr.BeginTxBatch(2)
+	lgr.TxBegin("hash1")
+	lgr.SetState(chaincodeVersionsNamespace, "mycc", []byte("1"))
+	lgr.SetState(chaincodeVersionsNamespace, "mycc:v1", []byte("hash1"))
+	if err = moveState(lgr, "mycc", 0, 1); err != nil {
+		t.Fatalf("Error moving state: %s", err)
+	}
+	lgr.TxFinished("hash1", true)
+	if err = lgr.CommitTxBatch(2, []*pb.Transaction{upgradeTx}, nil, nil); err != nil {
+		t.Fatalf("Error committing upgrade transaction: %s", err)
+	}
+
+	if version, _ := GetChaincodeVersion(lgr, "mycc"); version != 1 {
+		t.Fatalf("Expected version 1 but got %d", version)
+	}
+	namespace, _ := GetStateNamespace(lgr, "mycc")
+	if namespace != "mycc:v1" {
+		t.Fatalf("Expected namespace mycc:v1 but got %s", namespace)
+	}
+	if value, _ := lgr.GetState(namespace, "a", true); string(value) != "100" {
+		t.Fatalf("Expected the state to be moved to version 1 but got %s", value)
+	}
+	if value, _ := lgr.GetState("mycc", "a", true); value != nil {
+		t.Fatalf("Expected the state of version 0 to be removed but got %s", value)
+	}
+	current, err := chaincodeSupport.getCurrentDeploymentSpec(lgr, "mycc")
+	if err != nil {
+		t.Fatalf("Error getting current deployment spec: %s", err)
+	}
+	if current.ChaincodeSpec.ChaincodeID.Version != 1 {
+		t.Fatalf("Expected the deployment spec of version 1 but got version %d", current.ChaincodeSpec.ChaincodeID.Version)
+	}
+	if executable := getExecutable(current, "hash1"); executable != "hash1" {
+		t.Fatalf("Expected executable hash1 but got %s", executable)
+	}
+
+	// Terminating the chaincode purges the state of all its versions
+	termTx, _ := pb.NewChaincodeTerminateTransaction(&pb.ChaincodeTerminationSpec{ChaincodeSpec: &pb.ChaincodeSpec{ChaincodeID: &pb.ChaincodeID{Name: "mycc"}}, PurgeState: true}, "terminate1")
+	lgr.BeginTxBatch(3)
+	lgr.TxBegin("terminate1")
+	if err = chaincodeSupport.executeTerminate(lgr, termTx); err != nil {
+		t.Fatalf("Error terminating chaincode: %s", err)
+	}
+	lgr.TxFinished("terminate1", true)
+	if err = lgr.CommitTxBatch(3, []*pb.Transaction{termTx}, nil, nil); err != nil {
+		t.Fatalf("Error committing terminate transaction: %s", err)
+	}
+	for _, namespace := range []string{"mycc", "mycc:v1"} {
+		if value, _ := lgr.GetState(namespace, "a", true); value != nil {
+			t.Fatalf("Expected key a of %s to be purged but got %s", namespace, value)
+		}
+	}
+}
+
+func TestExecuteUpgradeAuthorization(t *testing.T) {
+	lgr := ledger.InitTestLedger(t)
+	chaincodeSupport := &ChaincodeSupport{secHelper: &mockSecHelper{admin: []byte("admin")}}
+
+	cds := &pb.ChaincodeDeploymentSpec{ChaincodeSpec: &pb.ChaincodeSpec{Type: pb.ChaincodeSpec_GOLANG, ChaincodeID: &pb.ChaincodeID{Name: "mycc"}}}
+	depTx, err := pb.NewChaincodeDeployTransaction(cds, "mycc")
+	if err != nil {
+		t.Fatalf("Error creating deploy transaction: %s", err)
+	}
+	depTx.Cert = []byte("deployer")
+	lgr.BeginTxBatch(1)
+	if err = lgr.CommitTxBatch(1, []*pb.Transaction{depTx}, nil, nil); err != nil {
+		t.Fatalf("Error committing deploy transaction: %s", err)
+	}
+
+	v1 := &pb.ChaincodeDeploymentSpec{ChaincodeSpec: &pb.ChaincodeSpec{Type: pb.ChaincodeSpec_GOLANG, ChaincodeID: &pb.ChaincodeID{Name: "mycc", Version: 1}}}
+	upgradeTx, _ := pb.NewChaincodeUpgradeTransaction(v1, "hash1")
+	upgradeTx.Cert = []byte("other")
+	lgr.BeginTxBatch(2)
+	lgr.TxBegin("hash1")
+	err = chaincodeSupport.executeUpgrade(context.Background(), lgr, upgradeTx)
+	lgr.TxFinished("hash1", false)
+	lgr.RollbackTxBatch(2)
+	if err == nil {
+		t.Fatalf("Expected an error upgrading the chaincode of another deployer")
+	}
+
+	for _, cert := range []string{"deployer", "admin"} {
+		upgradeTx.Cert = []byte(cert)
+		if err = chaincodeSupport.checkDeployerOrAdmin(lgr, "mycc", upgradeTx); err != nil {
+			t.Fatalf("Expected the certificate %s to be allowed to upgrade but got: %s", cert, err)
+		}
+	}
+}
+
+func TestExecuteUpgradeIgnoresCRLs(t *testing.T) {
+	lgr := ledger.InitTestLedger(t)
+
+	cds := &pb.ChaincodeDeploymentSpec{ChaincodeSpec: &pb.ChaincodeSpec{Type: pb.ChaincodeSpec_GOLANG, ChaincodeID: &pb.ChaincodeID{Name: "mycc"}}}
+	depTx, err := pb.NewChaincodeDeployTransaction(cds, "mycc")
+	if err != nil {
+		t.Fatalf("Error creating deploy transaction: %s", err)
+	}
+	depTx.Cert = []byte("deployer")
+	lgr.BeginTxBatch(1)
+	if err = lgr.CommitTxBatch(1, []*pb.Transaction{depTx}, nil, nil); err != nil {
+		t.Fatalf("Error committing deploy transaction: %s", err)
+	}
+
+	// One validator fetched the CRL revoking the deployer, the other did not
+	// yet: both must authorize the upgrade the same way
+	v1 := &pb.ChaincodeDeploymentSpec{ChaincodeSpec: &pb.ChaincodeSpec{Type: pb.ChaincodeSpec_GOLANG, ChaincodeID: &pb.ChaincodeID{Name: "mycc", Version: 1}}}
+	upgradeTx, _ := pb.NewChaincodeUpgradeTransaction(v1, "hash1")
+	upgradeTx.Cert = []byte("deployer")
+	revoking := &mockSecHelper{revoked: [][]byte{[]byte("deployer")}}
+	if _, err = revoking.TransactionPreValidation(upgradeTx); err == nil {
+		t.Fatalf("Expected the validator with the CRL to reject the deployer on submission")
+	}
+	for _, secHelper := range []*mockSecHelper{revoking, {}} {
+		chaincodeSupport := &ChaincodeSupport{secHelper: secHelper}
+		if err = chaincodeSupport.checkDeployerOrAdmin(lgr, "mycc", upgradeTx); err != nil {
+			t.Fatalf("Expected the upgrade to be authorized whatever the CRLs of the validator but got: %s", err)
+		}
+	}
+}
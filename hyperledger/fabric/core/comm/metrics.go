@@ -0,0 +1,32 @@
+/*
+Copyright IBM Corp. 2016 All Rights Reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+		 http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package comm
+
+import (
+	"github.com/hyperledger/fabric/core/metrics"
+)
+
+var grpcStreams = metrics.NewGaugeVec("grpc_streams",
+	"Number of open gRPC streams served by the peer, by service.", "service")
+
+// TrackStream counts a gRPC stream of the service as open until the returned
+// function is called, as in defer comm.TrackStream("peer")()
+func TrackStream(service string) func() {
+	gauge := grpcStreams.With(service)
+	gauge.Inc()
+	return gauge.Dec
+}
@@ -47,4 +47,7 @@ type CCID struct {
 	ChaincodeSpec *pb.ChaincodeSpec
 	NetworkID     string
 	PeerID        string
+	// ChainID is the chain the chaincode instance runs for, empty for the
+	// default chain
+	ChainID string
 }
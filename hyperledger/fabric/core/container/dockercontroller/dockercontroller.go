@@ -220,12 +220,21 @@ func (vm *DockerVM) Destroy(ctxt context.Context, ccid ccintf.CCID, force bool,
 
 //GetVMName generates the docker image from peer information given the hashcode. This is needed to
 //keep image name's unique in a single host, multi-peer environment (such as a development environment)
+//The versions of an upgraded chaincode get their own images, and so do the chains
+//other than the default one (lowercased, as docker requires).
 func (vm *DockerVM) GetVMName(ccid ccintf.CCID) (string, error) {
+	name := ccid.ChaincodeSpec.ChaincodeID.Name
+	if version := ccid.ChaincodeSpec.ChaincodeID.Version; version != 0 {
+		name = fmt.Sprintf("%s-v%d", name, version)
+	}
+	if ccid.ChainID != "" {
+		name = fmt.Sprintf("%s-%s", strings.ToLower(ccid.ChainID), name)
+	}
 	if ccid.NetworkID != "" {
-		return fmt.Sprintf("%s-%s-%s", ccid.NetworkID, ccid.PeerID, ccid.ChaincodeSpec.ChaincodeID.Name), nil
+		return fmt.Sprintf("%s-%s-%s", ccid.NetworkID, ccid.PeerID, name), nil
 	} else if ccid.PeerID != "" {
-		return fmt.Sprintf("%s-%s", ccid.PeerID, ccid.ChaincodeSpec.ChaincodeID.Name), nil
+		return fmt.Sprintf("%s-%s", ccid.PeerID, name), nil
 	} else {
-		return ccid.ChaincodeSpec.ChaincodeID.Name, nil
+		return name, nil
 	}
 }
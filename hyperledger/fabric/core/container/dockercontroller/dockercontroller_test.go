@@ -23,7 +23,9 @@ import (
 
 	"github.com/fsouza/go-dockerclient"
 	"github.com/hyperledger/fabric/core/config"
+	"github.com/hyperledger/fabric/core/container/ccintf"
 	"github.com/hyperledger/fabric/core/ledger/testutil"
+	pb "github.com/hyperledger/fabric/protos"
 )
 
 func TestHostConfig(t *testing.T) {
@@ -38,3 +40,15 @@ func TestHostConfig(t *testing.T) {
 	testutil.AssertEquals(t, hostConfig.LogConfig.Config["max-size"], "50m")
 	testutil.AssertEquals(t, hostConfig.LogConfig.Config["max-file"], "5")
 }
+
+func TestGetVMName(t *testing.T) {
+	vm := &DockerVM{}
+	spec := &pb.ChaincodeSpec{ChaincodeID: &pb.ChaincodeID{Name: "testcc", Version: 1}}
+
+	name, _ := vm.GetVMName(ccintf.CCID{ChaincodeSpec: spec, NetworkID: "dev", PeerID: "vp0"})
+	testutil.AssertEquals(t, name, "dev-vp0-testcc-v1")
+
+	// Every chain runs its own container of the chaincode
+	name, _ = vm.GetVMName(ccintf.CCID{ChaincodeSpec: spec, NetworkID: "dev", PeerID: "vp0", ChainID: "Chain1"})
+	testutil.AssertEquals(t, name, "dev-vp0-chain1-testcc-v1")
+}
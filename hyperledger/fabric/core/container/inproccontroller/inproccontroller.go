@@ -19,6 +19,7 @@ package inproccontroller
 import (
 	"fmt"
 	"io"
+	"sync"
 
 	"github.com/hyperledger/fabric/core/chaincode/shim"
 	"github.com/hyperledger/fabric/core/container/ccintf"
@@ -40,6 +41,8 @@ var (
 	inprocLogger = logging.MustGetLogger("inproccontroller")
 	typeRegistry = make(map[string]*inprocContainer)
 	instRegistry = make(map[string]*inprocContainer)
+	// the instances of different names are started and stopped concurrently
+	instRegistryLock sync.Mutex
 )
 
 //Register registers system chaincode with given path. The deploy should be called to initialize
@@ -59,14 +62,17 @@ type InprocVM struct {
 }
 
 func (vm *InprocVM) getInstance(ctxt context.Context, ipctemplate *inprocContainer, ccid ccintf.CCID, args []string, env []string) (*inprocContainer, error) {
-	ipc := instRegistry[ccid.ChaincodeSpec.ChaincodeID.Name]
+	name, _ := vm.GetVMName(ccid)
+	instRegistryLock.Lock()
+	defer instRegistryLock.Unlock()
+	ipc := instRegistry[name]
 	if ipc != nil {
-		inprocLogger.Warningf("chaincode instance exists for %s", ccid.ChaincodeSpec.ChaincodeID.Name)
+		inprocLogger.Warningf("chaincode instance exists for %s", name)
 		return ipc, nil
 	}
 	ipc = &inprocContainer{args: args, env: env, chaincode: ipctemplate.chaincode, stopChan: make(chan struct{})}
-	instRegistry[ccid.ChaincodeSpec.ChaincodeID.Name] = ipc
-	inprocLogger.Debugf("chaincode instance created for %s", ccid.ChaincodeSpec.ChaincodeID.Name)
+	instRegistry[name] = ipc
+	inprocLogger.Debugf("chaincode instance created for %s", name)
 	return ipc, nil
 }
 
@@ -192,19 +198,24 @@ func (vm *InprocVM) Stop(ctxt context.Context, ccid ccintf.CCID, timeout uint, d
 		return fmt.Errorf("%s not registered", path)
 	}
 
-	ipc := instRegistry[ccid.ChaincodeSpec.ChaincodeID.Name]
+	name, _ := vm.GetVMName(ccid)
+	instRegistryLock.Lock()
+	ipc := instRegistry[name]
+	instRegistryLock.Unlock()
 
 	if ipc == nil {
-		return fmt.Errorf("%s not found", ccid.ChaincodeSpec.ChaincodeID.Name)
+		return fmt.Errorf("%s not found", name)
 	}
 
 	if !ipc.running {
-		return fmt.Errorf("%s not running", ccid.ChaincodeSpec.ChaincodeID.Name)
+		return fmt.Errorf("%s not running", name)
 	}
 
 	ipc.stopChan <- struct{}{}
 
-	delete(instRegistry, ccid.ChaincodeSpec.ChaincodeID.Name)
+	instRegistryLock.Lock()
+	delete(instRegistry, name)
+	instRegistryLock.Unlock()
 	//TODO stop
 	return nil
 }
@@ -215,7 +226,16 @@ func (vm *InprocVM) Destroy(ctxt context.Context, ccid ccintf.CCID, force bool,
 	return nil
 }
 
-//GetVMName ignores the peer and network name as it just needs to be unique in process
+//GetVMName ignores the network name as it just needs to be unique in process,
+//but the chains other than the default one run their own instances, and so do
+//the peers of a process running several of them
 func (vm *InprocVM) GetVMName(ccid ccintf.CCID) (string, error) {
-	return ccid.ChaincodeSpec.ChaincodeID.Name, nil
+	name := ccid.ChaincodeSpec.ChaincodeID.Name
+	if ccid.ChainID != "" {
+		name = fmt.Sprintf("%s-%s", ccid.ChainID, name)
+	}
+	if ccid.PeerID != "" {
+		name = fmt.Sprintf("%s-%s", ccid.PeerID, name)
+	}
+	return name, nil
 }
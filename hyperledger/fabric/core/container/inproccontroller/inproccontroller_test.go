@@ -0,0 +1,39 @@
+/*
+Copyright IBM Corp. 2016 All Rights Reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+		 http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package inproccontroller
+
+import (
+	"testing"
+
+	"github.com/hyperledger/fabric/core/container/ccintf"
+	"github.com/hyperledger/fabric/core/ledger/testutil"
+	pb "github.com/hyperledger/fabric/protos"
+)
+
+func TestGetVMName(t *testing.T) {
+	vm := &InprocVM{}
+	spec := &pb.ChaincodeSpec{ChaincodeID: &pb.ChaincodeID{Name: "testcc", Version: 1}}
+
+	name, _ := vm.GetVMName(ccintf.CCID{ChaincodeSpec: spec, NetworkID: "dev"})
+	testutil.AssertEquals(t, name, "testcc")
+
+	// Every chain and every peer of the process runs its own instance
+	name, _ = vm.GetVMName(ccintf.CCID{ChaincodeSpec: spec, NetworkID: "dev", ChainID: "chain1"})
+	testutil.AssertEquals(t, name, "chain1-testcc")
+	name, _ = vm.GetVMName(ccintf.CCID{ChaincodeSpec: spec, NetworkID: "dev", PeerID: "vp0", ChainID: "chain1"})
+	testutil.AssertEquals(t, name, "vp0-chain1-testcc")
+}
@@ -0,0 +1,322 @@
+/*
+Copyright IBM Corp. 2016 All Rights Reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+		 http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package processcontroller
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/op/go-logging"
+	"github.com/spf13/viper"
+	"golang.org/x/net/context"
+
+	"github.com/hyperledger/fabric/core/container/ccintf"
+	pb "github.com/hyperledger/fabric/protos"
+)
+
+var processLogger = logging.MustGetLogger("processcontroller")
+
+type process struct {
+	cmd     *exec.Cmd
+	logFile *os.File
+	done    chan struct{}
+}
+
+var (
+	processesLock sync.Mutex
+	processes     = make(map[string]*process)
+)
+
+// ProcessVM is a vm running chaincodes as child processes of the peer. It is
+// identified by the name of the executable built from the chaincode package.
+type ProcessVM struct {
+	id string
+}
+
+// getCacheDir returns the directory holding the executables and their logs
+func getCacheDir() string {
+	if dir := viper.GetString("vm.process.cachedir"); dir != "" {
+		return dir
+	}
+	return filepath.Join(viper.GetString("peer.fileSystemPath"), "chaincodes")
+}
+
+func getExecutablePath(id string) string {
+	return filepath.Join(getCacheDir(), id)
+}
+
+func getLogPath(id string) string {
+	return filepath.Join(getCacheDir(), "logs", id+".log")
+}
+
+// getPackagePath returns the Go package of the chaincode, see the golang platform
+func getPackagePath(spec *pb.ChaincodeSpec) (string, error) {
+	if spec.Type != pb.ChaincodeSpec_GOLANG {
+		return "", fmt.Errorf("chaincode type %s cannot run as a process", spec.Type)
+	}
+	path := strings.TrimPrefix(strings.TrimPrefix(spec.ChaincodeID.Path, "http://"), "https://")
+	path = strings.TrimSuffix(path, "/")
+	if path == "" {
+		return "", fmt.Errorf("empty url location")
+	}
+	return path, nil
+}
+
+// extractSources writes the GOPATH sources of the chaincode package to dir,
+// leaving out the Dockerfile
+func extractSources(reader io.Reader, dir string) error {
+	gr, err := gzip.NewReader(reader)
+	if err != nil {
+		return fmt.Errorf("Error reading chaincode package: %s", err)
+	}
+	tr := tar.NewReader(gr)
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("Error reading chaincode package: %s", err)
+		}
+		name := filepath.Clean(header.Name)
+		if !strings.HasPrefix(name, "src"+string(filepath.Separator)) || header.Typeflag != tar.TypeReg && header.Typeflag != tar.TypeRegA {
+			continue
+		}
+		path := filepath.Join(dir, name)
+		if err = os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			return err
+		}
+		file, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0644)
+		if err != nil {
+			return err
+		}
+		_, err = io.Copy(file, tr)
+		file.Close()
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// build builds the executable from the chaincode package with the local Go
+// toolchain. The executable is named after the chaincode, which is the hash of
+// its package, so an executable in the cache is never rebuilt.
+func (vm *ProcessVM) build(ccid ccintf.CCID, id string, reader io.Reader) error {
+	executable := getExecutablePath(id)
+	if _, err := os.Stat(executable); err == nil {
+		processLogger.Debugf("executable %s already built", executable)
+		return nil
+	}
+	if reader == nil {
+		return fmt.Errorf("no chaincode package to build %s from", id)
+	}
+	packagePath, err := getPackagePath(ccid.ChaincodeSpec)
+	if err != nil {
+		return err
+	}
+	if err = os.MkdirAll(getCacheDir(), 0755); err != nil {
+		return err
+	}
+
+	gopath, err := ioutil.TempDir(getCacheDir(), "build")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(gopath)
+	if err = extractSources(reader, gopath); err != nil {
+		return err
+	}
+
+	//build next to the executable and rename, so that a failed build leaves
+	//nothing in the cache
+	output := filepath.Join(gopath, id)
+	cmd := exec.Command("go", "build", "-o", output, packagePath)
+	cmd.Env = append(filterEnv(os.Environ(), "GOPATH=", "GO111MODULE="), "GOPATH="+gopath, "GO111MODULE=off")
+	if out, err := cmd.CombinedOutput(); err != nil {
+		processLogger.Errorf("Error building chaincode %s:\n%s", id, out)
+		return fmt.Errorf("Error building chaincode %s: %s", id, err)
+	}
+	if err = os.Rename(output, executable); err != nil {
+		return err
+	}
+	processLogger.Debugf("Built executable %s", executable)
+	return nil
+}
+
+// filterEnv removes the variables with the given prefixes from env
+func filterEnv(env []string, prefixes ...string) []string {
+	var filtered []string
+	for _, v := range env {
+		keep := true
+		for _, prefix := range prefixes {
+			if strings.HasPrefix(v, prefix) {
+				keep = false
+				break
+			}
+		}
+		if keep {
+			filtered = append(filtered, v)
+		}
+	}
+	return filtered
+}
+
+// Deploy builds the executable of the chaincode from the targz of its package
+func (vm *ProcessVM) Deploy(ctxt context.Context, ccid ccintf.CCID, args []string, env []string, attachstdin bool, attachstdout bool, reader io.Reader) error {
+	id, _ := vm.GetVMName(ccid)
+	return vm.build(ccid, id, reader)
+}
+
+// Start runs the executable of the chaincode as a child process, building it
+// first if necessary. The first argument, the path of the executable in a
+// container, is replaced by the path of the executable in the cache. The peer
+// environment is inherited but for its CORE_ settings.
+func (vm *ProcessVM) Start(ctxt context.Context, ccid ccintf.CCID, args []string, env []string, attachstdin bool, attachstdout bool, reader io.Reader) error {
+	id, _ := vm.GetVMName(ccid)
+
+	//stop if necessary
+	vm.stopInternal(id, 0, false)
+
+	if err := vm.build(ccid, id, reader); err != nil {
+		processLogger.Errorf("start-could not build executable: %s", err)
+		return err
+	}
+
+	logPath := getLogPath(id)
+	if err := os.MkdirAll(filepath.Dir(logPath), 0755); err != nil {
+		return err
+	}
+	logFile, err := os.OpenFile(logPath, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return err
+	}
+
+	var cmdArgs []string
+	if len(args) > 1 {
+		cmdArgs = args[1:]
+	}
+	cmd := exec.Command(getExecutablePath(id), cmdArgs...)
+	cmd.Env = append(filterEnv(os.Environ(), "CORE_"), env...)
+	cmd.Stdout = logFile
+	cmd.Stderr = logFile
+	//run in its own process group, so that Stop also kills its children
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+	if err = cmd.Start(); err != nil {
+		logFile.Close()
+		processLogger.Errorf("start-could not start process %s", err)
+		return err
+	}
+
+	p := &process{cmd: cmd, logFile: logFile, done: make(chan struct{})}
+	processesLock.Lock()
+	processes[id] = p
+	processesLock.Unlock()
+	go func() {
+		err := cmd.Wait()
+		processLogger.Debugf("Process %s(%d) exited: %v", id, cmd.Process.Pid, err)
+		logFile.Close()
+		processesLock.Lock()
+		if processes[id] == p {
+			delete(processes, id)
+		}
+		processesLock.Unlock()
+		close(p.done)
+	}()
+
+	processLogger.Debugf("Started process %s(%d), logging to %s", id, cmd.Process.Pid, logPath)
+	return nil
+}
+
+// Stop stops a running chaincode. There is no container to remove: the log of
+// the process is kept.
+func (vm *ProcessVM) Stop(ctxt context.Context, ccid ccintf.CCID, timeout uint, dontkill bool, dontremove bool) error {
+	id, _ := vm.GetVMName(ccid)
+	return vm.stopInternal(id, timeout, dontkill)
+}
+
+// stopInternal sends SIGTERM to the process group of the chaincode and, unless
+// dontkill is set, SIGKILL if it is still running after the timeout in seconds
+func (vm *ProcessVM) stopInternal(id string, timeout uint, dontkill bool) error {
+	processesLock.Lock()
+	p, ok := processes[id]
+	processesLock.Unlock()
+	if !ok {
+		processLogger.Debugf("Stop process %s(not running)", id)
+		return nil
+	}
+
+	pgid := -p.cmd.Process.Pid
+	if err := syscall.Kill(pgid, syscall.SIGTERM); err != nil {
+		processLogger.Debugf("Stop process %s(%s)", id, err)
+	}
+	select {
+	case <-p.done:
+		processLogger.Debugf("Stopped process %s", id)
+		return nil
+	case <-time.After(time.Duration(timeout) * time.Second):
+	}
+	if dontkill {
+		return fmt.Errorf("process %s still running after %d seconds", id, timeout)
+	}
+	if err := syscall.Kill(pgid, syscall.SIGKILL); err != nil {
+		processLogger.Debugf("Kill process %s(%s)", id, err)
+	}
+	<-p.done
+	processLogger.Debugf("Killed process %s", id)
+	return nil
+}
+
+// Destroy removes the executable of the chaincode from the cache
+func (vm *ProcessVM) Destroy(ctxt context.Context, ccid ccintf.CCID, force bool, noprune bool) error {
+	id, _ := vm.GetVMName(ccid)
+	err := os.Remove(getExecutablePath(id))
+	if err != nil {
+		processLogger.Error(fmt.Sprintf("error while destroying executable: %s", err))
+	} else {
+		processLogger.Debugf("Destroyed executable %s", id)
+	}
+	return err
+}
+
+// GetVMName generates the executable name from peer information given the
+// hashcode, the same way the docker vm names its images
+func (vm *ProcessVM) GetVMName(ccid ccintf.CCID) (string, error) {
+	name := ccid.ChaincodeSpec.ChaincodeID.Name
+	if version := ccid.ChaincodeSpec.ChaincodeID.Version; version != 0 {
+		name = fmt.Sprintf("%s-v%d", name, version)
+	}
+	if ccid.ChainID != "" {
+		name = fmt.Sprintf("%s-%s", ccid.ChainID, name)
+	}
+	if ccid.NetworkID != "" {
+		return fmt.Sprintf("%s-%s-%s", ccid.NetworkID, ccid.PeerID, name), nil
+	} else if ccid.PeerID != "" {
+		return fmt.Sprintf("%s-%s", ccid.PeerID, name), nil
+	} else {
+		return name, nil
+	}
+}
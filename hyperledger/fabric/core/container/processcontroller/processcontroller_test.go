@@ -0,0 +1,156 @@
+/*
+Copyright IBM Corp. 2016 All Rights Reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+		 http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package processcontroller
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"io/ioutil"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/spf13/viper"
+	"golang.org/x/net/context"
+
+	"github.com/hyperledger/fabric/core/container/ccintf"
+	pb "github.com/hyperledger/fabric/protos"
+)
+
+// testChaincode prints its arguments and environment, and ignores SIGTERM so
+// that stopping it requires a kill
+const testChaincode = `package main
+
+import (
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+)
+
+func main() {
+	signal.Ignore(syscall.SIGTERM)
+	fmt.Println(os.Args[1:], os.Getenv("CORE_CHAINCODE_ID_NAME"))
+	time.Sleep(time.Hour)
+}
+`
+
+func getTestPackage(t *testing.T) []byte {
+	inputbuf := bytes.NewBuffer(nil)
+	gw := gzip.NewWriter(inputbuf)
+	tw := tar.NewWriter(gw)
+	dockerfile := "FROM scratch\n"
+	tw.WriteHeader(&tar.Header{Name: "Dockerfile", Size: int64(len(dockerfile)), Mode: 0644})
+	tw.Write([]byte(dockerfile))
+	tw.WriteHeader(&tar.Header{Name: "src/example.com/testcc/main.go", Size: int64(len(testChaincode)), Mode: 0644})
+	tw.Write([]byte(testChaincode))
+	if err := tw.Close(); err != nil {
+		t.Fatalf("Error writing package: %s", err)
+	}
+	gw.Close()
+	return inputbuf.Bytes()
+}
+
+func TestProcessVM(t *testing.T) {
+	dir, err := ioutil.TempDir("", "processvm")
+	if err != nil {
+		t.Fatalf("Error creating cache dir: %s", err)
+	}
+	defer os.RemoveAll(dir)
+	viper.Set("vm.process.cachedir", dir)
+	defer viper.Set("vm.process.cachedir", "")
+
+	vm := &ProcessVM{}
+	ccid := ccintf.CCID{ChaincodeSpec: &pb.ChaincodeSpec{Type: pb.ChaincodeSpec_GOLANG, ChaincodeID: &pb.ChaincodeID{Path: "example.com/testcc", Name: "testcc", Version: 1}}, PeerID: "vp0"}
+	id, _ := vm.GetVMName(ccid)
+	if id != "vp0-testcc-v1" {
+		t.Fatalf("Expected name vp0-testcc-v1 but got %s", id)
+	}
+
+	if err = vm.Deploy(context.Background(), ccid, nil, nil, false, false, bytes.NewReader(getTestPackage(t))); err != nil {
+		t.Fatalf("Error deploying chaincode: %s", err)
+	}
+	if _, err = os.Stat(getExecutablePath(id)); err != nil {
+		t.Fatalf("Expected an executable in the cache: %s", err)
+	}
+
+	// The first argument is the path of the executable in a container
+	args := []string{"/opt/gopath/bin/testcc", "-peer.address=localhost:30303"}
+	env := []string{"CORE_CHAINCODE_ID_NAME=testcc"}
+	if err = vm.Start(context.Background(), ccid, args, env, false, false, nil); err != nil {
+		t.Fatalf("Error starting chaincode: %s", err)
+	}
+	expected := "[-peer.address=localhost:30303] testcc\n"
+	var output []byte
+	for i := 0; i < 100 && string(output) != expected; i++ {
+		time.Sleep(50 * time.Millisecond)
+		output, _ = ioutil.ReadFile(getLogPath(id))
+	}
+	if string(output) != expected {
+		t.Fatalf("Expected output %q but got %q", expected, output)
+	}
+
+	// The chaincode ignores SIGTERM, so it is killed
+	if err = vm.Stop(context.Background(), ccid, 0, false, false); err != nil {
+		t.Fatalf("Error stopping chaincode: %s", err)
+	}
+	processesLock.Lock()
+	_, running := processes[id]
+	processesLock.Unlock()
+	if running {
+		t.Fatalf("Expected the chaincode process to be stopped")
+	}
+
+	if err = vm.Destroy(context.Background(), ccid, false, false); err != nil {
+		t.Fatalf("Error destroying chaincode: %s", err)
+	}
+	if _, err = os.Stat(getExecutablePath(id)); !os.IsNotExist(err) {
+		t.Fatalf("Expected the executable to be removed from the cache")
+	}
+	if err = vm.Start(context.Background(), ccid, args, env, false, false, nil); err == nil {
+		t.Fatalf("Expected an error starting a destroyed chaincode without its package")
+	}
+}
+
+func TestProcessVMBuildErrors(t *testing.T) {
+	dir, err := ioutil.TempDir("", "processvm")
+	if err != nil {
+		t.Fatalf("Error creating cache dir: %s", err)
+	}
+	defer os.RemoveAll(dir)
+	viper.Set("vm.process.cachedir", dir)
+	defer viper.Set("vm.process.cachedir", "")
+
+	vm := &ProcessVM{}
+	car := ccintf.CCID{ChaincodeSpec: &pb.ChaincodeSpec{Type: pb.ChaincodeSpec_CAR, ChaincodeID: &pb.ChaincodeID{Path: "example.com/testcc", Name: "carcc"}}}
+	if err = vm.Deploy(context.Background(), car, nil, nil, false, false, bytes.NewReader(getTestPackage(t))); err == nil {
+		t.Fatalf("Expected an error deploying a CAR chaincode")
+	}
+
+	missing := ccintf.CCID{ChaincodeSpec: &pb.ChaincodeSpec{Type: pb.ChaincodeSpec_GOLANG, ChaincodeID: &pb.ChaincodeID{Path: "example.com/missing", Name: "missingcc"}}}
+	err = vm.Deploy(context.Background(), missing, nil, nil, false, false, bytes.NewReader(getTestPackage(t)))
+	if err == nil || !strings.Contains(err.Error(), "Error building chaincode") {
+		t.Fatalf("Expected a build error but got %v", err)
+	}
+	files, _ := ioutil.ReadDir(dir)
+	if len(files) != 0 {
+		t.Fatalf("Expected a failed build to leave nothing in the cache, found %d files", len(files))
+	}
+}
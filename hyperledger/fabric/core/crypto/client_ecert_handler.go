@@ -113,3 +113,13 @@ func (handler *eCertTransactionHandlerImpl) NewChaincodeExecute(chaincodeInvocat
 func (handler *eCertTransactionHandlerImpl) NewChaincodeQuery(chaincodeInvocation *obc.ChaincodeInvocationSpec, uuid string, attributeNames ...string) (*obc.Transaction, error) {
 	return handler.client.newChaincodeQueryUsingECert(chaincodeInvocation, uuid, handler.nonce)
 }
+
+// NewChaincodeTerminate is used to terminate chaincode.
+func (handler *eCertTransactionHandlerImpl) NewChaincodeTerminate(chaincodeTermination *obc.ChaincodeTerminationSpec, uuid string, attributeNames ...string) (*obc.Transaction, error) {
+	return handler.client.newChaincodeTerminateUsingECert(chaincodeTermination, uuid, handler.nonce)
+}
+
+// NewChaincodeUpgrade is used to upgrade chaincode.
+func (handler *eCertTransactionHandlerImpl) NewChaincodeUpgrade(chaincodeDeploymentSpec *obc.ChaincodeDeploymentSpec, uuid string, attributeNames ...string) (*obc.Transaction, error) {
+	return handler.client.newChaincodeUpgradeUsingECert(chaincodeDeploymentSpec, uuid, handler.nonce)
+}
@@ -131,6 +131,52 @@ func (client *clientImpl) NewChaincodeQuery(chaincodeInvocation *obc.ChaincodeIn
 	return client.newChaincodeQueryUsingTCert(chaincodeInvocation, uuid, attributes, tBlocks[0].tCert, nil)
 }
 
+// NewChaincodeTerminate is used to terminate chaincode.
+func (client *clientImpl) NewChaincodeTerminate(chaincodeTermination *obc.ChaincodeTerminationSpec, uuid string, attributes ...string) (*obc.Transaction, error) {
+	// Verify that the client is initialized
+	if !client.isInitialized {
+		return nil, utils.ErrNotInitialized
+	}
+
+	// Get next available (not yet used) transaction certificate
+	tBlocks, err := client.tCertPool.GetNextTCerts(1, attributes...)
+	if err != nil {
+		client.Errorf("Failed to obtain a (not yet used) TCert [%s].", err.Error())
+		return nil, err
+	}
+
+	if len(tBlocks) != 1 {
+		client.Error("Failed to obtain a (not yet used) TCert.")
+		return nil, errors.New("Failed to obtain a TCert for Chaincode Termination. Expected exactly one returned TCert.")
+	}
+
+	// Create Transaction
+	return client.newChaincodeTerminateUsingTCert(chaincodeTermination, uuid, tBlocks[0].tCert, nil)
+}
+
+// NewChaincodeUpgrade is used to upgrade chaincode.
+func (client *clientImpl) NewChaincodeUpgrade(chaincodeDeploymentSpec *obc.ChaincodeDeploymentSpec, uuid string, attributes ...string) (*obc.Transaction, error) {
+	// Verify that the client is initialized
+	if !client.isInitialized {
+		return nil, utils.ErrNotInitialized
+	}
+
+	// Get next available (not yet used) transaction certificate
+	tBlocks, err := client.tCertPool.GetNextTCerts(1, attributes...)
+	if err != nil {
+		client.Errorf("Failed to obtain a (not yet used) TCert [%s].", err.Error())
+		return nil, err
+	}
+
+	if len(tBlocks) != 1 {
+		client.Error("Failed to obtain a (not yet used) TCert.")
+		return nil, errors.New("Failed to obtain a TCert for Chaincode Upgrade. Expected exactly one returned TCert.")
+	}
+
+	// Create Transaction
+	return client.newChaincodeUpgradeUsingTCert(chaincodeDeploymentSpec, uuid, attributes, tBlocks[0].tCert, nil)
+}
+
 // GetEnrollmentCertHandler returns a CertificateHandler whose certificate is the enrollment certificate
 func (client *clientImpl) GetEnrollmentCertificateHandler() (CertificateHandler, error) {
 	// Verify that the client is initialized
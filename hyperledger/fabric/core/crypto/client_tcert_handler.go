@@ -109,3 +109,13 @@ func (handler *tCertTransactionHandlerImpl) NewChaincodeExecute(chaincodeInvocat
 func (handler *tCertTransactionHandlerImpl) NewChaincodeQuery(chaincodeInvocation *obc.ChaincodeInvocationSpec, uuid string, attributeNames ...string) (*obc.Transaction, error) {
 	return handler.tCertHandler.client.newChaincodeQueryUsingTCert(chaincodeInvocation, uuid, attributeNames, handler.tCertHandler.tCert, handler.nonce)
 }
+
+// NewChaincodeTerminate is used to terminate chaincode.
+func (handler *tCertTransactionHandlerImpl) NewChaincodeTerminate(chaincodeTermination *obc.ChaincodeTerminationSpec, uuid string, attributeNames ...string) (*obc.Transaction, error) {
+	return handler.tCertHandler.client.newChaincodeTerminateUsingTCert(chaincodeTermination, uuid, handler.tCertHandler.tCert, handler.nonce)
+}
+
+// NewChaincodeUpgrade is used to upgrade chaincode.
+func (handler *tCertTransactionHandlerImpl) NewChaincodeUpgrade(chaincodeDeploymentSpec *obc.ChaincodeDeploymentSpec, uuid string, attributeNames ...string) (*obc.Transaction, error) {
+	return handler.tCertHandler.client.newChaincodeUpgradeUsingTCert(chaincodeDeploymentSpec, uuid, attributeNames, handler.tCertHandler.tCert, handler.nonce)
+}
@@ -456,3 +456,212 @@ func (client *clientImpl) checkTransaction(tx *obc.Transaction) error {
 
 	return utils.ErrTransactionMissingCert
 }
+
+func (client *clientImpl) createTerminateTx(chaincodeTermination *obc.ChaincodeTerminationSpec, uuid string, nonce []byte) (*obc.Transaction, error) {
+	// Create a new transaction
+	tx, err := obc.NewChaincodeTerminateTransaction(chaincodeTermination, uuid)
+	if err != nil {
+		client.Errorf("Failed creating new transaction [%s].", err.Error())
+		return nil, err
+	}
+
+	if nonce == nil {
+		tx.Nonce, err = primitives.GetRandomNonce()
+		if err != nil {
+			client.Errorf("Failed creating nonce [%s].", err.Error())
+			return nil, err
+		}
+	} else {
+		// TODO: check that it is a well formed nonce
+		tx.Nonce = nonce
+	}
+
+	// Terminate transactions are never confidential: every validator must be
+	// able to read which chaincode is being terminated.
+
+	return tx, nil
+}
+
+func (client *clientImpl) newChaincodeTerminateUsingTCert(chaincodeTermination *obc.ChaincodeTerminationSpec, uuid string, tCert tCert, nonce []byte) (*obc.Transaction, error) {
+	// Create a new transaction
+	tx, err := client.createTerminateTx(chaincodeTermination, uuid, nonce)
+	if err != nil {
+		client.Errorf("Failed creating new terminate transaction [%s].", err.Error())
+		return nil, err
+	}
+
+	// Sign the transaction
+
+	// Append the certificate to the transaction
+	client.Debugf("Appending certificate [% x].", tCert.GetCertificate().Raw)
+	tx.Cert = tCert.GetCertificate().Raw
+
+	// Sign the transaction and append the signature
+	// 1. Marshall tx to bytes
+	rawTx, err := proto.Marshal(tx)
+	if err != nil {
+		client.Errorf("Failed marshaling tx [%s].", err.Error())
+		return nil, err
+	}
+
+	// 2. Sign rawTx and check signature
+	rawSignature, err := tCert.Sign(rawTx)
+	if err != nil {
+		client.Errorf("Failed creating signature [% x]: [%s].", rawSignature, err.Error())
+		return nil, err
+	}
+
+	// 3. Append the signature
+	tx.Signature = rawSignature
+
+	client.Debugf("Appending signature [% x].", rawSignature)
+
+	return tx, nil
+}
+
+func (client *clientImpl) createUpgradeTx(chaincodeDeploymentSpec *obc.ChaincodeDeploymentSpec, uuid string, nonce []byte, tCert tCert, attrs ...string) (*obc.Transaction, error) {
+	// Upgrade transactions are never confidential: the state of the previous
+	// version is copied in clear to the new version.
+	if chaincodeDeploymentSpec.ChaincodeSpec.ConfidentialityLevel == obc.ConfidentialityLevel_CONFIDENTIAL {
+		client.Error("Confidential chaincode cannot be upgraded.")
+		return nil, utils.ErrInvalidConfidentialityLevel
+	}
+
+	// Create a new transaction
+	tx, err := obc.NewChaincodeUpgradeTransaction(chaincodeDeploymentSpec, uuid)
+	if err != nil {
+		client.Errorf("Failed creating new transaction [%s].", err.Error())
+		return nil, err
+	}
+
+	// Copy metadata from ChaincodeSpec
+	tx.Metadata, err = getMetadata(chaincodeDeploymentSpec.GetChaincodeSpec(), tCert, attrs...)
+	if err != nil {
+		client.Errorf("Failed creating new transaction [%s].", err.Error())
+		return nil, err
+	}
+
+	if nonce == nil {
+		tx.Nonce, err = primitives.GetRandomNonce()
+		if err != nil {
+			client.Errorf("Failed creating nonce [%s].", err.Error())
+			return nil, err
+		}
+	} else {
+		// TODO: check that it is a well formed nonce
+		tx.Nonce = nonce
+	}
+
+	return tx, nil
+}
+
+func (client *clientImpl) newChaincodeUpgradeUsingTCert(chaincodeDeploymentSpec *obc.ChaincodeDeploymentSpec, uuid string, attributeNames []string, tCert tCert, nonce []byte) (*obc.Transaction, error) {
+	// Create a new transaction
+	tx, err := client.createUpgradeTx(chaincodeDeploymentSpec, uuid, nonce, tCert, attributeNames...)
+	if err != nil {
+		client.Errorf("Failed creating new upgrade transaction [%s].", err.Error())
+		return nil, err
+	}
+
+	// Sign the transaction
+
+	// Append the certificate to the transaction
+	client.Debugf("Appending certificate [% x].", tCert.GetCertificate().Raw)
+	tx.Cert = tCert.GetCertificate().Raw
+
+	// Sign the transaction and append the signature
+	// 1. Marshall tx to bytes
+	rawTx, err := proto.Marshal(tx)
+	if err != nil {
+		client.Errorf("Failed marshaling tx [%s].", err.Error())
+		return nil, err
+	}
+
+	// 2. Sign rawTx and check signature
+	rawSignature, err := tCert.Sign(rawTx)
+	if err != nil {
+		client.Errorf("Failed creating signature [% x]: [%s].", rawTx, err.Error())
+		return nil, err
+	}
+
+	// 3. Append the signature
+	tx.Signature = rawSignature
+
+	client.Debugf("Appending signature: [% x]", rawSignature)
+
+	return tx, nil
+}
+
+func (client *clientImpl) newChaincodeTerminateUsingECert(chaincodeTermination *obc.ChaincodeTerminationSpec, uuid string, nonce []byte) (*obc.Transaction, error) {
+	// Create a new transaction
+	tx, err := client.createTerminateTx(chaincodeTermination, uuid, nonce)
+	if err != nil {
+		client.Errorf("Failed creating new terminate transaction [%s].", err.Error())
+		return nil, err
+	}
+
+	// Sign the transaction
+
+	// Append the certificate to the transaction
+	client.Debugf("Appending certificate [% x].", client.enrollCert.Raw)
+	tx.Cert = client.enrollCert.Raw
+
+	// Sign the transaction and append the signature
+	// 1. Marshall tx to bytes
+	rawTx, err := proto.Marshal(tx)
+	if err != nil {
+		client.Errorf("Failed marshaling tx [%s].", err.Error())
+		return nil, err
+	}
+
+	// 2. Sign rawTx and check signature
+	rawSignature, err := client.signWithEnrollmentKey(rawTx)
+	if err != nil {
+		client.Errorf("Failed creating signature [% x]: [%s].", rawTx, err.Error())
+		return nil, err
+	}
+
+	// 3. Append the signature
+	tx.Signature = rawSignature
+
+	client.Debugf("Appending signature: [% x]", rawSignature)
+
+	return tx, nil
+}
+
+func (client *clientImpl) newChaincodeUpgradeUsingECert(chaincodeDeploymentSpec *obc.ChaincodeDeploymentSpec, uuid string, nonce []byte) (*obc.Transaction, error) {
+	// Create a new transaction
+	tx, err := client.createUpgradeTx(chaincodeDeploymentSpec, uuid, nonce, nil)
+	if err != nil {
+		client.Errorf("Failed creating new upgrade transaction [%s].", err.Error())
+		return nil, err
+	}
+
+	// Sign the transaction
+
+	// Append the certificate to the transaction
+	client.Debugf("Appending certificate [% x].", client.enrollCert.Raw)
+	tx.Cert = client.enrollCert.Raw
+
+	// Sign the transaction and append the signature
+	// 1. Marshall tx to bytes
+	rawTx, err := proto.Marshal(tx)
+	if err != nil {
+		client.Errorf("Failed marshaling tx [%s].", err.Error())
+		return nil, err
+	}
+
+	// 2. Sign rawTx and check signature
+	rawSignature, err := client.signWithEnrollmentKey(rawTx)
+	if err != nil {
+		client.Errorf("Failed creating signature [% x]: [%s].", rawTx, err.Error())
+		return nil, err
+	}
+
+	// 3. Append the signature
+	tx.Signature = rawSignature
+
+	client.Debugf("Appending signature: [% x]", rawSignature)
+
+	return tx, nil
+}
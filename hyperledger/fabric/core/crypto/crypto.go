@@ -57,6 +57,12 @@ type Client interface {
 	// NewChaincodeQuery is used to query chaincode's functions.
 	NewChaincodeQuery(chaincodeInvocation *obc.ChaincodeInvocationSpec, uuid string, attributes ...string) (*obc.Transaction, error)
 
+	// NewChaincodeTerminate is used to terminate chaincode.
+	NewChaincodeTerminate(chaincodeTermination *obc.ChaincodeTerminationSpec, uuid string, attributes ...string) (*obc.Transaction, error)
+
+	// NewChaincodeUpgrade is used to upgrade chaincode.
+	NewChaincodeUpgrade(chaincodeDeploymentSpec *obc.ChaincodeDeploymentSpec, uuid string, attributes ...string) (*obc.Transaction, error)
+
 	// DecryptQueryResult is used to decrypt the result of a query transaction
 	DecryptQueryResult(queryTx *obc.Transaction, result []byte) ([]byte, error)
 
@@ -88,6 +94,13 @@ type Peer interface {
 	// prescriptions (i.e. signature verification).
 	TransactionPreValidation(tx *obc.Transaction) (*obc.Transaction, error)
 
+	// VerifyTransactionSignature verifies that the transaction is signed
+	// with the key of its certificate, without checking whether the
+	// certificate has been revoked. Unlike TransactionPreValidation, its
+	// outcome is the same on every peer, so it is the check to use while
+	// executing a transaction.
+	VerifyTransactionSignature(tx *obc.Transaction) error
+
 	// TransactionPreExecution verifies that the transaction is
 	// well formed with the respect to the security layer
 	// prescriptions (i.e. signature verification). If this is the case,
@@ -110,6 +123,10 @@ type Peer interface {
 	GetStateEncryptor(deployTx, executeTx *obc.Transaction) (StateEncryptor, error)
 
 	GetTransactionBinding(tx *obc.Transaction) ([]byte, error)
+
+	// IsAdmin returns true if cert is a valid enrollment certificate of one
+	// of the administrators listed in 'security.admins'.
+	IsAdmin(cert []byte) bool
 }
 
 // StateEncryptor is used to encrypt chaincode's state
@@ -157,4 +174,10 @@ type TransactionHandler interface {
 
 	// NewChaincodeQuery is used to query chaincode's functions
 	NewChaincodeQuery(chaincodeInvocation *obc.ChaincodeInvocationSpec, uuid string, attributeNames ...string) (*obc.Transaction, error)
+
+	// NewChaincodeTerminate is used to terminate chaincode
+	NewChaincodeTerminate(chaincodeTermination *obc.ChaincodeTerminationSpec, uuid string, attributeNames ...string) (*obc.Transaction, error)
+
+	// NewChaincodeUpgrade is used to upgrade chaincode
+	NewChaincodeUpgrade(chaincodeDeploymentSpec *obc.ChaincodeDeploymentSpec, uuid string, attributeNames ...string) (*obc.Transaction, error)
 }
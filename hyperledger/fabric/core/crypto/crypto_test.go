@@ -37,8 +37,11 @@ import (
 	"github.com/hyperledger/fabric/core/crypto/attributes"
 	"github.com/hyperledger/fabric/core/crypto/primitives"
 	"github.com/hyperledger/fabric/core/crypto/utils"
+	"github.com/golang/protobuf/proto"
 	"github.com/hyperledger/fabric/core/util"
 	"github.com/hyperledger/fabric/membersrvc/ca"
+	membersrvc "github.com/hyperledger/fabric/membersrvc/protos"
+	"golang.org/x/net/context"
 	"github.com/spf13/viper"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/credentials"
@@ -798,6 +801,83 @@ func TestPeerExecuteTransaction(t *testing.T) {
 	}
 }
 
+func TestPeerRevokedCertificate(t *testing.T) {
+	initNodes()
+	defer closeNodes()
+
+	_, tx, err := createPublicExecuteTransaction(t)
+	if err != nil {
+		t.Fatalf("TransactionPreValidation: failed creating transaction [%s].", err)
+	}
+	if _, err = peer.TransactionPreValidation(tx); err != nil {
+		t.Fatalf("Error must be nil [%s].", err)
+	}
+
+	// Revoke the TCert used to sign the transaction
+	client := invoker.(*clientImpl)
+	req := &membersrvc.TCertRevokeReq{
+		Id:     &membersrvc.Identity{Id: client.enrollID},
+		Cert:   &membersrvc.Cert{Cert: tx.Cert},
+		Reason: membersrvc.CRLReason_KEY_COMPROMISE,
+	}
+	rawReq, err := proto.Marshal(req)
+	if err != nil {
+		t.Fatalf("Failed marshaling request [%s].", err)
+	}
+	r, s, err := primitives.ECDSASignDirect(client.enrollPrivKey, rawReq)
+	if err != nil {
+		t.Fatalf("Failed signing request [%s].", err)
+	}
+	R, _ := r.MarshalText()
+	S, _ := s.MarshalText()
+	req.Sig = &membersrvc.Signature{Type: membersrvc.CryptoType_ECDSA, R: R, S: S}
+
+	sock, tcaP, err := client.getTCAClient()
+	if err != nil {
+		t.Fatalf("Failed getting TCA client [%s].", err)
+	}
+	defer sock.Close()
+	if _, err = tcaP.RevokeCertificate(context.Background(), req); err != nil {
+		t.Fatalf("Failed revoking TCert [%s].", err)
+	}
+
+	peer.(*peerImpl).refreshCRLs()
+	if _, err = peer.TransactionPreValidation(tx); err != utils.ErrRevokedCertificate {
+		t.Fatalf("Error must be ErrRevokedCertificate [%v].", err)
+	}
+
+	// The signature is still valid, whatever the CRLs of the peer
+	if err = peer.VerifyTransactionSignature(tx); err != nil {
+		t.Fatalf("Error must be nil [%s].", err)
+	}
+	tx.Signature[len(tx.Signature)-1] ^= 1
+	if err = peer.VerifyTransactionSignature(tx); err == nil {
+		t.Fatal("Error must be different from nil for a tampered signature.")
+	}
+}
+
+func TestPeerIsAdmin(t *testing.T) {
+	initNodes()
+	defer closeNodes()
+
+	peer.(*peerImpl).conf.admins = []string{deployer.(*clientImpl).enrollID}
+	defer func() { peer.(*peerImpl).conf.admins = nil }()
+
+	if !peer.IsAdmin(deployer.(*clientImpl).enrollCert.Raw) {
+		t.Fatal("The enrollment certificate of an administrator must be accepted.")
+	}
+	if peer.IsAdmin(invoker.(*clientImpl).enrollCert.Raw) {
+		t.Fatal("The enrollment certificate of a non administrator must be rejected.")
+	}
+	handler, err := deployer.GetTCertificateHandlerNext()
+	if err != nil {
+		t.Fatalf("Failed getting handler [%s].", err)
+	}
+	if peer.IsAdmin(handler.GetCertificate()) {
+		t.Fatal("The transaction certificate of an administrator must be rejected.")
+	}
+}
+
 func TestPeerQueryTransaction(t *testing.T) {
 	initNodes()
 	defer closeNodes()
@@ -1472,8 +1552,8 @@ func setup() {
 
 func initPKI() {
 	ca.LogInit(ioutil.Discard, os.Stdout, os.Stdout, os.Stderr, os.Stdout)
-	aca = ca.NewACA()
 	eca = ca.NewECA()
+	aca = ca.NewACA(eca)
 	tca = ca.NewTCA(eca)
 	tlsca = ca.NewTLSCA(eca)
 }
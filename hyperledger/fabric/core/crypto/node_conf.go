@@ -19,6 +19,7 @@ package crypto
 import (
 	"errors"
 	"path/filepath"
+	"time"
 
 	"github.com/spf13/viper"
 )
@@ -63,6 +64,11 @@ type configuration struct {
 
 	multiThreading bool
 	tCertBatchSize int
+
+	crlEnabled bool
+	crlRefresh time.Duration
+
+	admins []string
 }
 
 func (conf *configuration) init() error {
@@ -152,6 +158,23 @@ func (conf *configuration) init() error {
 		conf.multiThreading = viper.GetBool("security.multithreading.enabled")
 	}
 
+	// Set CRL checking
+	conf.crlEnabled = true
+	if viper.IsSet("security.crl.enabled") {
+		conf.crlEnabled = viper.GetBool("security.crl.enabled")
+	}
+
+	conf.crlRefresh = 10 * time.Minute
+	if viper.IsSet("security.crl.refresh") {
+		ovveride := viper.GetDuration("security.crl.refresh")
+		if ovveride != 0 {
+			conf.crlRefresh = ovveride
+		}
+	}
+
+	// Set administrators
+	conf.admins = viper.GetStringSlice("security.admins")
+
 	return nil
 }
 
@@ -235,6 +258,26 @@ func (conf *configuration) getECACertsChainFilename() string {
 	return "eca.cert.chain"
 }
 
+func (conf *configuration) getECACRLFilename() string {
+	return "eca.crl"
+}
+
+func (conf *configuration) getTCACRLFilename() string {
+	return "tca.crl"
+}
+
+func (conf *configuration) isCRLEnabled() bool {
+	return conf.crlEnabled
+}
+
+func (conf *configuration) getCRLRefreshInterval() time.Duration {
+	return conf.crlRefresh
+}
+
+func (conf *configuration) getAdmins() []string {
+	return conf.admins
+}
+
 func (conf *configuration) getTLSCACertsChainFilename() string {
 	return "tlsca.cert.chain"
 }
@@ -19,6 +19,7 @@ package crypto
 import (
 	"crypto/x509"
 	"database/sql"
+	"encoding/pem"
 	"io/ioutil"
 	"os"
 	"path/filepath"
@@ -268,6 +269,32 @@ func (ks *keyStore) loadCert(alias string) ([]byte, error) {
 	return pem, nil
 }
 
+func (ks *keyStore) storeCRL(alias string, der []byte) error {
+	raw := pem.EncodeToMemory(&pem.Block{Type: "X509 CRL", Bytes: der})
+
+	err := ioutil.WriteFile(ks.node.conf.getPathForAlias(alias), raw, 0700)
+	if err != nil {
+		ks.node.Errorf("Failed storing crl [%s]: [%s]", alias, err)
+		return err
+	}
+
+	return nil
+}
+
+func (ks *keyStore) loadCRL(alias string) ([]byte, error) {
+	path := ks.node.conf.getPathForAlias(alias)
+	ks.node.Debugf("Loading crl [%s] at [%s]...", alias, path)
+
+	pem, err := ioutil.ReadFile(path)
+	if err != nil {
+		ks.node.Debugf("Failed loading crl [%s]: [%s].", alias, err.Error())
+
+		return nil, err
+	}
+
+	return pem, nil
+}
+
 func (ks *keyStore) loadExternalCert(path string) ([]byte, error) {
 	ks.node.Debugf("Loading external certificate at [%s]...", path)
 
@@ -0,0 +1,170 @@
+/*
+Copyright IBM Corp. 2016 All Rights Reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+		 http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package crypto
+
+import (
+	"bytes"
+	"crypto/x509"
+	"time"
+
+	"golang.org/x/net/context"
+)
+
+// revocationList is a certificate revocation list published by either the
+// ECA or the TCA, indexed by the serial numbers of the revoked certificates.
+type revocationList struct {
+	issuer     *x509.Certificate
+	nextUpdate time.Time
+	revoked    map[string]bool
+}
+
+func (crl *revocationList) contains(cert *x509.Certificate) bool {
+	if !bytes.Equal(cert.RawIssuer, crl.issuer.RawSubject) {
+		return false
+	}
+
+	return crl.revoked[cert.SerialNumber.String()]
+}
+
+func (peer *peerImpl) initCRLs() error {
+	peer.crls = make(map[string]*revocationList)
+	if !peer.conf.isCRLEnabled() {
+		peer.Debug("CRL checking disabled.")
+
+		return nil
+	}
+
+	// Load the certificates of the CAs signing the CRLs
+	var err error
+	if peer.ecaCert, _, err = peer.ks.loadCertX509AndDer(peer.conf.getECACertsChainFilename()); err != nil {
+		peer.Errorf("Failed loading ECA certificate [%s].", err.Error())
+
+		return err
+	}
+	if peer.tcaCert, _, err = peer.ks.loadCertX509AndDer(peer.conf.getTCACertsChainFilename()); err != nil {
+		peer.Errorf("Failed loading TCA certificate [%s].", err.Error())
+
+		return err
+	}
+
+	// Start with the CRLs cached locally, if any, then fetch the latest ones
+	for alias, issuer := range map[string]*x509.Certificate{
+		peer.conf.getECACRLFilename(): peer.ecaCert,
+		peer.conf.getTCACRLFilename(): peer.tcaCert,
+	} {
+		if raw, err := peer.ks.loadCRL(alias); err == nil {
+			if err = peer.updateCRL(alias, issuer, raw, false); err != nil {
+				peer.Warningf("Discarding cached crl [%s]: [%s]", alias, err)
+			}
+		}
+	}
+	peer.refreshCRLs()
+
+	peer.crlsDone = make(chan struct{})
+	go peer.refreshCRLsPeriodically(peer.conf.getCRLRefreshInterval(), peer.crlsDone)
+
+	return nil
+}
+
+func (peer *peerImpl) refreshCRLsPeriodically(interval time.Duration, done chan struct{}) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			peer.refreshCRLs()
+		case <-done:
+			return
+		}
+	}
+}
+
+// refreshCRLs fetches the latest CRLs from the ECA and the TCA. Failures are
+// tolerated: the CRLs fetched last remain in use.
+func (peer *peerImpl) refreshCRLs() {
+	peer.Debug("Refreshing CRLs...")
+
+	if crl, err := peer.callECAReadCRL(context.Background()); err != nil {
+		peer.Warningf("Failed fetching ECA crl, using cached one [%s].", err)
+	} else if err = peer.updateCRL(peer.conf.getECACRLFilename(), peer.ecaCert, crl.Crl, true); err != nil {
+		peer.Errorf("Failed updating ECA crl [%s].", err)
+	}
+
+	if crl, err := peer.callTCAReadCRL(context.Background()); err != nil {
+		peer.Warningf("Failed fetching TCA crl, using cached one [%s].", err)
+	} else if err = peer.updateCRL(peer.conf.getTCACRLFilename(), peer.tcaCert, crl.Crl, true); err != nil {
+		peer.Errorf("Failed updating TCA crl [%s].", err)
+	}
+
+	peer.Debug("Refreshing CRLs...done")
+}
+
+// updateCRL verifies the CRL raw against issuer and, if valid, makes it the
+// CRL in use for alias.
+func (peer *peerImpl) updateCRL(alias string, issuer *x509.Certificate, raw []byte, store bool) error {
+	crl, err := x509.ParseCRL(raw)
+	if err != nil {
+		return err
+	}
+	if err = issuer.CheckCRLSignature(crl); err != nil {
+		return err
+	}
+
+	list := &revocationList{
+		issuer:     issuer,
+		nextUpdate: crl.TBSCertList.NextUpdate,
+		revoked:    make(map[string]bool),
+	}
+	for _, revoked := range crl.TBSCertList.RevokedCertificates {
+		list.revoked[revoked.SerialNumber.String()] = true
+	}
+
+	peer.crlsMutex.Lock()
+	peer.crls[alias] = list
+	peer.crlsMutex.Unlock()
+
+	if list.nextUpdate.Before(time.Now()) {
+		peer.Warningf("CRL [%s] expired at [%s].", alias, list.nextUpdate)
+	}
+
+	if store {
+		return peer.ks.storeCRL(alias, raw)
+	}
+	return nil
+}
+
+// isRevoked returns true if cert is listed in the CRL of its issuer.
+func (peer *peerImpl) isRevoked(cert *x509.Certificate) bool {
+	peer.crlsMutex.RLock()
+	defer peer.crlsMutex.RUnlock()
+
+	for _, crl := range peer.crls {
+		if crl.contains(cert) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (peer *peerImpl) closeCRLs() {
+	if peer.crlsDone != nil {
+		close(peer.crlsDone)
+		peer.crlsDone = nil
+	}
+}
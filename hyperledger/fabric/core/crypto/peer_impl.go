@@ -20,6 +20,7 @@ import (
 	"crypto/ecdsa"
 	"crypto/x509"
 	"fmt"
+	"strings"
 	"sync"
 
 	"github.com/golang/protobuf/proto"
@@ -34,6 +35,12 @@ type peerImpl struct {
 	nodeEnrollmentCertificatesMutex sync.RWMutex
 	nodeEnrollmentCertificates      map[string]*x509.Certificate
 
+	ecaCert   *x509.Certificate
+	tcaCert   *x509.Certificate
+	crlsMutex sync.RWMutex
+	crls      map[string]*revocationList
+	crlsDone  chan struct{}
+
 	isInitialized bool
 }
 
@@ -61,47 +68,65 @@ func (peer *peerImpl) TransactionPreValidation(tx *obc.Transaction) (*obc.Transa
 	peer.Debugf("Tx confdential level [%s].", tx.ConfidentialityLevel.String())
 
 	if tx.Cert != nil && tx.Signature != nil {
-		// Verify the transaction
-		// 1. Unmarshal cert
+		// Verify that cert has not been revoked
 		cert, err := primitives.DERToX509Certificate(tx.Cert)
 		if err != nil {
 			peer.Errorf("TransactionPreExecution: failed unmarshalling cert [%s].", err.Error())
 			return tx, err
 		}
+		if peer.isRevoked(cert) {
+			peer.Errorf("TransactionPreExecution: certificate [%s] has been revoked.", cert.SerialNumber)
+			return tx, utils.ErrRevokedCertificate
+		}
+	}
 
-		// TODO: verify cert
+	return tx, peer.VerifyTransactionSignature(tx)
+}
 
-		// 3. Marshall tx without signature
-		signature := tx.Signature
-		tx.Signature = nil
-		rawTx, err := proto.Marshal(tx)
-		if err != nil {
-			peer.Errorf("TransactionPreExecution: failed marshaling tx [%s].", err.Error())
-			return tx, err
-		}
-		tx.Signature = signature
+// VerifyTransactionSignature verifies that the transaction is signed
+// with the key of its certificate. Unlike TransactionPreValidation, it does
+// not check whether the certificate has been revoked, which depends on the
+// CRLs last fetched by this peer, so that every peer gets the same outcome.
+func (peer *peerImpl) VerifyTransactionSignature(tx *obc.Transaction) error {
+	if !peer.isInitialized {
+		return utils.ErrNotInitialized
+	}
 
-		// 2. Verify signature
-		ok, err := peer.verify(cert.PublicKey, rawTx, tx.Signature)
-		if err != nil {
-			peer.Errorf("TransactionPreExecution: failed marshaling tx [%s].", err.Error())
-			return tx, err
-		}
+	if tx.Cert == nil {
+		return utils.ErrTransactionCertificate
+	}
+	if tx.Signature == nil {
+		return utils.ErrTransactionSignature
+	}
 
-		if !ok {
-			return tx, utils.ErrInvalidTransactionSignature
-		}
-	} else {
-		if tx.Cert == nil {
-			return tx, utils.ErrTransactionCertificate
-		}
+	// 1. Unmarshal cert
+	cert, err := primitives.DERToX509Certificate(tx.Cert)
+	if err != nil {
+		peer.Errorf("VerifyTransactionSignature: failed unmarshalling cert [%s].", err.Error())
+		return err
+	}
 
-		if tx.Signature == nil {
-			return tx, utils.ErrTransactionSignature
-		}
+	// 2. Marshall tx without signature
+	signature := tx.Signature
+	tx.Signature = nil
+	rawTx, err := proto.Marshal(tx)
+	tx.Signature = signature
+	if err != nil {
+		peer.Errorf("VerifyTransactionSignature: failed marshaling tx [%s].", err.Error())
+		return err
+	}
+
+	// 3. Verify signature
+	ok, err := peer.verify(cert.PublicKey, rawTx, tx.Signature)
+	if err != nil {
+		peer.Errorf("VerifyTransactionSignature: failed verifying signature [%s].", err.Error())
+		return err
+	}
+	if !ok {
+		return utils.ErrInvalidTransactionSignature
 	}
 
-	return tx, nil
+	return nil
 }
 
 // TransactionPreValidation verifies that the transaction is
@@ -139,6 +164,12 @@ func (peer *peerImpl) Verify(vkID, signature, message []byte) error {
 		return err
 	}
 
+	if peer.isRevoked(cert) {
+		peer.Errorf("Failed enrollment certificate for [% x] has been revoked", vkID)
+
+		return utils.ErrRevokedCertificate
+	}
+
 	vk := cert.PublicKey.(*ecdsa.PublicKey)
 
 	ok, err := peer.verify(vk, message, signature)
@@ -165,6 +196,45 @@ func (peer *peerImpl) GetTransactionBinding(tx *obc.Transaction) ([]byte, error)
 	return primitives.Hash(append(tx.Cert, tx.Nonce...)), nil
 }
 
+// IsAdmin returns true if cert is a valid enrollment certificate of one of the
+// administrators listed in 'security.admins'. The common name of enrollment
+// certificates starts with the enrollment ID, followed by the affiliation.
+func (peer *peerImpl) IsAdmin(cert []byte) bool {
+	x509Cert, err := primitives.DERToX509Certificate(cert)
+	if err != nil {
+		peer.Debugf("Failed parsing certificate [%s].", err)
+
+		return false
+	}
+
+	if _, err = primitives.GetCriticalExtension(x509Cert, ECertSubjectRole); err != nil {
+		peer.Debugf("Certificate is not an enrollment certificate [%s].", err)
+
+		return false
+	}
+
+	if _, err = primitives.CheckCertAgainRoot(x509Cert, peer.ecaCertPool); err != nil {
+		peer.Debugf("Certificate is not an enrollment certificate [%s].", err)
+
+		return false
+	}
+
+	if peer.isRevoked(x509Cert) {
+		peer.Debugf("Enrollment certificate [%s] has been revoked.", x509Cert.SerialNumber)
+
+		return false
+	}
+
+	enrollID := strings.Split(x509Cert.Subject.CommonName, "\\")[0]
+	for _, admin := range peer.conf.getAdmins() {
+		if enrollID == admin {
+			return true
+		}
+	}
+
+	return false
+}
+
 // Private methods
 
 func (peer *peerImpl) register(eType NodeType, name string, pwd []byte, enrollID, enrollPWD string) error {
@@ -207,6 +277,13 @@ func (peer *peerImpl) init(eType NodeType, id string, pwd []byte) error {
 	}
 	peer.Debug("Init keystore...done.")
 
+	// CRLs
+	if err := peer.initCRLs(); err != nil {
+		peer.Errorf("Failed initiliazing CRLs [%s].", err)
+
+		return err
+	}
+
 	// initialized
 	peer.isInitialized = true
 
@@ -217,5 +294,7 @@ func (peer *peerImpl) init(eType NodeType, id string, pwd []byte) error {
 }
 
 func (peer *peerImpl) close() error {
+	peer.closeCRLs()
+
 	return peer.nodeImpl.close()
 }
@@ -119,8 +119,10 @@ func CBCDecrypt(key, src []byte) ([]byte, error) {
 
 	mode := cipher.NewCBCDecrypter(block, iv)
 
-	// CryptBlocks can work in-place if the two arguments are the same.
-	mode.CryptBlocks(src, src)
+	// Decrypt into a fresh buffer: src may alias data that must not change,
+	// such as an extension of a parsed certificate.
+	dst := make([]byte, len(src))
+	mode.CryptBlocks(dst, src)
 
 	// If the original plaintext lengths are not a multiple of the block
 	// size, padding would have to be added when encrypting, which would be
@@ -130,7 +132,7 @@ func CBCDecrypt(key, src []byte) ([]byte, error) {
 	// using crypto/hmac) before being decrypted in order to avoid creating
 	// a padding oracle.
 
-	return src, nil
+	return dst, nil
 }
 
 // CBCPKCS7Encrypt combines CBC encryption and PKCS7 padding
@@ -84,6 +84,9 @@ var (
 
 	// ErrInvalidProtocolVersion Invalid protocol version
 	ErrInvalidProtocolVersion = errors.New("Invalid protocol version")
+
+	// ErrRevokedCertificate Certificate has been revoked
+	ErrRevokedCertificate = errors.New("Certificate has been revoked.")
 )
 
 // ErrToString converts and error to a string. If the error is nil, it returns the string "<clean>"
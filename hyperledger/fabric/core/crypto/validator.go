@@ -129,7 +129,7 @@ func CloseAllValidators() (bool, []error) {
 // Private Methods
 
 func newValidator() *validatorImpl {
-	return &validatorImpl{&peerImpl{&nodeImpl{}, sync.RWMutex{}, nil, false}, false, nil}
+	return &validatorImpl{&peerImpl{nodeImpl: &nodeImpl{}}, false, nil}
 }
 
 func closeValidatorInternal(peer Peer, force bool) error {
@@ -48,6 +48,17 @@ func (validator *validatorImpl) TransactionPreValidation(tx *obc.Transaction) (*
 	return validator.peerImpl.TransactionPreValidation(tx)
 }
 
+// VerifyTransactionSignature verifies that the transaction is signed with the
+// key of its certificate, without checking whether the certificate has been
+// revoked
+func (validator *validatorImpl) VerifyTransactionSignature(tx *obc.Transaction) error {
+	if !validator.isInitialized {
+		return utils.ErrNotInitialized
+	}
+
+	return validator.peerImpl.VerifyTransactionSignature(tx)
+}
+
 // TransactionPreValidation verifies that the transaction is
 // well formed with the respect to the security layer
 // prescriptions (i.e. signature verification). If this is the case,
@@ -21,11 +21,13 @@ import (
 	"io"
 	"os"
 	"path"
+	"path/filepath"
+	"regexp"
 	"strings"
+	"sync"
 
 	"github.com/op/go-logging"
 	"github.com/spf13/viper"
-	"github.com/tecbot/gorocksdb"
 )
 
 var dbLogger = logging.MustGetLogger("db")
@@ -35,6 +37,8 @@ const stateCF = "stateCF"
 const stateDeltaCF = "stateDeltaCF"
 const indexesCF = "indexesCF"
 const persistCF = "persistCF"
+const historyCF = "historyCF"
+const queryIndexCF = "queryIndexCF"
 
 var columnfamilies = []string{
 	blockchainCF, // blocks of the block chain
@@ -42,24 +46,58 @@ var columnfamilies = []string{
 	stateDeltaCF, // open transaction state
 	indexesCF,    // tx uuid -> blockno
 	persistCF,    // persistent per-peer state (consensus)
+	historyCF,    // (chaincodeID, key, blockno, tx) -> key modification
+	queryIndexCF, // (chaincodeID, field, value, key) -> nil
 }
 
-// OpenchainDB encapsulates rocksdb's structures
+// OpenchainDB encapsulates the column families of the DB, stored by the
+// engine selected by 'peer.db.engine'
 type OpenchainDB struct {
-	DB           *gorocksdb.DB
-	BlockchainCF *gorocksdb.ColumnFamilyHandle
-	StateCF      *gorocksdb.ColumnFamilyHandle
-	StateDeltaCF *gorocksdb.ColumnFamilyHandle
-	IndexesCF    *gorocksdb.ColumnFamilyHandle
-	PersistCF    *gorocksdb.ColumnFamilyHandle
+	chainID      string
+	unshared     bool // opened by OpenDB, outside the DBs of the chains
+	path         string
+	engine       engine
+	engineName   string
+	defaultCF    ColumnFamily
+	BlockchainCF ColumnFamily
+	StateCF      ColumnFamily
+	StateDeltaCF ColumnFamily
+	IndexesCF    ColumnFamily
+	PersistCF    ColumnFamily
+	HistoryCF    ColumnFamily
+	QueryIndexCF ColumnFamily
 }
 
 var openchainDB *OpenchainDB
 var isOpen bool
 
-// CreateDB creates a rocks db database
+// The DBs of the chains other than the default chain, by chain ID
+var (
+	chainDBsLock sync.Mutex
+	chainDBs     = make(map[string]*OpenchainDB)
+)
+
+var chainIDPattern = regexp.MustCompile(`^[a-zA-Z0-9][a-zA-Z0-9_.-]{0,63}$`)
+
+// ValidateChainID returns an error if the chain ID cannot name a chain. The
+// empty chain ID names the default chain.
+func ValidateChainID(chainID string) error {
+	if chainID != "" && !chainIDPattern.MatchString(chainID) {
+		return fmt.Errorf("Invalid chain ID [%s], it must be at most 64 letters, digits, '_', '.' or '-' starting with a letter or digit", chainID)
+	}
+	return nil
+}
+
+// CreateDB creates a database with the engine selected by 'peer.db.engine'
 func CreateDB() error {
-	dbPath := getDBPath()
+	return createDB(getDBPath())
+}
+
+func createDB(dbPath string) error {
+	factory, err := getEngineFactory()
+	if err != nil {
+		return err
+	}
 	dbLogger.Debugf("Creating DB at [%s]", dbPath)
 	missing, err := dirMissingOrEmpty(dbPath)
 	if err != nil {
@@ -74,17 +112,11 @@ func CreateDB() error {
 		dbLogger.Errorf("Error calling  os.MkdirAll for directory path [%s]: %s", dbPath, err)
 		return fmt.Errorf("Error making directory path [%s]: %s", dbPath, err)
 	}
-	opts := gorocksdb.NewDefaultOptions()
-	defer opts.Destroy()
-	opts.SetCreateIfMissing(true)
-
-	db, err := gorocksdb.OpenDb(opts, dbPath)
+	err = factory.create(dbPath)
 	if err != nil {
 		return err
 	}
 
-	defer db.Close()
-
 	dbLogger.Debugf("DB created at [%s]", dbPath)
 	return nil
 }
@@ -108,13 +140,76 @@ func GetDBHandle() *OpenchainDB {
 	return openchainDB
 }
 
+// GetChainDBHandle returns a handle to the DB of the chain, which is the
+// OpenchainDB for the default chain and a DB under 'peer.fileSystemPath'/chains
+// for the other chains
+func GetChainDBHandle(chainID string) *OpenchainDB {
+	if chainID == "" {
+		return GetDBHandle()
+	}
+	chainDBsLock.Lock()
+	defer chainDBsLock.Unlock()
+	if chainDB, ok := chainDBs[chainID]; ok {
+		return chainDB
+	}
+	if err := ValidateChainID(chainID); err != nil {
+		panic(err.Error())
+	}
+
+	dbPath := getChainDBPath(chainID)
+	missing, err := dirMissingOrEmpty(dbPath)
+	if err == nil && missing {
+		err = createDB(dbPath)
+	}
+	if err != nil {
+		panic(fmt.Sprintf("Error while trying to create DB of chain %s: %s", chainID, err))
+	}
+	chainDB, err := openDBAt(dbPath)
+	if err != nil {
+		panic(fmt.Sprintf("Could not open db of chain %s error = [%s]", chainID, err))
+	}
+	chainDB.chainID = chainID
+	chainDBs[chainID] = chainDB
+	return chainDB
+}
+
+// OpenDB opens the DB at dbPath, creating it if the path is empty, with the
+// engine selected by 'peer.db.engine'. The DB is owned by the caller, it is
+// neither the DB of the default chain nor of any other chain, which lets a
+// process run several peers each on a DB of its own.
+func OpenDB(dbPath string) (*OpenchainDB, error) {
+	missing, err := dirMissingOrEmpty(dbPath)
+	if err == nil && missing {
+		err = createDB(dbPath)
+	}
+	if err != nil {
+		return nil, err
+	}
+	db, err := openDBAt(dbPath)
+	if err != nil {
+		return nil, err
+	}
+	db.unshared = true
+	return db, nil
+}
+
+// closeChainDBs closes the DBs of all the chains but the default chain
+func closeChainDBs() {
+	chainDBsLock.Lock()
+	defer chainDBsLock.Unlock()
+	for chainID, chainDB := range chainDBs {
+		chainDB.engine.close()
+		delete(chainDBs, chainID)
+	}
+}
+
 // GetFromBlockchainCF get value for given key from column family - blockchainCF
 func (openchainDB *OpenchainDB) GetFromBlockchainCF(key []byte) ([]byte, error) {
 	return openchainDB.Get(openchainDB.BlockchainCF, key)
 }
 
 // GetFromBlockchainCFSnapshot get value for given key from column family in a DB snapshot - blockchainCF
-func (openchainDB *OpenchainDB) GetFromBlockchainCFSnapshot(snapshot *gorocksdb.Snapshot, key []byte) ([]byte, error) {
+func (openchainDB *OpenchainDB) GetFromBlockchainCFSnapshot(snapshot Snapshot, key []byte) ([]byte, error) {
 	return openchainDB.getFromSnapshot(snapshot, openchainDB.BlockchainCF, key)
 }
 
@@ -133,32 +228,81 @@ func (openchainDB *OpenchainDB) GetFromIndexesCF(key []byte) ([]byte, error) {
 	return openchainDB.Get(openchainDB.IndexesCF, key)
 }
 
+// GetHistoryCFIterator get iterator for column family - historyCF
+func (openchainDB *OpenchainDB) GetHistoryCFIterator() Iterator {
+	return openchainDB.GetIterator(openchainDB.HistoryCF)
+}
+
+// GetHistoryCFSnapshotIterator get iterator for column family - historyCF. This iterator
+// is based on a snapshot. Remember to call iterator.Close() when you are done.
+func (openchainDB *OpenchainDB) GetHistoryCFSnapshotIterator(snapshot Snapshot) Iterator {
+	return openchainDB.getSnapshotIterator(snapshot, openchainDB.HistoryCF)
+}
+
+// GetFromQueryIndexCF get value for given key from column family - queryIndexCF
+func (openchainDB *OpenchainDB) GetFromQueryIndexCF(key []byte) ([]byte, error) {
+	return openchainDB.Get(openchainDB.QueryIndexCF, key)
+}
+
+// GetQueryIndexCFIterator get iterator for column family - queryIndexCF
+func (openchainDB *OpenchainDB) GetQueryIndexCFIterator() Iterator {
+	return openchainDB.GetIterator(openchainDB.QueryIndexCF)
+}
+
 // GetBlockchainCFIterator get iterator for column family - blockchainCF
-func (openchainDB *OpenchainDB) GetBlockchainCFIterator() *gorocksdb.Iterator {
+func (openchainDB *OpenchainDB) GetBlockchainCFIterator() Iterator {
 	return openchainDB.GetIterator(openchainDB.BlockchainCF)
 }
 
 // GetStateCFIterator get iterator for column family - stateCF
-func (openchainDB *OpenchainDB) GetStateCFIterator() *gorocksdb.Iterator {
+func (openchainDB *OpenchainDB) GetStateCFIterator() Iterator {
 	return openchainDB.GetIterator(openchainDB.StateCF)
 }
 
 // GetStateCFSnapshotIterator get iterator for column family - stateCF. This iterator
 // is based on a snapshot and should be used for long running scans, such as
 // reading the entire state. Remember to call iterator.Close() when you are done.
-func (openchainDB *OpenchainDB) GetStateCFSnapshotIterator(snapshot *gorocksdb.Snapshot) *gorocksdb.Iterator {
+func (openchainDB *OpenchainDB) GetStateCFSnapshotIterator(snapshot Snapshot) Iterator {
 	return openchainDB.getSnapshotIterator(snapshot, openchainDB.StateCF)
 }
 
 // GetStateDeltaCFIterator get iterator for column family - stateDeltaCF
-func (openchainDB *OpenchainDB) GetStateDeltaCFIterator() *gorocksdb.Iterator {
+func (openchainDB *OpenchainDB) GetStateDeltaCFIterator() Iterator {
 	return openchainDB.GetIterator(openchainDB.StateDeltaCF)
 }
 
 // GetSnapshot returns a point-in-time view of the DB. You MUST call snapshot.Release()
 // when you are done with the snapshot.
-func (openchainDB *OpenchainDB) GetSnapshot() *gorocksdb.Snapshot {
-	return openchainDB.DB.NewSnapshot()
+func (openchainDB *OpenchainDB) GetSnapshot() Snapshot {
+	return openchainDB.engine.newSnapshot()
+}
+
+// NewWriteBatch returns a new batch of changes, to be applied by Write. You
+// MUST call writeBatch.Destroy() when you are done with the batch.
+func (openchainDB *OpenchainDB) NewWriteBatch() WriteBatch {
+	return openchainDB.engine.newWriteBatch()
+}
+
+// Write atomically applies the changes of the batch to the DB
+func (openchainDB *OpenchainDB) Write(writeBatch WriteBatch) error {
+	return openchainDB.engine.write(writeBatch)
+}
+
+// Engine returns the name of the storage engine of the DB
+func (openchainDB *OpenchainDB) Engine() string {
+	return openchainDB.engineName
+}
+
+// GetProperty returns the value of a property of the storage engine for the
+// DB, or an empty string if the engine does not support the property
+func (openchainDB *OpenchainDB) GetProperty(property string) string {
+	return openchainDB.engine.getProperty(property, nil)
+}
+
+// GetPropertyCF returns the value of a property of the storage engine for the
+// column family, or an empty string if the engine does not support the property
+func (openchainDB *OpenchainDB) GetPropertyCF(property string, cf ColumnFamily) string {
+	return openchainDB.engine.getProperty(property, cf)
 }
 
 func getDBPath() string {
@@ -172,6 +316,10 @@ func getDBPath() string {
 	return dbPath + "db"
 }
 
+func getChainDBPath(chainID string) string {
+	return filepath.Join(path.Dir(getDBPath()), "chains", chainID, "db")
+}
+
 func createDBIfDBPathEmpty() error {
 	dbPath := getDBPath()
 	missing, err := dirMissingOrEmpty(dbPath)
@@ -192,94 +340,94 @@ func openDB() (*OpenchainDB, error) {
 	if isOpen {
 		return openchainDB, nil
 	}
+	db, err := openDBAt(getDBPath())
+	if err != nil {
+		return nil, err
+	}
+	isOpen = true
+	return db, nil
+}
 
-	dbPath := getDBPath()
-	opts := gorocksdb.NewDefaultOptions()
-	defer opts.Destroy()
-
-	opts.SetCreateIfMissing(false)
-	opts.SetCreateIfMissingColumnFamilies(true)
-
+func openDBAt(dbPath string) (*OpenchainDB, error) {
+	factory, err := getEngineFactory()
+	if err != nil {
+		return nil, err
+	}
 	cfNames := []string{"default"}
 	cfNames = append(cfNames, columnfamilies...)
-	var cfOpts []*gorocksdb.Options
-	for range cfNames {
-		cfOpts = append(cfOpts, opts)
-	}
 
-	db, cfHandlers, err := gorocksdb.OpenDbColumnFamilies(opts, dbPath, cfNames, cfOpts)
+	engine, cfs, err := factory.open(dbPath, cfNames)
 
 	if err != nil {
 		fmt.Println("Error opening DB", err)
 		return nil, err
 	}
-	isOpen = true
-	// XXX should we close cfHandlers[0]?
-	return &OpenchainDB{db, cfHandlers[1], cfHandlers[2], cfHandlers[3], cfHandlers[4], cfHandlers[5]}, nil
+	return &OpenchainDB{"", false, dbPath, engine, getEngineName(), cfs[0], cfs[1], cfs[2], cfs[3], cfs[4], cfs[5], cfs[6], cfs[7]}, nil
 }
 
-// CloseDB releases all column family handles and closes rocksdb
+// CloseDB releases all column family handles and closes the DB
 func (openchainDB *OpenchainDB) CloseDB() {
-	openchainDB.BlockchainCF.Destroy()
-	openchainDB.StateCF.Destroy()
-	openchainDB.StateDeltaCF.Destroy()
-	openchainDB.IndexesCF.Destroy()
-	openchainDB.PersistCF.Destroy()
-	openchainDB.DB.Close()
-	isOpen = false
-}
-
-// DeleteState delets ALL state keys/values from the DB. This is generally
-// only used during state synchronization when creating a new state from
-// a snapshot.
-func (openchainDB *OpenchainDB) DeleteState() error {
-	err := openchainDB.DB.DropColumnFamily(openchainDB.StateCF)
-	if err != nil {
-		dbLogger.Errorf("Error dropping state CF: %s", err)
-		return err
+	openchainDB.engine.close()
+	if openchainDB.unshared {
+		return
+	}
+	if openchainDB.chainID == "" {
+		isOpen = false
+		return
+	}
+	chainDBsLock.Lock()
+	if chainDBs[openchainDB.chainID] == openchainDB {
+		delete(chainDBs, openchainDB.chainID)
 	}
-	err = openchainDB.DB.DropColumnFamily(openchainDB.StateDeltaCF)
+	chainDBsLock.Unlock()
+}
+
+// ChainID returns the ID of the chain of the DB, empty for the default chain
+func (openchainDB *OpenchainDB) ChainID() string {
+	return openchainDB.chainID
+}
+
+// Path returns the directory of the DB
+func (openchainDB *OpenchainDB) Path() string {
+	return openchainDB.path
+}
+
+// DeleteState delets ALL state keys/values from the DB, along with the query
+// indexes of the state. This is generally only used during state
+// synchronization when creating a new state from a snapshot.
+func (openchainDB *OpenchainDB) DeleteState() error {
+	var err error
+	openchainDB.StateCF, err = openchainDB.engine.clearColumnFamily(openchainDB.StateCF)
 	if err != nil {
-		dbLogger.Errorf("Error dropping state delta CF: %s", err)
+		dbLogger.Errorf("Error clearing state CF: %s", err)
 		return err
 	}
-	opts := gorocksdb.NewDefaultOptions()
-	defer opts.Destroy()
-	openchainDB.StateCF, err = openchainDB.DB.CreateColumnFamily(opts, stateCF)
+	openchainDB.StateDeltaCF, err = openchainDB.engine.clearColumnFamily(openchainDB.StateDeltaCF)
 	if err != nil {
-		dbLogger.Errorf("Error creating state CF: %s", err)
+		dbLogger.Errorf("Error clearing state delta CF: %s", err)
 		return err
 	}
-	openchainDB.StateDeltaCF, err = openchainDB.DB.CreateColumnFamily(opts, stateDeltaCF)
+	openchainDB.QueryIndexCF, err = openchainDB.engine.clearColumnFamily(openchainDB.QueryIndexCF)
 	if err != nil {
-		dbLogger.Errorf("Error creating state delta CF: %s", err)
+		dbLogger.Errorf("Error clearing query index CF: %s", err)
 		return err
 	}
 	return nil
 }
 
 // Get returns the valud for the given column family and key
-func (openchainDB *OpenchainDB) Get(cfHandler *gorocksdb.ColumnFamilyHandle, key []byte) ([]byte, error) {
-	opt := gorocksdb.NewDefaultReadOptions()
-	defer opt.Destroy()
-	slice, err := openchainDB.DB.GetCF(opt, cfHandler, key)
+func (openchainDB *OpenchainDB) Get(cf ColumnFamily, key []byte) ([]byte, error) {
+	data, err := openchainDB.engine.get(nil, cf, key)
 	if err != nil {
 		fmt.Println("Error while trying to retrieve key:", key)
 		return nil, err
 	}
-	defer slice.Free()
-	if slice.Data() == nil {
-		return nil, nil
-	}
-	data := makeCopy(slice.Data())
 	return data, nil
 }
 
 // Put saves the key/value in the given column family
-func (openchainDB *OpenchainDB) Put(cfHandler *gorocksdb.ColumnFamilyHandle, key []byte, value []byte) error {
-	opt := gorocksdb.NewDefaultWriteOptions()
-	defer opt.Destroy()
-	err := openchainDB.DB.PutCF(opt, cfHandler, key, value)
+func (openchainDB *OpenchainDB) Put(cf ColumnFamily, key []byte, value []byte) error {
+	err := openchainDB.engine.put(cf, key, value)
 	if err != nil {
 		fmt.Println("Error while trying to write key:", key)
 		return err
@@ -288,10 +436,8 @@ func (openchainDB *OpenchainDB) Put(cfHandler *gorocksdb.ColumnFamilyHandle, key
 }
 
 // Delete delets the given key in the specified column family
-func (openchainDB *OpenchainDB) Delete(cfHandler *gorocksdb.ColumnFamilyHandle, key []byte) error {
-	opt := gorocksdb.NewDefaultWriteOptions()
-	defer opt.Destroy()
-	err := openchainDB.DB.DeleteCF(opt, cfHandler, key)
+func (openchainDB *OpenchainDB) Delete(cf ColumnFamily, key []byte) error {
+	err := openchainDB.engine.delete(cf, key)
 	if err != nil {
 		fmt.Println("Error while trying to delete key:", key)
 		return err
@@ -299,34 +445,22 @@ func (openchainDB *OpenchainDB) Delete(cfHandler *gorocksdb.ColumnFamilyHandle,
 	return nil
 }
 
-func (openchainDB *OpenchainDB) getFromSnapshot(snapshot *gorocksdb.Snapshot, cfHandler *gorocksdb.ColumnFamilyHandle, key []byte) ([]byte, error) {
-	opt := gorocksdb.NewDefaultReadOptions()
-	defer opt.Destroy()
-	opt.SetSnapshot(snapshot)
-	slice, err := openchainDB.DB.GetCF(opt, cfHandler, key)
+func (openchainDB *OpenchainDB) getFromSnapshot(snapshot Snapshot, cf ColumnFamily, key []byte) ([]byte, error) {
+	data, err := openchainDB.engine.get(snapshot, cf, key)
 	if err != nil {
 		fmt.Println("Error while trying to retrieve key:", key)
 		return nil, err
 	}
-	defer slice.Free()
-	data := append([]byte(nil), slice.Data()...)
 	return data, nil
 }
 
 // GetIterator returns an iterator for the given column family
-func (openchainDB *OpenchainDB) GetIterator(cfHandler *gorocksdb.ColumnFamilyHandle) *gorocksdb.Iterator {
-	opt := gorocksdb.NewDefaultReadOptions()
-	opt.SetFillCache(true)
-	defer opt.Destroy()
-	return openchainDB.DB.NewIteratorCF(opt, cfHandler)
-}
-
-func (openchainDB *OpenchainDB) getSnapshotIterator(snapshot *gorocksdb.Snapshot, cfHandler *gorocksdb.ColumnFamilyHandle) *gorocksdb.Iterator {
-	opt := gorocksdb.NewDefaultReadOptions()
-	defer opt.Destroy()
-	opt.SetSnapshot(snapshot)
-	iter := openchainDB.DB.NewIteratorCF(opt, cfHandler)
-	return iter
+func (openchainDB *OpenchainDB) GetIterator(cf ColumnFamily) Iterator {
+	return openchainDB.engine.newIterator(nil, cf)
+}
+
+func (openchainDB *OpenchainDB) getSnapshotIterator(snapshot Snapshot, cf ColumnFamily) Iterator {
+	return openchainDB.engine.newIterator(snapshot, cf)
 }
 
 func dirMissingOrEmpty(path string) (bool, error) {
@@ -20,15 +20,15 @@ import (
 	"bytes"
 	"io/ioutil"
 	"os"
+	"strings"
 	"testing"
 
 	"github.com/spf13/viper"
-	"github.com/tecbot/gorocksdb"
 )
 
 func TestMain(m *testing.M) {
 	setupTestConfig()
-	os.Exit(m.Run())
+	os.Exit(RunWithEngines(m))
 }
 
 func TestGetDBPathEmptyPath(t *testing.T) {
@@ -226,14 +226,158 @@ func TestDBIteratorAndSnapshotIterator(t *testing.T) {
 	testIterator(t, itr, map[string][]byte{"key6": []byte("value6"), "key7": []byte("value7")})
 }
 
-func testIterator(t *testing.T, itr *gorocksdb.Iterator, expectedValues map[string][]byte) {
+func TestDBIteratorSeek(t *testing.T) {
+	testDBWrapper := NewTestDBWrapper()
+	testDBWrapper.CreateFreshDB(t)
+	openchainDB := GetDBHandle()
+	defer testDBWrapper.cleanup()
+
+	openchainDB.Put(openchainDB.StateCF, []byte("a1"), []byte("value1"))
+	openchainDB.Put(openchainDB.StateCF, []byte("a2"), []byte("value2"))
+	openchainDB.Put(openchainDB.StateCF, []byte("b1"), []byte("value3"))
+	openchainDB.Put(openchainDB.StateDeltaCF, []byte("a3"), []byte("value4"))
+
+	itr := openchainDB.GetStateCFIterator()
+	defer itr.Close()
+	var keys []string
+	for itr.Seek([]byte("a")); itr.ValidForPrefix([]byte("a")); itr.Next() {
+		keys = append(keys, string(itr.Key()))
+	}
+	if len(keys) != 2 || keys[0] != "a1" || keys[1] != "a2" {
+		t.Fatalf("Expected keys [a1 a2] with prefix a, found %v", keys)
+	}
+	if !itr.Valid() || string(itr.Key()) != "b1" || string(itr.Value()) != "value3" {
+		t.Fatalf("Expected the iterator to be positioned at key b1")
+	}
+	itr.Prev()
+	if !itr.Valid() || string(itr.Key()) != "a2" {
+		t.Fatalf("Expected the iterator to be positioned at key a2")
+	}
+	itr.Seek([]byte("c"))
+	if itr.Valid() {
+		t.Fatalf("Expected the iterator to be exhausted, found key [%s]", itr.Key())
+	}
+	if err := itr.Err(); err != nil {
+		t.Fatalf("Error: %s", err)
+	}
+}
+
+func TestChainDBs(t *testing.T) {
+	createTestDB()
+	defer deleteTestDB()
+	defer closeChainDBs()
+
+	if GetChainDBHandle("") != GetDBHandle() {
+		t.Fatal("Expected the DB of the default chain for an empty chain ID")
+	}
+	chainDB := GetChainDBHandle("chain1")
+	if chainDB == GetDBHandle() || GetChainDBHandle("chain1") != chainDB || chainDB.ChainID() != "chain1" {
+		t.Fatal("Expected a single DB for chain1, apart from the DB of the default chain")
+	}
+
+	// The chains do not share keys
+	if err := chainDB.Put(chainDB.StateCF, []byte("key"), []byte("value")); err != nil {
+		t.Fatalf("Error writing to chain DB: %s", err)
+	}
+	if value, _ := GetDBHandle().GetFromStateCF([]byte("key")); value != nil {
+		t.Fatalf("Expected no value in the default chain but got %s", value)
+	}
+	if value, _ := GetChainDBHandle("chain2").GetFromStateCF([]byte("key")); value != nil {
+		t.Fatalf("Expected no value in chain2 but got %s", value)
+	}
+
+	// The DB of a chain is reopened with its content
+	chainDB.CloseDB()
+	if value, _ := GetChainDBHandle("chain1").GetFromStateCF([]byte("key")); !bytes.Equal(value, []byte("value")) {
+		t.Fatalf("Expected the value written to chain1 but got %s", value)
+	}
+}
+
+func TestOpenDB(t *testing.T) {
+	createTestDB()
+	defer deleteTestDB()
+	dir, err := ioutil.TempDir("", "opendb")
+	if err != nil {
+		t.Fatalf("Error creating temp dir: %s", err)
+	}
+	defer os.RemoveAll(dir)
+
+	ownDB, err := OpenDB(dir + "/db")
+	if err != nil {
+		t.Fatalf("Error opening DB: %s", err)
+	}
+	if err := ownDB.Put(ownDB.StateCF, []byte("key"), []byte("value")); err != nil {
+		t.Fatalf("Error writing to DB: %s", err)
+	}
+	if value, _ := GetDBHandle().GetFromStateCF([]byte("key")); value != nil {
+		t.Fatalf("Expected no value in the default chain but got %s", value)
+	}
+
+	// Closing it leaves the DB of the default chain open, and it is reopened
+	// with its content
+	ownDB.CloseDB()
+	if !isOpen {
+		t.Fatal("Expected the DB of the default chain to stay open")
+	}
+	if ownDB, err = OpenDB(dir + "/db"); err != nil {
+		t.Fatalf("Error reopening DB: %s", err)
+	}
+	defer ownDB.CloseDB()
+	if value, _ := ownDB.GetFromStateCF([]byte("key")); !bytes.Equal(value, []byte("value")) {
+		t.Fatalf("Expected the value written to the DB but got %s", value)
+	}
+}
+
+func TestValidateChainID(t *testing.T) {
+	for _, chainID := range []string{"", "chain1", "my-chain_2.0"} {
+		if err := ValidateChainID(chainID); err != nil {
+			t.Fatalf("Expected %s to be a valid chain ID: %s", chainID, err)
+		}
+	}
+	for _, chainID := range []string{"..", "a/b", "-chain", strings.Repeat("a", 65)} {
+		if err := ValidateChainID(chainID); err == nil {
+			t.Fatalf("Expected %s to be an invalid chain ID", chainID)
+		}
+	}
+}
+
+// TestEngines runs the tests of the DB against all the storage engines
+// compiled in
+func TestEngines(t *testing.T) {
+	defer viper.Set("peer.db.engine", viper.GetString("peer.db.engine"))
+	for name := range engines {
+		t.Logf("Testing engine [%s]", name)
+		viper.Set("peer.db.engine", name)
+		deleteTestDBPath()
+		TestWriteAndRead(t)
+		TestDBColumnUpgrade(t)
+		TestDeleteState(t)
+		TestDBSnapshot(t)
+		TestDBIteratorAndSnapshotIterator(t)
+		TestDBIteratorSeek(t)
+		if engine := GetDBHandle().Engine(); engine != name {
+			t.Fatalf("Expected engine [%s], found [%s]", name, engine)
+		}
+		deleteTestDB()
+	}
+}
+
+func TestUnknownEngine(t *testing.T) {
+	defer viper.Set("peer.db.engine", viper.GetString("peer.db.engine"))
+	deleteTestDBPath()
+	defer deleteTestDBPath()
+	viper.Set("peer.db.engine", "unknown")
+	if err := CreateDB(); err == nil {
+		t.Fatal("An error was expected creating a DB with an unknown engine")
+	}
+}
+
+func testIterator(t *testing.T, itr Iterator, expectedValues map[string][]byte) {
 	itrResults := make(map[string][]byte)
 	itr.SeekToFirst()
 	for ; itr.Valid(); itr.Next() {
-		key := itr.Key()
-		value := itr.Value()
-		k := makeCopy(key.Data())
-		v := makeCopy(value.Data())
+		k := makeCopy(itr.Key())
+		v := makeCopy(itr.Value())
 		itrResults[string(k)] = v
 	}
 	if len(itrResults) != len(expectedValues) {
@@ -282,15 +426,13 @@ func setupTestConfig() {
 
 func performBasicReadWrite(t *testing.T) {
 	openchainDB := GetDBHandle()
-	opt := gorocksdb.NewDefaultWriteOptions()
-	defer opt.Destroy()
-	writeBatch := gorocksdb.NewWriteBatch()
+	writeBatch := openchainDB.NewWriteBatch()
 	defer writeBatch.Destroy()
 	writeBatch.PutCF(openchainDB.BlockchainCF, []byte("dummyKey"), []byte("dummyValue"))
 	writeBatch.PutCF(openchainDB.StateCF, []byte("dummyKey1"), []byte("dummyValue1"))
 	writeBatch.PutCF(openchainDB.StateDeltaCF, []byte("dummyKey2"), []byte("dummyValue2"))
 	writeBatch.PutCF(openchainDB.IndexesCF, []byte("dummyKey3"), []byte("dummyValue3"))
-	err := openchainDB.DB.Write(opt, writeBatch)
+	err := openchainDB.Write(writeBatch)
 	if err != nil {
 		t.Fatalf("Error while writing to db: %s", err)
 	}
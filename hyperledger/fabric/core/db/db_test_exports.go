@@ -21,7 +21,6 @@ import (
 	"testing"
 
 	"github.com/spf13/viper"
-	"github.com/tecbot/gorocksdb"
 )
 
 // TestDBWrapper wraps the db. Can be used by other modules for testing
@@ -66,6 +65,7 @@ func (testDB *TestDBWrapper) CreateFreshDBGinkgo() error {
 func (testDB *TestDBWrapper) cleanup() {
 	if testDB.performCleanup {
 		GetDBHandle().CloseDB()
+		closeChainDBs()
 		testDB.performCleanup = false
 	}
 }
@@ -75,11 +75,25 @@ func (testDB *TestDBWrapper) removeDBPath() {
 	os.RemoveAll(dbPath)
 }
 
+// RunWithEngines runs the tests of a package once for each storage engine,
+// selected by 'peer.db.engine'. It returns the exit code of the first failed
+// run, to be passed to os.Exit from TestMain.
+func RunWithEngines(m *testing.M) int {
+	defer viper.Set("peer.db.engine", viper.GetString("peer.db.engine"))
+	for _, name := range Engines() {
+		dbLogger.Infof("Running tests with DB engine [%s]", name)
+		viper.Set("peer.db.engine", name)
+		if code := m.Run(); code != 0 {
+			dbLogger.Errorf("Tests failed with DB engine [%s]", name)
+			return code
+		}
+	}
+	return 0
+}
+
 // WriteToDB tests can use this method for persisting a given batch to db
-func (testDB *TestDBWrapper) WriteToDB(t testing.TB, writeBatch *gorocksdb.WriteBatch) {
-	opt := gorocksdb.NewDefaultWriteOptions()
-	defer opt.Destroy()
-	err := GetDBHandle().DB.Write(opt, writeBatch)
+func (testDB *TestDBWrapper) WriteToDB(t testing.TB, writeBatch WriteBatch) {
+	err := GetDBHandle().Write(writeBatch)
 	if err != nil {
 		t.Fatalf("Error while writing to db. Error:%s", err)
 	}
@@ -87,15 +101,11 @@ func (testDB *TestDBWrapper) WriteToDB(t testing.TB, writeBatch *gorocksdb.Write
 
 // GetFromDB gets the value for the given key from default column-family
 func (testDB *TestDBWrapper) GetFromDB(t testing.TB, key []byte) []byte {
-	db := GetDBHandle().DB
-	opt := gorocksdb.NewDefaultReadOptions()
-	defer opt.Destroy()
-	slice, err := db.Get(opt, key)
-	defer slice.Free()
+	openchainDB := GetDBHandle()
+	value, err := openchainDB.Get(openchainDB.defaultCF, key)
 	if err != nil {
 		t.Fatalf("Error while getting key-value from DB: %s", err)
 	}
-	value := append([]byte(nil), slice.Data()...)
 	return value
 }
 
@@ -125,19 +135,20 @@ func (testDB *TestDBWrapper) CloseDB(t testing.TB) {
 	openchainDB.CloseDB()
 }
 
-// GetEstimatedNumKeys returns estimated number of key-values in db. This is not accurate in all the cases
+// GetEstimatedNumKeys returns estimated number of key-values in db. This is not accurate in all the cases,
+// and only supported by the rocksdb engine
 func (testDB *TestDBWrapper) GetEstimatedNumKeys(t testing.TB) map[string]string {
 	openchainDB := GetDBHandle()
 	result := make(map[string]string, 5)
-	result["stateCF"] = openchainDB.DB.GetPropertyCF("rocksdb.estimate-num-keys", openchainDB.StateCF)
-	result["stateDeltaCF"] = openchainDB.DB.GetPropertyCF("rocksdb.estimate-num-keys", openchainDB.StateDeltaCF)
-	result["blockchainCF"] = openchainDB.DB.GetPropertyCF("rocksdb.estimate-num-keys", openchainDB.BlockchainCF)
-	result["indexCF"] = openchainDB.DB.GetPropertyCF("rocksdb.estimate-num-keys", openchainDB.IndexesCF)
+	result["stateCF"] = openchainDB.GetPropertyCF("rocksdb.estimate-num-keys", openchainDB.StateCF)
+	result["stateDeltaCF"] = openchainDB.GetPropertyCF("rocksdb.estimate-num-keys", openchainDB.StateDeltaCF)
+	result["blockchainCF"] = openchainDB.GetPropertyCF("rocksdb.estimate-num-keys", openchainDB.BlockchainCF)
+	result["indexCF"] = openchainDB.GetPropertyCF("rocksdb.estimate-num-keys", openchainDB.IndexesCF)
 	return result
 }
 
 // GetDBStats returns statistics for the database
 func (testDB *TestDBWrapper) GetDBStats() string {
 	openchainDB := GetDBHandle()
-	return openchainDB.DB.GetProperty("rocksdb.stats")
+	return openchainDB.GetProperty(openchainDB.Engine() + ".stats")
 }
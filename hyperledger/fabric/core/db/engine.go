@@ -0,0 +1,125 @@
+/*
+Copyright IBM Corp. 2016 All Rights Reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+		 http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package db
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/spf13/viper"
+)
+
+// ColumnFamily is a handle to a column family of the DB
+type ColumnFamily interface {
+	// Name returns the name of the column family
+	Name() string
+}
+
+// WriteBatch collects changes to the column families of the DB, that are
+// applied atomically by OpenchainDB.Write. Remember to call Destroy() when
+// you are done with the batch.
+type WriteBatch interface {
+	PutCF(cf ColumnFamily, key []byte, value []byte)
+	DeleteCF(cf ColumnFamily, key []byte)
+	Destroy()
+}
+
+// Iterator iterates over the keys of a column family in ascending order. The
+// slices returned by Key() and Value() are only valid until the iterator is
+// moved. Remember to call Close() when you are done with the iterator.
+type Iterator interface {
+	Seek(key []byte)
+	SeekToFirst()
+	Next()
+	Prev()
+	Valid() bool
+	ValidForPrefix(prefix []byte) bool
+	Key() []byte
+	Value() []byte
+	Err() error
+	Close()
+}
+
+// Snapshot is a point-in-time view of the DB. Remember to call Release() when
+// you are done with the snapshot.
+type Snapshot interface {
+	Release()
+}
+
+// engine is the storage engine under OpenchainDB. A nil snapshot reads the
+// latest data of the DB.
+type engine interface {
+	get(snapshot Snapshot, cf ColumnFamily, key []byte) ([]byte, error)
+	put(cf ColumnFamily, key []byte, value []byte) error
+	delete(cf ColumnFamily, key []byte) error
+	newWriteBatch() WriteBatch
+	write(writeBatch WriteBatch) error
+	newIterator(snapshot Snapshot, cf ColumnFamily) Iterator
+	newSnapshot() Snapshot
+	// clearColumnFamily removes all the keys of the column family, returns
+	// the handle to use for the column family from now on
+	clearColumnFamily(cf ColumnFamily) (ColumnFamily, error)
+	// getProperty returns the value of a property of the engine, for the
+	// column family or, if cf is nil, for the DB
+	getProperty(property string, cf ColumnFamily) string
+	close()
+}
+
+// engineFactory creates and opens the DBs of a storage engine. open returns
+// the handles to the column families in the order of cfNames, creating the
+// missing ones.
+type engineFactory struct {
+	create func(dbPath string) error
+	open   func(dbPath string, cfNames []string) (engine, []ColumnFamily, error)
+}
+
+const defaultEngine = "rocksdb"
+
+// engines holds the storage engines compiled in, keyed by the name used in
+// 'peer.db.engine'
+var engines = make(map[string]*engineFactory)
+
+func registerEngine(name string, factory *engineFactory) {
+	engines[name] = factory
+}
+
+func getEngineName() string {
+	name := viper.GetString("peer.db.engine")
+	if name == "" {
+		name = defaultEngine
+	}
+	return name
+}
+
+// Engines returns the sorted names of the storage engines compiled in
+func Engines() []string {
+	var names []string
+	for name := range engines {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func getEngineFactory() (*engineFactory, error) {
+	name := getEngineName()
+	factory, ok := engines[name]
+	if !ok {
+		return nil, fmt.Errorf("DB engine [%s] specified by 'peer.db.engine' is not available in this build, available engines are %v", name, Engines())
+	}
+	return factory, nil
+}
@@ -0,0 +1,230 @@
+/*
+Copyright IBM Corp. 2016 All Rights Reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+		 http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package db
+
+import (
+	"bytes"
+
+	"github.com/syndtr/goleveldb/leveldb"
+	"github.com/syndtr/goleveldb/leveldb/iterator"
+	"github.com/syndtr/goleveldb/leveldb/opt"
+	"github.com/syndtr/goleveldb/leveldb/util"
+)
+
+// The leveldb engine is a pure Go engine. LevelDB has no column families, so
+// the keys of a column family are stored under the prefix name 0x00, which
+// keeps the keys of the column families apart as no name contains a 0x00 byte.
+
+// leveldbClearBatchSize is the number of keys deleted by each of the batches
+// clearing a column family
+const leveldbClearBatchSize = 1000
+
+func init() {
+	registerEngine("leveldb", &engineFactory{createLevelDB, openLevelDB})
+}
+
+type leveldbColumnFamily struct {
+	name   string
+	prefix []byte
+}
+
+func newLeveldbColumnFamily(name string) *leveldbColumnFamily {
+	return &leveldbColumnFamily{name, append([]byte(name), 0x00)}
+}
+
+func (cf *leveldbColumnFamily) Name() string {
+	return cf.name
+}
+
+func (cf *leveldbColumnFamily) key(key []byte) []byte {
+	return append(append(make([]byte, 0, len(cf.prefix)+len(key)), cf.prefix...), key...)
+}
+
+type leveldbWriteBatch struct {
+	batch *leveldb.Batch
+}
+
+func (writeBatch *leveldbWriteBatch) PutCF(cf ColumnFamily, key []byte, value []byte) {
+	writeBatch.batch.Put(cf.(*leveldbColumnFamily).key(key), value)
+}
+
+func (writeBatch *leveldbWriteBatch) DeleteCF(cf ColumnFamily, key []byte) {
+	writeBatch.batch.Delete(cf.(*leveldbColumnFamily).key(key))
+}
+
+func (writeBatch *leveldbWriteBatch) Destroy() {
+	writeBatch.batch.Reset()
+}
+
+// leveldbIterator iterates over the keys of a column family, stripping the
+// prefix of the column family from the keys
+type leveldbIterator struct {
+	cf  *leveldbColumnFamily
+	itr iterator.Iterator
+}
+
+func (itr *leveldbIterator) Seek(key []byte) {
+	itr.itr.Seek(itr.cf.key(key))
+}
+
+func (itr *leveldbIterator) SeekToFirst() {
+	itr.itr.First()
+}
+
+func (itr *leveldbIterator) Next() {
+	itr.itr.Next()
+}
+
+func (itr *leveldbIterator) Prev() {
+	itr.itr.Prev()
+}
+
+func (itr *leveldbIterator) Valid() bool {
+	return itr.itr.Valid()
+}
+
+func (itr *leveldbIterator) ValidForPrefix(prefix []byte) bool {
+	return itr.itr.Valid() && bytes.HasPrefix(itr.Key(), prefix)
+}
+
+func (itr *leveldbIterator) Key() []byte {
+	return itr.itr.Key()[len(itr.cf.prefix):]
+}
+
+func (itr *leveldbIterator) Value() []byte {
+	return itr.itr.Value()
+}
+
+func (itr *leveldbIterator) Err() error {
+	return itr.itr.Error()
+}
+
+func (itr *leveldbIterator) Close() {
+	itr.itr.Release()
+}
+
+type leveldbEngine struct {
+	db *leveldb.DB
+}
+
+func createLevelDB(dbPath string) error {
+	db, err := leveldb.OpenFile(dbPath, nil)
+	if err != nil {
+		return err
+	}
+	return db.Close()
+}
+
+func openLevelDB(dbPath string, cfNames []string) (engine, []ColumnFamily, error) {
+	db, err := leveldb.OpenFile(dbPath, &opt.Options{ErrorIfMissing: true})
+	if err != nil {
+		return nil, nil, err
+	}
+	cfs := make([]ColumnFamily, len(cfNames))
+	for i, name := range cfNames {
+		cfs[i] = newLeveldbColumnFamily(name)
+	}
+	return &leveldbEngine{db}, cfs, nil
+}
+
+func (level *leveldbEngine) get(snapshot Snapshot, cf ColumnFamily, key []byte) ([]byte, error) {
+	var value []byte
+	var err error
+	if snapshot != nil {
+		value, err = snapshot.(*leveldb.Snapshot).Get(cf.(*leveldbColumnFamily).key(key), nil)
+	} else {
+		value, err = level.db.Get(cf.(*leveldbColumnFamily).key(key), nil)
+	}
+	if err == leveldb.ErrNotFound {
+		return nil, nil
+	}
+	return value, err
+}
+
+func (level *leveldbEngine) put(cf ColumnFamily, key []byte, value []byte) error {
+	return level.db.Put(cf.(*leveldbColumnFamily).key(key), value, nil)
+}
+
+func (level *leveldbEngine) delete(cf ColumnFamily, key []byte) error {
+	return level.db.Delete(cf.(*leveldbColumnFamily).key(key), nil)
+}
+
+func (level *leveldbEngine) newWriteBatch() WriteBatch {
+	return &leveldbWriteBatch{new(leveldb.Batch)}
+}
+
+func (level *leveldbEngine) write(writeBatch WriteBatch) error {
+	return level.db.Write(writeBatch.(*leveldbWriteBatch).batch, nil)
+}
+
+func (level *leveldbEngine) newIterator(snapshot Snapshot, cf ColumnFamily) Iterator {
+	leveldbCF := cf.(*leveldbColumnFamily)
+	cfRange := util.BytesPrefix(leveldbCF.prefix)
+	if snapshot != nil {
+		return &leveldbIterator{leveldbCF, snapshot.(*leveldb.Snapshot).NewIterator(cfRange, nil)}
+	}
+	return &leveldbIterator{leveldbCF, level.db.NewIterator(cfRange, nil)}
+}
+
+func (level *leveldbEngine) newSnapshot() Snapshot {
+	snapshot, err := level.db.GetSnapshot()
+	if err != nil {
+		// the DB is closed
+		panic(err)
+	}
+	return snapshot
+}
+
+func (level *leveldbEngine) clearColumnFamily(cf ColumnFamily) (ColumnFamily, error) {
+	leveldbCF := cf.(*leveldbColumnFamily)
+	itr := level.db.NewIterator(util.BytesPrefix(leveldbCF.prefix), nil)
+	defer itr.Release()
+	batch := new(leveldb.Batch)
+	for itr.Next() {
+		batch.Delete(itr.Key())
+		if batch.Len() == leveldbClearBatchSize {
+			if err := level.db.Write(batch, nil); err != nil {
+				return nil, err
+			}
+			batch.Reset()
+		}
+	}
+	if err := itr.Error(); err != nil {
+		return nil, err
+	}
+	if err := level.db.Write(batch, nil); err != nil {
+		return nil, err
+	}
+	return cf, nil
+}
+
+// getProperty returns the value of a leveldb property of the DB, such as
+// 'leveldb.stats'. There are no properties of the column families.
+func (level *leveldbEngine) getProperty(property string, cf ColumnFamily) string {
+	if cf != nil {
+		return ""
+	}
+	value, err := level.db.GetProperty(property)
+	if err != nil {
+		return ""
+	}
+	return value
+}
+
+func (level *leveldbEngine) close() {
+	level.db.Close()
+}
@@ -0,0 +1,193 @@
+// +build !norocksdb
+
+/*
+Copyright IBM Corp. 2016 All Rights Reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+		 http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package db
+
+import (
+	"fmt"
+
+	"github.com/tecbot/gorocksdb"
+)
+
+// The rocksdb engine maps each column family to a rocksdb column family. It
+// requires cgo and the rocksdb library, build with the tag 'norocksdb' to
+// leave it out.
+
+func init() {
+	registerEngine("rocksdb", &engineFactory{createRocksDB, openRocksDB})
+}
+
+type rocksdbColumnFamily struct {
+	name   string
+	handle *gorocksdb.ColumnFamilyHandle
+}
+
+func (cf *rocksdbColumnFamily) Name() string {
+	return cf.name
+}
+
+type rocksdbWriteBatch struct {
+	*gorocksdb.WriteBatch
+}
+
+func (writeBatch *rocksdbWriteBatch) PutCF(cf ColumnFamily, key []byte, value []byte) {
+	writeBatch.WriteBatch.PutCF(cf.(*rocksdbColumnFamily).handle, key, value)
+}
+
+func (writeBatch *rocksdbWriteBatch) DeleteCF(cf ColumnFamily, key []byte) {
+	writeBatch.WriteBatch.DeleteCF(cf.(*rocksdbColumnFamily).handle, key)
+}
+
+type rocksdbIterator struct {
+	*gorocksdb.Iterator
+}
+
+func (itr *rocksdbIterator) Key() []byte {
+	return itr.Iterator.Key().Data()
+}
+
+func (itr *rocksdbIterator) Value() []byte {
+	return itr.Iterator.Value().Data()
+}
+
+type rocksdbEngine struct {
+	db  *gorocksdb.DB
+	cfs map[string]*rocksdbColumnFamily
+}
+
+func createRocksDB(dbPath string) error {
+	opts := gorocksdb.NewDefaultOptions()
+	defer opts.Destroy()
+	opts.SetCreateIfMissing(true)
+
+	db, err := gorocksdb.OpenDb(opts, dbPath)
+	if err != nil {
+		return err
+	}
+	db.Close()
+	return nil
+}
+
+func openRocksDB(dbPath string, cfNames []string) (engine, []ColumnFamily, error) {
+	opts := gorocksdb.NewDefaultOptions()
+	defer opts.Destroy()
+
+	opts.SetCreateIfMissing(false)
+	opts.SetCreateIfMissingColumnFamilies(true)
+
+	var cfOpts []*gorocksdb.Options
+	for range cfNames {
+		cfOpts = append(cfOpts, opts)
+	}
+
+	db, cfHandlers, err := gorocksdb.OpenDbColumnFamilies(opts, dbPath, cfNames, cfOpts)
+	if err != nil {
+		return nil, nil, err
+	}
+	rocksdb := &rocksdbEngine{db, make(map[string]*rocksdbColumnFamily)}
+	cfs := make([]ColumnFamily, len(cfNames))
+	for i, name := range cfNames {
+		rocksdb.cfs[name] = &rocksdbColumnFamily{name, cfHandlers[i]}
+		cfs[i] = rocksdb.cfs[name]
+	}
+	return rocksdb, cfs, nil
+}
+
+func (rocksdb *rocksdbEngine) get(snapshot Snapshot, cf ColumnFamily, key []byte) ([]byte, error) {
+	opt := gorocksdb.NewDefaultReadOptions()
+	defer opt.Destroy()
+	if snapshot != nil {
+		opt.SetSnapshot(snapshot.(*gorocksdb.Snapshot))
+	}
+	slice, err := rocksdb.db.GetCF(opt, cf.(*rocksdbColumnFamily).handle, key)
+	if err != nil {
+		return nil, err
+	}
+	defer slice.Free()
+	if slice.Data() == nil {
+		return nil, nil
+	}
+	return makeCopy(slice.Data()), nil
+}
+
+func (rocksdb *rocksdbEngine) put(cf ColumnFamily, key []byte, value []byte) error {
+	opt := gorocksdb.NewDefaultWriteOptions()
+	defer opt.Destroy()
+	return rocksdb.db.PutCF(opt, cf.(*rocksdbColumnFamily).handle, key, value)
+}
+
+func (rocksdb *rocksdbEngine) delete(cf ColumnFamily, key []byte) error {
+	opt := gorocksdb.NewDefaultWriteOptions()
+	defer opt.Destroy()
+	return rocksdb.db.DeleteCF(opt, cf.(*rocksdbColumnFamily).handle, key)
+}
+
+func (rocksdb *rocksdbEngine) newWriteBatch() WriteBatch {
+	return &rocksdbWriteBatch{gorocksdb.NewWriteBatch()}
+}
+
+func (rocksdb *rocksdbEngine) write(writeBatch WriteBatch) error {
+	opt := gorocksdb.NewDefaultWriteOptions()
+	defer opt.Destroy()
+	return rocksdb.db.Write(opt, writeBatch.(*rocksdbWriteBatch).WriteBatch)
+}
+
+func (rocksdb *rocksdbEngine) newIterator(snapshot Snapshot, cf ColumnFamily) Iterator {
+	opt := gorocksdb.NewDefaultReadOptions()
+	defer opt.Destroy()
+	if snapshot != nil {
+		opt.SetSnapshot(snapshot.(*gorocksdb.Snapshot))
+	} else {
+		opt.SetFillCache(true)
+	}
+	return &rocksdbIterator{rocksdb.db.NewIteratorCF(opt, cf.(*rocksdbColumnFamily).handle)}
+}
+
+func (rocksdb *rocksdbEngine) newSnapshot() Snapshot {
+	return rocksdb.db.NewSnapshot()
+}
+
+func (rocksdb *rocksdbEngine) clearColumnFamily(cf ColumnFamily) (ColumnFamily, error) {
+	name := cf.Name()
+	err := rocksdb.db.DropColumnFamily(cf.(*rocksdbColumnFamily).handle)
+	if err != nil {
+		return nil, fmt.Errorf("Error dropping column family [%s]: %s", name, err)
+	}
+	opts := gorocksdb.NewDefaultOptions()
+	defer opts.Destroy()
+	handle, err := rocksdb.db.CreateColumnFamily(opts, name)
+	if err != nil {
+		return nil, fmt.Errorf("Error creating column family [%s]: %s", name, err)
+	}
+	rocksdb.cfs[name] = &rocksdbColumnFamily{name, handle}
+	return rocksdb.cfs[name], nil
+}
+
+func (rocksdb *rocksdbEngine) getProperty(property string, cf ColumnFamily) string {
+	if cf == nil {
+		return rocksdb.db.GetProperty(property)
+	}
+	return rocksdb.db.GetPropertyCF(property, cf.(*rocksdbColumnFamily).handle)
+}
+
+func (rocksdb *rocksdbEngine) close() {
+	for _, cf := range rocksdb.cfs {
+		cf.handle.Destroy()
+	}
+	rocksdb.db.Close()
+}
@@ -34,6 +34,7 @@ import (
 	"github.com/hyperledger/fabric/core/chaincode/platforms"
 	"github.com/hyperledger/fabric/core/container"
 	crypto "github.com/hyperledger/fabric/core/crypto"
+	"github.com/hyperledger/fabric/core/ledger"
 	"github.com/hyperledger/fabric/core/peer"
 	"github.com/hyperledger/fabric/core/util"
 	pb "github.com/hyperledger/fabric/protos"
@@ -118,7 +119,9 @@ func (*Devops) Build(context context.Context, spec *pb.ChaincodeSpec) (*pb.Chain
 			return nil, err
 		}
 	}
-	chaincodeDeploymentSpec := &pb.ChaincodeDeploymentSpec{ChaincodeSpec: spec, CodePackage: codePackageBytes}
+	// the effective date requested in the spec belongs to the deployment spec
+	chaincodeDeploymentSpec := &pb.ChaincodeDeploymentSpec{ChaincodeSpec: spec, EffectiveDate: spec.EffectiveDate, CodePackage: codePackageBytes}
+	spec.EffectiveDate = nil
 	return chaincodeDeploymentSpec, nil
 }
 
@@ -203,6 +206,93 @@ func (d *Devops) Deploy(ctx context.Context, spec *pb.ChaincodeSpec) (*pb.Chainc
 	return chaincodeDeploymentSpec, err
 }
 
+// Upgrade deploys a new version of the supplied chaincode package under the
+// name of an existing chaincode through a transaction. The state of the
+// chaincode is carried over to the new version.
+func (d *Devops) Upgrade(ctx context.Context, spec *pb.ChaincodeSpec) (*pb.ChaincodeDeploymentSpec, error) {
+	if spec == nil || spec.ChaincodeID == nil || spec.ChaincodeID.Name == "" {
+		return nil, fmt.Errorf("name not given for upgrade")
+	}
+	name := spec.ChaincodeID.Name
+	version := spec.ChaincodeID.Version
+
+	// get the deployment spec of the new version
+	chaincodeDeploymentSpec, err := d.getChaincodeBytes(ctx, spec)
+	if err != nil {
+		devopsLogger.Error(fmt.Sprintf("Error upgrading chaincode spec: %v\n\n error: %s", spec, err))
+		return nil, err
+	}
+
+	// The executable of the new version is named after the hash of its package
+	// which is thus used as the transaction ID, while the chaincode keeps its name
+	transID := chaincodeDeploymentSpec.ChaincodeSpec.ChaincodeID.Name
+	if transID == name {
+		transID = util.GenerateUUID()
+	}
+	chaincodeDeploymentSpec.ChaincodeSpec.ChaincodeID.Name = name
+	if version == 0 {
+		ledger, err := getChainLedger(spec.ChainID)
+		if err != nil {
+			return nil, err
+		}
+		current, err := chaincode.GetChaincodeVersion(ledger, name)
+		if err != nil {
+			return nil, err
+		}
+		version = current + 1
+	}
+	chaincodeDeploymentSpec.ChaincodeSpec.ChaincodeID.Version = version
+
+	var tx *pb.Transaction
+	var sec crypto.Client
+
+	if peer.SecurityEnabled() {
+		if devopsLogger.IsEnabledFor(logging.DEBUG) {
+			devopsLogger.Debugf("Initializing secure devops using context %s", spec.SecureContext)
+		}
+		sec, err = crypto.InitClient(spec.SecureContext, nil)
+		defer crypto.CloseClient(sec)
+
+		// remove the security context since we are no longer need it down stream
+		spec.SecureContext = ""
+
+		if nil != err {
+			return nil, err
+		}
+
+		if devopsLogger.IsEnabledFor(logging.DEBUG) {
+			devopsLogger.Debugf("Creating secure upgrade transaction %s", transID)
+		}
+		var txHandler crypto.TransactionHandler
+		txHandler, err = getOwnerTransactionHandler(sec, spec.ChainID, name)
+		if nil != err {
+			return nil, err
+		}
+		tx, err = txHandler.NewChaincodeUpgrade(chaincodeDeploymentSpec, transID, spec.Attributes...)
+		if nil != err {
+			return nil, err
+		}
+	} else {
+		if devopsLogger.IsEnabledFor(logging.DEBUG) {
+			devopsLogger.Debugf("Creating upgrade transaction (%s)", transID)
+		}
+		tx, err = pb.NewChaincodeUpgradeTransaction(chaincodeDeploymentSpec, transID)
+		if err != nil {
+			return nil, fmt.Errorf("Error upgrading chaincode: %s ", err)
+		}
+	}
+
+	if devopsLogger.IsEnabledFor(logging.DEBUG) {
+		devopsLogger.Debugf("Sending upgrade transaction (%s) to validator", tx.Uuid)
+	}
+	resp := d.coord.ExecuteTransaction(tx)
+	if resp.Status == pb.Response_FAILURE {
+		err = fmt.Errorf(string(resp.Msg))
+	}
+
+	return chaincodeDeploymentSpec, err
+}
+
 func (d *Devops) invokeOrQuery(ctx context.Context, chaincodeInvocationSpec *pb.ChaincodeInvocationSpec, attributes []string, invoke bool) (*pb.Response, error) {
 
 	if chaincodeInvocationSpec.ChaincodeSpec.ChaincodeID.Name == "" {
@@ -304,6 +394,128 @@ func (d *Devops) Query(ctx context.Context, chaincodeInvocationSpec *pb.Chaincod
 	return d.invokeOrQuery(ctx, chaincodeInvocationSpec, chaincodeInvocationSpec.ChaincodeSpec.Attributes, false)
 }
 
+// Terminate terminates the specified chaincode through a transaction. Once the
+// transaction is committed the chaincode can no longer be invoked or queried.
+func (d *Devops) Terminate(ctx context.Context, chaincodeTerminationSpec *pb.ChaincodeTerminationSpec) (*pb.Response, error) {
+	spec := chaincodeTerminationSpec.ChaincodeSpec
+	if spec == nil || spec.ChaincodeID == nil || spec.ChaincodeID.Name == "" {
+		return nil, fmt.Errorf("name not given for terminate")
+	}
+
+	// Now create the Transactions message and send to Peer.
+	id := util.GenerateUUID()
+
+	var tx *pb.Transaction
+	var err error
+	if peer.SecurityEnabled() {
+		if devopsLogger.IsEnabledFor(logging.DEBUG) {
+			devopsLogger.Debugf("Initializing secure devops using context %s", spec.SecureContext)
+		}
+		sec, err := crypto.InitClient(spec.SecureContext, nil)
+		defer crypto.CloseClient(sec)
+
+		// remove the security context since we are no longer need it down stream
+		spec.SecureContext = ""
+
+		if nil != err {
+			return nil, err
+		}
+
+		if devopsLogger.IsEnabledFor(logging.DEBUG) {
+			devopsLogger.Debugf("Creating secure terminate transaction %s", id)
+		}
+		txHandler, err := getOwnerTransactionHandler(sec, spec.ChainID, spec.ChaincodeID.Name)
+		if nil != err {
+			return nil, err
+		}
+		tx, err = txHandler.NewChaincodeTerminate(chaincodeTerminationSpec, id, spec.Attributes...)
+		if nil != err {
+			return nil, err
+		}
+	} else {
+		if devopsLogger.IsEnabledFor(logging.DEBUG) {
+			devopsLogger.Debugf("Creating terminate transaction (%s)", id)
+		}
+		tx, err = pb.NewChaincodeTerminateTransaction(chaincodeTerminationSpec, id)
+		if err != nil {
+			return nil, fmt.Errorf("Error terminating chaincode: %s ", err)
+		}
+	}
+
+	if devopsLogger.IsEnabledFor(logging.DEBUG) {
+		devopsLogger.Debugf("Sending terminate transaction (%s) to validator", tx.Uuid)
+	}
+	resp := d.coord.ExecuteTransaction(tx)
+	if resp.Status == pb.Response_FAILURE {
+		err = fmt.Errorf(string(resp.Msg))
+	}
+
+	return resp, err
+}
+
+// ApproveReconfiguration adds the approval of this validator to the payload of
+// a reconfiguration of the validators of the chain, a new one to the validators
+// if empty, on behalf of the administrator logged in with the secure context.
+// The payload is passed on to the other validators to approve, and submitted
+// with Reconfigure once approved by a quorum of them.
+func (d *Devops) ApproveReconfiguration(secureContext string, chainID string, validators []string, payload []byte) ([]byte, error) {
+	if err := d.checkAdmin(secureContext); err != nil {
+		return nil, err
+	}
+	approver, ok := d.coord.(peer.ReconfigurationApprover)
+	if !ok {
+		return nil, fmt.Errorf("This peer cannot approve reconfigurations")
+	}
+	return approver.ApproveReconfiguration(chainID, validators, payload)
+}
+
+// Reconfigure submits the reconfiguration of the validators of the chain
+// approved with ApproveReconfiguration. The validators check the approvals, so
+// anyone may submit it.
+func (d *Devops) Reconfigure(chainID string, payload []byte) (*pb.Response, error) {
+	if len(payload) == 0 {
+		return nil, fmt.Errorf("reconfiguration not given")
+	}
+	tx := &pb.Transaction{
+		Type:      pb.Transaction_CONSENSUS_RECONFIGURE,
+		ChainID:   chainID,
+		Payload:   payload,
+		Uuid:      util.GenerateUUID(),
+		Timestamp: util.CreateUtcTimestamp(),
+	}
+
+	if devopsLogger.IsEnabledFor(logging.DEBUG) {
+		devopsLogger.Debugf("Sending reconfiguration transaction (%s) to validator", tx.Uuid)
+	}
+	resp := d.coord.ExecuteTransaction(tx)
+	var err error
+	if resp.Status == pb.Response_FAILURE {
+		err = fmt.Errorf(string(resp.Msg))
+	}
+	return resp, err
+}
+
+// checkAdmin returns an error unless the client logged in with the secure
+// context is one of the administrators of this peer, which requires security
+func (d *Devops) checkAdmin(secureContext string) error {
+	if !peer.SecurityEnabled() {
+		return fmt.Errorf("Administrators cannot be identified with security disabled")
+	}
+	sec, err := crypto.InitClient(secureContext, nil)
+	if err != nil {
+		return err
+	}
+	defer crypto.CloseClient(sec)
+	certHandler, err := sec.GetEnrollmentCertificateHandler()
+	if err != nil {
+		return err
+	}
+	if !d.coord.GetSecHelper().IsAdmin(certHandler.GetCertificate()) {
+		return fmt.Errorf("%s is not an administrator of this peer", secureContext)
+	}
+	return nil
+}
+
 // CheckSpec to see if chaincode resides within current package capture for language.
 func CheckSpec(spec *pb.ChaincodeSpec) error {
 	// Don't allow nil value
@@ -319,6 +531,44 @@ func CheckSpec(spec *pb.ChaincodeSpec) error {
 	return platform.ValidateSpec(spec)
 }
 
+// getOwnerTransactionHandler returns the handler of the transactions of the
+// client terminating or upgrading the chaincode. They must be signed with the
+// certificate of the deploy transaction of the chaincode if the client deployed
+// it, and with the enrollment certificate of the client otherwise, which only
+// administrators are allowed.
+func getOwnerTransactionHandler(sec crypto.Client, chainID string, chaincode string) (crypto.TransactionHandler, error) {
+	ledger, err := getChainLedger(chainID)
+	if err != nil {
+		return nil, err
+	}
+	var certHandler crypto.CertificateHandler
+	//the deploy transaction is named after the chaincode
+	if depTx, err := ledger.GetTransactionByUUID(chaincode); err == nil && depTx != nil {
+		if certHandler, err = sec.GetTCertificateHandlerFromDER(depTx.Cert); err != nil {
+			devopsLogger.Debugf("Chaincode %s was not deployed with a transaction certificate of the client (%s)", chaincode, err)
+		}
+	}
+	if certHandler == nil {
+		if certHandler, err = sec.GetEnrollmentCertificateHandler(); err != nil {
+			return nil, err
+		}
+	}
+	return certHandler.GetTransactionHandler()
+}
+
+// getChainLedger returns the ledger of the chain, provided the peer hosts it,
+// so that no DB is created for the chains requested by the clients
+func getChainLedger(chainID string) (*ledger.Ledger, error) {
+	if !peer.HostsChain(chainID) {
+		return nil, fmt.Errorf("Chain %s is not hosted by this peer", chainID)
+	}
+	ledger, err := ledger.GetChainLedger(chainID)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to get handle to ledger (%s)", err)
+	}
+	return ledger, nil
+}
+
 // EXP_GetApplicationTCert retrieves an application TCert for the supplied user
 func (d *Devops) EXP_GetApplicationTCert(ctx context.Context, secret *pb.Secret) (*pb.Response, error) {
 	var sec crypto.Client
@@ -0,0 +1,267 @@
+/*
+Copyright IBM Corp. 2016 All Rights Reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+		 http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package core
+
+import (
+	"encoding/json"
+	"math/rand"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/op/go-logging"
+
+	"github.com/hyperledger/fabric/core/db"
+	"github.com/hyperledger/fabric/discovery"
+	pb "github.com/hyperledger/fabric/protos"
+)
+
+var discoveryLogger = logging.MustGetLogger("discovery")
+
+// discoveryMembersKey is the key of the known members in the persist column
+// family of the DB
+var discoveryMembersKey = []byte("discovery.members")
+
+// persistedMember is a known member as stored in the DB
+type persistedMember struct {
+	Endpoint *pb.PeerEndpoint
+	LastSeen time.Time
+}
+
+// GossipDiscovery is an implementation of Discovery which, besides the root
+// nodes, knows the members of the network learned from the peers it connects
+// to. The members are persisted so that they are known again after a restart.
+// A member which cannot be reached and has not been seen for longer than the
+// ttl is forgotten, root nodes are never forgotten.
+type GossipDiscovery struct {
+	sync.Mutex
+	rootNodes []string
+	members   map[string]*discovery.Member // by address
+	ttl       time.Duration
+	random    *rand.Rand
+}
+
+// NewGossipDiscovery is a constructor of a Discovery implementation learning
+// the membership from the network. Accepts the root node configuration, as
+// NewStaticDiscovery, and the ttl of the members which cannot be reached.
+func NewGossipDiscovery(rootNodesString string, ttl time.Duration) *GossipDiscovery {
+	gd := &GossipDiscovery{
+		members: make(map[string]*discovery.Member),
+		ttl:     ttl,
+		random:  rand.New(rand.NewSource(time.Now().Unix())),
+	}
+	for _, rootNode := range strings.Split(rootNodesString, ",") {
+		if rootNode != "" {
+			gd.rootNodes = append(gd.rootNodes, rootNode)
+		}
+	}
+	gd.load()
+	return gd
+}
+
+func (gd *GossipDiscovery) load() {
+	data, err := db.GetDBHandle().Get(db.GetDBHandle().PersistCF, discoveryMembersKey)
+	if err != nil || data == nil {
+		return
+	}
+	var persisted []*persistedMember
+	if err = json.Unmarshal(data, &persisted); err != nil {
+		discoveryLogger.Warningf("Ignoring the persisted members: %s", err)
+		return
+	}
+	for _, pm := range persisted {
+		if pm.Endpoint != nil && pm.Endpoint.Address != "" {
+			gd.members[pm.Endpoint.Address] = &discovery.Member{Endpoint: pm.Endpoint, LastSeen: pm.LastSeen}
+		}
+	}
+	discoveryLogger.Debugf("Loaded %d persisted members", len(gd.members))
+}
+
+// storeLocked persists the members
+func (gd *GossipDiscovery) storeLocked() {
+	var persisted []*persistedMember
+	for _, member := range gd.members {
+		persisted = append(persisted, &persistedMember{member.Endpoint, member.LastSeen})
+	}
+	data, err := json.Marshal(persisted)
+	if err == nil {
+		err = db.GetDBHandle().Put(db.GetDBHandle().PersistCF, discoveryMembersKey, data)
+	}
+	if err != nil {
+		discoveryLogger.Errorf("Error persisting the members: %s", err)
+	}
+}
+
+func (gd *GossipDiscovery) isRootNode(address string) bool {
+	for _, rootNode := range gd.rootNodes {
+		if rootNode == address {
+			return true
+		}
+	}
+	return false
+}
+
+// evictLocked forgets the members which cannot be reached and have not been
+// seen for longer than the ttl, and returns whether any was
+func (gd *GossipDiscovery) evictLocked(now time.Time) bool {
+	evicted := false
+	for address, member := range gd.members {
+		if !member.Connected && member.Failures > 0 && now.Sub(member.LastSeen) > gd.ttl && !gd.isRootNode(address) {
+			discoveryLogger.Infof("Forgetting member %s, last seen %s", address, member.LastSeen)
+			delete(gd.members, address)
+			evicted = true
+		}
+	}
+	return evicted
+}
+
+// seenLocked records that endpoint was seen and returns whether it is a new
+// member
+func (gd *GossipDiscovery) seenLocked(endpoint *pb.PeerEndpoint, now time.Time) (*discovery.Member, bool) {
+	member, ok := gd.members[endpoint.Address]
+	if !ok {
+		member = &discovery.Member{}
+		gd.members[endpoint.Address] = member
+	}
+	member.Endpoint = endpoint
+	member.LastSeen = now
+	member.Failures = 0
+	return member, !ok
+}
+
+// PeersDiscovered records the peers connected to another peer
+func (gd *GossipDiscovery) PeersDiscovered(peers []*pb.PeerEndpoint) {
+	gd.Lock()
+	defer gd.Unlock()
+	now := time.Now()
+	added := false
+	for _, endpoint := range peers {
+		if endpoint.Address == "" {
+			continue
+		}
+		if _, isNew := gd.seenLocked(endpoint, now); isNew {
+			discoveryLogger.Debugf("Discovered member %s", endpoint.Address)
+			added = true
+		}
+	}
+	if gd.evictLocked(now) || added {
+		gd.storeLocked()
+	}
+}
+
+// Connected records that the peer is connected to endpoint
+func (gd *GossipDiscovery) Connected(endpoint *pb.PeerEndpoint) {
+	gd.Lock()
+	defer gd.Unlock()
+	member, _ := gd.seenLocked(endpoint, time.Now())
+	member.Connected = true
+	gd.storeLocked()
+}
+
+// Disconnected records that the peer is no longer connected to endpoint
+func (gd *GossipDiscovery) Disconnected(endpoint *pb.PeerEndpoint) {
+	gd.Lock()
+	defer gd.Unlock()
+	if member, ok := gd.members[endpoint.Address]; ok {
+		member.Connected = false
+		member.LastSeen = time.Now()
+	}
+}
+
+// Unreachable records a failed attempt to connect to address and returns
+// whether the peer should keep trying, which it should until the member is
+// forgotten
+func (gd *GossipDiscovery) Unreachable(address string) bool {
+	gd.Lock()
+	defer gd.Unlock()
+	member, ok := gd.members[address]
+	if !ok {
+		return gd.isRootNode(address)
+	}
+	member.Failures++
+	if gd.evictLocked(time.Now()) {
+		gd.storeLocked()
+	}
+	_, ok = gd.members[address]
+	return ok || gd.isRootNode(address)
+}
+
+// GetMembers returns the known members of the network, sorted by address
+func (gd *GossipDiscovery) GetMembers() []*discovery.Member {
+	gd.Lock()
+	defer gd.Unlock()
+	var members []*discovery.Member
+	for _, member := range gd.members {
+		m := *member
+		members = append(members, &m)
+	}
+	sort.Sort(membersByAddress(members))
+	return members
+}
+
+type membersByAddress []*discovery.Member
+
+func (m membersByAddress) Len() int           { return len(m) }
+func (m membersByAddress) Swap(i, j int)      { m[i], m[j] = m[j], m[i] }
+func (m membersByAddress) Less(i, j int) bool { return m[i].Endpoint.Address < m[j].Endpoint.Address }
+
+// GetRandomNode returns a random validator among the members which are
+// connected or were reachable, or else a random root node, or else a random
+// member
+func (gd *GossipDiscovery) GetRandomNode() string {
+	gd.Lock()
+	defer gd.Unlock()
+	var validators, others []string
+	for address, member := range gd.members {
+		if !member.Connected && member.Failures > 0 {
+			continue
+		}
+		if member.Endpoint.Type == pb.PeerEndpoint_VALIDATOR {
+			validators = append(validators, address)
+		} else {
+			others = append(others, address)
+		}
+	}
+	for _, candidates := range [][]string{validators, gd.rootNodes, others} {
+		if len(candidates) > 0 {
+			sort.Strings(candidates)
+			return candidates[gd.random.Intn(len(candidates))]
+		}
+	}
+	return ""
+}
+
+// GetRootNodes returns the root nodes and the addresses of the known members,
+// or a single empty address if there are none, as StaticDiscovery
+func (gd *GossipDiscovery) GetRootNodes() []string {
+	gd.Lock()
+	defer gd.Unlock()
+	nodes := append([]string{}, gd.rootNodes...)
+	var addresses []string
+	for address := range gd.members {
+		if !gd.isRootNode(address) {
+			addresses = append(addresses, address)
+		}
+	}
+	sort.Strings(addresses)
+	nodes = append(nodes, addresses...)
+	if len(nodes) == 0 {
+		return []string{""}
+	}
+	return nodes
+}
@@ -0,0 +1,132 @@
+/*
+Copyright IBM Corp. 2016 All Rights Reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+		 http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package core
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/spf13/viper"
+
+	"github.com/hyperledger/fabric/core/db"
+	pb "github.com/hyperledger/fabric/protos"
+)
+
+func setupGossipDiscoveryTest(t *testing.T) func() {
+	dir, err := ioutil.TempDir("", "gossipdiscovery")
+	if err != nil {
+		t.Fatalf("Error creating temp dir: %s", err)
+	}
+	viper.Set("peer.fileSystemPath", dir)
+	db.NewTestDBWrapper().CreateFreshDB(t)
+	return func() {
+		db.GetDBHandle().CloseDB()
+		os.RemoveAll(dir)
+	}
+}
+
+func newTestEndpoint(name, address string, peerType pb.PeerEndpoint_Type) *pb.PeerEndpoint {
+	return &pb.PeerEndpoint{ID: &pb.PeerID{Name: name}, Address: address, Type: peerType}
+}
+
+func TestGossipDiscovery_Persistence(t *testing.T) {
+	defer setupGossipDiscoveryTest(t)()
+
+	gd := NewGossipDiscovery("root:30303", time.Minute)
+	if nodes := gd.GetRootNodes(); len(nodes) != 1 || nodes[0] != "root:30303" {
+		t.Fatalf("Expected the root node only but got %v", nodes)
+	}
+	gd.PeersDiscovered([]*pb.PeerEndpoint{newTestEndpoint("vp1", "vp1:30303", pb.PeerEndpoint_VALIDATOR)})
+	gd.Connected(newTestEndpoint("nvp1", "nvp1:30303", pb.PeerEndpoint_NON_VALIDATOR))
+
+	// A new instance knows the members discovered by the previous one
+	gd = NewGossipDiscovery("root:30303", time.Minute)
+	nodes := gd.GetRootNodes()
+	if len(nodes) != 3 || nodes[0] != "root:30303" || nodes[1] != "nvp1:30303" || nodes[2] != "vp1:30303" {
+		t.Fatalf("Expected the root node and the persisted members but got %v", nodes)
+	}
+	members := gd.GetMembers()
+	if len(members) != 2 || members[0].Endpoint.ID.Name != "nvp1" || members[0].Connected || members[0].LastSeen.IsZero() {
+		t.Fatalf("Expected the persisted members to be known but not connected, got %v", members)
+	}
+}
+
+func TestGossipDiscovery_Eviction(t *testing.T) {
+	defer setupGossipDiscoveryTest(t)()
+
+	gd := NewGossipDiscovery("root:30303", 0)
+	gd.PeersDiscovered([]*pb.PeerEndpoint{newTestEndpoint("vp1", "vp1:30303", pb.PeerEndpoint_VALIDATOR)})
+	gd.Connected(newTestEndpoint("vp2", "vp2:30303", pb.PeerEndpoint_VALIDATOR))
+	time.Sleep(time.Millisecond)
+
+	// A connected member is never forgotten
+	if !gd.Unreachable("vp2:30303") {
+		t.Fatalf("Expected a connected member to be kept")
+	}
+	if gd.Unreachable("vp1:30303") {
+		t.Fatalf("Expected an unreachable member past its ttl to be forgotten")
+	}
+	if !gd.Unreachable("root:30303") {
+		t.Fatalf("Expected a root node to be kept")
+	}
+	if gd.Unreachable("unknown:30303") {
+		t.Fatalf("Expected an unknown address not to be kept")
+	}
+	members := gd.GetMembers()
+	if len(members) != 1 || members[0].Endpoint.Address != "vp2:30303" || !members[0].Connected {
+		t.Fatalf("Expected the connected member only but got %v", members)
+	}
+
+	// Once disconnected, it is forgotten as well
+	gd.Disconnected(newTestEndpoint("vp2", "vp2:30303", pb.PeerEndpoint_VALIDATOR))
+	time.Sleep(time.Millisecond)
+	gd.Unreachable("vp2:30303")
+	if members = NewGossipDiscovery("root:30303", 0).GetMembers(); len(members) != 0 {
+		t.Fatalf("Expected no persisted members but got %v", members)
+	}
+}
+
+func TestGossipDiscovery_GetRandomNode(t *testing.T) {
+	defer setupGossipDiscoveryTest(t)()
+
+	gd := NewGossipDiscovery("root:30303", time.Minute)
+	if node := gd.GetRandomNode(); node != "root:30303" {
+		t.Fatalf("Expected the root node but got %s", node)
+	}
+
+	// Validators are preferred to the root nodes and other peers
+	gd.PeersDiscovered([]*pb.PeerEndpoint{
+		newTestEndpoint("nvp1", "nvp1:30303", pb.PeerEndpoint_NON_VALIDATOR),
+		newTestEndpoint("vp1", "vp1:30303", pb.PeerEndpoint_VALIDATOR),
+	})
+	for i := 0; i < 10; i++ {
+		if node := gd.GetRandomNode(); node != "vp1:30303" {
+			t.Fatalf("Expected the validator but got %s", node)
+		}
+	}
+
+	// unless they cannot be reached
+	gd.Unreachable("vp1:30303")
+	if node := gd.GetRandomNode(); node != "root:30303" {
+		t.Fatalf("Expected the root node but got %s", node)
+	}
+	if node := NewGossipDiscovery("", time.Minute).GetRandomNode(); node != "nvp1:30303" && node != "vp1:30303" {
+		t.Fatalf("Expected a persisted member but got %s", node)
+	}
+}
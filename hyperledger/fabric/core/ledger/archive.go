@@ -0,0 +1,447 @@
+/*
+Copyright IBM Corp. 2016 All Rights Reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+		 http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ledger
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"encoding/binary"
+	"fmt"
+	"hash"
+	"io"
+	"os"
+
+	"github.com/hyperledger/fabric/core/ledger/statemgmt"
+	"github.com/hyperledger/fabric/protos"
+)
+
+// An archive is a portable copy of the blocks, the world state and the history
+// index of a ledger, independent of the storage engine and of the state
+// implementation. It starts with the magic bytes archiveMagic followed by the
+// 4 byte big endian archiveVersion, then holds a sequence of records made of a
+// type byte, the uvarint length of the payload and the payload:
+//   'b' the marshalled block, for every block in block number order
+//   's' a key-value of the state, the payload is the uvarint length of the
+//       composite key (chaincodeID 0x00 key), the composite key and the value
+//   'h' an entry of the history index, encoded like a key-value of the state
+//   'e' the end of the archive, the payload is the uvarint numbers of blocks,
+//       key-values of the state and entries of the history index, followed by
+//       the sha256 hash of all the bytes of the archive preceding the record
+// The state deltas of the blocks are not archived, so that the restored ledger
+// cannot be rolled backwards by state transfer.
+
+var archiveMagic = []byte("HLLEDGER")
+
+const archiveVersion = 1
+
+const (
+	archiveBlockRecord   = 'b'
+	archiveStateRecord   = 's'
+	archiveHistoryRecord = 'h'
+	archiveEndRecord     = 'e'
+)
+
+// maxArchiveRecordSize bounds the payload of the records read from an archive,
+// so that a corrupted length does not exhaust the memory
+const maxArchiveRecordSize = 1 << 30
+
+// archiveImportBatchSize is the number of key-values of the state, or entries
+// of the history index, written to the DB at once by an import
+const archiveImportBatchSize = 1000
+
+// ArchiveInfo describes the content of an archive of the ledger
+type ArchiveInfo struct {
+	Blocks         uint64
+	StateEntries   uint64
+	HistoryEntries uint64
+}
+
+type archiveWriter struct {
+	w    *bufio.Writer
+	hash hash.Hash
+	err  error
+}
+
+func newArchiveWriter(w io.Writer) *archiveWriter {
+	return &archiveWriter{bufio.NewWriter(w), sha256.New(), nil}
+}
+
+func (archive *archiveWriter) write(data []byte) {
+	if archive.err != nil {
+		return
+	}
+	if _, archive.err = archive.w.Write(data); archive.err == nil {
+		archive.hash.Write(data)
+	}
+}
+
+func (archive *archiveWriter) writeHeader() error {
+	version := make([]byte, 4)
+	binary.BigEndian.PutUint32(version, archiveVersion)
+	archive.write(archiveMagic)
+	archive.write(version)
+	return archive.err
+}
+
+func (archive *archiveWriter) writeRecord(recordType byte, payload []byte) error {
+	header := make([]byte, 1+binary.MaxVarintLen64)
+	header[0] = recordType
+	n := binary.PutUvarint(header[1:], uint64(len(payload)))
+	archive.write(header[:1+n])
+	archive.write(payload)
+	return archive.err
+}
+
+func (archive *archiveWriter) writeEnd(info *ArchiveInfo) error {
+	payload := encodeArchiveUvarints(info.Blocks, info.StateEntries, info.HistoryEntries)
+	payload = append(payload, archive.hash.Sum(nil)...)
+	if err := archive.writeRecord(archiveEndRecord, payload); err != nil {
+		return err
+	}
+	return archive.w.Flush()
+}
+
+type archiveReader struct {
+	r    *bufio.Reader
+	hash hash.Hash
+}
+
+func newArchiveReader(r io.Reader) *archiveReader {
+	return &archiveReader{bufio.NewReader(r), sha256.New()}
+}
+
+func (archive *archiveReader) read(data []byte) error {
+	if _, err := io.ReadFull(archive.r, data); err != nil {
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
+			return fmt.Errorf("Archive is truncated")
+		}
+		return err
+	}
+	archive.hash.Write(data)
+	return nil
+}
+
+func (archive *archiveReader) readHeader() error {
+	header := make([]byte, len(archiveMagic)+4)
+	if err := archive.read(header); err != nil {
+		return err
+	}
+	if !bytes.Equal(header[:len(archiveMagic)], archiveMagic) {
+		return fmt.Errorf("Not a ledger archive")
+	}
+	if version := binary.BigEndian.Uint32(header[len(archiveMagic):]); version != archiveVersion {
+		return fmt.Errorf("Unsupported archive version %d, expected version %d", version, archiveVersion)
+	}
+	return nil
+}
+
+// readRecord returns the type and the payload of the next record, and the
+// hash of the bytes of the archive preceding the record
+func (archive *archiveReader) readRecord() (byte, []byte, []byte, error) {
+	sum := archive.hash.Sum(nil)
+	recordType := make([]byte, 1)
+	if err := archive.read(recordType); err != nil {
+		return 0, nil, nil, err
+	}
+	length, err := binary.ReadUvarint(archive.r)
+	if err != nil {
+		return 0, nil, nil, fmt.Errorf("Archive is truncated")
+	}
+	if length > maxArchiveRecordSize {
+		return 0, nil, nil, fmt.Errorf("Archive record of %d bytes exceeds the maximum size", length)
+	}
+	archive.hash.Write(encodeArchiveUvarints(length))
+	payload := make([]byte, length)
+	if err := archive.read(payload); err != nil {
+		return 0, nil, nil, err
+	}
+	return recordType[0], payload, sum, nil
+}
+
+func encodeArchiveUvarints(numbers ...uint64) []byte {
+	buf := make([]byte, binary.MaxVarintLen64)
+	var encoded []byte
+	for _, number := range numbers {
+		n := binary.PutUvarint(buf, number)
+		encoded = append(encoded, buf[:n]...)
+	}
+	return encoded
+}
+
+func encodeArchiveKeyValue(key []byte, value []byte) []byte {
+	payload := encodeArchiveUvarints(uint64(len(key)))
+	payload = append(payload, key...)
+	return append(payload, value...)
+}
+
+func decodeArchiveKeyValue(payload []byte) ([]byte, []byte, error) {
+	length, n := binary.Uvarint(payload)
+	if n <= 0 || uint64(len(payload)-n) < length {
+		return nil, nil, fmt.Errorf("Malformed key-value in archive")
+	}
+	return payload[n : n+int(length)], payload[n+int(length):], nil
+}
+
+// Export writes an archive of the ledger, taken from a point-in-time view of
+// the DB, so that the ledger can be exported while transactions are committed.
+// The pruned blocks are read from their archive files.
+func (ledger *Ledger) Export(w io.Writer) (*ArchiveInfo, error) {
+	openchainDB := ledger.getDB()
+	dbSnapshot := openchainDB.GetSnapshot()
+	blockchainSize, err := fetchBlockchainSizeFromSnapshot(openchainDB, dbSnapshot)
+	if err != nil {
+		dbSnapshot.Release()
+		return nil, err
+	}
+	if blockchainSize == 0 {
+		dbSnapshot.Release()
+		return nil, fmt.Errorf("Blockchain has no blocks, nothing to export")
+	}
+	// releasing the state snapshot releases the DB snapshot
+	stateSnapshot, err := ledger.state.GetSnapshot(blockchainSize-1, dbSnapshot)
+	if err != nil {
+		dbSnapshot.Release()
+		return nil, err
+	}
+	defer stateSnapshot.Release()
+
+	ledgerLogger.Infof("Exporting ledger of %d blocks", blockchainSize)
+	archive := newArchiveWriter(w)
+	if err = archive.writeHeader(); err != nil {
+		return nil, err
+	}
+	info := &ArchiveInfo{}
+	for ; info.Blocks < blockchainSize; info.Blocks++ {
+		blockBytes, err := openchainDB.GetFromBlockchainCFSnapshot(dbSnapshot, encodeBlockNumberDBKey(info.Blocks))
+		if err != nil {
+			return nil, err
+		}
+		if blockBytes == nil && info.Blocks < ledger.blockchain.pruner.getPrunedHeight() {
+			blockBytes, err = ledger.blockchain.pruner.getRawBlock(info.Blocks)
+			if err != nil {
+				return nil, err
+			}
+		}
+		if blockBytes == nil {
+			return nil, fmt.Errorf("Block %d is missing from the blockchain", info.Blocks)
+		}
+		if err = archive.writeRecord(archiveBlockRecord, blockBytes); err != nil {
+			return nil, err
+		}
+	}
+
+	for stateSnapshot.Next() {
+		key, value := stateSnapshot.GetRawKeyValue()
+		if err = archive.writeRecord(archiveStateRecord, encodeArchiveKeyValue(key, value)); err != nil {
+			return nil, err
+		}
+		info.StateEntries++
+	}
+
+	itr := openchainDB.GetHistoryCFSnapshotIterator(dbSnapshot)
+	defer itr.Close()
+	for itr.SeekToFirst(); itr.Valid(); itr.Next() {
+		if err = archive.writeRecord(archiveHistoryRecord, encodeArchiveKeyValue(itr.Key(), itr.Value())); err != nil {
+			return nil, err
+		}
+		info.HistoryEntries++
+	}
+	if err = itr.Err(); err != nil {
+		return nil, err
+	}
+
+	if err = archive.writeEnd(info); err != nil {
+		return nil, err
+	}
+	ledgerLogger.Infof("Exported ledger of %d blocks, %d state key-values and %d history entries",
+		info.Blocks, info.StateEntries, info.HistoryEntries)
+	return info, nil
+}
+
+// ExportToFile writes an archive of the ledger to a new file
+func (ledger *Ledger) ExportToFile(path string) (*ArchiveInfo, error) {
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0644)
+	if err != nil {
+		return nil, err
+	}
+	info, err := ledger.Export(file)
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(path)
+		return nil, err
+	}
+	return info, nil
+}
+
+// Import restores an archive written by Export into an empty ledger. The hash
+// chain of the blocks and the hash of the restored state are verified against
+// the last block, an error is returned if they do not match. The ledger must
+// not be used by a running peer while it is imported.
+func (ledger *Ledger) Import(r io.Reader) (*ArchiveInfo, error) {
+	if size := ledger.GetBlockchainSize(); size != 0 {
+		return nil, fmt.Errorf("Cannot import an archive into a ledger holding %d blocks", size)
+	}
+	archive := newArchiveReader(r)
+	if err := archive.readHeader(); err != nil {
+		return nil, err
+	}
+
+	info := &ArchiveInfo{}
+	var lastBlock *protos.Block
+	stateDelta := statemgmt.NewStateDelta()
+	historyBatch := ledger.getDB().NewWriteBatch()
+	defer func() {
+		historyBatch.Destroy()
+	}()
+	for {
+		recordType, payload, sum, err := archive.readRecord()
+		if err != nil {
+			return nil, err
+		}
+		switch recordType {
+		case archiveBlockRecord:
+			block, err := protos.UnmarshallBlock(payload)
+			if err != nil {
+				return nil, fmt.Errorf("Error unmarshalling block %d: %s", info.Blocks, err)
+			}
+			if err = ledger.blockchain.persistRawBlock(block, info.Blocks); err != nil {
+				return nil, err
+			}
+			lastBlock = block
+			info.Blocks++
+
+		case archiveStateRecord:
+			compositeKey, value, err := decodeArchiveKeyValue(payload)
+			if err != nil {
+				return nil, err
+			}
+			chaincodeID, key := statemgmt.DecodeCompositeKey(compositeKey)
+			stateDelta.Set(chaincodeID, key, value, nil)
+			info.StateEntries++
+			if info.StateEntries%archiveImportBatchSize == 0 {
+				if err = ledger.importStateDelta(stateDelta); err != nil {
+					return nil, err
+				}
+				stateDelta = statemgmt.NewStateDelta()
+			}
+
+		case archiveHistoryRecord:
+			key, value, err := decodeArchiveKeyValue(payload)
+			if err != nil {
+				return nil, err
+			}
+			historyBatch.PutCF(ledger.getDB().HistoryCF, key, value)
+			info.HistoryEntries++
+			if info.HistoryEntries%archiveImportBatchSize == 0 {
+				if err = ledger.getDB().Write(historyBatch); err != nil {
+					return nil, err
+				}
+				historyBatch.Destroy()
+				historyBatch = ledger.getDB().NewWriteBatch()
+			}
+
+		case archiveEndRecord:
+			if err = ledger.importStateDelta(stateDelta); err != nil {
+				return nil, err
+			}
+			if err = ledger.getDB().Write(historyBatch); err != nil {
+				return nil, err
+			}
+			if err = verifyArchiveEnd(payload, sum, info); err != nil {
+				return nil, err
+			}
+			if err = ledger.verifyImport(lastBlock); err != nil {
+				return nil, err
+			}
+			ledgerLogger.Infof("Imported ledger of %d blocks, %d state key-values and %d history entries",
+				info.Blocks, info.StateEntries, info.HistoryEntries)
+			return info, nil
+
+		default:
+			return nil, fmt.Errorf("Unknown archive record type %d", recordType)
+		}
+	}
+}
+
+// ImportFromFile restores the archive of a file into an empty ledger
+func (ledger *Ledger) ImportFromFile(path string) (*ArchiveInfo, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	return ledger.Import(file)
+}
+
+func (ledger *Ledger) importStateDelta(stateDelta *statemgmt.StateDelta) error {
+	if stateDelta.IsEmpty() {
+		return nil
+	}
+	id := "import"
+	if err := ledger.ApplyStateDelta(id, stateDelta); err != nil {
+		return err
+	}
+	return ledger.CommitStateDelta(id)
+}
+
+func verifyArchiveEnd(payload []byte, sum []byte, info *ArchiveInfo) error {
+	counts := make([]uint64, 3)
+	for i := range counts {
+		count, n := binary.Uvarint(payload)
+		if n <= 0 {
+			return fmt.Errorf("Malformed end of archive")
+		}
+		counts[i] = count
+		payload = payload[n:]
+	}
+	if !bytes.Equal(payload, sum) {
+		return fmt.Errorf("Archive is corrupted, its hash does not match")
+	}
+	if counts[0] != info.Blocks || counts[1] != info.StateEntries || counts[2] != info.HistoryEntries {
+		return fmt.Errorf("Archive is corrupted, expected %d blocks, %d state key-values and %d history entries, found %d, %d and %d",
+			counts[0], counts[1], counts[2], info.Blocks, info.StateEntries, info.HistoryEntries)
+	}
+	return nil
+}
+
+// verifyImport checks the hash chain of the imported blocks, and the hash of
+// the imported state against the state hash of the last block
+func (ledger *Ledger) verifyImport(lastBlock *protos.Block) error {
+	if lastBlock == nil {
+		return fmt.Errorf("Archive holds no blocks")
+	}
+	size := ledger.GetBlockchainSize()
+	lowestValid, err := ledger.VerifyChain(size-1, 0)
+	if err != nil {
+		return err
+	}
+	if lowestValid != 0 {
+		return fmt.Errorf("Hash chain of the imported blocks is broken at block %d", lowestValid)
+	}
+	stateHash, err := ledger.state.GetHash()
+	if err != nil {
+		return err
+	}
+	if !bytes.Equal(stateHash, lastBlock.StateHash) {
+		return fmt.Errorf("Hash of the imported state [%x] does not match the state hash of block %d [%x]",
+			stateHash, size-1, lastBlock.StateHash)
+	}
+	return nil
+}
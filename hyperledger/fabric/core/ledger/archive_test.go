@@ -0,0 +1,152 @@
+/*
+Copyright IBM Corp. 2016 All Rights Reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+		 http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ledger
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/hyperledger/fabric/core/ledger/testutil"
+	"github.com/hyperledger/fabric/protos"
+)
+
+func commitArchiveTestBlocks(t *testing.T, l *Ledger) {
+	l.BeginTxBatch(1)
+	l.TxBegin("txUUID1")
+	l.SetState("chaincodeID1", "key1", []byte("value1"))
+	l.SetState("chaincodeID1", "key2", []byte("value2"))
+	l.SetState("chaincodeID2", "key1", []byte("value3"))
+	l.TxFinished("txUUID1", true)
+	tx, _ := buildTestTx(t)
+	l.CommitTxBatch(1, []*protos.Transaction{tx}, nil, nil)
+
+	l.BeginTxBatch(2)
+	l.TxBegin("txUUID2")
+	l.SetState("chaincodeID1", "key1", []byte("value4"))
+	l.DeleteState("chaincodeID1", "key2")
+	l.TxFinished("txUUID2", true)
+	tx, _ = buildTestTx(t)
+	l.CommitTxBatch(2, []*protos.Transaction{tx}, nil, nil)
+}
+
+func exportArchiveTestLedger(t *testing.T) (*ledgerTestWrapper, []byte) {
+	ledgerTestWrapper := createFreshDBAndTestLedgerWrapper(t)
+	commitArchiveTestBlocks(t, ledgerTestWrapper.ledger)
+	var archive bytes.Buffer
+	info, err := ledgerTestWrapper.ledger.Export(&archive)
+	testutil.AssertNoError(t, err, "Error while exporting ledger")
+	testutil.AssertEquals(t, info, &ArchiveInfo{Blocks: 2, StateEntries: 2, HistoryEntries: 5})
+	return ledgerTestWrapper, archive.Bytes()
+}
+
+func TestLedgerExportImport(t *testing.T) {
+	ledgerTestWrapper, archive := exportArchiveTestLedger(t)
+	exportedInfo, err := ledgerTestWrapper.ledger.GetBlockchainInfo()
+	testutil.AssertNoError(t, err, "Error while getting blockchain info")
+	exportedBlock := ledgerTestWrapper.GetBlockByNumber(1)
+
+	ledgerTestWrapper = createFreshDBAndTestLedgerWrapper(t)
+	l := ledgerTestWrapper.ledger
+	info, err := l.Import(bytes.NewReader(archive))
+	testutil.AssertNoError(t, err, "Error while importing ledger")
+	testutil.AssertEquals(t, info, &ArchiveInfo{Blocks: 2, StateEntries: 2, HistoryEntries: 5})
+
+	importedInfo, err := l.GetBlockchainInfo()
+	testutil.AssertNoError(t, err, "Error while getting blockchain info")
+	testutil.AssertEquals(t, importedInfo, exportedInfo)
+	testutil.AssertEquals(t, ledgerTestWrapper.GetBlockByNumber(1), exportedBlock)
+	testutil.AssertEquals(t, ledgerTestWrapper.VerifyChain(1, 0), uint64(0))
+
+	testutil.AssertEquals(t, ledgerTestWrapper.GetState("chaincodeID1", "key1", true), []byte("value4"))
+	testutil.AssertNil(t, ledgerTestWrapper.GetState("chaincodeID1", "key2", true))
+	testutil.AssertEquals(t, ledgerTestWrapper.GetState("chaincodeID2", "key1", true), []byte("value3"))
+
+	itr, err := l.GetHistoryForKey("chaincodeID1", "key2")
+	testutil.AssertNoError(t, err, "Error while getting history")
+	defer itr.Close()
+	var modifications []*protos.KeyModification
+	for itr.Next() {
+		modifications = append(modifications, itr.GetKeyModification())
+	}
+	testutil.AssertNoError(t, itr.Err(), "Error while iterating history")
+	testutil.AssertEquals(t, modifications, []*protos.KeyModification{
+		&protos.KeyModification{TxID: "txUUID1", BlockNumber: 0, Value: []byte("value2")},
+		&protos.KeyModification{TxID: "txUUID2", BlockNumber: 1, IsDelete: true},
+	})
+
+	// the imported ledger keeps committing on top of the imported blocks
+	l.BeginTxBatch(3)
+	l.TxBegin("txUUID3")
+	l.SetState("chaincodeID1", "key3", []byte("value5"))
+	l.TxFinished("txUUID3", true)
+	tx, _ := buildTestTx(t)
+	testutil.AssertNoError(t, l.CommitTxBatch(3, []*protos.Transaction{tx}, nil, nil), "Error while committing")
+	testutil.AssertEquals(t, l.GetBlockchainSize(), uint64(3))
+	testutil.AssertEquals(t, ledgerTestWrapper.VerifyChain(2, 0), uint64(0))
+}
+
+func TestLedgerExportEmpty(t *testing.T) {
+	ledgerTestWrapper := createFreshDBAndTestLedgerWrapper(t)
+	var archive bytes.Buffer
+	_, err := ledgerTestWrapper.ledger.Export(&archive)
+	testutil.AssertError(t, err, "Expected an error exporting an empty ledger")
+}
+
+func TestLedgerImportNotEmpty(t *testing.T) {
+	ledgerTestWrapper, archive := exportArchiveTestLedger(t)
+	_, err := ledgerTestWrapper.ledger.Import(bytes.NewReader(archive))
+	testutil.AssertError(t, err, "Expected an error importing into a ledger holding blocks")
+}
+
+func TestLedgerImportCorrupted(t *testing.T) {
+	_, archive := exportArchiveTestLedger(t)
+
+	corrupted := append([]byte{}, archive...)
+	corrupted[len(corrupted)/2] ^= 0xff
+	ledgerTestWrapper := createFreshDBAndTestLedgerWrapper(t)
+	_, err := ledgerTestWrapper.ledger.Import(bytes.NewReader(corrupted))
+	testutil.AssertError(t, err, "Expected an error importing a corrupted archive")
+
+	ledgerTestWrapper = createFreshDBAndTestLedgerWrapper(t)
+	_, err = ledgerTestWrapper.ledger.Import(bytes.NewReader(archive[:len(archive)-1]))
+	testutil.AssertError(t, err, "Expected an error importing a truncated archive")
+
+	ledgerTestWrapper = createFreshDBAndTestLedgerWrapper(t)
+	_, err = ledgerTestWrapper.ledger.Import(bytes.NewReader([]byte("not an archive")))
+	testutil.AssertError(t, err, "Expected an error importing a file which is not an archive")
+}
+
+func TestLedgerExportImportFile(t *testing.T) {
+	ledgerTestWrapper := createFreshDBAndTestLedgerWrapper(t)
+	commitArchiveTestBlocks(t, ledgerTestWrapper.ledger)
+	dir, err := ioutil.TempDir("", "ledger-archive")
+	testutil.AssertNoError(t, err, "Error while creating temp dir")
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "ledger.archive")
+	_, err = ledgerTestWrapper.ledger.ExportToFile(path)
+	testutil.AssertNoError(t, err, "Error while exporting ledger")
+	_, err = ledgerTestWrapper.ledger.ExportToFile(path)
+	testutil.AssertError(t, err, "Expected an error exporting to an existing file")
+
+	ledgerTestWrapper = createFreshDBAndTestLedgerWrapper(t)
+	info, err := ledgerTestWrapper.ledger.ImportFromFile(path)
+	testutil.AssertNoError(t, err, "Error while importing ledger")
+	testutil.AssertEquals(t, info.Blocks, uint64(2))
+}
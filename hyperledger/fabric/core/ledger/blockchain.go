@@ -26,17 +26,19 @@ import (
 	"github.com/hyperledger/fabric/core/db"
 	"github.com/hyperledger/fabric/core/util"
 	"github.com/hyperledger/fabric/protos"
-	"github.com/tecbot/gorocksdb"
 	"golang.org/x/net/context"
 )
 
 // Blockchain holds basic information in memory. Operations on Blockchain are not thread-safe
 // TODO synchronize access to in-memory variables
 type blockchain struct {
+	chainID            string
+	openchainDB        *db.OpenchainDB // set if the blockchain is not persisted to the db of its chain
 	size               uint64
 	previousBlockHash  []byte
 	indexer            blockchainIndexer
 	lastProcessedBlock *lastProcessedBlock
+	pruner             *blockPruner
 }
 
 type lastProcessedBlock struct {
@@ -47,15 +49,24 @@ type lastProcessedBlock struct {
 
 var indexBlockDataSynchronously = true
 
-func newBlockchain() (*blockchain, error) {
-	size, err := fetchBlockchainSizeFromDB()
+func newBlockchain(chainID string) (*blockchain, error) {
+	return openBlockchain(&blockchain{chainID: chainID})
+}
+
+func newBlockchainWithDB(openchainDB *db.OpenchainDB) (*blockchain, error) {
+	return openBlockchain(&blockchain{openchainDB: openchainDB})
+}
+
+func openBlockchain(blockchain *blockchain) (*blockchain, error) {
+	chainID := blockchain.chainID
+	size, err := fetchBlockchainSizeFromDB(blockchain.getDB())
 	if err != nil {
 		return nil, err
 	}
-	blockchain := &blockchain{0, nil, nil, nil}
 	blockchain.size = size
+	blockchainHeight.With(chainID).Set(float64(size))
 	if size > 0 {
-		previousBlock, err := fetchBlockFromDB(size - 1)
+		previousBlock, err := fetchBlockFromDB(blockchain.getDB(), size-1)
 		if err != nil {
 			return nil, err
 		}
@@ -66,6 +77,11 @@ func newBlockchain() (*blockchain, error) {
 		blockchain.previousBlockHash = previousBlockHash
 	}
 
+	blockchain.pruner, err = newBlockPruner(blockchain)
+	if err != nil {
+		return nil, err
+	}
+
 	err = blockchain.startIndexer()
 	if err != nil {
 		return nil, err
@@ -96,9 +112,43 @@ func (blockchain *blockchain) getSize() uint64 {
 	return blockchain.size
 }
 
-// getBlock get block at arbitrary height in block chain
+// getBlock get block at arbitrary height in block chain, reading the pruned
+// blocks from their archive files
 func (blockchain *blockchain) getBlock(blockNumber uint64) (*protos.Block, error) {
-	return fetchBlockFromDB(blockNumber)
+	if blockNumber >= blockchain.pruner.getPrunedHeight() {
+		block, err := fetchBlockFromDB(blockchain.getDB(), blockNumber)
+		// the block may have been pruned meanwhile
+		if err != nil || block != nil || blockNumber >= blockchain.pruner.getPrunedHeight() {
+			return block, err
+		}
+	}
+	blockBytes, err := blockchain.pruner.getRawBlock(blockNumber)
+	if err != nil {
+		return nil, err
+	}
+	return protos.UnmarshallBlock(blockBytes)
+}
+
+// getBlockHashes returns the hash of a block and the hash of its previous
+// block, taken from its checkpoint if it is pruned, or nil if there is no such
+// block
+func (blockchain *blockchain) getBlockHashes(blockNumber uint64) ([]byte, []byte, error) {
+	if blockNumber < blockchain.pruner.getPrunedHeight() {
+		info, err := blockchain.pruner.getPrunedBlockInfo(blockNumber)
+		if err != nil {
+			return nil, nil, err
+		}
+		return info.CurrentBlockHash, info.PreviousBlockHash, nil
+	}
+	block, err := blockchain.getBlock(blockNumber)
+	if err != nil || block == nil {
+		return nil, nil, err
+	}
+	blockHash, err := block.GetHash()
+	if err != nil {
+		return nil, nil, err
+	}
+	return blockHash, block.PreviousBlockHash, nil
 }
 
 // getBlockByHash get block by block hash
@@ -214,7 +264,7 @@ func (blockchain *blockchain) buildBlock(block *protos.Block, stateHash []byte)
 }
 
 func (blockchain *blockchain) addPersistenceChangesForNewBlock(ctx context.Context,
-	block *protos.Block, stateHash []byte, writeBatch *gorocksdb.WriteBatch) (uint64, error) {
+	block *protos.Block, stateHash []byte, writeBatch db.WriteBatch) (uint64, error) {
 	block = blockchain.buildBlock(block, stateHash)
 	if block.NonHashData == nil {
 		block.NonHashData = &protos.NonHashData{LocalLedgerCommitTimestamp: util.CreateUtcTimestamp()}
@@ -230,8 +280,8 @@ func (blockchain *blockchain) addPersistenceChangesForNewBlock(ctx context.Conte
 	if blockBytesErr != nil {
 		return 0, blockBytesErr
 	}
-	writeBatch.PutCF(db.GetDBHandle().BlockchainCF, encodeBlockNumberDBKey(blockNumber), blockBytes)
-	writeBatch.PutCF(db.GetDBHandle().BlockchainCF, blockCountKey, encodeUint64(blockNumber+1))
+	writeBatch.PutCF(blockchain.getDB().BlockchainCF, encodeBlockNumberDBKey(blockNumber), blockBytes)
+	writeBatch.PutCF(blockchain.getDB().BlockchainCF, blockCountKey, encodeUint64(blockNumber+1))
 	if blockchain.indexer.isSynchronous() {
 		blockchain.indexer.createIndexesSync(block, blockNumber, blockHash, writeBatch)
 	}
@@ -242,6 +292,7 @@ func (blockchain *blockchain) addPersistenceChangesForNewBlock(ctx context.Conte
 func (blockchain *blockchain) blockPersistenceStatus(success bool) {
 	if success {
 		blockchain.size++
+		blockchainHeight.With(blockchain.chainID).Set(float64(blockchain.size))
 		blockchain.previousBlockHash = blockchain.lastProcessedBlock.blockHash
 		if !blockchain.indexer.isSynchronous() {
 			blockchain.indexer.createIndexesAsync(blockchain.lastProcessedBlock.block,
@@ -256,9 +307,9 @@ func (blockchain *blockchain) persistRawBlock(block *protos.Block, blockNumber u
 	if blockBytesErr != nil {
 		return blockBytesErr
 	}
-	writeBatch := gorocksdb.NewWriteBatch()
+	writeBatch := blockchain.getDB().NewWriteBatch()
 	defer writeBatch.Destroy()
-	writeBatch.PutCF(db.GetDBHandle().BlockchainCF, encodeBlockNumberDBKey(blockNumber), blockBytes)
+	writeBatch.PutCF(blockchain.getDB().BlockchainCF, encodeBlockNumberDBKey(blockNumber), blockBytes)
 
 	blockHash, err := block.GetHash()
 	if err != nil {
@@ -269,8 +320,9 @@ func (blockchain *blockchain) persistRawBlock(block *protos.Block, blockNumber u
 	// really blockchain height, not size.
 	if blockchain.getSize() < blockNumber+1 {
 		sizeBytes := encodeUint64(blockNumber + 1)
-		writeBatch.PutCF(db.GetDBHandle().BlockchainCF, blockCountKey, sizeBytes)
+		writeBatch.PutCF(blockchain.getDB().BlockchainCF, blockCountKey, sizeBytes)
 		blockchain.size = blockNumber + 1
+		blockchainHeight.With(blockchain.chainID).Set(float64(blockchain.size))
 		blockchain.previousBlockHash = blockHash
 	}
 
@@ -278,17 +330,23 @@ func (blockchain *blockchain) persistRawBlock(block *protos.Block, blockNumber u
 		blockchain.indexer.createIndexesSync(block, blockNumber, blockHash, writeBatch)
 	}
 
-	opt := gorocksdb.NewDefaultWriteOptions()
-	defer opt.Destroy()
-	err = db.GetDBHandle().DB.Write(opt, writeBatch)
+	err = blockchain.getDB().Write(writeBatch)
 	if err != nil {
 		return err
 	}
 	return nil
 }
 
-func fetchBlockFromDB(blockNumber uint64) (*protos.Block, error) {
-	blockBytes, err := db.GetDBHandle().GetFromBlockchainCF(encodeBlockNumberDBKey(blockNumber))
+// getDB returns the db of the chain
+func (blockchain *blockchain) getDB() *db.OpenchainDB {
+	if blockchain.openchainDB != nil {
+		return blockchain.openchainDB
+	}
+	return db.GetChainDBHandle(blockchain.chainID)
+}
+
+func fetchBlockFromDB(openchainDB *db.OpenchainDB, blockNumber uint64) (*protos.Block, error) {
+	blockBytes, err := openchainDB.GetFromBlockchainCF(encodeBlockNumberDBKey(blockNumber))
 	if err != nil {
 		return nil, err
 	}
@@ -298,8 +356,8 @@ func fetchBlockFromDB(blockNumber uint64) (*protos.Block, error) {
 	return protos.UnmarshallBlock(blockBytes)
 }
 
-func fetchBlockchainSizeFromDB() (uint64, error) {
-	bytes, err := db.GetDBHandle().GetFromBlockchainCF(blockCountKey)
+func fetchBlockchainSizeFromDB(openchainDB *db.OpenchainDB) (uint64, error) {
+	bytes, err := openchainDB.GetFromBlockchainCF(blockCountKey)
 	if err != nil {
 		return 0, err
 	}
@@ -309,8 +367,8 @@ func fetchBlockchainSizeFromDB() (uint64, error) {
 	return decodeToUint64(bytes), nil
 }
 
-func fetchBlockchainSizeFromSnapshot(snapshot *gorocksdb.Snapshot) (uint64, error) {
-	blockNumberBytes, err := db.GetDBHandle().GetFromBlockchainCFSnapshot(snapshot, blockCountKey)
+func fetchBlockchainSizeFromSnapshot(openchainDB *db.OpenchainDB, snapshot db.Snapshot) (uint64, error) {
+	blockNumberBytes, err := openchainDB.GetFromBlockchainCFSnapshot(snapshot, blockCountKey)
 	if err != nil {
 		return 0, err
 	}
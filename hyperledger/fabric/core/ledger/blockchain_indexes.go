@@ -23,7 +23,6 @@ import (
 	"github.com/hyperledger/fabric/core/db"
 	"github.com/hyperledger/fabric/protos"
 	"github.com/op/go-logging"
-	"github.com/tecbot/gorocksdb"
 )
 
 var indexLogger = logging.MustGetLogger("indexes")
@@ -34,7 +33,7 @@ var prefixAddressBlockNumCompositeKey = byte(3)
 type blockchainIndexer interface {
 	isSynchronous() bool
 	start(blockchain *blockchain) error
-	createIndexesSync(block *protos.Block, blockNumber uint64, blockHash []byte, writeBatch *gorocksdb.WriteBatch) error
+	createIndexesSync(block *protos.Block, blockNumber uint64, blockHash []byte, writeBatch db.WriteBatch) error
 	createIndexesAsync(block *protos.Block, blockNumber uint64, blockHash []byte) error
 	fetchBlockNumberByBlockHash(blockHash []byte) (uint64, error)
 	fetchTransactionIndexByUUID(txUUID string) (uint64, uint64, error)
@@ -43,6 +42,7 @@ type blockchainIndexer interface {
 
 // Implementation for sync indexer
 type blockchainIndexerSync struct {
+	blockchain *blockchain
 }
 
 func newBlockchainIndexerSync() *blockchainIndexerSync {
@@ -54,12 +54,13 @@ func (indexer *blockchainIndexerSync) isSynchronous() bool {
 }
 
 func (indexer *blockchainIndexerSync) start(blockchain *blockchain) error {
+	indexer.blockchain = blockchain
 	return nil
 }
 
 func (indexer *blockchainIndexerSync) createIndexesSync(
-	block *protos.Block, blockNumber uint64, blockHash []byte, writeBatch *gorocksdb.WriteBatch) error {
-	return addIndexDataForPersistence(block, blockNumber, blockHash, writeBatch)
+	block *protos.Block, blockNumber uint64, blockHash []byte, writeBatch db.WriteBatch) error {
+	return addIndexDataForPersistence(indexer.blockchain.getDB(), block, blockNumber, blockHash, writeBatch)
 }
 
 func (indexer *blockchainIndexerSync) createIndexesAsync(block *protos.Block, blockNumber uint64, blockHash []byte) error {
@@ -67,11 +68,11 @@ func (indexer *blockchainIndexerSync) createIndexesAsync(block *protos.Block, bl
 }
 
 func (indexer *blockchainIndexerSync) fetchBlockNumberByBlockHash(blockHash []byte) (uint64, error) {
-	return fetchBlockNumberByBlockHashFromDB(blockHash)
+	return fetchBlockNumberByBlockHashFromDB(indexer.blockchain.getDB(), blockHash)
 }
 
 func (indexer *blockchainIndexerSync) fetchTransactionIndexByUUID(txUUID string) (uint64, uint64, error) {
-	return fetchTransactionIndexByUUIDFromDB(txUUID)
+	return fetchTransactionIndexByUUIDFromDB(indexer.blockchain.getDB(), txUUID)
 }
 
 func (indexer *blockchainIndexerSync) stop() {
@@ -79,8 +80,7 @@ func (indexer *blockchainIndexerSync) stop() {
 }
 
 // Functions for persisting and retrieving index data
-func addIndexDataForPersistence(block *protos.Block, blockNumber uint64, blockHash []byte, writeBatch *gorocksdb.WriteBatch) error {
-	openchainDB := db.GetDBHandle()
+func addIndexDataForPersistence(openchainDB *db.OpenchainDB, block *protos.Block, blockNumber uint64, blockHash []byte, writeBatch db.WriteBatch) error {
 	cf := openchainDB.IndexesCF
 
 	// add blockhash -> blockNumber
@@ -112,9 +112,9 @@ func addIndexDataForPersistence(block *protos.Block, blockNumber uint64, blockHa
 	return nil
 }
 
-func fetchBlockNumberByBlockHashFromDB(blockHash []byte) (uint64, error) {
+func fetchBlockNumberByBlockHashFromDB(openchainDB *db.OpenchainDB, blockHash []byte) (uint64, error) {
 	indexLogger.Debugf("fetchBlockNumberByBlockHashFromDB() for blockhash [%x]", blockHash)
-	blockNumberBytes, err := db.GetDBHandle().GetFromIndexesCF(encodeBlockHashKey(blockHash))
+	blockNumberBytes, err := openchainDB.GetFromIndexesCF(encodeBlockHashKey(blockHash))
 	if err != nil {
 		return 0, err
 	}
@@ -126,8 +126,8 @@ func fetchBlockNumberByBlockHashFromDB(blockHash []byte) (uint64, error) {
 	return blockNumber, nil
 }
 
-func fetchTransactionIndexByUUIDFromDB(txUUID string) (uint64, uint64, error) {
-	blockNumTxIndexBytes, err := db.GetDBHandle().GetFromIndexesCF(encodeTxUUIDKey(txUUID))
+func fetchTransactionIndexByUUIDFromDB(openchainDB *db.OpenchainDB, txUUID string) (uint64, uint64, error) {
+	blockNumTxIndexBytes, err := openchainDB.GetFromIndexesCF(encodeTxUUIDKey(txUUID))
 	if err != nil {
 		return 0, 0, err
 	}
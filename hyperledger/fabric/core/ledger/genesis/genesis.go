@@ -32,18 +32,29 @@ var once sync.Once
 // and adds it to the blockchain.
 func MakeGenesis() error {
 	once.Do(func() {
-		ledger, err := ledger.GetLedger()
-		if err != nil {
-			makeGenesisError = err
-			return
-		}
-
-		if ledger.GetBlockchainSize() == 0 {
-			genesisLogger.Info("Creating genesis block.")
-			if makeGenesisError = ledger.BeginTxBatch(0); makeGenesisError == nil {
-				makeGenesisError = ledger.CommitTxBatch(0, nil, nil, nil)
-			}
-		}
+		makeGenesisError = MakeChainGenesis("")
 	})
 	return makeGenesisError
 }
+
+// MakeChainGenesis creates the genesis block of the chain identified by
+// chainID if its blockchain is empty
+func MakeChainGenesis(chainID string) error {
+	lgr, err := ledger.GetChainLedger(chainID)
+	if err != nil {
+		return err
+	}
+	return MakeLedgerGenesis(lgr)
+}
+
+// MakeLedgerGenesis creates the genesis block of the given ledger if its
+// blockchain is empty
+func MakeLedgerGenesis(lgr *ledger.Ledger) (err error) {
+	if lgr.GetBlockchainSize() == 0 {
+		genesisLogger.Infof("Creating genesis block of chain [%s].", lgr.GetChainID())
+		if err = lgr.BeginTxBatch(0); err == nil {
+			err = lgr.CommitTxBatch(0, nil, nil, nil)
+		}
+	}
+	return err
+}
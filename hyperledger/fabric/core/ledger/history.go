@@ -0,0 +1,139 @@
+/*
+Copyright IBM Corp. 2016 All Rights Reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+		 http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ledger
+
+import (
+	"encoding/binary"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/hyperledger/fabric/core/db"
+	"github.com/hyperledger/fabric/core/ledger/statemgmt"
+	"github.com/hyperledger/fabric/core/ledger/statemgmt/state"
+	"github.com/hyperledger/fabric/protos"
+)
+
+// The history index maps (chaincodeID, key) to every committed change of the key.
+// An entry is stored in historyCF under the key
+// chaincodeID 0x00 key 0x00 blockNumber txSeq
+// where blockNumber and txSeq (the position of the tx among the txs of the block
+// that changed the state) are 8 byte big endian numbers, so that the entries of
+// a key are sorted in commit order. The value is a marshalled protos.KeyModification.
+//
+// The blocks obtained by state transfer are indexed when their state delta is
+// played forward. The state delta of a block does not tell which of its
+// transactions changed a key, so their changes are recorded without TxID, as
+// done by a single transaction. A state snapshot carries no history at all.
+
+const historyKeySuffixLength = 16
+
+var historyKeyDelimiter = []byte{0x00}
+
+func addHistoryForPersistence(openchainDB *db.OpenchainDB, blockNumber uint64, txStateDeltas []*state.TxStateDelta, writeBatch db.WriteBatch) error {
+	for txSeq, txStateDelta := range txStateDeltas {
+		err := addDeltaHistoryForPersistence(openchainDB, blockNumber, uint64(txSeq), txStateDelta.TxUUID, txStateDelta.StateDelta, writeBatch)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func addDeltaHistoryForPersistence(openchainDB *db.OpenchainDB, blockNumber uint64, txSeq uint64, txUUID string, delta *statemgmt.StateDelta, writeBatch db.WriteBatch) error {
+	cf := openchainDB.HistoryCF
+	for _, chaincodeID := range delta.GetUpdatedChaincodeIds(false) {
+		for key, updatedValue := range delta.GetUpdates(chaincodeID) {
+			modification := &protos.KeyModification{
+				TxID:        txUUID,
+				BlockNumber: blockNumber,
+				Value:       updatedValue.GetValue(),
+				IsDelete:    updatedValue.IsDelete(),
+			}
+			modificationBytes, err := proto.Marshal(modification)
+			if err != nil {
+				return err
+			}
+			ledgerLogger.Debugf("Adding history of key [%s] of chaincode [%s] for tx [%s] in block [%d]",
+				key, chaincodeID, txUUID, blockNumber)
+			writeBatch.PutCF(cf, encodeHistoryKey(chaincodeID, key, blockNumber, txSeq), modificationBytes)
+		}
+	}
+	return nil
+}
+
+func encodeHistoryKeyPrefix(chaincodeID string, key string) []byte {
+	prefix := statemgmt.ConstructCompositeKey(chaincodeID, key)
+	return append(prefix, historyKeyDelimiter...)
+}
+
+func encodeHistoryKey(chaincodeID string, key string, blockNumber uint64, txSeq uint64) []byte {
+	historyKey := encodeHistoryKeyPrefix(chaincodeID, key)
+	suffix := make([]byte, historyKeySuffixLength)
+	binary.BigEndian.PutUint64(suffix, blockNumber)
+	binary.BigEndian.PutUint64(suffix[8:], txSeq)
+	return append(historyKey, suffix...)
+}
+
+// HistoryIterator iterates over the committed changes of a key, oldest first
+type HistoryIterator struct {
+	dbItr        db.Iterator
+	prefix       []byte
+	modification *protos.KeyModification
+	err          error
+}
+
+func newHistoryIterator(openchainDB *db.OpenchainDB, chaincodeID string, key string) *HistoryIterator {
+	prefix := encodeHistoryKeyPrefix(chaincodeID, key)
+	dbItr := openchainDB.GetHistoryCFIterator()
+	dbItr.Seek(prefix)
+	return &HistoryIterator{dbItr: dbItr, prefix: prefix}
+}
+
+// Next moves to the next change of the key. Returns false when there are no
+// more changes or an error occurred, see Err()
+func (itr *HistoryIterator) Next() bool {
+	for ; itr.err == nil && itr.dbItr.ValidForPrefix(itr.prefix); itr.dbItr.Next() {
+		// The prefix also matches the keys that extend this key with a 0x00 byte,
+		// skip their entries
+		if len(itr.dbItr.Key()) != len(itr.prefix)+historyKeySuffixLength {
+			continue
+		}
+		modification := &protos.KeyModification{}
+		if itr.err = proto.Unmarshal(itr.dbItr.Value(), modification); itr.err != nil {
+			return false
+		}
+		itr.modification = modification
+		itr.dbItr.Next()
+		return true
+	}
+	itr.modification = nil
+	return false
+}
+
+// GetKeyModification returns the change of the key the iterator is positioned at
+func (itr *HistoryIterator) GetKeyModification() *protos.KeyModification {
+	return itr.modification
+}
+
+// Err returns the error, if any, that stopped the iteration
+func (itr *HistoryIterator) Err() error {
+	return itr.err
+}
+
+// Close releases the resources held by the iterator
+func (itr *HistoryIterator) Close() {
+	itr.dbItr.Close()
+}
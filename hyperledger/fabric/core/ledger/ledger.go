@@ -21,14 +21,17 @@ import (
 	"fmt"
 	"reflect"
 	"sync"
+	"time"
+
+	google_protobuf "google/protobuf"
 
-	"github.com/golang/protobuf/proto"
 	"github.com/hyperledger/fabric/core/db"
+	"github.com/hyperledger/fabric/core/ledger/query"
 	"github.com/hyperledger/fabric/core/ledger/statemgmt"
 	"github.com/hyperledger/fabric/core/ledger/statemgmt/state"
 	"github.com/hyperledger/fabric/events/producer"
 	"github.com/op/go-logging"
-	"github.com/tecbot/gorocksdb"
+	"github.com/spf13/viper"
 
 	"github.com/hyperledger/fabric/protos"
 	"golang.org/x/net/context"
@@ -48,6 +51,8 @@ const (
 	ErrorTypeResourceNotFound = ErrorType("ResourceNotFound")
 	//ErrorTypeBlockNotFound used to indicate if a block is not found when looked up by it's hash
 	ErrorTypeBlockNotFound = ErrorType("ErrorTypeBlockNotFound")
+	//ErrorTypeBlockPruned used to indicate that a block has been pruned and cannot be read
+	ErrorTypeBlockPruned = ErrorType("BlockPruned")
 )
 
 //Error can be used for throwing an error from ledger code.
@@ -75,13 +80,23 @@ var (
 
 	// ErrResourceNotFound is returned if a resource is not found
 	ErrResourceNotFound = newLedgerError(ErrorTypeResourceNotFound, "ledger: resource not found")
+
+	// ErrHistoryNotEnabled is returned if key history is requested but the history index is disabled
+	ErrHistoryNotEnabled = newLedgerError(ErrorTypeResourceNotFound, "ledger: history index is not enabled")
 )
 
 // Ledger - the struct for openchain ledger
 type Ledger struct {
-	blockchain *blockchain
-	state      *state.State
-	currentID  interface{}
+	chainID        string
+	openchainDB    *db.OpenchainDB // set for the ledgers of NewLedger
+	blockchain     *blockchain
+	state          *state.State
+	currentID      interface{}
+	historyEnabled bool
+	// set by Verify if the state cannot be repaired locally
+	stateDamaged bool
+	// JSON fields of the documents of the state indexed for the rich queries
+	queryIndexFields []string
 }
 
 var ledger *Ledger
@@ -98,13 +113,66 @@ func GetLedger() (*Ledger, error) {
 
 // GetNewLedger - gives a reference to a new ledger TODO need better approach
 func GetNewLedger() (*Ledger, error) {
-	blockchain, err := newBlockchain()
+	return newChainLedger("")
+}
+
+var chainLedgers = make(map[string]*Ledger)
+var chainLedgersLock sync.Mutex
+
+// GetChainLedger - gives a reference to the 'singleton' ledger of the chain.
+// The empty chain ID is the default chain of GetLedger
+func GetChainLedger(chainID string) (*Ledger, error) {
+	if chainID == "" {
+		return GetLedger()
+	}
+	if err := db.ValidateChainID(chainID); err != nil {
+		return nil, newLedgerError(ErrorTypeInvalidArgument, err.Error())
+	}
+	chainLedgersLock.Lock()
+	defer chainLedgersLock.Unlock()
+	if ledger, ok := chainLedgers[chainID]; ok {
+		return ledger, nil
+	}
+	ledger, err := newChainLedger(chainID)
+	if err != nil {
+		return nil, err
+	}
+	chainLedgers[chainID] = ledger
+	return ledger, nil
+}
+
+func newChainLedger(chainID string) (*Ledger, error) {
+	blockchain, err := newBlockchain(chainID)
+	if err != nil {
+		return nil, err
+	}
+	return newLedger(chainID, nil, blockchain, state.NewChainState(chainID)), nil
+}
+
+// NewLedger returns a new ledger of the default chain persisted to the given
+// db instead of the db of the peer, so that a process can run several peers
+// each with a ledger of its own. The blocks committed to it are not sent to
+// the event producer of the process.
+func NewLedger(openchainDB *db.OpenchainDB) (*Ledger, error) {
+	blockchain, err := newBlockchainWithDB(openchainDB)
 	if err != nil {
 		return nil, err
 	}
+	return newLedger("", openchainDB, blockchain, state.NewStateWithDB(openchainDB)), nil
+}
+
+func newLedger(chainID string, openchainDB *db.OpenchainDB, blockchain *blockchain, state *state.State) *Ledger {
+	if viper.GetBool("ledger.pruning.enabled") {
+		blockchain.pruner.start(viper.GetDuration("ledger.pruning.interval"))
+	}
+	return &Ledger{chainID: chainID, openchainDB: openchainDB, blockchain: blockchain, state: state,
+		historyEnabled:   viper.GetBool("ledger.history.enabled"),
+		queryIndexFields: viper.GetStringSlice("ledger.queryIndex.fields")}
+}
 
-	state := state.NewState()
-	return &Ledger{blockchain, state, nil}, nil
+// GetChainID returns the ID of the chain of the ledger, empty for the default chain
+func (ledger *Ledger) GetChainID() string {
+	return ledger.chainID
 }
 
 /////////////////// Transaction-batch related methods ///////////////////////////////
@@ -126,6 +194,13 @@ func (ledger *Ledger) BeginTxBatch(id interface{}) error {
 // state is modified by a transaction between these two calls, the
 // contained hash will be different.
 func (ledger *Ledger) GetTXBatchPreviewBlockInfo(id interface{},
+	transactions []*protos.Transaction, metadata []byte) (*protos.BlockchainInfo, error) {
+	return ledger.GetTXBatchPreviewBlockInfoAt(id, nil, transactions, metadata)
+}
+
+// GetTXBatchPreviewBlockInfoAt returns the preview block info of the block
+// CommitTxBatchAt would commit with the timestamp
+func (ledger *Ledger) GetTXBatchPreviewBlockInfoAt(id interface{}, timestamp *google_protobuf.Timestamp,
 	transactions []*protos.Transaction, metadata []byte) (*protos.BlockchainInfo, error) {
 	err := ledger.checkValidIDCommitORRollback(id)
 	if err != nil {
@@ -135,7 +210,9 @@ func (ledger *Ledger) GetTXBatchPreviewBlockInfo(id interface{},
 	if err != nil {
 		return nil, err
 	}
-	block := ledger.blockchain.buildBlock(protos.NewBlock(transactions, metadata), stateHash)
+	block := protos.NewBlock(transactions, metadata)
+	block.Timestamp = timestamp
+	block = ledger.blockchain.buildBlock(block, stateHash)
 	info := ledger.blockchain.getBlockchainInfoForBlock(ledger.blockchain.getSize()+1, block)
 	return info, nil
 }
@@ -144,10 +221,23 @@ func (ledger *Ledger) GetTXBatchPreviewBlockInfo(id interface{},
 // This function returns successfully iff the transactions details and state changes (that
 // may have happened during execution of this transaction-batch) have been committed to permanent storage
 func (ledger *Ledger) CommitTxBatch(id interface{}, transactions []*protos.Transaction, transactionResults []*protos.TransactionResult, metadata []byte) error {
-	err := ledger.checkValidIDCommitORRollback(id)
+	return ledger.CommitTxBatchAt(id, nil, transactions, transactionResults, metadata)
+}
+
+// CommitTxBatchAt commits the current transaction-batch as CommitTxBatch does,
+// the timestamp of the block being the time the consensus agreed on for it
+func (ledger *Ledger) CommitTxBatchAt(id interface{}, timestamp *google_protobuf.Timestamp, transactions []*protos.Transaction, transactionResults []*protos.TransactionResult, metadata []byte) (err error) {
+	err = ledger.checkValidIDCommitORRollback(id)
 	if err != nil {
 		return err
 	}
+	defer func(start time.Time) {
+		if err != nil {
+			commitFailures.Inc()
+			return
+		}
+		commitDuration.ObserveSince(start)
+	}(time.Now())
 
 	stateHash, err := ledger.state.GetHash()
 	if err != nil {
@@ -156,9 +246,10 @@ func (ledger *Ledger) CommitTxBatch(id interface{}, transactions []*protos.Trans
 		return err
 	}
 
-	writeBatch := gorocksdb.NewWriteBatch()
+	writeBatch := ledger.getDB().NewWriteBatch()
 	defer writeBatch.Destroy()
 	block := protos.NewBlock(transactions, metadata)
+	block.Timestamp = timestamp
 	block.NonHashData = &protos.NonHashData{TransactionResults: transactionResults}
 	newBlockNumber, err := ledger.blockchain.addPersistenceChangesForNewBlock(context.TODO(), block, stateHash, writeBatch)
 	if err != nil {
@@ -167,9 +258,21 @@ func (ledger *Ledger) CommitTxBatch(id interface{}, transactions []*protos.Trans
 		return err
 	}
 	ledger.state.AddChangesForPersistence(newBlockNumber, writeBatch)
-	opt := gorocksdb.NewDefaultWriteOptions()
-	defer opt.Destroy()
-	dbErr := db.GetDBHandle().DB.Write(opt, writeBatch)
+	if ledger.historyEnabled {
+		err = addHistoryForPersistence(ledger.getDB(), newBlockNumber, ledger.state.GetTxStateDeltas(), writeBatch)
+		if err != nil {
+			ledger.resetForNextTxGroup(false)
+			ledger.blockchain.blockPersistenceStatus(false)
+			return err
+		}
+	}
+	err = addQueryIndexesForPersistence(ledger.getDB(), ledger.queryIndexFields, ledger.state, writeBatch)
+	if err != nil {
+		ledger.resetForNextTxGroup(false)
+		ledger.blockchain.blockPersistenceStatus(false)
+		return err
+	}
+	dbErr := ledger.getDB().Write(writeBatch)
 	if dbErr != nil {
 		ledger.resetForNextTxGroup(false)
 		ledger.blockchain.blockPersistenceStatus(false)
@@ -179,7 +282,7 @@ func (ledger *Ledger) CommitTxBatch(id interface{}, transactions []*protos.Trans
 	ledger.resetForNextTxGroup(true)
 	ledger.blockchain.blockPersistenceStatus(true)
 
-	sendProducerBlockEvent(block)
+	ledger.sendProducerBlockEvent(newBlockNumber, block)
 	return nil
 }
 
@@ -206,6 +309,26 @@ func (ledger *Ledger) TxFinished(txUUID string, txSuccessful bool) {
 	ledger.state.TxFinish(txUUID, txSuccessful)
 }
 
+// TxBeginParallel - Marks the begin of a transaction executed concurrently with other transactions
+// of the ongoing batch. Its reads and changes, made through the GetTxState, GetTxStateRangeScanIterator,
+// SetTxState and DeleteTxState methods, are tracked apart until TxFinishParallel
+func (ledger *Ledger) TxBeginParallel(txUUID string) {
+	ledger.state.TxBeginParallel(txUUID)
+}
+
+// TxFinishParallel - Marks the completion of a transaction begun with TxBeginParallel. The transactions
+// must finish in batch order. If false is returned the transaction read state changed by the transactions
+// finished meanwhile, its changes are discarded and it has to be executed again
+func (ledger *Ledger) TxFinishParallel(txUUID string) bool {
+	return ledger.state.TxFinishParallel(txUUID)
+}
+
+// AbortParallelTx - Marks a transaction begun with TxBeginParallel as to be executed again.
+// Returns false if the transaction is not executed in parallel
+func (ledger *Ledger) AbortParallelTx(txUUID string) bool {
+	return ledger.state.AbortParallelTx(txUUID)
+}
+
 /////////////////// world-state related methods /////////////////////////////////////
 /////////////////////////////////////////////////////////////////////////////////////
 
@@ -252,11 +375,70 @@ func (ledger *Ledger) DeleteState(chaincodeID string, key string) error {
 	return ledger.state.Delete(chaincodeID, key)
 }
 
-// CopyState copies all the key-values from sourceChaincodeID to destChaincodeID
+// GetTxState gets state for chaincodeID and key as seen by the transaction txUUID, see TxBeginParallel.
+// This is the same as GetState for a transaction not executed in parallel
+func (ledger *Ledger) GetTxState(txUUID string, chaincodeID string, key string, committed bool) ([]byte, error) {
+	return ledger.state.TxGet(txUUID, chaincodeID, key, committed)
+}
+
+// GetTxStateRangeScanIterator returns an iterator over the key-values between startKey and endKey as seen
+// by the transaction txUUID, see TxBeginParallel. This is the same as GetStateRangeScanIterator for a
+// transaction not executed in parallel
+func (ledger *Ledger) GetTxStateRangeScanIterator(txUUID string, chaincodeID string, startKey string, endKey string, committed bool) (statemgmt.RangeScanIterator, error) {
+	return ledger.state.TxGetRangeScanIterator(txUUID, chaincodeID, startKey, endKey, committed)
+}
+
+// SetTxState sets state to given value for chaincodeID and key in the context of the transaction txUUID,
+// see TxBeginParallel. This is the same as SetState for a transaction not executed in parallel
+func (ledger *Ledger) SetTxState(txUUID string, chaincodeID string, key string, value []byte) error {
+	if key == "" || value == nil {
+		return newLedgerError(ErrorTypeInvalidArgument,
+			fmt.Sprintf("An empty string key or a nil value is not supported. Method invoked with key='%s', value='%#v'", key, value))
+	}
+	return ledger.state.TxSet(txUUID, chaincodeID, key, value)
+}
+
+// DeleteTxState tracks the deletion of state for chaincodeID and key in the context of the transaction
+// txUUID, see TxBeginParallel. This is the same as DeleteState for a transaction not executed in parallel
+func (ledger *Ledger) DeleteTxState(txUUID string, chaincodeID string, key string) error {
+	return ledger.state.TxDelete(txUUID, chaincodeID, key)
+}
+
+// CopyState copies all the key-values from sourceChaincodeID to destChaincodeID,
+// including the changes of sourceChaincodeID not committed yet
 func (ledger *Ledger) CopyState(sourceChaincodeID string, destChaincodeID string) error {
 	return ledger.state.CopyState(sourceChaincodeID, destChaincodeID)
 }
 
+// GetHistoryForKey returns an iterator over the committed changes of the key for
+// chaincodeID, oldest first. Only the changes committed while the history index
+// ('ledger.history.enabled') was enabled are returned. The changes of the blocks
+// obtained by state transfer are returned without TxID, see AddStateDeltaToHistory.
+// You must call iterator.Close() once you are done with the iterator.
+func (ledger *Ledger) GetHistoryForKey(chaincodeID string, key string) (*HistoryIterator, error) {
+	if !ledger.historyEnabled {
+		return nil, ErrHistoryNotEnabled
+	}
+	return newHistoryIterator(ledger.getDB(), chaincodeID, key), nil
+}
+
+// GetQueryResult returns an iterator over the committed key-values of
+// chaincodeID whose values are JSON documents matching the rich query, see
+// package query for its syntax. The query scans the state of chaincodeID,
+// unless it constrains one of the fields indexed for the rich queries
+// ('ledger.queryIndex.fields').
+func (ledger *Ledger) GetQueryResult(chaincodeID string, queryString string) (statemgmt.RangeScanIterator, error) {
+	q, err := query.Parse(queryString)
+	if err != nil {
+		return nil, newLedgerError(ErrorTypeInvalidArgument, err.Error())
+	}
+	kvs, err := executeQuery(ledger.getDB(), ledger.queryIndexFields, chaincodeID, q, ledger.state)
+	if err != nil {
+		return nil, err
+	}
+	return newQueryResultIterator(kvs), nil
+}
+
 // GetStateMultipleKeys returns the values for the multiple keys.
 // This method is mainly to amortize the cost of grpc communication between chaincode shim peer
 func (ledger *Ledger) GetStateMultipleKeys(chaincodeID string, keys []string, committed bool) ([][]byte, error) {
@@ -273,8 +455,8 @@ func (ledger *Ledger) SetStateMultipleKeys(chaincodeID string, kvs map[string][]
 // should be used when transferring the state from one peer to another peer. You must call
 // stateSnapshot.Release() once you are done with the snapsnot to free up resources.
 func (ledger *Ledger) GetStateSnapshot() (*state.StateSnapshot, error) {
-	dbSnapshot := db.GetDBHandle().GetSnapshot()
-	blockHeight, err := fetchBlockchainSizeFromSnapshot(dbSnapshot)
+	dbSnapshot := ledger.getDB().GetSnapshot()
+	blockHeight, err := fetchBlockchainSizeFromSnapshot(ledger.getDB(), dbSnapshot)
 	if err != nil {
 		dbSnapshot.Release()
 		return nil, err
@@ -318,6 +500,12 @@ func (ledger *Ledger) ApplyStateDelta(id interface{}, delta *statemgmt.StateDelt
 	if err != nil {
 		return err
 	}
+	// the query indexes are built again once the state is changed by a batch,
+	// as the delta may roll the state backwards
+	err = invalidateQueryIndexes(ledger.getDB(), ledger.queryIndexFields, delta.GetUpdatedChaincodeIds(false))
+	if err != nil {
+		return err
+	}
 	ledger.currentID = id
 	ledger.state.ApplyStateDelta(delta)
 	return nil
@@ -331,7 +519,13 @@ func (ledger *Ledger) CommitStateDelta(id interface{}) error {
 		return err
 	}
 	defer ledger.resetForNextTxGroup(true)
-	return ledger.state.CommitStateDelta()
+	if err = ledger.state.CommitStateDelta(); err != nil {
+		return err
+	}
+	if ledger.stateDamaged {
+		ledger.stateDamaged = !ledger.isStateAtLastBlock()
+	}
+	return nil
 }
 
 // RollbackStateDelta will discard the state delta passed
@@ -362,7 +556,9 @@ func (ledger *Ledger) GetBlockchainInfo() (*protos.BlockchainInfo, error) {
 }
 
 // GetBlockByNumber return block given the number of the block on blockchain.
-// Lowest block on chain is block number zero
+// Lowest block on chain is block number zero. Pruned blocks are read from
+// their archive files, an error of type ErrorTypeBlockPruned is returned if
+// the archive file is not available.
 func (ledger *Ledger) GetBlockByNumber(blockNumber uint64) (*protos.Block, error) {
 	if blockNumber >= ledger.GetBlockchainSize() {
 		return nil, ErrOutOfBounds
@@ -370,6 +566,25 @@ func (ledger *Ledger) GetBlockByNumber(blockNumber uint64) (*protos.Block, error
 	return ledger.blockchain.getBlock(blockNumber)
 }
 
+// GetPrunedHeight returns the number of blocks which have been pruned from the
+// DB into archive files
+func (ledger *Ledger) GetPrunedHeight() uint64 {
+	return ledger.blockchain.pruner.getPrunedHeight()
+}
+
+// PruneBlocks prunes the blocks according to the retention policy configured
+// under ledger.pruning, as the background pruning does, and returns the new
+// pruned height
+func (ledger *Ledger) PruneBlocks() (uint64, error) {
+	return ledger.blockchain.pruner.prune()
+}
+
+// StopPruning stops the background pruning enabled by ledger.pruning.enabled,
+// waiting for the pruning in progress if any
+func (ledger *Ledger) StopPruning() {
+	ledger.blockchain.pruner.stop()
+}
+
 // GetBlockchainSize returns number of blocks in blockchain
 func (ledger *Ledger) GetBlockchainSize() uint64 {
 	return ledger.blockchain.getSize()
@@ -385,6 +600,22 @@ func (ledger *Ledger) GetTransactionResultByUUID(txUUID string) (*protos.Transac
 	return ledger.blockchain.getTransactionResultByUUID(txUUID)
 }
 
+// AddStateDeltaToHistory adds the changes of the state delta of the block, played
+// forward by state transfer, to the history index if it is enabled. A raw block
+// carries no state changes, its history is only known once its state delta is
+// applied.
+func (ledger *Ledger) AddStateDeltaToHistory(blockNumber uint64, delta *statemgmt.StateDelta) error {
+	if !ledger.historyEnabled {
+		return nil
+	}
+	writeBatch := ledger.getDB().NewWriteBatch()
+	defer writeBatch.Destroy()
+	if err := addDeltaHistoryForPersistence(ledger.getDB(), blockNumber, 0, "", delta, writeBatch); err != nil {
+		return err
+	}
+	return ledger.getDB().Write(writeBatch)
+}
+
 // PutRawBlock puts a raw block on the chain. This function should only be
 // used for synchronization between peers.
 func (ledger *Ledger) PutRawBlock(block *protos.Block, blockNumber uint64) error {
@@ -392,7 +623,7 @@ func (ledger *Ledger) PutRawBlock(block *protos.Block, blockNumber uint64) error
 	if err != nil {
 		return err
 	}
-	sendProducerBlockEvent(block)
+	ledger.sendProducerBlockEvent(blockNumber, block)
 	return nil
 }
 
@@ -418,30 +649,27 @@ func (ledger *Ledger) VerifyChain(highBlock, lowBlock uint64) (uint64, error) {
 		return lowBlock, ErrOutOfBounds
 	}
 
-	currentBlock, err := ledger.GetBlockByNumber(highBlock)
+	// the hashes of the pruned blocks are taken from their checkpoints
+	currentBlockHash, currentPreviousBlockHash, err := ledger.blockchain.getBlockHashes(highBlock)
 	if err != nil {
 		return highBlock, fmt.Errorf("Error fetching block %d.", highBlock)
 	}
-	if currentBlock == nil {
+	if currentBlockHash == nil {
 		return highBlock, fmt.Errorf("Block %d is nil.", highBlock)
 	}
 
 	for i := highBlock; i > lowBlock; i-- {
-		previousBlock, err := ledger.GetBlockByNumber(i - 1)
+		previousBlockHash, previousPreviousBlockHash, err := ledger.blockchain.getBlockHashes(i - 1)
 		if err != nil {
 			return i, nil
 		}
-		if previousBlock == nil {
-			return i, nil
-		}
-		previousBlockHash, err := previousBlock.GetHash()
-		if err != nil {
+		if previousBlockHash == nil {
 			return i, nil
 		}
-		if bytes.Compare(previousBlockHash, currentBlock.PreviousBlockHash) != 0 {
+		if bytes.Compare(previousBlockHash, currentPreviousBlockHash) != 0 {
 			return i, nil
 		}
-		currentBlock = previousBlock
+		currentPreviousBlockHash = previousPreviousBlockHash
 	}
 
 	return lowBlock, nil
@@ -467,45 +695,23 @@ func (ledger *Ledger) resetForNextTxGroup(txCommited bool) {
 	ledger.state.ClearInMemoryChanges(txCommited)
 }
 
-func sendProducerBlockEvent(block *protos.Block) {
-
-	// Remove payload from deploy transactions. This is done to make block
-	// events more lightweight as the payload for these types of transactions
-	// can be very large.
-	blockTransactions := block.GetTransactions()
-	for _, transaction := range blockTransactions {
-		if transaction.Type == protos.Transaction_CHAINCODE_DEPLOY {
-			deploymentSpec := &protos.ChaincodeDeploymentSpec{}
-			err := proto.Unmarshal(transaction.Payload, deploymentSpec)
-			if err != nil {
-				ledgerLogger.Errorf("Error unmarshalling deployment transaction for block event: %s", err)
-				continue
-			}
-			deploymentSpec.CodePackage = nil
-			deploymentSpecBytes, err := proto.Marshal(deploymentSpec)
-			if err != nil {
-				ledgerLogger.Errorf("Error marshalling deployment transaction for block event: %s", err)
-				continue
-			}
-			transaction.Payload = deploymentSpecBytes
-		}
+func (ledger *Ledger) getDB() *db.OpenchainDB {
+	if ledger.openchainDB != nil {
+		return ledger.openchainDB
 	}
+	return db.GetChainDBHandle(ledger.chainID)
+}
 
-	producer.Send(producer.CreateBlockEvent(block))
-
-	//when we send block event, send chaincode events as well
-	sendChaincodeEvents(block)
+// GetDB returns the db the ledger is persisted to
+func (ledger *Ledger) GetDB() *db.OpenchainDB {
+	return ledger.getDB()
 }
 
-//send chaincode events created by transactions in the block
-func sendChaincodeEvents(block *protos.Block) {
-	nonHashData := block.GetNonHashData()
-	if nonHashData != nil {
-		trs := nonHashData.GetTransactionResults()
-		for _, tr := range trs {
-			if tr.ChaincodeEvent != nil {
-				producer.Send(producer.CreateChaincodeEvent(tr.ChaincodeEvent))
-			}
-		}
+func (ledger *Ledger) sendProducerBlockEvent(blockNumber uint64, block *protos.Block) {
+	if ledger.openchainDB != nil {
+		return
+	}
+	if err := producer.SendBlockEvents(ledger.chainID, blockNumber, block); err != nil {
+		ledgerLogger.Errorf("Error sending events of block %d: %s", blockNumber, err)
 	}
 }
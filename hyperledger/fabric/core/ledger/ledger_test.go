@@ -18,11 +18,17 @@ package ledger
 
 import (
 	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
 	"strconv"
 	"testing"
 
+	"github.com/hyperledger/fabric/core/db"
+	"github.com/hyperledger/fabric/core/ledger/query"
 	"github.com/hyperledger/fabric/core/ledger/statemgmt"
 	"github.com/hyperledger/fabric/core/ledger/testutil"
+	"github.com/hyperledger/fabric/core/util"
 	"github.com/hyperledger/fabric/protos"
 )
 
@@ -683,6 +689,27 @@ func TestPreviewTXBatchBlock(t *testing.T) {
 	testutil.AssertEquals(t, previewBlockInfo, commitedBlockInfo)
 }
 
+func TestPreviewTXBatchBlockAt(t *testing.T) {
+	ledgerTestWrapper := createFreshDBAndTestLedgerWrapper(t)
+	ledger := ledgerTestWrapper.ledger
+
+	ledger.BeginTxBatch(0)
+	ledger.TxBegin("txUuid1")
+	ledger.SetState("chaincode1", "key1", []byte("value1A"))
+	ledger.TxFinished("txUuid1", true)
+	transaction, _ := buildTestTx(t)
+	timestamp := util.CreateUtcTimestamp()
+
+	previewBlockInfo, err := ledger.GetTXBatchPreviewBlockInfoAt(0, timestamp, []*protos.Transaction{transaction}, []byte("proof"))
+	testutil.AssertNoError(t, err, "Error fetching preview block info.")
+
+	ledger.CommitTxBatchAt(0, timestamp, []*protos.Transaction{transaction}, nil, []byte("proof"))
+	commitedBlockInfo, err := ledger.GetBlockchainInfo()
+	testutil.AssertNoError(t, err, "Error fetching committed block hash.")
+
+	testutil.AssertEquals(t, previewBlockInfo, commitedBlockInfo)
+}
+
 func TestGetTransactionByUUID(t *testing.T) {
 	ledgerTestWrapper := createFreshDBAndTestLedgerWrapper(t)
 	ledger := ledgerTestWrapper.ledger
@@ -900,6 +927,32 @@ func TestCopyState(t *testing.T) {
 	testutil.AssertEquals(t, values, [][]byte{[]byte("value1"), []byte("value2"), []byte("value3")})
 }
 
+func TestCopyStateUncommitted(t *testing.T) {
+	ledgerTestWrapper := createFreshDBAndTestLedgerWrapper(t)
+	l := ledgerTestWrapper.ledger
+	l.BeginTxBatch(1)
+	l.TxBegin("txUUID")
+	l.SetStateMultipleKeys("chaincodeID1", map[string][]byte{"key1": []byte("value1"), "key2": []byte("value2")})
+	l.TxFinished("txUUID", true)
+	tx, _ := buildTestTx(t)
+	l.CommitTxBatch(1, []*protos.Transaction{tx}, nil, nil)
+
+	l.BeginTxBatch(2)
+	l.TxBegin("txUUID1")
+	l.SetState("chaincodeID1", "key3", []byte("value3"))
+	l.DeleteState("chaincodeID1", "key1")
+	l.TxFinished("txUUID1", true)
+	l.TxBegin("txUUID2")
+	l.SetState("chaincodeID1", "key2", []byte("value2_new"))
+	l.CopyState("chaincodeID1", "chaincodeID2")
+	l.TxFinished("txUUID2", true)
+	tx, _ = buildTestTx(t)
+	l.CommitTxBatch(2, []*protos.Transaction{tx}, nil, nil)
+
+	values, _ := l.GetStateMultipleKeys("chaincodeID2", []string{"key1", "key2", "key3"}, true)
+	testutil.AssertEquals(t, values, [][]byte{nil, []byte("value2_new"), []byte("value3")})
+}
+
 func TestLedgerEmptyArrayValue(t *testing.T) {
 	ledgerTestWrapper := createFreshDBAndTestLedgerWrapper(t)
 	l := ledgerTestWrapper.ledger
@@ -950,3 +1003,277 @@ func TestLedgerInvalidInput(t *testing.T) {
 	value, _ := l.GetState("chaincodeID1", "key1", true)
 	testutil.AssertEquals(t, value, []byte("value1"))
 }
+
+func TestGetHistoryForKey(t *testing.T) {
+	ledgerTestWrapper := createFreshDBAndTestLedgerWrapper(t)
+	l := ledgerTestWrapper.ledger
+
+	l.BeginTxBatch(1)
+	l.TxBegin("txUUID1")
+	l.SetState("chaincodeID1", "key1", []byte("value1"))
+	l.SetState("chaincodeID1", "key1\x00suffix", []byte("other"))
+	l.SetState("chaincodeID2", "key1", []byte("other"))
+	l.TxFinished("txUUID1", true)
+	l.TxBegin("txUUID2")
+	l.SetState("chaincodeID1", "key1", []byte("value2"))
+	l.TxFinished("txUUID2", true)
+	l.TxBegin("txUUID3")
+	l.SetState("chaincodeID1", "key1", []byte("failed"))
+	l.TxFinished("txUUID3", false)
+	tx, _ := buildTestTx(t)
+	l.CommitTxBatch(1, []*protos.Transaction{tx}, nil, nil)
+
+	l.BeginTxBatch(2)
+	l.TxBegin("txUUID4")
+	l.DeleteState("chaincodeID1", "key1")
+	l.TxFinished("txUUID4", true)
+	tx, _ = buildTestTx(t)
+	l.CommitTxBatch(2, []*protos.Transaction{tx}, nil, nil)
+
+	itr, err := l.GetHistoryForKey("chaincodeID1", "key1")
+	testutil.AssertNoError(t, err, "Error while getting history")
+	defer itr.Close()
+	var modifications []*protos.KeyModification
+	for itr.Next() {
+		modifications = append(modifications, itr.GetKeyModification())
+	}
+	testutil.AssertNoError(t, itr.Err(), "Error while iterating history")
+	testutil.AssertEquals(t, modifications, []*protos.KeyModification{
+		&protos.KeyModification{TxID: "txUUID1", BlockNumber: 0, Value: []byte("value1")},
+		&protos.KeyModification{TxID: "txUUID2", BlockNumber: 0, Value: []byte("value2")},
+		&protos.KeyModification{TxID: "txUUID4", BlockNumber: 1, IsDelete: true},
+	})
+
+	itr2, err := l.GetHistoryForKey("chaincodeID1", "non-existing-key")
+	testutil.AssertNoError(t, err, "Error while getting history")
+	defer itr2.Close()
+	testutil.AssertEquals(t, itr2.Next(), false)
+}
+
+func TestAddStateDeltaToHistory(t *testing.T) {
+	ledgerTestWrapper := createFreshDBAndTestLedgerWrapper(t)
+	l := ledgerTestWrapper.ledger
+
+	delta := statemgmt.NewStateDelta()
+	delta.Set("chaincodeID1", "key1", []byte("value1"), nil)
+	delta.Set("chaincodeID1", "key2", []byte("value2"), nil)
+	l.ApplyStateDelta(1, delta)
+	l.CommitStateDelta(1)
+	testutil.AssertNoError(t, l.AddStateDeltaToHistory(3, delta), "Error while adding state delta to history")
+
+	delta = statemgmt.NewStateDelta()
+	delta.Delete("chaincodeID1", "key1", []byte("value1"))
+	l.ApplyStateDelta(2, delta)
+	l.CommitStateDelta(2)
+	testutil.AssertNoError(t, l.AddStateDeltaToHistory(4, delta), "Error while adding state delta to history")
+
+	itr, err := l.GetHistoryForKey("chaincodeID1", "key1")
+	testutil.AssertNoError(t, err, "Error while getting history")
+	defer itr.Close()
+	var modifications []*protos.KeyModification
+	for itr.Next() {
+		modifications = append(modifications, itr.GetKeyModification())
+	}
+	testutil.AssertNoError(t, itr.Err(), "Error while iterating history")
+	testutil.AssertEquals(t, modifications, []*protos.KeyModification{
+		&protos.KeyModification{BlockNumber: 3, Value: []byte("value1")},
+		&protos.KeyModification{BlockNumber: 4, IsDelete: true},
+	})
+}
+
+func TestGetHistoryForKeyDisabled(t *testing.T) {
+	ledgerTestWrapper := createFreshDBAndTestLedgerWrapper(t)
+	l := ledgerTestWrapper.ledger
+	l.historyEnabled = false
+
+	l.BeginTxBatch(1)
+	l.TxBegin("txUUID")
+	l.SetState("chaincodeID1", "key1", []byte("value1"))
+	l.TxFinished("txUUID", true)
+	tx, _ := buildTestTx(t)
+	l.CommitTxBatch(1, []*protos.Transaction{tx}, nil, nil)
+
+	_, err := l.GetHistoryForKey("chaincodeID1", "key1")
+	testutil.AssertSame(t, err, ErrHistoryNotEnabled)
+}
+
+func getQueryResultKeys(t *testing.T, l *Ledger, chaincodeID string, query string) []string {
+	itr, err := l.GetQueryResult(chaincodeID, query)
+	testutil.AssertNoError(t, err, "Error while getting query result")
+	defer itr.Close()
+	keys := []string{}
+	for itr.Next() {
+		key, _ := itr.GetKeyValue()
+		keys = append(keys, key)
+	}
+	return keys
+}
+
+func TestGetQueryResult(t *testing.T) {
+	ledgerTestWrapper := createFreshDBAndTestLedgerWrapper(t)
+	l := ledgerTestWrapper.ledger
+
+	l.BeginTxBatch(1)
+	l.TxBegin("txUUID1")
+	l.SetState("chaincodeID1", "member1", []byte(`{"docType":"Member","name":"bob","age":40}`))
+	l.SetState("chaincodeID1", "member2", []byte(`{"docType":"Member","name":"alice","age":30}`))
+	l.SetState("chaincodeID1", "project1", []byte(`{"docType":"Project","name":"ledger"}`))
+	l.SetState("chaincodeID1", "raw", []byte("not a document"))
+	l.SetState("chaincodeID2", "member3", []byte(`{"docType":"Member","name":"carol","age":50}`))
+	l.TxFinished("txUUID1", true)
+	tx, _ := buildTestTx(t)
+	l.CommitTxBatch(1, []*protos.Transaction{tx}, nil, nil)
+
+	// uncommitted changes are not returned
+	l.BeginTxBatch(2)
+	l.TxBegin("txUUID2")
+	l.SetState("chaincodeID1", "member4", []byte(`{"docType":"Member","name":"dave","age":20}`))
+
+	testutil.AssertEquals(t, getQueryResultKeys(t, l, "chaincodeID1", `{"selector":{"docType":"Member"}}`), []string{"member1", "member2"})
+	testutil.AssertEquals(t, getQueryResultKeys(t, l, "chaincodeID1", `{"selector":{"docType":"Member"},"sort":["name"]}`), []string{"member2", "member1"})
+	testutil.AssertEquals(t, getQueryResultKeys(t, l, "chaincodeID1", `{"selector":{"age":{"$gt":35}}}`), []string{"member1"})
+	testutil.AssertEquals(t, getQueryResultKeys(t, l, "chaincodeID1", `{"selector":{"$or":[{"docType":"Project"},{"name":"alice"}]},"limit":1}`), []string{"member2"})
+
+	l.TxFinished("txUUID2", false)
+	l.RollbackTxBatch(2)
+
+	_, err := l.GetQueryResult("chaincodeID1", `{"docType":"Member"}`)
+	testutil.AssertError(t, err, "Expected an error for a query without selector")
+}
+
+func TestGetQueryResultWithIndex(t *testing.T) {
+	ledgerTestWrapper := createFreshDBAndTestLedgerWrapper(t)
+	l := ledgerTestWrapper.ledger
+
+	// the state committed before the field is indexed is indexed by the next batch
+	l.BeginTxBatch(1)
+	l.TxBegin("txUUID1")
+	l.SetState("chaincodeID1", "member1", []byte(`{"docType":"Member","age":40}`))
+	l.SetState("chaincodeID1", "member2", []byte(`{"docType":"Member","age":30}`))
+	l.TxFinished("txUUID1", true)
+	tx, _ := buildTestTx(t)
+	l.CommitTxBatch(1, []*protos.Transaction{tx}, nil, nil)
+
+	l.queryIndexFields = []string{"age"}
+	built, _ := isQueryIndexBuilt(db.GetDBHandle(), "chaincodeID1", "age")
+	testutil.AssertEquals(t, built, false)
+	testutil.AssertEquals(t, getQueryResultKeys(t, l, "chaincodeID1", `{"selector":{"age":{"$lt":35}}}`), []string{"member2"})
+
+	l.BeginTxBatch(2)
+	l.TxBegin("txUUID2")
+	l.SetState("chaincodeID1", "member3", []byte(`{"docType":"Member","age":-5}`))
+	l.SetState("chaincodeID1", "member1", []byte(`{"docType":"Member","age":20}`))
+	l.TxFinished("txUUID2", true)
+	tx, _ = buildTestTx(t)
+	l.CommitTxBatch(2, []*protos.Transaction{tx}, nil, nil)
+
+	built, _ = isQueryIndexBuilt(db.GetDBHandle(), "chaincodeID1", "age")
+	testutil.AssertEquals(t, built, true)
+	keys, _ := getQueryIndexKeys(db.GetDBHandle(), "chaincodeID1", "age", &query.Range{Upper: float64(35)})
+	testutil.AssertEquals(t, keys, []string{"member1", "member2", "member3"})
+	keys, _ = getQueryIndexKeys(db.GetDBHandle(), "chaincodeID1", "age", &query.Range{Lower: float64(30), Upper: float64(30)})
+	testutil.AssertEquals(t, keys, []string{"member2"})
+	keys, _ = getQueryIndexKeys(db.GetDBHandle(), "chaincodeID1", "age", &query.Range{Lower: "30"})
+	testutil.AssertEquals(t, keys, []string{})
+
+	testutil.AssertEquals(t, getQueryResultKeys(t, l, "chaincodeID1", `{"selector":{"age":{"$lt":35}},"sort":["age"]}`), []string{"member3", "member1", "member2"})
+	testutil.AssertEquals(t, getQueryResultKeys(t, l, "chaincodeID1", `{"selector":{"age":{"$gte":0,"$lte":25}}}`), []string{"member1"})
+	testutil.AssertEquals(t, getQueryResultKeys(t, l, "chaincodeID1", `{"selector":{"age":40}}`), []string{})
+
+	// deleted keys are removed from the index
+	l.BeginTxBatch(3)
+	l.TxBegin("txUUID3")
+	l.DeleteState("chaincodeID1", "member2")
+	l.TxFinished("txUUID3", true)
+	tx, _ = buildTestTx(t)
+	l.CommitTxBatch(3, []*protos.Transaction{tx}, nil, nil)
+	keys, _ = getQueryIndexKeys(db.GetDBHandle(), "chaincodeID1", "age", &query.Range{Lower: float64(30)})
+	testutil.AssertEquals(t, keys, []string{})
+
+	// applying a state delta invalidates the index
+	delta := statemgmt.NewStateDelta()
+	delta.Set("chaincodeID1", "member4", []byte(`{"docType":"Member","age":10}`), nil)
+	l.ApplyStateDelta(4, delta)
+	l.CommitStateDelta(4)
+	built, _ = isQueryIndexBuilt(db.GetDBHandle(), "chaincodeID1", "age")
+	testutil.AssertEquals(t, built, false)
+	testutil.AssertEquals(t, getQueryResultKeys(t, l, "chaincodeID1", `{"selector":{"age":{"$lt":15}}}`), []string{"member3", "member4"})
+}
+
+func TestChainLedgers(t *testing.T) {
+	ledgerTestWrapper := createFreshDBAndTestLedgerWrapper(t)
+	defaultLedger := ledgerTestWrapper.ledger
+	chainLedger, err := newChainLedger("chain1")
+	testutil.AssertNoError(t, err, "Error creating chain ledger")
+	testutil.AssertEquals(t, chainLedger.GetChainID(), "chain1")
+
+	chainLedger.BeginTxBatch(1)
+	chainLedger.TxBegin("txUuid")
+	chainLedger.SetState("chaincode1", "key1", []byte("value1"))
+	chainLedger.TxFinished("txUuid", true)
+	transaction, _ := buildTestTx(t)
+	err = chainLedger.CommitTxBatch(1, []*protos.Transaction{transaction}, nil, nil)
+	testutil.AssertNoError(t, err, "Error committing to chain ledger")
+
+	// the state and blockchain of the chain are isolated from the default chain
+	value, _ := chainLedger.GetState("chaincode1", "key1", true)
+	testutil.AssertEquals(t, value, []byte("value1"))
+	value, _ = defaultLedger.GetState("chaincode1", "key1", true)
+	testutil.AssertNil(t, value)
+	testutil.AssertEquals(t, chainLedger.GetBlockchainSize(), uint64(1))
+	testutil.AssertEquals(t, defaultLedger.GetBlockchainSize(), uint64(0))
+	tx, _ := chainLedger.GetTransactionByUUID(transaction.Uuid)
+	testutil.AssertNotNil(t, tx)
+	tx, _ = defaultLedger.GetTransactionByUUID(transaction.Uuid)
+	testutil.AssertNil(t, tx)
+
+	// the chain is reopened from its own database
+	reopened, err := newChainLedger("chain1")
+	testutil.AssertNoError(t, err, "Error reopening chain ledger")
+	testutil.AssertEquals(t, reopened.GetBlockchainSize(), uint64(1))
+
+	_, err = GetChainLedger("../chain")
+	testutil.AssertError(t, err, "Expected an error for an invalid chain ID")
+}
+
+func TestNewLedger(t *testing.T) {
+	ledgerTestWrapper := createFreshDBAndTestLedgerWrapper(t)
+	defaultLedger := ledgerTestWrapper.ledger
+	dir, err := ioutil.TempDir("", "ledger")
+	testutil.AssertNoError(t, err, "Error creating temp dir")
+	defer os.RemoveAll(dir)
+	openchainDB, err := db.OpenDB(filepath.Join(dir, "db"))
+	testutil.AssertNoError(t, err, "Error opening db")
+	ownLedger, err := NewLedger(openchainDB)
+	testutil.AssertNoError(t, err, "Error creating ledger")
+	testutil.AssertEquals(t, ownLedger.GetChainID(), "")
+
+	ownLedger.BeginTxBatch(1)
+	ownLedger.TxBegin("txUuid")
+	ownLedger.SetState("chaincode1", "key1", []byte("value1"))
+	ownLedger.TxFinished("txUuid", true)
+	transaction, _ := buildTestTx(t)
+	err = ownLedger.CommitTxBatch(1, []*protos.Transaction{transaction}, nil, nil)
+	testutil.AssertNoError(t, err, "Error committing to ledger")
+
+	// the ledger is isolated from the ledger of the peer
+	value, _ := ownLedger.GetState("chaincode1", "key1", true)
+	testutil.AssertEquals(t, value, []byte("value1"))
+	value, _ = defaultLedger.GetState("chaincode1", "key1", true)
+	testutil.AssertNil(t, value)
+	testutil.AssertEquals(t, defaultLedger.GetBlockchainSize(), uint64(0))
+	tx, _ := defaultLedger.GetTransactionByUUID(transaction.Uuid)
+	testutil.AssertNil(t, tx)
+
+	// the ledger is reopened from its db
+	ownLedger.StopPruning()
+	openchainDB.CloseDB()
+	openchainDB, err = db.OpenDB(filepath.Join(dir, "db"))
+	testutil.AssertNoError(t, err, "Error reopening db")
+	defer openchainDB.CloseDB()
+	reopened, err := NewLedger(openchainDB)
+	testutil.AssertNoError(t, err, "Error reopening ledger")
+	testutil.AssertEquals(t, reopened.GetBlockchainSize(), uint64(1))
+	value, _ = reopened.GetState("chaincode1", "key1", true)
+	testutil.AssertEquals(t, value, []byte("value1"))
+}
@@ -0,0 +1,32 @@
+/*
+Copyright IBM Corp. 2016 All Rights Reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+		 http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ledger
+
+import (
+	"github.com/hyperledger/fabric/core/metrics"
+)
+
+var (
+	blockchainHeight = metrics.NewGaugeVec("ledger_blockchain_height",
+		"Number of blocks in the blockchain, by chain (empty for the default chain).", "chain")
+	blockchainPrunedHeight = metrics.NewGaugeVec("ledger_blockchain_pruned_height",
+		"Number of blocks pruned from the DB into archive files, by chain (empty for the default chain).", "chain")
+	commitDuration = metrics.NewHistogram("ledger_commit_duration_seconds",
+		"Time taken by CommitTxBatch to commit a block, including the state hash computation.", nil)
+	commitFailures = metrics.NewCounter("ledger_commit_failures_total",
+		"Number of transaction batches which failed to commit.")
+)
@@ -17,18 +17,18 @@ limitations under the License.
 package ledger
 
 import (
+	"bytes"
 	"flag"
 	"strconv"
 	"testing"
 	"time"
 
 	"github.com/hyperledger/fabric/core/db"
-	"github.com/hyperledger/fabric/core/ledger/perfstat"
 	"github.com/hyperledger/fabric/core/ledger/testutil"
+	"github.com/hyperledger/fabric/core/metrics"
 	"github.com/hyperledger/fabric/core/util"
 	"github.com/hyperledger/fabric/protos"
 	"github.com/op/go-logging"
-	"github.com/tecbot/gorocksdb"
 )
 
 func BenchmarkDB(b *testing.B) {
@@ -50,7 +50,7 @@ func BenchmarkDB(b *testing.B) {
 	b.ResetTimer()
 	for i := 0; i < b.N; i++ {
 		key := []byte(*keyPrefix + strconv.Itoa(randNumGen.Next()))
-		value := dbWrapper.GetFromDB(b, key)
+		value := dbWrapper.GetFromStateCF(b, key)
 		b.SetBytes(int64(len(value)))
 	}
 }
@@ -195,22 +195,24 @@ func BenchmarkLedgerRandomTransactions(b *testing.B) {
 		}
 	}
 	b.StopTimer()
-	perfstat.UpdateTimeStat("timeSpent", startTime)
-	perfstat.PrintStats()
+	b.Logf("Time spent populating: %s", time.Since(startTime))
+	var stats bytes.Buffer
+	metrics.DefaultRegistry.WriteText(&stats)
+	b.Logf("Metrics after populating:\n%s", stats.String())
 	b.Logf("DB stats afters populating: %s", testDBWrapper.GetEstimatedNumKeys(b))
 }
 
 func populateDB(tb testing.TB, kvSize int, totalKeys int, keyPrefix string) {
 	dbWrapper := db.NewTestDBWrapper()
 	dbWrapper.CreateFreshDB(tb)
-	batch := gorocksdb.NewWriteBatch()
+	batch := db.GetDBHandle().NewWriteBatch()
 	for i := 0; i < totalKeys; i++ {
 		key := []byte(keyPrefix + strconv.Itoa(i))
 		value := testutil.ConstructRandomBytes(tb, kvSize-len(key))
-		batch.Put(key, value)
+		batch.PutCF(db.GetDBHandle().StateCF, key, value)
 		if i%1000 == 0 {
 			dbWrapper.WriteToDB(tb, batch)
-			batch = gorocksdb.NewWriteBatch()
+			batch = db.GetDBHandle().NewWriteBatch()
 		}
 	}
 	dbWrapper.CloseDB(tb)
@@ -20,11 +20,11 @@ import (
 	"os"
 	"testing"
 
+	"github.com/hyperledger/fabric/core/db"
 	"github.com/hyperledger/fabric/core/ledger/statemgmt"
 	"github.com/hyperledger/fabric/core/ledger/testutil"
 	"github.com/hyperledger/fabric/core/util"
 	"github.com/hyperledger/fabric/protos"
-	"github.com/tecbot/gorocksdb"
 	"golang.org/x/net/context"
 )
 
@@ -33,7 +33,7 @@ var testParams []string
 func TestMain(m *testing.M) {
 	testParams = testutil.ParseTestParams()
 	testutil.SetupTestConfig()
-	os.Exit(m.Run())
+	os.Exit(db.RunWithEngines(m))
 }
 
 type blockchainTestWrapper struct {
@@ -42,13 +42,13 @@ type blockchainTestWrapper struct {
 }
 
 func newTestBlockchainWrapper(t *testing.T) *blockchainTestWrapper {
-	blockchain, err := newBlockchain()
+	blockchain, err := newBlockchain("")
 	testutil.AssertNoError(t, err, "Error while getting handle to chain")
 	return &blockchainTestWrapper{t, blockchain}
 }
 
 func (testWrapper *blockchainTestWrapper) addNewBlock(block *protos.Block, stateHash []byte) uint64 {
-	writeBatch := gorocksdb.NewWriteBatch()
+	writeBatch := db.GetDBHandle().NewWriteBatch()
 	defer writeBatch.Destroy()
 	newBlockNumber, err := testWrapper.blockchain.addPersistenceChangesForNewBlock(context.TODO(), block, stateHash, writeBatch)
 	testutil.AssertNoError(testWrapper.t, err, "Error while adding a new block")
@@ -58,7 +58,7 @@ func (testWrapper *blockchainTestWrapper) addNewBlock(block *protos.Block, state
 }
 
 func (testWrapper *blockchainTestWrapper) fetchBlockchainSizeFromDB() uint64 {
-	size, err := fetchBlockchainSizeFromDB()
+	size, err := fetchBlockchainSizeFromDB(db.GetDBHandle())
 	testutil.AssertNoError(testWrapper.t, err, "Error while fetching blockchain size from db")
 	return size
 }
@@ -0,0 +1,447 @@
+/*
+Copyright IBM Corp. 2016 All Rights Reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+		 http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ledger
+
+import (
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/spf13/viper"
+
+	"github.com/hyperledger/fabric/core/db"
+	"github.com/hyperledger/fabric/protos"
+)
+
+// Blocks below the pruned height of a chain are removed from the blockchainCF
+// and kept in compressed archive files, each holding a segment of consecutive
+// blocks in the format of the ledger archives (see archive.go) without state
+// or history records. In place of a pruned block the blockchainCF holds a
+// checkpoint, the BlockchainInfo of the chain as of that block, so that the
+// hash chain remains verifiable without the archive files and the blocks read
+// from them are checked against it. The size of the segments is recorded by
+// the first pruning and cannot be changed afterwards.
+
+var (
+	prunedHeightKey          = []byte("prunedHeight")
+	pruningSegmentSizeKey    = []byte("pruningSegmentSize")
+	prunedBlockInfoKeyPrefix = []byte("prunedBlockInfo")
+)
+
+// defaultPruningSegmentSize is the number of blocks per archive file when
+// ledger.pruning.segmentSize is not set
+const defaultPruningSegmentSize = 1000
+
+type blockPruner struct {
+	blockchain   *blockchain
+	prunedHeight uint64 // accessed atomically
+	lock         sync.Mutex
+	// the last segment read from an archive file, by block number
+	cachedFirst  uint64
+	cachedBlocks [][]byte
+	// the background pruning started by start
+	stopChan chan struct{}
+	stopped  chan struct{}
+	stopOnce sync.Once
+}
+
+func newBlockPruner(blockchain *blockchain) (*blockPruner, error) {
+	pruner := &blockPruner{blockchain: blockchain}
+	heightBytes, err := blockchain.getDB().GetFromBlockchainCF(prunedHeightKey)
+	if err != nil {
+		return nil, err
+	}
+	if heightBytes != nil {
+		pruner.setPrunedHeight(decodeToUint64(heightBytes))
+	}
+	return pruner, nil
+}
+
+func (pruner *blockPruner) getPrunedHeight() uint64 {
+	return atomic.LoadUint64(&pruner.prunedHeight)
+}
+
+func (pruner *blockPruner) setPrunedHeight(height uint64) {
+	atomic.StoreUint64(&pruner.prunedHeight, height)
+	blockchainPrunedHeight.With(pruner.blockchain.chainID).Set(float64(height))
+}
+
+// getArchiveDir returns the directory of the archive files of the chain. The
+// blockchains persisted to a db of their own archive next to it.
+func (pruner *blockPruner) getArchiveDir() string {
+	if openchainDB := pruner.blockchain.openchainDB; openchainDB != nil {
+		return filepath.Join(filepath.Dir(openchainDB.Path()), "archive")
+	}
+	dir := viper.GetString("ledger.pruning.archivePath")
+	if dir == "" {
+		dir = filepath.Join(viper.GetString("peer.fileSystemPath"), "archive")
+	}
+	if pruner.blockchain.chainID != "" {
+		dir = filepath.Join(dir, "chains", pruner.blockchain.chainID)
+	}
+	return dir
+}
+
+func (pruner *blockPruner) getArchivePath(first uint64, segmentSize uint64) string {
+	return filepath.Join(pruner.getArchiveDir(), fmt.Sprintf("blocks-%020d-%020d.gz", first, first+segmentSize-1))
+}
+
+func (pruner *blockPruner) getSegmentSize() (uint64, error) {
+	sizeBytes, err := pruner.blockchain.getDB().GetFromBlockchainCF(pruningSegmentSizeKey)
+	if err != nil || sizeBytes == nil {
+		return 0, err
+	}
+	return decodeToUint64(sizeBytes), nil
+}
+
+func encodePrunedBlockInfoKey(blockNumber uint64) []byte {
+	return append(append([]byte{}, prunedBlockInfoKeyPrefix...), encodeUint64(blockNumber)...)
+}
+
+// getPrunedBlockInfo returns the checkpoint of a pruned block
+func (pruner *blockPruner) getPrunedBlockInfo(blockNumber uint64) (*protos.BlockchainInfo, error) {
+	infoBytes, err := pruner.blockchain.getDB().GetFromBlockchainCF(encodePrunedBlockInfoKey(blockNumber))
+	if err != nil {
+		return nil, err
+	}
+	if infoBytes == nil {
+		return nil, fmt.Errorf("No checkpoint for pruned block %d", blockNumber)
+	}
+	info := &protos.BlockchainInfo{}
+	if err = proto.Unmarshal(infoBytes, info); err != nil {
+		return nil, err
+	}
+	return info, nil
+}
+
+func newBlockPrunedError(blockNumber uint64, reason string) *Error {
+	return newLedgerError(ErrorTypeBlockPruned, fmt.Sprintf("ledger: block %d has been pruned, %s", blockNumber, reason))
+}
+
+// getRawBlock returns the bytes of a pruned block read from its archive file,
+// checked against its checkpoint
+func (pruner *blockPruner) getRawBlock(blockNumber uint64) ([]byte, error) {
+	info, err := pruner.getPrunedBlockInfo(blockNumber)
+	if err != nil {
+		return nil, err
+	}
+	segmentSize, err := pruner.getSegmentSize()
+	if err != nil {
+		return nil, err
+	}
+	if segmentSize == 0 {
+		return nil, fmt.Errorf("No segment size recorded for pruned block %d", blockNumber)
+	}
+	first := blockNumber - blockNumber%segmentSize
+
+	pruner.lock.Lock()
+	defer pruner.lock.Unlock()
+	if pruner.cachedBlocks == nil || pruner.cachedFirst != first {
+		blocks, err := readArchiveSegment(pruner.getArchivePath(first, segmentSize))
+		if os.IsNotExist(err) {
+			return nil, newBlockPrunedError(blockNumber, "its archive file is not available")
+		}
+		if err != nil {
+			return nil, err
+		}
+		if uint64(len(blocks)) != segmentSize {
+			return nil, fmt.Errorf("Archive of blocks %d to %d holds %d blocks", first, first+segmentSize-1, len(blocks))
+		}
+		pruner.cachedFirst, pruner.cachedBlocks = first, blocks
+	}
+	blockBytes := pruner.cachedBlocks[blockNumber-first]
+
+	block, err := protos.UnmarshallBlock(blockBytes)
+	if err != nil {
+		return nil, err
+	}
+	blockHash, err := block.GetHash()
+	if err != nil {
+		return nil, err
+	}
+	if !bytes.Equal(blockHash, info.CurrentBlockHash) {
+		return nil, fmt.Errorf("Archived block %d does not match its checkpoint, hash [%x] instead of [%x]", blockNumber, blockHash, info.CurrentBlockHash)
+	}
+	return blockBytes, nil
+}
+
+// readArchiveSegment returns the bytes of the blocks of an archive file
+func readArchiveSegment(path string) ([][]byte, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	gzipReader, err := gzip.NewReader(file)
+	if err != nil {
+		return nil, fmt.Errorf("Error reading archive %s: %s", path, err)
+	}
+	archive := newArchiveReader(gzipReader)
+	if err = archive.readHeader(); err != nil {
+		return nil, fmt.Errorf("Error reading archive %s: %s", path, err)
+	}
+	var blocks [][]byte
+	for {
+		recordType, payload, sum, err := archive.readRecord()
+		if err != nil {
+			return nil, fmt.Errorf("Error reading archive %s: %s", path, err)
+		}
+		switch recordType {
+		case archiveBlockRecord:
+			blocks = append(blocks, payload)
+		case archiveEndRecord:
+			if err = verifyArchiveEnd(payload, sum, &ArchiveInfo{Blocks: uint64(len(blocks))}); err != nil {
+				return nil, fmt.Errorf("Error reading archive %s: %s", path, err)
+			}
+			return blocks, nil
+		default:
+			return nil, fmt.Errorf("Unexpected record type %d in archive %s", recordType, path)
+		}
+	}
+}
+
+// writeArchiveSegment writes the blocks to a new archive file. The file is
+// written aside and renamed, so that it is complete once it exists.
+func writeArchiveSegment(path string, blocks [][]byte) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	file, err := ioutil.TempFile(filepath.Dir(path), filepath.Base(path)+".tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(file.Name())
+	err = writeArchiveSegmentTo(file, blocks)
+	if err == nil {
+		err = file.Sync()
+	}
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return err
+	}
+	return os.Rename(file.Name(), path)
+}
+
+func writeArchiveSegmentTo(w io.Writer, blocks [][]byte) error {
+	gzipWriter := gzip.NewWriter(w)
+	archive := newArchiveWriter(gzipWriter)
+	if err := archive.writeHeader(); err != nil {
+		return err
+	}
+	for _, blockBytes := range blocks {
+		if err := archive.writeRecord(archiveBlockRecord, blockBytes); err != nil {
+			return err
+		}
+	}
+	if err := archive.writeEnd(&ArchiveInfo{Blocks: uint64(len(blocks))}); err != nil {
+		return err
+	}
+	return gzipWriter.Close()
+}
+
+// getPruningTarget returns the height below which the blocks may be pruned
+// according to the retention policy, given the blockchain of the snapshot. The
+// last block is always retained.
+func (pruner *blockPruner) getPruningTarget(snapshot db.Snapshot, size uint64) (uint64, error) {
+	if size == 0 {
+		return 0, nil
+	}
+	target := size - 1
+	if retainBlocks := viper.GetInt("ledger.pruning.retainBlocks"); retainBlocks > 0 {
+		if uint64(retainBlocks) >= size {
+			return 0, nil
+		}
+		target = size - uint64(retainBlocks)
+	}
+	retainPeriod := viper.GetDuration("ledger.pruning.retainPeriod")
+	if retainPeriod <= 0 {
+		return target, nil
+	}
+	// blocks are pruned up to the first one committed within the period, as of
+	// its timestamp or else the time it was committed locally
+	cutoff := time.Now().Add(-retainPeriod).Unix()
+	for height := pruner.getPrunedHeight(); height < target; height++ {
+		blockBytes, err := pruner.blockchain.getDB().GetFromBlockchainCFSnapshot(snapshot, encodeBlockNumberDBKey(height))
+		if err != nil {
+			return 0, err
+		}
+		if blockBytes == nil {
+			return height, nil
+		}
+		block, err := protos.UnmarshallBlock(blockBytes)
+		if err != nil {
+			return 0, err
+		}
+		timestamp := block.Timestamp
+		if timestamp == nil && block.NonHashData != nil {
+			timestamp = block.NonHashData.LocalLedgerCommitTimestamp
+		}
+		if timestamp == nil || timestamp.Seconds >= cutoff {
+			return height, nil
+		}
+	}
+	return target, nil
+}
+
+// prune moves the segments of blocks below the pruning target of the
+// retention policy to archive files. The blocks are read from a snapshot of
+// the DB, so that the chain can be committed to meanwhile. Returns the new
+// pruned height.
+func (pruner *blockPruner) prune() (uint64, error) {
+	openchainDB := pruner.blockchain.getDB()
+	snapshot := openchainDB.GetSnapshot()
+	defer snapshot.Release()
+
+	size, err := fetchBlockchainSizeFromSnapshot(openchainDB, snapshot)
+	if err != nil {
+		return 0, err
+	}
+	target, err := pruner.getPruningTarget(snapshot, size)
+	if err != nil {
+		return 0, err
+	}
+	segmentSize, err := pruner.getSegmentSize()
+	if err != nil {
+		return 0, err
+	}
+	if segmentSize == 0 {
+		segmentSize = defaultPruningSegmentSize
+		if configured := viper.GetInt("ledger.pruning.segmentSize"); configured > 0 {
+			segmentSize = uint64(configured)
+		}
+	}
+
+	height := pruner.getPrunedHeight()
+	for height+segmentSize <= target {
+		if err = pruner.pruneSegment(snapshot, height, segmentSize); err != nil {
+			return height, err
+		}
+		height += segmentSize
+	}
+	return height, nil
+}
+
+// pruneSegment archives the blocks of the segment starting at block first and
+// replaces them in the DB with their checkpoints
+func (pruner *blockPruner) pruneSegment(snapshot db.Snapshot, first uint64, segmentSize uint64) error {
+	openchainDB := pruner.blockchain.getDB()
+	var previousBlockHash []byte
+	if first > 0 {
+		previousInfo, err := pruner.getPrunedBlockInfo(first - 1)
+		if err != nil {
+			return err
+		}
+		previousBlockHash = previousInfo.CurrentBlockHash
+	}
+
+	blocks := make([][]byte, segmentSize)
+	infos := make([]*protos.BlockchainInfo, segmentSize)
+	for i := range blocks {
+		blockNumber := first + uint64(i)
+		blockBytes, err := openchainDB.GetFromBlockchainCFSnapshot(snapshot, encodeBlockNumberDBKey(blockNumber))
+		if err != nil {
+			return err
+		}
+		if blockBytes == nil {
+			return fmt.Errorf("Block %d is missing from the blockchain, cannot prune it", blockNumber)
+		}
+		block, err := protos.UnmarshallBlock(blockBytes)
+		if err != nil {
+			return err
+		}
+		blockHash, err := block.GetHash()
+		if err != nil {
+			return err
+		}
+		if blockNumber > 0 && !bytes.Equal(block.PreviousBlockHash, previousBlockHash) {
+			return fmt.Errorf("Hash chain is broken at block %d, cannot prune it", blockNumber)
+		}
+		blocks[i] = blockBytes
+		infos[i] = &protos.BlockchainInfo{Height: blockNumber + 1, CurrentBlockHash: blockHash, PreviousBlockHash: block.PreviousBlockHash}
+		previousBlockHash = blockHash
+	}
+
+	path := pruner.getArchivePath(first, segmentSize)
+	if err := writeArchiveSegment(path, blocks); err != nil {
+		return fmt.Errorf("Error writing archive %s: %s", path, err)
+	}
+
+	writeBatch := openchainDB.NewWriteBatch()
+	defer writeBatch.Destroy()
+	for i, info := range infos {
+		infoBytes, err := proto.Marshal(info)
+		if err != nil {
+			return err
+		}
+		blockNumber := first + uint64(i)
+		writeBatch.PutCF(openchainDB.BlockchainCF, encodePrunedBlockInfoKey(blockNumber), infoBytes)
+		writeBatch.DeleteCF(openchainDB.BlockchainCF, encodeBlockNumberDBKey(blockNumber))
+	}
+	writeBatch.PutCF(openchainDB.BlockchainCF, pruningSegmentSizeKey, encodeUint64(segmentSize))
+	writeBatch.PutCF(openchainDB.BlockchainCF, prunedHeightKey, encodeUint64(first+segmentSize))
+	if err := openchainDB.Write(writeBatch); err != nil {
+		return err
+	}
+	pruner.setPrunedHeight(first + segmentSize)
+	ledgerLogger.Infof("Pruned blocks %d to %d of chain [%s] to %s", first, first+segmentSize-1, pruner.blockchain.chainID, path)
+	return nil
+}
+
+// start prunes the blockchain periodically in the background until stop is
+// called
+func (pruner *blockPruner) start(interval time.Duration) {
+	pruner.stopChan = make(chan struct{})
+	pruner.stopped = make(chan struct{})
+	go pruner.run(interval)
+}
+
+// stop stops the background pruning, waiting for the pruning in progress if
+// any. It is a no-op if the pruning was not started.
+func (pruner *blockPruner) stop() {
+	if pruner.stopChan == nil {
+		return
+	}
+	pruner.stopOnce.Do(func() { close(pruner.stopChan) })
+	<-pruner.stopped
+}
+
+// run prunes the blockchain periodically until the stop channel is closed
+func (pruner *blockPruner) run(interval time.Duration) {
+	defer close(pruner.stopped)
+	ledgerLogger.Infof("Pruning chain [%s] every %s", pruner.blockchain.chainID, interval)
+	for {
+		if _, err := pruner.prune(); err != nil {
+			ledgerLogger.Errorf("Error pruning chain [%s]: %s", pruner.blockchain.chainID, err)
+		}
+		select {
+		case <-pruner.stopChan:
+			ledgerLogger.Infof("Stopped pruning chain [%s]", pruner.blockchain.chainID)
+			return
+		case <-time.After(interval):
+		}
+	}
+}
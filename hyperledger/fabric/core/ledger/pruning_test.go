@@ -0,0 +1,188 @@
+/*
+Copyright IBM Corp. 2016 All Rights Reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+		 http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ledger
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/spf13/viper"
+
+	"github.com/hyperledger/fabric/core/ledger/testutil"
+	"github.com/hyperledger/fabric/protos"
+)
+
+func commitPruningTestBlocks(t *testing.T, l *Ledger, numBlocks int) {
+	for i := 0; i < numBlocks; i++ {
+		l.BeginTxBatch(i)
+		tx, _ := buildTestTx(t)
+		err := l.CommitTxBatch(i, []*protos.Transaction{tx}, nil, nil)
+		testutil.AssertNoError(t, err, "Error while committing block")
+	}
+}
+
+func setPruningTestConfig(retainBlocks int, retainPeriod time.Duration, segmentSize int) func() {
+	viper.Set("ledger.pruning.retainBlocks", retainBlocks)
+	viper.Set("ledger.pruning.retainPeriod", retainPeriod)
+	viper.Set("ledger.pruning.segmentSize", segmentSize)
+	return func() {
+		viper.Set("ledger.pruning.retainBlocks", 0)
+		viper.Set("ledger.pruning.retainPeriod", 0)
+		viper.Set("ledger.pruning.segmentSize", 0)
+	}
+}
+
+func TestLedgerPruneBlocks(t *testing.T) {
+	defer setPruningTestConfig(2, 0, 2)()
+	ledgerTestWrapper := createFreshDBAndTestLedgerWrapper(t)
+	l := ledgerTestWrapper.ledger
+	commitPruningTestBlocks(t, l, 7)
+	blocks := make([]*protos.Block, 7)
+	for i := range blocks {
+		blocks[i] = ledgerTestWrapper.GetBlockByNumber(uint64(i))
+	}
+	info, err := l.GetBlockchainInfo()
+	testutil.AssertNoError(t, err, "Error while getting blockchain info")
+
+	// Two full segments are below the 2 retained blocks
+	height, err := l.PruneBlocks()
+	testutil.AssertNoError(t, err, "Error while pruning blocks")
+	testutil.AssertEquals(t, height, uint64(4))
+	testutil.AssertEquals(t, l.GetPrunedHeight(), uint64(4))
+	blockBytes, _ := l.getDB().GetFromBlockchainCF(encodeBlockNumberDBKey(1))
+	testutil.AssertNil(t, blockBytes)
+
+	// Pruned blocks are read from the archive files
+	for i := range blocks {
+		testutil.AssertEquals(t, ledgerTestWrapper.GetBlockByNumber(uint64(i)), blocks[i])
+	}
+	pruned, err := l.GetTransactionByUUID(blocks[1].Transactions[0].Uuid)
+	testutil.AssertNoError(t, err, "Error while getting transaction of pruned block")
+	testutil.AssertEquals(t, pruned, blocks[1].Transactions[0])
+	prunedInfo, err := l.GetBlockchainInfo()
+	testutil.AssertNoError(t, err, "Error while getting blockchain info")
+	testutil.AssertEquals(t, prunedInfo, info)
+	testutil.AssertEquals(t, ledgerTestWrapper.VerifyChain(6, 0), uint64(0))
+
+	// Pruning again is a no-op until more blocks are committed
+	height, err = l.PruneBlocks()
+	testutil.AssertNoError(t, err, "Error while pruning blocks")
+	testutil.AssertEquals(t, height, uint64(4))
+
+	// A new ledger on the same DB knows the pruned blocks
+	l, err = GetNewLedger()
+	testutil.AssertNoError(t, err, "Error while constructing ledger")
+	testutil.AssertEquals(t, l.GetPrunedHeight(), uint64(4))
+	block, err := l.GetBlockByNumber(2)
+	testutil.AssertNoError(t, err, "Error while getting pruned block")
+	testutil.AssertEquals(t, block, blocks[2])
+
+	// The whole ledger can still be exported
+	var archive bytes.Buffer
+	archiveInfo, err := l.Export(&archive)
+	testutil.AssertNoError(t, err, "Error while exporting pruned ledger")
+	testutil.AssertEquals(t, archiveInfo.Blocks, uint64(7))
+
+	// Without its archive file a pruned block cannot be read, but the hash
+	// chain is still verified with the checkpoints
+	err = os.Remove(l.blockchain.pruner.getArchivePath(0, 2))
+	testutil.AssertNoError(t, err, "Error while removing archive file")
+	_, err = l.GetBlockByNumber(1)
+	testutil.AssertError(t, err, "Expected an error getting a block without archive file")
+	ledgerErr, ok := err.(*Error)
+	if !ok || ledgerErr.Type() != ErrorTypeBlockPruned {
+		t.Fatalf("Expected a pruned block error, got %v", err)
+	}
+	block, err = l.GetBlockByNumber(3)
+	testutil.AssertNoError(t, err, "Error while getting pruned block")
+	testutil.AssertEquals(t, block, blocks[3])
+	testutil.AssertEquals(t, ledgerTestWrapper.VerifyChain(6, 0), uint64(0))
+}
+
+func TestLedgerPruneBlocksCorruptedArchive(t *testing.T) {
+	defer setPruningTestConfig(1, 0, 2)()
+	ledgerTestWrapper := createFreshDBAndTestLedgerWrapper(t)
+	l := ledgerTestWrapper.ledger
+	commitPruningTestBlocks(t, l, 5)
+	_, err := l.PruneBlocks()
+	testutil.AssertNoError(t, err, "Error while pruning blocks")
+
+	// An archive file holding other blocks is detected
+	path0, path2 := l.blockchain.pruner.getArchivePath(0, 2), l.blockchain.pruner.getArchivePath(2, 2)
+	err = os.Rename(path2, path0)
+	testutil.AssertNoError(t, err, "Error while replacing archive file")
+	_, err = l.GetBlockByNumber(0)
+	testutil.AssertError(t, err, "Expected an error reading a block from the wrong archive file")
+
+	err = os.MkdirAll(filepath.Dir(path0), 0755)
+	testutil.AssertNoError(t, err, "Error while creating archive dir")
+	f, err := os.Create(path0)
+	testutil.AssertNoError(t, err, "Error while truncating archive file")
+	f.Close()
+	_, err = l.GetBlockByNumber(0)
+	testutil.AssertError(t, err, "Expected an error reading a block from an empty archive file")
+}
+
+func TestLedgerPruneBlocksRetainPeriod(t *testing.T) {
+	defer setPruningTestConfig(0, time.Hour, 1)()
+	ledgerTestWrapper := createFreshDBAndTestLedgerWrapper(t)
+	l := ledgerTestWrapper.ledger
+	commitPruningTestBlocks(t, l, 3)
+
+	// All the blocks were committed within the period
+	height, err := l.PruneBlocks()
+	testutil.AssertNoError(t, err, "Error while pruning blocks")
+	testutil.AssertEquals(t, height, uint64(0))
+
+	// All but the last block are pruned once they are older than the period
+	viper.Set("ledger.pruning.retainPeriod", time.Nanosecond)
+	time.Sleep(1100 * time.Millisecond)
+	height, err = l.PruneBlocks()
+	testutil.AssertNoError(t, err, "Error while pruning blocks")
+	testutil.AssertEquals(t, height, uint64(2))
+	testutil.AssertEquals(t, ledgerTestWrapper.VerifyChain(2, 0), uint64(0))
+}
+
+func TestLedgerBackgroundPruning(t *testing.T) {
+	defer setPruningTestConfig(2, 0, 2)()
+	ledgerTestWrapper := createFreshDBAndTestLedgerWrapper(t)
+	commitPruningTestBlocks(t, ledgerTestWrapper.ledger, 5)
+
+	// A ledger prunes in the background once enabled
+	viper.Set("ledger.pruning.enabled", true)
+	viper.Set("ledger.pruning.interval", 10*time.Millisecond)
+	l, err := GetNewLedger()
+	viper.Set("ledger.pruning.enabled", false)
+	testutil.AssertNoError(t, err, "Error while constructing ledger")
+	for i := 0; l.GetPrunedHeight() < 2 && i < 100; i++ {
+		time.Sleep(10 * time.Millisecond)
+	}
+	testutil.AssertEquals(t, l.GetPrunedHeight(), uint64(2))
+
+	// Once stopped, the blocks committed are no longer pruned
+	l.StopPruning()
+	l.StopPruning()
+	commitPruningTestBlocks(t, l, 4)
+	time.Sleep(50 * time.Millisecond)
+	testutil.AssertEquals(t, l.GetPrunedHeight(), uint64(2))
+	height, err := l.PruneBlocks()
+	testutil.AssertNoError(t, err, "Error while pruning blocks")
+	testutil.AssertEquals(t, height, uint64(6))
+}
@@ -0,0 +1,504 @@
+/*
+Copyright IBM Corp. 2016 All Rights Reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+		 http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package query implements the rich queries over the JSON documents stored in
+// the state of a chaincode. A query is a JSON object of the form
+//
+//	{
+//	  "selector": {"docType": "Member", "age": {"$gte": 18}},
+//	  "sort": ["name", {"age": "desc"}],
+//	  "limit": 10
+//	}
+//
+// The selector is required. Each of its fields is a condition on the value at
+// a dotted path of the document, that is either a value the field must be
+// equal to, or an object of operators: $eq, $ne, $gt, $gte, $lt and $lte.
+// An object without operators is a selector over the fields of a nested
+// document. The conditions of a selector must all hold; the $and and $or
+// operators combine an array of selectors. Numbers are compared numerically
+// and strings lexically; a comparison between values of different types, or
+// with a missing field, never holds.
+//
+// The matching documents are sorted by the sort fields, in ascending order
+// unless "desc" is given, then by key. The query returns at most limit
+// documents when limit is positive.
+package query
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// KV is a key-value of the state matching a query
+type KV struct {
+	Key   string
+	Value []byte
+}
+
+// Query is a parsed rich query
+type Query struct {
+	selector condition
+	sort     []sortField
+	limit    int
+}
+
+// Range holds the bounds of the values of a field. The bounds are inclusive,
+// and a nil bound is unbounded. The bounds, when both set, are of the same
+// type, either string or float64.
+type Range struct {
+	Lower interface{}
+	Upper interface{}
+}
+
+type sortField struct {
+	path       []string
+	descending bool
+}
+
+type condition interface {
+	matches(doc interface{}) bool
+}
+
+// andCondition holds if all its conditions hold
+type andCondition []condition
+
+// orCondition holds if any of its conditions holds
+type orCondition []condition
+
+// fieldCondition holds if the operator holds for the value at the path of the
+// document
+type fieldCondition struct {
+	path     []string
+	operator string
+	operand  interface{}
+}
+
+// Parse parses a rich query
+func Parse(query string) (*Query, error) {
+	var raw struct {
+		Selector map[string]interface{} `json:"selector"`
+		Sort     []interface{}          `json:"sort"`
+		Limit    int                    `json:"limit"`
+	}
+	decoder := json.NewDecoder(strings.NewReader(query))
+	if err := decoder.Decode(&raw); err != nil {
+		return nil, fmt.Errorf("invalid query (%s)", err)
+	}
+	if raw.Selector == nil {
+		return nil, fmt.Errorf("invalid query: selector is required")
+	}
+	if raw.Limit < 0 {
+		return nil, fmt.Errorf("invalid query: negative limit %d", raw.Limit)
+	}
+	selector, err := parseSelector(nil, raw.Selector)
+	if err != nil {
+		return nil, err
+	}
+	sortFields, err := parseSort(raw.Sort)
+	if err != nil {
+		return nil, err
+	}
+	return &Query{selector: selector, sort: sortFields, limit: raw.Limit}, nil
+}
+
+func parseSelector(path []string, selector map[string]interface{}) (andCondition, error) {
+	var conditions andCondition
+	// iterate in a fixed order so that parse errors are deterministic
+	for _, name := range sortedNames(selector) {
+		value := selector[name]
+		switch name {
+		case "$and", "$or":
+			selectors, ok := value.([]interface{})
+			if !ok || len(selectors) == 0 {
+				return nil, fmt.Errorf("invalid query: %s expects a non-empty array of selectors", name)
+			}
+			var combined []condition
+			for _, s := range selectors {
+				object, ok := s.(map[string]interface{})
+				if !ok {
+					return nil, fmt.Errorf("invalid query: %s expects a non-empty array of selectors", name)
+				}
+				c, err := parseSelector(path, object)
+				if err != nil {
+					return nil, err
+				}
+				combined = append(combined, c)
+			}
+			if name == "$and" {
+				conditions = append(conditions, andCondition(combined))
+			} else {
+				conditions = append(conditions, orCondition(combined))
+			}
+		default:
+			if strings.HasPrefix(name, "$") {
+				return nil, fmt.Errorf("invalid query: unknown operator %s", name)
+			}
+			c, err := parseField(append(append([]string{}, path...), strings.Split(name, ".")...), value)
+			if err != nil {
+				return nil, err
+			}
+			conditions = append(conditions, c...)
+		}
+	}
+	return conditions, nil
+}
+
+func parseField(path []string, value interface{}) (andCondition, error) {
+	object, ok := value.(map[string]interface{})
+	if !ok {
+		return andCondition{&fieldCondition{path, "$eq", value}}, nil
+	}
+	operators := 0
+	for name := range object {
+		if strings.HasPrefix(name, "$") {
+			operators++
+		}
+	}
+	if operators == 0 {
+		return parseSelector(path, object)
+	}
+	if operators != len(object) {
+		return nil, fmt.Errorf("invalid query: field %s mixes operators and fields", strings.Join(path, "."))
+	}
+	var conditions andCondition
+	for _, operator := range sortedNames(object) {
+		operand := object[operator]
+		switch operator {
+		case "$eq", "$ne":
+		case "$gt", "$gte", "$lt", "$lte":
+			if !isComparable(operand) {
+				return nil, fmt.Errorf("invalid query: %s of field %s expects a number or a string", operator, strings.Join(path, "."))
+			}
+		default:
+			return nil, fmt.Errorf("invalid query: unknown operator %s of field %s", operator, strings.Join(path, "."))
+		}
+		conditions = append(conditions, &fieldCondition{path, operator, operand})
+	}
+	return conditions, nil
+}
+
+func parseSort(fields []interface{}) ([]sortField, error) {
+	var sortFields []sortField
+	for _, field := range fields {
+		switch f := field.(type) {
+		case string:
+			sortFields = append(sortFields, sortField{path: strings.Split(f, ".")})
+		case map[string]interface{}:
+			if len(f) != 1 {
+				return nil, fmt.Errorf("invalid query: a sort field expects a single field name")
+			}
+			for name, direction := range f {
+				switch direction {
+				case "asc":
+					sortFields = append(sortFields, sortField{path: strings.Split(name, ".")})
+				case "desc":
+					sortFields = append(sortFields, sortField{path: strings.Split(name, "."), descending: true})
+				default:
+					return nil, fmt.Errorf("invalid query: the direction of sort field %s must be asc or desc", name)
+				}
+			}
+		default:
+			return nil, fmt.Errorf("invalid query: invalid sort field %v", field)
+		}
+	}
+	return sortFields, nil
+}
+
+func sortedNames(object map[string]interface{}) []string {
+	names := make([]string, 0, len(object))
+	for name := range object {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func (conditions andCondition) matches(doc interface{}) bool {
+	for _, c := range conditions {
+		if !c.matches(doc) {
+			return false
+		}
+	}
+	return true
+}
+
+func (conditions orCondition) matches(doc interface{}) bool {
+	for _, c := range conditions {
+		if c.matches(doc) {
+			return true
+		}
+	}
+	return false
+}
+
+func (c *fieldCondition) matches(doc interface{}) bool {
+	value, ok := lookup(doc, c.path)
+	if !ok {
+		return false
+	}
+	switch c.operator {
+	case "$eq":
+		return reflect.DeepEqual(value, c.operand)
+	case "$ne":
+		return !reflect.DeepEqual(value, c.operand)
+	}
+	cmp, ok := compare(value, c.operand)
+	if !ok {
+		return false
+	}
+	switch c.operator {
+	case "$gt":
+		return cmp > 0
+	case "$gte":
+		return cmp >= 0
+	case "$lt":
+		return cmp < 0
+	default:
+		return cmp <= 0
+	}
+}
+
+func lookup(doc interface{}, path []string) (interface{}, bool) {
+	for _, name := range path {
+		object, ok := doc.(map[string]interface{})
+		if !ok {
+			return nil, false
+		}
+		if doc, ok = object[name]; !ok {
+			return nil, false
+		}
+	}
+	return doc, true
+}
+
+func isComparable(value interface{}) bool {
+	switch value.(type) {
+	case float64, string:
+		return true
+	}
+	return false
+}
+
+// compare compares two numbers or two strings. Returns false if the values
+// are not both numbers or both strings.
+func compare(a, b interface{}) (int, bool) {
+	switch x := a.(type) {
+	case float64:
+		y, ok := b.(float64)
+		if !ok {
+			return 0, false
+		}
+		switch {
+		case x < y:
+			return -1, true
+		case x > y:
+			return 1, true
+		}
+		return 0, true
+	case string:
+		y, ok := b.(string)
+		if !ok {
+			return 0, false
+		}
+		return strings.Compare(x, y), true
+	}
+	return 0, false
+}
+
+// typeRank orders the values of different types for sorting: missing fields
+// and null first, then booleans, numbers, strings, arrays and objects
+func typeRank(value interface{}, present bool) int {
+	if !present {
+		return 0
+	}
+	switch value.(type) {
+	case nil:
+		return 1
+	case bool:
+		return 2
+	case float64:
+		return 3
+	case string:
+		return 4
+	case []interface{}:
+		return 5
+	}
+	return 6
+}
+
+func compareForSort(a interface{}, aPresent bool, b interface{}, bPresent bool) int {
+	rankA, rankB := typeRank(a, aPresent), typeRank(b, bPresent)
+	if rankA != rankB {
+		return rankA - rankB
+	}
+	if x, ok := a.(bool); ok {
+		y := b.(bool)
+		switch {
+		case !x && y:
+			return -1
+		case x && !y:
+			return 1
+		}
+		return 0
+	}
+	cmp, _ := compare(a, b)
+	return cmp
+}
+
+func decode(value []byte) (interface{}, bool) {
+	var doc interface{}
+	if err := json.Unmarshal(value, &doc); err != nil {
+		return nil, false
+	}
+	return doc, true
+}
+
+// Matches returns true if the value is a JSON document matching the selector
+// of the query
+func (q *Query) Matches(value []byte) bool {
+	doc, ok := decode(value)
+	return ok && q.selector.matches(doc)
+}
+
+// match is a key-value matching a query, along with its decoded document
+type match struct {
+	kv  *KV
+	doc interface{}
+}
+
+// sortedMatches sorts the matches of a query by its sort fields, then by key
+type sortedMatches struct {
+	matches []*match
+	sort    []sortField
+}
+
+func (s *sortedMatches) Len() int      { return len(s.matches) }
+func (s *sortedMatches) Swap(i, j int) { s.matches[i], s.matches[j] = s.matches[j], s.matches[i] }
+func (s *sortedMatches) Less(i, j int) bool {
+	for _, field := range s.sort {
+		a, aPresent := lookup(s.matches[i].doc, field.path)
+		b, bPresent := lookup(s.matches[j].doc, field.path)
+		cmp := compareForSort(a, aPresent, b, bPresent)
+		if field.descending {
+			cmp = -cmp
+		}
+		if cmp != 0 {
+			return cmp < 0
+		}
+	}
+	return s.matches[i].kv.Key < s.matches[j].kv.Key
+}
+
+// Execute returns the key-values returned by next, until it returns false,
+// whose values match the query, sorted and limited as requested
+func (q *Query) Execute(next func() (string, []byte, bool)) []*KV {
+	var matches []*match
+	for key, value, ok := next(); ok; key, value, ok = next() {
+		doc, isJSON := decode(value)
+		if isJSON && q.selector.matches(doc) {
+			matches = append(matches, &match{&KV{key, value}, doc})
+		}
+	}
+
+	sort.Stable(&sortedMatches{matches, q.sort})
+	if q.limit > 0 && len(matches) > q.limit {
+		matches = matches[:q.limit]
+	}
+
+	kvs := make([]*KV, len(matches))
+	for i, m := range matches {
+		kvs[i] = m.kv
+	}
+	return kvs
+}
+
+// FieldRange returns the range of values the field must have in the documents
+// matching the query, as constrained by the conditions of the top level
+// selector of the query on the field. Returns false if these conditions do not
+// constrain the field to a range of numbers or strings.
+func (q *Query) FieldRange(field string) (*Range, bool) {
+	var r *Range
+	for _, c := range topLevelConditions(q.selector) {
+		if strings.Join(c.path, ".") != field {
+			continue
+		}
+		var lower, upper interface{}
+		switch c.operator {
+		case "$eq":
+			if !isComparable(c.operand) {
+				continue
+			}
+			lower, upper = c.operand, c.operand
+		case "$gt", "$gte":
+			lower = c.operand
+		case "$lt", "$lte":
+			upper = c.operand
+		default:
+			continue
+		}
+		if r == nil {
+			r = &Range{lower, upper}
+			continue
+		}
+		if reflect.TypeOf(r.bound()) != reflect.TypeOf(c.operand) {
+			// no document can match, any of the ranges will do
+			continue
+		}
+		if cmp, _ := compare(lower, r.Lower); lower != nil && (r.Lower == nil || cmp > 0) {
+			r.Lower = lower
+		}
+		if cmp, _ := compare(upper, r.Upper); upper != nil && (r.Upper == nil || cmp < 0) {
+			r.Upper = upper
+		}
+	}
+	return r, r != nil
+}
+
+// bound returns a bound of the range that is set
+func (r *Range) bound() interface{} {
+	if r.Lower != nil {
+		return r.Lower
+	}
+	return r.Upper
+}
+
+func topLevelConditions(c condition) []*fieldCondition {
+	switch conditions := c.(type) {
+	case andCondition:
+		var fields []*fieldCondition
+		for _, c := range conditions {
+			fields = append(fields, topLevelConditions(c)...)
+		}
+		return fields
+	case *fieldCondition:
+		return []*fieldCondition{conditions}
+	}
+	return nil
+}
+
+// FieldValue returns the value of the field at the dotted path of a JSON
+// document. Returns false if the value is not a JSON document or does not
+// have the field.
+func FieldValue(value []byte, field string) (interface{}, bool) {
+	doc, ok := decode(value)
+	if !ok {
+		return nil, false
+	}
+	return lookup(doc, strings.Split(field, "."))
+}
@@ -0,0 +1,127 @@
+/*
+Copyright IBM Corp. 2016 All Rights Reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+		 http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package query
+
+import (
+	"reflect"
+	"testing"
+)
+
+var testDocuments = []*KV{
+	{"member1", []byte(`{"docType":"Member","name":"bob","age":40,"address":{"city":"Paris"}}`)},
+	{"member2", []byte(`{"docType":"Member","name":"alice","age":30,"address":{"city":"Oslo"}}`)},
+	{"member3", []byte(`{"docType":"Member","name":"carol","age":30}`)},
+	{"project1", []byte(`{"docType":"Project","name":"ledger","active":true}`)},
+	{"raw", []byte("not a document")},
+}
+
+func executeTestQuery(t *testing.T, query string) []string {
+	q, err := Parse(query)
+	if err != nil {
+		t.Fatalf("Error parsing query %s: %s", query, err)
+	}
+	i := 0
+	kvs := q.Execute(func() (string, []byte, bool) {
+		if i == len(testDocuments) {
+			return "", nil, false
+		}
+		i++
+		return testDocuments[i-1].Key, testDocuments[i-1].Value, true
+	})
+	keys := []string{}
+	for _, kv := range kvs {
+		keys = append(keys, kv.Key)
+	}
+	return keys
+}
+
+func TestQueryExecute(t *testing.T) {
+	tests := []struct {
+		query string
+		keys  []string
+	}{
+		{`{"selector":{}}`, []string{"member1", "member2", "member3", "project1"}},
+		{`{"selector":{"docType":"Member"}}`, []string{"member1", "member2", "member3"}},
+		{`{"selector":{"docType":{"$ne":"Member"}}}`, []string{"project1"}},
+		{`{"selector":{"active":true}}`, []string{"project1"}},
+		{`{"selector":{"age":{"$gt":30}}}`, []string{"member1"}},
+		{`{"selector":{"age":{"$gte":30,"$lt":40}}}`, []string{"member2", "member3"}},
+		{`{"selector":{"age":{"$lte":"40"}}}`, []string{}},
+		{`{"selector":{"name":{"$lt":"bob"}}}`, []string{"member2"}},
+		{`{"selector":{"address.city":"Oslo"}}`, []string{"member2"}},
+		{`{"selector":{"address":{"city":"Paris"}}}`, []string{"member1"}},
+		{`{"selector":{"address":{"city":{"$ne":"Paris"}}}}`, []string{"member2"}},
+		{`{"selector":{"$or":[{"name":"carol"},{"docType":"Project"}]}}`, []string{"member3", "project1"}},
+		{`{"selector":{"$and":[{"age":30},{"name":"carol"}]}}`, []string{"member3"}},
+		{`{"selector":{"docType":"Member"},"sort":["age","name"]}`, []string{"member2", "member3", "member1"}},
+		{`{"selector":{"docType":"Member"},"sort":[{"age":"desc"}]}`, []string{"member1", "member2", "member3"}},
+		{`{"selector":{"docType":"Member"},"sort":[{"age":"desc"}],"limit":2}`, []string{"member1", "member2"}},
+		{`{"selector":{},"sort":["age"]}`, []string{"project1", "member2", "member3", "member1"}},
+	}
+	for _, test := range tests {
+		if keys := executeTestQuery(t, test.query); !reflect.DeepEqual(keys, test.keys) {
+			t.Fatalf("Query %s returned %v, expected %v", test.query, keys, test.keys)
+		}
+	}
+}
+
+func TestQueryParseErrors(t *testing.T) {
+	for _, query := range []string{
+		`not json`,
+		`{"docType":"Member"}`,
+		`{"selector":{"$nor":[{"a":1}]}}`,
+		`{"selector":{"a":{"$in":[1,2]}}}`,
+		`{"selector":{"a":{"$gt":true}}}`,
+		`{"selector":{"a":{"$gt":1,"b":2}}}`,
+		`{"selector":{"$or":[]}}`,
+		`{"selector":{"$or":{"a":1}}}`,
+		`{"selector":{},"sort":[{"a":"up"}]}`,
+		`{"selector":{},"sort":[1]}`,
+		`{"selector":{},"limit":-1}`,
+	} {
+		if _, err := Parse(query); err == nil {
+			t.Fatalf("Expected an error parsing query %s", query)
+		}
+	}
+}
+
+func TestQueryFieldRange(t *testing.T) {
+	tests := []struct {
+		query string
+		field string
+		r     *Range
+	}{
+		{`{"selector":{"age":30}}`, "age", &Range{float64(30), float64(30)}},
+		{`{"selector":{"age":{"$gt":10,"$lte":50}}}`, "age", &Range{float64(10), float64(50)}},
+		{`{"selector":{"$and":[{"age":{"$gt":10}},{"age":{"$gt":20}}]}}`, "age", &Range{float64(20), nil}},
+		{`{"selector":{"address":{"city":{"$lt":"P"}}}}`, "address.city", &Range{nil, "P"}},
+		{`{"selector":{"age":{"$ne":30}}}`, "age", nil},
+		{`{"selector":{"active":true}}`, "active", nil},
+		{`{"selector":{"$or":[{"age":30},{"age":40}]}}`, "age", nil},
+		{`{"selector":{"name":"bob"}}`, "age", nil},
+	}
+	for _, test := range tests {
+		q, err := Parse(test.query)
+		if err != nil {
+			t.Fatalf("Error parsing query %s: %s", test.query, err)
+		}
+		r, ok := q.FieldRange(test.field)
+		if ok != (test.r != nil) || ok && !reflect.DeepEqual(r, test.r) {
+			t.Fatalf("Query %s returned range %v of field %s, expected %v", test.query, r, test.field, test.r)
+		}
+	}
+}
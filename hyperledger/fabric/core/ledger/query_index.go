@@ -0,0 +1,334 @@
+/*
+Copyright IBM Corp. 2016 All Rights Reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+		 http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ledger
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/hex"
+	"math"
+	"sort"
+
+	"github.com/hyperledger/fabric/core/db"
+	"github.com/hyperledger/fabric/core/ledger/query"
+	"github.com/hyperledger/fabric/core/ledger/statemgmt"
+	"github.com/hyperledger/fabric/core/ledger/statemgmt/state"
+)
+
+// The query indexes map the values of the JSON fields declared in
+// 'ledger.queryIndex.fields' to the keys of the documents holding them, so that
+// the rich queries constraining one of these fields do not scan the whole state
+// of the chaincode. An entry is stored in queryIndexCF under the key
+// chaincodeID 0x00 field 0x00 encodedValue 0x00 key
+// with an empty value. Only numbers and strings are indexed: encodedValue is
+// 'n' followed by the hex encoding of an order preserving 8 byte encoding of a
+// number, or 's' followed by the hex encoding of a string, so that the entries
+// of a field are sorted by value and encodedValue never contains a 0x00 byte.
+//
+// The index of a field for a chaincode is built from the committed state the
+// first time a batch changing the state of the chaincode is committed while the
+// field is declared, which is recorded by a marker stored under the key
+// 0x00 chaincodeID 0x00 field. Queries scan the state of the chaincodes whose
+// index is not built. The markers of the chaincodes changed by a state delta
+// applied by the state transfer are removed, so that their indexes are built
+// again. An index may hold stale entries, but never misses one: the queries
+// always evaluate the committed values of the keys found in the index.
+
+var queryIndexKeyDelimiter = []byte{0x00}
+
+const (
+	queryIndexNumberType = 'n'
+	queryIndexStringType = 's'
+)
+
+func encodeQueryIndexFieldPrefix(chaincodeID string, field string) []byte {
+	prefix := statemgmt.ConstructCompositeKey(chaincodeID, field)
+	return append(prefix, queryIndexKeyDelimiter...)
+}
+
+func encodeQueryIndexMarker(chaincodeID string, field string) []byte {
+	return append(append([]byte{}, queryIndexKeyDelimiter...), statemgmt.ConstructCompositeKey(chaincodeID, field)...)
+}
+
+// encodeQueryIndexValue encodes a number or a string, returns false for the
+// values of other types
+func encodeQueryIndexValue(value interface{}) ([]byte, bool) {
+	switch v := value.(type) {
+	case float64:
+		if v == 0 {
+			// -0 and 0 are equal
+			v = 0
+		}
+		bits := math.Float64bits(v)
+		if v < 0 {
+			bits = ^bits
+		} else {
+			bits |= 1 << 63
+		}
+		number := make([]byte, 8)
+		binary.BigEndian.PutUint64(number, bits)
+		return append([]byte{queryIndexNumberType}, hex.EncodeToString(number)...), true
+	case string:
+		return append([]byte{queryIndexStringType}, hex.EncodeToString([]byte(v))...), true
+	}
+	return nil, false
+}
+
+// encodeQueryIndexEntry returns the index entry of the field of the value of
+// the key, returns false if the value has no indexed value for the field
+func encodeQueryIndexEntry(chaincodeID string, field string, key string, value []byte) ([]byte, bool) {
+	fieldValue, ok := query.FieldValue(value, field)
+	if !ok {
+		return nil, false
+	}
+	encodedValue, ok := encodeQueryIndexValue(fieldValue)
+	if !ok {
+		return nil, false
+	}
+	entry := append(encodeQueryIndexFieldPrefix(chaincodeID, field), encodedValue...)
+	entry = append(entry, queryIndexKeyDelimiter...)
+	return append(entry, key...), true
+}
+
+func isQueryIndexBuilt(openchainDB *db.OpenchainDB, chaincodeID string, field string) (bool, error) {
+	marker, err := openchainDB.GetFromQueryIndexCF(encodeQueryIndexMarker(chaincodeID, field))
+	return marker != nil, err
+}
+
+// addQueryIndexesForPersistence adds to the writeBatch the changes to the
+// query indexes of the fields for the state changes of the current batch
+func addQueryIndexesForPersistence(openchainDB *db.OpenchainDB, fields []string, state *state.State, writeBatch db.WriteBatch) error {
+	if len(fields) == 0 {
+		return nil
+	}
+	updatedKeys := make(map[string]map[string]bool)
+	for _, txStateDelta := range state.GetTxStateDeltas() {
+		delta := txStateDelta.StateDelta
+		for _, chaincodeID := range delta.GetUpdatedChaincodeIds(false) {
+			if updatedKeys[chaincodeID] == nil {
+				updatedKeys[chaincodeID] = make(map[string]bool)
+			}
+			for key := range delta.GetUpdates(chaincodeID) {
+				updatedKeys[chaincodeID][key] = true
+			}
+		}
+	}
+
+	cf := openchainDB.QueryIndexCF
+	for chaincodeID, keys := range updatedKeys {
+		for _, field := range fields {
+			built, err := isQueryIndexBuilt(openchainDB, chaincodeID, field)
+			if err != nil {
+				return err
+			}
+			if !built {
+				if err = buildQueryIndex(openchainDB, chaincodeID, field, state, writeBatch); err != nil {
+					return err
+				}
+			}
+			for key := range keys {
+				previousValue, err := state.Get(chaincodeID, key, true)
+				if err != nil {
+					return err
+				}
+				value, err := state.Get(chaincodeID, key, false)
+				if err != nil {
+					return err
+				}
+				if entry, ok := encodeQueryIndexEntry(chaincodeID, field, key, previousValue); ok {
+					writeBatch.DeleteCF(cf, entry)
+				}
+				if entry, ok := encodeQueryIndexEntry(chaincodeID, field, key, value); ok {
+					writeBatch.PutCF(cf, entry, []byte{})
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// buildQueryIndex adds to the writeBatch the index of the field for the
+// committed state of the chaincode
+func buildQueryIndex(openchainDB *db.OpenchainDB, chaincodeID string, field string, state *state.State, writeBatch db.WriteBatch) error {
+	ledgerLogger.Debugf("Building query index of field [%s] for chaincode [%s]", field, chaincodeID)
+	itr, err := state.GetRangeScanIterator(chaincodeID, "", "", true)
+	if err != nil {
+		return err
+	}
+	defer itr.Close()
+	cf := openchainDB.QueryIndexCF
+	for itr.Next() {
+		key, value := itr.GetKeyValue()
+		if entry, ok := encodeQueryIndexEntry(chaincodeID, field, key, value); ok {
+			writeBatch.PutCF(cf, entry, []byte{})
+		}
+	}
+	writeBatch.PutCF(cf, encodeQueryIndexMarker(chaincodeID, field), []byte{})
+	return nil
+}
+
+// invalidateQueryIndexes removes the markers of the query indexes of the
+// fields for the chaincodes, so that the indexes are built again
+func invalidateQueryIndexes(openchainDB *db.OpenchainDB, fields []string, chaincodeIDs []string) error {
+	if len(fields) == 0 {
+		return nil
+	}
+	writeBatch := openchainDB.NewWriteBatch()
+	defer writeBatch.Destroy()
+	cf := openchainDB.QueryIndexCF
+	for _, chaincodeID := range chaincodeIDs {
+		for _, field := range fields {
+			writeBatch.DeleteCF(cf, encodeQueryIndexMarker(chaincodeID, field))
+		}
+	}
+	return openchainDB.Write(writeBatch)
+}
+
+// getQueryIndexKeys returns the sorted keys of the chaincode found in the index
+// of the field within the range of values
+func getQueryIndexKeys(openchainDB *db.OpenchainDB, chaincodeID string, field string, valueRange *query.Range) ([]string, error) {
+	prefix := encodeQueryIndexFieldPrefix(chaincodeID, field)
+	var bound interface{} = valueRange.Lower
+	if bound == nil {
+		bound = valueRange.Upper
+	}
+	encodedBound, ok := encodeQueryIndexValue(bound)
+	if !ok {
+		return nil, nil
+	}
+	typePrefix := append(append([]byte{}, prefix...), encodedBound[0])
+
+	startKey := typePrefix
+	if valueRange.Lower != nil {
+		encodedLower, _ := encodeQueryIndexValue(valueRange.Lower)
+		startKey = append(append([]byte{}, prefix...), encodedLower...)
+	}
+	// the entries of a value are followed by 0x00, and the hex encoding of
+	// longer values by bytes greater than 0x01
+	endKey := append(append([]byte{}, typePrefix...), 0xff)
+	if valueRange.Upper != nil {
+		encodedUpper, _ := encodeQueryIndexValue(valueRange.Upper)
+		endKey = append(append(append([]byte{}, prefix...), encodedUpper...), 0x01)
+	}
+
+	dbItr := openchainDB.GetQueryIndexCFIterator()
+	defer dbItr.Close()
+	keys := make(map[string]bool)
+	for dbItr.Seek(startKey); dbItr.ValidForPrefix(typePrefix); dbItr.Next() {
+		entry := dbItr.Key()
+		if bytes.Compare(entry, endKey) >= 0 {
+			break
+		}
+		encodedKey := entry[len(prefix):]
+		delimiter := bytes.Index(encodedKey, queryIndexKeyDelimiter)
+		if delimiter < 0 {
+			continue
+		}
+		keys[string(encodedKey[delimiter+1:])] = true
+	}
+	if err := dbItr.Err(); err != nil {
+		return nil, err
+	}
+
+	sortedKeys := make([]string, 0, len(keys))
+	for key := range keys {
+		sortedKeys = append(sortedKeys, key)
+	}
+	sort.Strings(sortedKeys)
+	return sortedKeys, nil
+}
+
+// executeQuery runs the query over the committed state of the chaincode, using
+// the first of the query indexes of the fields that is built and whose field is
+// constrained by the query
+func executeQuery(openchainDB *db.OpenchainDB, fields []string, chaincodeID string, q *query.Query, state *state.State) ([]*query.KV, error) {
+	for _, field := range fields {
+		valueRange, ok := q.FieldRange(field)
+		if !ok {
+			continue
+		}
+		built, err := isQueryIndexBuilt(openchainDB, chaincodeID, field)
+		if err != nil {
+			return nil, err
+		}
+		if !built {
+			continue
+		}
+		ledgerLogger.Debugf("Querying the state of chaincode [%s] with the index of field [%s]", chaincodeID, field)
+		keys, err := getQueryIndexKeys(openchainDB, chaincodeID, field, valueRange)
+		if err != nil {
+			return nil, err
+		}
+		var queryErr error
+		next := 0
+		kvs := q.Execute(func() (string, []byte, bool) {
+			for queryErr == nil && next < len(keys) {
+				key := keys[next]
+				next++
+				var value []byte
+				if value, queryErr = state.Get(chaincodeID, key, true); value != nil {
+					return key, value, true
+				}
+			}
+			return "", nil, false
+		})
+		return kvs, queryErr
+	}
+
+	ledgerLogger.Debugf("Querying the state of chaincode [%s] with a scan", chaincodeID)
+	itr, err := state.GetRangeScanIterator(chaincodeID, "", "", true)
+	if err != nil {
+		return nil, err
+	}
+	defer itr.Close()
+	return q.Execute(func() (string, []byte, bool) {
+		if !itr.Next() {
+			return "", nil, false
+		}
+		key, value := itr.GetKeyValue()
+		return key, value, true
+	}), nil
+}
+
+// queryResultIterator iterates over the key-values matching a query
+type queryResultIterator struct {
+	kvs     []*query.KV
+	current int
+}
+
+func newQueryResultIterator(kvs []*query.KV) *queryResultIterator {
+	return &queryResultIterator{kvs, -1}
+}
+
+// Next moves to the next key-value. Returns true if next key-value exists
+func (itr *queryResultIterator) Next() bool {
+	if itr.current < len(itr.kvs) {
+		itr.current++
+	}
+	return itr.current < len(itr.kvs)
+}
+
+// GetKeyValue returns the key-value the iterator is positioned at
+func (itr *queryResultIterator) GetKeyValue() (string, []byte) {
+	kv := itr.kvs[itr.current]
+	return kv.Key, kv.Value
+}
+
+// Close releases the resources held by the iterator
+func (itr *queryResultIterator) Close() {
+	itr.kvs = nil
+}
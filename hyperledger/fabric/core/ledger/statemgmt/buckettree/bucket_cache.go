@@ -22,40 +22,53 @@ import (
 	"unsafe"
 
 	"github.com/hyperledger/fabric/core/db"
-	"github.com/hyperledger/fabric/core/ledger/perfstat"
 	"github.com/hyperledger/fabric/core/ledger/statemgmt"
+	"github.com/hyperledger/fabric/core/metrics"
 )
 
 var defaultBucketCacheMaxSize = 100 // MBs
 
+var cacheGetDuration = metrics.NewHistogram("ledger_buckettree_cache_get_duration_seconds",
+	"Time taken to get a bucket node from the bucket cache, or from the db on a miss.",
+	[]float64{.000001, .00001, .0001, .001, .01, .1})
+
 // We can create a cache and keep all the bucket nodes pre-loaded.
 // Since, the bucket nodes do not contain actual data and max possible
 // buckets are pre-determined, the memory demand may not be very high or can easily
 // be controlled - by keeping seletive buckets in the cache (most likely first few levels of the bucket tree - because,
 // higher the level of the bucket, more are the chances that the bucket would be required for recomputation of hash)
 type bucketCache struct {
-	isEnabled bool
-	c         map[bucketKey]*bucketNode
-	lock      sync.RWMutex
-	size      uint64
-	maxSize   uint64
+	chainID     string
+	openchainDB *db.OpenchainDB
+	isEnabled   bool
+	c           map[bucketKey]*bucketNode
+	lock        sync.RWMutex
+	size        uint64
+	maxSize     uint64
 }
 
-func newBucketCache(maxSizeMBs int) *bucketCache {
+func newBucketCache(chainID string, maxSizeMBs int) *bucketCache {
 	isEnabled := true
 	if maxSizeMBs <= 0 {
 		isEnabled = false
 	} else {
 		logger.Infof("Constructing bucket-cache with max bucket cache size = [%d] MBs", maxSizeMBs)
 	}
-	return &bucketCache{c: make(map[bucketKey]*bucketNode), maxSize: uint64(maxSizeMBs * 1024 * 1024), isEnabled: isEnabled}
+	return &bucketCache{chainID: chainID, c: make(map[bucketKey]*bucketNode), maxSize: uint64(maxSizeMBs * 1024 * 1024), isEnabled: isEnabled}
+}
+
+func (cache *bucketCache) getDB() *db.OpenchainDB {
+	if cache.openchainDB != nil {
+		return cache.openchainDB
+	}
+	return db.GetChainDBHandle(cache.chainID)
 }
 
 func (cache *bucketCache) loadAllBucketNodesFromDB() {
 	if !cache.isEnabled {
 		return
 	}
-	openchainDB := db.GetDBHandle()
+	openchainDB := cache.getDB()
 	itr := openchainDB.GetStateCFIterator()
 	defer itr.Close()
 	itr.Seek([]byte{byte(0)})
@@ -63,14 +76,12 @@ func (cache *bucketCache) loadAllBucketNodesFromDB() {
 	cache.lock.Lock()
 	defer cache.lock.Unlock()
 	for ; itr.Valid(); itr.Next() {
-		key := itr.Key().Data()
+		key := itr.Key()
 		if key[0] != byte(0) {
-			itr.Key().Free()
-			itr.Value().Free()
 			break
 		}
-		bKey := decodeBucketKey(statemgmt.Copy(itr.Key().Data()))
-		nodeBytes := statemgmt.Copy(itr.Value().Data())
+		bKey := decodeBucketKey(statemgmt.Copy(itr.Key()))
+		nodeBytes := statemgmt.Copy(itr.Value())
 		bucketNode := unmarshalBucketNode(&bKey, nodeBytes)
 		size := bKey.size() + bucketNode.size()
 		cache.size += size
@@ -79,8 +90,6 @@ func (cache *bucketCache) loadAllBucketNodesFromDB() {
 			break
 		}
 		cache.c[bKey] = bucketNode
-		itr.Key().Free()
-		itr.Value().Free()
 		count++
 	}
 	logger.Infof("Loaded buckets data in cache. Total buckets in DB = [%d]. Total cache size:=%d", count, cache.size)
@@ -114,15 +123,15 @@ func (cache *bucketCache) putWithoutLock(key bucketKey, node *bucketNode) {
 }
 
 func (cache *bucketCache) get(key bucketKey) (*bucketNode, error) {
-	defer perfstat.UpdateTimeStat("timeSpent", time.Now())
+	defer cacheGetDuration.ObserveSince(time.Now())
 	if !cache.isEnabled {
-		return fetchBucketNodeFromDB(&key)
+		return fetchBucketNodeFromDB(cache.getDB(), &key)
 	}
 	cache.lock.RLock()
 	defer cache.lock.RUnlock()
 	bucketNode := cache.c[key]
 	if bucketNode == nil {
-		return fetchBucketNodeFromDB(&key)
+		return fetchBucketNodeFromDB(cache.getDB(), &key)
 	}
 	return bucketNode, nil
 }
@@ -25,7 +25,6 @@ import (
 	"github.com/hyperledger/fabric/core/db"
 	"github.com/hyperledger/fabric/core/ledger/statemgmt"
 	"github.com/hyperledger/fabric/core/ledger/testutil"
-	"github.com/tecbot/gorocksdb"
 )
 
 var testDBWrapper = db.NewTestDBWrapper()
@@ -34,7 +33,7 @@ var testParams []string
 func TestMain(m *testing.M) {
 	testParams = testutil.ParseTestParams()
 	testutil.SetupTestConfig()
-	os.Exit(m.Run())
+	os.Exit(db.RunWithEngines(m))
 }
 
 // testHasher is a hash function for testing.
@@ -130,13 +129,13 @@ func (testWrapper *stateImplTestWrapper) prepareWorkingSetAndComputeCryptoHash(s
 	return testWrapper.computeCryptoHash()
 }
 
-func (testWrapper *stateImplTestWrapper) addChangesForPersistence(writeBatch *gorocksdb.WriteBatch) {
+func (testWrapper *stateImplTestWrapper) addChangesForPersistence(writeBatch db.WriteBatch) {
 	err := testWrapper.stateImpl.AddChangesForPersistence(writeBatch)
 	testutil.AssertNoError(testWrapper.t, err, "Error while adding changes to db write-batch")
 }
 
 func (testWrapper *stateImplTestWrapper) persistChangesAndResetInMemoryChanges() {
-	writeBatch := gorocksdb.NewWriteBatch()
+	writeBatch := db.GetDBHandle().NewWriteBatch()
 	defer writeBatch.Destroy()
 	testWrapper.addChangesForPersistence(writeBatch)
 	testDBWrapper.WriteToDB(testWrapper.t, writeBatch)
@@ -19,12 +19,11 @@ package buckettree
 import (
 	"github.com/hyperledger/fabric/core/db"
 	"github.com/hyperledger/fabric/core/ledger/statemgmt"
-	"github.com/tecbot/gorocksdb"
 )
 
 // RangeScanIterator implements the interface 'statemgmt.RangeScanIterator'
 type RangeScanIterator struct {
-	dbItr               *gorocksdb.Iterator
+	dbItr               db.Iterator
 	chaincodeID         string
 	startKey            string
 	endKey              string
@@ -34,8 +33,8 @@ type RangeScanIterator struct {
 	done                bool
 }
 
-func newRangeScanIterator(chaincodeID string, startKey string, endKey string) (*RangeScanIterator, error) {
-	dbItr := db.GetDBHandle().GetStateCFIterator()
+func newRangeScanIterator(openchainDB *db.OpenchainDB, chaincodeID string, startKey string, endKey string) (*RangeScanIterator, error) {
+	dbItr := openchainDB.GetStateCFIterator()
 	itr := &RangeScanIterator{
 		dbItr:       dbItr,
 		chaincodeID: chaincodeID,
@@ -56,8 +55,8 @@ func (itr *RangeScanIterator) Next() bool {
 
 		// making a copy of key-value bytes because, underlying key bytes are reused by itr.
 		// no need to free slices as iterator frees memory when closed.
-		keyBytes := statemgmt.Copy(itr.dbItr.Key().Data())
-		valueBytes := statemgmt.Copy(itr.dbItr.Value().Data())
+		keyBytes := statemgmt.Copy(itr.dbItr.Key())
+		valueBytes := statemgmt.Copy(itr.dbItr.Value())
 
 		dataNode := unmarshalDataNodeFromBytes(keyBytes, valueBytes)
 		dataKey := dataNode.dataKey
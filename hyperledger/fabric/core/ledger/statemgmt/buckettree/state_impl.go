@@ -18,17 +18,20 @@ package buckettree
 
 import (
 	"bytes"
+	"fmt"
+	"math/rand"
 
 	"github.com/hyperledger/fabric/core/db"
 	"github.com/hyperledger/fabric/core/ledger/statemgmt"
 	"github.com/op/go-logging"
-	"github.com/tecbot/gorocksdb"
 )
 
 var logger = logging.MustGetLogger("buckettree")
 
 // StateImpl - implements the interface - 'statemgmt.HashableState'
 type StateImpl struct {
+	chainID                string
+	openchainDB            *db.OpenchainDB
 	dataNodesDelta         *dataNodesDelta
 	bucketTreeDelta        *bucketTreeDelta
 	persistedStateHash     []byte
@@ -42,10 +45,27 @@ func NewStateImpl() *StateImpl {
 	return &StateImpl{}
 }
 
+// NewChainStateImpl constructs a new StateImpl persisting to the db of the given chain
+func NewChainStateImpl(chainID string) *StateImpl {
+	return &StateImpl{chainID: chainID}
+}
+
+// NewStateImplWithDB constructs a new StateImpl persisting to the given db
+func NewStateImplWithDB(openchainDB *db.OpenchainDB) *StateImpl {
+	return &StateImpl{openchainDB: openchainDB}
+}
+
+func (stateImpl *StateImpl) getDB() *db.OpenchainDB {
+	if stateImpl.openchainDB != nil {
+		return stateImpl.openchainDB
+	}
+	return db.GetChainDBHandle(stateImpl.chainID)
+}
+
 // Initialize - method implementation for interface 'statemgmt.HashableState'
 func (stateImpl *StateImpl) Initialize(configs map[string]interface{}) error {
 	initConfig(configs)
-	rootBucketNode, err := fetchBucketNodeFromDB(constructRootBucketKey())
+	rootBucketNode, err := fetchBucketNodeFromDB(stateImpl.getDB(), constructRootBucketKey())
 	if err != nil {
 		return err
 	}
@@ -58,7 +78,8 @@ func (stateImpl *StateImpl) Initialize(configs map[string]interface{}) error {
 	if !ok {
 		bucketCacheMaxSize = defaultBucketCacheMaxSize
 	}
-	stateImpl.bucketCache = newBucketCache(bucketCacheMaxSize)
+	stateImpl.bucketCache = newBucketCache(stateImpl.chainID, bucketCacheMaxSize)
+	stateImpl.bucketCache.openchainDB = stateImpl.openchainDB
 	stateImpl.bucketCache.loadAllBucketNodesFromDB()
 	return nil
 }
@@ -66,7 +87,7 @@ func (stateImpl *StateImpl) Initialize(configs map[string]interface{}) error {
 // Get - method implementation for interface 'statemgmt.HashableState'
 func (stateImpl *StateImpl) Get(chaincodeID string, key string) ([]byte, error) {
 	dataKey := newDataKey(chaincodeID, key)
-	dataNode, err := fetchDataNodeFromDB(dataKey)
+	dataNode, err := fetchDataNodeFromDB(stateImpl.getDB(), dataKey)
 	if err != nil {
 		return nil, err
 	}
@@ -128,7 +149,7 @@ func (stateImpl *StateImpl) processDataNodeDelta() error {
 	afftectedBuckets := stateImpl.dataNodesDelta.getAffectedBuckets()
 	for _, bucketKey := range afftectedBuckets {
 		updatedDataNodes := stateImpl.dataNodesDelta.getSortedDataNodesFor(bucketKey)
-		existingDataNodes, err := fetchDataNodesFromDBFor(bucketKey)
+		existingDataNodes, err := fetchDataNodesFromDBFor(stateImpl.getDB(), bucketKey)
 		if err != nil {
 			return err
 		}
@@ -216,7 +237,7 @@ func computeDataNodesCryptoHash(bucketKey *bucketKey, updatedNodes dataNodes, ex
 }
 
 // AddChangesForPersistence - method implementation for interface 'statemgmt.HashableState'
-func (stateImpl *StateImpl) AddChangesForPersistence(writeBatch *gorocksdb.WriteBatch) error {
+func (stateImpl *StateImpl) AddChangesForPersistence(writeBatch db.WriteBatch) error {
 
 	if stateImpl.dataNodesDelta == nil {
 		return nil
@@ -233,8 +254,8 @@ func (stateImpl *StateImpl) AddChangesForPersistence(writeBatch *gorocksdb.Write
 	return nil
 }
 
-func (stateImpl *StateImpl) addDataNodeChangesForPersistence(writeBatch *gorocksdb.WriteBatch) {
-	openchainDB := db.GetDBHandle()
+func (stateImpl *StateImpl) addDataNodeChangesForPersistence(writeBatch db.WriteBatch) {
+	openchainDB := stateImpl.getDB()
 	affectedBuckets := stateImpl.dataNodesDelta.getAffectedBuckets()
 	for _, affectedBucket := range affectedBuckets {
 		dataNodes := stateImpl.dataNodesDelta.getSortedDataNodesFor(affectedBucket)
@@ -250,8 +271,8 @@ func (stateImpl *StateImpl) addDataNodeChangesForPersistence(writeBatch *gorocks
 	}
 }
 
-func (stateImpl *StateImpl) addBucketNodeChangesForPersistence(writeBatch *gorocksdb.WriteBatch) {
-	openchainDB := db.GetDBHandle()
+func (stateImpl *StateImpl) addBucketNodeChangesForPersistence(writeBatch db.WriteBatch) {
+	openchainDB := stateImpl.getDB()
 	secondLastLevel := conf.getLowestLevel() - 1
 	for level := secondLastLevel; level >= 0; level-- {
 		bucketNodes := stateImpl.bucketTreeDelta.getBucketNodesAt(level)
@@ -285,6 +306,75 @@ func (stateImpl *StateImpl) updateBucketCache() {
 	}
 }
 
+// VerifyCryptoHash - method implementation for interface 'statemgmt.VerifiableState'.
+// The crypto-hash of each sampled lowest-level bucket is recomputed from its data
+// nodes and compared with the one persisted in its parent bucket. With a
+// sampleRate of 1, the whole tree is recomputed from the data nodes and its root
+// compared with the persisted root.
+func (stateImpl *StateImpl) VerifyCryptoHash(sampleRate float64) ([]string, error) {
+	openchainDB := stateImpl.getDB()
+	fullTree := sampleRate >= 1
+	treeDelta := newBucketTreeDelta()
+	var mismatches []string
+	var parentNode *bucketNode
+	for bucketNumber := 1; bucketNumber <= conf.getNumBucketsAtLowestLevel(); bucketNumber++ {
+		if !fullTree && rand.Float64() >= sampleRate {
+			continue
+		}
+		bucketKey := newBucketKeyAtLowestLevel(bucketNumber)
+		existingDataNodes, err := fetchDataNodesFromDBFor(openchainDB, bucketKey)
+		if err != nil {
+			return nil, err
+		}
+		cryptoHash := computeDataNodesCryptoHash(bucketKey, nil, existingDataNodes)
+
+		parentKey := bucketKey.getParentKey()
+		if parentNode == nil || !parentNode.bucketKey.equals(parentKey) {
+			parentNode, err = fetchBucketNodeFromDB(openchainDB, parentKey)
+			if err != nil {
+				return nil, err
+			}
+			if parentNode == nil {
+				parentNode = newBucketNode(parentKey)
+			}
+		}
+		persistedCryptoHash := parentNode.childrenCryptoHash[parentKey.getChildIndex(bucketKey)]
+		if !bytes.Equal(cryptoHash, persistedCryptoHash) {
+			mismatches = append(mismatches, fmt.Sprintf("Crypto-hash of bucket [%s] is [%x], but [%x] is persisted", bucketKey, cryptoHash, persistedCryptoHash))
+		}
+		if fullTree && cryptoHash != nil {
+			treeDelta.getOrCreateBucketNode(parentKey).setChildCryptoHash(bucketKey, cryptoHash)
+		}
+	}
+	if !fullTree {
+		return mismatches, nil
+	}
+
+	for level := conf.getLowestLevel() - 1; level > 0; level-- {
+		for _, bucketNode := range treeDelta.getBucketNodesAt(level) {
+			if cryptoHash := bucketNode.computeCryptoHash(); cryptoHash != nil {
+				treeDelta.getOrCreateBucketNode(bucketNode.bucketKey.getParentKey()).setChildCryptoHash(bucketNode.bucketKey, cryptoHash)
+			}
+		}
+	}
+	var stateHash []byte
+	if !treeDelta.isEmpty() {
+		stateHash = treeDelta.getRootNode().computeCryptoHash()
+	}
+	rootBucketNode, err := fetchBucketNodeFromDB(openchainDB, constructRootBucketKey())
+	if err != nil {
+		return nil, err
+	}
+	var persistedStateHash []byte
+	if rootBucketNode != nil {
+		persistedStateHash = rootBucketNode.computeCryptoHash()
+	}
+	if !bytes.Equal(stateHash, persistedStateHash) {
+		mismatches = append(mismatches, fmt.Sprintf("Crypto-hash of the state is [%x], but [%x] is persisted", stateHash, persistedStateHash))
+	}
+	return mismatches, nil
+}
+
 // PerfHintKeyChanged - method implementation for interface 'statemgmt.HashableState'
 func (stateImpl *StateImpl) PerfHintKeyChanged(chaincodeID string, key string) {
 	// We can create a cache. Pull all the keys for the bucket (to which given key belongs) in a separate thread
@@ -292,11 +382,11 @@ func (stateImpl *StateImpl) PerfHintKeyChanged(chaincodeID string, key string) {
 }
 
 // GetStateSnapshotIterator - method implementation for interface 'statemgmt.HashableState'
-func (stateImpl *StateImpl) GetStateSnapshotIterator(snapshot *gorocksdb.Snapshot) (statemgmt.StateSnapshotIterator, error) {
-	return newStateSnapshotIterator(snapshot)
+func (stateImpl *StateImpl) GetStateSnapshotIterator(snapshot db.Snapshot) (statemgmt.StateSnapshotIterator, error) {
+	return newStateSnapshotIterator(stateImpl.getDB(), snapshot)
 }
 
 // GetRangeScanIterator - method implementation for interface 'statemgmt.HashableState'
 func (stateImpl *StateImpl) GetRangeScanIterator(chaincodeID string, startKey string, endKey string) (statemgmt.RangeScanIterator, error) {
-	return newRangeScanIterator(chaincodeID, startKey, endKey)
+	return newRangeScanIterator(stateImpl.getDB(), chaincodeID, startKey, endKey)
 }
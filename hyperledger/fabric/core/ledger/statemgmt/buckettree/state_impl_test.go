@@ -17,8 +17,10 @@ limitations under the License.
 package buckettree
 
 import (
+	"strings"
 	"testing"
 
+	"github.com/hyperledger/fabric/core/db"
 	"github.com/hyperledger/fabric/core/ledger/statemgmt"
 	"github.com/hyperledger/fabric/core/ledger/testutil"
 )
@@ -345,27 +347,27 @@ func TestStateImpl_DB_Changes(t *testing.T) {
 	testutil.AssertEquals(t, stateImplTestWrapper.get("chaincodeID2", "key1"), []byte("value3"))
 
 	// fetch datanode from DB
-	dataNodeFromDB, _ := fetchDataNodeFromDB(newDataKey("chaincodeID2", "key1"))
+	dataNodeFromDB, _ := fetchDataNodeFromDB(db.GetDBHandle(), newDataKey("chaincodeID2", "key1"))
 	testutil.AssertEquals(t, dataNodeFromDB, newDataNode(newDataKey("chaincodeID2", "key1"), []byte("value3")))
 
 	//fetch non-existing data node from DB
-	dataNodeFromDB, _ = fetchDataNodeFromDB(newDataKey("chaincodeID10", "key10"))
+	dataNodeFromDB, _ = fetchDataNodeFromDB(db.GetDBHandle(), newDataKey("chaincodeID10", "key10"))
 	t.Logf("isNIL...[%t]", dataNodeFromDB == nil)
 	testutil.AssertNil(t, dataNodeFromDB)
 
 	// fetch all data nodes from db that belong to bucket 1 at lowest level
-	dataNodesFromDB, _ := fetchDataNodesFromDBFor(newBucketKeyAtLowestLevel(1))
+	dataNodesFromDB, _ := fetchDataNodesFromDBFor(db.GetDBHandle(), newBucketKeyAtLowestLevel(1))
 	testutil.AssertContainsAll(t, dataNodesFromDB,
 		dataNodes{newDataNode(newDataKey("chaincodeID1", "key1"), []byte("value1")),
 			newDataNode(newDataKey("chaincodeID1", "key2"), []byte("value2"))})
 
 	// fetch all data nodes from db that belong to bucket 2 at lowest level
-	dataNodesFromDB, _ = fetchDataNodesFromDBFor(newBucketKeyAtLowestLevel(2))
+	dataNodesFromDB, _ = fetchDataNodesFromDBFor(db.GetDBHandle(), newBucketKeyAtLowestLevel(2))
 	testutil.AssertContainsAll(t, dataNodesFromDB,
 		dataNodes{newDataNode(newDataKey("chaincodeID2", "key1"), []byte("value3"))})
 
 	// fetch first bucket at second level
-	bucketNodeFromDB, _ := fetchBucketNodeFromDB(newBucketKey(2, 1))
+	bucketNodeFromDB, _ := fetchBucketNodeFromDB(db.GetDBHandle(), newBucketKey(2, 1))
 	testutil.AssertEquals(t, bucketNodeFromDB.bucketKey, newBucketKey(2, 1))
 	//check childrenCryptoHash entries in the bucket node from DB
 	testutil.AssertEquals(t, bucketNodeFromDB.childrenCryptoHash[0],
@@ -377,10 +379,50 @@ func TestStateImpl_DB_Changes(t *testing.T) {
 	testutil.AssertNil(t, bucketNodeFromDB.childrenCryptoHash[2])
 
 	// third bucket at second level should be nil
-	bucketNodeFromDB, _ = fetchBucketNodeFromDB(newBucketKey(2, 3))
+	bucketNodeFromDB, _ = fetchBucketNodeFromDB(db.GetDBHandle(), newBucketKey(2, 3))
 	testutil.AssertNil(t, bucketNodeFromDB)
 }
 
+func TestStateImpl_VerifyCryptoHash(t *testing.T) {
+	// number of buckets at each level 26,9,3,1
+	testHasher, stateImplTestWrapper, stateDelta := createFreshDBAndInitTestStateImplWithCustomHasher(t, 26, 3)
+	mismatches, err := stateImplTestWrapper.stateImpl.VerifyCryptoHash(1)
+	testutil.AssertNoError(t, err, "Error while verifying crypto hash")
+	testutil.AssertEquals(t, len(mismatches), 0)
+
+	testHasher.populate("chaincodeID1", "key1", 0)
+	testHasher.populate("chaincodeID2", "key2", 0)
+	testHasher.populate("chaincodeID3", "key3", 9)
+	testHasher.populate("chaincodeID4", "key4", 25)
+	stateDelta.Set("chaincodeID1", "key1", []byte("value1"), nil)
+	stateDelta.Set("chaincodeID2", "key2", []byte("value2"), nil)
+	stateDelta.Set("chaincodeID3", "key3", []byte("value3"), nil)
+	stateDelta.Set("chaincodeID4", "key4", []byte("value4"), nil)
+	stateImplTestWrapper.prepareWorkingSetAndComputeCryptoHash(stateDelta)
+	stateImplTestWrapper.persistChangesAndResetInMemoryChanges()
+
+	mismatches, err = stateImplTestWrapper.stateImpl.VerifyCryptoHash(1)
+	testutil.AssertNoError(t, err, "Error while verifying crypto hash")
+	testutil.AssertEquals(t, len(mismatches), 0)
+
+	// Change a value without updating the bucket tree
+	writeBatch := db.GetDBHandle().NewWriteBatch()
+	defer writeBatch.Destroy()
+	writeBatch.PutCF(db.GetDBHandle().StateCF, newDataKey("chaincodeID3", "key3").getEncodedBytes(), []byte("tampered"))
+	testDBWrapper.WriteToDB(t, writeBatch)
+
+	// the mismatches of bucket 10 and of the root are found
+	mismatches, err = stateImplTestWrapper.stateImpl.VerifyCryptoHash(1)
+	testutil.AssertNoError(t, err, "Error while verifying crypto hash")
+	testutil.AssertEquals(t, len(mismatches), 2)
+	testutil.AssertEquals(t, strings.Contains(mismatches[0], "bucketNumber=[10]"), true)
+
+	// nothing is sampled
+	mismatches, err = stateImplTestWrapper.stateImpl.VerifyCryptoHash(0)
+	testutil.AssertNoError(t, err, "Error while verifying crypto hash")
+	testutil.AssertEquals(t, len(mismatches), 0)
+}
+
 func TestStateImpl_DB_EmptyArrayValues(t *testing.T) {
 	testDBWrapper.CreateFreshDB(t)
 	stateImplTestWrapper := newStateImplTestWrapper(t)
@@ -17,7 +17,7 @@ limitations under the License.
 package statemgmt
 
 import (
-	"github.com/tecbot/gorocksdb"
+	"github.com/hyperledger/fabric/core/db"
 )
 
 // HashableState - Interface that is be implemented by state management
@@ -42,7 +42,7 @@ type HashableState interface {
 	// to persist for committing the  stateDelta (passed in PrepareWorkingSet method) to DB.
 	// In addition to the information in the StateDelta, the implementation may also want to
 	// persist intermediate results for faster crypto-hash computation
-	AddChangesForPersistence(writeBatch *gorocksdb.WriteBatch) error
+	AddChangesForPersistence(writeBatch db.WriteBatch) error
 
 	// ClearWorkingSet state implementation may clear any data structures that it may have constructed
 	// for computing cryptoHash and persisting the changes for the stateDelta (passed in PrepareWorkingSet method)
@@ -52,7 +52,7 @@ type HashableState interface {
 	// All the key-value of global state. A particular implementation may need to remove additional information
 	// that the implementation keeps for faster crypto-hash computation. For instance, filter a few of the
 	// key-values or remove some data from particular key-values.
-	GetStateSnapshotIterator(snapshot *gorocksdb.Snapshot) (StateSnapshotIterator, error)
+	GetStateSnapshotIterator(snapshot db.Snapshot) (StateSnapshotIterator, error)
 
 	// GetRangeScanIterator - state implementation to provide an iterator that is supposed to give
 	// All the key-values for a given chaincodeID such that a return key should be lexically greater than or
@@ -68,6 +68,18 @@ type HashableState interface {
 	PerfHintKeyChanged(chaincodeID string, key string)
 }
 
+// VerifiableState is implemented by the HashableState implementations that can
+// check the intermediate results they persist for a faster crypto-hash
+// computation against the key-values of the state
+type VerifiableState interface {
+
+	// VerifyCryptoHash recomputes the crypto-hash of a random sample of the
+	// persisted key-values, sampleRate being the fraction sampled, and returns
+	// a description of each mismatch with the persisted intermediate results.
+	// A sampleRate of 1 recomputes the crypto-hash of the whole state.
+	VerifyCryptoHash(sampleRate float64) ([]string, error)
+}
+
 // StateSnapshotIterator An interface that is to be implemented by the return value of
 // GetStateSnapshotIterator method in the implementation of HashableState interface
 type StateSnapshotIterator interface {
@@ -19,13 +19,14 @@ package raw
 import (
 	"github.com/hyperledger/fabric/core/db"
 	"github.com/hyperledger/fabric/core/ledger/statemgmt"
-	"github.com/tecbot/gorocksdb"
 )
 
 // StateImpl implements raw state management. This implementation does not support computation of crypto-hash of the state.
 // It simply stores the compositeKey and value in the db
 type StateImpl struct {
-	stateDelta *statemgmt.StateDelta
+	chainID     string
+	openchainDB *db.OpenchainDB
+	stateDelta  *statemgmt.StateDelta
 }
 
 // NewRawState constructs new instance of raw state
@@ -33,6 +34,23 @@ func NewRawState() *StateImpl {
 	return &StateImpl{}
 }
 
+// NewChainRawState constructs new instance of raw state persisting to the db of the given chain
+func NewChainRawState(chainID string) *StateImpl {
+	return &StateImpl{chainID: chainID}
+}
+
+// NewRawStateWithDB constructs new instance of raw state persisting to the given db
+func NewRawStateWithDB(openchainDB *db.OpenchainDB) *StateImpl {
+	return &StateImpl{openchainDB: openchainDB}
+}
+
+func (impl *StateImpl) getDB() *db.OpenchainDB {
+	if impl.openchainDB != nil {
+		return impl.openchainDB
+	}
+	return db.GetChainDBHandle(impl.chainID)
+}
+
 // Initialize - method implementation for interface 'statemgmt.HashableState'
 func (impl *StateImpl) Initialize(configs map[string]interface{}) error {
 	return nil
@@ -41,7 +59,7 @@ func (impl *StateImpl) Initialize(configs map[string]interface{}) error {
 // Get - method implementation for interface 'statemgmt.HashableState'
 func (impl *StateImpl) Get(chaincodeID string, key string) ([]byte, error) {
 	compositeKey := statemgmt.ConstructCompositeKey(chaincodeID, key)
-	openchainDB := db.GetDBHandle()
+	openchainDB := impl.getDB()
 	return openchainDB.GetFromStateCF(compositeKey)
 }
 
@@ -62,12 +80,12 @@ func (impl *StateImpl) ComputeCryptoHash() ([]byte, error) {
 }
 
 // AddChangesForPersistence - method implementation for interface 'statemgmt.HashableState'
-func (impl *StateImpl) AddChangesForPersistence(writeBatch *gorocksdb.WriteBatch) error {
+func (impl *StateImpl) AddChangesForPersistence(writeBatch db.WriteBatch) error {
 	delta := impl.stateDelta
 	if delta == nil {
 		return nil
 	}
-	openchainDB := db.GetDBHandle()
+	openchainDB := impl.getDB()
 	updatedChaincodeIds := delta.GetUpdatedChaincodeIds(false)
 	for _, updatedChaincodeID := range updatedChaincodeIds {
 		updates := delta.GetUpdates(updatedChaincodeID)
@@ -88,7 +106,7 @@ func (impl *StateImpl) PerfHintKeyChanged(chaincodeID string, key string) {
 }
 
 // GetStateSnapshotIterator - method implementation for interface 'statemgmt.HashableState'
-func (impl *StateImpl) GetStateSnapshotIterator(snapshot *gorocksdb.Snapshot) (statemgmt.StateSnapshotIterator, error) {
+func (impl *StateImpl) GetStateSnapshotIterator(snapshot db.Snapshot) (statemgmt.StateSnapshotIterator, error) {
 	panic("Not a full-fledged state implementation. Implemented only for measuring best-case performance benchmark")
 }
 
@@ -16,10 +16,52 @@ limitations under the License.
 
 package raw
 
-import "testing"
+import (
+	"testing"
+
+	"github.com/hyperledger/fabric/core/db"
+	"github.com/hyperledger/fabric/core/ledger/statemgmt"
+	"github.com/hyperledger/fabric/core/ledger/testutil"
+)
 
 func TestSkipAll(t *testing.T) {
 	t.Skip(`No tests in this package for now - This package is only experimental as yet.
 		The primary use of this package as yet is to measure what maximum performance we can get from a very basic state implementation
     that doe not implement any hash computation function`)
 }
+
+func TestRawStateGetAndPersist(t *testing.T) {
+	testDBWrapper.CreateFreshDB(t)
+	stateImpl := NewRawState()
+	stateImpl.Initialize(nil)
+
+	delta := statemgmt.NewStateDelta()
+	delta.Set("chaincodeID1", "key1", []byte("value1"), nil)
+	delta.Set("chaincodeID2", "key2", []byte("value2"), nil)
+	stateImpl.PrepareWorkingSet(delta)
+	writeBatch := db.GetDBHandle().NewWriteBatch()
+	testutil.AssertNoError(t, stateImpl.AddChangesForPersistence(writeBatch), "Error while adding changes for persistence")
+	testDBWrapper.WriteToDB(t, writeBatch)
+	writeBatch.Destroy()
+	stateImpl.ClearWorkingSet(true)
+
+	value, err := stateImpl.Get("chaincodeID1", "key1")
+	testutil.AssertNoError(t, err, "Error while getting state")
+	testutil.AssertEquals(t, value, []byte("value1"))
+
+	delta = statemgmt.NewStateDelta()
+	delta.Delete("chaincodeID1", "key1", nil)
+	stateImpl.PrepareWorkingSet(delta)
+	writeBatch = db.GetDBHandle().NewWriteBatch()
+	testutil.AssertNoError(t, stateImpl.AddChangesForPersistence(writeBatch), "Error while adding changes for persistence")
+	testDBWrapper.WriteToDB(t, writeBatch)
+	writeBatch.Destroy()
+	stateImpl.ClearWorkingSet(true)
+
+	value, err = stateImpl.Get("chaincodeID1", "key1")
+	testutil.AssertNoError(t, err, "Error while getting state")
+	testutil.AssertNil(t, value)
+	value, err = stateImpl.Get("chaincodeID2", "key2")
+	testutil.AssertNoError(t, err, "Error while getting state")
+	testutil.AssertEquals(t, value, []byte("value2"))
+}
@@ -23,14 +23,13 @@ import (
 	"github.com/hyperledger/fabric/core/db"
 	"github.com/hyperledger/fabric/core/ledger/statemgmt"
 	"github.com/hyperledger/fabric/core/ledger/testutil"
-	"github.com/tecbot/gorocksdb"
 )
 
 var testDBWrapper = db.NewTestDBWrapper()
 
 func TestMain(m *testing.M) {
 	testutil.SetupTestConfig()
-	os.Exit(m.Run())
+	os.Exit(db.RunWithEngines(m))
 }
 
 func createFreshDBAndConstructState(t *testing.T) (*stateTestWrapper, *State) {
@@ -62,7 +61,7 @@ func (testWrapper *stateTestWrapper) getSnapshot() *StateSnapshot {
 }
 
 func (testWrapper *stateTestWrapper) persistAndClearInMemoryChanges(blockNumber uint64) {
-	writeBatch := gorocksdb.NewWriteBatch()
+	writeBatch := db.GetDBHandle().NewWriteBatch()
 	defer writeBatch.Destroy()
 	testWrapper.state.AddChangesForPersistence(blockNumber, writeBatch)
 	testDBWrapper.WriteToDB(testWrapper.t, writeBatch)
@@ -18,47 +18,89 @@ package state
 
 import (
 	"encoding/binary"
+	"errors"
 	"fmt"
+	"sync"
+	"time"
 
 	"github.com/hyperledger/fabric/core/db"
 	"github.com/hyperledger/fabric/core/ledger/statemgmt"
 	"github.com/hyperledger/fabric/core/ledger/statemgmt/buckettree"
 	"github.com/hyperledger/fabric/core/ledger/statemgmt/raw"
 	"github.com/hyperledger/fabric/core/ledger/statemgmt/trie"
+	"github.com/hyperledger/fabric/core/metrics"
 	"github.com/op/go-logging"
-	"github.com/tecbot/gorocksdb"
 )
 
 var logger = logging.MustGetLogger("state")
 
 const detaultStateImpl = "buckettree"
 
-var stateImpl statemgmt.HashableState
+// ErrHashNotVerifiable is returned by VerifyHash when the state implementation
+// cannot check the hash of the state against its key-values
+var ErrHashNotVerifiable = errors.New("state: the state implementation cannot verify the state hash")
+
+var hashDuration = metrics.NewHistogramVec("ledger_state_hash_duration_seconds",
+	"Time taken by the state implementation to compute the state hash.", nil, "impl")
 
 // State structure for maintaining world state.
 // This encapsulates a particular implementation for managing the state persistence
 // This is not thread safe
 type State struct {
+	chainID               string
+	openchainDB           *db.OpenchainDB
 	stateImpl             statemgmt.HashableState
 	stateDelta            *statemgmt.StateDelta
 	currentTxStateDelta   *statemgmt.StateDelta
 	currentTxUUID         string
 	txStateDeltaHash      map[string][]byte
+	txStateDeltas         []*TxStateDelta
 	updateStateImpl       bool
 	historyStateDeltaSize uint64
+	parallelTxs           map[string]*TxRWSet
+	parallelTxsLock       sync.RWMutex
+}
+
+// TxStateDelta holds the state changes made by a single successful tx
+type TxStateDelta struct {
+	TxUUID     string
+	StateDelta *statemgmt.StateDelta
 }
 
 // NewState constructs a new State. This Initializes encapsulated state implementation
 func NewState() *State {
+	return NewChainState("")
+}
+
+// NewChainState constructs a new State of the given chain, the default chain
+// if chainID is empty
+func NewChainState(chainID string) *State {
+	return newState(chainID, nil)
+}
+
+// NewStateWithDB constructs a new State persisting to the given db, apart
+// from the db of any chain
+func NewStateWithDB(openchainDB *db.OpenchainDB) *State {
+	return newState("", openchainDB)
+}
+
+func newState(chainID string, openchainDB *db.OpenchainDB) *State {
 	initConfig()
-	logger.Infof("Initializing state implementation [%s]", stateImplName)
-	switch stateImplName {
-	case "buckettree":
-		stateImpl = buckettree.NewStateImpl()
-	case "trie":
-		stateImpl = trie.NewStateTrie()
-	case "raw":
-		stateImpl = raw.NewRawState()
+	logger.Infof("Initializing state implementation [%s] for chain [%s]", stateImplName, chainID)
+	var stateImpl statemgmt.HashableState
+	switch {
+	case stateImplName == "buckettree" && openchainDB != nil:
+		stateImpl = buckettree.NewStateImplWithDB(openchainDB)
+	case stateImplName == "buckettree":
+		stateImpl = buckettree.NewChainStateImpl(chainID)
+	case stateImplName == "trie" && openchainDB != nil:
+		stateImpl = trie.NewStateTrieWithDB(openchainDB)
+	case stateImplName == "trie":
+		stateImpl = trie.NewChainStateTrie(chainID)
+	case stateImplName == "raw" && openchainDB != nil:
+		stateImpl = raw.NewRawStateWithDB(openchainDB)
+	case stateImplName == "raw":
+		stateImpl = raw.NewChainRawState(chainID)
 	default:
 		panic("Should not reach here. Configs should have checked for the stateImplName being a valid names ")
 	}
@@ -66,13 +108,18 @@ func NewState() *State {
 	if err != nil {
 		panic(fmt.Errorf("Error during initialization of state implementation: %s", err))
 	}
-	return &State{stateImpl, statemgmt.NewStateDelta(), statemgmt.NewStateDelta(), "", make(map[string][]byte),
-		false, uint64(deltaHistorySize)}
+	return &State{chainID: chainID, openchainDB: openchainDB, stateImpl: stateImpl, stateDelta: statemgmt.NewStateDelta(), currentTxStateDelta: statemgmt.NewStateDelta(),
+		txStateDeltaHash: make(map[string][]byte), historyStateDeltaSize: uint64(deltaHistorySize),
+		parallelTxs: make(map[string]*TxRWSet)}
 }
 
 // TxBegin marks begin of a new tx. If a tx is already in progress, this call panics
 func (state *State) TxBegin(txUUID string) {
 	logger.Debugf("txBegin() for txUuid [%s]", txUUID)
+	if state.getParallelTx(txUUID) != nil {
+		// already begun by TxBeginParallel
+		return
+	}
 	if state.txInProgress() {
 		panic(fmt.Errorf("A tx [%s] is already in progress. Received call for begin of another tx [%s]", state.currentTxUUID, txUUID))
 	}
@@ -82,23 +129,33 @@ func (state *State) TxBegin(txUUID string) {
 // TxFinish marks the completion of on-going tx. If txUUID is not same as of the on-going tx, this call panics
 func (state *State) TxFinish(txUUID string, txSuccessful bool) {
 	logger.Debugf("txFinish() for txUuid [%s], txSuccessful=[%t]", txUUID, txSuccessful)
+	if rwset := state.getParallelTx(txUUID); rwset != nil {
+		rwset.finish(txSuccessful)
+		return
+	}
 	if state.currentTxUUID != txUUID {
 		panic(fmt.Errorf("Different Uuid in tx-begin [%s] and tx-finish [%s]", state.currentTxUUID, txUUID))
 	}
 	if txSuccessful {
-		if !state.currentTxStateDelta.IsEmpty() {
-			logger.Debugf("txFinish() for txUuid [%s] merging state changes", txUUID)
-			state.stateDelta.ApplyChanges(state.currentTxStateDelta)
-			state.txStateDeltaHash[txUUID] = state.currentTxStateDelta.ComputeCryptoHash()
-			state.updateStateImpl = true
-		} else {
-			state.txStateDeltaHash[txUUID] = nil
-		}
+		state.mergeTxStateDelta(txUUID, state.currentTxStateDelta)
 	}
 	state.currentTxStateDelta = statemgmt.NewStateDelta()
 	state.currentTxUUID = ""
 }
 
+// mergeTxStateDelta merges the changes made by a successful tx into the changes of the batch
+func (state *State) mergeTxStateDelta(txUUID string, txStateDelta *statemgmt.StateDelta) {
+	if !txStateDelta.IsEmpty() {
+		logger.Debugf("txFinish() for txUuid [%s] merging state changes", txUUID)
+		state.stateDelta.ApplyChanges(txStateDelta)
+		state.txStateDeltaHash[txUUID] = txStateDelta.ComputeCryptoHash()
+		state.txStateDeltas = append(state.txStateDeltas, &TxStateDelta{txUUID, txStateDelta})
+		state.updateStateImpl = true
+	} else {
+		state.txStateDeltaHash[txUUID] = nil
+	}
+}
+
 func (state *State) txInProgress() bool {
 	return state.currentTxUUID != ""
 }
@@ -142,19 +199,22 @@ func (state *State) Set(chaincodeID string, key string, value []byte) error {
 	if !state.txInProgress() {
 		panic("State can be changed only in context of a tx.")
 	}
+	return state.setInTxStateDelta(state.currentTxStateDelta, chaincodeID, key, value)
+}
 
+func (state *State) setInTxStateDelta(txStateDelta *statemgmt.StateDelta, chaincodeID string, key string, value []byte) error {
 	// Check if a previous value is already set in the state delta
-	if state.currentTxStateDelta.IsUpdatedValueSet(chaincodeID, key) {
+	if txStateDelta.IsUpdatedValueSet(chaincodeID, key) {
 		// No need to bother looking up the previous value as we will not
 		// set it again. Just pass nil
-		state.currentTxStateDelta.Set(chaincodeID, key, value, nil)
+		txStateDelta.Set(chaincodeID, key, value, nil)
 	} else {
 		// Need to lookup the previous value
 		previousValue, err := state.Get(chaincodeID, key, true)
 		if err != nil {
 			return err
 		}
-		state.currentTxStateDelta.Set(chaincodeID, key, value, previousValue)
+		txStateDelta.Set(chaincodeID, key, value, previousValue)
 	}
 
 	return nil
@@ -166,31 +226,35 @@ func (state *State) Delete(chaincodeID string, key string) error {
 	if !state.txInProgress() {
 		panic("State can be changed only in context of a tx.")
 	}
+	return state.deleteInTxStateDelta(state.currentTxStateDelta, chaincodeID, key)
+}
 
+func (state *State) deleteInTxStateDelta(txStateDelta *statemgmt.StateDelta, chaincodeID string, key string) error {
 	// Check if a previous value is already set in the state delta
-	if state.currentTxStateDelta.IsUpdatedValueSet(chaincodeID, key) {
+	if txStateDelta.IsUpdatedValueSet(chaincodeID, key) {
 		// No need to bother looking up the previous value as we will not
 		// set it again. Just pass nil
-		state.currentTxStateDelta.Delete(chaincodeID, key, nil)
+		txStateDelta.Delete(chaincodeID, key, nil)
 	} else {
 		// Need to lookup the previous value
 		previousValue, err := state.Get(chaincodeID, key, true)
 		if err != nil {
 			return err
 		}
-		state.currentTxStateDelta.Delete(chaincodeID, key, previousValue)
+		txStateDelta.Delete(chaincodeID, key, previousValue)
 	}
 
 	return nil
 }
 
-// CopyState copies all the key-values from sourceChaincodeID to destChaincodeID
+// CopyState copies all the key-values from sourceChaincodeID to destChaincodeID,
+// including the changes of sourceChaincodeID not committed yet
 func (state *State) CopyState(sourceChaincodeID string, destChaincodeID string) error {
-	itr, err := state.GetRangeScanIterator(sourceChaincodeID, "", "", true)
-	defer itr.Close()
+	itr, err := state.GetRangeScanIterator(sourceChaincodeID, "", "", false)
 	if err != nil {
 		return err
 	}
+	defer itr.Close()
 	for itr.Next() {
 		k, v := itr.GetKeyValue()
 		err := state.Set(destChaincodeID, k, v)
@@ -229,6 +293,7 @@ func (state *State) SetMultipleKeys(chaincodeID string, kvs map[string][]byte) e
 // Recomputes only if stateDelta has changed after most recent call to this function
 func (state *State) GetHash() ([]byte, error) {
 	logger.Debug("Enter - GetHash()")
+	defer hashDuration.With(stateImplName).ObserveSince(time.Now())
 	if state.updateStateImpl {
 		logger.Debug("updating stateImpl with working-set")
 		state.stateImpl.PrepareWorkingSet(state.stateDelta)
@@ -242,15 +307,34 @@ func (state *State) GetHash() ([]byte, error) {
 	return hash, nil
 }
 
+// VerifyHash checks the intermediate results persisted by the state
+// implementation for a faster hash computation against a sample of the
+// key-values, see statemgmt.VerifiableState. ErrHashNotVerifiable is returned
+// if the state implementation does not support it.
+func (state *State) VerifyHash(sampleRate float64) ([]string, error) {
+	verifiableState, ok := state.stateImpl.(statemgmt.VerifiableState)
+	if !ok {
+		return nil, ErrHashNotVerifiable
+	}
+	return verifiableState.VerifyCryptoHash(sampleRate)
+}
+
 // GetTxStateDeltaHash return the hash of the StateDelta
 func (state *State) GetTxStateDeltaHash() map[string][]byte {
 	return state.txStateDeltaHash
 }
 
+// GetTxStateDeltas returns the state changes made by each successful tx since the most
+// recent call to ClearInMemoryChanges, in the order the txs finished
+func (state *State) GetTxStateDeltas() []*TxStateDelta {
+	return state.txStateDeltas
+}
+
 // ClearInMemoryChanges remove from memory all the changes to state
 func (state *State) ClearInMemoryChanges(changesPersisted bool) {
 	state.stateDelta = statemgmt.NewStateDelta()
 	state.txStateDeltaHash = make(map[string][]byte)
+	state.txStateDeltas = nil
 	state.stateImpl.ClearWorkingSet(changesPersisted)
 }
 
@@ -261,13 +345,13 @@ func (state *State) getStateDelta() *statemgmt.StateDelta {
 
 // GetSnapshot returns a snapshot of the global state for the current block. stateSnapshot.Release()
 // must be called once you are done.
-func (state *State) GetSnapshot(blockNumber uint64, dbSnapshot *gorocksdb.Snapshot) (*StateSnapshot, error) {
-	return newStateSnapshot(blockNumber, dbSnapshot)
+func (state *State) GetSnapshot(blockNumber uint64, dbSnapshot db.Snapshot) (*StateSnapshot, error) {
+	return newStateSnapshot(state.stateImpl, blockNumber, dbSnapshot)
 }
 
 // FetchStateDeltaFromDB fetches the StateDelta corrsponding to given blockNumber
 func (state *State) FetchStateDeltaFromDB(blockNumber uint64) (*statemgmt.StateDelta, error) {
-	stateDeltaBytes, err := db.GetDBHandle().GetFromStateDeltaCF(encodeStateDeltaKey(blockNumber))
+	stateDeltaBytes, err := state.getDB().GetFromStateDeltaCF(encodeStateDeltaKey(blockNumber))
 	if err != nil {
 		return nil, err
 	}
@@ -280,7 +364,7 @@ func (state *State) FetchStateDeltaFromDB(blockNumber uint64) (*statemgmt.StateD
 }
 
 // AddChangesForPersistence adds key-value pairs to writeBatch
-func (state *State) AddChangesForPersistence(blockNumber uint64, writeBatch *gorocksdb.WriteBatch) {
+func (state *State) AddChangesForPersistence(blockNumber uint64, writeBatch db.WriteBatch) {
 	logger.Debug("state.addChangesForPersistence()...start")
 	if state.updateStateImpl {
 		state.stateImpl.PrepareWorkingSet(state.stateDelta)
@@ -289,7 +373,7 @@ func (state *State) AddChangesForPersistence(blockNumber uint64, writeBatch *gor
 	state.stateImpl.AddChangesForPersistence(writeBatch)
 
 	serializedStateDelta := state.stateDelta.Marshal()
-	cf := db.GetDBHandle().StateDeltaCF
+	cf := state.getDB().StateDeltaCF
 	logger.Debugf("Adding state-delta corresponding to block number[%d]", blockNumber)
 	writeBatch.PutCF(cf, encodeStateDeltaKey(blockNumber), serializedStateDelta)
 	if blockNumber >= state.historyStateDeltaSize {
@@ -319,12 +403,10 @@ func (state *State) CommitStateDelta() error {
 		state.updateStateImpl = false
 	}
 
-	writeBatch := gorocksdb.NewWriteBatch()
+	writeBatch := state.getDB().NewWriteBatch()
 	defer writeBatch.Destroy()
 	state.stateImpl.AddChangesForPersistence(writeBatch)
-	opt := gorocksdb.NewDefaultWriteOptions()
-	defer opt.Destroy()
-	return db.GetDBHandle().DB.Write(opt, writeBatch)
+	return state.getDB().Write(writeBatch)
 }
 
 // DeleteState deletes ALL state keys/values from the DB. This is generally
@@ -332,13 +414,20 @@ func (state *State) CommitStateDelta() error {
 // a snapshot.
 func (state *State) DeleteState() error {
 	state.ClearInMemoryChanges(false)
-	err := db.GetDBHandle().DeleteState()
+	err := state.getDB().DeleteState()
 	if err != nil {
 		logger.Errorf("Error deleting state: %s", err)
 	}
 	return err
 }
 
+func (state *State) getDB() *db.OpenchainDB {
+	if state.openchainDB != nil {
+		return state.openchainDB
+	}
+	return db.GetChainDBHandle(state.chainID)
+}
+
 func encodeStateDeltaKey(blockNumber uint64) []byte {
 	return encodeUint64(blockNumber)
 }
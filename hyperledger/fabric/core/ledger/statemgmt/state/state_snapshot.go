@@ -17,19 +17,19 @@ limitations under the License.
 package state
 
 import (
+	"github.com/hyperledger/fabric/core/db"
 	"github.com/hyperledger/fabric/core/ledger/statemgmt"
-	"github.com/tecbot/gorocksdb"
 )
 
 // StateSnapshot encapsulates StateSnapshotIterator given by actual state implementation and the db snapshot
 type StateSnapshot struct {
 	blockNumber  uint64
 	stateImplItr statemgmt.StateSnapshotIterator
-	dbSnapshot   *gorocksdb.Snapshot
+	dbSnapshot   db.Snapshot
 }
 
 // newStateSnapshot creates a new snapshot of the global state for the current block.
-func newStateSnapshot(blockNumber uint64, dbSnapshot *gorocksdb.Snapshot) (*StateSnapshot, error) {
+func newStateSnapshot(stateImpl statemgmt.HashableState, blockNumber uint64, dbSnapshot db.Snapshot) (*StateSnapshot, error) {
 	itr, err := stateImpl.GetStateSnapshotIterator(dbSnapshot)
 	if err != nil {
 		return nil, err
@@ -0,0 +1,237 @@
+/*
+Copyright IBM Corp. 2016 All Rights Reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+		 http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package state
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/hyperledger/fabric/core/ledger/statemgmt"
+)
+
+// TxRWSet tracks the keys read and the state changes made by a tx executed
+// concurrently with other txs of the batch, see State.TxBeginParallel
+type TxRWSet struct {
+	sync.Mutex
+	readKeys   map[string]map[string]bool
+	readRanges map[string][]keyRange
+	stateDelta *statemgmt.StateDelta
+	successful bool
+	aborted    bool
+	// number of txs whose changes were merged in the batch when the tx began
+	txStateDeltasSeen int
+}
+
+type keyRange struct {
+	startKey string
+	endKey   string
+}
+
+func newTxRWSet(txStateDeltasSeen int) *TxRWSet {
+	return &TxRWSet{readKeys: make(map[string]map[string]bool), readRanges: make(map[string][]keyRange),
+		stateDelta: statemgmt.NewStateDelta(), txStateDeltasSeen: txStateDeltasSeen}
+}
+
+func (rwset *TxRWSet) addRead(chaincodeID string, key string) {
+	keys, ok := rwset.readKeys[chaincodeID]
+	if !ok {
+		keys = make(map[string]bool)
+		rwset.readKeys[chaincodeID] = keys
+	}
+	keys[key] = true
+}
+
+func (rwset *TxRWSet) addRangeRead(chaincodeID string, startKey string, endKey string) {
+	rwset.readRanges[chaincodeID] = append(rwset.readRanges[chaincodeID], keyRange{startKey, endKey})
+}
+
+func (rwset *TxRWSet) finish(txSuccessful bool) {
+	rwset.Lock()
+	defer rwset.Unlock()
+	rwset.successful = txSuccessful
+	if !txSuccessful {
+		rwset.stateDelta = statemgmt.NewStateDelta()
+	}
+}
+
+// conflictsWith returns true if the tx read a key changed by stateDelta. Range
+// reads conflict with the changes of any key in the range, as for
+// statemgmt.NewStateDeltaRangeScanIterator an empty endKey has no upper bound.
+func (rwset *TxRWSet) conflictsWith(stateDelta *statemgmt.StateDelta) bool {
+	for _, chaincodeID := range stateDelta.GetUpdatedChaincodeIds(false) {
+		keys := rwset.readKeys[chaincodeID]
+		ranges := rwset.readRanges[chaincodeID]
+		if keys == nil && ranges == nil {
+			continue
+		}
+		for key := range stateDelta.GetUpdates(chaincodeID) {
+			if keys[key] {
+				return true
+			}
+			for _, r := range ranges {
+				if key >= r.startKey && (r.endKey == "" || key <= r.endKey) {
+					return true
+				}
+			}
+		}
+	}
+	return false
+}
+
+// TxBeginParallel marks the begin of a tx executed concurrently with other txs
+// of the batch. The tx sees its own changes on top of the state at this point,
+// which must not change until the tx finishes: its reads and changes are
+// tracked apart, through the TxGet, TxGetRangeScanIterator, TxSet and TxDelete
+// methods, until TxFinishParallel. TxBegin and TxFinish for the tx only record
+// whether it is successful.
+func (state *State) TxBeginParallel(txUUID string) {
+	logger.Debugf("txBeginParallel() for txUuid [%s]", txUUID)
+	if state.txInProgress() {
+		panic(fmt.Errorf("A tx [%s] is already in progress. Received call for begin of parallel tx [%s]", state.currentTxUUID, txUUID))
+	}
+	state.parallelTxsLock.Lock()
+	defer state.parallelTxsLock.Unlock()
+	if _, ok := state.parallelTxs[txUUID]; ok {
+		panic(fmt.Errorf("A parallel tx [%s] is already in progress", txUUID))
+	}
+	state.parallelTxs[txUUID] = newTxRWSet(len(state.txStateDeltas))
+}
+
+// TxFinishParallel marks the completion of a tx begun with TxBeginParallel.
+// The txs of the batch must finish in order. If the tx read keys changed by
+// the txs finished since it began, or was aborted, its changes are discarded
+// and false is returned: the tx has to be executed again to get the result of a
+// sequential execution. Otherwise the changes of a successful tx are merged as
+// TxFinish does and true is returned.
+func (state *State) TxFinishParallel(txUUID string) bool {
+	logger.Debugf("txFinishParallel() for txUuid [%s]", txUUID)
+	if state.txInProgress() {
+		panic(fmt.Errorf("A tx [%s] is in progress. Received call for finish of parallel tx [%s]", state.currentTxUUID, txUUID))
+	}
+	state.parallelTxsLock.Lock()
+	rwset, ok := state.parallelTxs[txUUID]
+	delete(state.parallelTxs, txUUID)
+	state.parallelTxsLock.Unlock()
+	if !ok {
+		panic(fmt.Errorf("No parallel tx [%s] in progress", txUUID))
+	}
+
+	rwset.Lock()
+	defer rwset.Unlock()
+	if rwset.aborted {
+		logger.Debugf("txFinishParallel() for txUuid [%s] aborted", txUUID)
+		return false
+	}
+	for _, txStateDelta := range state.txStateDeltas[rwset.txStateDeltasSeen:] {
+		if rwset.conflictsWith(txStateDelta.StateDelta) {
+			logger.Debugf("txFinishParallel() for txUuid [%s] conflicts with tx [%s]", txUUID, txStateDelta.TxUUID)
+			return false
+		}
+	}
+	if rwset.successful {
+		state.mergeTxStateDelta(txUUID, rwset.stateDelta)
+	}
+	return true
+}
+
+// AbortParallelTx marks a tx begun with TxBeginParallel as to be executed
+// again, see TxFinishParallel. It returns false if the tx is not executed in
+// parallel.
+func (state *State) AbortParallelTx(txUUID string) bool {
+	rwset := state.getParallelTx(txUUID)
+	if rwset == nil {
+		return false
+	}
+	rwset.Lock()
+	defer rwset.Unlock()
+	rwset.aborted = true
+	return true
+}
+
+func (state *State) getParallelTx(txUUID string) *TxRWSet {
+	state.parallelTxsLock.RLock()
+	defer state.parallelTxsLock.RUnlock()
+	return state.parallelTxs[txUUID]
+}
+
+// TxGet returns state for chaincodeID and key as seen by the tx txUUID. Unless
+// the tx was begun with TxBeginParallel, this is the same as Get.
+func (state *State) TxGet(txUUID string, chaincodeID string, key string, committed bool) ([]byte, error) {
+	rwset := state.getParallelTx(txUUID)
+	if rwset == nil || committed {
+		return state.Get(chaincodeID, key, committed)
+	}
+	rwset.Lock()
+	defer rwset.Unlock()
+	if valueHolder := rwset.stateDelta.Get(chaincodeID, key); valueHolder != nil {
+		return valueHolder.GetValue(), nil
+	}
+	rwset.addRead(chaincodeID, key)
+	if valueHolder := state.stateDelta.Get(chaincodeID, key); valueHolder != nil {
+		return valueHolder.GetValue(), nil
+	}
+	return state.stateImpl.Get(chaincodeID, key)
+}
+
+// TxGetRangeScanIterator returns an iterator over the keys between startKey and
+// endKey as seen by the tx txUUID. Unless the tx was begun with
+// TxBeginParallel, this is the same as GetRangeScanIterator.
+func (state *State) TxGetRangeScanIterator(txUUID string, chaincodeID string, startKey string, endKey string, committed bool) (statemgmt.RangeScanIterator, error) {
+	rwset := state.getParallelTx(txUUID)
+	if rwset == nil || committed {
+		return state.GetRangeScanIterator(chaincodeID, startKey, endKey, committed)
+	}
+	stateImplItr, err := state.stateImpl.GetRangeScanIterator(chaincodeID, startKey, endKey)
+	if err != nil {
+		return nil, err
+	}
+	rwset.Lock()
+	defer rwset.Unlock()
+	rwset.addRangeRead(chaincodeID, startKey, endKey)
+	return newCompositeRangeScanIterator(
+		statemgmt.NewStateDeltaRangeScanIterator(rwset.stateDelta, chaincodeID, startKey, endKey),
+		statemgmt.NewStateDeltaRangeScanIterator(state.stateDelta, chaincodeID, startKey, endKey),
+		stateImplItr), nil
+}
+
+// TxSet sets state to given value for chaincodeID and key in the context of
+// the tx txUUID. Unless the tx was begun with TxBeginParallel, this is the same
+// as Set.
+func (state *State) TxSet(txUUID string, chaincodeID string, key string, value []byte) error {
+	rwset := state.getParallelTx(txUUID)
+	if rwset == nil {
+		return state.Set(chaincodeID, key, value)
+	}
+	logger.Debugf("set() txUuid=[%s], chaincodeID=[%s], key=[%s], value=[%#v]", txUUID, chaincodeID, key, value)
+	rwset.Lock()
+	defer rwset.Unlock()
+	return state.setInTxStateDelta(rwset.stateDelta, chaincodeID, key, value)
+}
+
+// TxDelete tracks the deletion of state for chaincodeID and key in the context
+// of the tx txUUID. Unless the tx was begun with TxBeginParallel, this is the
+// same as Delete.
+func (state *State) TxDelete(txUUID string, chaincodeID string, key string) error {
+	rwset := state.getParallelTx(txUUID)
+	if rwset == nil {
+		return state.Delete(chaincodeID, key)
+	}
+	logger.Debugf("delete() txUuid=[%s], chaincodeID=[%s], key=[%s]", txUUID, chaincodeID, key)
+	rwset.Lock()
+	defer rwset.Unlock()
+	return state.deleteInTxStateDelta(rwset.stateDelta, chaincodeID, key)
+}
@@ -0,0 +1,125 @@
+/*
+Copyright IBM Corp. 2016 All Rights Reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+		 http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package state
+
+import (
+	"testing"
+
+	"github.com/hyperledger/fabric/core/ledger/testutil"
+)
+
+func TestStateParallelTxs(t *testing.T) {
+	stateTestWrapper, state := createFreshDBAndConstructState(t)
+	state.TxBegin("txUuid")
+	state.Set("chaincode1", "key1", []byte("value1"))
+	state.Set("chaincode2", "key1", []byte("value1"))
+	state.TxFinish("txUuid", true)
+	stateTestWrapper.persistAndClearInMemoryChanges(0)
+
+	for _, txUUID := range []string{"tx1", "tx2", "tx3", "tx4", "tx5", "tx6"} {
+		state.TxBeginParallel(txUUID)
+		state.TxBegin(txUUID)
+	}
+	// tx1 changes a key read by tx3
+	value, _ := state.TxGet("tx1", "chaincode1", "key1", false)
+	testutil.AssertEquals(t, value, []byte("value1"))
+	state.TxSet("tx1", "chaincode1", "key1", []byte("value2"))
+	value, _ = state.TxGet("tx1", "chaincode1", "key1", false)
+	testutil.AssertEquals(t, value, []byte("value2"))
+	state.TxFinish("tx1", true)
+
+	// tx2 adds a key in the range read by tx4
+	state.TxSet("tx2", "chaincode2", "key2", []byte("value2"))
+	state.TxFinish("tx2", true)
+
+	value, _ = state.TxGet("tx3", "chaincode1", "key1", false)
+	testutil.AssertEquals(t, value, []byte("value1"))
+	state.TxSet("tx3", "chaincode1", "key2", []byte("value1"))
+	state.TxFinish("tx3", true)
+
+	itr, _ := state.TxGetRangeScanIterator("tx4", "chaincode2", "key", "", false)
+	count := 0
+	for itr.Next() {
+		count++
+	}
+	itr.Close()
+	testutil.AssertEquals(t, count, 1)
+	state.TxFinish("tx4", true)
+
+	// a failed tx which read nothing changed keeps its result
+	state.TxSet("tx5", "chaincode1", "key3", []byte("value3"))
+	state.TxFinish("tx5", false)
+
+	testutil.AssertEquals(t, state.AbortParallelTx("tx6"), true)
+	testutil.AssertEquals(t, state.AbortParallelTx("unknown"), false)
+	state.TxFinish("tx6", true)
+
+	// nothing is visible outside of the txs until they finish
+	testutil.AssertNil(t, stateTestWrapper.get("chaincode2", "key2", false))
+
+	testutil.AssertEquals(t, state.TxFinishParallel("tx1"), true)
+	testutil.AssertEquals(t, state.TxFinishParallel("tx2"), true)
+	testutil.AssertEquals(t, state.TxFinishParallel("tx3"), false)
+	testutil.AssertEquals(t, state.TxFinishParallel("tx4"), false)
+	testutil.AssertEquals(t, state.TxFinishParallel("tx5"), true)
+	testutil.AssertEquals(t, state.TxFinishParallel("tx6"), false)
+	testutil.AssertNil(t, stateTestWrapper.get("chaincode1", "key2", false))
+	testutil.AssertNil(t, stateTestWrapper.get("chaincode1", "key3", false))
+
+	// the conflicting txs are executed again in order
+	state.TxBegin("tx3")
+	state.TxSet("tx3", "chaincode1", "key2", []byte("value2"))
+	state.TxFinish("tx3", true)
+	state.TxBegin("tx4")
+	state.TxFinish("tx4", true)
+	state.TxBegin("tx6")
+	state.TxSet("tx6", "chaincode2", "key3", []byte("value3"))
+	state.TxFinish("tx6", true)
+	parallelHash, _ := state.GetHash()
+	parallelDeltaHashes := state.GetTxStateDeltaHash()
+	testutil.AssertEquals(t, len(state.GetTxStateDeltas()), 4)
+
+	// same state as with a sequential execution
+	state.ClearInMemoryChanges(false)
+	state.TxBegin("tx1")
+	state.Set("chaincode1", "key1", []byte("value2"))
+	state.TxFinish("tx1", true)
+	state.TxBegin("tx2")
+	state.Set("chaincode2", "key2", []byte("value2"))
+	state.TxFinish("tx2", true)
+	state.TxBegin("tx3")
+	state.Set("chaincode1", "key2", []byte("value2"))
+	state.TxFinish("tx3", true)
+	state.TxBegin("tx4")
+	state.TxFinish("tx4", true)
+	state.TxBegin("tx5")
+	state.Set("chaincode1", "key3", []byte("value3"))
+	state.TxFinish("tx5", false)
+	state.TxBegin("tx6")
+	state.Set("chaincode2", "key3", []byte("value3"))
+	state.TxFinish("tx6", true)
+	sequentialHash, _ := state.GetHash()
+	testutil.AssertEquals(t, parallelHash, sequentialHash)
+	testutil.AssertEquals(t, parallelDeltaHashes, state.GetTxStateDeltaHash())
+}
+
+func TestStateParallelTxWrongCallCausePanic(t *testing.T) {
+	_, state := createFreshDBAndConstructState(t)
+	state.TxBegin("txUuid")
+	defer testutil.AssertPanic(t, "A parallel tx should not begin while a tx is in progress")
+	state.TxBeginParallel("txUuid2")
+}
@@ -19,12 +19,11 @@ package trie
 import (
 	"github.com/hyperledger/fabric/core/db"
 	"github.com/hyperledger/fabric/core/ledger/statemgmt"
-	"github.com/tecbot/gorocksdb"
 )
 
 // RangeScanIterator implements the interface 'statemgmt.RangeScanIterator'
 type RangeScanIterator struct {
-	dbItr        *gorocksdb.Iterator
+	dbItr        db.Iterator
 	chaincodeID  string
 	endKey       string
 	currentKey   string
@@ -32,8 +31,8 @@ type RangeScanIterator struct {
 	done         bool
 }
 
-func newRangeScanIterator(chaincodeID string, startKey string, endKey string) (*RangeScanIterator, error) {
-	dbItr := db.GetDBHandle().GetStateCFIterator()
+func newRangeScanIterator(openchainDB *db.OpenchainDB, chaincodeID string, startKey string, endKey string) (*RangeScanIterator, error) {
+	dbItr := openchainDB.GetStateCFIterator()
 	encodedStartKey := newTrieKey(chaincodeID, startKey).getEncodedBytes()
 	dbItr.Seek(encodedStartKey)
 	return &RangeScanIterator{dbItr, chaincodeID, endKey, "", nil, false}, nil
@@ -48,8 +47,8 @@ func (itr *RangeScanIterator) Next() bool {
 
 		// making a copy of key-value bytes because, underlying key bytes are reused by itr.
 		// no need to free slices as iterator frees memory when closed.
-		trieKeyBytes := statemgmt.Copy(itr.dbItr.Key().Data())
-		trieNodeBytes := statemgmt.Copy(itr.dbItr.Value().Data())
+		trieKeyBytes := statemgmt.Copy(itr.dbItr.Key())
+		trieNodeBytes := statemgmt.Copy(itr.dbItr.Value())
 		value := unmarshalTrieNodeValue(trieNodeBytes)
 		if value == nil {
 			continue
@@ -22,7 +22,6 @@ import (
 	"github.com/hyperledger/fabric/core/db"
 	"github.com/hyperledger/fabric/core/ledger/statemgmt"
 	"github.com/op/go-logging"
-	"github.com/tecbot/gorocksdb"
 )
 
 var stateTrieLogger = logging.MustGetLogger("stateTrie")
@@ -31,6 +30,8 @@ var logHashOfEveryNode = false
 // StateTrie defines the trie for the state, a merkle tree where keys
 // and values are stored for fast hash computation.
 type StateTrie struct {
+	chainID                string
+	openchainDB            *db.OpenchainDB
 	trieDelta              *trieDelta
 	persistedStateHash     []byte
 	lastComputedCryptoHash []byte
@@ -42,9 +43,26 @@ func NewStateTrie() *StateTrie {
 	return &StateTrie{}
 }
 
+// NewChainStateTrie contructs a new empty StateTrie persisting to the db of the given chain
+func NewChainStateTrie(chainID string) *StateTrie {
+	return &StateTrie{chainID: chainID}
+}
+
+// NewStateTrieWithDB contructs a new empty StateTrie persisting to the given db
+func NewStateTrieWithDB(openchainDB *db.OpenchainDB) *StateTrie {
+	return &StateTrie{openchainDB: openchainDB}
+}
+
+func (stateTrie *StateTrie) getDB() *db.OpenchainDB {
+	if stateTrie.openchainDB != nil {
+		return stateTrie.openchainDB
+	}
+	return db.GetChainDBHandle(stateTrie.chainID)
+}
+
 // Initialize the state trie with the root key
 func (stateTrie *StateTrie) Initialize(configs map[string]interface{}) error {
-	rootNode, err := fetchTrieNodeFromDB(rootTrieKey)
+	rootNode, err := fetchTrieNodeFromDB(stateTrie.getDB(), rootTrieKey)
 	if err != nil {
 		panic(fmt.Errorf("Error in fetching root node from DB while initializing state trie: %s", err))
 	}
@@ -57,7 +75,7 @@ func (stateTrie *StateTrie) Initialize(configs map[string]interface{}) error {
 
 // Get the value for a given chaincode ID and key
 func (stateTrie *StateTrie) Get(chaincodeID string, key string) ([]byte, error) {
-	trieNode, err := fetchTrieNodeFromDB(newTrieKey(chaincodeID, key))
+	trieNode, err := fetchTrieNodeFromDB(stateTrie.getDB(), newTrieKey(chaincodeID, key))
 	if err != nil {
 		return nil, err
 	}
@@ -117,7 +135,7 @@ func (stateTrie *StateTrie) ComputeCryptoHash() ([]byte, error) {
 
 func (stateTrie *StateTrie) processChangedNode(changedNode *trieNode) error {
 	stateTrieLogger.Debugf("Enter - processChangedNode() for node [%s]", changedNode)
-	dbNode, err := fetchTrieNodeFromDB(changedNode.trieKey)
+	dbNode, err := fetchTrieNodeFromDB(stateTrie.getDB(), changedNode.trieKey)
 	if err != nil {
 		return err
 	}
@@ -141,7 +159,7 @@ func (stateTrie *StateTrie) processChangedNode(changedNode *trieNode) error {
 }
 
 // AddChangesForPersistence commits current changes to the database
-func (stateTrie *StateTrie) AddChangesForPersistence(writeBatch *gorocksdb.WriteBatch) error {
+func (stateTrie *StateTrie) AddChangesForPersistence(writeBatch db.WriteBatch) error {
 	if stateTrie.recomputeCryptoHash {
 		_, err := stateTrie.ComputeCryptoHash()
 		if err != nil {
@@ -154,7 +172,7 @@ func (stateTrie *StateTrie) AddChangesForPersistence(writeBatch *gorocksdb.Write
 		return nil
 	}
 
-	openchainDB := db.GetDBHandle()
+	openchainDB := stateTrie.getDB()
 	lowestLevel := stateTrie.trieDelta.getLowestLevel()
 	for level := lowestLevel; level >= 0; level-- {
 		changedNodes := stateTrie.trieDelta.deltaMap[level]
@@ -180,11 +198,11 @@ func (stateTrie *StateTrie) PerfHintKeyChanged(chaincodeID string, key string) {
 }
 
 // GetStateSnapshotIterator - method implementation for interface 'statemgmt.HashableState'
-func (stateTrie *StateTrie) GetStateSnapshotIterator(snapshot *gorocksdb.Snapshot) (statemgmt.StateSnapshotIterator, error) {
-	return newStateSnapshotIterator(snapshot)
+func (stateTrie *StateTrie) GetStateSnapshotIterator(snapshot db.Snapshot) (statemgmt.StateSnapshotIterator, error) {
+	return newStateSnapshotIterator(stateTrie.getDB(), snapshot)
 }
 
 // GetRangeScanIterator returns an iterator for performing a range scan between the start and end keys
 func (stateTrie *StateTrie) GetRangeScanIterator(chaincodeID string, startKey string, endKey string) (statemgmt.RangeScanIterator, error) {
-	return newRangeScanIterator(chaincodeID, startKey, endKey)
+	return newRangeScanIterator(stateTrie.getDB(), chaincodeID, startKey, endKey)
 }
@@ -0,0 +1,261 @@
+/*
+Copyright IBM Corp. 2016 All Rights Reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+		 http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ledger
+
+import (
+	"bytes"
+	"fmt"
+	"math/rand"
+	"strings"
+
+	"github.com/hyperledger/fabric/core/ledger/statemgmt/state"
+)
+
+// VerifyOptions configures the checks run by Ledger.Verify
+type VerifyOptions struct {
+	// StateSampleRate is the fraction of the state buckets whose crypto-hash
+	// is recomputed from the key-values, 1 recomputing the whole state hash
+	StateSampleRate float64
+	// IndexSampleRate is the fraction of the blocks whose index entries are
+	// checked
+	IndexSampleRate float64
+	// Repair rewrites the index entries found missing or wrong and replays the
+	// state deltas onto a state lagging behind the chain
+	Repair bool
+}
+
+// VerifyReport is the result of Ledger.Verify
+type VerifyReport struct {
+	Height uint64
+	// BrokenBlock is the highest block whose previous block hash does not
+	// match the hash of the previous block, 0 if the chain is valid
+	BrokenBlock uint64
+	// StateHash is the hash of the persisted state and HeadStateHash the state
+	// hash recorded in the last block
+	StateHash     []byte
+	HeadStateHash []byte
+	// StateErrors lists the intermediate hashes of the state found not to
+	// match its key-values
+	StateErrors []string
+	// StateNotVerified is set if the state implementation cannot check its
+	// hash against its key-values
+	StateNotVerified bool
+	IndexErrors      []string
+	RepairedIndexes  int
+	// StateRepaired is set if the state has been brought up to the last block
+	// by replaying the state deltas
+	StateRepaired bool
+	// NeedsStateTransfer is set if the state could not be repaired locally
+	NeedsStateTransfer bool
+}
+
+// OK returns true if no inconsistency remains in the ledger
+func (report *VerifyReport) OK() bool {
+	return report.BrokenBlock == 0 && !report.NeedsStateTransfer &&
+		len(report.IndexErrors) == report.RepairedIndexes
+}
+
+func (report *VerifyReport) String() string {
+	lines := []string{fmt.Sprintf("height=[%d], stateHash=[%x], headStateHash=[%x]", report.Height, report.StateHash, report.HeadStateHash)}
+	if report.BrokenBlock != 0 {
+		lines = append(lines, fmt.Sprintf("previous block hash of block [%d] does not match", report.BrokenBlock))
+	}
+	if report.StateNotVerified {
+		lines = append(lines, "the state implementation cannot verify the state hash")
+	}
+	lines = append(lines, report.StateErrors...)
+	if report.StateRepaired {
+		lines = append(lines, "state repaired by replaying the state deltas")
+	}
+	if report.NeedsStateTransfer {
+		lines = append(lines, "state cannot be repaired locally, a state transfer is needed")
+	}
+	lines = append(lines, report.IndexErrors...)
+	if report.RepairedIndexes > 0 {
+		lines = append(lines, fmt.Sprintf("%d index entries repaired", report.RepairedIndexes))
+	}
+	return strings.Join(lines, "\n")
+}
+
+// Verify checks the consistency of the ledger, as a crash or a disk failure
+// may leave it: the previous block hashes of the chain, the hash of the state
+// against the last block and against a sample of its key-values, and the
+// indexes of a sample of the blocks. With opts.Repair, the wrong index entries
+// are rewritten and a state lagging behind the chain, which the hash of an
+// earlier block matches, is brought up to date with the state deltas. A state
+// which cannot be repaired this way is flagged as damaged, see IsStateDamaged.
+// Verify must not run concurrently with a transaction batch.
+func (ledger *Ledger) Verify(opts VerifyOptions) (*VerifyReport, error) {
+	if err := ledger.checkValidIDBegin(); err != nil {
+		return nil, err
+	}
+	report := &VerifyReport{Height: ledger.GetBlockchainSize()}
+	if report.Height == 0 {
+		return report, nil
+	}
+
+	validBlock, err := ledger.VerifyChain(report.Height-1, 0)
+	if err != nil {
+		return nil, err
+	}
+	report.BrokenBlock = validBlock
+
+	if err = ledger.verifyState(report, opts); err != nil {
+		return nil, err
+	}
+	if err = ledger.verifyIndexes(report, opts); err != nil {
+		return nil, err
+	}
+	ledger.stateDamaged = report.NeedsStateTransfer
+	return report, nil
+}
+
+// IsStateDamaged returns true if Verify found the state inconsistent and could
+// not repair it, until a state transfer brings the state to the hash of the
+// last block
+func (ledger *Ledger) IsStateDamaged() bool {
+	return ledger.stateDamaged
+}
+
+// isStateAtLastBlock returns true if the hash of the state is the state hash
+// recorded in the last block
+func (ledger *Ledger) isStateAtLastBlock() bool {
+	head, err := ledger.blockchain.getLastBlock()
+	if err != nil || head == nil {
+		return false
+	}
+	stateHash, err := ledger.state.GetHash()
+	return err == nil && bytes.Equal(stateHash, head.StateHash)
+}
+
+func (ledger *Ledger) verifyState(report *VerifyReport, opts VerifyOptions) error {
+	head, err := ledger.GetBlockByNumber(report.Height - 1)
+	if err != nil {
+		return err
+	}
+	report.HeadStateHash = head.StateHash
+	if report.StateHash, err = ledger.state.GetHash(); err != nil {
+		return err
+	}
+	if !bytes.Equal(report.StateHash, report.HeadStateHash) && opts.Repair {
+		if report.StateRepaired, err = ledger.replayStateDeltas(report); err != nil {
+			return err
+		}
+	}
+
+	report.StateErrors, err = ledger.state.VerifyHash(opts.StateSampleRate)
+	if err == state.ErrHashNotVerifiable {
+		report.StateNotVerified = true
+	} else if err != nil {
+		return err
+	}
+	report.NeedsStateTransfer = len(report.StateErrors) > 0 || !bytes.Equal(report.StateHash, report.HeadStateHash)
+	return nil
+}
+
+// replayStateDeltas looks for the last block whose state hash is the hash of
+// the persisted state, within the blocks whose state delta is still kept, and
+// applies the state deltas of the following blocks
+func (ledger *Ledger) replayStateDeltas(report *VerifyReport) (bool, error) {
+	// first is the first block whose state delta is to be applied
+	var first uint64
+	for blockNumber := report.Height - 1; blockNumber > 0 && first == 0; blockNumber-- {
+		delta, err := ledger.GetStateDelta(blockNumber)
+		if err != nil {
+			return false, err
+		}
+		if delta == nil {
+			break
+		}
+		block, err := ledger.GetBlockByNumber(blockNumber - 1)
+		if err != nil {
+			return false, err
+		}
+		if bytes.Equal(block.StateHash, report.StateHash) {
+			first = blockNumber
+		}
+	}
+	if first == 0 {
+		ledgerLogger.Warningf("No block with the state hash [%x] found, the state cannot be repaired locally", report.StateHash)
+		return false, nil
+	}
+
+	for blockNumber := first; blockNumber < report.Height; blockNumber++ {
+		ledgerLogger.Infof("Repairing the state with the state delta of block [%d]", blockNumber)
+		delta, err := ledger.GetStateDelta(blockNumber)
+		if err != nil {
+			return false, err
+		}
+		id := fmt.Sprintf("repair-%d", blockNumber)
+		if err = ledger.ApplyStateDelta(id, delta); err != nil {
+			return false, err
+		}
+		if err = ledger.CommitStateDelta(id); err != nil {
+			return false, err
+		}
+	}
+	stateHash, err := ledger.state.GetHash()
+	if err != nil {
+		return false, err
+	}
+	report.StateHash = stateHash
+	return bytes.Equal(stateHash, report.HeadStateHash), nil
+}
+
+// verifyIndexes checks the block hash and transaction UUID index entries of a
+// sample of the blocks still in the DB, rewriting them with opts.Repair
+func (ledger *Ledger) verifyIndexes(report *VerifyReport, opts VerifyOptions) error {
+	openchainDB := ledger.getDB()
+	writeBatch := openchainDB.NewWriteBatch()
+	defer writeBatch.Destroy()
+	for blockNumber := ledger.GetPrunedHeight(); blockNumber < report.Height; blockNumber++ {
+		if opts.IndexSampleRate < 1 && rand.Float64() >= opts.IndexSampleRate {
+			continue
+		}
+		block, err := fetchBlockFromDB(openchainDB, blockNumber)
+		if err != nil {
+			return err
+		}
+		blockHash, err := block.GetHash()
+		if err != nil {
+			return err
+		}
+		var errs []string
+		indexedNumber, err := fetchBlockNumberByBlockHashFromDB(openchainDB, blockHash)
+		if err != nil || indexedNumber != blockNumber {
+			errs = append(errs, fmt.Sprintf("Block hash [%x] of block [%d] is not indexed", blockHash, blockNumber))
+		}
+		for txIndex, tx := range block.GetTransactions() {
+			indexedNumber, indexedTxIndex, err := fetchTransactionIndexByUUIDFromDB(openchainDB, tx.Uuid)
+			if err != nil || indexedNumber != blockNumber || indexedTxIndex != uint64(txIndex) {
+				errs = append(errs, fmt.Sprintf("Transaction [%s] of block [%d] is not indexed", tx.Uuid, blockNumber))
+			}
+		}
+		report.IndexErrors = append(report.IndexErrors, errs...)
+		if len(errs) > 0 && opts.Repair {
+			if err = addIndexDataForPersistence(openchainDB, block, blockNumber, blockHash, writeBatch); err != nil {
+				return err
+			}
+			report.RepairedIndexes += len(errs)
+		}
+	}
+	if report.RepairedIndexes == 0 {
+		return nil
+	}
+	ledgerLogger.Infof("Repairing %d index entries", report.RepairedIndexes)
+	return openchainDB.Write(writeBatch)
+}
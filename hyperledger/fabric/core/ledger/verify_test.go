@@ -0,0 +1,153 @@
+/*
+Copyright IBM Corp. 2016 All Rights Reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+		 http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ledger
+
+import (
+	"testing"
+
+	"github.com/hyperledger/fabric/core/ledger/statemgmt"
+	"github.com/hyperledger/fabric/core/ledger/testutil"
+	"github.com/hyperledger/fabric/protos"
+)
+
+func commitTestBlocksForVerify(t *testing.T, ledger *Ledger, numBlocks int) {
+	for i := 0; i < numBlocks; i++ {
+		ledger.BeginTxBatch(i)
+		ledger.TxBegin("txUuid")
+		ledger.SetState("chaincode1", "key1", []byte{byte(i)})
+		ledger.SetState("chaincode2", "key2", []byte{byte(i + 1)})
+		ledger.TxFinished("txUuid", true)
+		transaction, _ := buildTestTx(t)
+		err := ledger.CommitTxBatch(i, []*protos.Transaction{transaction}, nil, []byte("proof"))
+		testutil.AssertNoError(t, err, "Error committing block")
+	}
+}
+
+func TestVerifyHealthyLedger(t *testing.T) {
+	ledgerTestWrapper := createFreshDBAndTestLedgerWrapper(t)
+	ledger := ledgerTestWrapper.ledger
+
+	report, err := ledger.Verify(VerifyOptions{StateSampleRate: 1, IndexSampleRate: 1})
+	testutil.AssertNoError(t, err, "Error verifying an empty ledger")
+	testutil.AssertEquals(t, report.OK(), true)
+
+	commitTestBlocksForVerify(t, ledger, 3)
+	report, err = ledger.Verify(VerifyOptions{StateSampleRate: 1, IndexSampleRate: 1})
+	testutil.AssertNoError(t, err, "Error verifying ledger")
+	testutil.AssertEquals(t, report.OK(), true)
+	testutil.AssertEquals(t, report.Height, uint64(3))
+	testutil.AssertEquals(t, report.StateHash, report.HeadStateHash)
+	testutil.AssertEquals(t, len(report.StateErrors), 0)
+	testutil.AssertEquals(t, len(report.IndexErrors), 0)
+	testutil.AssertEquals(t, ledger.IsStateDamaged(), false)
+
+	// Verify refuses to run during a transaction batch
+	ledger.BeginTxBatch(3)
+	_, err = ledger.Verify(VerifyOptions{})
+	testutil.AssertError(t, err, "Expected an error verifying the ledger during a transaction batch")
+	ledger.RollbackTxBatch(3)
+}
+
+func TestVerifyRepairsStateByReplayingDeltas(t *testing.T) {
+	ledgerTestWrapper := createFreshDBAndTestLedgerWrapper(t)
+	ledger := ledgerTestWrapper.ledger
+	commitTestBlocksForVerify(t, ledger, 4)
+
+	// Roll the state back to block 1, as an interrupted state transfer may leave it
+	for blockNumber := uint64(3); blockNumber > 1; blockNumber-- {
+		delta := ledgerTestWrapper.GetStateDelta(blockNumber)
+		delta.RollBackwards = true
+		ledgerTestWrapper.ApplyStateDelta(blockNumber, delta)
+		ledgerTestWrapper.CommitStateDelta(blockNumber)
+	}
+	testutil.AssertEquals(t, ledgerTestWrapper.GetState("chaincode1", "key1", true), []byte{1})
+
+	report, err := ledger.Verify(VerifyOptions{StateSampleRate: 1, IndexSampleRate: 1})
+	testutil.AssertNoError(t, err, "Error verifying ledger")
+	testutil.AssertEquals(t, report.OK(), false)
+	testutil.AssertEquals(t, report.NeedsStateTransfer, true)
+	testutil.AssertEquals(t, report.StateHash, ledgerTestWrapper.GetBlockByNumber(1).StateHash)
+	testutil.AssertEquals(t, ledger.IsStateDamaged(), true)
+
+	report, err = ledger.Verify(VerifyOptions{StateSampleRate: 1, IndexSampleRate: 1, Repair: true})
+	testutil.AssertNoError(t, err, "Error repairing ledger")
+	testutil.AssertEquals(t, report.OK(), true)
+	testutil.AssertEquals(t, report.StateRepaired, true)
+	testutil.AssertEquals(t, report.StateHash, report.HeadStateHash)
+	testutil.AssertEquals(t, ledger.IsStateDamaged(), false)
+	testutil.AssertEquals(t, ledgerTestWrapper.GetState("chaincode1", "key1", true), []byte{3})
+	testutil.AssertEquals(t, ledgerTestWrapper.GetState("chaincode2", "key2", true), []byte{4})
+}
+
+func TestVerifyDetectsUnrepairableState(t *testing.T) {
+	ledgerTestWrapper := createFreshDBAndTestLedgerWrapper(t)
+	ledger := ledgerTestWrapper.ledger
+	commitTestBlocksForVerify(t, ledger, 2)
+
+	// A state matching no block cannot be repaired from the state deltas
+	delta := statemgmt.NewStateDelta()
+	delta.Set("chaincode1", "key1", []byte("unknown"), []byte{1})
+	ledgerTestWrapper.ApplyStateDelta(2, delta)
+	ledgerTestWrapper.CommitStateDelta(2)
+
+	report, err := ledger.Verify(VerifyOptions{StateSampleRate: 1, IndexSampleRate: 1, Repair: true})
+	testutil.AssertNoError(t, err, "Error verifying ledger")
+	testutil.AssertEquals(t, report.OK(), false)
+	testutil.AssertEquals(t, report.StateRepaired, false)
+	testutil.AssertEquals(t, report.NeedsStateTransfer, true)
+	testutil.AssertEquals(t, ledger.IsStateDamaged(), true)
+
+	// The state is no longer damaged once brought back to the last block
+	delta.RollBackwards = true
+	ledgerTestWrapper.ApplyStateDelta(3, delta)
+	ledgerTestWrapper.CommitStateDelta(3)
+	testutil.AssertEquals(t, ledger.IsStateDamaged(), false)
+}
+
+func TestVerifyRepairsIndexes(t *testing.T) {
+	ledgerTestWrapper := createFreshDBAndTestLedgerWrapper(t)
+	ledger := ledgerTestWrapper.ledger
+	commitTestBlocksForVerify(t, ledger, 3)
+
+	block := ledgerTestWrapper.GetBlockByNumber(1)
+	blockHash, _ := block.GetHash()
+	txUUID := block.GetTransactions()[0].Uuid
+	openchainDB := ledger.getDB()
+	writeBatch := openchainDB.NewWriteBatch()
+	defer writeBatch.Destroy()
+	writeBatch.DeleteCF(openchainDB.IndexesCF, encodeTxUUIDKey(txUUID))
+	writeBatch.PutCF(openchainDB.IndexesCF, encodeBlockHashKey(blockHash), encodeBlockNumber(2))
+	testutil.AssertNoError(t, openchainDB.Write(writeBatch), "Error writing to DB")
+
+	report, err := ledger.Verify(VerifyOptions{IndexSampleRate: 1})
+	testutil.AssertNoError(t, err, "Error verifying ledger")
+	testutil.AssertEquals(t, report.OK(), false)
+	testutil.AssertEquals(t, len(report.IndexErrors), 2)
+	testutil.AssertEquals(t, report.RepairedIndexes, 0)
+
+	report, err = ledger.Verify(VerifyOptions{IndexSampleRate: 1, Repair: true})
+	testutil.AssertNoError(t, err, "Error repairing ledger")
+	testutil.AssertEquals(t, report.OK(), true)
+	testutil.AssertEquals(t, report.RepairedIndexes, 2)
+	tx, err := ledger.GetTransactionByUUID(txUUID)
+	testutil.AssertNoError(t, err, "Error getting transaction")
+	testutil.AssertEquals(t, tx.Uuid, txUUID)
+
+	report, err = ledger.Verify(VerifyOptions{IndexSampleRate: 1})
+	testutil.AssertNoError(t, err, "Error verifying ledger")
+	testutil.AssertEquals(t, len(report.IndexErrors), 0)
+}
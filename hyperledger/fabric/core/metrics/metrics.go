@@ -0,0 +1,459 @@
+/*
+Copyright IBM Corp. 2016 All Rights Reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+		 http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package metrics implements the counters, gauges and histograms of the peer
+// and exposes them in the Prometheus text format.
+package metrics
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// ContentType is the content type of the Prometheus text format
+const ContentType = "text/plain; version=0.0.4; charset=utf-8"
+
+// DefBuckets are the default buckets of the histograms, in seconds
+var DefBuckets = []float64{.0005, .001, .0025, .005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10}
+
+// DefaultRegistry holds the metrics created by the package functions
+var DefaultRegistry = NewRegistry()
+
+// Registry holds metric families by name
+type Registry struct {
+	lock     sync.RWMutex
+	families map[string]*family
+}
+
+// NewRegistry returns an empty registry
+func NewRegistry() *Registry {
+	return &Registry{families: make(map[string]*family)}
+}
+
+// family is a metric and its children, one for each combination of the values
+// of its labels
+type family struct {
+	name     string
+	help     string
+	kind     string
+	labels   []string
+	newChild func() child
+
+	lock     sync.RWMutex
+	children map[string]child
+	values   map[string][]string
+}
+
+// child is a single time series, or the series of a histogram
+type child interface {
+	write(w io.Writer, name string, labels string)
+}
+
+func (r *Registry) register(name, help, kind string, labels []string, newChild func() child) *family {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	if _, ok := r.families[name]; ok {
+		panic(fmt.Sprintf("metric %s registered twice", name))
+	}
+	f := &family{name: name, help: help, kind: kind, labels: labels, newChild: newChild,
+		children: make(map[string]child), values: make(map[string][]string)}
+	r.families[name] = f
+	return f
+}
+
+// with returns the child for the label values, creating it if necessary
+func (f *family) with(values []string) child {
+	if len(values) != len(f.labels) {
+		panic(fmt.Sprintf("metric %s has %d labels, got %d values", f.name, len(f.labels), len(values)))
+	}
+	key := strings.Join(values, "\xff")
+	f.lock.RLock()
+	c, ok := f.children[key]
+	f.lock.RUnlock()
+	if ok {
+		return c
+	}
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	if c, ok = f.children[key]; !ok {
+		c = f.newChild()
+		f.children[key] = c
+		f.values[key] = append([]string(nil), values...)
+	}
+	return c
+}
+
+func (f *family) write(w io.Writer) {
+	fmt.Fprintf(w, "# HELP %s %s\n", f.name, escapeHelp(f.help))
+	fmt.Fprintf(w, "# TYPE %s %s\n", f.name, f.kind)
+	f.lock.RLock()
+	defer f.lock.RUnlock()
+	keys := make([]string, 0, len(f.children))
+	for key := range f.children {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		f.children[key].write(w, f.name, formatLabels(f.labels, f.values[key]))
+	}
+}
+
+// WriteText writes the metrics of the registry in the Prometheus text format,
+// sorted by name
+func (r *Registry) WriteText(w io.Writer) error {
+	r.lock.RLock()
+	names := make([]string, 0, len(r.families))
+	for name := range r.families {
+		names = append(names, name)
+	}
+	r.lock.RUnlock()
+	sort.Strings(names)
+
+	bw := bufio.NewWriter(w)
+	for _, name := range names {
+		r.lock.RLock()
+		f := r.families[name]
+		r.lock.RUnlock()
+		f.write(bw)
+	}
+	return bw.Flush()
+}
+
+// ServeHTTP serves the metrics of the registry to a Prometheus scrape
+func (r *Registry) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	w.Header().Set("Content-Type", ContentType)
+	r.WriteText(w)
+}
+
+// Handler returns the http handler serving the metrics of the default registry
+func Handler() http.Handler {
+	return DefaultRegistry
+}
+
+func formatLabels(names []string, values []string) string {
+	if len(names) == 0 {
+		return ""
+	}
+	pairs := make([]string, len(names))
+	for i, name := range names {
+		pairs[i] = fmt.Sprintf(`%s="%s"`, name, labelReplacer.Replace(values[i]))
+	}
+	return strings.Join(pairs, ",")
+}
+
+var (
+	helpReplacer  = strings.NewReplacer("\\", `\\`, "\n", `\n`)
+	labelReplacer = strings.NewReplacer("\\", `\\`, "\n", `\n`, "\"", `\"`)
+)
+
+func escapeHelp(help string) string {
+	return helpReplacer.Replace(help)
+}
+
+func formatFloat(v float64) string {
+	switch {
+	case math.IsInf(v, 1):
+		return "+Inf"
+	case math.IsInf(v, -1):
+		return "-Inf"
+	case math.IsNaN(v):
+		return "NaN"
+	}
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
+
+func writeSample(w io.Writer, name string, labels string, v float64) {
+	if labels == "" {
+		fmt.Fprintf(w, "%s %s\n", name, formatFloat(v))
+	} else {
+		fmt.Fprintf(w, "%s{%s} %s\n", name, labels, formatFloat(v))
+	}
+}
+
+// value is a float64 updated atomically
+type value struct {
+	bits uint64
+}
+
+func (v *value) get() float64 {
+	return math.Float64frombits(atomic.LoadUint64(&v.bits))
+}
+
+func (v *value) set(f float64) {
+	atomic.StoreUint64(&v.bits, math.Float64bits(f))
+}
+
+func (v *value) add(delta float64) {
+	for {
+		old := atomic.LoadUint64(&v.bits)
+		if atomic.CompareAndSwapUint64(&v.bits, old, math.Float64bits(math.Float64frombits(old)+delta)) {
+			return
+		}
+	}
+}
+
+func (v *value) write(w io.Writer, name string, labels string) {
+	writeSample(w, name, labels, v.get())
+}
+
+// Counter is a value which only goes up
+type Counter struct {
+	v value
+}
+
+// Inc increments the counter by 1
+func (c *Counter) Inc() {
+	c.v.add(1)
+}
+
+// Add adds delta, which must not be negative, to the counter
+func (c *Counter) Add(delta float64) {
+	if delta < 0 {
+		panic("counter cannot decrease")
+	}
+	c.v.add(delta)
+}
+
+// Value returns the current value of the counter
+func (c *Counter) Value() float64 {
+	return c.v.get()
+}
+
+func (c *Counter) write(w io.Writer, name string, labels string) {
+	c.v.write(w, name, labels)
+}
+
+// Gauge is a value which goes up and down
+type Gauge struct {
+	v value
+}
+
+// Set sets the gauge to v
+func (g *Gauge) Set(v float64) {
+	g.v.set(v)
+}
+
+// Add adds delta to the gauge
+func (g *Gauge) Add(delta float64) {
+	g.v.add(delta)
+}
+
+// Inc increments the gauge by 1
+func (g *Gauge) Inc() {
+	g.v.add(1)
+}
+
+// Dec decrements the gauge by 1
+func (g *Gauge) Dec() {
+	g.v.add(-1)
+}
+
+// Value returns the current value of the gauge
+func (g *Gauge) Value() float64 {
+	return g.v.get()
+}
+
+func (g *Gauge) write(w io.Writer, name string, labels string) {
+	g.v.write(w, name, labels)
+}
+
+// Histogram counts observations in buckets of increasing upper bounds
+type Histogram struct {
+	lock    sync.Mutex
+	buckets []float64
+	counts  []uint64
+	count   uint64
+	sum     float64
+}
+
+func newHistogram(buckets []float64) *Histogram {
+	if len(buckets) == 0 {
+		buckets = DefBuckets
+	}
+	for i := 1; i < len(buckets); i++ {
+		if buckets[i] <= buckets[i-1] {
+			panic("histogram buckets must be in increasing order")
+		}
+	}
+	return &Histogram{buckets: buckets, counts: make([]uint64, len(buckets))}
+}
+
+// Observe adds an observation to the histogram
+func (h *Histogram) Observe(v float64) {
+	i := sort.SearchFloat64s(h.buckets, v)
+	h.lock.Lock()
+	defer h.lock.Unlock()
+	if i < len(h.counts) {
+		h.counts[i]++
+	}
+	h.count++
+	h.sum += v
+}
+
+// ObserveSince observes the time elapsed since start in seconds, as in
+// defer h.ObserveSince(time.Now())
+func (h *Histogram) ObserveSince(start time.Time) {
+	h.Observe(time.Since(start).Seconds())
+}
+
+// Count returns the number of observations
+func (h *Histogram) Count() uint64 {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+	return h.count
+}
+
+// Sum returns the sum of the observations
+func (h *Histogram) Sum() float64 {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+	return h.sum
+}
+
+func (h *Histogram) write(w io.Writer, name string, labels string) {
+	h.lock.Lock()
+	counts := append([]uint64(nil), h.counts...)
+	count, sum := h.count, h.sum
+	h.lock.Unlock()
+
+	prefix := labels
+	if prefix != "" {
+		prefix += ","
+	}
+	var cumulative uint64
+	for i, bound := range h.buckets {
+		cumulative += counts[i]
+		writeSample(w, name+"_bucket", fmt.Sprintf(`%sle="%s"`, prefix, formatFloat(bound)), float64(cumulative))
+	}
+	writeSample(w, name+"_bucket", prefix+`le="+Inf"`, float64(count))
+	writeSample(w, name+"_sum", labels, sum)
+	writeSample(w, name+"_count", labels, float64(count))
+}
+
+// CounterVec is a counter partitioned by labels
+type CounterVec struct {
+	f *family
+}
+
+// With returns the counter for the label values, in the order of the labels
+func (v *CounterVec) With(values ...string) *Counter {
+	return v.f.with(values).(*Counter)
+}
+
+// GaugeVec is a gauge partitioned by labels
+type GaugeVec struct {
+	f *family
+}
+
+// With returns the gauge for the label values, in the order of the labels
+func (v *GaugeVec) With(values ...string) *Gauge {
+	return v.f.with(values).(*Gauge)
+}
+
+// HistogramVec is a histogram partitioned by labels
+type HistogramVec struct {
+	f *family
+}
+
+// With returns the histogram for the label values, in the order of the labels
+func (v *HistogramVec) With(values ...string) *Histogram {
+	return v.f.with(values).(*Histogram)
+}
+
+func newCounter() child {
+	return &Counter{}
+}
+
+func newGauge() child {
+	return &Gauge{}
+}
+
+func histogramFactory(buckets []float64) func() child {
+	//check the buckets on registration rather than on first use
+	newHistogram(buckets)
+	return func() child { return newHistogram(buckets) }
+}
+
+// NewCounter registers a counter in the registry
+func (r *Registry) NewCounter(name, help string) *Counter {
+	return r.NewCounterVec(name, help).With()
+}
+
+// NewCounterVec registers a counter with labels in the registry
+func (r *Registry) NewCounterVec(name, help string, labels ...string) *CounterVec {
+	return &CounterVec{r.register(name, help, "counter", labels, newCounter)}
+}
+
+// NewGauge registers a gauge in the registry
+func (r *Registry) NewGauge(name, help string) *Gauge {
+	return r.NewGaugeVec(name, help).With()
+}
+
+// NewGaugeVec registers a gauge with labels in the registry
+func (r *Registry) NewGaugeVec(name, help string, labels ...string) *GaugeVec {
+	return &GaugeVec{r.register(name, help, "gauge", labels, newGauge)}
+}
+
+// NewHistogram registers a histogram in the registry, with DefBuckets if
+// buckets is nil
+func (r *Registry) NewHistogram(name, help string, buckets []float64) *Histogram {
+	return r.NewHistogramVec(name, help, buckets).With()
+}
+
+// NewHistogramVec registers a histogram with labels in the registry, with
+// DefBuckets if buckets is nil
+func (r *Registry) NewHistogramVec(name, help string, buckets []float64, labels ...string) *HistogramVec {
+	return &HistogramVec{r.register(name, help, "histogram", labels, histogramFactory(buckets))}
+}
+
+// NewCounter registers a counter in the default registry
+func NewCounter(name, help string) *Counter {
+	return DefaultRegistry.NewCounter(name, help)
+}
+
+// NewCounterVec registers a counter with labels in the default registry
+func NewCounterVec(name, help string, labels ...string) *CounterVec {
+	return DefaultRegistry.NewCounterVec(name, help, labels...)
+}
+
+// NewGauge registers a gauge in the default registry
+func NewGauge(name, help string) *Gauge {
+	return DefaultRegistry.NewGauge(name, help)
+}
+
+// NewGaugeVec registers a gauge with labels in the default registry
+func NewGaugeVec(name, help string, labels ...string) *GaugeVec {
+	return DefaultRegistry.NewGaugeVec(name, help, labels...)
+}
+
+// NewHistogram registers a histogram in the default registry
+func NewHistogram(name, help string, buckets []float64) *Histogram {
+	return DefaultRegistry.NewHistogram(name, help, buckets)
+}
+
+// NewHistogramVec registers a histogram with labels in the default registry
+func NewHistogramVec(name, help string, buckets []float64, labels ...string) *HistogramVec {
+	return DefaultRegistry.NewHistogramVec(name, help, buckets, labels...)
+}
@@ -0,0 +1,111 @@
+/*
+Copyright IBM Corp. 2016 All Rights Reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+		 http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package metrics
+
+import (
+	"bytes"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestRegistryWriteText(t *testing.T) {
+	r := NewRegistry()
+	c := r.NewCounter("test_requests_total", "Number of requests.")
+	g := r.NewGaugeVec("test_streams", "Number of open streams.", "service")
+	h := r.NewHistogram("test_duration_seconds", "Duration\nof things.", []float64{0.1, 1})
+
+	c.Inc()
+	c.Add(2)
+	g.With("peer").Inc()
+	g.With("peer").Inc()
+	g.With("events").Inc()
+	g.With("events").Dec()
+	g.With(`a"b`).Set(1.5)
+	h.Observe(0.05)
+	h.Observe(0.5)
+	h.Observe(5)
+
+	var buf bytes.Buffer
+	if err := r.WriteText(&buf); err != nil {
+		t.Fatalf("Error writing metrics: %s", err)
+	}
+	expected := `# HELP test_duration_seconds Duration\nof things.
+# TYPE test_duration_seconds histogram
+test_duration_seconds_bucket{le="0.1"} 1
+test_duration_seconds_bucket{le="1"} 2
+test_duration_seconds_bucket{le="+Inf"} 3
+test_duration_seconds_sum 5.55
+test_duration_seconds_count 3
+# HELP test_requests_total Number of requests.
+# TYPE test_requests_total counter
+test_requests_total 3
+# HELP test_streams Number of open streams.
+# TYPE test_streams gauge
+test_streams{service="a\"b"} 1.5
+test_streams{service="events"} 0
+test_streams{service="peer"} 2
+`
+	if buf.String() != expected {
+		t.Fatalf("Expected\n%s\nbut got\n%s", expected, buf.String())
+	}
+}
+
+func TestHistogramVecLabels(t *testing.T) {
+	r := NewRegistry()
+	h := r.NewHistogramVec("test_hash_seconds", "Hash time.", []float64{1}, "impl")
+	h.With("buckettree").Observe(2)
+	if h.With("buckettree").Count() != 1 || h.With("buckettree").Sum() != 2 || h.With("trie").Count() != 0 {
+		t.Fatalf("Expected one observation for buckettree only")
+	}
+	var buf bytes.Buffer
+	r.WriteText(&buf)
+	if !strings.Contains(buf.String(), `test_hash_seconds_bucket{impl="buckettree",le="1"} 0`) {
+		t.Fatalf("Expected the labels before le, got\n%s", buf.String())
+	}
+}
+
+func TestRegistryPanics(t *testing.T) {
+	r := NewRegistry()
+	v := r.NewCounterVec("test_total", "Test.", "a", "b")
+	expectPanic(t, "registering a metric twice", func() { r.NewGauge("test_total", "Test.") })
+	expectPanic(t, "passing the wrong number of label values", func() { v.With("x") })
+	expectPanic(t, "decreasing a counter", func() { v.With("x", "y").Add(-1) })
+	expectPanic(t, "unsorted buckets", func() { r.NewHistogram("test_seconds", "Test.", []float64{1, 0.5}) })
+}
+
+func expectPanic(t *testing.T, what string, f func()) {
+	defer func() {
+		if recover() == nil {
+			t.Fatalf("Expected a panic %s", what)
+		}
+	}()
+	f()
+}
+
+func TestServeHTTP(t *testing.T) {
+	r := NewRegistry()
+	r.NewGauge("test_height", "Height.").Set(7)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest("GET", "/metrics", nil))
+	if ct := w.Header().Get("Content-Type"); ct != ContentType {
+		t.Fatalf("Expected content type %s but got %s", ContentType, ct)
+	}
+	if !strings.Contains(w.Body.String(), "test_height 7\n") {
+		t.Fatalf("Expected the gauge in the response, got\n%s", w.Body.String())
+	}
+}
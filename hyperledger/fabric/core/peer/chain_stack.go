@@ -0,0 +1,132 @@
+/*
+Copyright IBM Corp. 2016 All Rights Reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+		 http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package peer
+
+import (
+	"github.com/hyperledger/fabric/core/ledger"
+	"github.com/hyperledger/fabric/core/ledger/statemgmt"
+	pb "github.com/hyperledger/fabric/protos"
+)
+
+// ChainStack performs the ledger operations of the state transfer on the
+// ledger of a chain, requesting the ledger data of the chain from the peers of
+// the coordinator
+type ChainStack struct {
+	coord         MessageHandlerCoordinator
+	chainID       string
+	ledgerWrapper *ledgerWrapper
+}
+
+// NewChainStack returns the ChainStack of the chain identified by chainID
+func NewChainStack(coord MessageHandlerCoordinator, chainID string) (*ChainStack, error) {
+	lgr, err := ledger.GetChainLedger(chainID)
+	if err != nil {
+		return nil, err
+	}
+	return &ChainStack{coord: coord, chainID: chainID, ledgerWrapper: &ledgerWrapper{ledger: lgr}}, nil
+}
+
+// GetPeers returns the peers of the coordinator
+func (cs *ChainStack) GetPeers() (*pb.PeersMessage, error) {
+	return cs.coord.GetPeers()
+}
+
+// GetPeerEndpoint returns the endpoint of the coordinator
+func (cs *ChainStack) GetPeerEndpoint() (*pb.PeerEndpoint, error) {
+	return cs.coord.GetPeerEndpoint()
+}
+
+// GetRemoteLedger returns the RemoteLedger of the chain at the remote peer
+func (cs *ChainStack) GetRemoteLedger(receiver *pb.PeerID) (RemoteLedger, error) {
+	return cs.coord.GetChainRemoteLedger(cs.chainID, receiver)
+}
+
+// GetBlockByNumber return a block by block number
+func (cs *ChainStack) GetBlockByNumber(blockNumber uint64) (*pb.Block, error) {
+	cs.ledgerWrapper.RLock()
+	defer cs.ledgerWrapper.RUnlock()
+	return cs.ledgerWrapper.ledger.GetBlockByNumber(blockNumber)
+}
+
+// GetBlockchainSize returns the height/length of the blockchain
+func (cs *ChainStack) GetBlockchainSize() uint64 {
+	cs.ledgerWrapper.RLock()
+	defer cs.ledgerWrapper.RUnlock()
+	return cs.ledgerWrapper.ledger.GetBlockchainSize()
+}
+
+// GetCurrentStateHash returns the current non-committed hash of the in memory state
+func (cs *ChainStack) GetCurrentStateHash() (stateHash []byte, err error) {
+	cs.ledgerWrapper.RLock()
+	defer cs.ledgerWrapper.RUnlock()
+	return cs.ledgerWrapper.ledger.GetTempStateHash()
+}
+
+// HashBlock returns the hash of the included block
+func (cs *ChainStack) HashBlock(block *pb.Block) ([]byte, error) {
+	return block.GetHash()
+}
+
+// VerifyBlockchain checks the integrity of the blockchain between indices start and finish
+func (cs *ChainStack) VerifyBlockchain(start, finish uint64) (uint64, error) {
+	cs.ledgerWrapper.RLock()
+	defer cs.ledgerWrapper.RUnlock()
+	return cs.ledgerWrapper.ledger.VerifyChain(start, finish)
+}
+
+// ApplyStateDelta applies a state delta to the current state
+func (cs *ChainStack) ApplyStateDelta(id interface{}, delta *statemgmt.StateDelta) error {
+	cs.ledgerWrapper.Lock()
+	defer cs.ledgerWrapper.Unlock()
+	return cs.ledgerWrapper.ledger.ApplyStateDelta(id, delta)
+}
+
+// CommitStateDelta makes the result of ApplyStateDelta permanent
+func (cs *ChainStack) CommitStateDelta(id interface{}) error {
+	cs.ledgerWrapper.Lock()
+	defer cs.ledgerWrapper.Unlock()
+	return cs.ledgerWrapper.ledger.CommitStateDelta(id)
+}
+
+// AddStateDeltaToHistory adds the committed state delta of the block to the
+// history index of the ledger
+func (cs *ChainStack) AddStateDeltaToHistory(blockNumber uint64, delta *statemgmt.StateDelta) error {
+	cs.ledgerWrapper.Lock()
+	defer cs.ledgerWrapper.Unlock()
+	return cs.ledgerWrapper.ledger.AddStateDeltaToHistory(blockNumber, delta)
+}
+
+// RollbackStateDelta undoes the results of ApplyStateDelta
+func (cs *ChainStack) RollbackStateDelta(id interface{}) error {
+	cs.ledgerWrapper.Lock()
+	defer cs.ledgerWrapper.Unlock()
+	return cs.ledgerWrapper.ledger.RollbackStateDelta(id)
+}
+
+// EmptyState completely empties the state and prepares it to restore a snapshot
+func (cs *ChainStack) EmptyState() error {
+	cs.ledgerWrapper.Lock()
+	defer cs.ledgerWrapper.Unlock()
+	return cs.ledgerWrapper.ledger.DeleteALLStateKeysAndValues()
+}
+
+// PutBlock inserts a raw block into the blockchain at the specified index
+func (cs *ChainStack) PutBlock(blockNumber uint64, block *pb.Block) error {
+	cs.ledgerWrapper.Lock()
+	defer cs.ledgerWrapper.Unlock()
+	return cs.ledgerWrapper.ledger.PutRawBlock(block, blockNumber)
+}
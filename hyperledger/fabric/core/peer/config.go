@@ -53,6 +53,7 @@ var syncStateSnapshotChannelSize int
 var syncStateDeltasChannelSize int
 var syncBlocksChannelSize int
 var validatorEnabled bool
+var chains []string
 
 // Note: There is some kind of circular import issue that prevents us from
 // importing the "core" package into the "peer" package. The
@@ -105,6 +106,7 @@ func CacheConfiguration() (err error) {
 	syncStateDeltasChannelSize = viper.GetInt("peer.sync.state.deltas.channelSize")
 	syncBlocksChannelSize = viper.GetInt("peer.sync.blocks.channelSize")
 	validatorEnabled = viper.GetBool("peer.validator.enabled")
+	chains = viper.GetStringSlice("peer.chains")
 
 	securityEnabled = viper.GetBool("security.enabled")
 
@@ -174,6 +176,29 @@ func ValidatorEnabled() bool {
 	return validatorEnabled
 }
 
+// Chains returns the peer.chains property, the chains hosted by the peer
+// besides the default chain
+func Chains() []string {
+	if !configurationCached {
+		cacheConfiguration()
+	}
+	return chains
+}
+
+// HostsChain returns whether the peer hosts the chain, the empty ID being the
+// default chain
+func HostsChain(chainID string) bool {
+	if chainID == "" {
+		return true
+	}
+	for _, id := range Chains() {
+		if id == chainID {
+			return true
+		}
+	}
+	return false
+}
+
 func SecurityEnabled() bool {
 	if !configurationCached {
 		cacheConfiguration()
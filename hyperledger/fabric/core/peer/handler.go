@@ -17,7 +17,9 @@ limitations under the License.
 package peer
 
 import (
+	"bytes"
 	"fmt"
+	"sort"
 	"sync"
 	"time"
 
@@ -25,24 +27,28 @@ import (
 	"github.com/looplab/fsm"
 	"github.com/spf13/viper"
 
+	"github.com/hyperledger/fabric/core/ledger"
 	"github.com/hyperledger/fabric/core/ledger/statemgmt"
+	"github.com/hyperledger/fabric/core/ledger/statemgmt/state"
+	"github.com/hyperledger/fabric/core/util"
 	pb "github.com/hyperledger/fabric/protos"
 )
 
 // Handler peer handler implementation.
 type Handler struct {
-	chatMutex                     sync.Mutex
-	ToPeerEndpoint                *pb.PeerEndpoint
-	Coordinator                   MessageHandlerCoordinator
-	ChatStream                    ChatStream
-	doneChan                      chan struct{}
-	FSM                           *fsm.FSM
-	initiatedStream               bool // Was the stream initiated within this Peer
-	registered                    bool
-	syncBlocks                    chan *pb.SyncBlocks
-	snapshotRequestHandler        *syncStateSnapshotRequestHandler
-	syncStateDeltasRequestHandler *syncStateDeltasHandler
-	syncBlocksRequestHandler      *syncBlocksRequestHandler
+	chatMutex        sync.Mutex
+	ToPeerEndpoint   *pb.PeerEndpoint
+	Coordinator      MessageHandlerCoordinator
+	ChatStream       ChatStream
+	doneChan         chan struct{}
+	FSM              *fsm.FSM
+	initiatedStream  bool // Was the stream initiated within this Peer
+	registered       bool
+	syncBlocks       chan *pb.SyncBlocks
+	syncHandlersLock sync.Mutex
+	syncHandlers     map[string]*chainSyncHandlers // by chain ID, empty for the default chain
+	peersLock        sync.Mutex
+	lastPeers        *pb.PeersMessage // the last peers received, with their digest
 }
 
 // NewPeerHandler returns a new Peer handler
@@ -56,9 +62,7 @@ func NewPeerHandler(coord MessageHandlerCoordinator, stream ChatStream, initiate
 	}
 	d.doneChan = make(chan struct{})
 
-	d.snapshotRequestHandler = newSyncStateSnapshotRequestHandler()
-	d.syncStateDeltasRequestHandler = newSyncStateDeltasHandler()
-	d.syncBlocksRequestHandler = newSyncBlocksRequestHandler()
+	d.syncHandlers = map[string]*chainSyncHandlers{"": newChainSyncHandlers()}
 	d.FSM = fsm.NewFSM(
 		"created",
 		fsm.Events{
@@ -118,6 +122,65 @@ func (d *Handler) deregister() error {
 	return err
 }
 
+// getSyncHandlers returns the handlers of the sync requests for the chain
+func (d *Handler) getSyncHandlers(chainID string) *chainSyncHandlers {
+	d.syncHandlersLock.Lock()
+	defer d.syncHandlersLock.Unlock()
+	handlers, ok := d.syncHandlers[chainID]
+	if !ok {
+		handlers = newChainSyncHandlers()
+		d.syncHandlers[chainID] = handlers
+	}
+	return handlers
+}
+
+// chainLedger is the part of the ledger serving the sync requests
+type chainLedger interface {
+	GetBlockByNumber(blockNumber uint64) (*pb.Block, error)
+	GetStateSnapshot() (*state.StateSnapshot, error)
+	GetStateDelta(blockNumber uint64) (*statemgmt.StateDelta, error)
+}
+
+// getChainLedger returns the ledger of the chain to serve the sync requests
+// from, provided the peer hosts the chain
+func (d *Handler) getChainLedger(chainID string) (chainLedger, error) {
+	if chainID == "" {
+		return d.Coordinator, nil
+	}
+	if !HostsChain(chainID) {
+		return nil, fmt.Errorf("chain %s is not hosted by this peer", chainID)
+	}
+	lgr, err := ledger.GetChainLedger(chainID)
+	if err != nil {
+		return nil, err
+	}
+	return lgr, nil
+}
+
+// chainRemoteLedger requests the ledger data of a chain from the remote peer
+type chainRemoteLedger struct {
+	handler *Handler
+	chainID string
+}
+
+// GetChainRemoteLedger returns the RemoteLedger for the ledger of the chain
+// at the remote peer
+func (d *Handler) GetChainRemoteLedger(chainID string) RemoteLedger {
+	return &chainRemoteLedger{handler: d, chainID: chainID}
+}
+
+func (rl *chainRemoteLedger) RequestBlocks(syncBlockRange *pb.SyncBlockRange) (<-chan *pb.SyncBlocks, error) {
+	return rl.handler.requestBlocks(rl.chainID, syncBlockRange)
+}
+
+func (rl *chainRemoteLedger) RequestStateSnapshot() (<-chan *pb.SyncStateSnapshot, error) {
+	return rl.handler.requestStateSnapshot(rl.chainID)
+}
+
+func (rl *chainRemoteLedger) RequestStateDeltas(syncBlockRange *pb.SyncBlockRange) (<-chan *pb.SyncStateDeltas, error) {
+	return rl.handler.requestStateDeltas(rl.chainID, syncBlockRange)
+}
+
 // To return the PeerEndpoint this Handler is connected to.
 func (d *Handler) To() (pb.PeerEndpoint, error) {
 	if d.ToPeerEndpoint == nil {
@@ -189,12 +252,39 @@ func (d *Handler) beforeHello(e *fsm.Event) {
 	}
 }
 
+// digestPeers returns the digest of the peers, which does not depend on their
+// order
+func digestPeers(peers []*pb.PeerEndpoint) []byte {
+	var entries []string
+	for _, peer := range peers {
+		var name string
+		if peer.ID != nil {
+			name = peer.ID.Name
+		}
+		entries = append(entries, fmt.Sprintf("%s %s %d %x", name, peer.Address, peer.Type, peer.PkiID))
+	}
+	sort.Strings(entries)
+	var buffer bytes.Buffer
+	for _, entry := range entries {
+		buffer.WriteString(entry)
+		buffer.WriteByte('\n')
+	}
+	return util.ComputeCryptoHash(buffer.Bytes())
+}
+
+// beforeGetPeers sends back the peers connected to this peer. The request
+// carries the digest of the peers the requester last received, in which case
+// only the digest is sent back if they did not change.
 func (d *Handler) beforeGetPeers(e *fsm.Event) {
 	peersMessage, err := d.Coordinator.GetPeers()
 	if err != nil {
 		e.Cancel(fmt.Errorf("Error Getting Peers: %s", err))
 		return
 	}
+	peersMessage.Digest = digestPeers(peersMessage.Peers)
+	if msg, ok := e.Args[0].(*pb.Message); ok && bytes.Equal(msg.Payload, peersMessage.Digest) {
+		peersMessage = &pb.PeersMessage{Digest: peersMessage.Digest}
+	}
 	data, err := proto.Marshal(peersMessage)
 	if err != nil {
 		e.Cancel(fmt.Errorf("Error Marshalling PeersMessage: %s", err))
@@ -222,6 +312,15 @@ func (d *Handler) beforePeers(e *fsm.Event) {
 		return
 	}
 
+	// Peers sent as a digest are the ones last received
+	d.peersLock.Lock()
+	if len(peersMessage.Peers) == 0 && d.lastPeers != nil && len(peersMessage.Digest) > 0 && bytes.Equal(peersMessage.Digest, d.lastPeers.Digest) {
+		peersMessage = d.lastPeers
+	} else {
+		d.lastPeers = peersMessage
+	}
+	d.peersLock.Unlock()
+
 	peerLogger.Debugf("Received PeersMessage with Peers: %s", peersMessage)
 	d.Coordinator.PeersDiscovered(peersMessage)
 
@@ -264,12 +363,38 @@ func (d *Handler) HandleMessage(msg *pb.Message) error {
 	return nil
 }
 
+// MessageFilter tells whether a message is sent to the remote peer, so that
+// tests can drop the messages between peers
+type MessageFilter func(to *pb.PeerID, msg *pb.Message) bool
+
+// MessageFilterer is implemented by the coordinators filtering the messages
+// their handlers send
+type MessageFilterer interface {
+	// GetMessageFilter returns the filter of the messages sent to the remote
+	// peers once they said hello, nil sending all messages
+	GetMessageFilter() MessageFilter
+}
+
+// filtered returns true if the message must not be sent to the remote peer
+func (d *Handler) filtered(msg *pb.Message) bool {
+	filterer, ok := d.Coordinator.(MessageFilterer)
+	if !ok || d.ToPeerEndpoint == nil {
+		return false
+	}
+	filter := filterer.GetMessageFilter()
+	return filter != nil && !filter(d.ToPeerEndpoint.ID, msg)
+}
+
 // SendMessage sends a message to the remote PEER through the stream
 func (d *Handler) SendMessage(msg *pb.Message) error {
 	//make sure Sends are serialized. Also make sure everyone uses SendMessage
 	//instead of calling Send directly on the grpc stream
 	d.chatMutex.Lock()
 	defer d.chatMutex.Unlock()
+	if d.filtered(msg) {
+		peerLogger.Debugf("Dropping message of type %s to %s", msg.Type, d.ToPeerEndpoint.ID)
+		return nil
+	}
 	peerLogger.Debugf("Sending message to stream of type: %s ", msg.Type)
 	err := d.ChatStream.Send(msg)
 	if err != nil {
@@ -286,7 +411,13 @@ func (d *Handler) start() error {
 	for {
 		select {
 		case <-tickChan:
-			if err := d.SendMessage(&pb.Message{Type: pb.Message_DISC_GET_PEERS}); err != nil {
+			var digest []byte
+			d.peersLock.Lock()
+			if d.lastPeers != nil {
+				digest = d.lastPeers.Digest
+			}
+			d.peersLock.Unlock()
+			if err := d.SendMessage(&pb.Message{Type: pb.Message_DISC_GET_PEERS, Payload: digest}); err != nil {
 				peerLogger.Errorf("Error sending %s during handler discovery tick: %s", pb.Message_DISC_GET_PEERS, err)
 			}
 			// // TODO: For testing only, remove eventually.  Test the blocks transfer functionality.
@@ -352,11 +483,16 @@ func (d *Handler) start() error {
 // RequestBlocks get the blocks from the other PeerEndpoint based upon supplied SyncBlockRange, will provide them through the returned channel.
 // this will also stop writing any received blocks to channels created from Prior calls to RequestBlocks(..)
 func (d *Handler) RequestBlocks(syncBlockRange *pb.SyncBlockRange) (<-chan *pb.SyncBlocks, error) {
-	d.syncBlocksRequestHandler.Lock()
-	defer d.syncBlocksRequestHandler.Unlock()
+	return d.requestBlocks("", syncBlockRange)
+}
+
+func (d *Handler) requestBlocks(chainID string, syncBlockRange *pb.SyncBlockRange) (<-chan *pb.SyncBlocks, error) {
+	requestHandler := d.getSyncHandlers(chainID).blocks
+	requestHandler.Lock()
+	defer requestHandler.Unlock()
 
-	d.syncBlocksRequestHandler.reset()
-	syncBlockRange.CorrelationId = d.syncBlocksRequestHandler.correlationID
+	requestHandler.reset()
+	syncBlockRange.CorrelationId = requestHandler.correlationID
 
 	// Marshal the SyncBlockRange as the payload
 	syncBlockRangeBytes, err := proto.Marshal(syncBlockRange)
@@ -364,10 +500,10 @@ func (d *Handler) RequestBlocks(syncBlockRange *pb.SyncBlockRange) (<-chan *pb.S
 		return nil, fmt.Errorf("Error marshaling syncBlockRange during GetBlocks: %s", err)
 	}
 	peerLogger.Debugf("Sending %s with Range %s", pb.Message_SYNC_GET_BLOCKS.String(), syncBlockRange)
-	if err := d.SendMessage(&pb.Message{Type: pb.Message_SYNC_GET_BLOCKS, Payload: syncBlockRangeBytes}); err != nil {
+	if err := d.SendMessage(&pb.Message{Type: pb.Message_SYNC_GET_BLOCKS, Payload: syncBlockRangeBytes, ChainID: chainID}); err != nil {
 		return nil, fmt.Errorf("Error sending %s during GetBlocks: %s", pb.Message_SYNC_GET_BLOCKS, err)
 	}
-	return d.syncBlocksRequestHandler.channel, nil
+	return requestHandler.channel, nil
 }
 
 func (d *Handler) beforeSyncGetBlocks(e *fsm.Event) {
@@ -385,7 +521,7 @@ func (d *Handler) beforeSyncGetBlocks(e *fsm.Event) {
 		return
 	}
 
-	go d.sendBlocks(syncBlockRange)
+	go d.sendBlocks(msg.ChainID, syncBlockRange)
 }
 
 func (d *Handler) beforeSyncBlocks(e *fsm.Event) {
@@ -412,25 +548,31 @@ func (d *Handler) beforeSyncBlocks(e *fsm.Event) {
 		}
 	}()
 
-	d.syncBlocksRequestHandler.Lock()
-	defer d.syncBlocksRequestHandler.Unlock()
+	requestHandler := d.getSyncHandlers(msg.ChainID).blocks
+	requestHandler.Lock()
+	defer requestHandler.Unlock()
 	// Use non-blocking send, will WARN if missed message.
-	if d.syncBlocksRequestHandler.shouldHandle(syncBlocks.Range.CorrelationId) {
+	if requestHandler.shouldHandle(syncBlocks.Range.CorrelationId) {
 		select {
-		case d.syncBlocksRequestHandler.channel <- syncBlocks:
+		case requestHandler.channel <- syncBlocks:
 		default:
 			peerLogger.Warningf("Did NOT send SyncBlocks message to channel for range: %d - %d", syncBlocks.Range.Start, syncBlocks.Range.End)
-			d.syncBlocksRequestHandler.reset()
+			requestHandler.reset()
 		}
 	} else {
 		//Ignore the message, does not match the current correlationId
-		peerLogger.Warningf("Ignoring SyncBlocks message with correlationId = %d, blocks %d to %d, as current correlationId = %d", syncBlocks.Range.CorrelationId, syncBlocks.Range.Start, syncBlocks.Range.End, d.syncBlocksRequestHandler.correlationID)
+		peerLogger.Warningf("Ignoring SyncBlocks message with correlationId = %d, blocks %d to %d, as current correlationId = %d", syncBlocks.Range.CorrelationId, syncBlocks.Range.Start, syncBlocks.Range.End, requestHandler.correlationID)
 	}
 }
 
 // sendBlocks sends the blocks based upon the supplied SyncBlockRange over the stream.
-func (d *Handler) sendBlocks(syncBlockRange *pb.SyncBlockRange) {
+func (d *Handler) sendBlocks(chainID string, syncBlockRange *pb.SyncBlockRange) {
 	peerLogger.Debugf("Sending blocks %d-%d", syncBlockRange.Start, syncBlockRange.End)
+	source, err := d.getChainLedger(chainID)
+	if err != nil {
+		peerLogger.Errorf("Error sending blocks: %s", err)
+		return
+	}
 	var blockNums []uint64
 	if syncBlockRange.Start > syncBlockRange.End {
 		// Send in reverse order
@@ -447,7 +589,7 @@ func (d *Handler) sendBlocks(syncBlockRange *pb.SyncBlockRange) {
 	}
 	for _, currBlockNum := range blockNums {
 		// Get the Block from
-		block, err := d.Coordinator.GetBlockByNumber(currBlockNum)
+		block, err := source.GetBlockByNumber(currBlockNum)
 		if err != nil {
 			peerLogger.Errorf("Error sending blockNum %d: %s", currBlockNum, err)
 			break
@@ -459,7 +601,7 @@ func (d *Handler) sendBlocks(syncBlockRange *pb.SyncBlockRange) {
 			peerLogger.Errorf("Error marshalling syncBlocks for BlockNum = %d: %s", currBlockNum, err)
 			break
 		}
-		if err := d.SendMessage(&pb.Message{Type: pb.Message_SYNC_BLOCKS, Payload: syncBlocksBytes}); err != nil {
+		if err := d.SendMessage(&pb.Message{Type: pb.Message_SYNC_BLOCKS, Payload: syncBlocksBytes, ChainID: chainID}); err != nil {
 			peerLogger.Errorf("Error sending blockNum %d: %s", currBlockNum, err)
 			break
 		}
@@ -476,23 +618,28 @@ func (d *Handler) sendBlocks(syncBlockRange *pb.SyncBlockRange) {
 // RequestStateSnapshot request the state snapshot deltas from the other PeerEndpoint, will provide them through the returned channel.
 // this will also stop writing any received syncStateSnapshot(s) to channels created from Prior calls to RequestStateSnapshot()
 func (d *Handler) RequestStateSnapshot() (<-chan *pb.SyncStateSnapshot, error) {
-	d.snapshotRequestHandler.Lock()
-	defer d.snapshotRequestHandler.Unlock()
+	return d.requestStateSnapshot("")
+}
+
+func (d *Handler) requestStateSnapshot(chainID string) (<-chan *pb.SyncStateSnapshot, error) {
+	requestHandler := d.getSyncHandlers(chainID).snapshot
+	requestHandler.Lock()
+	defer requestHandler.Unlock()
 	// Reset the handler
-	d.snapshotRequestHandler.reset()
+	requestHandler.reset()
 
 	// Create the syncStateSnapshotRequest
-	syncStateSnapshotRequest := d.snapshotRequestHandler.createRequest()
+	syncStateSnapshotRequest := requestHandler.createRequest()
 	syncStateSnapshotRequestBytes, err := proto.Marshal(syncStateSnapshotRequest)
 	if err != nil {
 		return nil, fmt.Errorf("Error marshaling syncStateSnapshotRequest during GetStateSnapshot: %s", err)
 	}
 	peerLogger.Debugf("Sending %s with syncStateSnapshotRequest = %s", pb.Message_SYNC_STATE_GET_SNAPSHOT.String(), syncStateSnapshotRequest)
-	if err := d.SendMessage(&pb.Message{Type: pb.Message_SYNC_STATE_GET_SNAPSHOT, Payload: syncStateSnapshotRequestBytes}); err != nil {
+	if err := d.SendMessage(&pb.Message{Type: pb.Message_SYNC_STATE_GET_SNAPSHOT, Payload: syncStateSnapshotRequestBytes, ChainID: chainID}); err != nil {
 		return nil, fmt.Errorf("Error sending %s during GetStateSnapshot: %s", pb.Message_SYNC_STATE_GET_SNAPSHOT, err)
 	}
 
-	return d.snapshotRequestHandler.channel, nil
+	return requestHandler.channel, nil
 }
 
 // beforeSyncStateGetSnapshot triggers the sending of State Snapshot deltas to remote Peer.
@@ -512,7 +659,7 @@ func (d *Handler) beforeSyncStateGetSnapshot(e *fsm.Event) {
 	}
 
 	// Start a separate go FUNC to send the State snapshot
-	go d.sendStateSnapshot(syncStateSnapshotRequest)
+	go d.sendStateSnapshot(msg.ChainID, syncStateSnapshotRequest)
 }
 
 // beforeSyncStateSnapshot will write the State Snapshot deltas to the respective channel.
@@ -538,29 +685,35 @@ func (d *Handler) beforeSyncStateSnapshot(e *fsm.Event) {
 		}
 	}()
 	// Use non-blocking send, will WARN and close channel if missed message.
-	d.snapshotRequestHandler.Lock()
-	defer d.snapshotRequestHandler.Unlock()
+	requestHandler := d.getSyncHandlers(msg.ChainID).snapshot
+	requestHandler.Lock()
+	defer requestHandler.Unlock()
 	// Make sure the correlationID matches
-	if d.snapshotRequestHandler.shouldHandle(syncStateSnapshot.Request.CorrelationId) {
+	if requestHandler.shouldHandle(syncStateSnapshot.Request.CorrelationId) {
 		select {
-		case d.snapshotRequestHandler.channel <- syncStateSnapshot:
+		case requestHandler.channel <- syncStateSnapshot:
 		default:
 			// Was not able to write to the channel, in which case the Snapshot stream is incomplete, and must be discarded, closing the channel
 			// without sending the terminating message which would have had an empty byte slice.
 			peerLogger.Warningf("Did NOT send SyncStateSnapshot message to channel for correlationId = %d, sequence = %d, closing channel as the message has been discarded", syncStateSnapshot.Request.CorrelationId, syncStateSnapshot.Sequence)
-			d.snapshotRequestHandler.reset()
+			requestHandler.reset()
 		}
 	} else {
 		//Ignore the message, does not match the current correlationId
-		peerLogger.Warningf("Ignoring SyncStateSnapshot message with correlationId = %d, sequence = %d, as current correlationId = %d", syncStateSnapshot.Request.CorrelationId, syncStateSnapshot.Sequence, d.snapshotRequestHandler.correlationID)
+		peerLogger.Warningf("Ignoring SyncStateSnapshot message with correlationId = %d, sequence = %d, as current correlationId = %d", syncStateSnapshot.Request.CorrelationId, syncStateSnapshot.Sequence, requestHandler.correlationID)
 	}
 }
 
 // sendBlocks sends the blocks based upon the supplied SyncBlockRange over the stream.
-func (d *Handler) sendStateSnapshot(syncStateSnapshotRequest *pb.SyncStateSnapshotRequest) {
+func (d *Handler) sendStateSnapshot(chainID string, syncStateSnapshotRequest *pb.SyncStateSnapshotRequest) {
 	peerLogger.Debugf("Sending state snapshot with correlationId = %d", syncStateSnapshotRequest.CorrelationId)
 
-	snapshot, err := d.Coordinator.GetStateSnapshot()
+	source, err := d.getChainLedger(chainID)
+	if err != nil {
+		peerLogger.Errorf("Error getting snapshot: %s", err)
+		return
+	}
+	snapshot, err := source.GetStateSnapshot()
 	if err != nil {
 		peerLogger.Errorf("Error getting snapshot: %s", err)
 		return
@@ -587,7 +740,7 @@ func (d *Handler) sendStateSnapshot(syncStateSnapshotRequest *pb.SyncStateSnapsh
 			peerLogger.Errorf("Error marshalling syncStateSnapsot for BlockNum = %d: %s", currBlockNumber, err)
 			break
 		}
-		if err := d.SendMessage(&pb.Message{Type: pb.Message_SYNC_STATE_SNAPSHOT, Payload: syncStateSnapshotBytes}); err != nil {
+		if err := d.SendMessage(&pb.Message{Type: pb.Message_SYNC_STATE_SNAPSHOT, Payload: syncStateSnapshotBytes, ChainID: chainID}); err != nil {
 			peerLogger.Errorf("Error sending syncStateSnapsot for BlockNum = %d: %s", currBlockNumber, err)
 			break
 		}
@@ -600,7 +753,7 @@ func (d *Handler) sendStateSnapshot(syncStateSnapshotRequest *pb.SyncStateSnapsh
 		peerLogger.Errorf("Error marshalling terminating syncStateSnapsot message for correlationId = %d, BlockNum = %d: %s", syncStateSnapshotRequest.CorrelationId, currBlockNumber, err)
 		return
 	}
-	if err := d.SendMessage(&pb.Message{Type: pb.Message_SYNC_STATE_SNAPSHOT, Payload: syncStateSnapshotBytes}); err != nil {
+	if err := d.SendMessage(&pb.Message{Type: pb.Message_SYNC_STATE_SNAPSHOT, Payload: syncStateSnapshotBytes, ChainID: chainID}); err != nil {
 		peerLogger.Errorf("Error sending terminating syncStateSnapsot for correlationId = %d, BlockNum = %d: %s", syncStateSnapshotRequest.CorrelationId, currBlockNumber, err)
 		return
 	}
@@ -617,24 +770,29 @@ func (d *Handler) sendStateSnapshot(syncStateSnapshotRequest *pb.SyncStateSnapsh
 // RequestStateDeltas get the state snapshot deltas from the other PeerEndpoint, will provide them through the returned channel.
 // this will also stop writing any received syncStateSnapshot(s) to channels created from Prior calls to GetStateSnapshot()
 func (d *Handler) RequestStateDeltas(syncBlockRange *pb.SyncBlockRange) (<-chan *pb.SyncStateDeltas, error) {
-	d.syncStateDeltasRequestHandler.Lock()
-	defer d.syncStateDeltasRequestHandler.Unlock()
+	return d.requestStateDeltas("", syncBlockRange)
+}
+
+func (d *Handler) requestStateDeltas(chainID string, syncBlockRange *pb.SyncBlockRange) (<-chan *pb.SyncStateDeltas, error) {
+	requestHandler := d.getSyncHandlers(chainID).deltas
+	requestHandler.Lock()
+	defer requestHandler.Unlock()
 	// Reset the handler
-	d.syncStateDeltasRequestHandler.reset()
-	syncBlockRange.CorrelationId = d.syncStateDeltasRequestHandler.correlationID
+	requestHandler.reset()
+	syncBlockRange.CorrelationId = requestHandler.correlationID
 
 	// Create the syncStateSnapshotRequest
-	syncStateDeltasRequest := d.syncStateDeltasRequestHandler.createRequest(syncBlockRange)
+	syncStateDeltasRequest := requestHandler.createRequest(syncBlockRange)
 	syncStateDeltasRequestBytes, err := proto.Marshal(syncStateDeltasRequest)
 	if err != nil {
 		return nil, fmt.Errorf("Error marshaling syncStateDeltasRequest during RequestStateDeltas: %s", err)
 	}
 	peerLogger.Debugf("Sending %s with syncStateDeltasRequest = %s", pb.Message_SYNC_STATE_GET_DELTAS.String(), syncStateDeltasRequest)
-	if err := d.SendMessage(&pb.Message{Type: pb.Message_SYNC_STATE_GET_DELTAS, Payload: syncStateDeltasRequestBytes}); err != nil {
+	if err := d.SendMessage(&pb.Message{Type: pb.Message_SYNC_STATE_GET_DELTAS, Payload: syncStateDeltasRequestBytes, ChainID: chainID}); err != nil {
 		return nil, fmt.Errorf("Error sending %s during RequestStateDeltas: %s", pb.Message_SYNC_STATE_GET_DELTAS, err)
 	}
 
-	return d.syncStateDeltasRequestHandler.channel, nil
+	return requestHandler.channel, nil
 }
 
 // beforeSyncStateGetDeltas triggers the sending of Get SyncStateDeltas to remote Peer.
@@ -654,12 +812,17 @@ func (d *Handler) beforeSyncStateGetDeltas(e *fsm.Event) {
 	}
 
 	// Start a separate go FUNC to send the State Deltas
-	go d.sendStateDeltas(syncStateDeltasRequest)
+	go d.sendStateDeltas(msg.ChainID, syncStateDeltasRequest)
 }
 
 // sendBlocks sends the blocks based upon the supplied SyncBlockRange over the stream.
-func (d *Handler) sendStateDeltas(syncStateDeltasRequest *pb.SyncStateDeltasRequest) {
+func (d *Handler) sendStateDeltas(chainID string, syncStateDeltasRequest *pb.SyncStateDeltasRequest) {
 	peerLogger.Debugf("Sending state deltas for block range %d-%d", syncStateDeltasRequest.Range.Start, syncStateDeltasRequest.Range.End)
+	source, err := d.getChainLedger(chainID)
+	if err != nil {
+		peerLogger.Errorf("Error sending state deltas: %s", err)
+		return
+	}
 	var blockNums []uint64
 	syncBlockRange := syncStateDeltasRequest.Range
 	if syncBlockRange.Start > syncBlockRange.End {
@@ -676,7 +839,7 @@ func (d *Handler) sendStateDeltas(syncStateDeltasRequest *pb.SyncStateDeltasRequ
 	}
 	for _, currBlockNum := range blockNums {
 		// Get the state deltas for Block from coordinator
-		stateDelta, err := d.Coordinator.GetStateDelta(currBlockNum)
+		stateDelta, err := source.GetStateDelta(currBlockNum)
 		if err != nil {
 			peerLogger.Errorf("Error sending stateDelta for blockNum %d: %s", currBlockNum, err)
 			break
@@ -693,7 +856,7 @@ func (d *Handler) sendStateDeltas(syncStateDeltasRequest *pb.SyncStateDeltasRequ
 			peerLogger.Errorf("Error marshalling syncStateDeltas for BlockNum = %d: %s", currBlockNum, err)
 			break
 		}
-		if err := d.SendMessage(&pb.Message{Type: pb.Message_SYNC_STATE_DELTAS, Payload: syncStateDeltasBytes}); err != nil {
+		if err := d.SendMessage(&pb.Message{Type: pb.Message_SYNC_STATE_DELTAS, Payload: syncStateDeltasBytes, ChainID: chainID}); err != nil {
 			peerLogger.Errorf("Error sending stateDeltas for blockNum %d: %s", currBlockNum, err)
 			break
 		}
@@ -724,19 +887,20 @@ func (d *Handler) beforeSyncStateDeltas(e *fsm.Event) {
 	}()
 
 	// Use non-blocking send, will WARN and close channel if missed message.
-	d.syncStateDeltasRequestHandler.Lock()
-	defer d.syncStateDeltasRequestHandler.Unlock()
-	if d.syncStateDeltasRequestHandler.shouldHandle(syncStateDeltas.Range.CorrelationId) {
+	requestHandler := d.getSyncHandlers(msg.ChainID).deltas
+	requestHandler.Lock()
+	defer requestHandler.Unlock()
+	if requestHandler.shouldHandle(syncStateDeltas.Range.CorrelationId) {
 		select {
-		case d.syncStateDeltasRequestHandler.channel <- syncStateDeltas:
+		case requestHandler.channel <- syncStateDeltas:
 		default:
 			// Was not able to write to the channel, in which case the SyncStateDeltasRequest stream is incomplete, and must be discarded, closing the channel
 			peerLogger.Warningf("Did NOT send SyncStateDeltas message to channel for block range %d-%d, closing channel as the message has been discarded", syncStateDeltas.Range.Start, syncStateDeltas.Range.End)
-			d.syncStateDeltasRequestHandler.reset()
+			requestHandler.reset()
 		}
 	} else {
 		//Ignore the message, does not match the current correlationId
-		peerLogger.Warningf("Ignoring SyncStateDeltas message with correlationId = %d, blocks %d to %d, as current correlationId = %d", syncStateDeltas.Range.CorrelationId, syncStateDeltas.Range.Start, syncStateDeltas.Range.End, d.syncStateDeltasRequestHandler.correlationID)
+		peerLogger.Warningf("Ignoring SyncStateDeltas message with correlationId = %d, blocks %d to %d, as current correlationId = %d", syncStateDeltas.Range.CorrelationId, syncStateDeltas.Range.Start, syncStateDeltas.Range.End, requestHandler.correlationID)
 	}
 
 }
@@ -37,6 +37,21 @@ func (sh *syncHandler) shouldHandle(correlationID uint64) bool {
 	return correlationID == sh.correlationID
 }
 
+// chainSyncHandlers holds the handlers of the sync requests for a chain
+type chainSyncHandlers struct {
+	blocks   *syncBlocksRequestHandler
+	snapshot *syncStateSnapshotRequestHandler
+	deltas   *syncStateDeltasHandler
+}
+
+func newChainSyncHandlers() *chainSyncHandlers {
+	return &chainSyncHandlers{
+		blocks:   newSyncBlocksRequestHandler(),
+		snapshot: newSyncStateSnapshotRequestHandler(),
+		deltas:   newSyncStateDeltasHandler(),
+	}
+}
+
 //-----------------------------------------------------------------------------
 //
 // Sync Blocks Handler
@@ -103,6 +103,7 @@ type StateAccessor interface {
 // MessageHandler standard interface for handling Openchain messages.
 type MessageHandler interface {
 	RemoteLedger
+	GetChainRemoteLedger(chainID string) RemoteLedger
 	HandleMessage(msg *pb.Message) error
 	SendMessage(msg *pb.Message) error
 	To() (pb.PeerEndpoint, error)
@@ -124,6 +125,7 @@ type MessageHandlerCoordinator interface {
 	Unicast(*pb.Message, *pb.PeerID) error
 	GetPeers() (*pb.PeersMessage, error)
 	GetRemoteLedger(receiver *pb.PeerID) (RemoteLedger, error)
+	GetChainRemoteLedger(chainID string, receiver *pb.PeerID) (RemoteLedger, error)
 	PeersDiscovered(*pb.PeersMessage) error
 	ExecuteTransaction(transaction *pb.Transaction) *pb.Response
 }
@@ -197,6 +199,11 @@ type PeerImpl struct {
 	isValidator    bool
 	discoverySvc   discovery.Discovery
 	reconnectOnce  sync.Once
+	endpoint       *pb.PeerEndpoint // set by NewPeerWithLedger
+	filterLock     sync.RWMutex
+	filter         MessageFilter
+	stopped        chan struct{}
+	stopOnce       sync.Once
 }
 
 // TransactionProccesor responsible for processing of Transactions
@@ -212,9 +219,17 @@ type Engine interface {
 	//GetInputChannel() (chan<- *pb.Transaction, error)
 }
 
+// ReconfigurationApprover is implemented by the engines whose consensus can
+// change the set of validators of a chain, and by the peers running them
+type ReconfigurationApprover interface {
+	// ApproveReconfiguration adds the approval of this validator to the payload
+	// of a CONSENSUS_RECONFIGURE transaction, a new one to the validators if empty
+	ApproveReconfiguration(chainID string, validators []string, payload []byte) ([]byte, error)
+}
+
 // NewPeerWithHandler returns a Peer which uses the supplied handler factory function for creating new handlers on new Chat service invocations.
 func NewPeerWithHandler(secHelperFunc func() crypto.Peer, handlerFact HandlerFactory, discInstance discovery.Discovery) (*PeerImpl, error) {
-	peer := new(PeerImpl)
+	peer := &PeerImpl{stopped: make(chan struct{})}
 
 	peer.discoverySvc = discInstance
 
@@ -245,7 +260,25 @@ func NewPeerWithHandler(secHelperFunc func() crypto.Peer, handlerFact HandlerFac
 
 // NewPeerWithEngine returns a Peer which uses the supplied handler factory function for creating new handlers on new Chat service invocations.
 func NewPeerWithEngine(secHelperFunc func() crypto.Peer, engFactory EngineFactory, discInstance discovery.Discovery) (peer *PeerImpl, err error) {
-	peer = new(PeerImpl)
+	// Initialize the ledger before the engine, as consensus may want to begin interrogating the ledger immediately
+	ledgerPtr, err := ledger.GetLedger()
+	if err != nil {
+		return nil, fmt.Errorf("Error constructing NewPeerWithHandler: %s", err)
+	}
+	return newPeerWithEngine(nil, ledgerPtr, secHelperFunc, engFactory, discInstance)
+}
+
+// NewPeerWithLedger returns a Peer as NewPeerWithEngine does, but with the
+// given endpoint and ledger instead of the endpoint of the configuration and
+// the ledger of the process, so that a process can run several peers. The
+// engine of the factory must operate on the same ledger.
+func NewPeerWithLedger(endpoint *pb.PeerEndpoint, lgr *ledger.Ledger, secHelperFunc func() crypto.Peer, engFactory EngineFactory, discInstance discovery.Discovery) (*PeerImpl, error) {
+	return newPeerWithEngine(endpoint, lgr, secHelperFunc, engFactory, discInstance)
+}
+
+func newPeerWithEngine(endpoint *pb.PeerEndpoint, lgr *ledger.Ledger, secHelperFunc func() crypto.Peer, engFactory EngineFactory, discInstance discovery.Discovery) (peer *PeerImpl, err error) {
+	peer = &PeerImpl{stopped: make(chan struct{})}
+	peer.endpoint = endpoint
 
 	peer.discoverySvc = discInstance
 
@@ -261,12 +294,7 @@ func NewPeerWithEngine(secHelperFunc func() crypto.Peer, engFactory EngineFactor
 		}
 	}
 
-	// Initialize the ledger before the engine, as consensus may want to begin interrogating the ledger immediately
-	ledgerPtr, err := ledger.GetLedger()
-	if err != nil {
-		return nil, fmt.Errorf("Error constructing NewPeerWithHandler: %s", err)
-	}
-	peer.ledgerWrapper = &ledgerWrapper{ledger: ledgerPtr}
+	peer.ledgerWrapper = &ledgerWrapper{ledger: lgr}
 
 	peer.engine, err = engFactory(peer)
 	if err != nil {
@@ -284,6 +312,7 @@ func NewPeerWithEngine(secHelperFunc func() crypto.Peer, engFactory EngineFactor
 
 // Chat implementation of the the Chat bidi streaming RPC function
 func (p *PeerImpl) Chat(stream pb.Peer_ChatServer) error {
+	defer comm.TrackStream("peer")()
 	return p.handleChat(stream.Context(), stream, false)
 }
 
@@ -333,19 +362,40 @@ func (p *PeerImpl) GetRemoteLedger(receiverHandle *pb.PeerID) (RemoteLedger, err
 	return remoteLedger, nil
 }
 
+// GetChainRemoteLedger returns the RemoteLedger interface for the ledger of the
+// chain at the remote Peer Endpoint
+func (p *PeerImpl) GetChainRemoteLedger(chainID string, receiverHandle *pb.PeerID) (RemoteLedger, error) {
+	p.handlerMap.RLock()
+	defer p.handlerMap.RUnlock()
+	handler, ok := p.handlerMap.m[*receiverHandle]
+	if !ok {
+		return nil, fmt.Errorf("Remote ledger not found for receiver %s", receiverHandle.Name)
+	}
+	return handler.GetChainRemoteLedger(chainID), nil
+}
+
 // PeersDiscovered used by MessageHandlers for notifying this coordinator of discovered PeerEndoints. May include this Peer's PeerEndpoint.
 func (p *PeerImpl) PeersDiscovered(peersMessage *pb.PeersMessage) error {
-	thisPeersEndpoint, err := GetPeerEndpoint()
+	thisPeersEndpoint, err := p.localEndpoint()
 	if err != nil {
 		return fmt.Errorf("Error in processing PeersDiscovered: %s", err)
 	}
 	p.handlerMap.RLock()
 	defer p.handlerMap.RUnlock()
+	var others []*pb.PeerEndpoint
 	for _, peerEndpoint := range peersMessage.Peers {
 		// Filter out THIS Peer's endpoint
-		if *getHandlerKeyFromPeerEndpoint(thisPeersEndpoint) == *getHandlerKeyFromPeerEndpoint(peerEndpoint) {
-			// NOOP
-		} else if _, ok := p.handlerMap.m[*getHandlerKeyFromPeerEndpoint(peerEndpoint)]; ok == false {
+		if *getHandlerKeyFromPeerEndpoint(thisPeersEndpoint) != *getHandlerKeyFromPeerEndpoint(peerEndpoint) {
+			others = append(others, peerEndpoint)
+		}
+	}
+	// Record the peers before dialing them, so that they are known when they
+	// cannot be reached
+	if membership, ok := p.discoverySvc.(discovery.Membership); ok {
+		membership.PeersDiscovered(others)
+	}
+	for _, peerEndpoint := range others {
+		if _, ok := p.handlerMap.m[*getHandlerKeyFromPeerEndpoint(peerEndpoint)]; ok == false {
 			// Start chat with Peer
 			p.chatWithSomePeers([]string{peerEndpoint.Address})
 		}
@@ -353,6 +403,23 @@ func (p *PeerImpl) PeersDiscovered(peersMessage *pb.PeersMessage) error {
 	return nil
 }
 
+// GetMembers returns the members of the network known to the discovery
+// service or, if it does not learn them from the network, the connected peers
+func (p *PeerImpl) GetMembers() ([]*discovery.Member, error) {
+	if membership, ok := p.discoverySvc.(discovery.Membership); ok {
+		return membership.GetMembers(), nil
+	}
+	peersMessage, err := p.GetPeers()
+	if err != nil {
+		return nil, err
+	}
+	var members []*discovery.Member
+	for _, peerEndpoint := range peersMessage.Peers {
+		members = append(members, &discovery.Member{Endpoint: peerEndpoint, Connected: true})
+	}
+	return members, nil
+}
+
 func getHandlerKey(peerMessageHandler MessageHandler) (*pb.PeerID, error) {
 	peerEndpoint, err := peerMessageHandler.To()
 	if err != nil {
@@ -379,6 +446,11 @@ func (p *PeerImpl) RegisterHandler(messageHandler MessageHandler) error {
 	}
 	p.handlerMap.m[*key] = messageHandler
 	peerLogger.Debugf("registered handler with key: %s", key)
+	if membership, ok := p.discoverySvc.(discovery.Membership); ok {
+		if endpoint, err := messageHandler.To(); err == nil {
+			membership.Connected(&endpoint)
+		}
+	}
 	return nil
 }
 
@@ -396,6 +468,11 @@ func (p *PeerImpl) DeregisterHandler(messageHandler MessageHandler) error {
 	}
 	delete(p.handlerMap.m, *key)
 	peerLogger.Debugf("Deregistered handler with key: %s", key)
+	if membership, ok := p.discoverySvc.(discovery.Membership); ok {
+		if endpoint, err := messageHandler.To(); err == nil {
+			membership.Disconnected(&endpoint)
+		}
+	}
 	return nil
 }
 
@@ -515,9 +592,11 @@ func (p *PeerImpl) sendTransactionsToLocalEngine(transaction *pb.Transaction) *p
 
 func (p *PeerImpl) ensureConnected() {
 	touchPeriod := viper.GetDuration("peer.discovery.touchPeriod")
-	tickChan := time.NewTicker(touchPeriod).C
-	// See if rootNode(s) defined, if NOT, simply return
-	if len(p.discoverySvc.GetRootNodes()) == 1 {
+	ticker := time.NewTicker(touchPeriod)
+	defer ticker.Stop()
+	// See if rootNode(s) defined, if NOT, simply return. A discovery learning
+	// the membership from the network may know peers later on.
+	if _, ok := p.discoverySvc.(discovery.Membership); !ok && len(p.discoverySvc.GetRootNodes()) == 1 {
 		if len(p.discoverySvc.GetRootNodes()[0]) == 0 {
 			peerLogger.Warning("Touch service stopping, no rootNode(s) defined")
 			return
@@ -526,7 +605,12 @@ func (p *PeerImpl) ensureConnected() {
 	peerLogger.Debug("Starting Peer reconnect service, with touchPeriod = %s", touchPeriod)
 	for {
 		// Simply loop and check if need to reconnect
-		<-tickChan
+		select {
+		case <-ticker.C:
+		case <-p.stopped:
+			peerLogger.Debug("Stopping Peer reconnect service")
+			return
+		}
 		peersMsg, err := p.GetPeers()
 		if err != nil {
 			peerLogger.Errorf("Error in touch service: %s", err.Error())
@@ -540,8 +624,20 @@ func (p *PeerImpl) ensureConnected() {
 
 }
 
+// Stop stops the peer connecting to the root nodes, so that a process can
+// stop a peer of NewPeerWithLedger. The connections to the remote peers end
+// with the server of the peer.
+func (p *PeerImpl) Stop() {
+	p.stopOnce.Do(func() { close(p.stopped) })
+}
+
 // chatWithSomePeers initiates chat with 1 or all peers according to whether the node is a validator or not
 func (p *PeerImpl) chatWithSomePeers(peers []string) {
+	select {
+	case <-p.stopped:
+		return
+	default:
+	}
 	// Start the function to ensure we are connected
 	p.reconnectOnce.Do(func() {
 		go p.ensureConnected()
@@ -560,7 +656,7 @@ func (p *PeerImpl) chatWithSomePeers(peers []string) {
 			return // nothing to do
 		}
 		// Skip ourselves
-		if pe, err := GetPeerEndpoint(); err == nil {
+		if pe, err := p.localEndpoint(); err == nil {
 			if rootNode == pe.Address {
 				peerLogger.Debugf(fmt.Sprintf("Skipping my own address(%v)", rootNode))
 				continue
@@ -588,6 +684,9 @@ func (p *PeerImpl) chatWithPeer(peerAddress string, chatTokens chan token) error
 			peerLogger.Error(e.Error())
 			// relinquish token
 			<-chatTokens
+			if !p.keepDialing(peerAddress) {
+				return e
+			}
 			continue
 		}
 		serverClient := pb.NewPeerClient(conn)
@@ -598,6 +697,9 @@ func (p *PeerImpl) chatWithPeer(peerAddress string, chatTokens chan token) error
 			peerLogger.Errorf("%s", e.Error())
 			// relinquish token
 			<-chatTokens
+			if !p.keepDialing(peerAddress) {
+				return e
+			}
 			continue
 		}
 		peerLogger.Debugf("Established Chat with peer address: %s", peerAddress)
@@ -613,6 +715,17 @@ func (p *PeerImpl) chatWithPeer(peerAddress string, chatTokens chan token) error
 	}
 }
 
+// keepDialing records that peerAddress could not be reached and returns
+// whether to keep trying, which is always the case unless the discovery
+// service forgets the peer
+func (p *PeerImpl) keepDialing(peerAddress string) bool {
+	if membership, ok := p.discoverySvc.(discovery.Membership); ok && !membership.Unreachable(peerAddress) {
+		peerLogger.Infof("Giving up on unreachable peer address=%s", peerAddress)
+		return false
+	}
+	return true
+}
+
 // Chat implementation of the the Chat bidi streaming RPC function
 func (p *PeerImpl) handleChat(ctx context.Context, stream ChatStream, initiatedStream bool) error {
 	deadline, ok := ctx.Deadline()
@@ -652,9 +765,28 @@ func (p *PeerImpl) ExecuteTransaction(transaction *pb.Transaction) (response *pb
 	return response
 }
 
+// ApproveReconfiguration implements ReconfigurationApprover for the validators
+func (p *PeerImpl) ApproveReconfiguration(chainID string, validators []string, payload []byte) ([]byte, error) {
+	approver, ok := p.engine.(ReconfigurationApprover)
+	if !p.isValidator || !ok {
+		return nil, fmt.Errorf("This peer cannot approve reconfigurations")
+	}
+	return approver.ApproveReconfiguration(chainID, validators, payload)
+}
+
+// localEndpoint returns the endpoint of the peer, the endpoint of the
+// configuration unless set by NewPeerWithLedger
+func (p *PeerImpl) localEndpoint() (*pb.PeerEndpoint, error) {
+	if p.endpoint == nil {
+		return GetPeerEndpoint()
+	}
+	ep := *p.endpoint
+	return &ep, nil
+}
+
 // GetPeerEndpoint returns the endpoint for this peer
 func (p *PeerImpl) GetPeerEndpoint() (*pb.PeerEndpoint, error) {
-	ep, err := GetPeerEndpoint()
+	ep, err := p.localEndpoint()
 	if err == nil && SecurityEnabled() {
 		// Set the PkiID on the PeerEndpoint if security is enabled
 		ep.PkiID = p.GetSecHelper().GetID()
@@ -662,6 +794,21 @@ func (p *PeerImpl) GetPeerEndpoint() (*pb.PeerEndpoint, error) {
 	return ep, err
 }
 
+// SetMessageFilter sets the filter of the messages sent by the peer to the
+// remote peers once they said hello, nil sending all messages
+func (p *PeerImpl) SetMessageFilter(filter MessageFilter) {
+	p.filterLock.Lock()
+	defer p.filterLock.Unlock()
+	p.filter = filter
+}
+
+// GetMessageFilter implements MessageFilterer
+func (p *PeerImpl) GetMessageFilter() MessageFilter {
+	p.filterLock.RLock()
+	defer p.filterLock.RUnlock()
+	return p.filter
+}
+
 func (p *PeerImpl) newHelloMessage() (*pb.HelloMessage, error) {
 	endpoint, err := p.GetPeerEndpoint()
 	if err != nil {
@@ -729,6 +876,14 @@ func (p *PeerImpl) CommitStateDelta(id interface{}) error {
 	return p.ledgerWrapper.ledger.CommitStateDelta(id)
 }
 
+// AddStateDeltaToHistory adds the committed state delta of the block to the
+// history index of the ledger
+func (p *PeerImpl) AddStateDeltaToHistory(blockNumber uint64, delta *statemgmt.StateDelta) error {
+	p.ledgerWrapper.Lock()
+	defer p.ledgerWrapper.Unlock()
+	return p.ledgerWrapper.ledger.AddStateDeltaToHistory(blockNumber, delta)
+}
+
 // RollbackStateDelta undoes the results of ApplyStateDelta to revert
 // the current state back to the state before ApplyStateDelta was invoked
 func (p *PeerImpl) RollbackStateDelta(id interface{}) error {
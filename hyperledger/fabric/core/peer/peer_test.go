@@ -17,6 +17,7 @@ limitations under the License.
 package peer
 
 import (
+	"bytes"
 	"fmt"
 	"io"
 	"os"
@@ -55,6 +56,18 @@ func TestMissingMessageHandlerUnicast(t *testing.T) {
 	}
 }
 
+func TestDigestPeers(t *testing.T) {
+	vp0 := &pb.PeerEndpoint{ID: &pb.PeerID{Name: "vp0"}, Address: "vp0:30303", Type: pb.PeerEndpoint_VALIDATOR}
+	vp1 := &pb.PeerEndpoint{ID: &pb.PeerID{Name: "vp1"}, Address: "vp1:30303", Type: pb.PeerEndpoint_VALIDATOR}
+	digest := digestPeers([]*pb.PeerEndpoint{vp0, vp1})
+	if !bytes.Equal(digest, digestPeers([]*pb.PeerEndpoint{vp1, vp0})) {
+		t.Error("Expected the digest not to depend on the order of the peers")
+	}
+	if bytes.Equal(digest, digestPeers([]*pb.PeerEndpoint{vp0})) {
+		t.Error("Expected different peers to have different digests")
+	}
+}
+
 func performChat(t testing.TB, conn *grpc.ClientConn) error {
 	serverClient := pb.NewPeerClient(conn)
 	stream, err := serverClient.Chat(context.Background())
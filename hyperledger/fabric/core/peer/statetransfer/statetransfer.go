@@ -56,6 +56,12 @@ type PartialStack interface {
 	GetRemoteLedger(receiver *protos.PeerID) (peer.RemoteLedger, error)
 }
 
+// historyIndexer is implemented by the stacks which keep a history index of the
+// state, the state deltas played forward are added to it once committed
+type historyIndexer interface {
+	AddStateDeltaToHistory(blockNumber uint64, delta *statemgmt.StateDelta) error
+}
+
 // Coordinator is used to initiate state transfer.  Start must be called before use, and Stop should be called to free allocated resources
 type Coordinator interface {
 	Start() // Start the block transfer go routine
@@ -745,12 +751,14 @@ func (sts *coordinatorImpl) playStateUpToBlockNumber(fromBlockNumber, toBlockNum
 					return fmt.Errorf("%v received a state delta from %v either in the wrong order (backwards) or not next in sequence, aborting, start=%d, end=%d", sts.id, peerID, deltaMessage.Range.Start, deltaMessage.Range.End)
 				}
 
-				for _, delta := range deltaMessage.Deltas {
+				umDeltas := make([]*statemgmt.StateDelta, len(deltaMessage.Deltas))
+				for i, delta := range deltaMessage.Deltas {
 					umDelta := &statemgmt.StateDelta{}
 					if err := umDelta.Unmarshal(delta); nil != err {
 						return fmt.Errorf("%v received a corrupt state delta from %v : %s", sts.id, peerID, err)
 					}
 					sts.stack.ApplyStateDelta(deltaMessage, umDelta)
+					umDeltas[i] = umDelta
 				}
 
 				success := false
@@ -787,6 +795,14 @@ func (sts *coordinatorImpl) playStateUpToBlockNumber(fromBlockNumber, toBlockNum
 					return fmt.Errorf("%v played state forward according to %v, hashes matched, but failed to commit, invalidated state", sts.id, peerID)
 				}
 
+				if indexer, ok := sts.stack.(historyIndexer); ok {
+					for i, umDelta := range umDeltas {
+						if err := indexer.AddStateDeltaToHistory(deltaMessage.Range.Start+uint64(i), umDelta); nil != err {
+							logger.Warningf("%v could not add the state delta of block %d to the history: %s", sts.id, deltaMessage.Range.Start+uint64(i), err)
+						}
+					}
+				}
+
 				if currentBlock == toBlockNumber {
 					return nil
 				}
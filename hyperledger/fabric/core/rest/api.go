@@ -19,27 +19,59 @@ package rest
 import (
 	"errors"
 	"fmt"
+	"sort"
 
+	"github.com/spf13/viper"
 	"golang.org/x/net/context"
 
 	"google/protobuf"
 
 	"github.com/golang/protobuf/proto"
+	"github.com/hyperledger/fabric/core/chaincode"
 	"github.com/hyperledger/fabric/core/ledger"
+	"github.com/hyperledger/fabric/core/peer"
+	"github.com/hyperledger/fabric/discovery"
 	pb "github.com/hyperledger/fabric/protos"
 )
 
 var (
 	// ErrNotFound is returned if a requested resource does not exist
 	ErrNotFound = errors.New("openchain: resource not found")
+
+	// ErrStateQueriesDisabled is returned if the state of a chaincode is
+	// requested but rest.stateQueries.enabled is not set
+	ErrStateQueriesDisabled = errors.New("openchain: state queries are disabled")
 )
 
+// maxStateRangeLimit is the maximum number of keys returned by GetStateRange
+const maxStateRangeLimit = 1000
+
+// maxStateRangeScan is the maximum number of keys GetStateRange goes through,
+// as the keys of a range are not stored in order
+var maxStateRangeScan = 10 * maxStateRangeLimit
+
+// checkStateQueriesEnabled returns ErrStateQueriesDisabled unless the peer
+// serves the committed state of the chaincodes, which bypasses the access
+// control of their query functions
+func checkStateQueriesEnabled() error {
+	if !viper.GetBool("rest.stateQueries.enabled") {
+		return ErrStateQueriesDisabled
+	}
+	return nil
+}
+
 // PeerInfo defines API to peer info data
 type PeerInfo interface {
 	GetPeers() (*pb.PeersMessage, error)
 	GetPeerEndpoint() (*pb.PeerEndpoint, error)
 }
 
+// MembershipInfo is implemented by the PeerInfo knowing the members of the
+// network besides the connected peers
+type MembershipInfo interface {
+	GetMembers() ([]*discovery.Member, error)
+}
+
 // ServerOpenchain defines the Openchain server object, which holds the
 // Ledger data structure and the pointer to the peerServer.
 type ServerOpenchain struct {
@@ -73,6 +105,22 @@ func NewOpenchainServerWithPeerInfo(peerServer PeerInfo) (*ServerOpenchain, erro
 	return s, nil
 }
 
+// ForChain returns the ServerOpenchain serving the ledger of the chain
+// identified by chainID, the server itself for the default chain
+func (s *ServerOpenchain) ForChain(chainID string) (*ServerOpenchain, error) {
+	if chainID == "" {
+		return s, nil
+	}
+	if !peer.HostsChain(chainID) {
+		return nil, fmt.Errorf("Chain %s is not hosted by this peer", chainID)
+	}
+	ledger, err := ledger.GetChainLedger(chainID)
+	if err != nil {
+		return nil, err
+	}
+	return &ServerOpenchain{ledger: ledger, peerInfo: s.peerInfo}, nil
+}
+
 // GetBlockchainInfo returns information about the blockchain ledger such as
 // height, current block hash, and previous block hash.
 func (s *ServerOpenchain) GetBlockchainInfo(ctx context.Context, e *google_protobuf.Empty) (*pb.BlockchainInfo, error) {
@@ -96,28 +144,65 @@ func (s *ServerOpenchain) GetBlockByNumber(ctx context.Context, num *pb.BlockNum
 		}
 	}
 
-	// Remove payload from deploy transactions. This is done to make rest api
-	// calls more lightweight as the payload for these types of transactions
-	// can be very large. If the payload is needed, the caller should fetch the
-	// individual transaction.
-	blockTransactions := block.GetTransactions()
-	for _, transaction := range blockTransactions {
+	if err = stripCodePackages(block); err != nil {
+		return nil, err
+	}
+	return block, nil
+}
+
+// stripCodePackages removes the code package from the payload of the deploy
+// transactions of the block. This is done to make the block calls more
+// lightweight as the payload for these types of transactions can be very
+// large. If the payload is needed, the caller should fetch the individual
+// transaction.
+func stripCodePackages(block *pb.Block) error {
+	for _, transaction := range block.GetTransactions() {
 		if transaction.Type == pb.Transaction_CHAINCODE_DEPLOY {
 			deploymentSpec := &pb.ChaincodeDeploymentSpec{}
 			err := proto.Unmarshal(transaction.Payload, deploymentSpec)
 			if err != nil {
-				return nil, err
+				return err
 			}
 			deploymentSpec.CodePackage = nil
 			deploymentSpecBytes, err := proto.Marshal(deploymentSpec)
 			if err != nil {
-				return nil, err
+				return err
 			}
 			transaction.Payload = deploymentSpecBytes
 		}
 	}
+	return nil
+}
 
-	return block, nil
+// GetBlocksRange streams the blocks from start to end, both included, up to
+// the last block. The code packages of the deploy transactions are left out,
+// as by GetBlockByNumber.
+func (s *ServerOpenchain) GetBlocksRange(blocksRange *pb.BlocksRange, stream pb.Openchain_GetBlocksRangeServer) error {
+	server, err := s.ForChain(blocksRange.ChainID)
+	if err != nil {
+		return err
+	}
+	if blocksRange.End < blocksRange.Start {
+		return fmt.Errorf("Block range end %d is lower than its start %d", blocksRange.End, blocksRange.Start)
+	}
+	size := server.ledger.GetBlockchainSize()
+	if blocksRange.Start >= size {
+		return ErrNotFound
+	}
+	end := blocksRange.End
+	if end >= size {
+		end = size - 1
+	}
+	for blockNumber := blocksRange.Start; blockNumber <= end; blockNumber++ {
+		block, err := server.GetBlockByNumber(stream.Context(), &pb.BlockNumber{Number: blockNumber})
+		if err != nil {
+			return err
+		}
+		if err = stream.Send(block); err != nil {
+			return err
+		}
+	}
+	return nil
 }
 
 // GetBlockCount returns the current number of blocks in the blockchain data
@@ -137,14 +222,91 @@ func (s *ServerOpenchain) GetBlockCount(ctx context.Context, e *google_protobuf.
 	return nil, fmt.Errorf("No blocks in blockchain.")
 }
 
-// GetState returns the value for a particular chaincode ID and key
-func (s *ServerOpenchain) GetState(ctx context.Context, chaincodeID, key string) ([]byte, error) {
-	return s.ledger.GetState(chaincodeID, key, true)
+// GetState returns the committed value for a particular chaincode ID and key
+// in the state of the current version of the chaincode, if state queries are
+// enabled
+func (s *ServerOpenchain) GetState(ctx context.Context, stateKey *pb.StateKey) (*pb.StateValue, error) {
+	if err := checkStateQueriesEnabled(); err != nil {
+		return nil, err
+	}
+	server, err := s.ForChain(stateKey.ChainID)
+	if err != nil {
+		return nil, err
+	}
+	namespace, err := chaincode.GetStateNamespace(server.ledger, stateKey.ChaincodeID)
+	if err != nil {
+		return nil, err
+	}
+	value, err := server.ledger.GetState(namespace, stateKey.Key, true)
+	if err != nil {
+		return nil, err
+	}
+	return &pb.StateValue{Value: value}, nil
+}
+
+// GetStateRange returns the committed keys between the start and end keys,
+// both included, in the state of the current version of the chaincode, ordered
+// by key, if state queries are enabled. At most the limit of the range, and
+// maxStateRangeLimit, keys are returned. An error is returned for a range of
+// more than maxStateRangeScan keys, which must be narrowed.
+func (s *ServerOpenchain) GetStateRange(ctx context.Context, stateRange *pb.StateRange) (*pb.StateRangeResponse, error) {
+	if err := checkStateQueriesEnabled(); err != nil {
+		return nil, err
+	}
+	server, err := s.ForChain(stateRange.ChainID)
+	if err != nil {
+		return nil, err
+	}
+	namespace, err := chaincode.GetStateNamespace(server.ledger, stateRange.ChaincodeID)
+	if err != nil {
+		return nil, err
+	}
+	itr, err := server.ledger.GetStateRangeScanIterator(namespace, stateRange.StartKey, stateRange.EndKey, true)
+	if err != nil {
+		return nil, err
+	}
+	defer itr.Close()
+
+	limit := int(stateRange.Limit)
+	if limit == 0 || limit > maxStateRangeLimit {
+		limit = maxStateRangeLimit
+	}
+
+	// the iterator does not return the keys in order, so the smallest keys
+	// are kept in order, one more than the limit to tell if there are more
+	var keysAndValues []*pb.RangeQueryStateKeyValue
+	for scanned := 0; itr.Next(); scanned++ {
+		if scanned == maxStateRangeScan {
+			return nil, fmt.Errorf("The range has more than %d keys, narrow it with its start and end keys", maxStateRangeScan)
+		}
+		key, value := itr.GetKeyValue()
+		i := sort.Search(len(keysAndValues), func(i int) bool { return keysAndValues[i].Key > key })
+		if i > limit {
+			continue
+		}
+		keysAndValues = append(keysAndValues, nil)
+		copy(keysAndValues[i+1:], keysAndValues[i:])
+		keysAndValues[i] = &pb.RangeQueryStateKeyValue{Key: key, Value: value}
+		if len(keysAndValues) > limit+1 {
+			keysAndValues = keysAndValues[:limit+1]
+		}
+	}
+
+	response := &pb.StateRangeResponse{KeysAndValues: keysAndValues}
+	if len(keysAndValues) > limit {
+		response.KeysAndValues = keysAndValues[:limit]
+		response.HasMore = true
+	}
+	return response, nil
 }
 
 // GetTransactionByUUID returns a transaction matching the specified UUID
-func (s *ServerOpenchain) GetTransactionByUUID(ctx context.Context, txUUID string) (*pb.Transaction, error) {
-	transaction, err := s.ledger.GetTransactionByUUID(txUUID)
+func (s *ServerOpenchain) GetTransactionByUUID(ctx context.Context, txUUID *pb.TransactionUUID) (*pb.Transaction, error) {
+	server, err := s.ForChain(txUUID.ChainID)
+	if err != nil {
+		return nil, err
+	}
+	transaction, err := server.ledger.GetTransactionByUUID(txUUID.Uuid)
 	if err != nil {
 		switch err {
 		case ledger.ErrResourceNotFound:
@@ -156,11 +318,100 @@ func (s *ServerOpenchain) GetTransactionByUUID(ctx context.Context, txUUID strin
 	return transaction, nil
 }
 
+// GetTransactionResult returns the result of the transaction matching the
+// specified UUID
+func (s *ServerOpenchain) GetTransactionResult(ctx context.Context, txUUID *pb.TransactionUUID) (*pb.TransactionResult, error) {
+	server, err := s.ForChain(txUUID.ChainID)
+	if err != nil {
+		return nil, err
+	}
+	result, err := server.ledger.GetTransactionResultByUUID(txUUID.Uuid)
+	if err != nil {
+		switch err {
+		case ledger.ErrResourceNotFound:
+			return nil, ErrNotFound
+		default:
+			return nil, fmt.Errorf("Error retrieving transaction result from blockchain: %s", err)
+		}
+	}
+	return result, nil
+}
+
+// GetHistoryForKey returns the committed changes of a particular chaincode ID
+// and key, oldest first, if state queries are enabled. Only the changes made
+// since the current version of the chaincode was deployed are returned.
+func (s *ServerOpenchain) GetHistoryForKey(ctx context.Context, chaincodeID, key string) (*pb.HistoryQueryResponse, error) {
+	if err := checkStateQueriesEnabled(); err != nil {
+		return nil, err
+	}
+	namespace, err := chaincode.GetStateNamespace(s.ledger, chaincodeID)
+	if err != nil {
+		return nil, err
+	}
+	itr, err := s.ledger.GetHistoryForKey(namespace, key)
+	if err != nil {
+		return nil, err
+	}
+	defer itr.Close()
+
+	response := &pb.HistoryQueryResponse{}
+	for itr.Next() {
+		response.Modifications = append(response.Modifications, itr.GetKeyModification())
+	}
+	if err = itr.Err(); err != nil {
+		return nil, fmt.Errorf("Error retrieving history from ledger: %s", err)
+	}
+	return response, nil
+}
+
+// GetChaincodeActivation returns the effective date of the current version
+// of a chaincode, nil if it has none, and whether the chaincode is active as of
+// the last committed block
+func (s *ServerOpenchain) GetChaincodeActivation(ctx context.Context, chaincodeID string) (*google_protobuf.Timestamp, bool, error) {
+	return chaincode.GetActivation(s.ledger, chaincodeID)
+}
+
+// GetChaincodeInfo returns the current version of a deployed chaincode. The
+// path and type of a confidential chaincode are not disclosed.
+func (s *ServerOpenchain) GetChaincodeInfo(ctx context.Context, request *pb.ChaincodeInfoRequest) (*pb.ChaincodeInfo, error) {
+	server, err := s.ForChain(request.ChainID)
+	if err != nil {
+		return nil, err
+	}
+	info, err := chaincode.GetChaincodeInfo(server.ledger, request.Name)
+	if err != nil {
+		switch err {
+		case ledger.ErrResourceNotFound:
+			return nil, ErrNotFound
+		default:
+			return nil, fmt.Errorf("Error retrieving chaincode %s: %s", request.Name, err)
+		}
+	}
+	return info, nil
+}
+
 // GetPeers returns a list of all peer nodes currently connected to the target peer.
 func (s *ServerOpenchain) GetPeers(ctx context.Context, e *google_protobuf.Empty) (*pb.PeersMessage, error) {
 	return s.peerInfo.GetPeers()
 }
 
+// GetMembers returns the members of the network known to the target peer,
+// which are the connected peers unless its PeerInfo is a MembershipInfo.
+func (s *ServerOpenchain) GetMembers() ([]*discovery.Member, error) {
+	if membershipInfo, ok := s.peerInfo.(MembershipInfo); ok {
+		return membershipInfo.GetMembers()
+	}
+	peersMessage, err := s.peerInfo.GetPeers()
+	if err != nil {
+		return nil, err
+	}
+	var members []*discovery.Member
+	for _, peerEndpoint := range peersMessage.Peers {
+		members = append(members, &discovery.Member{Endpoint: peerEndpoint, Connected: true})
+	}
+	return members, nil
+}
+
 // GetPeerEndpoint returns PeerEndpoint info of target peer.
 func (s *ServerOpenchain) GetPeerEndpoint(ctx context.Context, e *google_protobuf.Empty) (*pb.PeersMessage, error) {
 	peers := []*pb.PeerEndpoint{}
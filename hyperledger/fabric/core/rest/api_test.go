@@ -245,14 +245,165 @@ func TestServerOpenchain_API_GetState(t *testing.T) {
 		t.Fail()
 	}
 
+	// The state is not served unless state queries are enabled
+	if _, err = server.GetState(context.Background(), &protos.StateKey{ChaincodeID: "MyContract1", Key: "code"}); err != ErrStateQueriesDisabled {
+		t.Fatalf("Expected ErrStateQueriesDisabled but got %v", err)
+	}
+	viper.Set("rest.stateQueries.enabled", true)
+	defer viper.Set("rest.stateQueries.enabled", false)
+
 	// Retrieve the current number of blocks in the blockchain. Must be 3.
-	val, stateErr := server.GetState(context.Background(), "MyContract1", "code")
+	val, stateErr := server.GetState(context.Background(), &protos.StateKey{ChaincodeID: "MyContract1", Key: "code"})
 	if stateErr != nil {
 		t.Fatalf("Error retrieving state: %s", stateErr)
-	} else if bytes.Compare(val, []byte("code example")) != 0 {
-		t.Fatalf("Expected %s, but got %s", []byte("code example"), val)
+	} else if bytes.Compare(val.Value, []byte("code example")) != 0 {
+		t.Fatalf("Expected %s, but got %s", []byte("code example"), val.Value)
+	}
+
+}
+
+func TestServerOpenchain_API_GetStateRange(t *testing.T) {
+	ledger1 := ledger.InitTestLedger(t)
+	buildTestLedger1(ledger1, t)
+	ledger1.BeginTxBatch(3)
+	ledger1.TxBegin("txUUID")
+	for _, key := range []string{"k3", "k1", "k4", "k2", "k5"} {
+		ledger1.SetState("MyContract", key, []byte("value-"+key))
+	}
+	ledger1.TxFinished("txUUID", true)
+	ledger1.CommitTxBatch(3, nil, nil, []byte("dummy-proof"))
+
+	server, err := NewOpenchainServerWithPeerInfo(new(peerInfo))
+	if err != nil {
+		t.Fatalf("Error creating OpenchainServer: %s", err)
+	}
+
+	// The state is not served unless state queries are enabled
+	if _, err = server.GetStateRange(context.Background(), &protos.StateRange{ChaincodeID: "MyContract"}); err != ErrStateQueriesDisabled {
+		t.Fatalf("Expected ErrStateQueriesDisabled but got %v", err)
+	}
+	viper.Set("rest.stateQueries.enabled", true)
+	defer viper.Set("rest.stateQueries.enabled", false)
+
+	response, err := server.GetStateRange(context.Background(), &protos.StateRange{ChaincodeID: "MyContract", StartKey: "k2", EndKey: "k5", Limit: 2})
+	if err != nil {
+		t.Fatalf("Error retrieving state range: %s", err)
+	}
+	if len(response.KeysAndValues) != 2 || !response.HasMore {
+		t.Fatalf("Expected 2 keys and more to come but got %v", response)
+	}
+	if kv := response.KeysAndValues[0]; kv.Key != "k2" || string(kv.Value) != "value-k2" || response.KeysAndValues[1].Key != "k3" {
+		t.Fatalf("Expected the keys k2 and k3 in order but got %v", response.KeysAndValues)
+	}
+
+	response, err = server.GetStateRange(context.Background(), &protos.StateRange{ChaincodeID: "MyContract", StartKey: "k4"})
+	if err != nil {
+		t.Fatalf("Error retrieving state range: %s", err)
+	}
+	if len(response.KeysAndValues) != 3 || response.HasMore {
+		t.Fatalf("Expected the keys k4, k5 and x but got %v", response)
+	}
+
+	// The chain must be hosted by the peer
+	if _, err = server.GetStateRange(context.Background(), &protos.StateRange{ChainID: "unknownchain", ChaincodeID: "MyContract"}); err == nil {
+		t.Fatalf("Expected an error retrieving the state of a chain not hosted by the peer")
+	}
+
+	// A range with more keys than can be scanned must be narrowed
+	defer func(scan int) { maxStateRangeScan = scan }(maxStateRangeScan)
+	maxStateRangeScan = 3
+	if _, err = server.GetStateRange(context.Background(), &protos.StateRange{ChaincodeID: "MyContract", Limit: 1}); err == nil {
+		t.Fatalf("Expected an error retrieving a range of more than %d keys", maxStateRangeScan)
+	}
+	response, err = server.GetStateRange(context.Background(), &protos.StateRange{ChaincodeID: "MyContract", StartKey: "k2", EndKey: "k4", Limit: 1})
+	if err != nil {
+		t.Fatalf("Error retrieving state range: %s", err)
+	}
+	if len(response.KeysAndValues) != 1 || response.KeysAndValues[0].Key != "k2" || !response.HasMore {
+		t.Fatalf("Expected the key k2 and more to come but got %v", response)
+	}
+}
+
+func TestServerOpenchain_API_GetBlocksRange(t *testing.T) {
+	ledger1 := ledger.InitTestLedger(t)
+	buildTestLedger2(ledger1, t)
+
+	server, err := NewOpenchainServerWithPeerInfo(new(peerInfo))
+	if err != nil {
+		t.Fatalf("Error creating OpenchainServer: %s", err)
+	}
+
+	// The range is cut at the last block
+	collector := &blocksCollector{}
+	if err = server.GetBlocksRange(&protos.BlocksRange{Start: 2, End: 10}, collector); err != nil {
+		t.Fatalf("Error retrieving blocks range: %s", err)
+	}
+	if len(collector.blocks) != 3 {
+		t.Fatalf("Expected blocks 2 to 4 but got %d blocks", len(collector.blocks))
+	}
+	for i, block := range collector.blocks {
+		if len(block.Transactions) != i+2 {
+			t.Errorf("Expected block %d to contain %d transactions but got %d", i+2, i+2, len(block.Transactions))
+		}
+	}
+
+	if err = server.GetBlocksRange(&protos.BlocksRange{Start: 5, End: 10}, &blocksCollector{}); err != ErrNotFound {
+		t.Fatalf("Expected ErrNotFound for a range after the last block but got %v", err)
+	}
+	if err = server.GetBlocksRange(&protos.BlocksRange{Start: 3, End: 1}, &blocksCollector{}); err == nil {
+		t.Fatalf("Expected an error for a range ending before its start")
+	}
+}
+
+func TestServerOpenchain_API_GetTransactionResult(t *testing.T) {
+	ledger1 := ledger.InitTestLedger(t)
+	buildTestLedger1(ledger1, t)
+	tx, _ := protos.NewTransaction(protos.ChaincodeID{Path: "MyContract"}, generateUUID(t), "setX", []string{"{x: \"hello\"}"})
+	ledger1.BeginTxBatch(3)
+	results := []*protos.TransactionResult{{Uuid: tx.Uuid, Result: []byte("result"), ErrorCode: 0}}
+	ledger1.CommitTxBatch(3, []*protos.Transaction{tx}, results, []byte("dummy-proof"))
+
+	server, err := NewOpenchainServerWithPeerInfo(new(peerInfo))
+	if err != nil {
+		t.Fatalf("Error creating OpenchainServer: %s", err)
 	}
 
+	result, err := server.GetTransactionResult(context.Background(), &protos.TransactionUUID{Uuid: tx.Uuid})
+	if err != nil {
+		t.Fatalf("Error retrieving transaction result: %s", err)
+	}
+	if result.Uuid != tx.Uuid || string(result.Result) != "result" {
+		t.Fatalf("Unexpected transaction result %v", result)
+	}
+	if _, err = server.GetTransactionResult(context.Background(), &protos.TransactionUUID{Uuid: "NON-EXISTING-UUID"}); err != ErrNotFound {
+		t.Fatalf("Expected ErrNotFound for a non-existing transaction but got %v", err)
+	}
+}
+
+func TestServerOpenchain_API_GetChaincodeInfo(t *testing.T) {
+	ledger1 := ledger.InitTestLedger(t)
+	buildTestLedger1(ledger1, t)
+	cds := &protos.ChaincodeDeploymentSpec{ChaincodeSpec: &protos.ChaincodeSpec{Type: protos.ChaincodeSpec_GOLANG,
+		ChaincodeID: &protos.ChaincodeID{Path: "example.com/mycc", Name: "mycc"}}}
+	tx, _ := protos.NewChaincodeDeployTransaction(cds, "mycc")
+	ledger1.BeginTxBatch(3)
+	ledger1.CommitTxBatch(3, []*protos.Transaction{tx}, nil, []byte("dummy-proof"))
+
+	server, err := NewOpenchainServerWithPeerInfo(new(peerInfo))
+	if err != nil {
+		t.Fatalf("Error creating OpenchainServer: %s", err)
+	}
+
+	info, err := server.GetChaincodeInfo(context.Background(), &protos.ChaincodeInfoRequest{Name: "mycc"})
+	if err != nil {
+		t.Fatalf("Error retrieving chaincode info: %s", err)
+	}
+	if info.ChaincodeID.Path != "example.com/mycc" || info.TransactionUUID != "mycc" || !info.Active {
+		t.Fatalf("Unexpected chaincode info %v", info)
+	}
+	if _, err = server.GetChaincodeInfo(context.Background(), &protos.ChaincodeInfoRequest{Name: "othercc"}); err != ErrNotFound {
+		t.Fatalf("Expected ErrNotFound for a chaincode not deployed but got %v", err)
+	}
 }
 
 // buildTestLedger1 builds a simple ledger data structure that contains a blockchain with 3 blocks.
@@ -28,6 +28,7 @@ import (
 	"os"
 	"strconv"
 	"strings"
+	"time"
 
 	"golang.org/x/net/context"
 
@@ -35,12 +36,14 @@ import (
 	"github.com/golang/protobuf/jsonpb"
 	"github.com/op/go-logging"
 	"github.com/spf13/viper"
+	"google.golang.org/grpc"
 
 	core "github.com/hyperledger/fabric/core"
 	"github.com/hyperledger/fabric/core/chaincode"
 	"github.com/hyperledger/fabric/core/comm"
 	"github.com/hyperledger/fabric/core/crypto"
 	"github.com/hyperledger/fabric/core/crypto/primitives"
+	"github.com/hyperledger/fabric/core/ledger"
 	pb "github.com/hyperledger/fabric/protos"
 )
 
@@ -67,6 +70,49 @@ type restResult struct {
 	Error string `json:",omitempty"`
 }
 
+// chaincodeActivation reports when a chaincode becomes executable.
+type chaincodeActivation struct {
+	ChaincodeID   string `json:"chaincodeID"`
+	EffectiveDate string `json:"effectiveDate,omitempty"`
+	Active        bool   `json:"active"`
+}
+
+// reconfigurationRequest defines the request payload of the
+// /network/reconfiguration endpoints. The payload is base64 encoded.
+type reconfigurationRequest struct {
+	ChainID       string   `json:"chainID,omitempty"`
+	Validators    []string `json:"validators,omitempty"`
+	Payload       []byte   `json:"payload,omitempty"`
+	SecureContext string   `json:"secureContext,omitempty"`
+}
+
+// reconfigurer is implemented by the devops servers able to reconfigure the
+// validators of a chain
+type reconfigurer interface {
+	ApproveReconfiguration(secureContext string, chainID string, validators []string, payload []byte) ([]byte, error)
+	Reconfigure(chainID string, payload []byte) (*pb.Response, error)
+}
+
+// maxBlocksRange is the maximum number of blocks returned by the
+// /chain/blocks endpoint, which holds them in memory unlike the gRPC stream
+const maxBlocksRange = 100
+
+// blocksCollector is the GetBlocksRange stream collecting the blocks of a
+// /chain/blocks response
+type blocksCollector struct {
+	grpc.ServerStream
+	blocks []*pb.Block
+}
+
+func (c *blocksCollector) Send(block *pb.Block) error {
+	c.blocks = append(c.blocks, block)
+	return nil
+}
+
+func (c *blocksCollector) Context() context.Context {
+	return context.Background()
+}
+
 // rpcRequest defines the JSON RPC 2.0 request payload for the /chaincode endpoint.
 type rpcRequest struct {
 	Jsonrpc *string           `json:"jsonrpc,omitempty"`
@@ -115,9 +161,12 @@ type rpcResponse struct {
 
 // rpcResult defines the structure for an rpc sucess/error result message.
 type rpcResult struct {
-	Status  string    `json:"status,omitempty"`
-	Message string    `json:"message,omitempty"`
-	Error   *rpcError `json:"error,omitempty"`
+	Status  string `json:"status,omitempty"`
+	Message string `json:"message,omitempty"`
+	Version uint64 `json:"version,omitempty"`
+	// EffectiveDate is set when the deployed chaincode is not executable right away
+	EffectiveDate string    `json:"effectiveDate,omitempty"`
+	Error         *rpcError `json:"error,omitempty"`
 }
 
 // rpcError defines the structure for an rpc error.
@@ -147,12 +196,24 @@ var (
 	ChaincodeDeployError     = &rpcError{Code: -32001, Message: "Deployment failure", Data: "Chaincode deployment has failed."}
 	ChaincodeInvokeError     = &rpcError{Code: -32002, Message: "Invocation failure", Data: "Chaincode invocation has failed."}
 	ChaincodeQueryError      = &rpcError{Code: -32003, Message: "Query failure", Data: "Chaincode query has failed."}
+	ChaincodeTerminateError  = &rpcError{Code: -32004, Message: "Termination failure", Data: "Chaincode termination has failed."}
+	ChaincodeUpgradeError    = &rpcError{Code: -32005, Message: "Upgrade failure", Data: "Chaincode upgrade has failed."}
 )
 
 // SetOpenchainServer is a middleware function that sets the pointer to the
-// underlying ServerOpenchain object and the undeflying Devops object.
+// underlying ServerOpenchain object and the undeflying Devops object. The
+// ledger endpoints serve the chain selected by the "chain" query parameter,
+// the default chain if absent.
 func (s *ServerOpenchainREST) SetOpenchainServer(rw web.ResponseWriter, req *web.Request, next web.NextMiddlewareFunc) {
-	s.server = serverOpenchain
+	server, err := serverOpenchain.ForChain(req.URL.Query().Get("chain"))
+	if err != nil {
+		rw.Header().Set("Content-Type", "application/json")
+		rw.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(rw).Encode(restResult{Error: err.Error()})
+		restLogger.Errorf("Error selecting chain: %s", err)
+		return
+	}
+	s.server = server
 	s.devops = serverDevops
 
 	next(rw, req)
@@ -648,7 +709,7 @@ func (s *ServerOpenchainREST) GetTransactionByUUID(rw web.ResponseWriter, req *w
 	txUUID := req.PathParams["uuid"]
 
 	// Retrieve the transaction matching the UUID
-	tx, err := s.server.GetTransactionByUUID(context.Background(), txUUID)
+	tx, err := s.server.GetTransactionByUUID(context.Background(), &pb.TransactionUUID{Uuid: txUUID})
 
 	encoder := json.NewEncoder(rw)
 
@@ -671,6 +732,230 @@ func (s *ServerOpenchainREST) GetTransactionByUUID(rw web.ResponseWriter, req *w
 	}
 }
 
+// GetBlocksRange returns the blocks from the "start" to the "end" query
+// parameters, both included, up to the last block. The code packages of the
+// deploy transactions are left out.
+func (s *ServerOpenchainREST) GetBlocksRange(rw web.ResponseWriter, req *web.Request) {
+	encoder := json.NewEncoder(rw)
+
+	// Parse out the range
+	query := req.URL.Query()
+	start, err := strconv.ParseUint(query.Get("start"), 10, 64)
+	if err != nil {
+		rw.WriteHeader(http.StatusBadRequest)
+		encoder.Encode(restResult{Error: "Range start must be an integer (uint64)."})
+		return
+	}
+	end, err := strconv.ParseUint(query.Get("end"), 10, 64)
+	if err != nil {
+		rw.WriteHeader(http.StatusBadRequest)
+		encoder.Encode(restResult{Error: "Range end must be an integer (uint64)."})
+		return
+	}
+	if end >= start && end-start >= maxBlocksRange {
+		rw.WriteHeader(http.StatusBadRequest)
+		encoder.Encode(restResult{Error: fmt.Sprintf("At most %d blocks can be retrieved at once.", maxBlocksRange)})
+		return
+	}
+
+	// Retrieve the blocks from the blockchain
+	collector := &blocksCollector{}
+	err = s.server.GetBlocksRange(&pb.BlocksRange{Start: start, End: end}, collector)
+	if err != nil {
+		switch err {
+		case ErrNotFound:
+			rw.WriteHeader(http.StatusNotFound)
+		default:
+			rw.WriteHeader(http.StatusBadRequest)
+		}
+		encoder.Encode(restResult{Error: err.Error()})
+		return
+	}
+	rw.WriteHeader(http.StatusOK)
+	encoder.Encode(collector.blocks)
+}
+
+// GetTransactionResult returns the result of the transaction matching the
+// specified UUID
+func (s *ServerOpenchainREST) GetTransactionResult(rw web.ResponseWriter, req *web.Request) {
+	// Parse out the transaction UUID
+	txUUID := req.PathParams["uuid"]
+
+	// Retrieve the result of the transaction matching the UUID
+	result, err := s.server.GetTransactionResult(context.Background(), &pb.TransactionUUID{Uuid: txUUID})
+
+	encoder := json.NewEncoder(rw)
+
+	// Check for Error
+	if err != nil {
+		switch err {
+		case ErrNotFound:
+			rw.WriteHeader(http.StatusNotFound)
+			encoder.Encode(restResult{Error: fmt.Sprintf("Transaction %s is not found.", txUUID)})
+		default:
+			rw.WriteHeader(http.StatusInternalServerError)
+			encoder.Encode(restResult{Error: fmt.Sprintf("Error retrieving result of transaction %s: %s.", txUUID, err)})
+			restLogger.Errorf("Error retrieving result of transaction %s: %s", txUUID, err)
+		}
+	} else {
+		rw.WriteHeader(http.StatusOK)
+		encoder.Encode(result)
+		restLogger.Infof("Successfully retrieved result of transaction: %s", txUUID)
+	}
+}
+
+// GetState returns the committed value of a key of a chaincode. The peer must
+// run with rest.stateQueries.enabled.
+func (s *ServerOpenchainREST) GetState(rw web.ResponseWriter, req *web.Request) {
+	// Parse out the chaincode ID and the key
+	chaincodeID := req.PathParams["chaincodeID"]
+	key := req.PathParams["key"]
+
+	// Retrieve the value of the key
+	value, err := s.server.GetState(context.Background(), &pb.StateKey{ChaincodeID: chaincodeID, Key: key})
+
+	encoder := json.NewEncoder(rw)
+
+	// Check for Error
+	if err == ErrStateQueriesDisabled {
+		rw.WriteHeader(http.StatusForbidden)
+		encoder.Encode(restResult{Error: "State queries are not enabled on this peer."})
+	} else if err != nil {
+		rw.WriteHeader(http.StatusInternalServerError)
+		encoder.Encode(restResult{Error: fmt.Sprintf("Error retrieving key %s of chaincode %s: %s.", key, chaincodeID, err)})
+		restLogger.Errorf("Error retrieving key %s of chaincode %s: %s", key, chaincodeID, err)
+	} else if value.Value == nil {
+		rw.WriteHeader(http.StatusNotFound)
+		encoder.Encode(restResult{Error: fmt.Sprintf("Key %s of chaincode %s is not found.", key, chaincodeID)})
+	} else {
+		rw.WriteHeader(http.StatusOK)
+		encoder.Encode(value)
+	}
+}
+
+// GetStateRange returns the committed keys of a chaincode between the "start"
+// and "end" query parameters, both included, ordered by key. At most "limit"
+// keys are returned. The peer must run with rest.stateQueries.enabled.
+func (s *ServerOpenchainREST) GetStateRange(rw web.ResponseWriter, req *web.Request) {
+	encoder := json.NewEncoder(rw)
+
+	// Parse out the chaincode ID and the range
+	chaincodeID := req.PathParams["chaincodeID"]
+	query := req.URL.Query()
+	stateRange := &pb.StateRange{ChaincodeID: chaincodeID, StartKey: query.Get("start"), EndKey: query.Get("end")}
+	if rawLimit := query.Get("limit"); rawLimit != "" {
+		limit, err := strconv.ParseUint(rawLimit, 10, 32)
+		if err != nil {
+			rw.WriteHeader(http.StatusBadRequest)
+			encoder.Encode(restResult{Error: "Limit must be an integer (uint32)."})
+			return
+		}
+		stateRange.Limit = uint32(limit)
+	}
+
+	// Retrieve the keys of the range
+	response, err := s.server.GetStateRange(context.Background(), stateRange)
+
+	// Check for Error
+	if err == ErrStateQueriesDisabled {
+		rw.WriteHeader(http.StatusForbidden)
+		encoder.Encode(restResult{Error: "State queries are not enabled on this peer."})
+	} else if err != nil {
+		rw.WriteHeader(http.StatusInternalServerError)
+		encoder.Encode(restResult{Error: fmt.Sprintf("Error retrieving keys of chaincode %s: %s.", chaincodeID, err)})
+		restLogger.Errorf("Error retrieving keys of chaincode %s: %s", chaincodeID, err)
+	} else {
+		rw.WriteHeader(http.StatusOK)
+		encoder.Encode(response)
+	}
+}
+
+// GetChaincodeInfo returns the current version of a deployed chaincode
+func (s *ServerOpenchainREST) GetChaincodeInfo(rw web.ResponseWriter, req *web.Request) {
+	chaincodeID := req.PathParams["chaincodeID"]
+
+	info, err := s.server.GetChaincodeInfo(context.Background(), &pb.ChaincodeInfoRequest{Name: chaincodeID})
+
+	encoder := json.NewEncoder(rw)
+
+	// Check for Error
+	if err != nil {
+		switch err {
+		case ErrNotFound:
+			rw.WriteHeader(http.StatusNotFound)
+			encoder.Encode(restResult{Error: fmt.Sprintf("Chaincode %s is not deployed.", chaincodeID)})
+		default:
+			rw.WriteHeader(http.StatusInternalServerError)
+			encoder.Encode(restResult{Error: err.Error()})
+			restLogger.Errorf("Error retrieving chaincode %s: %s", chaincodeID, err)
+		}
+	} else {
+		rw.WriteHeader(http.StatusOK)
+		encoder.Encode(info)
+	}
+}
+
+// GetHistoryForKey returns the committed changes of a key of a chaincode,
+// oldest first. The peer must run with state queries and the ledger history
+// index enabled.
+func (s *ServerOpenchainREST) GetHistoryForKey(rw web.ResponseWriter, req *web.Request) {
+	// Parse out the chaincode ID and the key
+	chaincodeID := req.PathParams["chaincodeID"]
+	key := req.PathParams["key"]
+
+	// Retrieve the history of the key
+	history, err := s.server.GetHistoryForKey(context.Background(), chaincodeID, key)
+
+	encoder := json.NewEncoder(rw)
+
+	// Check for Error
+	if err != nil {
+		switch err {
+		case ErrStateQueriesDisabled:
+			rw.WriteHeader(http.StatusForbidden)
+			encoder.Encode(restResult{Error: "State queries are not enabled on this peer."})
+		case ledger.ErrHistoryNotEnabled:
+			rw.WriteHeader(http.StatusNotImplemented)
+			encoder.Encode(restResult{Error: "The ledger history index is not enabled on this peer."})
+		default:
+			rw.WriteHeader(http.StatusInternalServerError)
+			encoder.Encode(restResult{Error: fmt.Sprintf("Error retrieving history of key %s of chaincode %s: %s.", key, chaincodeID, err)})
+			restLogger.Errorf("Error retrieving history of key %s of chaincode %s: %s", key, chaincodeID, err)
+		}
+	} else {
+		// Return the changes of the key
+		rw.WriteHeader(http.StatusOK)
+		encoder.Encode(history)
+		restLogger.Infof("Successfully retrieved history of key %s of chaincode %s", key, chaincodeID)
+	}
+}
+
+// GetChaincodeActivation returns the effective date of the current version of
+// a chaincode and whether the chaincode is active, i.e. whether a block
+// timestamped at or after the effective date has been committed.
+func (s *ServerOpenchainREST) GetChaincodeActivation(rw web.ResponseWriter, req *web.Request) {
+	chaincodeID := req.PathParams["chaincodeID"]
+
+	date, active, err := s.server.GetChaincodeActivation(context.Background(), chaincodeID)
+
+	encoder := json.NewEncoder(rw)
+
+	// Check for Error
+	if err != nil {
+		rw.WriteHeader(http.StatusInternalServerError)
+		encoder.Encode(restResult{Error: fmt.Sprintf("Error retrieving activation of chaincode %s: %s.", chaincodeID, err)})
+		restLogger.Errorf("Error retrieving activation of chaincode %s: %s", chaincodeID, err)
+	} else {
+		activation := chaincodeActivation{ChaincodeID: chaincodeID, Active: active}
+		if date != nil {
+			activation.EffectiveDate = chaincode.FormatEffectiveDate(date)
+		}
+		rw.WriteHeader(http.StatusOK)
+		encoder.Encode(activation)
+		restLogger.Infof("Successfully retrieved activation of chaincode %s", chaincodeID)
+	}
+}
+
 // Deploy first builds the chaincode package and subsequently deploys it to the
 // blockchain.
 //
@@ -1197,7 +1482,7 @@ func (s *ServerOpenchainREST) ProcessChaincode(rw web.ResponseWriter, req *web.R
 		return
 	}
 
-	// Insure that the JSON method string is present and is either deploy, invoke or query
+	// Insure that the JSON method string is present and is either deploy, upgrade, invoke, query or terminate
 	if requestPayload.Method == nil {
 		// If the request is not a notification, produce a response.
 		if !notification {
@@ -1209,7 +1494,7 @@ func (s *ServerOpenchainREST) ProcessChaincode(rw web.ResponseWriter, req *web.R
 		restLogger.Error("Missing JSON RPC 2.0 method string.")
 
 		return
-	} else if (*(requestPayload.Method) != "deploy") && (*(requestPayload.Method) != "invoke") && (*(requestPayload.Method) != "query") {
+	} else if (*(requestPayload.Method) != "deploy") && (*(requestPayload.Method) != "upgrade") && (*(requestPayload.Method) != "invoke") && (*(requestPayload.Method) != "query") && (*(requestPayload.Method) != "terminate") {
 		// If the request is not a notification, produce a response.
 		if !notification {
 			// Format the error appropriately and produce JSON RPC 2.0 response
@@ -1254,6 +1539,62 @@ func (s *ServerOpenchainREST) ProcessChaincode(rw web.ResponseWriter, req *web.R
 
 		// Process the chaincode deployment request and record the result
 		result = s.processChaincodeDeploy(deploySpec)
+	} else if *(requestPayload.Method) == "upgrade" {
+
+		//
+		// Chaincode upgrade was requested
+		//
+
+		// Payload params field must contain a ChaincodeSpec message
+		if requestPayload.Params == nil {
+			// If the request is not a notification, produce a response.
+			if !notification {
+				// Format the error appropriately and produce JSON RPC 2.0 response
+				errObj := formatRPCError(InvalidParams.Code, InvalidParams.Message, "Client must supply ChaincodeSpec for chaincode upgrade request.")
+				rw.WriteHeader(http.StatusBadRequest)
+				encoder.Encode(formatRPCResponse(errObj, requestPayload.ID))
+			}
+			restLogger.Error("Client must supply ChaincodeSpec for chaincode upgrade request.")
+
+			return
+		}
+
+		// Process the chaincode upgrade request and record the result
+		result = s.processChaincodeUpgrade(requestPayload.Params)
+	} else if *(requestPayload.Method) == "terminate" {
+
+		//
+		// Chaincode termination was requested
+		//
+
+		// Payload params field must contain a ChaincodeSpec message
+		if requestPayload.Params == nil {
+			// If the request is not a notification, produce a response.
+			if !notification {
+				// Format the error appropriately and produce JSON RPC 2.0 response
+				errObj := formatRPCError(InvalidParams.Code, InvalidParams.Message, "Client must supply ChaincodeSpec for chaincode terminate request.")
+				rw.WriteHeader(http.StatusBadRequest)
+				encoder.Encode(formatRPCResponse(errObj, requestPayload.ID))
+			}
+			restLogger.Error("Client must supply ChaincodeSpec for chaincode terminate request.")
+
+			return
+		}
+
+		// The params field may also carry the purgeState flag of the
+		// ChaincodeTerminationSpec next to the ChaincodeSpec fields. The request
+		// payload has been decoded successfully above, so this cannot fail.
+		var terminateRequest struct {
+			Params struct {
+				PurgeState bool `json:"purgeState"`
+			} `json:"params"`
+		}
+		json.Unmarshal(reqBody, &terminateRequest)
+
+		terminateSpec := &pb.ChaincodeTerminationSpec{ChaincodeSpec: requestPayload.Params, PurgeState: terminateRequest.Params.PurgeState}
+
+		// Process the chaincode termination request and record the result
+		result = s.processChaincodeTerminate(terminateSpec)
 	} else {
 
 		//
@@ -1296,9 +1637,9 @@ func (s *ServerOpenchainREST) ProcessChaincode(rw web.ResponseWriter, req *web.R
 		rw.Write(jsonResponse)
 	}
 
-	// Make a clarification in the invoke response message, that the transaction has been successfully submitted but not completed
-	if *(requestPayload.Method) == "invoke" {
-		restLogger.Infof("REST successfully submitted invoke transaction: %s", string(jsonResponse))
+	// Make a clarification in the invoke and terminate response messages, that the transaction has been successfully submitted but not completed
+	if *(requestPayload.Method) == "invoke" || *(requestPayload.Method) == "terminate" {
+		restLogger.Infof("REST successfully submitted %s transaction: %s", *(requestPayload.Method), string(jsonResponse))
 	} else {
 		restLogger.Infof("REST successfully %s chaincode: %s", *(requestPayload.Method), string(jsonResponse))
 	}
@@ -1449,11 +1790,259 @@ func (s *ServerOpenchainREST) processChaincodeDeploy(spec *pb.ChaincodeSpec) rpc
 	//
 
 	result := formatRPCOK(chainID)
+	if date := chaincodeDeploymentSpec.EffectiveDate; date != nil {
+		result.EffectiveDate = chaincode.FormatEffectiveDate(date)
+	}
 	restLogger.Infof("Successfully deployed chainCode: %s", chainID)
 
 	return result
 }
 
+// processChaincodeUpgrade triggers chaincode upgrade and returns a result or an error
+func (s *ServerOpenchainREST) processChaincodeUpgrade(spec *pb.ChaincodeSpec) rpcResult {
+	restLogger.Info("REST upgrading chaincode...")
+
+	// Check that the ChaincodeID is not nil.
+	if spec.ChaincodeID == nil {
+		// Format the error appropriately for further processing
+		error := formatRPCError(InvalidParams.Code, InvalidParams.Message, "Payload must contain a ChaincodeID.")
+		restLogger.Error("Payload must contain a ChaincodeID.")
+
+		return error
+	}
+
+	// Check that the name of the chaincode to upgrade is not blank.
+	if spec.ChaincodeID.Name == "" {
+		// Format the error appropriately for further processing
+		error := formatRPCError(InvalidParams.Code, InvalidParams.Message, "Chaincode name may not be blank.")
+		restLogger.Error("Chaincode name may not be blank.")
+
+		return error
+	}
+
+	// In network mode, the new version is built from the chaincode path
+	if viper.GetString("chaincode.mode") != chaincode.DevModeUserRunsChaincode && spec.ChaincodeID.Path == "" {
+		// Format the error appropriately for further processing
+		error := formatRPCError(InvalidParams.Code, InvalidParams.Message, "Chaincode path may not be blank.")
+		restLogger.Error("Chaincode path may not be blank.")
+
+		return error
+	}
+
+	// Check that the CtorMsg is not left blank.
+	if (spec.CtorMsg == nil) || (spec.CtorMsg.Function == "") {
+		// Format the error appropriately for further processing
+		error := formatRPCError(InvalidParams.Code, InvalidParams.Message, "Payload must contain a CtorMsg with a Chaincode function name.")
+		restLogger.Error("Payload must contain a CtorMsg with a Chaincode function name.")
+
+		return error
+	}
+
+	//
+	// Check if security is enabled
+	//
+
+	if core.SecurityEnabled() {
+		// User registrationID must be present inside request payload with security enabled
+		chaincodeUsr := spec.SecureContext
+		if chaincodeUsr == "" {
+			// Format the error appropriately for further processing
+			error := formatRPCError(InvalidParams.Code, InvalidParams.Message, "Must supply username for chaincode when security is enabled.")
+			restLogger.Error("Must supply username for chaincode when security is enabled.")
+
+			return error
+		}
+
+		// Retrieve the REST data storage path
+		// Returns /var/hyperledger/production/client/
+		localStore := getRESTFilePath()
+
+		// Check if the user is logged in before sending transaction
+		if _, err := os.Stat(localStore + "loginToken_" + chaincodeUsr); err == nil {
+			// No error returned, therefore token exists so user is already logged in
+			restLogger.Infof("Local user '%s' is already logged in. Retrieving login token.", chaincodeUsr)
+
+			// Read in the login token
+			token, err := ioutil.ReadFile(localStore + "loginToken_" + chaincodeUsr)
+			if err != nil {
+				// Format the error appropriately for further processing
+				error := formatRPCError(InternalError.Code, InternalError.Message, fmt.Sprintf("Fatal error when reading client login token: %s", err))
+				restLogger.Errorf("Fatal error when reading client login token: %s", err)
+
+				return error
+			}
+
+			// Add the login token to the chaincodeSpec. Confidential chaincodes
+			// cannot be upgraded.
+			spec.SecureContext = string(token)
+		} else {
+			// Check if the token is not there and fail
+			if os.IsNotExist(err) {
+				// Format the error appropriately for further processing
+				error := formatRPCError(MissingRegistrationError.Code, MissingRegistrationError.Message, MissingRegistrationError.Data)
+				restLogger.Error(MissingRegistrationError.Data)
+
+				return error
+			}
+			// Unexpected error
+			// Format the error appropriately for further processing
+			error := formatRPCError(InternalError.Code, InternalError.Message, fmt.Sprintf("Unexpected fatal error when checking for client login token: %s", err))
+			restLogger.Errorf("Unexpected fatal error when checking for client login token: %s", err)
+
+			return error
+		}
+	}
+
+	//
+	// Trigger the chaincode upgrade through the devops service
+	//
+	chaincodeDeploymentSpec, err := s.devops.Upgrade(context.Background(), spec)
+
+	//
+	// Upgrade failed
+	//
+
+	if err != nil {
+		// Format the error appropriately for further processing
+		error := formatRPCError(ChaincodeUpgradeError.Code, ChaincodeUpgradeError.Message, fmt.Sprintf("Error when upgrading chaincode: %s", err))
+		restLogger.Errorf("Error when upgrading chaincode: %s", err)
+
+		return error
+	}
+
+	//
+	// Upgrade succeeded
+	//
+
+	// The chaincode keeps its name, report the version it was upgraded to
+	chainID := chaincodeDeploymentSpec.ChaincodeSpec.ChaincodeID.Name
+	version := chaincodeDeploymentSpec.ChaincodeSpec.ChaincodeID.Version
+
+	//
+	// Output correctly formatted response
+	//
+
+	result := formatRPCOK(chainID)
+	result.Version = version
+	if date := chaincodeDeploymentSpec.EffectiveDate; date != nil {
+		result.EffectiveDate = chaincode.FormatEffectiveDate(date)
+	}
+	restLogger.Infof("Successfully upgraded chainCode %s to version %d", chainID, version)
+
+	return result
+}
+
+// processChaincodeTerminate triggers chaincode termination and returns a result or an error
+func (s *ServerOpenchainREST) processChaincodeTerminate(spec *pb.ChaincodeTerminationSpec) rpcResult {
+	restLogger.Info("REST terminating chaincode...")
+
+	// Check that the ChaincodeID is not nil.
+	if spec.ChaincodeSpec.ChaincodeID == nil {
+		// Format the error appropriately for further processing
+		error := formatRPCError(InvalidParams.Code, InvalidParams.Message, "Payload must contain a ChaincodeID.")
+		restLogger.Error("Payload must contain a ChaincodeID.")
+
+		return error
+	}
+
+	// Check that the Chaincode name is not blank.
+	if spec.ChaincodeSpec.ChaincodeID.Name == "" {
+		// Format the error appropriately for further processing
+		error := formatRPCError(InvalidParams.Code, InvalidParams.Message, "Chaincode name may not be blank.")
+		restLogger.Error("Chaincode name may not be blank.")
+
+		return error
+	}
+
+	//
+	// Check if security is enabled
+	//
+
+	if core.SecurityEnabled() {
+		// User registrationID must be present inside request payload with security enabled
+		chaincodeUsr := spec.ChaincodeSpec.SecureContext
+		if chaincodeUsr == "" {
+			// Format the error appropriately for further processing
+			error := formatRPCError(InvalidParams.Code, InvalidParams.Message, "Must supply username for chaincode when security is enabled.")
+			restLogger.Error("Must supply username for chaincode when security is enabled.")
+
+			return error
+		}
+
+		// Retrieve the REST data storage path
+		// Returns /var/hyperledger/production/client/
+		localStore := getRESTFilePath()
+
+		// Check if the user is logged in before sending transaction
+		if _, err := os.Stat(localStore + "loginToken_" + chaincodeUsr); err == nil {
+			// No error returned, therefore token exists so user is already logged in
+			restLogger.Infof("Local user '%s' is already logged in. Retrieving login token.", chaincodeUsr)
+
+			// Read in the login token
+			token, err := ioutil.ReadFile(localStore + "loginToken_" + chaincodeUsr)
+			if err != nil {
+				// Format the error appropriately for further processing
+				error := formatRPCError(InternalError.Code, InternalError.Message, fmt.Sprintf("Fatal error when reading client login token: %s", err))
+				restLogger.Errorf("Fatal error when reading client login token: %s", err)
+
+				return error
+			}
+
+			// Add the login token to the chaincodeSpec. Terminate transactions
+			// are never confidential.
+			spec.ChaincodeSpec.SecureContext = string(token)
+		} else {
+			// Check if the token is not there and fail
+			if os.IsNotExist(err) {
+				// Format the error appropriately for further processing
+				error := formatRPCError(MissingRegistrationError.Code, MissingRegistrationError.Message, MissingRegistrationError.Data)
+				restLogger.Error(MissingRegistrationError.Data)
+
+				return error
+			}
+			// Unexpected error
+			// Format the error appropriately for further processing
+			error := formatRPCError(InternalError.Code, InternalError.Message, fmt.Sprintf("Unexpected fatal error when checking for client login token: %s", err))
+			restLogger.Errorf("Unexpected fatal error when checking for client login token: %s", err)
+
+			return error
+		}
+	}
+
+	//
+	// Trigger the chaincode termination through the devops service
+	//
+	resp, err := s.devops.Terminate(context.Background(), spec)
+
+	//
+	// Termination failed
+	//
+
+	if err != nil {
+		// Format the error appropriately for further processing
+		error := formatRPCError(ChaincodeTerminateError.Code, ChaincodeTerminateError.Message, fmt.Sprintf("Error when terminating chaincode: %s", err))
+		restLogger.Errorf("Error when terminating chaincode: %s", err)
+
+		return error
+	}
+
+	//
+	// Termination submitted
+	//
+
+	// Clients will need the txuuid in order to track it, record it
+	txuuid := string(resp.Msg)
+
+	//
+	// Output correctly formatted response
+	//
+
+	result := formatRPCOK(txuuid)
+	restLogger.Infof("Successfully submitted terminate transaction with txuuid (%s)", txuuid)
+
+	return result
+}
+
 // processChaincodeInvokeOrQuery triggers chaincode invoke or query and returns a result or an error
 func (s *ServerOpenchainREST) processChaincodeInvokeOrQuery(method string, spec *pb.ChaincodeInvocationSpec) rpcResult {
 	restLogger.Infof("REST %s chaincode...", method)
@@ -1624,9 +2213,17 @@ func (s *ServerOpenchainREST) processChaincodeInvokeOrQuery(method string, spec
 	return result
 }
 
-// GetPeers returns a list of all peer nodes currently connected to the target peer, including itself
+// peerStatus is a peer in the /network/peers response, along with whether the
+// target peer is connected to it and when it was last seen
+type peerStatus struct {
+	*pb.PeerEndpoint
+	Status   string `json:"status,omitempty"`
+	LastSeen string `json:"lastSeen,omitempty"`
+}
+
+// GetPeers returns a list of all peer nodes known to the target peer, including itself
 func (s *ServerOpenchainREST) GetPeers(rw web.ResponseWriter, req *web.Request) {
-	peers, err := s.server.GetPeers(context.Background(), &google_protobuf.Empty{})
+	members, err := s.server.GetMembers()
 	currentPeer, err1 := s.server.GetPeerEndpoint(context.Background(), &google_protobuf.Empty{})
 
 	encoder := json.NewEncoder(rw)
@@ -1644,22 +2241,95 @@ func (s *ServerOpenchainREST) GetPeers(rw web.ResponseWriter, req *web.Request)
 		restLogger.Errorf("Error: Accesing target peer endpoint data -- %s", err1)
 	} else {
 		currentPeerFound := false
-		peersList := peers.Peers
-		for _, peer := range peers.Peers {
+		peersList := []*peerStatus{}
+		for _, member := range members {
 			for _, cPeer := range currentPeer.Peers {
-				if *peer.GetID() == *cPeer.GetID() {
+				if *member.Endpoint.GetID() == *cPeer.GetID() {
 					currentPeerFound = true
 				}
 			}
+			peer := &peerStatus{PeerEndpoint: member.Endpoint, Status: "DISCOVERED"}
+			if member.Connected {
+				peer.Status = "CONNECTED"
+			}
+			if !member.LastSeen.IsZero() {
+				peer.LastSeen = member.LastSeen.UTC().Format(time.RFC3339)
+			}
+			peersList = append(peersList, peer)
 		}
 		if currentPeerFound == false {
-			peersList = append(peersList, currentPeer.Peers...)
+			for _, cPeer := range currentPeer.Peers {
+				peersList = append(peersList, &peerStatus{PeerEndpoint: cPeer})
+			}
 		}
-		peersMessage := &pb.PeersMessage{Peers: peersList}
 		// Success
 		rw.WriteHeader(http.StatusOK)
-		encoder.Encode(peersMessage)
+		encoder.Encode(map[string]interface{}{"peers": peersList})
+	}
+}
+
+// decodeReconfigurationRequest decodes the request of a /network/reconfiguration
+// endpoint and returns the devops server handling it, writing the error
+// response if it cannot be handled
+func (s *ServerOpenchainREST) decodeReconfigurationRequest(rw web.ResponseWriter, req *web.Request) (reconfigurer, *reconfigurationRequest) {
+	encoder := json.NewEncoder(rw)
+
+	r, ok := s.devops.(reconfigurer)
+	if !ok {
+		rw.WriteHeader(http.StatusNotImplemented)
+		encoder.Encode(restResult{Error: "This peer cannot reconfigure the validators."})
+		return nil, nil
 	}
+	request := &reconfigurationRequest{}
+	if err := json.NewDecoder(req.Body).Decode(request); err != nil {
+		rw.WriteHeader(http.StatusBadRequest)
+		encoder.Encode(restResult{Error: fmt.Sprintf("Invalid reconfiguration request: %s", err)})
+		restLogger.Errorf("Error: Invalid reconfiguration request -- %s", err)
+		return nil, nil
+	}
+	return r, request
+}
+
+// ApproveReconfiguration adds the approval of the target peer, a validator, to
+// the reconfiguration of the validators given in the request, or to a new one
+// to the validators. The caller must be logged in as an administrator of the
+// peer. The returned payload is passed on to the other validators to approve.
+func (s *ServerOpenchainREST) ApproveReconfiguration(rw web.ResponseWriter, req *web.Request) {
+	r, request := s.decodeReconfigurationRequest(rw, req)
+	if request == nil {
+		return
+	}
+
+	encoder := json.NewEncoder(rw)
+	payload, err := r.ApproveReconfiguration(request.SecureContext, request.ChainID, request.Validators, request.Payload)
+	if err != nil {
+		rw.WriteHeader(http.StatusBadRequest)
+		encoder.Encode(restResult{Error: err.Error()})
+		restLogger.Errorf("Error: Approving reconfiguration -- %s", err)
+		return
+	}
+	rw.WriteHeader(http.StatusOK)
+	encoder.Encode(reconfigurationRequest{ChainID: request.ChainID, Payload: payload})
+}
+
+// Reconfigure submits the reconfiguration of the validators approved by a
+// quorum of them, and returns the UUID of its transaction
+func (s *ServerOpenchainREST) Reconfigure(rw web.ResponseWriter, req *web.Request) {
+	r, request := s.decodeReconfigurationRequest(rw, req)
+	if request == nil {
+		return
+	}
+
+	encoder := json.NewEncoder(rw)
+	resp, err := r.Reconfigure(request.ChainID, request.Payload)
+	if err != nil {
+		rw.WriteHeader(http.StatusBadRequest)
+		encoder.Encode(restResult{Error: err.Error()})
+		restLogger.Errorf("Error: Submitting reconfiguration -- %s", err)
+		return
+	}
+	rw.WriteHeader(http.StatusOK)
+	encoder.Encode(restResult{OK: string(resp.Msg)})
 }
 
 // NotFound returns a custom landing page when a given hyperledger end point
@@ -1684,7 +2354,13 @@ func buildOpenchainRESTRouter() *web.Router {
 	router.Get("/registrar/:id/tcert", (*ServerOpenchainREST).GetTransactionCert)
 
 	router.Get("/chain", (*ServerOpenchainREST).GetBlockchainInfo)
+	router.Get("/chain/blocks", (*ServerOpenchainREST).GetBlocksRange)
 	router.Get("/chain/blocks/:id", (*ServerOpenchainREST).GetBlockByNumber)
+	router.Get("/chain/state/:chaincodeID", (*ServerOpenchainREST).GetStateRange)
+	router.Get("/chain/state/:chaincodeID/:key", (*ServerOpenchainREST).GetState)
+	router.Get("/chain/history/:chaincodeID/:key", (*ServerOpenchainREST).GetHistoryForKey)
+	router.Get("/chain/activation/:chaincodeID", (*ServerOpenchainREST).GetChaincodeActivation)
+	router.Get("/chain/chaincodes/:chaincodeID", (*ServerOpenchainREST).GetChaincodeInfo)
 
 	// The /devops endpoint is now considered deprecated and superseded by the /chaincode endpoint
 	router.Post("/devops/deploy", (*ServerOpenchainREST).Deploy)
@@ -1695,8 +2371,11 @@ func buildOpenchainRESTRouter() *web.Router {
 	router.Post("/chaincode", (*ServerOpenchainREST).ProcessChaincode)
 
 	router.Get("/transactions/:uuid", (*ServerOpenchainREST).GetTransactionByUUID)
+	router.Get("/transactions/:uuid/result", (*ServerOpenchainREST).GetTransactionResult)
 
 	router.Get("/network/peers", (*ServerOpenchainREST).GetPeers)
+	router.Post("/network/reconfiguration", (*ServerOpenchainREST).Reconfigure)
+	router.Post("/network/reconfiguration/approvals", (*ServerOpenchainREST).ApproveReconfiguration)
 
 	// Add not found page
 	router.NotFound((*ServerOpenchainREST).NotFound)
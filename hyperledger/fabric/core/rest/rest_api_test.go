@@ -23,13 +23,18 @@ import (
 	"io/ioutil"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
 	"time"
 
 	"golang.org/x/net/context"
 
+	"github.com/golang/protobuf/proto"
+	google_protobuf "google/protobuf"
+
 	"github.com/hyperledger/fabric/core/ledger"
 	"github.com/hyperledger/fabric/protos"
+	"github.com/spf13/viper"
 )
 
 func performHTTPGet(t *testing.T, url string) []byte {
@@ -95,6 +100,14 @@ func (d *mockDevops) Deploy(c context.Context, spec *protos.ChaincodeSpec) (*pro
 	return &protos.ChaincodeDeploymentSpec{ChaincodeSpec: spec, CodePackage: []byte{}}, nil
 }
 
+func (d *mockDevops) Upgrade(c context.Context, spec *protos.ChaincodeSpec) (*protos.ChaincodeDeploymentSpec, error) {
+	if spec.ChaincodeID.Name == "non-existing" {
+		return nil, fmt.Errorf("Upgrade failure on non-existing chaincode")
+	}
+	spec.ChaincodeID.Version = 2
+	return &protos.ChaincodeDeploymentSpec{ChaincodeSpec: spec, CodePackage: []byte{}}, nil
+}
+
 func (d *mockDevops) Invoke(c context.Context, cis *protos.ChaincodeInvocationSpec) (*protos.Response, error) {
 	switch cis.ChaincodeSpec.CtorMsg.Function {
 	case "fail":
@@ -115,6 +128,16 @@ func (d *mockDevops) Query(c context.Context, cis *protos.ChaincodeInvocationSpe
 	return nil, fmt.Errorf("Unknown query function")
 }
 
+func (d *mockDevops) Terminate(c context.Context, spec *protos.ChaincodeTerminationSpec) (*protos.Response, error) {
+	if spec.ChaincodeSpec.ChaincodeID.Name == "non-existing" {
+		return nil, fmt.Errorf("Terminate failure on non-existing chaincode")
+	}
+	if !spec.PurgeState {
+		return nil, fmt.Errorf("Terminate expected a purge of the state")
+	}
+	return &protos.Response{Status: protos.Response_SUCCESS, Msg: []byte("terminate_result")}, nil
+}
+
 func (d *mockDevops) EXP_GetApplicationTCert(ctx context.Context, secret *protos.Secret) (*protos.Response, error) {
 	return nil, nil
 }
@@ -135,6 +158,20 @@ func (d *mockDevops) GetTransactionResult(ctx context.Context, txRequest *protos
 	return nil, nil
 }
 
+func (d *mockDevops) ApproveReconfiguration(secureContext string, chainID string, validators []string, payload []byte) ([]byte, error) {
+	if secureContext != "admin" {
+		return nil, fmt.Errorf("Not an administrator")
+	}
+	return append(payload, []byte(strings.Join(validators, ","))...), nil
+}
+
+func (d *mockDevops) Reconfigure(chainID string, payload []byte) (*protos.Response, error) {
+	if string(payload) != "vp0,vp1" {
+		return nil, fmt.Errorf("Not approved")
+	}
+	return &protos.Response{Status: protos.Response_SUCCESS, Msg: []byte("reconfiguration_uuid")}, nil
+}
+
 func initGlobalServerOpenchain(t *testing.T) {
 	var err error
 	serverOpenchain, err = NewOpenchainServerWithPeerInfo(new(peerInfo))
@@ -286,6 +323,228 @@ func TestServerOpenchainREST_API_GetTransactionByUUID(t *testing.T) {
 	}
 }
 
+func TestServerOpenchainREST_API_GetHistoryForKey(t *testing.T) {
+	viper.Set("ledger.history.enabled", true)
+	defer viper.Set("ledger.history.enabled", false)
+
+	// Construct a ledger with 3 blocks.
+	ledger := ledger.InitTestLedger(t)
+	buildTestLedger1(ledger, t)
+
+	initGlobalServerOpenchain(t)
+
+	// Start the HTTP REST test server
+	httpServer := httptest.NewServer(buildOpenchainRESTRouter())
+	defer httpServer.Close()
+
+	// The history is not served unless state queries are enabled
+	response, err := http.Get(httpServer.URL + "/chain/history/MyContract/x")
+	if err != nil {
+		t.Fatalf("Error attempt to GET the history of key x: %v", err)
+	}
+	response.Body.Close()
+	if response.StatusCode != http.StatusForbidden {
+		t.Errorf("Expected status %d with state queries disabled but got %d", http.StatusForbidden, response.StatusCode)
+	}
+	viper.Set("rest.stateQueries.enabled", true)
+	defer viper.Set("rest.stateQueries.enabled", false)
+
+	body := performHTTPGet(t, httpServer.URL+"/chain/history/MyContract/x")
+	var history protos.HistoryQueryResponse
+	err = json.Unmarshal(body, &history)
+	if err != nil {
+		t.Fatalf("Invalid JSON response: %v", err)
+	}
+	if len(history.Modifications) != 1 {
+		t.Fatalf("Expected 1 modification of key x but got %d", len(history.Modifications))
+	}
+	if modification := history.Modifications[0]; modification.BlockNumber != 2 || string(modification.Value) != "hello" {
+		t.Errorf("Expected value 'hello' in block 2 but got '%s' in block %d", modification.Value, modification.BlockNumber)
+	}
+
+	body = performHTTPGet(t, httpServer.URL+"/chain/history/MyContract/non-existing")
+	var emptyHistory protos.HistoryQueryResponse
+	err = json.Unmarshal(body, &emptyHistory)
+	if err != nil {
+		t.Fatalf("Invalid JSON response: %v", err)
+	}
+	if len(emptyHistory.Modifications) != 0 {
+		t.Errorf("Expected no modifications of a non-existing key but got %d", len(emptyHistory.Modifications))
+	}
+}
+
+func TestServerOpenchainREST_API_GetChaincodeActivation(t *testing.T) {
+	// Construct a ledger with 3 blocks, and a chaincode taking effect in 2100
+	ledger := ledger.InitTestLedger(t)
+	buildTestLedger1(ledger, t)
+	effectiveDate, _ := proto.Marshal(&google_protobuf.Timestamp{Seconds: 4102444800})
+	ledger.BeginTxBatch(3)
+	ledger.TxBegin("txUUID")
+	ledger.SetState("#effectivedates", "MyContract", effectiveDate)
+	ledger.TxFinished("txUUID", true)
+	tx, _ := protos.NewTransaction(protos.ChaincodeID{Path: "MyContract"}, generateUUID(t), "setX", []string{"{x: \"hello\"}"})
+	ledger.CommitTxBatch(3, []*protos.Transaction{tx}, nil, []byte("dummy-proof"))
+
+	initGlobalServerOpenchain(t)
+
+	// Start the HTTP REST test server
+	httpServer := httptest.NewServer(buildOpenchainRESTRouter())
+	defer httpServer.Close()
+
+	body := performHTTPGet(t, httpServer.URL+"/chain/activation/MyContract")
+	var activation chaincodeActivation
+	if err := json.Unmarshal(body, &activation); err != nil {
+		t.Fatalf("Invalid JSON response: %v", err)
+	}
+	if activation.Active || activation.EffectiveDate != "2100-01-01T00:00:00Z" {
+		t.Errorf("Expected a pending activation on 2100-01-01T00:00:00Z but got %+v", activation)
+	}
+
+	body = performHTTPGet(t, httpServer.URL+"/chain/activation/MyOtherContract")
+	activation = chaincodeActivation{}
+	if err := json.Unmarshal(body, &activation); err != nil {
+		t.Fatalf("Invalid JSON response: %v", err)
+	}
+	if !activation.Active || activation.EffectiveDate != "" {
+		t.Errorf("Expected a chaincode without effective date to be active but got %+v", activation)
+	}
+}
+
+func TestServerOpenchainREST_API_GetBlocksRange(t *testing.T) {
+	// Construct a ledger with 3 blocks.
+	ledger := ledger.InitTestLedger(t)
+	buildTestLedger1(ledger, t)
+
+	initGlobalServerOpenchain(t)
+
+	// Start the HTTP REST test server
+	httpServer := httptest.NewServer(buildOpenchainRESTRouter())
+	defer httpServer.Close()
+
+	body := performHTTPGet(t, httpServer.URL+"/chain/blocks?start=1&end=10")
+	var blocks []*protos.Block
+	if err := json.Unmarshal(body, &blocks); err != nil {
+		t.Fatalf("Invalid JSON response: %v", err)
+	}
+	if len(blocks) != 2 || len(blocks[0].Transactions) != 1 || len(blocks[1].Transactions) != 2 {
+		t.Fatalf("Expected blocks 1 and 2 but got %v", blocks)
+	}
+
+	res := parseRESTResult(t, performHTTPGet(t, httpServer.URL+"/chain/blocks?start=3&end=10"))
+	if res.Error == "" {
+		t.Errorf("Expected an error when retrieving blocks after the last block, but got none")
+	}
+	res = parseRESTResult(t, performHTTPGet(t, httpServer.URL+"/chain/blocks?start=0&end=1000"))
+	if res.Error == "" {
+		t.Errorf("Expected an error when retrieving too many blocks at once, but got none")
+	}
+	res = parseRESTResult(t, performHTTPGet(t, httpServer.URL+"/chain/blocks?start=NOT_A_NUMBER&end=1"))
+	if res.Error == "" {
+		t.Errorf("Expected an error when the range start is not a number, but got none")
+	}
+}
+
+func TestServerOpenchainREST_API_GetState(t *testing.T) {
+	// Construct a ledger with 3 blocks.
+	ledger := ledger.InitTestLedger(t)
+	buildTestLedger1(ledger, t)
+
+	initGlobalServerOpenchain(t)
+
+	// Start the HTTP REST test server
+	httpServer := httptest.NewServer(buildOpenchainRESTRouter())
+	defer httpServer.Close()
+
+	// The state is not served unless state queries are enabled
+	for _, url := range []string{"/chain/state/MyContract/x", "/chain/state/MyContract1"} {
+		response, err := http.Get(httpServer.URL + url)
+		if err != nil {
+			t.Fatalf("Error attempt to GET %s: %v", url, err)
+		}
+		response.Body.Close()
+		if response.StatusCode != http.StatusForbidden {
+			t.Errorf("Expected status %d for %s with state queries disabled but got %d", http.StatusForbidden, url, response.StatusCode)
+		}
+	}
+	viper.Set("rest.stateQueries.enabled", true)
+	defer viper.Set("rest.stateQueries.enabled", false)
+
+	body := performHTTPGet(t, httpServer.URL+"/chain/state/MyContract/x")
+	var value protos.StateValue
+	if err := json.Unmarshal(body, &value); err != nil {
+		t.Fatalf("Invalid JSON response: %v", err)
+	}
+	if string(value.Value) != "hello" {
+		t.Errorf("Expected value 'hello' but got '%s'", value.Value)
+	}
+
+	res := parseRESTResult(t, performHTTPGet(t, httpServer.URL+"/chain/state/MyContract/non-existing"))
+	if res.Error == "" {
+		t.Errorf("Expected an error when retrieving a non-existing key, but got none")
+	}
+
+	body = performHTTPGet(t, httpServer.URL+"/chain/state/MyContract1?start=a&end=z")
+	var response protos.StateRangeResponse
+	if err := json.Unmarshal(body, &response); err != nil {
+		t.Fatalf("Invalid JSON response: %v", err)
+	}
+	if len(response.KeysAndValues) != 1 || response.KeysAndValues[0].Key != "code" || response.HasMore {
+		t.Errorf("Expected the key 'code' but got %v", response)
+	}
+
+	res = parseRESTResult(t, performHTTPGet(t, httpServer.URL+"/chain/state/MyContract1?limit=-1"))
+	if res.Error == "" {
+		t.Errorf("Expected an error when the limit is not a number, but got none")
+	}
+}
+
+func TestServerOpenchainREST_API_GetTransactionResult(t *testing.T) {
+	// Construct a ledger with 3 blocks, and a fourth block with results
+	ledger := ledger.InitTestLedger(t)
+	buildTestLedger1(ledger, t)
+	tx, _ := protos.NewTransaction(protos.ChaincodeID{Path: "MyContract"}, generateUUID(t), "setX", []string{"{x: \"hello\"}"})
+	ledger.BeginTxBatch(3)
+	results := []*protos.TransactionResult{{Uuid: tx.Uuid, ErrorCode: 1, Error: "failed"}}
+	ledger.CommitTxBatch(3, []*protos.Transaction{tx}, results, []byte("dummy-proof"))
+
+	initGlobalServerOpenchain(t)
+
+	// Start the HTTP REST test server
+	httpServer := httptest.NewServer(buildOpenchainRESTRouter())
+	defer httpServer.Close()
+
+	body := performHTTPGet(t, httpServer.URL+"/transactions/"+tx.Uuid+"/result")
+	var result protos.TransactionResult
+	if err := json.Unmarshal(body, &result); err != nil {
+		t.Fatalf("Invalid JSON response: %v", err)
+	}
+	if result.Uuid != tx.Uuid || result.ErrorCode != 1 || result.Error != "failed" {
+		t.Errorf("Unexpected transaction result %v", result)
+	}
+
+	res := parseRESTResult(t, performHTTPGet(t, httpServer.URL+"/transactions/NON-EXISTING-UUID/result"))
+	if res.Error == "" {
+		t.Errorf("Expected an error when retrieving the result of a non-existing transaction, but got none")
+	}
+}
+
+func TestServerOpenchainREST_API_GetChaincodeInfo(t *testing.T) {
+	// Construct a ledger with 3 blocks.
+	ledger := ledger.InitTestLedger(t)
+	buildTestLedger1(ledger, t)
+
+	initGlobalServerOpenchain(t)
+
+	// Start the HTTP REST test server
+	httpServer := httptest.NewServer(buildOpenchainRESTRouter())
+	defer httpServer.Close()
+
+	res := parseRESTResult(t, performHTTPGet(t, httpServer.URL+"/chain/chaincodes/NON-EXISTING-CHAINCODE"))
+	if res.Error == "" {
+		t.Errorf("Expected an error when retrieving a chaincode not deployed, but got none")
+	}
+}
+
 func TestServerOpenchainREST_API_GetEnrollmentID(t *testing.T) {
 	initGlobalServerOpenchain(t)
 
@@ -328,6 +587,45 @@ func TestServerOpenchainREST_API_GetPeers(t *testing.T) {
 	}
 }
 
+func TestServerOpenchainREST_API_Reconfiguration(t *testing.T) {
+	initGlobalServerOpenchain(t)
+
+	// Start the HTTP REST test server
+	httpServer := httptest.NewServer(buildOpenchainRESTRouter())
+	defer httpServer.Close()
+
+	_, body := performHTTPPost(t, httpServer.URL+"/network/reconfiguration/approvals", []byte(`{"validators":["vp0","vp1"]}`))
+	res := parseRESTResult(t, body)
+	if res.Error != "Not an administrator" {
+		t.Errorf("Expected an error when approving without being an administrator, but got %#v", res.Error)
+	}
+
+	_, body = performHTTPPost(t, httpServer.URL+"/network/reconfiguration/approvals", []byte(`{"validators":["vp0","vp1"],"secureContext":"admin"}`))
+	var approved reconfigurationRequest
+	if err := json.Unmarshal(body, &approved); err != nil {
+		t.Fatalf("Invalid JSON response: %v", err)
+	}
+	if string(approved.Payload) != "vp0,vp1" {
+		t.Errorf("Expected the approved payload 'vp0,vp1' but got '%s'", approved.Payload)
+	}
+
+	_, body = performHTTPPost(t, httpServer.URL+"/network/reconfiguration", []byte(`{"payload":"bm90LWFwcHJvdmVk"}`))
+	res = parseRESTResult(t, body)
+	if res.Error != "Not approved" {
+		t.Errorf("Expected an error when submitting an unapproved reconfiguration, but got %#v", res.Error)
+	}
+
+	request, _ := json.Marshal(approved)
+	_, body = performHTTPPost(t, httpServer.URL+"/network/reconfiguration", request)
+	res = parseRESTResult(t, body)
+	if res.Error != "" {
+		t.Errorf("Expected no error but got: %v", res.Error)
+	}
+	if res.OK != "reconfiguration_uuid" {
+		t.Errorf("Expected the transaction UUID 'reconfiguration_uuid' but got '%v'", res.OK)
+	}
+}
+
 func TestServerOpenchainREST_API_Chaincode_InvalidRequests(t *testing.T) {
 	// Construct a ledger with 3 blocks.
 	ledger := ledger.InitTestLedger(t)
@@ -473,6 +771,135 @@ func TestServerOpenchainREST_API_Chaincode_Deploy(t *testing.T) {
 	}
 }
 
+func TestServerOpenchainREST_API_Chaincode_Terminate(t *testing.T) {
+	// Construct a ledger with 3 blocks.
+	ledger := ledger.InitTestLedger(t)
+	buildTestLedger1(ledger, t)
+
+	initGlobalServerOpenchain(t)
+
+	// Start the HTTP REST test server
+	httpServer := httptest.NewServer(buildOpenchainRESTRouter())
+	defer httpServer.Close()
+
+	// Test terminate without params
+	httpResponse, body := performHTTPPost(t, httpServer.URL+"/chaincode", []byte(`{"jsonrpc":"2.0","ID":123,"method":"terminate"}`))
+	if httpResponse.StatusCode != http.StatusBadRequest {
+		t.Errorf("Expected an HTTP status code %#v but got %#v", http.StatusBadRequest, httpResponse.StatusCode)
+	}
+	res := parseRPCResponse(t, body)
+	if res.Error == nil || res.Error.Code != InvalidParams.Code {
+		t.Errorf("Expected an error when sending missing params, but got %#v", res.Error)
+	}
+
+	// Test terminate without chaincode name
+	httpResponse, body = performHTTPPost(t, httpServer.URL+"/chaincode", []byte(`{"jsonrpc":"2.0","ID":123,"method":"terminate","params":{"type":1,"chaincodeID":{}}}`))
+	if httpResponse.StatusCode != http.StatusOK {
+		t.Errorf("Expected an HTTP status code %#v but got %#v", http.StatusOK, httpResponse.StatusCode)
+	}
+	res = parseRPCResponse(t, body)
+	if res.Error == nil || res.Error.Code != InvalidParams.Code {
+		t.Errorf("Expected an error when sending missing chaincode name, but got %#v", res.Error)
+	}
+
+	// Test terminate with non-existing chaincode name
+	httpResponse, body = performHTTPPost(t, httpServer.URL+"/chaincode", []byte(`{"jsonrpc":"2.0","ID":123,"method":"terminate","params":{"type":1,"chaincodeID":{"name":"non-existing"},"purgeState":true}}`))
+	if httpResponse.StatusCode != http.StatusOK {
+		t.Errorf("Expected an HTTP status code %#v but got %#v", http.StatusOK, httpResponse.StatusCode)
+	}
+	res = parseRPCResponse(t, body)
+	if res.Error == nil || res.Error.Code != ChaincodeTerminateError.Code {
+		t.Errorf("Expected an error when sending non-existing chaincode name, but got %#v", res.Error)
+	}
+
+	// Test terminate with purge of the state
+	httpResponse, body = performHTTPPost(t, httpServer.URL+"/chaincode", []byte(`{"jsonrpc":"2.0","ID":123,"method":"terminate","params":{"type":1,"chaincodeID":{"name":"dummy"},"purgeState":true}}`))
+	if httpResponse.StatusCode != http.StatusOK {
+		t.Errorf("Expected an HTTP status code %#v but got %#v", http.StatusOK, httpResponse.StatusCode)
+	}
+	res = parseRPCResponse(t, body)
+	if res.Error != nil {
+		t.Errorf("Expected success but got %#v", res.Error)
+	}
+	if res.Result.Status != "OK" {
+		t.Errorf("Expected OK but got %#v", res.Result.Status)
+	}
+	if res.Result.Message != "terminate_result" {
+		t.Errorf("Expected 'terminate_result' but got '%v'", res.Result.Message)
+	}
+}
+
+func TestServerOpenchainREST_API_Chaincode_Upgrade(t *testing.T) {
+	// Construct a ledger with 3 blocks.
+	ledger := ledger.InitTestLedger(t)
+	buildTestLedger1(ledger, t)
+
+	initGlobalServerOpenchain(t)
+
+	// Start the HTTP REST test server
+	httpServer := httptest.NewServer(buildOpenchainRESTRouter())
+	defer httpServer.Close()
+
+	// Test upgrade without params
+	httpResponse, body := performHTTPPost(t, httpServer.URL+"/chaincode", []byte(`{"jsonrpc":"2.0","ID":123,"method":"upgrade"}`))
+	if httpResponse.StatusCode != http.StatusBadRequest {
+		t.Errorf("Expected an HTTP status code %#v but got %#v", http.StatusBadRequest, httpResponse.StatusCode)
+	}
+	res := parseRPCResponse(t, body)
+	if res.Error == nil || res.Error.Code != InvalidParams.Code {
+		t.Errorf("Expected an error when sending missing params, but got %#v", res.Error)
+	}
+
+	// Test upgrade without chaincode name
+	httpResponse, body = performHTTPPost(t, httpServer.URL+"/chaincode", []byte(`{"jsonrpc":"2.0","ID":123,"method":"upgrade","params":{"type":1,"chaincodeID":{"path":"github.com/hyperledger/fabric/examples/chaincode/go/chaincode_example02"},"ctorMsg":{"function":"init","args":["a","100"]}}}`))
+	if httpResponse.StatusCode != http.StatusOK {
+		t.Errorf("Expected an HTTP status code %#v but got %#v", http.StatusOK, httpResponse.StatusCode)
+	}
+	res = parseRPCResponse(t, body)
+	if res.Error == nil || res.Error.Code != InvalidParams.Code {
+		t.Errorf("Expected an error when sending missing chaincode name, but got %#v", res.Error)
+	}
+
+	// Test upgrade without function
+	httpResponse, body = performHTTPPost(t, httpServer.URL+"/chaincode", []byte(`{"jsonrpc":"2.0","ID":123,"method":"upgrade","params":{"type":1,"chaincodeID":{"name":"dummy","path":"github.com/hyperledger/fabric/examples/chaincode/go/chaincode_example02"},"ctorMsg":{"args":["a","100"]}}}`))
+	if httpResponse.StatusCode != http.StatusOK {
+		t.Errorf("Expected an HTTP status code %#v but got %#v", http.StatusOK, httpResponse.StatusCode)
+	}
+	res = parseRPCResponse(t, body)
+	if res.Error == nil || res.Error.Code != InvalidParams.Code {
+		t.Errorf("Expected an error when sending missing function, but got %#v", res.Error)
+	}
+
+	// Test upgrade of non-existing chaincode
+	httpResponse, body = performHTTPPost(t, httpServer.URL+"/chaincode", []byte(`{"jsonrpc":"2.0","ID":123,"method":"upgrade","params":{"type":1,"chaincodeID":{"name":"non-existing","path":"github.com/hyperledger/fabric/examples/chaincode/go/chaincode_example02"},"ctorMsg":{"function":"upgrade","args":[]}}}`))
+	if httpResponse.StatusCode != http.StatusOK {
+		t.Errorf("Expected an HTTP status code %#v but got %#v", http.StatusOK, httpResponse.StatusCode)
+	}
+	res = parseRPCResponse(t, body)
+	if res.Error == nil || res.Error.Code != ChaincodeUpgradeError.Code {
+		t.Errorf("Expected an error when upgrading non-existing chaincode, but got %#v", res.Error)
+	}
+
+	// Test successful upgrade
+	httpResponse, body = performHTTPPost(t, httpServer.URL+"/chaincode", []byte(`{"jsonrpc":"2.0","ID":123,"method":"upgrade","params":{"type":1,"chaincodeID":{"name":"dummy","path":"github.com/hyperledger/fabric/examples/chaincode/go/chaincode_example02"},"ctorMsg":{"function":"upgrade","args":[]}}}`))
+	if httpResponse.StatusCode != http.StatusOK {
+		t.Errorf("Expected an HTTP status code %#v but got %#v", http.StatusOK, httpResponse.StatusCode)
+	}
+	res = parseRPCResponse(t, body)
+	if res.Error != nil {
+		t.Errorf("Expected success but got %#v", res.Error)
+	}
+	if res.Result.Status != "OK" {
+		t.Errorf("Expected OK but got %#v", res.Result.Status)
+	}
+	if res.Result.Message != "dummy" {
+		t.Errorf("Expected 'dummy' but got '%v'", res.Result.Message)
+	}
+	if res.Result.Version != 2 {
+		t.Errorf("Expected version 2 but got %v", res.Result.Version)
+	}
+}
+
 func TestServerOpenchainREST_API_Chaincode_Invoke(t *testing.T) {
 	// Construct a ledger with 3 blocks.
 	ledger := ledger.InitTestLedger(t)
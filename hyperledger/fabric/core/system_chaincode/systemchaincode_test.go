@@ -139,7 +139,7 @@ func TestExecuteDeploySysChaincode(t *testing.T) {
 
 	cds := &pb.ChaincodeDeploymentSpec{ExecEnv: 1, ChaincodeSpec: &pb.ChaincodeSpec{Type: 1, ChaincodeID: &pb.ChaincodeID{Name: "sample_syscc", Path: url}, CtorMsg: &pb.ChaincodeInput{Args: args}}}
 
-	chaincode.GetChain(chaincode.DefaultChain).Stop(ctxt, cds)
+	chaincode.GetChain(chaincode.DefaultChain).Stop(ctxt, "", cds)
 
 	closeListenerAndSleep(lis)
 }
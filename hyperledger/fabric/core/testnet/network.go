@@ -0,0 +1,408 @@
+/*
+Copyright IBM Corp. 2016 All Rights Reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+		 http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package testnet
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/hyperledger/fabric/core/peer"
+	"github.com/hyperledger/fabric/core/util"
+	pb "github.com/hyperledger/fabric/protos"
+)
+
+// Options configure a network
+type Options struct {
+	// Validators is the number of validating peers, named vp0, vp1...
+	Validators int
+	// Consensus is the consensus plugin of the validators, noops by default
+	Consensus string
+	// Env are settings of all the nodes, as CORE_ environment variables
+	// overriding core.yaml and the settings of the network
+	Env []string
+	// StartTimeout bounds the time for the nodes to start and connect to each
+	// other, 30 seconds by default
+	StartTimeout time.Duration
+	// CommitTimeout bounds the time for a transaction to be committed, 30
+	// seconds by default
+	CommitTimeout time.Duration
+	// KeepFiles keeps the directory of the network once stopped, with the
+	// databases of the nodes
+	KeepFiles bool
+}
+
+// Network is a network of validating peers for integration tests, all
+// running in the process of the test. Each peer has its own database,
+// ledger, chaincode support and consensus engine, and listens on a loopback
+// port. The chaincodes run within the peers, which have them registered by
+// RegisterChaincode, and the messages between the peers go through filters
+// of the network dropping them on demand. The settings of the peers are
+// process wide, so a process runs one network at a time.
+type Network struct {
+	Nodes []*Node
+
+	dir        string
+	options    Options
+	env        map[string]*string
+	chaincodes []*pb.ChaincodeDeploymentSpec
+	lock       sync.Mutex
+}
+
+// Start starts a network of validating peers and waits for them to be
+// connected to each other
+func Start(options Options) (*Network, error) {
+	if options.Validators < 1 {
+		return nil, fmt.Errorf("A network needs at least one validator")
+	}
+	if options.Consensus == "" {
+		options.Consensus = "noops"
+	}
+	if options.StartTimeout == 0 {
+		options.StartTimeout = 30 * time.Second
+	}
+	if options.CommitTimeout == 0 {
+		options.CommitTimeout = 30 * time.Second
+	}
+	if err := loadConfig(); err != nil {
+		return nil, err
+	}
+	dir, err := ioutil.TempDir("", "testnet")
+	if err != nil {
+		return nil, err
+	}
+	n := &Network{dir: dir, options: options, env: make(map[string]*string)}
+	if err = n.setEnv(); err != nil {
+		n.abort()
+		return nil, err
+	}
+	var rootNodes []string
+	for i := 0; i < options.Validators; i++ {
+		node := &Node{
+			Name:   fmt.Sprintf("vp%d", i),
+			drops:  make(map[string]float64),
+			random: rand.New(rand.NewSource(time.Now().UnixNano() + int64(i))),
+		}
+		node.Dir = filepath.Join(dir, node.Name)
+		n.Nodes = append(n.Nodes, node)
+		if err = os.MkdirAll(node.Dir, 0755); err != nil {
+			n.abort()
+			return nil, err
+		}
+		if err = node.start(rootNodes); err != nil {
+			n.abort()
+			return nil, fmt.Errorf("Error starting node %s: %s", node.Name, err)
+		}
+		rootNodes = append(rootNodes, node.Address)
+	}
+	if err = n.waitConnected(); err != nil {
+		n.abort()
+		return nil, err
+	}
+	return n, nil
+}
+
+// abort stops a network which failed to start, keeping the databases of its
+// nodes
+func (n *Network) abort() {
+	n.options.KeepFiles = true
+	n.Stop()
+}
+
+// setEnv sets the settings of the network in the environment, read by the
+// peers through viper, remembering the previous values for Stop to restore
+func (n *Network) setEnv() error {
+	size := n.options.Validators
+	env := append([]string{
+		"CORE_PEER_VALIDATOR_ENABLED=true",
+		"CORE_PEER_VALIDATOR_CONSENSUS_PLUGIN=" + n.options.Consensus,
+		"CORE_PEER_TLS_ENABLED=false",
+		"CORE_SECURITY_ENABLED=false",
+		"CORE_VM_TYPE=inproc",
+		"CORE_LOGGING_PEER=warning",
+		fmt.Sprintf("CORE_PBFT_GENERAL_N=%d", size),
+		fmt.Sprintf("CORE_PBFT_GENERAL_F=%d", (size-1)/3),
+		fmt.Sprintf("CORE_RAFT_GENERAL_N=%d", size),
+	}, n.options.Env...)
+	for _, setting := range env {
+		kv := strings.SplitN(setting, "=", 2)
+		if len(kv) != 2 {
+			return fmt.Errorf("Invalid setting %s, expected KEY=VALUE", setting)
+		}
+		if _, ok := n.env[kv[0]]; !ok {
+			if value, ok := os.LookupEnv(kv[0]); ok {
+				n.env[kv[0]] = &value
+			} else {
+				n.env[kv[0]] = nil
+			}
+		}
+		os.Setenv(kv[0], kv[1])
+	}
+	return peer.CacheConfiguration()
+}
+
+// restoreEnv restores the environment changed by setEnv
+func (n *Network) restoreEnv() {
+	for key, value := range n.env {
+		if value == nil {
+			os.Unsetenv(key)
+		} else {
+			os.Setenv(key, *value)
+		}
+	}
+	n.env = make(map[string]*string)
+}
+
+// waitConnected waits for every node to be connected to all the others
+func (n *Network) waitConnected() error {
+	deadline := time.Now().Add(n.options.StartTimeout)
+	for _, node := range n.Nodes {
+		for {
+			status, err := node.GetStatus()
+			if err != nil {
+				return err
+			}
+			if len(status.Peers) >= len(n.Nodes)-1 {
+				break
+			}
+			if time.Now().After(deadline) {
+				return fmt.Errorf("Timeout waiting for node %s to connect, connected to %v", node.Name, status.Peers)
+			}
+			time.Sleep(100 * time.Millisecond)
+		}
+	}
+	return nil
+}
+
+// Stop stops the nodes and, unless the files are kept, removes the
+// directory of the network
+func (n *Network) Stop() {
+	n.lock.Lock()
+	chaincodes := n.chaincodes
+	n.lock.Unlock()
+	for _, node := range n.Nodes {
+		node.stop(chaincodes)
+	}
+	n.restoreEnv()
+	if !n.options.KeepFiles {
+		os.RemoveAll(n.dir)
+	}
+}
+
+// execute submits a transaction to a node
+func (n *Network) execute(node int, tx *pb.Transaction) ([]byte, error) {
+	response := n.Nodes[node].peer.ExecuteTransaction(tx)
+	if response.Status != pb.Response_SUCCESS {
+		return nil, fmt.Errorf("Error executing transaction on node %s: %s", n.Nodes[node].Name, response.Msg)
+	}
+	return response.Msg, nil
+}
+
+// waitCommitted waits for a transaction submitted to a node to be committed
+// by the node, returning its result
+func (n *Network) waitCommitted(node int, uuid string) (*pb.TransactionResult, error) {
+	deadline := time.Now().Add(n.options.CommitTimeout)
+	for {
+		result, err := n.Nodes[node].ledger.GetTransactionResultByUUID(uuid)
+		if err == nil && result != nil {
+			return result, nil
+		}
+		if time.Now().After(deadline) {
+			return nil, fmt.Errorf("Timeout waiting for transaction %s to be committed by node %s", uuid, n.Nodes[node].Name)
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+}
+
+// Deploy deploys through a node the chaincode registered with the path under
+// the name, and waits for the deployment to be committed
+func (n *Network) Deploy(node int, name, path, function string, args ...string) error {
+	// the chaincode runs within the peers, there is no package to build
+	spec := &pb.ChaincodeSpec{
+		Type:        pb.ChaincodeSpec_GOLANG,
+		ChaincodeID: &pb.ChaincodeID{Path: path, Name: name},
+		CtorMsg:     &pb.ChaincodeInput{Function: function, Args: args},
+	}
+	cds := &pb.ChaincodeDeploymentSpec{ChaincodeSpec: spec}
+	tx, err := pb.NewChaincodeDeployTransaction(cds, name)
+	if err != nil {
+		return err
+	}
+	n.lock.Lock()
+	n.chaincodes = append(n.chaincodes, cds)
+	n.lock.Unlock()
+	if _, err = n.execute(node, tx); err != nil {
+		return err
+	}
+	result, err := n.waitCommitted(node, tx.Uuid)
+	if err != nil {
+		return err
+	}
+	if result.ErrorCode != 0 {
+		return fmt.Errorf("Error deploying %s: %s", name, result.Error)
+	}
+	return nil
+}
+
+// Invoke invokes a chaincode through a node and waits for the invocation to
+// be committed. An error is returned if the invocation failed.
+func (n *Network) Invoke(node int, name, function string, args ...string) (*pb.TransactionResult, error) {
+	spec := &pb.ChaincodeInvocationSpec{ChaincodeSpec: &pb.ChaincodeSpec{
+		Type:        pb.ChaincodeSpec_GOLANG,
+		ChaincodeID: &pb.ChaincodeID{Name: name},
+		CtorMsg:     &pb.ChaincodeInput{Function: function, Args: args},
+	}}
+	tx, err := pb.NewChaincodeExecute(spec, util.GenerateUUID(), pb.Transaction_CHAINCODE_INVOKE)
+	if err != nil {
+		return nil, err
+	}
+	if _, err = n.execute(node, tx); err != nil {
+		return nil, err
+	}
+	result, err := n.waitCommitted(node, tx.Uuid)
+	if err != nil {
+		return nil, err
+	}
+	if result.ErrorCode != 0 {
+		return result, fmt.Errorf("Error invoking %s: %s", name, result.Error)
+	}
+	return result, nil
+}
+
+// Query queries a chaincode on a node
+func (n *Network) Query(node int, name, function string, args ...string) ([]byte, error) {
+	spec := &pb.ChaincodeInvocationSpec{ChaincodeSpec: &pb.ChaincodeSpec{
+		Type:        pb.ChaincodeSpec_GOLANG,
+		ChaincodeID: &pb.ChaincodeID{Name: name},
+		CtorMsg:     &pb.ChaincodeInput{Function: function, Args: args},
+	}}
+	tx, err := pb.NewChaincodeExecute(spec, util.GenerateUUID(), pb.Transaction_CHAINCODE_QUERY)
+	if err != nil {
+		return nil, err
+	}
+	return n.execute(node, tx)
+}
+
+// setDrop sets the rate of the messages dropped from a node to another,
+// without applying it
+func (n *Network) setDrop(from, to int, rate float64) {
+	n.Nodes[from].setDrop(n.Nodes[to].Name, rate)
+}
+
+// applyDrops sets the filters of the nodes
+func (n *Network) applyDrops() error {
+	for _, node := range n.Nodes {
+		node.applyDrops()
+	}
+	return nil
+}
+
+// DropMessages drops a rate of the messages sent from a node to another, 1
+// dropping all of them and 0 none
+func (n *Network) DropMessages(from, to int, rate float64) error {
+	n.lock.Lock()
+	defer n.lock.Unlock()
+	n.setDrop(from, to, rate)
+	return n.applyDrops()
+}
+
+// Partition drops all messages between the groups of nodes. The nodes in no
+// group are partitioned from all the others.
+func (n *Network) Partition(groups ...[]int) error {
+	n.lock.Lock()
+	defer n.lock.Unlock()
+	group := make(map[int]int)
+	for g, nodes := range groups {
+		for _, node := range nodes {
+			group[node] = g + 1
+		}
+	}
+	for from := range n.Nodes {
+		for to := range n.Nodes {
+			if from == to {
+				continue
+			}
+			if g, ok := group[from]; ok && g == group[to] {
+				n.setDrop(from, to, 0)
+			} else {
+				n.setDrop(from, to, 1)
+			}
+		}
+	}
+	return n.applyDrops()
+}
+
+// Heal stops dropping messages between the nodes
+func (n *Network) Heal() error {
+	n.lock.Lock()
+	defer n.lock.Unlock()
+	for _, node := range n.Nodes {
+		node.lock.Lock()
+		node.drops = make(map[string]float64)
+		node.lock.Unlock()
+	}
+	return n.applyDrops()
+}
+
+// checkConverged returns an error if the ledgers of the nodes differ or a
+// blockchain is not valid
+func (n *Network) checkConverged() error {
+	var first *Status
+	for _, node := range n.Nodes {
+		status, err := node.GetStatus()
+		if err != nil {
+			return err
+		}
+		if status.VerifiedBlock != 0 {
+			return fmt.Errorf("The blockchain of node %s is not valid from block %d", node.Name, status.VerifiedBlock)
+		}
+		if first == nil {
+			first = status
+			continue
+		}
+		if status.Height != first.Height {
+			return fmt.Errorf("Node %s has %d blocks but node %s has %d", node.Name, status.Height, n.Nodes[0].Name, first.Height)
+		}
+		if !bytes.Equal(status.BlockHash, first.BlockHash) {
+			return fmt.Errorf("The last block of node %s differs from the one of node %s", node.Name, n.Nodes[0].Name)
+		}
+		if !bytes.Equal(status.StateHash, first.StateHash) {
+			return fmt.Errorf("The state of node %s differs from the one of node %s", node.Name, n.Nodes[0].Name)
+		}
+	}
+	return nil
+}
+
+// WaitConverged waits for the nodes to have the same valid blockchain and the
+// same state, returning the last difference if they do not within the
+// timeout
+func (n *Network) WaitConverged(timeout time.Duration) error {
+	deadline := time.Now().Add(timeout)
+	for {
+		err := n.checkConverged()
+		if err == nil || time.Now().After(deadline) {
+			return err
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+}
@@ -0,0 +1,162 @@
+/*
+Copyright IBM Corp. 2016 All Rights Reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+		 http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package testnet
+
+import (
+	"fmt"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+)
+
+const counterPath = "github.com/hyperledger/fabric/core/testnet/counter"
+
+// counter counts its invocations
+type counter struct{}
+
+func (c *counter) Init(stub shim.ChaincodeStubInterface, function string, args []string) ([]byte, error) {
+	return nil, stub.PutState("count", []byte("0"))
+}
+
+func (c *counter) Invoke(stub shim.ChaincodeStubInterface, function string, args []string) ([]byte, error) {
+	value, err := stub.GetState("count")
+	if err != nil {
+		return nil, err
+	}
+	count, err := strconv.Atoi(string(value))
+	if err != nil {
+		return nil, err
+	}
+	return nil, stub.PutState("count", []byte(strconv.Itoa(count+1)))
+}
+
+func (c *counter) Query(stub shim.ChaincodeStubInterface, function string, args []string) ([]byte, error) {
+	return stub.GetState("count")
+}
+
+func init() {
+	RegisterChaincode(counterPath, &counter{})
+}
+
+func startNetwork(t *testing.T, options Options) *Network {
+	n, err := Start(options)
+	if err != nil {
+		t.Fatalf("Error starting network: %s", err)
+	}
+	return n
+}
+
+func invoke(t *testing.T, n *Network, node int, times int) {
+	for i := 0; i < times; i++ {
+		if _, err := n.Invoke(node, "counter", "add"); err != nil {
+			t.Fatalf("Error invoking counter through node %d: %s", node, err)
+		}
+	}
+}
+
+// checkCount waits for the count of a node to be the expected one, as the
+// node may commit the invocations through other nodes later
+func checkCount(t *testing.T, n *Network, node int, expected int) {
+	var count []byte
+	var err error
+	for i := 0; i < 50; i++ {
+		if count, err = n.Query(node, "counter", "get"); err == nil && string(count) == fmt.Sprint(expected) {
+			return
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+	if err != nil {
+		t.Fatalf("Error querying counter on node %d: %s", node, err)
+	}
+	t.Fatalf("Expected a count of %d on node %d but got %s", expected, node, count)
+}
+
+func TestNetworkNoops(t *testing.T) {
+	n := startNetwork(t, Options{Validators: 2})
+	defer n.Stop()
+
+	if err := n.Deploy(0, "counter", counterPath, "init"); err != nil {
+		t.Fatalf("Error deploying counter: %s", err)
+	}
+	invoke(t, n, 0, 2)
+	if err := n.WaitConverged(10 * time.Second); err != nil {
+		t.Fatalf("Expected the ledgers to converge: %s", err)
+	}
+	checkCount(t, n, 1, 2)
+
+	// noops does not recover the transactions lost in a partition
+	if err := n.Partition([]int{0}, []int{1}); err != nil {
+		t.Fatalf("Error partitioning the network: %s", err)
+	}
+	invoke(t, n, 0, 1)
+	checkCount(t, n, 0, 3)
+	checkCount(t, n, 1, 2)
+	if err := n.WaitConverged(time.Second); err == nil {
+		t.Fatalf("Expected the ledgers to diverge in a partition")
+	}
+
+	if err := n.Heal(); err != nil {
+		t.Fatalf("Error healing the network: %s", err)
+	}
+	invoke(t, n, 1, 1)
+	checkCount(t, n, 0, 4)
+	checkCount(t, n, 1, 3)
+
+	// the messages are dropped in one direction only
+	if err := n.DropMessages(1, 0, 1); err != nil {
+		t.Fatalf("Error dropping messages: %s", err)
+	}
+	invoke(t, n, 1, 1)
+	invoke(t, n, 0, 1)
+	checkCount(t, n, 0, 5)
+	checkCount(t, n, 1, 5)
+}
+
+func TestNetworkPBFT(t *testing.T) {
+	n := startNetwork(t, Options{
+		Validators: 4,
+		Consensus:  "pbft",
+		// checkpoints every 2 requests, for the lagging node to catch up
+		Env: []string{"CORE_PBFT_GENERAL_MODE=batch", "CORE_PBFT_GENERAL_K=2", "CORE_PBFT_GENERAL_LOGMULTIPLIER=2", "CORE_PBFT_GENERAL_BATCHSIZE=1"},
+	})
+	defer n.Stop()
+
+	if err := n.Deploy(0, "counter", counterPath, "init"); err != nil {
+		t.Fatalf("Error deploying counter: %s", err)
+	}
+	invoke(t, n, 1, 2)
+
+	// the other nodes make progress without vp3
+	if err := n.Partition([]int{0, 1, 2}); err != nil {
+		t.Fatalf("Error partitioning the network: %s", err)
+	}
+	invoke(t, n, 0, 6)
+	checkCount(t, n, 0, 8)
+	checkCount(t, n, 3, 2)
+
+	// vp3 catches up by state transfer once the network is healed
+	if err := n.Heal(); err != nil {
+		t.Fatalf("Error healing the network: %s", err)
+	}
+	invoke(t, n, 2, 4)
+	if err := n.WaitConverged(20 * time.Second); err != nil {
+		t.Fatalf("Expected the ledgers to converge: %s", err)
+	}
+	checkCount(t, n, 3, 12)
+}
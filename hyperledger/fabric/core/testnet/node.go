@@ -0,0 +1,245 @@
+/*
+Copyright IBM Corp. 2016 All Rights Reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+		 http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package testnet
+
+import (
+	"fmt"
+	"math/rand"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/op/go-logging"
+	"github.com/spf13/viper"
+	"golang.org/x/net/context"
+	"google.golang.org/grpc"
+
+	"github.com/hyperledger/fabric/consensus/helper"
+	"github.com/hyperledger/fabric/core"
+	"github.com/hyperledger/fabric/core/chaincode"
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+	"github.com/hyperledger/fabric/core/container/inproccontroller"
+	"github.com/hyperledger/fabric/core/crypto"
+	"github.com/hyperledger/fabric/core/db"
+	"github.com/hyperledger/fabric/core/ledger"
+	"github.com/hyperledger/fabric/core/ledger/genesis"
+	"github.com/hyperledger/fabric/core/peer"
+	pb "github.com/hyperledger/fabric/protos"
+)
+
+var logger = logging.MustGetLogger("testnet")
+
+// RegisterChaincode registers a chaincode with the inproc container, to be
+// deployed on the network with its path
+func RegisterChaincode(path string, cc shim.Chaincode) error {
+	return inproccontroller.Register(path, cc)
+}
+
+var loadConfigOnce sync.Once
+var loadConfigErr error
+
+// loadConfig reads core.yaml, as the peer command does, the settings of the
+// network being set in the environment
+func loadConfig() error {
+	loadConfigOnce.Do(func() {
+		viper.SetEnvPrefix("CORE")
+		viper.AutomaticEnv()
+		replacer := strings.NewReplacer(".", "_")
+		viper.SetEnvKeyReplacer(replacer)
+		viper.SetConfigName("core")
+		for _, p := range filepath.SplitList(os.Getenv("GOPATH")) {
+			viper.AddConfigPath(filepath.Join(p, "src/github.com/hyperledger/fabric/peer"))
+		}
+		if loadConfigErr = viper.ReadInConfig(); loadConfigErr != nil {
+			loadConfigErr = fmt.Errorf("Error reading core config file: %s", loadConfigErr)
+		}
+	})
+	return loadConfigErr
+}
+
+// Node is a validating peer of the network
+type Node struct {
+	Name    string
+	Address string
+	// Dir is the file system path of the node, holding its database
+	Dir string
+
+	endpoint         *pb.PeerEndpoint
+	db               *db.OpenchainDB
+	ledger           *ledger.Ledger
+	chaincodeSupport *chaincode.ChaincodeSupport
+	engine           *helper.EngineImpl
+	peer             *peer.PeerImpl
+	server           *grpc.Server
+
+	lock   sync.Mutex
+	drops  map[string]float64
+	random *rand.Rand
+}
+
+// start starts the node the way the peer command starts a validator, but on
+// a database, a ledger, a chaincode support and an engine of its own, and
+// connects it to the root nodes
+func (node *Node) start(rootNodes []string) error {
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return err
+	}
+	node.Address = lis.Addr().String()
+	node.endpoint = &pb.PeerEndpoint{ID: &pb.PeerID{Name: node.Name}, Address: node.Address, Type: pb.PeerEndpoint_VALIDATOR}
+	getEndpoint := func() (*pb.PeerEndpoint, error) {
+		return node.endpoint, nil
+	}
+
+	if node.db, err = db.OpenDB(filepath.Join(node.Dir, "db")); err != nil {
+		lis.Close()
+		return err
+	}
+	if node.ledger, err = ledger.NewLedger(node.db); err != nil {
+		lis.Close()
+		return err
+	}
+	if err = genesis.MakeLedgerGenesis(node.ledger); err != nil {
+		lis.Close()
+		return err
+	}
+	node.chaincodeSupport = chaincode.NewLedgerChaincodeSupport(node.ledger, getEndpoint, false, time.Duration(viper.GetInt("chaincode.startuptimeout"))*time.Millisecond, nil)
+
+	newEngine := func(coord peer.MessageHandlerCoordinator) (peer.Engine, error) {
+		engine, err := helper.NewEngine(coord, node.chaincodeSupport, node.ledger)
+		if err != nil {
+			return nil, err
+		}
+		node.engine = engine.(*helper.EngineImpl)
+		return engine, nil
+	}
+	discInstance := core.NewStaticDiscovery(strings.Join(rootNodes, ","))
+	if node.peer, err = peer.NewPeerWithLedger(node.endpoint, node.ledger, func() crypto.Peer { return nil }, newEngine, discInstance); err != nil {
+		lis.Close()
+		return err
+	}
+	node.server = grpc.NewServer()
+	pb.RegisterPeerServer(node.server, node.peer)
+	go node.server.Serve(lis)
+	logger.Infof("Node %s started at %s", node.Name, node.Address)
+	return nil
+}
+
+// stop stops the node and the chaincodes it runs, then closes its database
+func (node *Node) stop(chaincodes []*pb.ChaincodeDeploymentSpec) {
+	if node.peer != nil {
+		node.peer.Stop()
+	}
+	if node.server != nil {
+		node.server.Stop()
+	}
+	if node.engine != nil {
+		node.engine.Close()
+	}
+	if node.chaincodeSupport != nil {
+		for _, cds := range chaincodes {
+			node.chaincodeSupport.Stop(context.Background(), "", cds)
+		}
+	}
+	if node.ledger != nil {
+		node.ledger.StopPruning()
+	}
+	if node.db != nil {
+		node.db.CloseDB()
+	}
+}
+
+// Status is the state of a node
+type Status struct {
+	// Peers are the names of the peers connected to the node
+	Peers []string
+	// Height is the size of the blockchain
+	Height uint64
+	// BlockHash is the hash of the last block
+	BlockHash []byte
+	// StateHash is the hash of the current state
+	StateHash []byte
+	// VerifiedBlock is the lowest block from which VerifyChain verified the
+	// blockchain up to its last block, 0 if the whole blockchain is valid
+	VerifiedBlock uint64
+}
+
+// GetStatus returns the status of the node
+func (node *Node) GetStatus() (*Status, error) {
+	status := &Status{}
+	peers, err := node.peer.GetPeers()
+	if err != nil {
+		return nil, fmt.Errorf("Error getting the peers of node %s: %s", node.Name, err)
+	}
+	for _, endpoint := range peers.Peers {
+		status.Peers = append(status.Peers, endpoint.ID.Name)
+	}
+	info, err := node.ledger.GetBlockchainInfo()
+	if err != nil {
+		return nil, fmt.Errorf("Error getting the blockchain of node %s: %s", node.Name, err)
+	}
+	status.Height = info.Height
+	status.BlockHash = info.CurrentBlockHash
+	if status.StateHash, err = node.ledger.GetTempStateHash(); err != nil {
+		return nil, fmt.Errorf("Error getting the state hash of node %s: %s", node.Name, err)
+	}
+	if info.Height > 0 {
+		if status.VerifiedBlock, err = node.ledger.VerifyChain(info.Height-1, 0); err != nil {
+			return nil, fmt.Errorf("Error verifying the blockchain of node %s: %s", node.Name, err)
+		}
+	}
+	return status, nil
+}
+
+// setDrop sets the rate of the messages dropped from the node to a peer,
+// without applying it
+func (node *Node) setDrop(to string, rate float64) {
+	node.lock.Lock()
+	defer node.lock.Unlock()
+	if rate <= 0 {
+		delete(node.drops, to)
+	} else {
+		node.drops[to] = rate
+	}
+}
+
+// applyDrops sets the message filter of the peer of the node dropping the
+// messages at the rates set
+func (node *Node) applyDrops() {
+	node.lock.Lock()
+	defer node.lock.Unlock()
+	if len(node.drops) == 0 {
+		node.peer.SetMessageFilter(nil)
+		return
+	}
+	drops := make(map[string]float64)
+	for to, rate := range node.drops {
+		drops[to] = rate
+	}
+	node.peer.SetMessageFilter(func(to *pb.PeerID, msg *pb.Message) bool {
+		rate, ok := drops[to.Name]
+		if !ok {
+			return true
+		}
+		node.lock.Lock()
+		defer node.lock.Unlock()
+		return node.random.Float64() >= rate
+	})
+}
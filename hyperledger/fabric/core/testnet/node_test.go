@@ -0,0 +1,64 @@
+/*
+Copyright IBM Corp. 2016 All Rights Reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+		 http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package testnet
+
+import (
+	"io"
+	"testing"
+
+	"github.com/hyperledger/fabric/core/peer"
+	pb "github.com/hyperledger/fabric/protos"
+)
+
+type recordingStream struct {
+	sent []*pb.Message
+}
+
+func (s *recordingStream) Send(msg *pb.Message) error {
+	s.sent = append(s.sent, msg)
+	return nil
+}
+
+func (s *recordingStream) Recv() (*pb.Message, error) {
+	return nil, io.EOF
+}
+
+// TestMessageFilter checks the filter of a peer drops the messages its
+// handlers send, which the network relies on
+func TestMessageFilter(t *testing.T) {
+	stream := &recordingStream{}
+	coord := &peer.PeerImpl{}
+	handler := &peer.Handler{ChatStream: stream, Coordinator: coord}
+	coord.SetMessageFilter(func(to *pb.PeerID, msg *pb.Message) bool {
+		return to.Name != "vp1" || msg.Type != pb.Message_CONSENSUS
+	})
+
+	// Messages are not filtered before the remote peer said hello
+	handler.SendMessage(&pb.Message{Type: pb.Message_CONSENSUS})
+	handler.ToPeerEndpoint = &pb.PeerEndpoint{ID: &pb.PeerID{Name: "vp1"}}
+	handler.SendMessage(&pb.Message{Type: pb.Message_CONSENSUS})
+	handler.SendMessage(&pb.Message{Type: pb.Message_DISC_GET_PEERS})
+	if len(stream.sent) != 2 || stream.sent[1].Type != pb.Message_DISC_GET_PEERS {
+		t.Fatalf("Expected the consensus message to vp1 to be dropped, sent %v", stream.sent)
+	}
+
+	coord.SetMessageFilter(nil)
+	handler.SendMessage(&pb.Message{Type: pb.Message_CONSENSUS})
+	if len(stream.sent) != 3 {
+		t.Fatalf("Expected all messages to be sent without a filter, sent %d", len(stream.sent))
+	}
+}
@@ -19,6 +19,12 @@ under the License.
 
 package discovery
 
+import (
+	"time"
+
+	pb "github.com/hyperledger/fabric/protos"
+)
+
 // Discovery is the interface that consolidates bootstrap peer membership selection
 // and validating peer selection for non validating peers
 type Discovery interface {
@@ -29,3 +35,37 @@ type Discovery interface {
 	// GetRootNode function for providing all bootstrap addresses for a peer
 	GetRootNodes() []string
 }
+
+// Member is a peer of the network known to a Membership
+type Member struct {
+	Endpoint  *pb.PeerEndpoint
+	Connected bool
+	// LastSeen is the last time the peer was connected to this peer or to
+	// one of its peers
+	LastSeen time.Time
+	// Failures is the number of failed attempts to connect to the peer since
+	// it was last seen
+	Failures int
+}
+
+// Membership is a Discovery which learns the members of the network from the
+// peer it serves
+type Membership interface {
+	Discovery
+
+	// PeersDiscovered records the peers connected to another peer
+	PeersDiscovered(peers []*pb.PeerEndpoint)
+
+	// Connected records that the peer is connected to endpoint
+	Connected(endpoint *pb.PeerEndpoint)
+
+	// Disconnected records that the peer is no longer connected to endpoint
+	Disconnected(endpoint *pb.PeerEndpoint)
+
+	// Unreachable records a failed attempt to connect to address and returns
+	// whether the peer should keep trying
+	Unreachable(address string) bool
+
+	// GetMembers returns the known members of the network
+	GetMembers() []*Member
+}
@@ -33,11 +33,48 @@ type EventsClient struct {
 	peerAddress string
 	stream      ehpb.Events_ChatClient
 	adapter     EventAdapter
+	consumerID  string
+	chainID     string
+	replay      bool
+	startBlock  uint64
 }
 
 //NewEventsClient Returns a new grpc.ClientConn to the configured local PEER.
 func NewEventsClient(peerAddress string, adapter EventAdapter) *EventsClient {
-	return &EventsClient{peerAddress, nil, adapter}
+	return &EventsClient{peerAddress: peerAddress, adapter: adapter}
+}
+
+//SetConsumerID identifies the consumer to the event hub, which keeps the
+//number of the last block acknowledged by the consumer with Ack. Must be
+//called before Start
+func (ec *EventsClient) SetConsumerID(consumerID string) {
+	ec.consumerID = consumerID
+}
+
+//SetChainID selects the chain whose events are received, the default chain
+//if not called. Must be called before Start
+func (ec *EventsClient) SetChainID(chainID string) {
+	ec.chainID = chainID
+}
+
+//ReplayFrom requests the events of the blocks of the ledger from startBlock
+//on to be replayed before the live events. When the consumer acknowledged
+//blocks under its consumer ID, the replay resumes after the last acknowledged
+//block if that is later. Must be called before Start
+func (ec *EventsClient) ReplayFrom(startBlock uint64) {
+	ec.replay = true
+	ec.startBlock = startBlock
+}
+
+//Ack acknowledges the events of the blocks up to blockNumber
+func (ec *EventsClient) Ack(blockNumber uint64) error {
+	if ec.consumerID == "" {
+		return fmt.Errorf("consumer ID must be set to acknowledge blocks")
+	}
+	if ec.stream == nil {
+		return fmt.Errorf("events client is not started")
+	}
+	return ec.stream.Send(&ehpb.Event{Event: &ehpb.Event_Ack{Ack: &ehpb.Ack{BlockNumber: blockNumber}}})
 }
 
 //newEventsClientConnectionWithAddress Returns a new grpc.ClientConn to the configured local PEER.
@@ -49,7 +86,7 @@ func newEventsClientConnectionWithAddress(peerAddress string) (*grpc.ClientConn,
 }
 
 func (ec *EventsClient) register(ies []*ehpb.Interest) error {
-	emsg := &ehpb.Event{Event: &ehpb.Event_Register{Register: &ehpb.Register{Events: ies}}}
+	emsg := &ehpb.Event{Event: &ehpb.Event_Register{Register: &ehpb.Register{Events: ies, Replay: ec.replay, StartBlock: ec.startBlock, ConsumerID: ec.consumerID, ChainID: ec.chainID}}}
 	var err error
 	if err = ec.stream.Send(emsg); err != nil {
 		fmt.Printf("error on Register send %s\n", err)
@@ -24,6 +24,7 @@ import (
 	"testing"
 	"time"
 
+	"github.com/hyperledger/fabric/core/db"
 	"github.com/hyperledger/fabric/events/consumer"
 	"github.com/hyperledger/fabric/events/producer"
 	ehpb "github.com/hyperledger/fabric/protos"
@@ -163,6 +164,272 @@ func TestFailReceive(t *testing.T) {
 	}
 }
 
+type eventsAdapter struct {
+	interests []*ehpb.Interest
+	events    chan *ehpb.Event
+}
+
+func (a *eventsAdapter) GetInterestedEvents() ([]*ehpb.Interest, error) {
+	return a.interests, nil
+}
+
+func (a *eventsAdapter) Recv(msg *ehpb.Event) (bool, error) {
+	a.events <- msg
+	return true, nil
+}
+
+func (a *eventsAdapter) Disconnected(err error) {
+}
+
+func startEventsClient(t *testing.T, a *eventsAdapter, consumerID string, replay bool, startBlock uint64) *consumer.EventsClient {
+	client := consumer.NewEventsClient(peerAddress, a)
+	client.SetConsumerID(consumerID)
+	if replay {
+		client.ReplayFrom(startBlock)
+	}
+	if err := client.Start(); err != nil {
+		t.Fatalf("Error starting events client: %s", err)
+	}
+	return client
+}
+
+func chaincodeInterest(chaincodeID string, eventName string, match ehpb.ChaincodeReg_MatchType) *ehpb.Interest {
+	return &ehpb.Interest{EventType: ehpb.EventType_CHAINCODE, RegInfo: &ehpb.Interest_ChaincodeRegInfo{ChaincodeRegInfo: &ehpb.ChaincodeReg{ChaincodeID: chaincodeID, EventName: eventName, Match: match}}}
+}
+
+func expectEvent(t *testing.T, a *eventsAdapter) *ehpb.Event {
+	select {
+	case e := <-a.events:
+		return e
+	case <-time.After(5 * time.Second):
+		t.Fatalf("timed out on message")
+	}
+	return nil
+}
+
+func expectNoEvent(t *testing.T, a *eventsAdapter) {
+	select {
+	case e := <-a.events:
+		t.Fatalf("unexpected message %v", e)
+	case <-time.After(time.Second):
+	}
+}
+
+func expectChaincodeEvent(t *testing.T, a *eventsAdapter, eventName string, blockNumber uint64) {
+	e := expectEvent(t, a)
+	if e.GetChaincodeEvent() == nil || e.GetChaincodeEvent().EventName != eventName || e.BlockNumber != blockNumber {
+		t.Fatalf("expected chaincode event %s of block %d, got %v", eventName, blockNumber, e)
+	}
+}
+
+func expectFilteredBlock(t *testing.T, a *eventsAdapter, blockNumber uint64) {
+	e := expectEvent(t, a)
+	if e.GetFilteredBlock() == nil || e.BlockNumber != blockNumber {
+		t.Fatalf("expected filtered block %d, got %v", blockNumber, e)
+	}
+	txs := e.GetFilteredBlock().Transactions
+	if len(txs) != 1 || txs[0].Uuid != fmt.Sprintf("tx%d", blockNumber) || txs[0].ErrorCode != uint32(blockNumber%2) {
+		t.Fatalf("unexpected transactions of filtered block %d: %v", blockNumber, txs)
+	}
+}
+
+type testBlockSource struct {
+	blocks []*ehpb.Block
+}
+
+func (s *testBlockSource) GetBlockchainSize() uint64 {
+	return uint64(len(s.blocks))
+}
+
+func (s *testBlockSource) GetBlockByNumber(blockNumber uint64) (*ehpb.Block, error) {
+	if blockNumber >= uint64(len(s.blocks)) {
+		return nil, fmt.Errorf("block %d not found", blockNumber)
+	}
+	return s.blocks[blockNumber], nil
+}
+
+// createReplayTestBlocks creates blocks with a transaction tx<n> which failed in
+// odd blocks and sent the chaincode event e<n> of chaincode replaycc
+func createReplayTestBlocks(n int) []*ehpb.Block {
+	var blocks []*ehpb.Block
+	for i := 0; i < n; i++ {
+		uuid := fmt.Sprintf("tx%d", i)
+		blocks = append(blocks, &ehpb.Block{
+			Transactions: []*ehpb.Transaction{&ehpb.Transaction{Uuid: uuid}},
+			NonHashData: &ehpb.NonHashData{TransactionResults: []*ehpb.TransactionResult{&ehpb.TransactionResult{
+				Uuid:           uuid,
+				ErrorCode:      uint32(i % 2),
+				ChaincodeEvent: &ehpb.ChaincodeEvent{ChaincodeID: "replaycc", TxID: uuid, EventName: fmt.Sprintf("e%d", i)},
+			}}},
+		})
+	}
+	return blocks
+}
+
+func TestReceiveMatchingEventNames(t *testing.T) {
+	a := &eventsAdapter{events: make(chan *ehpb.Event, 100), interests: []*ehpb.Interest{
+		chaincodeInterest("matchcc", "pre", ehpb.ChaincodeReg_PREFIX),
+		chaincodeInterest("matchcc", "^ev[0-9]+$", ehpb.ChaincodeReg_REGEX),
+	}}
+	client := startEventsClient(t, a, "", false, 0)
+	defer client.Stop()
+
+	for _, name := range []string{"prefix1", "other", "ev12", "evx", "xpre"} {
+		if err := producer.Send(createTestChaincodeEvent("matchcc", name)); err != nil {
+			t.Fatalf("Error sending message %s", err)
+		}
+	}
+	if err := producer.Send(createTestChaincodeEvent("othercc", "prefix1")); err != nil {
+		t.Fatalf("Error sending message %s", err)
+	}
+
+	expectChaincodeEvent(t, a, "prefix1", 0)
+	expectChaincodeEvent(t, a, "ev12", 0)
+	expectNoEvent(t, a)
+}
+
+func TestReceiveFilteredBlock(t *testing.T) {
+	a := &eventsAdapter{events: make(chan *ehpb.Event, 100), interests: []*ehpb.Interest{
+		&ehpb.Interest{EventType: ehpb.EventType_FILTEREDBLOCK},
+	}}
+	client := startEventsClient(t, a, "", false, 0)
+	defer client.Stop()
+
+	block := createReplayTestBlocks(2)[1]
+	emsg := producer.CreateFilteredBlockEvent(producer.CreateFilteredBlock(block))
+	emsg.BlockNumber = 1
+	if err := producer.Send(emsg); err != nil {
+		t.Fatalf("Error sending message %s", err)
+	}
+	expectFilteredBlock(t, a, 1)
+}
+
+func TestReplayAndAck(t *testing.T) {
+	interests := []*ehpb.Interest{
+		&ehpb.Interest{EventType: ehpb.EventType_FILTEREDBLOCK},
+		chaincodeInterest("replaycc", "", ehpb.ChaincodeReg_EXACT),
+	}
+	a := &eventsAdapter{events: make(chan *ehpb.Event, 100), interests: interests}
+	client := startEventsClient(t, a, "replayer", true, 1)
+	for blockNumber := uint64(1); blockNumber < 4; blockNumber++ {
+		expectFilteredBlock(t, a, blockNumber)
+		expectChaincodeEvent(t, a, fmt.Sprintf("e%d", blockNumber), blockNumber)
+	}
+
+	//live events follow the replayed events
+	emsg := producer.CreateChaincodeEvent(&ehpb.ChaincodeEvent{ChaincodeID: "replaycc", EventName: "e4"})
+	emsg.BlockNumber = 4
+	if err := producer.Send(emsg); err != nil {
+		t.Fatalf("Error sending message %s", err)
+	}
+	expectChaincodeEvent(t, a, "e4", 4)
+
+	if err := client.Ack(2); err != nil {
+		t.Fatalf("Error acknowledging block %s", err)
+	}
+	time.Sleep(time.Second)
+	client.Stop()
+
+	//the replay resumes after the acknowledged block
+	a = &eventsAdapter{events: make(chan *ehpb.Event, 100), interests: interests}
+	client = startEventsClient(t, a, "replayer", true, 0)
+	defer client.Stop()
+	expectFilteredBlock(t, a, 3)
+	expectChaincodeEvent(t, a, "e3", 3)
+	expectNoEvent(t, a)
+}
+
+// memAckCursorStore keeps the ack cursors as a store surviving a restart of the
+// event hub would
+type memAckCursorStore struct {
+	sync.Mutex
+	cursors map[string]uint64
+}
+
+func (s *memAckCursorStore) GetAckCursor(chainID string, consumerID string) (uint64, bool, error) {
+	s.Lock()
+	defer s.Unlock()
+	cursor, ok := s.cursors[chainID+"/"+consumerID]
+	return cursor, ok, nil
+}
+
+func (s *memAckCursorStore) PutAckCursor(chainID string, consumerID string, blockNumber uint64) error {
+	s.Lock()
+	defer s.Unlock()
+	s.cursors[chainID+"/"+consumerID] = blockNumber
+	return nil
+}
+
+func TestAckCursorStore(t *testing.T) {
+	//the consumer acknowledged block 1 before the event hub restarted
+	store := &memAckCursorStore{cursors: map[string]uint64{"/restarted": 1}}
+	producer.SetAckCursorStore(store)
+	defer producer.SetAckCursorStore(nil)
+
+	interests := []*ehpb.Interest{&ehpb.Interest{EventType: ehpb.EventType_FILTEREDBLOCK}}
+	a := &eventsAdapter{events: make(chan *ehpb.Event, 100), interests: interests}
+	client := startEventsClient(t, a, "restarted", true, 0)
+	defer client.Stop()
+	expectFilteredBlock(t, a, 2)
+	expectFilteredBlock(t, a, 3)
+	expectNoEvent(t, a)
+
+	if err := client.Ack(3); err != nil {
+		t.Fatalf("Error acknowledging block %s", err)
+	}
+	time.Sleep(time.Second)
+	if cursor, _, _ := store.GetAckCursor("", "restarted"); cursor != 3 {
+		t.Fatalf("expected the ack cursor of block 3 to be saved, got %d", cursor)
+	}
+}
+
+func TestDBAckCursorStore(t *testing.T) {
+	db.NewTestDBWrapper().CreateFreshDB(t)
+	store := producer.NewDBAckCursorStore()
+	if err := store.PutAckCursor("", "consumer1", 5); err != nil {
+		t.Fatalf("Error saving ack cursor: %s", err)
+	}
+	if cursor, ok, err := store.GetAckCursor("", "consumer1"); err != nil || !ok || cursor != 5 {
+		t.Fatalf("expected ack cursor 5, got %d %t %v", cursor, ok, err)
+	}
+	if _, ok, err := store.GetAckCursor("", "consumer2"); err != nil || ok {
+		t.Fatalf("expected no ack cursor, got %t %v", ok, err)
+	}
+}
+
+func TestChainEvents(t *testing.T) {
+	a := &eventsAdapter{events: make(chan *ehpb.Event, 100), interests: []*ehpb.Interest{
+		&ehpb.Interest{EventType: ehpb.EventType_FILTEREDBLOCK},
+		chaincodeInterest("chaincc", "", ehpb.ChaincodeReg_EXACT),
+	}}
+	client := consumer.NewEventsClient(peerAddress, a)
+	client.SetChainID("chain1")
+	client.ReplayFrom(0)
+	if err := client.Start(); err != nil {
+		t.Fatalf("Error starting events client: %s", err)
+	}
+	defer client.Stop()
+
+	//the blocks of the chain are replayed
+	for blockNumber := uint64(0); blockNumber < 2; blockNumber++ {
+		expectFilteredBlock(t, a, blockNumber)
+	}
+
+	//only the events of the chain are received
+	for _, chainID := range []string{"", "chain2", "chain1"} {
+		emsg := producer.CreateChaincodeEvent(&ehpb.ChaincodeEvent{ChaincodeID: "chaincc", EventName: "ev-" + chainID})
+		emsg.ChainID = chainID
+		if err := producer.Send(emsg); err != nil {
+			t.Fatalf("Error sending message %s", err)
+		}
+	}
+	e := expectEvent(t, a)
+	if e.GetChaincodeEvent() == nil || e.GetChaincodeEvent().EventName != "ev-chain1" || e.ChainID != "chain1" {
+		t.Fatalf("expected the chaincode event of chain1, got %v", e)
+	}
+	expectNoEvent(t, a)
+}
+
 func BenchmarkMessages(b *testing.B) {
 	numMessages := 10000
 
@@ -216,6 +483,13 @@ func TestMain(m *testing.M) {
 	// use a buffer of 100 and blocking timeout
 	ehServer := producer.NewEventsServer(100, 0)
 	ehpb.RegisterEventsServer(grpcServer, ehServer)
+	producer.SetBlockSource(&testBlockSource{createReplayTestBlocks(4)})
+	producer.SetChainBlockSources(func(chainID string) (producer.BlockSource, error) {
+		if chainID != "chain1" {
+			return nil, fmt.Errorf("chain %s not found", chainID)
+		}
+		return &testBlockSource{createReplayTestBlocks(2)}, nil
+	})
 
 	fmt.Printf("Starting events server\n")
 	go grpcServer.Serve(lis)
@@ -0,0 +1,57 @@
+/*
+Copyright IBM Corp. 2016 All Rights Reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+		 http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package producer
+
+import (
+	"encoding/binary"
+	"fmt"
+
+	"github.com/hyperledger/fabric/core/db"
+)
+
+// dbAckCursorStore persists the ack cursors in the persist column family of the
+// DB of their chain
+type dbAckCursorStore struct{}
+
+// NewDBAckCursorStore returns a store persisting the ack cursors in the DB of
+// their chain
+func NewDBAckCursorStore() AckCursorStore {
+	return dbAckCursorStore{}
+}
+
+func ackCursorDBKey(consumerID string) []byte {
+	return []byte("events.ack." + consumerID)
+}
+
+func (dbAckCursorStore) GetAckCursor(chainID string, consumerID string) (uint64, bool, error) {
+	openchainDB := db.GetChainDBHandle(chainID)
+	value, err := openchainDB.Get(openchainDB.PersistCF, ackCursorDBKey(consumerID))
+	if err != nil || value == nil {
+		return 0, false, err
+	}
+	if len(value) != 8 {
+		return 0, false, fmt.Errorf("invalid ack cursor of consumer %s", consumerID)
+	}
+	return binary.BigEndian.Uint64(value), true, nil
+}
+
+func (dbAckCursorStore) PutAckCursor(chainID string, consumerID string, blockNumber uint64) error {
+	openchainDB := db.GetChainDBHandle(chainID)
+	value := make([]byte, 8)
+	binary.BigEndian.PutUint64(value, blockNumber)
+	return openchainDB.Put(openchainDB.PersistCF, ackCursorDBKey(consumerID), value)
+}
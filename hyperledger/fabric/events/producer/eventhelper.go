@@ -17,6 +17,8 @@ limitations under the License.
 package producer
 
 import (
+	"github.com/golang/protobuf/proto"
+
 	ehpb "github.com/hyperledger/fabric/protos"
 )
 
@@ -29,3 +31,61 @@ func CreateBlockEvent(te *ehpb.Block) *ehpb.Event {
 func CreateChaincodeEvent(te *ehpb.ChaincodeEvent) *ehpb.Event {
 	return &ehpb.Event{Event: &ehpb.Event_ChaincodeEvent{ChaincodeEvent: te}}
 }
+
+//CreateFilteredBlockEvent creates a Event from a FilteredBlock
+func CreateFilteredBlockEvent(te *ehpb.FilteredBlock) *ehpb.Event {
+	return &ehpb.Event{Event: &ehpb.Event_FilteredBlock{FilteredBlock: te}}
+}
+
+//CreateFilteredBlock creates a FilteredBlock with the UUIDs and the results of
+//the transactions of a block
+func CreateFilteredBlock(block *ehpb.Block) *ehpb.FilteredBlock {
+	results := make(map[string]*ehpb.TransactionResult)
+	for _, tr := range block.GetNonHashData().GetTransactionResults() {
+		results[tr.Uuid] = tr
+	}
+	filteredBlock := &ehpb.FilteredBlock{}
+	for _, tx := range block.GetTransactions() {
+		ftx := &ehpb.FilteredTransaction{Uuid: tx.Uuid}
+		if tr := results[tx.Uuid]; tr != nil {
+			ftx.ErrorCode = tr.ErrorCode
+			ftx.Error = tr.Error
+		}
+		filteredBlock.Transactions = append(filteredBlock.Transactions, ftx)
+	}
+	return filteredBlock
+}
+
+//CreateBlockEvents creates the block, filtered block and chaincode events of a
+//block committed to the ledger. The payload of deploy transactions is removed
+//from the block to make block events more lightweight, as it can be very large.
+func CreateBlockEvents(blockNumber uint64, block *ehpb.Block) []*ehpb.Event {
+	for _, transaction := range block.GetTransactions() {
+		if transaction.Type == ehpb.Transaction_CHAINCODE_DEPLOY {
+			deploymentSpec := &ehpb.ChaincodeDeploymentSpec{}
+			err := proto.Unmarshal(transaction.Payload, deploymentSpec)
+			if err != nil {
+				producerLogger.Errorf("Error unmarshalling deployment transaction for block event: %s", err)
+				continue
+			}
+			deploymentSpec.CodePackage = nil
+			deploymentSpecBytes, err := proto.Marshal(deploymentSpec)
+			if err != nil {
+				producerLogger.Errorf("Error marshalling deployment transaction for block event: %s", err)
+				continue
+			}
+			transaction.Payload = deploymentSpecBytes
+		}
+	}
+
+	events := []*ehpb.Event{CreateBlockEvent(block), CreateFilteredBlockEvent(CreateFilteredBlock(block))}
+	for _, tr := range block.GetNonHashData().GetTransactionResults() {
+		if tr.ChaincodeEvent != nil {
+			events = append(events, CreateChaincodeEvent(tr.ChaincodeEvent))
+		}
+	}
+	for _, e := range events {
+		e.BlockNumber = blockNumber
+	}
+	return events
+}
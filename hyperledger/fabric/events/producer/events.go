@@ -18,6 +18,8 @@ package producer
 
 import (
 	"fmt"
+	"regexp"
+	"strings"
 	"sync"
 	"time"
 
@@ -50,6 +52,72 @@ type chaincodeHandlerList struct {
 	sync.RWMutex
 	// this map used as a list - add/del/iterate
 	handlers map[string]map[string]map[*handler]bool
+	// handlers registered for the event names matching a prefix or a
+	// regular expression, by chaincode ID
+	matchers map[string][]*eventNameMatcher
+}
+
+type eventNameMatcher struct {
+	match   pb.ChaincodeReg_MatchType
+	pattern string
+	regexp  *regexp.Regexp
+	h       *handler
+}
+
+func (m *eventNameMatcher) matches(eventName string) bool {
+	if m.match == pb.ChaincodeReg_PREFIX {
+		return strings.HasPrefix(eventName, m.pattern)
+	}
+	return m.regexp.MatchString(eventName)
+}
+
+//newHandlerList creates the handler list for an event type, nil if the
+//event type cannot be registered for
+func newHandlerList(eventType pb.EventType) handlerList {
+	switch eventType {
+	case pb.EventType_BLOCK, pb.EventType_FILTEREDBLOCK:
+		return &genericHandlerList{handlers: make(map[*handler]bool)}
+	case pb.EventType_CHAINCODE:
+		return &chaincodeHandlerList{handlers: make(map[string]map[string]map[*handler]bool), matchers: make(map[string][]*eventNameMatcher)}
+	}
+	return nil
+}
+
+func (hl *chaincodeHandlerList) addMatcher(ccReg *pb.ChaincodeReg, h *handler) (bool, error) {
+	m := &eventNameMatcher{match: ccReg.Match, pattern: ccReg.EventName, h: h}
+	switch ccReg.Match {
+	case pb.ChaincodeReg_PREFIX:
+	case pb.ChaincodeReg_REGEX:
+		var err error
+		if m.regexp, err = regexp.Compile(ccReg.EventName); err != nil {
+			return false, fmt.Errorf("invalid event name regular expression %s: %s", ccReg.EventName, err)
+		}
+	default:
+		return false, fmt.Errorf("invalid event name match type %s", ccReg.Match)
+	}
+	for _, other := range hl.matchers[ccReg.ChaincodeID] {
+		if other.h == h && other.match == m.match && other.pattern == m.pattern {
+			return false, fmt.Errorf("handler exists for event type")
+		}
+	}
+	hl.matchers[ccReg.ChaincodeID] = append(hl.matchers[ccReg.ChaincodeID], m)
+	return true, nil
+}
+
+func (hl *chaincodeHandlerList) delMatcher(ccReg *pb.ChaincodeReg, h *handler) (bool, error) {
+	matchers := hl.matchers[ccReg.ChaincodeID]
+	for i, m := range matchers {
+		if m.h == h && m.match == ccReg.Match && m.pattern == ccReg.EventName {
+			matchers = append(matchers[:i], matchers[i+1:]...)
+			if len(matchers) == 0 {
+				delete(hl.matchers, ccReg.ChaincodeID)
+			} else {
+				hl.matchers[ccReg.ChaincodeID] = matchers
+			}
+			return true, nil
+		}
+	}
+	return false, fmt.Errorf("handler not registered for event name %s %s for chaincode ID %s", ccReg.Match, ccReg.EventName, ccReg.ChaincodeID)
 }
 
 func (hl *chaincodeHandlerList) add(ie *pb.Interest, h *handler) (bool, error) {
@@ -64,6 +132,10 @@ func (hl *chaincodeHandlerList) add(ie *pb.Interest, h *handler) (bool, error) {
 	if ie.GetChaincodeRegInfo().ChaincodeID == "" {
 		return false, fmt.Errorf("chaincode ID not provided for registering")
 	}
+
+	if ie.GetChaincodeRegInfo().Match != pb.ChaincodeReg_EXACT {
+		return hl.addMatcher(ie.GetChaincodeRegInfo(), h)
+	}
 	//is there a event type map for the chaincode
 	emap, ok := hl.handlers[ie.GetChaincodeRegInfo().ChaincodeID]
 	if !ok {
@@ -99,6 +171,10 @@ func (hl *chaincodeHandlerList) del(ie *pb.Interest, h *handler) (bool, error) {
 		return false, fmt.Errorf("chaincode ID not provided for de-registering")
 	}
 
+	if ie.GetChaincodeRegInfo().Match != pb.ChaincodeReg_EXACT {
+		return hl.delMatcher(ie.GetChaincodeRegInfo(), h)
+	}
+
 	//if there's no event type map, nothing to do
 	emap, ok := hl.handlers[ie.GetChaincodeRegInfo().ChaincodeID]
 	if !ok {
@@ -158,6 +234,14 @@ func (hl *chaincodeHandlerList) foreach(e *pb.Event, action func(h *handler)) {
 			}
 		}
 	}
+
+	//send to handlers who want the events whose name matches a prefix or a
+	//regular expression
+	for _, m := range hl.matchers[e.GetChaincodeEvent().ChaincodeID] {
+		if m.matches(e.GetChaincodeEvent().EventName) {
+			action(m.h)
+		}
+	}
 }
 
 func (hl *genericHandlerList) add(ie *pb.Interest, h *handler) (bool, error) {
@@ -207,6 +291,36 @@ type eventProcessor struct {
 	//if 0, if buffer full, will block and guarantee the event will be sent out
 	//if > 0, if buffer full, blocks till timeout
 	timeout int
+
+	//blocks from which the events are replayed, set by SetBlockSource for
+	//the default chain and by SetChainBlockSources for the other chains
+	blockSource       BlockSource
+	chainBlockSources func(chainID string) (BlockSource, error)
+
+	//number of the last block acknowledged by each consumer, loaded from and
+	//saved to ackStore, set by SetAckCursorStore
+	ackCursors map[ackCursorKey]uint64
+	ackStore   AckCursorStore
+}
+
+//BlockSource provides the blocks of the ledger to replay their events
+type BlockSource interface {
+	GetBlockchainSize() uint64
+	GetBlockByNumber(blockNumber uint64) (*pb.Block, error)
+}
+
+//AckCursorStore persists the number of the last block of the chain acknowledged
+//by each consumer, so that its replay resumes after it when the event hub is
+//restarted
+type AckCursorStore interface {
+	GetAckCursor(chainID string, consumerID string) (uint64, bool, error)
+	PutAckCursor(chainID string, consumerID string, blockNumber uint64) error
+}
+
+//consumers acknowledge the blocks of each chain separately
+type ackCursorKey struct {
+	chainID    string
+	consumerID string
 }
 
 //global eventProcessor singleton created by initializeEvents. Openchain producers
@@ -231,8 +345,10 @@ func (ep *eventProcessor) start() {
 		ep.Unlock()
 
 		hl.foreach(e, func(h *handler) {
-			if e.Event != nil {
-				h.SendMessage(e)
+			if e.Event != nil && e.ChainID == h.chainID {
+				if err := h.SendMessage(e); err != nil {
+					droppedEvents.With("send_failed").Inc()
+				}
 			}
 		})
 
@@ -245,7 +361,7 @@ func initializeEvents(bufferSize uint, tout int) {
 		panic("should not be called twice")
 	}
 
-	gEventProcessor = &eventProcessor{eventConsumers: make(map[pb.EventType]handlerList), eventChannel: make(chan *pb.Event, bufferSize), timeout: tout}
+	gEventProcessor = &eventProcessor{eventConsumers: make(map[pb.EventType]handlerList), eventChannel: make(chan *pb.Event, bufferSize), timeout: tout, ackCursors: make(map[ackCursorKey]uint64)}
 
 	addInternalEventTypes()
 
@@ -262,11 +378,8 @@ func AddEventType(eventType pb.EventType) error {
 		return fmt.Errorf("event type exists %s", pb.EventType_name[int32(eventType)])
 	}
 
-	switch eventType {
-	case pb.EventType_BLOCK:
-		gEventProcessor.eventConsumers[eventType] = &genericHandlerList{handlers: make(map[*handler]bool)}
-	case pb.EventType_CHAINCODE:
-		gEventProcessor.eventConsumers[eventType] = &chaincodeHandlerList{handlers: make(map[string]map[string]map[*handler]bool)}
+	if hl := newHandlerList(eventType); hl != nil {
+		gEventProcessor.eventConsumers[eventType] = hl
 	}
 	gEventProcessor.Unlock()
 
@@ -301,9 +414,133 @@ func deRegisterHandler(ie *pb.Interest, h *handler) error {
 	return nil
 }
 
+//getBlockSource returns the blocks of the chain, an error if the events of the
+//chain cannot be replayed
+func getBlockSource(chainID string) (BlockSource, error) {
+	gEventProcessor.RLock()
+	source, chainSources := gEventProcessor.blockSource, gEventProcessor.chainBlockSources
+	gEventProcessor.RUnlock()
+	if chainID != "" {
+		if chainSources == nil {
+			return nil, fmt.Errorf("replay is not supported for chain %s by this event hub", chainID)
+		}
+		return chainSources(chainID)
+	}
+	if source == nil {
+		return nil, fmt.Errorf("replay is not supported by this event hub")
+	}
+	return source, nil
+}
+
+//getAckStore returns the store of the ack cursors of the chain, nil if they
+//are not persisted. They are persisted only for the chains whose events can be
+//replayed, which are the only ones they are used for.
+func getAckStore(chainID string) AckCursorStore {
+	gEventProcessor.RLock()
+	store := gEventProcessor.ackStore
+	gEventProcessor.RUnlock()
+	if store == nil {
+		return nil
+	}
+	if _, err := getBlockSource(chainID); err != nil {
+		return nil
+	}
+	return store
+}
+
+//ackBlock moves the cursor of a consumer to the block of the chain it
+//acknowledged. The cursor never moves backwards.
+func ackBlock(chainID string, consumerID string, blockNumber uint64) error {
+	if cursor, ok := getAckCursor(chainID, consumerID); ok && blockNumber <= cursor {
+		return nil
+	}
+	gEventProcessor.Lock()
+	gEventProcessor.ackCursors[ackCursorKey{chainID, consumerID}] = blockNumber
+	gEventProcessor.Unlock()
+	if store := getAckStore(chainID); store != nil {
+		return store.PutAckCursor(chainID, consumerID, blockNumber)
+	}
+	return nil
+}
+
+func getAckCursor(chainID string, consumerID string) (uint64, bool) {
+	key := ackCursorKey{chainID, consumerID}
+	gEventProcessor.RLock()
+	cursor, ok := gEventProcessor.ackCursors[key]
+	gEventProcessor.RUnlock()
+	if ok {
+		return cursor, true
+	}
+	store := getAckStore(chainID)
+	if store == nil {
+		return 0, false
+	}
+	cursor, ok, err := store.GetAckCursor(chainID, consumerID)
+	if err != nil {
+		producerLogger.Errorf("Error loading the ack cursor of consumer %s: %s", consumerID, err)
+		return 0, false
+	}
+	if ok {
+		gEventProcessor.Lock()
+		if current, found := gEventProcessor.ackCursors[key]; !found || cursor > current {
+			gEventProcessor.ackCursors[key] = cursor
+		}
+		gEventProcessor.Unlock()
+	}
+	return cursor, ok
+}
+
 //------------- producer API's -------------------------------
 
-//Send sends the event to interested consumers
+//SetBlockSource sets the blocks from which the events are replayed to the
+//consumers registering with a start block
+func SetBlockSource(source BlockSource) {
+	if gEventProcessor == nil {
+		return
+	}
+	gEventProcessor.Lock()
+	gEventProcessor.blockSource = source
+	gEventProcessor.Unlock()
+}
+
+//SetChainBlockSources sets the function giving the blocks of the chains other
+//than the default one, from which the events are replayed
+func SetChainBlockSources(sources func(chainID string) (BlockSource, error)) {
+	if gEventProcessor == nil {
+		return
+	}
+	gEventProcessor.Lock()
+	gEventProcessor.chainBlockSources = sources
+	gEventProcessor.Unlock()
+}
+
+//SetAckCursorStore sets the store persisting the cursors of the consumers
+//acknowledging blocks
+func SetAckCursorStore(store AckCursorStore) {
+	if gEventProcessor == nil {
+		return
+	}
+	gEventProcessor.Lock()
+	gEventProcessor.ackStore = store
+	gEventProcessor.Unlock()
+}
+
+//SendBlockEvents sends the block, filtered block and chaincode events of a block
+//committed to the ledger of the chain, the default chain if chainID is empty
+func SendBlockEvents(chainID string, blockNumber uint64, block *pb.Block) error {
+	if gEventProcessor == nil {
+		return nil
+	}
+	for _, e := range CreateBlockEvents(blockNumber, block) {
+		e.ChainID = chainID
+		if err := Send(e); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+//Send sends the event to the interested consumers of the chain of the event
 func Send(e *pb.Event) error {
 	if e.Event == nil {
 		producerLogger.Error("event not set")
@@ -318,6 +555,7 @@ func Send(e *pb.Event) error {
 		select {
 		case gEventProcessor.eventChannel <- e:
 		default:
+			droppedEvents.With("buffer_full").Inc()
 			return fmt.Errorf("could not send the blocking event")
 		}
 	} else if gEventProcessor.timeout == 0 {
@@ -326,6 +564,7 @@ func Send(e *pb.Event) error {
 		select {
 		case gEventProcessor.eventChannel <- e:
 		case <-time.After(time.Duration(gEventProcessor.timeout) * time.Millisecond):
+			droppedEvents.With("buffer_full").Inc()
 			return fmt.Errorf("could not send the blocking event")
 		}
 	}
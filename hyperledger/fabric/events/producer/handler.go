@@ -18,6 +18,7 @@ package producer
 
 import (
 	"fmt"
+	"sync"
 
 	pb "github.com/hyperledger/fabric/protos"
 )
@@ -28,6 +29,15 @@ type handler struct {
 	registered bool
 	// PM: this should be a list, add/del, iterate
 	interestedEvents []*pb.Interest
+	consumerID       string
+	// the handler receives the events of this chain only
+	chainID string
+
+	// sendLock serializes the messages sent through the stream. While the
+	// events of the ledger are replayed, the live events are kept in pending.
+	sendLock  sync.Mutex
+	replaying bool
+	pending   []*pb.Event
 }
 
 func newEventHandler(stream pb.Events_ChatServer) (*handler, error) {
@@ -55,6 +65,9 @@ func (d *handler) addInterest(interest *pb.Interest) {
 func (d *handler) Stop() error {
 	d.deregister()
 	d.doneChan <- true
+	if d.registered {
+		consumers.Dec()
+	}
 	d.registered = false
 	return nil
 }
@@ -88,29 +101,152 @@ func (d *handler) deregister() {
 // HandleMessage handles the Openchain messages for the Peer.
 func (d *handler) HandleMessage(msg *pb.Event) error {
 	producerLogger.Debug("Handling Event")
+	if ackObj := msg.GetAck(); ackObj != nil {
+		return d.ack(ackObj)
+	}
 	eventsObj := msg.GetRegister()
 	if eventsObj == nil {
 		return fmt.Errorf("Invalid object from consumer %v", msg.GetEvent())
 	}
 
+	if eventsObj.ConsumerID != "" {
+		d.consumerID = eventsObj.ConsumerID
+	}
+	if d.registered && eventsObj.ChainID != d.chainID {
+		return fmt.Errorf("Could not register events, consumer already registered for chain [%s]", d.chainID)
+	}
+	d.chainID = eventsObj.ChainID
+
+	var startBlock uint64
+	var source BlockSource
+	if eventsObj.Replay {
+		var err error
+		if source, err = getBlockSource(d.chainID); err != nil {
+			return fmt.Errorf("Could not register events, %s", err)
+		}
+		startBlock = eventsObj.StartBlock
+		if cursor, ok := getAckCursor(d.chainID, d.consumerID); ok && d.consumerID != "" && cursor >= startBlock {
+			startBlock = cursor + 1
+		}
+		d.sendLock.Lock()
+		d.replaying = true
+		d.sendLock.Unlock()
+	}
+
 	if err := d.register(eventsObj.Events); err != nil {
 		return fmt.Errorf("Could not register events %s", err)
 	}
 
 	//TODO return supported events.. for now just return the received msg
-	if err := d.ChatStream.Send(msg); err != nil {
+	d.sendLock.Lock()
+	err := d.ChatStream.Send(msg)
+	d.sendLock.Unlock()
+	if err != nil {
 		return fmt.Errorf("Error sending response to %v:  %s", msg, err)
 	}
 
+	if !d.registered {
+		consumers.Inc()
+	}
 	d.registered = true
 
+	if eventsObj.Replay {
+		return d.replay(source, startBlock)
+	}
+	return nil
+}
+
+// replay sends the events of the blocks of the ledger from startBlock on, then
+// the live events received meanwhile which belong to later blocks
+func (d *handler) replay(source BlockSource, startBlock uint64) error {
+	size := source.GetBlockchainSize()
+	defer d.endReplay(size)
+
+	//the replayed events are matched against the interests of this handler only
+	lists := make(map[pb.EventType]handlerList)
+	for _, ie := range d.interestedEvents {
+		hl, ok := lists[ie.EventType]
+		if !ok {
+			if hl = newHandlerList(ie.EventType); hl == nil {
+				continue
+			}
+			lists[ie.EventType] = hl
+		}
+		hl.add(ie, d)
+	}
+
+	producerLogger.Debugf("Replaying events of blocks %d to %d", startBlock, size)
+	for blockNumber := startBlock; blockNumber < size; blockNumber++ {
+		block, err := source.GetBlockByNumber(blockNumber)
+		if err != nil {
+			return fmt.Errorf("Error replaying events of block %d: %s", blockNumber, err)
+		}
+		for _, e := range CreateBlockEvents(blockNumber, block) {
+			e.ChainID = d.chainID
+			hl := lists[getMessageType(e)]
+			if hl == nil {
+				continue
+			}
+			var sendErr error
+			hl.foreach(e, func(h *handler) {
+				if sendErr == nil {
+					sendErr = h.send(e)
+				}
+			})
+			if sendErr != nil {
+				return sendErr
+			}
+		}
+	}
+	return nil
+}
+
+// endReplay sends the pending live events from block nextBlock on, the events
+// of the earlier blocks were replayed
+func (d *handler) endReplay(nextBlock uint64) {
+	d.sendLock.Lock()
+	defer d.sendLock.Unlock()
+	for _, e := range d.pending {
+		if e.BlockNumber < nextBlock {
+			continue
+		}
+		if err := d.ChatStream.Send(e); err != nil {
+			producerLogger.Errorf("Error sending pending event: %s", err)
+			break
+		}
+	}
+	d.pending = nil
+	d.replaying = false
+}
+
+func (d *handler) ack(ackObj *pb.Ack) error {
+	if d.consumerID == "" {
+		return fmt.Errorf("Ack from a consumer registered without consumer ID")
+	}
+	if err := ackBlock(d.chainID, d.consumerID, ackObj.BlockNumber); err != nil {
+		producerLogger.Errorf("Error saving the ack cursor of consumer %s: %s", d.consumerID, err)
+	}
+	return nil
+}
+
+func (d *handler) send(msg *pb.Event) error {
+	d.sendLock.Lock()
+	defer d.sendLock.Unlock()
+	if err := d.ChatStream.Send(msg); err != nil {
+		return fmt.Errorf("Error Sending message through ChatStream: %s", err)
+	}
 	return nil
 }
 
 // SendMessage sends a message to the remote PEER through the stream
 func (d *handler) SendMessage(msg *pb.Event) error {
-	err := d.ChatStream.Send(msg)
-	if err != nil {
+	d.sendLock.Lock()
+	defer d.sendLock.Unlock()
+	if d.replaying {
+		d.pending = append(d.pending, msg)
+		return nil
+	}
+	if err := d.ChatStream.Send(msg); err != nil {
 		return fmt.Errorf("Error Sending message through ChatStream: %s", err)
 	}
 	return nil
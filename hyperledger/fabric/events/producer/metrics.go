@@ -0,0 +1,28 @@
+/*
+Copyright IBM Corp. 2016 All Rights Reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+		 http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package producer
+
+import (
+	"github.com/hyperledger/fabric/core/metrics"
+)
+
+var (
+	consumers = metrics.NewGauge("eventhub_consumers",
+		"Number of consumers registered with the event hub.")
+	droppedEvents = metrics.NewCounterVec("eventhub_dropped_events_total",
+		"Number of events dropped by the event hub, because its buffer was full or sending to a consumer failed.", "reason")
+)
@@ -21,6 +21,7 @@ import (
 	"io"
 	"time"
 
+	"github.com/hyperledger/fabric/core/comm"
 	pb "github.com/hyperledger/fabric/protos"
 	"github.com/op/go-logging"
 )
@@ -49,6 +50,7 @@ func NewEventsServer(bufferSize uint, timeout int) *EventsServer {
 
 // Chat implementation of the the Chat bidi streaming RPC function
 func (p *EventsServer) Chat(stream pb.Events_ChatServer) error {
+	defer comm.TrackStream("events")()
 	handler, err := newEventHandler(stream)
 	if err != nil {
 		return fmt.Errorf("Error creating handler during handleChat initiation: %s", err)
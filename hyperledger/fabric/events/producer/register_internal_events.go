@@ -39,6 +39,8 @@ func getMessageType(e *pb.Event) pb.EventType {
 		return pb.EventType_BLOCK
 	case *pb.Event_ChaincodeEvent:
 		return pb.EventType_CHAINCODE
+	case *pb.Event_FilteredBlock:
+		return pb.EventType_FILTEREDBLOCK
 	default:
 		return -1
 	}
@@ -48,4 +50,5 @@ func getMessageType(e *pb.Event) pb.EventType {
 func addInternalEventTypes() {
 	AddEventType(pb.EventType_BLOCK)
 	AddEventType(pb.EventType_CHAINCODE)
+	AddEventType(pb.EventType_FILTEREDBLOCK)
 }
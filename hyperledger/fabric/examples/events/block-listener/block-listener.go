@@ -29,7 +29,7 @@ import (
 )
 
 type adapter struct {
-	notfy chan *pb.Event_Block
+	notfy chan *pb.Event
 }
 
 //GetInterestedEvents implements consumer.EventAdapter interface for registering interested events
@@ -41,7 +41,7 @@ func (a *adapter) GetInterestedEvents() ([]*pb.Interest, error) {
 func (a *adapter) Recv(msg *pb.Event) (bool, error) {
 	switch msg.Event.(type) {
 	case *pb.Event_Block:
-		a.notfy <- msg.Event.(*pb.Event_Block)
+		a.notfy <- msg
 		return true, nil
 	default:
 		a.notfy <- nil
@@ -55,42 +55,51 @@ func (a *adapter) Disconnected(err error) {
 	os.Exit(1)
 }
 
-func createEventClient(eventAddress string) *adapter {
+func createEventClient(eventAddress string, consumerID string, startBlock int64) (*adapter, *consumer.EventsClient) {
 	var obcEHClient *consumer.EventsClient
 
-	done := make(chan *pb.Event_Block)
+	done := make(chan *pb.Event)
 	adapter := &adapter{notfy: done}
 	obcEHClient = consumer.NewEventsClient(eventAddress, adapter)
+	obcEHClient.SetConsumerID(consumerID)
+	if startBlock >= 0 {
+		obcEHClient.ReplayFrom(uint64(startBlock))
+	}
 	if err := obcEHClient.Start(); err != nil {
 		fmt.Printf("could not start chat %s\n", err)
 		obcEHClient.Stop()
-		return nil
+		return nil, nil
 	}
 
-	return adapter
+	return adapter, obcEHClient
 }
 
 func main() {
 	var eventAddress string
+	var consumerID string
+	var startBlock int64
 	flag.StringVar(&eventAddress, "events-address", "0.0.0.0:31315", "address of events server")
+	flag.StringVar(&consumerID, "consumer-id", "", "ID under which the received blocks are acknowledged, to resume after them when reconnecting")
+	flag.Int64Var(&startBlock, "start-block", -1, "number of the first block to replay, live blocks only if negative")
 	flag.Parse()
 
 	fmt.Printf("Event Address: %s\n", eventAddress)
 
-	a := createEventClient(eventAddress)
+	a, obcEHClient := createEventClient(eventAddress, consumerID, startBlock)
 	if a == nil {
 		fmt.Printf("Error creating event client\n")
 		return
 	}
 
 	for {
-		b := <-a.notfy
-		if b.Block.NonHashData.TransactionResults == nil {
+		e := <-a.notfy
+		b := e.GetBlock()
+		if b.NonHashData.TransactionResults == nil {
 			fmt.Printf("INVALID BLOCK ... NO TRANSACTION RESULTS %v\n", b)
 		} else {
-			fmt.Printf("Received block\n")
+			fmt.Printf("Received block %d\n", e.BlockNumber)
 			fmt.Printf("--------------\n")
-			for _, r := range b.Block.NonHashData.TransactionResults {
+			for _, r := range b.NonHashData.TransactionResults {
 				if r.ErrorCode != 0 {
 					fmt.Printf("Err Transaction:\n\t[%v]\n", r)
 				} else {
@@ -98,5 +107,10 @@ func main() {
 				}
 			}
 		}
+		if consumerID != "" {
+			if err := obcEHClient.Ack(e.BlockNumber); err != nil {
+				fmt.Printf("Error acknowledging block %d: %s\n", e.BlockNumber, err)
+			}
+		}
 	}
 }
@@ -32,7 +32,6 @@ import (
 	"database/sql"
 
 	"github.com/golang/protobuf/proto"
-	"github.com/spf13/viper"
 	"golang.org/x/net/context"
 	"google.golang.org/grpc"
 
@@ -48,6 +47,8 @@ var (
 // ACA is the attribute certificate authority.
 type ACA struct {
 	*CA
+	eca      *ECA
+	provider AttributeProvider
 }
 
 // ACAP serves the public GRPC interface of the ACA.
@@ -229,12 +230,23 @@ func (attrPair *AttributePair) ToACAAttribute() *pb.ACAAttribute {
 }
 
 // NewACA sets up a new ACA.
-func NewACA() *ACA {
-	aca := &ACA{NewCA("aca", initializeACATables)}
+func NewACA(eca *ECA) *ACA {
+	aca := &ACA{CA: NewCA("aca", initializeACATables), eca: eca}
+
+	provider, err := newAttributeProvider(aca)
+	if err != nil {
+		Panic.Panicln(err)
+	}
+	aca.provider = provider
 
 	return aca
 }
 
+// SetAttributeProvider replaces the source of the attributes of the users.
+func (aca *ACA) SetAttributeProvider(provider AttributeProvider) {
+	aca.provider = provider
+}
+
 func (aca *ACA) getECACertificate() (*x509.Certificate, error) {
 	raw, err := aca.readCACertificate("eca")
 	if err != nil {
@@ -252,34 +264,11 @@ func (aca *ACA) getTCACertificate() (*x509.Certificate, error) {
 }
 
 func (aca *ACA) fetchAttributes(id, affiliation string) ([]*AttributePair, error) {
-	// TODO this attributes should be readed from the outside world in place of configuration file.
-	var attributes = make([]*AttributePair, 0)
-	attrs := viper.GetStringMapString("aca.attributes")
-
-	for _, flds := range attrs {
-		vals := strings.Fields(flds)
-		if len(vals) >= 1 {
-			val := ""
-			for _, eachVal := range vals {
-				val = val + " " + eachVal
-			}
-			attributeVals := strings.Split(val, ";")
-			if len(attributeVals) >= 6 {
-				attrPair, err := NewAttributePair(attributeVals, nil)
-				if err != nil {
-					return nil, errors.New("Invalid attribute entry " + val + " " + err.Error())
-				}
-				if attrPair.GetID() != id || attrPair.GetAffiliation() != affiliation {
-					continue
-				}
-				attributes = append(attributes, attrPair)
-			} else {
-				Error.Printf("Invalid attribute entry '%v'", vals[0])
-			}
-		}
+	attributes, err := aca.provider.FetchAttributes(id, affiliation)
+	if err != nil {
+		return nil, err
 	}
-
-	fmt.Printf("%v %v", id, attributes)
+	Trace.Printf("Fetched %d attributes of %s\n", len(attributes), id)
 
 	return attributes, nil
 }
@@ -373,6 +362,70 @@ func (aca *ACA) findAttribute(owner *AttributeOwner, attributeName string) (*Att
 	return &AttributePair{owner, attName, attValue, validFrom, validTo}, nil
 }
 
+// changeAttributes applies change to each of attrs within a single transaction.
+func (aca *ACA) changeAttributes(attrs []*AttributePair, change func(*sql.Tx, *AttributePair) error) error {
+	mutex.Lock()
+	defer mutex.Unlock()
+
+	tx, err := aca.db.Begin()
+	if err != nil {
+		return err
+	}
+	for _, attr := range attrs {
+		if err = change(tx, attr); err != nil {
+			tx.Rollback()
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+func existsAttribute(tx *sql.Tx, attr *AttributePair) (bool, error) {
+	var count int
+	err := tx.QueryRow("SELECT count(row) FROM Attributes WHERE id=? AND affiliation=? AND attributeName=?",
+		attr.GetID(), attr.GetAffiliation(), attr.GetAttributeName()).Scan(&count)
+	return count > 0, err
+}
+
+func addAttribute(tx *sql.Tx, attr *AttributePair) error {
+	exists, err := existsAttribute(tx, attr)
+	if err != nil {
+		return err
+	}
+	if exists {
+		return fmt.Errorf("Attribute %s of %s already exists.", attr.GetAttributeName(), attr.GetID())
+	}
+	_, err = tx.Exec("INSERT INTO Attributes (validFrom, validTo, attributeValue, id, affiliation, attributeName) VALUES (?,?,?,?,?,?)",
+		attr.GetValidFrom(), attr.GetValidTo(), attr.GetAttributeValue(), attr.GetID(), attr.GetAffiliation(), attr.GetAttributeName())
+	return err
+}
+
+func updateAttribute(tx *sql.Tx, attr *AttributePair) error {
+	exists, err := existsAttribute(tx, attr)
+	if err != nil {
+		return err
+	}
+	if !exists {
+		return fmt.Errorf("Attribute %s of %s does not exist.", attr.GetAttributeName(), attr.GetID())
+	}
+	_, err = tx.Exec("UPDATE Attributes SET validFrom=?, validTo=?, attributeValue=? WHERE id=? AND affiliation=? AND attributeName=?",
+		attr.GetValidFrom(), attr.GetValidTo(), attr.GetAttributeValue(), attr.GetID(), attr.GetAffiliation(), attr.GetAttributeName())
+	return err
+}
+
+func expireAttribute(tx *sql.Tx, attr *AttributePair) error {
+	exists, err := existsAttribute(tx, attr)
+	if err != nil {
+		return err
+	}
+	if !exists {
+		return fmt.Errorf("Attribute %s of %s does not exist.", attr.GetAttributeName(), attr.GetID())
+	}
+	_, err = tx.Exec("UPDATE Attributes SET validTo=? WHERE id=? AND affiliation=? AND attributeName=?",
+		attr.GetValidTo(), attr.GetID(), attr.GetAffiliation(), attr.GetAttributeName())
+	return err
+}
+
 // FetchAttributes fetchs the attributes from the outside world and populate them into the database.
 func (acap *ACAP) FetchAttributes(ctx context.Context, in *pb.ACAFetchAttrReq) (*pb.ACAFetchAttrResp, error) {
 	Trace.Println("grpc ACAP:FetchAttributes")
@@ -565,12 +618,96 @@ func (acap *ACAP) ReadCACertificate(ctx context.Context, in *pb.Empty) (*pb.Cert
 	return &pb.Cert{Cert: acap.aca.raw}, nil
 }
 
+// checkRequest verifies that an ACAA request is signed by an administrator.
+func (acaa *ACAA) checkRequest(in *pb.ACAAttrAdminReq) error {
+	if in.Id == nil {
+		return errors.New("Identity missing.")
+	}
+	id := in.Id.Id
+	if err := acaa.aca.eca.checkAdmin(id); err != nil {
+		return err
+	}
+
+	sig := in.Sig
+	in.Sig = nil
+	return acaa.aca.eca.checkSignature(id, in, sig)
+}
+
+// toAttributePairs converts the attributes of an ACAA request. A missing
+// validTo is set to expireAt.
+func toAttributePairs(entries []*pb.ACAAttrEntry, expireAt time.Time) ([]*AttributePair, error) {
+	var attrs []*AttributePair
+	for _, entry := range entries {
+		if entry.Id == "" || entry.AttributeName == "" {
+			return nil, errors.New("Attribute owner or name missing.")
+		}
+		attr := &AttributePair{owner: &AttributeOwner{entry.Id, entry.Affiliation}, attributeName: entry.AttributeName, attributeValue: entry.AttributeValue, validTo: expireAt}
+		if entry.ValidFrom != nil {
+			attr.validFrom = time.Unix(entry.ValidFrom.Seconds, int64(entry.ValidFrom.Nanos))
+		}
+		if entry.ValidTo != nil {
+			attr.validTo = time.Unix(entry.ValidTo.Seconds, int64(entry.ValidTo.Nanos))
+		}
+		attrs = append(attrs, attr)
+	}
+	return attrs, nil
+}
+
+// changeAttributes applies change to the attributes of an ACAA request.
+func (acaa *ACAA) changeAttributes(in *pb.ACAAttrAdminReq, expireAt time.Time, change func(*sql.Tx, *AttributePair) error) (*pb.CAStatus, error) {
+	if err := acaa.checkRequest(in); err != nil {
+		return nil, err
+	}
+
+	attrs, err := toAttributePairs(in.Attributes, expireAt)
+	if err != nil {
+		return nil, err
+	}
+	if err = acaa.aca.changeAttributes(attrs, change); err != nil {
+		return nil, err
+	}
+
+	return &pb.CAStatus{Status: pb.CAStatus_OK}, nil
+}
+
+// AddAttributes adds attributes to users. Attributes already owned by the
+// users are rejected.
+//
+// Attributes changed by an administrator are overwritten on the next fetch
+// from the attribute provider only if the provider returns them with a later
+// validFrom.
+func (acaa *ACAA) AddAttributes(ctx context.Context, in *pb.ACAAttrAdminReq) (*pb.CAStatus, error) {
+	Trace.Println("grpc ACAA:AddAttributes")
+
+	return acaa.changeAttributes(in, time.Time{}, addAttribute)
+}
+
+// UpdateAttributes replaces the values and validity of attributes of users.
+func (acaa *ACAA) UpdateAttributes(ctx context.Context, in *pb.ACAAttrAdminReq) (*pb.CAStatus, error) {
+	Trace.Println("grpc ACAA:UpdateAttributes")
+
+	return acaa.changeAttributes(in, time.Time{}, updateAttribute)
+}
+
+// ExpireAttributes sets the end of the validity of attributes of users, now
+// unless a validTo is given.
+func (acaa *ACAA) ExpireAttributes(ctx context.Context, in *pb.ACAAttrAdminReq) (*pb.CAStatus, error) {
+	Trace.Println("grpc ACAA:ExpireAttributes")
+
+	return acaa.changeAttributes(in, time.Now(), expireAttribute)
+}
+
 func (aca *ACA) startACAP(srv *grpc.Server) {
 	pb.RegisterACAPServer(srv, &ACAP{aca})
 }
 
-// Start starts the ECA.
+func (aca *ACA) startACAA(srv *grpc.Server) {
+	pb.RegisterACAAServer(srv, &ACAA{aca})
+}
+
+// Start starts the ACA.
 func (aca *ACA) Start(srv *grpc.Server) {
 	aca.startACAP(srv)
+	aca.startACAA(srv)
 	Info.Println("ACA started.")
 }
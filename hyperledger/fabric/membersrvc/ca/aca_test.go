@@ -622,3 +622,75 @@ func contains(s []string, e string) bool {
 	}
 	return false
 }
+
+func TestACAAAttributes(t *testing.T) {
+	acaa := &ACAA{aca}
+
+	admin := User{enrollID: "testAttributeAdmin"}
+	tok, err := eca.registerUser(admin.enrollID, "institution_a", "00001", pb.Role_AUDITOR, "", "")
+	if err != nil {
+		t.Fatalf("Failed registering administrator [%s]", err)
+	}
+	admin.enrollPwd = []byte(tok)
+	if err = enrollUser(&admin); err != nil {
+		t.Fatalf("Failed enrolling administrator [%s]", err)
+	}
+	owner := &AttributeOwner{"testAttributeUser", "bank_a"}
+	validFrom := &google_protobuf.Timestamp{Seconds: time.Now().Add(-time.Hour).Unix()}
+	newRequest := func(value string) *pb.ACAAttrAdminReq {
+		req := &pb.ACAAttrAdminReq{
+			Id:         &pb.Identity{Id: admin.enrollID},
+			Attributes: []*pb.ACAAttrEntry{{Id: owner.id, Affiliation: owner.affiliation, AttributeName: "role", AttributeValue: []byte(value), ValidFrom: validFrom}}}
+		req.Sig, _ = signRequest(admin.enrollPrivKey, req)
+		return req
+	}
+
+	// Updating or expiring a missing attribute fails
+	if _, err = acaa.UpdateAttributes(context.Background(), newRequest("client")); err == nil {
+		t.Fatal("Expected an error updating a missing attribute")
+	}
+	if _, err = acaa.ExpireAttributes(context.Background(), newRequest("")); err == nil {
+		t.Fatal("Expected an error expiring a missing attribute")
+	}
+
+	if _, err = acaa.AddAttributes(context.Background(), newRequest("client")); err != nil {
+		t.Fatalf("Failed adding attribute [%s]", err)
+	}
+	if _, err = acaa.AddAttributes(context.Background(), newRequest("client")); err == nil {
+		t.Fatal("Expected an error adding an existing attribute")
+	}
+
+	if _, err = acaa.UpdateAttributes(context.Background(), newRequest("manager")); err != nil {
+		t.Fatalf("Failed updating attribute [%s]", err)
+	}
+	attr, err := aca.findAttribute(owner, "role")
+	if err != nil || attr == nil || string(attr.GetAttributeValue()) != "manager" || !attr.IsValidFor(time.Now()) {
+		t.Fatalf("Expected a valid manager role but got %v (%v)", attr, err)
+	}
+
+	// A refresh from the attribute provider keeps the changes
+	if err = aca.fetchAndPopulateAttributes(owner.id, owner.affiliation); err != nil {
+		t.Fatalf("Failed fetching attributes [%s]", err)
+	}
+
+	if _, err = acaa.ExpireAttributes(context.Background(), newRequest("")); err != nil {
+		t.Fatalf("Failed expiring attribute [%s]", err)
+	}
+	attr, err = aca.findAttribute(owner, "role")
+	if err != nil || attr == nil || string(attr.GetAttributeValue()) != "manager" || attr.IsValidFor(time.Now()) {
+		t.Fatalf("Expected an expired manager role but got %v (%v)", attr, err)
+	}
+
+	// Only administrators can change attributes
+	req := newRequest("client")
+	req.Id = &pb.Identity{Id: testUser.enrollID}
+	req.Sig, _ = signRequest(admin.enrollPrivKey, req)
+	if _, err = acaa.UpdateAttributes(context.Background(), req); err == nil {
+		t.Fatal("Only admins should be able to change attributes")
+	}
+	req = newRequest("client")
+	req.Attributes[0].AttributeValue = []byte("auditor")
+	if _, err = acaa.UpdateAttributes(context.Background(), req); err == nil {
+		t.Fatal("Expected an error for a tampered request")
+	}
+}
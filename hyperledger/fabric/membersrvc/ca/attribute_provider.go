@@ -0,0 +1,216 @@
+/*
+Copyright IBM Corp. 2016 All Rights Reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+		 http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ca
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/spf13/viper"
+)
+
+// AttributeProvider is a source of the attributes of the users. The ACA
+// fetches the attributes of a user from its provider and populates them into
+// its database before issuing attribute certificates.
+type AttributeProvider interface {
+	// FetchAttributes returns the attributes of the user id of affiliation.
+	FetchAttributes(id, affiliation string) ([]*AttributePair, error)
+}
+
+// newAttributeProvider returns the provider configured by aca.provider.
+func newAttributeProvider(aca *ACA) (AttributeProvider, error) {
+	switch provider := viper.GetString("aca.provider"); provider {
+	case "", "file":
+		return &FileAttributeProvider{viper.GetString("aca.file")}, nil
+	case "sql":
+		return &SQLAttributeProvider{aca.db}, nil
+	case "ldap":
+		return newLDAPAttributeProvider(), nil
+	default:
+		return nil, fmt.Errorf("Unknown attribute provider %s.", provider)
+	}
+}
+
+// parseAttributeEntries returns the attributes of the user id of affiliation
+// among the entries in the format
+// {userid};{affiliation};{attributeName};{attributeValue};{valid from};{valid to}
+func parseAttributeEntries(entries map[string]string, id, affiliation string) ([]*AttributePair, error) {
+	var attributes = make([]*AttributePair, 0)
+	for _, flds := range entries {
+		vals := strings.Fields(flds)
+		if len(vals) >= 1 {
+			val := ""
+			for _, eachVal := range vals {
+				val = val + " " + eachVal
+			}
+			attributeVals := strings.Split(val, ";")
+			if len(attributeVals) >= 6 {
+				attrPair, err := NewAttributePair(attributeVals, nil)
+				if err != nil {
+					return nil, errors.New("Invalid attribute entry " + val + " " + err.Error())
+				}
+				if attrPair.GetID() != id || attrPair.GetAffiliation() != affiliation {
+					continue
+				}
+				attributes = append(attributes, attrPair)
+			} else {
+				Error.Printf("Invalid attribute entry '%v'", vals[0])
+			}
+		}
+	}
+	return attributes, nil
+}
+
+// FileAttributeProvider reads the attributes from the attributes section of a
+// YAML or JSON file, read again on each fetch so that it can be edited while
+// the ACA is running. Without a file the aca.attributes section of the
+// configuration is used.
+type FileAttributeProvider struct {
+	Path string
+}
+
+// FetchAttributes returns the attributes of the user id of affiliation.
+func (p *FileAttributeProvider) FetchAttributes(id, affiliation string) ([]*AttributePair, error) {
+	if p.Path == "" {
+		return parseAttributeEntries(viper.GetStringMapString("aca.attributes"), id, affiliation)
+	}
+
+	config := viper.New()
+	config.SetConfigFile(p.Path)
+	if err := config.ReadInConfig(); err != nil {
+		return nil, fmt.Errorf("Error reading attributes file %s: %s", p.Path, err)
+	}
+	return parseAttributeEntries(config.GetStringMapString("attributes"), id, affiliation)
+}
+
+// SQLAttributeProvider reads the attributes from the Attributes table of an
+// ACA database, such as the one maintained through the ACAA service.
+type SQLAttributeProvider struct {
+	DB *sql.DB
+}
+
+// FetchAttributes returns the attributes of the user id of affiliation.
+func (p *SQLAttributeProvider) FetchAttributes(id, affiliation string) ([]*AttributePair, error) {
+	rows, err := p.DB.Query("SELECT attributeName, attributeValue, validFrom, validTo FROM Attributes WHERE id=? AND affiliation=?", id, affiliation)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	owner := &AttributeOwner{id, affiliation}
+	var attributes = make([]*AttributePair, 0)
+	for rows.Next() {
+		attr := &AttributePair{owner: owner}
+		if err = rows.Scan(&attr.attributeName, &attr.attributeValue, &attr.validFrom, &attr.validTo); err != nil {
+			return nil, err
+		}
+		attributes = append(attributes, attr)
+	}
+	return attributes, rows.Err()
+}
+
+// LDAPAttributeProvider reads the attributes from the entry of the user in an
+// LDAP directory. The entry is searched under BaseDN by UserAttribute equal to
+// the enrollment ID and, if set, AffiliationAttribute equal to the
+// affiliation. Attributes maps the LDAP attributes to the names of the
+// attributes of the user; the first value of an LDAP attribute is used.
+//
+// The directory does not tell since when the values are valid, so they are
+// valid from the time they are fetched.
+type LDAPAttributeProvider struct {
+	Address              string
+	BindDN               string
+	Password             string
+	BaseDN               string
+	UserAttribute        string
+	AffiliationAttribute string
+	Attributes           map[string]string
+	Timeout              time.Duration
+}
+
+func newLDAPAttributeProvider() *LDAPAttributeProvider {
+	timeout := viper.GetDuration("aca.ldap.timeout")
+	if timeout == 0 {
+		timeout = 5 * time.Second
+	}
+	userAttribute := viper.GetString("aca.ldap.userattribute")
+	if userAttribute == "" {
+		userAttribute = "uid"
+	}
+	return &LDAPAttributeProvider{
+		Address:              viper.GetString("aca.ldap.address"),
+		BindDN:               viper.GetString("aca.ldap.binddn"),
+		Password:             viper.GetString("aca.ldap.password"),
+		BaseDN:               viper.GetString("aca.ldap.basedn"),
+		UserAttribute:        userAttribute,
+		AffiliationAttribute: viper.GetString("aca.ldap.affiliationattribute"),
+		Attributes:           viper.GetStringMapString("aca.ldap.attributes"),
+		Timeout:              timeout,
+	}
+}
+
+// FetchAttributes returns the attributes of the user id of affiliation.
+func (p *LDAPAttributeProvider) FetchAttributes(id, affiliation string) ([]*AttributePair, error) {
+	conn, err := dialLDAP(p.Address, p.Timeout)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.close()
+
+	if p.BindDN != "" {
+		if err = conn.bind(p.BindDN, p.Password); err != nil {
+			return nil, err
+		}
+	}
+
+	filter := ldapEqualityFilter(p.UserAttribute, id)
+	if p.AffiliationAttribute != "" {
+		filter = ldapAndFilter(filter, ldapEqualityFilter(p.AffiliationAttribute, affiliation))
+	}
+	var ldapAttributes []string
+	for ldapAttribute := range p.Attributes {
+		ldapAttributes = append(ldapAttributes, ldapAttribute)
+	}
+	sort.Strings(ldapAttributes)
+
+	entries, err := conn.search(p.BaseDN, filter, ldapAttributes)
+	if err != nil {
+		return nil, err
+	}
+	var attributes = make([]*AttributePair, 0)
+	if len(entries) == 0 {
+		return attributes, nil
+	}
+	if len(entries) > 1 {
+		return nil, fmt.Errorf("Found %d LDAP entries for user %s.", len(entries), id)
+	}
+
+	owner := &AttributeOwner{id, affiliation}
+	now := time.Now()
+	for _, ldapAttribute := range ldapAttributes {
+		values := entries[0].attributes[strings.ToLower(ldapAttribute)]
+		if len(values) == 0 {
+			continue
+		}
+		attributes = append(attributes, &AttributePair{owner: owner, attributeName: p.Attributes[ldapAttribute], attributeValue: []byte(values[0]), validFrom: now})
+	}
+	return attributes, nil
+}
@@ -0,0 +1,251 @@
+/*
+Copyright IBM Corp. 2016 All Rights Reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+		 http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ca
+
+import (
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestFileAttributeProvider(t *testing.T) {
+	dir, err := ioutil.TempDir("", "attributes")
+	if err != nil {
+		t.Fatalf("Error creating directory: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	files := map[string]string{
+		"attributes.yaml": "attributes:\n    entry-0: carol;bank_a;role;manager;2016-01-01T00:00:00Z;;\n    entry-1: dave;bank_a;role;client;2016-01-01T00:00:00Z;;\n",
+		"attributes.json": `{"attributes": {"entry-0": "carol;bank_a;role;manager;2016-01-01T00:00:00Z;;", "entry-1": "carol;bank_b;role;client;2016-01-01T00:00:00Z;;"}}`,
+	}
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		if err = ioutil.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatalf("Error writing %s: %v", name, err)
+		}
+		attrs, err := (&FileAttributeProvider{path}).FetchAttributes("carol", "bank_a")
+		if err != nil {
+			t.Fatalf("Error fetching attributes from %s: %v", name, err)
+		}
+		if len(attrs) != 1 || attrs[0].GetAttributeName() != "role" || string(attrs[0].GetAttributeValue()) != "manager" {
+			t.Fatalf("Expected the role of carol in %s but got %v", name, attrs)
+		}
+	}
+
+	if _, err = (&FileAttributeProvider{filepath.Join(dir, "missing.yaml")}).FetchAttributes("carol", "bank_a"); err == nil {
+		t.Fatal("Expected an error reading a missing file")
+	}
+
+	// Without a file the attributes of the configuration are used
+	attrs, err := (&FileAttributeProvider{}).FetchAttributes("test_user0", "bank_a")
+	if err != nil || len(attrs) != 4 {
+		t.Fatalf("Expected the 4 configured attributes of test_user0 but got %d (%v)", len(attrs), err)
+	}
+}
+
+func TestSQLAttributeProvider(t *testing.T) {
+	if err := aca.fetchAndPopulateAttributes("test_user0", "bank_a"); err != nil {
+		t.Fatalf("Error populating attributes: %v", err)
+	}
+
+	attrs, err := (&SQLAttributeProvider{aca.db}).FetchAttributes("test_user0", "bank_a")
+	if err != nil {
+		t.Fatalf("Error fetching attributes: %v", err)
+	}
+	values := make(map[string]string)
+	for _, attr := range attrs {
+		values[attr.GetAttributeName()] = string(attr.GetAttributeValue())
+	}
+	if len(values) != 3 || values["company"] != "ACompany" || values["business_unit"] != "Sales" {
+		t.Fatalf("Unexpected attributes %v", values)
+	}
+
+	if attrs, err = (&SQLAttributeProvider{aca.db}).FetchAttributes("test_user0", "bank_b"); err != nil || len(attrs) != 0 {
+		t.Fatalf("Expected no attributes in another affiliation but got %d (%v)", len(attrs), err)
+	}
+}
+
+// ldapStub is an in-process LDAP server answering simple binds and searches
+// with equality and and filters
+type ldapStub struct {
+	listener net.Listener
+	bindDN   string
+	password string
+	entries  []*ldapEntry
+}
+
+func startLDAPStub(t *testing.T, bindDN, password string, entries ...*ldapEntry) *ldapStub {
+	listener, err := net.Listen("tcp", "localhost:0")
+	if err != nil {
+		t.Fatalf("Error listening: %v", err)
+	}
+	stub := &ldapStub{listener, bindDN, password, entries}
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go stub.handle(conn)
+		}
+	}()
+	return stub
+}
+
+func ldapStubResult(tag byte, code int) []byte {
+	return berEncode(tag, berInteger(berTagEnumerated, code), berString(""), berString(""))
+}
+
+// matches returns whether entry matches filter
+func (s *ldapStub) matches(entry *ldapEntry, filter *berElement) bool {
+	children, _ := filter.children()
+	switch filter.tag {
+	case ldapTagFilterAnd:
+		for _, child := range children {
+			if !s.matches(entry, child) {
+				return false
+			}
+		}
+		return true
+	case ldapTagFilterEquality:
+		for name, values := range entry.attributes {
+			if strings.EqualFold(name, string(children[0].content)) {
+				for _, value := range values {
+					if value == string(children[1].content) {
+						return true
+					}
+				}
+			}
+		}
+	}
+	return false
+}
+
+func (s *ldapStub) handle(conn net.Conn) {
+	defer conn.Close()
+	for {
+		message, err := berRead(conn)
+		if err != nil {
+			return
+		}
+		fields, _ := message.children()
+		messageID, op := fields[0].int(), fields[1]
+		reply := func(op []byte) {
+			conn.Write(berEncode(berTagSequence, berInteger(berTagInteger, messageID), op))
+		}
+		request, _ := op.children()
+		switch op.tag {
+		case ldapTagBindRequest:
+			if string(request[1].content) == s.bindDN && string(request[2].content) == s.password {
+				reply(ldapStubResult(ldapTagBindResponse, 0))
+			} else {
+				reply(ldapStubResult(ldapTagBindResponse, 49)) // invalidCredentials
+			}
+		case ldapTagSearchRequest:
+			requested, _ := request[7].children()
+			for _, entry := range s.entries {
+				if !strings.HasSuffix(entry.dn, string(request[0].content)) || !s.matches(entry, request[6]) {
+					continue
+				}
+				var attributes []byte
+				for _, name := range requested {
+					for attribute, values := range entry.attributes {
+						if !strings.EqualFold(attribute, string(name.content)) {
+							continue
+						}
+						var encoded []byte
+						for _, value := range values {
+							encoded = append(encoded, berString(value)...)
+						}
+						attributes = append(attributes, berEncode(berTagSequence, berString(attribute), berEncode(berTagSet, encoded))...)
+					}
+				}
+				reply(berEncode(ldapTagSearchEntry, berString(entry.dn), berEncode(berTagSequence, attributes)))
+			}
+			reply(ldapStubResult(ldapTagSearchDone, 0))
+		default:
+			return
+		}
+	}
+}
+
+func TestLDAPAttributeProvider(t *testing.T) {
+	stub := startLDAPStub(t, "cn=admin,dc=example,dc=com", "secret",
+		&ldapEntry{"uid=carol,ou=bank_a,dc=example,dc=com", map[string][]string{"uid": {"carol"}, "ou": {"bank_a"}, "employeeType": {"manager", "client"}, "departmentNumber": {"42"}}},
+		&ldapEntry{"uid=carol,ou=bank_b,dc=example,dc=com", map[string][]string{"uid": {"carol"}, "ou": {"bank_b"}, "employeeType": {"client"}}},
+		&ldapEntry{"uid=dave,ou=bank_a,dc=example,dc=com", map[string][]string{"uid": {"dave"}, "ou": {"bank_a"}}})
+	defer stub.listener.Close()
+
+	provider := &LDAPAttributeProvider{
+		Address:              stub.listener.Addr().String(),
+		BindDN:               "cn=admin,dc=example,dc=com",
+		Password:             "secret",
+		BaseDN:               "dc=example,dc=com",
+		UserAttribute:        "uid",
+		AffiliationAttribute: "ou",
+		Attributes:           map[string]string{"employeetype": "role", "departmentnumber": "department"},
+		Timeout:              5 * time.Second,
+	}
+
+	before := time.Now()
+	attrs, err := provider.FetchAttributes("carol", "bank_a")
+	if err != nil {
+		t.Fatalf("Error fetching attributes: %v", err)
+	}
+	if len(attrs) != 2 || attrs[0].GetAttributeName() != "department" || string(attrs[0].GetAttributeValue()) != "42" ||
+		attrs[1].GetAttributeName() != "role" || string(attrs[1].GetAttributeValue()) != "manager" {
+		t.Fatalf("Unexpected attributes %v", attrs)
+	}
+	if attrs[1].GetValidFrom().Before(before) || !attrs[1].GetValidTo().IsZero() {
+		t.Fatalf("Expected the attributes to be valid from now on but got %v to %v", attrs[1].GetValidFrom(), attrs[1].GetValidTo())
+	}
+
+	if attrs, err = provider.FetchAttributes("dave", "bank_a"); err != nil || len(attrs) != 0 {
+		t.Fatalf("Expected no attributes for dave but got %d (%v)", len(attrs), err)
+	}
+	if attrs, err = provider.FetchAttributes("erin", "bank_a"); err != nil || len(attrs) != 0 {
+		t.Fatalf("Expected no attributes for an unknown user but got %d (%v)", len(attrs), err)
+	}
+
+	provider.AffiliationAttribute = ""
+	if _, err = provider.FetchAttributes("carol", "bank_a"); err == nil {
+		t.Fatal("Expected an error for a user with several entries")
+	}
+
+	provider.Password = "wrong"
+	if _, err = provider.FetchAttributes("dave", "bank_a"); err == nil {
+		t.Fatal("Expected an error binding with a wrong password")
+	}
+
+	// The ACA populates its database from the directory
+	provider.Password = "secret"
+	provider.AffiliationAttribute = "ou"
+	defer aca.SetAttributeProvider(aca.provider)
+	aca.SetAttributeProvider(provider)
+	if err = aca.fetchAndPopulateAttributes("carol", "bank_a"); err != nil {
+		t.Fatalf("Error populating attributes: %v", err)
+	}
+	attr, err := aca.findAttribute(&AttributeOwner{"carol", "bank_a"}, "role")
+	if err != nil || attr == nil || string(attr.GetAttributeValue()) != "manager" {
+		t.Fatalf("Expected the role of carol in the ACA database but got %v (%v)", attr, err)
+	}
+}
@@ -22,6 +22,7 @@ import (
 	"crypto/x509"
 	"crypto/x509/pkix"
 	"database/sql"
+	"encoding/asn1"
 	"encoding/json"
 	"encoding/pem"
 	"errors"
@@ -34,6 +35,7 @@ import (
 	"time"
 
 	"github.com/hyperledger/fabric/core/crypto/primitives"
+	"github.com/hyperledger/fabric/core/util"
 	pb "github.com/hyperledger/fabric/membersrvc/protos"
 
 	_ "github.com/mattn/go-sqlite3" // This blank import is required to load sqlite3 driver
@@ -45,10 +47,13 @@ type CA struct {
 	db *sql.DB
 
 	path string
+	name string
 
 	priv *ecdsa.PrivateKey
 	cert *x509.Certificate
 	raw  []byte
+
+	crl []byte
 }
 
 // CertificateSpec defines the parameter used to create a new certificate.
@@ -73,6 +78,15 @@ type AffiliationGroup struct {
 
 var (
 	mutex = &sync.Mutex{}
+
+	// CRLReasonCode is the ASN1 object identifier of the CRL entry reason code extension.
+	CRLReasonCode = asn1.ObjectIdentifier{2, 5, 29, 21}
+
+	// ErrCertificateNotFound is returned when a certificate to be revoked was not issued by the CA.
+	ErrCertificateNotFound = errors.New("Certificate not found.")
+
+	// ErrCertificateRevoked is returned when a certificate has already been revoked.
+	ErrCertificateRevoked = errors.New("Certificate has already been revoked.")
 )
 
 // NewCertificateSpec creates a new certificate spec
@@ -193,7 +207,10 @@ func (spec *CertificateSpec) GetExtensions() *[]pkix.Extension {
 type TableInitializer func(*sql.DB) error
 
 func initializeCommonTables(db *sql.DB) error {
-	if _, err := db.Exec("CREATE TABLE IF NOT EXISTS Certificates (row INTEGER PRIMARY KEY, id VARCHAR(64), timestamp INTEGER, usage INTEGER, cert BLOB, hash BLOB, kdfkey BLOB)"); err != nil {
+	if _, err := db.Exec("CREATE TABLE IF NOT EXISTS Certificates (row INTEGER PRIMARY KEY, id VARCHAR(64), timestamp INTEGER, usage INTEGER, cert BLOB, hash BLOB, kdfkey BLOB, revoked INTEGER DEFAULT 0, reason INTEGER DEFAULT 0, revocationTime INTEGER DEFAULT 0)"); err != nil {
+		return err
+	}
+	if err := addRevocationColumns(db); err != nil {
 		return err
 	}
 	if _, err := db.Exec("CREATE TABLE IF NOT EXISTS Users (row INTEGER PRIMARY KEY, id VARCHAR(64), enrollmentId VARCHAR(100), role INTEGER, metadata VARCHAR(256), token BLOB, state INTEGER, key BLOB)"); err != nil {
@@ -205,9 +222,41 @@ func initializeCommonTables(db *sql.DB) error {
 	return nil
 }
 
+// addRevocationColumns upgrades a Certificates table created before revocation
+// was supported.
+func addRevocationColumns(db *sql.DB) error {
+	rows, err := db.Query("PRAGMA table_info(Certificates)")
+	if err != nil {
+		return err
+	}
+	columns := make(map[string]bool)
+	for rows.Next() {
+		var cid, notNull, pk int
+		var name, ctype string
+		var dflt sql.NullString
+		if err = rows.Scan(&cid, &name, &ctype, &notNull, &dflt, &pk); err != nil {
+			rows.Close()
+			return err
+		}
+		columns[name] = true
+	}
+	rows.Close()
+
+	for _, column := range []string{"revoked", "reason", "revocationTime"} {
+		if columns[column] {
+			continue
+		}
+		if _, err = db.Exec("ALTER TABLE Certificates ADD COLUMN " + column + " INTEGER DEFAULT 0"); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 // NewCA sets up a new CA.
 func NewCA(name string, initTables TableInitializer) *CA {
 	ca := new(CA)
+	ca.name = name
 	ca.path = viper.GetString("server.rootpath") + "/" + viper.GetString("server.cadir")
 
 	if _, err := os.Stat(ca.path); err != nil {
@@ -343,7 +392,7 @@ func (ca *CA) readCACertificate(name string) ([]byte, error) {
 }
 
 func (ca *CA) createCertificate(id string, pub interface{}, usage x509.KeyUsage, timestamp int64, kdfKey []byte, opt ...pkix.Extension) ([]byte, error) {
-	spec := NewDefaultCertificateSpec(id, pub, usage, opt...)
+	spec := NewDefaultPeriodCertificateSpec(id, util.GenerateIntUUID(), pub, usage, opt...)
 	return ca.createCertificateFromSpec(spec, timestamp, kdfKey, true)
 }
 
@@ -477,6 +526,203 @@ func (ca *CA) readCertificateByHash(hash []byte) ([]byte, error) {
 	return raw, err
 }
 
+// revokeCertificate marks a certificate issued by the CA as revoked.  If id is
+// not empty the certificate must belong to id.
+//
+func (ca *CA) revokeCertificate(id string, certRaw []byte, reason pb.CRLReason) error {
+	mutex.Lock()
+	defer mutex.Unlock()
+
+	Trace.Printf("Revoking certificate for %s with reason %s", id, reason)
+
+	hash := primitives.NewHash()
+	hash.Write(certRaw)
+	certHash := hash.Sum(nil)
+
+	var owner string
+	var revoked int
+	err := ca.db.QueryRow("SELECT id, revoked FROM Certificates WHERE hash=?", certHash).Scan(&owner, &revoked)
+	if err == sql.ErrNoRows || (err == nil && id != "" && owner != id) {
+		return ErrCertificateNotFound
+	}
+	if err != nil {
+		Error.Println(err)
+		return err
+	}
+	if revoked != 0 {
+		return ErrCertificateRevoked
+	}
+
+	_, err = ca.db.Exec("UPDATE Certificates SET revoked=1, reason=?, revocationTime=? WHERE hash=?", reason, time.Now().UnixNano(), certHash)
+	if err != nil {
+		Error.Println(err)
+	}
+	return err
+}
+
+// revokeCertificates marks every certificate of id issued at timestamp as
+// revoked.  A timestamp of 0 selects the latest certificates issued to id and
+// a negative timestamp selects all of them.  It returns the number of newly
+// revoked certificates.
+//
+func (ca *CA) revokeCertificates(id string, timestamp int64, reason pb.CRLReason) (int64, error) {
+	mutex.Lock()
+	defer mutex.Unlock()
+
+	Trace.Printf("Revoking certificates for %s issued at %d with reason %s", id, timestamp, reason)
+
+	if timestamp == 0 {
+		var latest sql.NullInt64
+		if err := ca.db.QueryRow("SELECT MAX(timestamp) FROM Certificates WHERE id=?", id).Scan(&latest); err != nil {
+			Error.Println(err)
+			return 0, err
+		}
+		if !latest.Valid {
+			return 0, ErrCertificateNotFound
+		}
+		timestamp = latest.Int64
+	}
+
+	var res sql.Result
+	var err error
+	if timestamp < 0 {
+		res, err = ca.db.Exec("UPDATE Certificates SET revoked=1, reason=?, revocationTime=? WHERE id=? AND revoked=0", reason, time.Now().UnixNano(), id)
+	} else {
+		res, err = ca.db.Exec("UPDATE Certificates SET revoked=1, reason=?, revocationTime=? WHERE id=? AND timestamp=? AND revoked=0", reason, time.Now().UnixNano(), id, timestamp)
+	}
+	if err != nil {
+		Error.Println(err)
+		return 0, err
+	}
+	return res.RowsAffected()
+}
+
+// readCertificateTimestamp returns the timestamp of a certificate issued to id.
+//
+func (ca *CA) readCertificateTimestamp(id string, certRaw []byte) (int64, error) {
+	hash := primitives.NewHash()
+	hash.Write(certRaw)
+
+	var ts int64
+	err := ca.db.QueryRow("SELECT timestamp FROM Certificates WHERE id=? AND hash=?", id, hash.Sum(nil)).Scan(&ts)
+	if err == sql.ErrNoRows {
+		return 0, ErrCertificateNotFound
+	}
+
+	return ts, err
+}
+
+// isRevoked returns true if the given certificate has been revoked by the CA.
+//
+func (ca *CA) isRevoked(certRaw []byte) bool {
+	hash := primitives.NewHash()
+	hash.Write(certRaw)
+
+	var revoked int
+	ca.db.QueryRow("SELECT revoked FROM Certificates WHERE hash=?", hash.Sum(nil)).Scan(&revoked)
+
+	return revoked != 0
+}
+
+// createCRL creates a DER encoded X.509 certificate revocation list, signed by
+// the CA, of all certificates it has revoked.
+//
+func (ca *CA) createCRL() ([]byte, error) {
+	Trace.Println("Creating CRL for " + ca.name + ".")
+
+	rows, err := ca.db.Query("SELECT cert, reason, revocationTime FROM Certificates WHERE revoked=1 ORDER BY revocationTime")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var revoked []pkix.RevokedCertificate
+	for rows.Next() {
+		var raw []byte
+		var reason int
+		var revocationTime int64
+		if err = rows.Scan(&raw, &reason, &revocationTime); err != nil {
+			return nil, err
+		}
+		cert, err := x509.ParseCertificate(raw)
+		if err != nil {
+			return nil, err
+		}
+
+		entry := pkix.RevokedCertificate{SerialNumber: cert.SerialNumber, RevocationTime: time.Unix(0, revocationTime).UTC()}
+		if reason != int(pb.CRLReason_UNSPECIFIED) {
+			value, err := asn1.Marshal(asn1.Enumerated(reason))
+			if err != nil {
+				return nil, err
+			}
+			entry.Extensions = []pkix.Extension{{Id: CRLReasonCode, Value: value}}
+		}
+		revoked = append(revoked, entry)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	validity := viper.GetDuration("pki.crl.validity")
+	if validity <= 0 {
+		validity = 24 * time.Hour
+	}
+	now := time.Now().UTC()
+
+	return ca.cert.CreateCRL(rand.Reader, ca.priv, revoked, now, now.Add(validity))
+}
+
+// publishCRL creates a new certificate revocation list and stores it as the
+// CA's current CRL.
+//
+func (ca *CA) publishCRL() ([]byte, error) {
+	raw, err := ca.createCRL()
+	if err != nil {
+		Error.Println(err)
+		return nil, err
+	}
+
+	cooked := pem.EncodeToMemory(
+		&pem.Block{
+			Type:  "X509 CRL",
+			Bytes: raw,
+		})
+	if err = ioutil.WriteFile(ca.path+"/"+ca.name+".crl", cooked, 0644); err != nil {
+		Error.Println(err)
+		return nil, err
+	}
+
+	mutex.Lock()
+	ca.crl = raw
+	mutex.Unlock()
+
+	return raw, nil
+}
+
+// readCRL returns the CA's current certificate revocation list, publishing
+// one if none exists yet.
+//
+func (ca *CA) readCRL() ([]byte, error) {
+	mutex.Lock()
+	raw := ca.crl
+	mutex.Unlock()
+	if raw != nil {
+		return raw, nil
+	}
+
+	cooked, err := ioutil.ReadFile(ca.path + "/" + ca.name + ".crl")
+	if err == nil {
+		if block, _ := pem.Decode(cooked); block != nil {
+			mutex.Lock()
+			ca.crl = block.Bytes
+			mutex.Unlock()
+			return block.Bytes, nil
+		}
+	}
+
+	return ca.publishCRL()
+}
+
 func (ca *CA) isValidAffiliation(affiliation string) (bool, error) {
 	Trace.Println("Validating affiliation: " + affiliation)
 
@@ -40,6 +40,7 @@ import (
 
 	"github.com/golang/protobuf/proto"
 	"github.com/hyperledger/fabric/core/crypto/primitives"
+	"github.com/hyperledger/fabric/core/util"
 	pb "github.com/hyperledger/fabric/membersrvc/protos"
 	"github.com/spf13/viper"
 	"golang.org/x/net/context"
@@ -206,6 +207,7 @@ func (eca *ECA) populateAffiliationGroupsTable() {
 // Start starts the ECA.
 //
 func (eca *ECA) Start(srv *grpc.Server) {
+
 	eca.startECAP(srv)
 	eca.startECAA(srv)
 
@@ -357,14 +359,14 @@ func (ecap *ECAP) CreateCertificatePair(ctx context.Context, in *pb.ECertCreateR
 		// create new certificate pair
 		ts := time.Now().Add(-1 * time.Minute).UnixNano()
 
-		spec := NewDefaultCertificateSpecWithCommonName(id, enrollID, skey.(*ecdsa.PublicKey), x509.KeyUsageDigitalSignature, pkix.Extension{Id: ECertSubjectRole, Critical: true, Value: []byte(strconv.Itoa(ecap.eca.readRole(id)))})
+		spec := NewDefaultPeriodCertificateSpecWithCommonName(id, enrollID, util.GenerateIntUUID(), skey.(*ecdsa.PublicKey), x509.KeyUsageDigitalSignature, pkix.Extension{Id: ECertSubjectRole, Critical: true, Value: []byte(strconv.Itoa(ecap.eca.readRole(id)))})
 		sraw, err := ecap.eca.createCertificateFromSpec(spec, ts, nil, true)
 		if err != nil {
 			Error.Println(err)
 			return nil, err
 		}
 
-		spec = NewDefaultCertificateSpecWithCommonName(id, enrollID, ekey.(*ecdsa.PublicKey), x509.KeyUsageDataEncipherment, pkix.Extension{Id: ECertSubjectRole, Critical: true, Value: []byte(strconv.Itoa(ecap.eca.readRole(id)))})
+		spec = NewDefaultPeriodCertificateSpecWithCommonName(id, enrollID, util.GenerateIntUUID(), ekey.(*ecdsa.PublicKey), x509.KeyUsageDataEncipherment, pkix.Extension{Id: ECertSubjectRole, Critical: true, Value: []byte(strconv.Itoa(ecap.eca.readRole(id)))})
 		eraw, err := ecap.eca.createCertificateFromSpec(spec, ts, nil, true)
 		if err != nil {
 			ecap.eca.db.Exec("DELETE FROM Certificates Where id=?", id)
@@ -415,8 +417,8 @@ func (ecap *ECAP) ReadCertificatePair(ctx context.Context, in *pb.ECertReadReq)
 	if err == nil {
 		for rows.Next() {
 			hasResults = true
-			var raw []byte
-			err = rows.Scan(&raw)
+			var raw, kdfKey []byte
+			err = rows.Scan(&raw, &kdfKey)
 			certs = append(certs, raw)
 		}
 		err = rows.Err()
@@ -437,12 +439,102 @@ func (ecap *ECAP) ReadCertificateByHash(ctx context.Context, hash *pb.Hash) (*pb
 	return &pb.Cert{Cert: raw}, err
 }
 
-// RevokeCertificatePair revokes a certificate pair from the ECA.  Not yet implemented.
+// checkSignature verifies that msg has been signed with the enrollment key of
+// the (unrevoked) enrollment certificate of id.
 //
-func (ecap *ECAP) RevokeCertificatePair(context.Context, *pb.ECertRevokeReq) (*pb.CAStatus, error) {
+func (eca *ECA) checkSignature(id string, msg proto.Message, sig *pb.Signature) error {
+	if sig == nil {
+		return errors.New("Signature missing.")
+	}
+
+	raw, err := eca.readCertificateByKeyUsage(id, x509.KeyUsageDigitalSignature)
+	if err != nil {
+		return err
+	}
+	if eca.isRevoked(raw) {
+		return errors.New("Enrollment certificate has been revoked.")
+	}
+	cert, err := x509.ParseCertificate(raw)
+	if err != nil {
+		return err
+	}
+
+	r, s := big.NewInt(0), big.NewInt(0)
+	r.UnmarshalText(sig.R)
+	s.UnmarshalText(sig.S)
+
+	hash := primitives.NewHash()
+	raw, _ = proto.Marshal(msg)
+	hash.Write(raw)
+	if ecdsa.Verify(cert.PublicKey.(*ecdsa.PublicKey), hash.Sum(nil), r, s) == false {
+		return errors.New("Signature verification failed.")
+	}
+
+	return nil
+}
+
+// checkAdmin verifies that id is allowed to use the administrator services of the CAs.
+//
+func (eca *ECA) checkAdmin(id string) error {
+	if eca.readRole(id)&int(pb.Role_AUDITOR) == 0 {
+		return errors.New("Access denied.")
+	}
+
+	return nil
+}
+
+// RevokeCertificatePair revokes a certificate pair from the ECA.  The pair is
+// selected by either of its certificates, or the latest pair if no certificate
+// is given.  A user can only revoke his/her own certificate pair.
+//
+func (ecap *ECAP) RevokeCertificatePair(ctx context.Context, in *pb.ECertRevokeReq) (*pb.CAStatus, error) {
 	Trace.Println("gRPC ECAP:RevokeCertificate")
 
-	return nil, errors.New("ECAP:RevokeCertificate method not (yet) implemented")
+	if in.Id == nil {
+		return nil, errors.New("Identity missing.")
+	}
+	id := in.Id.Id
+
+	sig := in.Sig
+	in.Sig = nil
+	if err := ecap.eca.checkSignature(id, in, sig); err != nil {
+		return nil, err
+	}
+
+	var ts int64
+	if in.Cert != nil && len(in.Cert.Cert) > 0 {
+		var err error
+		if ts, err = ecap.eca.readCertificateTimestamp(id, in.Cert.Cert); err != nil {
+			return nil, err
+		}
+	}
+
+	n, err := ecap.eca.revokeCertificates(id, ts, in.Reason)
+	if err != nil {
+		return nil, err
+	}
+	if n == 0 {
+		return nil, ErrCertificateRevoked
+	}
+
+	if _, err = ecap.eca.publishCRL(); err != nil {
+		return nil, err
+	}
+
+	return &pb.CAStatus{Status: pb.CAStatus_OK}, nil
+}
+
+// ReadCRL returns the latest certificate revocation list published by the ECA.
+//
+func (ecap *ECAP) ReadCRL(ctx context.Context, in *pb.Empty) (*pb.CRL, error) {
+	Trace.Println("gRPC ECAP:ReadCRL")
+
+	raw, err := ecap.eca.readCRL()
+	if err != nil {
+		return nil, err
+	}
+
+	return &pb.CRL{Crl: raw}, nil
 }
 
 // RegisterUser registers a new user with the ECA.  If the user had been registered before
@@ -575,18 +667,58 @@ func (ecaa *ECAA) ReadUserSet(ctx context.Context, in *pb.ReadUserSetReq) (*pb.U
 	return &pb.UserSet{Users: users}, err
 }
 
-// RevokeCertificate revokes a certificate from the ECA.  Not yet implemented.
+// RevokeCertificate revokes a certificate from the ECA.  An admin can revoke any certificate.
 //
-func (ecaa *ECAA) RevokeCertificate(context.Context, *pb.ECertRevokeReq) (*pb.CAStatus, error) {
+func (ecaa *ECAA) RevokeCertificate(ctx context.Context, in *pb.ECertRevokeReq) (*pb.CAStatus, error) {
 	Trace.Println("gRPC ECAA:RevokeCertificate")
 
-	return nil, errors.New("ECAA:RevokeCertificate method not (yet) implemented")
+	if in.Id == nil || in.Cert == nil {
+		return nil, errors.New("Identity or certificate missing.")
+	}
+	id := in.Id.Id
+	if err := ecaa.eca.checkAdmin(id); err != nil {
+		return nil, err
+	}
+
+	sig := in.Sig
+	in.Sig = nil
+	if err := ecaa.eca.checkSignature(id, in, sig); err != nil {
+		return nil, err
+	}
+
+	if err := ecaa.eca.revokeCertificate("", in.Cert.Cert, in.Reason); err != nil {
+		return nil, err
+	}
+
+	if _, err := ecaa.eca.publishCRL(); err != nil {
+		return nil, err
+	}
+
+	return &pb.CAStatus{Status: pb.CAStatus_OK}, nil
 }
 
-// PublishCRL requests the creation of a certificate revocation list from the ECA.  Not yet implemented.
+// PublishCRL requests the creation of a certificate revocation list from the ECA.
 //
-func (ecaa *ECAA) PublishCRL(context.Context, *pb.ECertCRLReq) (*pb.CAStatus, error) {
+func (ecaa *ECAA) PublishCRL(ctx context.Context, in *pb.ECertCRLReq) (*pb.CAStatus, error) {
 	Trace.Println("gRPC ECAA:CreateCRL")
 
-	return nil, errors.New("ECAA:PublishCRL method not (yet) implemented")
+	if in.Id == nil {
+		return nil, errors.New("Identity missing.")
+	}
+	id := in.Id.Id
+	if err := ecaa.eca.checkAdmin(id); err != nil {
+		return nil, err
+	}
+
+	sig := in.Sig
+	in.Sig = nil
+	if err := ecaa.eca.checkSignature(id, in, sig); err != nil {
+		return nil, err
+	}
+
+	if _, err := ecaa.eca.publishCRL(); err != nil {
+		return nil, err
+	}
+
+	return &pb.CAStatus{Status: pb.CAStatus_OK}, nil
 }
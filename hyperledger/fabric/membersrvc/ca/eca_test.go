@@ -20,6 +20,7 @@ import (
 	"crypto/ecdsa"
 	"crypto/rand"
 	"crypto/x509"
+	"crypto/x509/pkix"
 	"errors"
 	"os"
 	"testing"
@@ -466,13 +467,97 @@ func TestCreateCertificatePairBadToken(t *testing.T) {
 	}
 }
 
+func signRequest(priv *ecdsa.PrivateKey, msg proto.Message) (*pb.Signature, error) {
+	hash := primitives.NewHash()
+	raw, _ := proto.Marshal(msg)
+	hash.Write(raw)
+
+	r, s, err := ecdsa.Sign(rand.Reader, priv, hash.Sum(nil))
+	if err != nil {
+		return nil, err
+	}
+	R, _ := r.MarshalText()
+	S, _ := s.MarshalText()
+	return &pb.Signature{Type: pb.CryptoType_ECDSA, R: R, S: S}, nil
+}
+
+// isInCRL checks the signature of the CRL published by ca and returns the
+// revocation entry of cert if it is listed.
+func isInCRL(t *testing.T, ca *CA, cert []byte) (*pkix.RevokedCertificate, bool) {
+	raw, err := ca.readCRL()
+	if err != nil {
+		t.Fatalf("Failed reading CRL [%s]", err)
+	}
+	crl, err := x509.ParseCRL(raw)
+	if err != nil {
+		t.Fatalf("Failed parsing CRL [%s]", err)
+	}
+	if err = ca.cert.CheckCRLSignature(crl); err != nil {
+		t.Fatalf("Invalid CRL signature [%s]", err)
+	}
+
+	x509Cert, err := x509.ParseCertificate(cert)
+	if err != nil {
+		t.Fatalf("Failed parsing certificate [%s]", err)
+	}
+	for i, revoked := range crl.TBSCertList.RevokedCertificates {
+		if revoked.SerialNumber.Cmp(x509Cert.SerialNumber) == 0 {
+			return &crl.TBSCertList.RevokedCertificates[i], true
+		}
+	}
+	return nil, false
+}
+
 func TestRevokeCertificatePair(t *testing.T) {
 
 	ecap := &ECAP{eca}
 
-	_, err := ecap.RevokeCertificatePair(context.Background(), &pb.ECertRevokeReq{})
-	if err.Error() != "ECAP:RevokeCertificate method not (yet) implemented" {
-		t.Fatalf("Expected error was not returned: [%s]", err.Error())
+	user := User{enrollID: "test_user3", enrollPwd: []byte("vWdLCE00vJy0")}
+	if err := enrollUser(&user); err != nil {
+		t.Fatalf("Failed enrolling user [%s]", err)
+	}
+	certs, err := ecap.ReadCertificatePair(context.Background(), &pb.ECertReadReq{Id: &pb.Identity{Id: user.enrollID}})
+	if err != nil {
+		t.Fatalf("Failed reading certificate pair [%s]", err)
+	}
+
+	req := &pb.ECertRevokeReq{Id: &pb.Identity{Id: user.enrollID}, Cert: &pb.Cert{Cert: certs.Sign}, Reason: pb.CRLReason_KEY_COMPROMISE}
+	req.Sig, err = signRequest(testUser.enrollPrivKey, req)
+	if err != nil {
+		t.Fatalf("Failed (ECDSA) signing [%s]", err)
+	}
+	if _, err = ecap.RevokeCertificatePair(context.Background(), req); err == nil {
+		t.Fatal("A request signed by another user should have been rejected")
+	}
+
+	req.Sig, err = signRequest(user.enrollPrivKey, req)
+	if err != nil {
+		t.Fatalf("Failed (ECDSA) signing [%s]", err)
+	}
+	status, err := ecap.RevokeCertificatePair(context.Background(), req)
+	if err != nil {
+		t.Fatalf("Failed revoking certificate pair [%s]", err)
+	}
+	if status.Status != pb.CAStatus_OK {
+		t.Fatalf("Unexpected status [%s]", status.Status)
+	}
+
+	for _, cert := range [][]byte{certs.Sign, certs.Enc} {
+		if !eca.isRevoked(cert) {
+			t.Fatal("Certificate should have been revoked")
+		}
+		revoked, ok := isInCRL(t, eca.CA, cert)
+		if !ok {
+			t.Fatal("Revoked certificate is missing from the CRL")
+		}
+		if len(revoked.Extensions) != 1 || !revoked.Extensions[0].Id.Equal(CRLReasonCode) {
+			t.Fatal("Revocation reason is missing from the CRL")
+		}
+	}
+
+	req.Sig, _ = signRequest(user.enrollPrivKey, req)
+	if _, err = ecap.RevokeCertificatePair(context.Background(), req); err == nil {
+		t.Fatal("Revoked users should not be able to revoke certificates")
 	}
 }
 
@@ -480,17 +565,56 @@ func TestRevokeCertificate(t *testing.T) {
 
 	ecaa := &ECAA{eca}
 
-	_, err := ecaa.RevokeCertificate(context.Background(), &pb.ECertRevokeReq{})
-	if err.Error() != "ECAA:RevokeCertificate method not (yet) implemented" {
-		t.Fatalf("Expected error was not returned: [%s]", err.Error())
+	user := User{enrollID: "test_user4", enrollPwd: []byte("4nXSrfoYGFCP")}
+	if err := enrollUser(&user); err != nil {
+		t.Fatalf("Failed enrolling user [%s]", err)
+	}
+	cert, err := eca.readCertificateByKeyUsage(user.enrollID, x509.KeyUsageDigitalSignature)
+	if err != nil {
+		t.Fatalf("Failed reading certificate [%s]", err)
+	}
+
+	req := &pb.ECertRevokeReq{Id: &pb.Identity{Id: testUser.enrollID}, Cert: &pb.Cert{Cert: cert}}
+	req.Sig, _ = signRequest(testUser.enrollPrivKey, req)
+	if _, err = ecaa.RevokeCertificate(context.Background(), req); err == nil {
+		t.Fatal("Only admins should be able to call RevokeCertificate")
+	}
+
+	req = &pb.ECertRevokeReq{Id: &pb.Identity{Id: testAuditor.enrollID}, Cert: &pb.Cert{Cert: cert}, Reason: pb.CRLReason_AFFILIATION_CHANGED}
+	req.Sig, _ = signRequest(testAuditor.enrollPrivKey, req)
+	if _, err = ecaa.RevokeCertificate(context.Background(), req); err != nil {
+		t.Fatalf("Failed revoking certificate [%s]", err)
+	}
+	if _, ok := isInCRL(t, eca.CA, cert); !ok {
+		t.Fatal("Revoked certificate is missing from the CRL")
+	}
+
+	req.Sig, _ = signRequest(testAuditor.enrollPrivKey, req)
+	if _, err = ecaa.RevokeCertificate(context.Background(), req); err != ErrCertificateRevoked {
+		t.Fatalf("Expected error was not returned: [%v]", err)
 	}
 }
 
 func TestPublishCRL(t *testing.T) {
 	ecaa := &ECAA{eca}
 
-	_, err := ecaa.PublishCRL(context.Background(), &pb.ECertCRLReq{})
-	if err.Error() != "ECAA:PublishCRL method not (yet) implemented" {
-		t.Fatalf("Expected error was not returned: [%s]", err.Error())
+	req := &pb.ECertCRLReq{Id: &pb.Identity{Id: testUser.enrollID}}
+	req.Sig, _ = signRequest(testUser.enrollPrivKey, req)
+	if _, err := ecaa.PublishCRL(context.Background(), req); err == nil {
+		t.Fatal("Only admins should be able to call PublishCRL")
+	}
+
+	req = &pb.ECertCRLReq{Id: &pb.Identity{Id: testAuditor.enrollID}}
+	req.Sig, _ = signRequest(testAuditor.enrollPrivKey, req)
+	if _, err := ecaa.PublishCRL(context.Background(), req); err != nil {
+		t.Fatalf("Failed publishing CRL [%s]", err)
+	}
+
+	crl, err := (&ECAP{eca}).ReadCRL(context.Background(), &pb.Empty{})
+	if err != nil {
+		t.Fatalf("Failed reading CRL [%s]", err)
+	}
+	if _, err = x509.ParseCRL(crl.Crl); err != nil {
+		t.Fatalf("Failed parsing CRL [%s]", err)
 	}
 }
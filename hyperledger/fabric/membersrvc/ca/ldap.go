@@ -0,0 +1,316 @@
+/*
+Copyright IBM Corp. 2016 All Rights Reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+		 http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ca
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strings"
+	"time"
+)
+
+// A minimal LDAPv3 client (RFC 4511): just enough to bind and search for the
+// entry of a user.
+
+const (
+	berTagBoolean     = 0x01
+	berTagInteger     = 0x02
+	berTagOctetString = 0x04
+	berTagEnumerated  = 0x0a
+	berTagSequence    = 0x30
+	berTagSet         = 0x31
+
+	ldapTagBindRequest     = 0x60
+	ldapTagBindResponse    = 0x61
+	ldapTagUnbindRequest   = 0x42
+	ldapTagSearchRequest   = 0x63
+	ldapTagSearchEntry     = 0x64
+	ldapTagSearchDone      = 0x65
+	ldapTagSearchReference = 0x73
+	ldapTagSimpleAuth      = 0x80
+	ldapTagFilterAnd       = 0xa0
+	ldapTagFilterEquality  = 0xa3
+
+	ldapScopeWholeSubtree = 2
+
+	// berMaxLength bounds the length of the elements read from the directory
+	berMaxLength = 1 << 24
+)
+
+// berElement is a BER encoded element: its tag and its content, which is a
+// sequence of elements for the constructed ones.
+type berElement struct {
+	tag     byte
+	content []byte
+}
+
+func berLength(n int) []byte {
+	if n < 0x80 {
+		return []byte{byte(n)}
+	}
+	var b []byte
+	for ; n > 0; n >>= 8 {
+		b = append([]byte{byte(n)}, b...)
+	}
+	return append([]byte{0x80 | byte(len(b))}, b...)
+}
+
+// berEncode encodes the element tag with the concatenation of contents
+func berEncode(tag byte, contents ...[]byte) []byte {
+	var content []byte
+	for _, c := range contents {
+		content = append(content, c...)
+	}
+	return append(append([]byte{tag}, berLength(len(content))...), content...)
+}
+
+func berInteger(tag byte, v int) []byte {
+	var b []byte
+	for {
+		b = append([]byte{byte(v)}, b...)
+		if v >>= 8; v == 0 {
+			break
+		}
+	}
+	if b[0]&0x80 != 0 {
+		b = append([]byte{0}, b...)
+	}
+	return berEncode(tag, b)
+}
+
+func berString(s string) []byte {
+	return berEncode(berTagOctetString, []byte(s))
+}
+
+// berRead reads an element in definite length form
+func berRead(r io.Reader) (*berElement, error) {
+	header := make([]byte, 2)
+	if _, err := io.ReadFull(r, header); err != nil {
+		return nil, err
+	}
+	n := int(header[1])
+	if n&0x80 != 0 {
+		size := n & 0x7f
+		if size == 0 || size > 4 {
+			return nil, errors.New("Unsupported BER length.")
+		}
+		b := make([]byte, size)
+		if _, err := io.ReadFull(r, b); err != nil {
+			return nil, err
+		}
+		n = 0
+		for _, c := range b {
+			n = n<<8 | int(c)
+		}
+	}
+	if n > berMaxLength {
+		return nil, fmt.Errorf("BER element too long (%d bytes).", n)
+	}
+	content := make([]byte, n)
+	if _, err := io.ReadFull(r, content); err != nil {
+		return nil, err
+	}
+	return &berElement{header[0], content}, nil
+}
+
+// children decodes the content of a constructed element
+func (e *berElement) children() ([]*berElement, error) {
+	var children []*berElement
+	r := bytes.NewReader(e.content)
+	for r.Len() > 0 {
+		child, err := berRead(r)
+		if err != nil {
+			return nil, err
+		}
+		children = append(children, child)
+	}
+	return children, nil
+}
+
+func (e *berElement) int() int {
+	v := 0
+	for _, c := range e.content {
+		v = v<<8 | int(c)
+	}
+	return v
+}
+
+// ldapEqualityFilter returns the filter (attribute=value)
+func ldapEqualityFilter(attribute, value string) []byte {
+	return berEncode(ldapTagFilterEquality, berString(attribute), berString(value))
+}
+
+// ldapAndFilter returns the filter (&filters...)
+func ldapAndFilter(filters ...[]byte) []byte {
+	return berEncode(ldapTagFilterAnd, filters...)
+}
+
+// ldapEntry is an entry returned by a search, its attribute names in lower case
+type ldapEntry struct {
+	dn         string
+	attributes map[string][]string
+}
+
+type ldapConn struct {
+	conn      net.Conn
+	messageID int
+}
+
+func dialLDAP(address string, timeout time.Duration) (*ldapConn, error) {
+	conn, err := net.DialTimeout("tcp", address, timeout)
+	if err != nil {
+		return nil, err
+	}
+	conn.SetDeadline(time.Now().Add(timeout))
+	return &ldapConn{conn: conn}, nil
+}
+
+func (c *ldapConn) request(op []byte) error {
+	c.messageID++
+	_, err := c.conn.Write(berEncode(berTagSequence, berInteger(berTagInteger, c.messageID), op))
+	return err
+}
+
+// response reads the protocol operation of the next response
+func (c *ldapConn) response() (*berElement, error) {
+	message, err := berRead(c.conn)
+	if err != nil {
+		return nil, err
+	}
+	fields, err := message.children()
+	if err != nil {
+		return nil, err
+	}
+	if message.tag != berTagSequence || len(fields) < 2 || fields[0].int() != c.messageID {
+		return nil, errors.New("Invalid LDAP response.")
+	}
+	return fields[1], nil
+}
+
+// ldapResult returns the error reported by an LDAPResult, if any
+func ldapResult(op *berElement) error {
+	fields, err := op.children()
+	if err != nil {
+		return err
+	}
+	if len(fields) < 3 {
+		return errors.New("Invalid LDAP result.")
+	}
+	if code := fields[0].int(); code != 0 {
+		return fmt.Errorf("LDAP error %d: %s", code, fields[2].content)
+	}
+	return nil
+}
+
+func (c *ldapConn) bind(dn, password string) error {
+	err := c.request(berEncode(ldapTagBindRequest, berInteger(berTagInteger, 3), berString(dn), berEncode(ldapTagSimpleAuth, []byte(password))))
+	if err != nil {
+		return err
+	}
+	op, err := c.response()
+	if err != nil {
+		return err
+	}
+	if op.tag != ldapTagBindResponse {
+		return errors.New("Invalid LDAP bind response.")
+	}
+	return ldapResult(op)
+}
+
+// search returns the entries under baseDN matching filter, with the given
+// attributes
+func (c *ldapConn) search(baseDN string, filter []byte, attributes []string) ([]*ldapEntry, error) {
+	var names []byte
+	for _, attribute := range attributes {
+		names = append(names, berString(attribute)...)
+	}
+	err := c.request(berEncode(ldapTagSearchRequest,
+		berString(baseDN),
+		berInteger(berTagEnumerated, ldapScopeWholeSubtree),
+		berInteger(berTagEnumerated, 0), // never dereference aliases
+		berInteger(berTagInteger, 0),    // no size limit
+		berInteger(berTagInteger, 0),    // no time limit
+		berEncode(berTagBoolean, []byte{0}),
+		filter,
+		berEncode(berTagSequence, names)))
+	if err != nil {
+		return nil, err
+	}
+
+	var entries []*ldapEntry
+	for {
+		op, err := c.response()
+		if err != nil {
+			return nil, err
+		}
+		switch op.tag {
+		case ldapTagSearchEntry:
+			entry, err := parseLDAPEntry(op)
+			if err != nil {
+				return nil, err
+			}
+			entries = append(entries, entry)
+		case ldapTagSearchReference:
+			// referrals to other servers are not followed
+		case ldapTagSearchDone:
+			return entries, ldapResult(op)
+		default:
+			return nil, errors.New("Invalid LDAP search response.")
+		}
+	}
+}
+
+func parseLDAPEntry(op *berElement) (*ldapEntry, error) {
+	fields, err := op.children()
+	if err != nil {
+		return nil, err
+	}
+	if len(fields) < 2 {
+		return nil, errors.New("Invalid LDAP entry.")
+	}
+	attributes, err := fields[1].children()
+	if err != nil {
+		return nil, err
+	}
+	entry := &ldapEntry{dn: string(fields[0].content), attributes: make(map[string][]string)}
+	for _, attribute := range attributes {
+		typeAndValues, err := attribute.children()
+		if err != nil {
+			return nil, err
+		}
+		if len(typeAndValues) < 2 {
+			return nil, errors.New("Invalid LDAP attribute.")
+		}
+		values, err := typeAndValues[1].children()
+		if err != nil {
+			return nil, err
+		}
+		name := strings.ToLower(string(typeAndValues[0].content))
+		for _, value := range values {
+			entry.attributes[name] = append(entry.attributes[name], string(value.content))
+		}
+	}
+	return entry, nil
+}
+
+func (c *ldapConn) close() {
+	c.request(berEncode(ldapTagUnbindRequest))
+	c.conn.Close()
+}
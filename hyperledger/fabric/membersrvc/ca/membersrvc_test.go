@@ -70,8 +70,8 @@ func setupTestConfig() {
 
 func initPKI() {
 	LogInit(ioutil.Discard, os.Stdout, os.Stdout, os.Stderr, os.Stdout)
-	aca = NewACA()
 	eca = NewECA()
+	aca = NewACA(eca)
 	tca = NewTCA(eca)
 }
 
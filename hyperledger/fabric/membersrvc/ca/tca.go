@@ -351,6 +351,9 @@ func (tcap *TCAP) CreateCertificateSet(ctx context.Context, in *pb.TCertCreateSe
 	if err != nil {
 		return nil, err
 	}
+	if tcap.tca.eca.isRevoked(raw) {
+		return nil, errors.New("Enrollment certificate has been revoked.")
+	}
 
 	return tcap.createCertificateSet(ctx, raw, in)
 }
@@ -445,7 +448,7 @@ func (tcap *TCAP) createCertificateSet(ctx context.Context, raw []byte, in *pb.T
 		}
 
 		spec := NewDefaultPeriodCertificateSpec(id, tcertid, &txPub, x509.KeyUsageDigitalSignature, extensions...)
-		if raw, err = tcap.tca.createCertificateFromSpec(spec, timestamp, kdfKey, false); err != nil {
+		if raw, err = tcap.tca.createCertificateFromSpec(spec, timestamp, kdfKey, true); err != nil {
 			Error.Println(err)
 			return nil, err
 		}
@@ -578,39 +581,166 @@ func (tcap *TCAP) generateExtensions(tcertid *big.Int, tidx []byte, enrollmentCe
 	return extensions, preK0, nil
 }
 
-// RevokeCertificate revokes a certificate from the TCA.  Not yet implemented.
-func (tcap *TCAP) RevokeCertificate(context.Context, *pb.TCertRevokeReq) (*pb.CAStatus, error) {
+// revokeCertificateSet revokes the certificate set of owner issued at ts.  A
+// zero timestamp selects the latest set, a missing one all sets.
+func (tca *TCA) revokeCertificateSet(owner string, ts *google_protobuf.Timestamp, reason pb.CRLReason) error {
+	timestamp := int64(-1)
+	if ts != nil {
+		timestamp = ts.Seconds
+	}
+
+	n, err := tca.revokeCertificates(owner, timestamp, reason)
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return ErrCertificateNotFound
+	}
+
+	_, err = tca.publishCRL()
+	return err
+}
+
+// RevokeCertificate revokes a certificate from the TCA.  A user can only revoke his/her own certificates.
+func (tcap *TCAP) RevokeCertificate(ctx context.Context, in *pb.TCertRevokeReq) (*pb.CAStatus, error) {
 	Trace.Println("grpc TCAP:RevokeCertificate")
 
-	return nil, errors.New("not yet implemented")
+	if in.Id == nil || in.Cert == nil {
+		return nil, errors.New("Identity or certificate missing.")
+	}
+	id := in.Id.Id
+
+	sig := in.Sig
+	in.Sig = nil
+	if err := tcap.tca.eca.checkSignature(id, in, sig); err != nil {
+		return nil, err
+	}
+
+	if err := tcap.tca.revokeCertificate(id, in.Cert.Cert, in.Reason); err != nil {
+		return nil, err
+	}
+
+	if _, err := tcap.tca.publishCRL(); err != nil {
+		return nil, err
+	}
+
+	return &pb.CAStatus{Status: pb.CAStatus_OK}, nil
 }
 
-// RevokeCertificateSet revokes a certificate set from the TCA.  Not yet implemented.
-func (tcap *TCAP) RevokeCertificateSet(context.Context, *pb.TCertRevokeSetReq) (*pb.CAStatus, error) {
+// RevokeCertificateSet revokes a certificate set from the TCA.  A user can only revoke his/her own certificates.
+func (tcap *TCAP) RevokeCertificateSet(ctx context.Context, in *pb.TCertRevokeSetReq) (*pb.CAStatus, error) {
 	Trace.Println("grpc TCAP:RevokeCertificateSet")
 
-	return nil, errors.New("not yet implemented")
+	if in.Id == nil {
+		return nil, errors.New("Identity missing.")
+	}
+	id := in.Id.Id
+	if in.Owner != nil && in.Owner.Id != id {
+		return nil, errors.New("Access denied.")
+	}
+
+	sig := in.Sig
+	in.Sig = nil
+	if err := tcap.tca.eca.checkSignature(id, in, sig); err != nil {
+		return nil, err
+	}
+
+	if err := tcap.tca.revokeCertificateSet(id, in.Ts, in.Reason); err != nil {
+		return nil, err
+	}
+
+	return &pb.CAStatus{Status: pb.CAStatus_OK}, nil
 }
 
-// RevokeCertificate revokes a certificate from the TCA.  Not yet implemented.
-func (tcaa *TCAA) RevokeCertificate(context.Context, *pb.TCertRevokeReq) (*pb.CAStatus, error) {
+// ReadCRL returns the latest certificate revocation list published by the TCA.
+func (tcap *TCAP) ReadCRL(ctx context.Context, in *pb.Empty) (*pb.CRL, error) {
+	Trace.Println("grpc TCAP:ReadCRL")
+
+	raw, err := tcap.tca.readCRL()
+	if err != nil {
+		return nil, err
+	}
+
+	return &pb.CRL{Crl: raw}, nil
+}
+
+// RevokeCertificate revokes a certificate from the TCA.  An admin can revoke any certificate.
+func (tcaa *TCAA) RevokeCertificate(ctx context.Context, in *pb.TCertRevokeReq) (*pb.CAStatus, error) {
 	Trace.Println("grpc TCAA:RevokeCertificate")
 
-	return nil, errors.New("not yet implemented")
+	if in.Id == nil || in.Cert == nil {
+		return nil, errors.New("Identity or certificate missing.")
+	}
+	id := in.Id.Id
+	if err := tcaa.tca.eca.checkAdmin(id); err != nil {
+		return nil, err
+	}
+
+	sig := in.Sig
+	in.Sig = nil
+	if err := tcaa.tca.eca.checkSignature(id, in, sig); err != nil {
+		return nil, err
+	}
+
+	if err := tcaa.tca.revokeCertificate("", in.Cert.Cert, in.Reason); err != nil {
+		return nil, err
+	}
+
+	if _, err := tcaa.tca.publishCRL(); err != nil {
+		return nil, err
+	}
+
+	return &pb.CAStatus{Status: pb.CAStatus_OK}, nil
 }
 
-// RevokeCertificateSet revokes a certificate set from the TCA.  Not yet implemented.
-func (tcaa *TCAA) RevokeCertificateSet(context.Context, *pb.TCertRevokeSetReq) (*pb.CAStatus, error) {
+// RevokeCertificateSet revokes a certificate set of any user from the TCA.
+func (tcaa *TCAA) RevokeCertificateSet(ctx context.Context, in *pb.TCertRevokeSetReq) (*pb.CAStatus, error) {
 	Trace.Println("grpc TCAA:RevokeCertificateSet")
 
-	return nil, errors.New("not yet implemented")
+	if in.Id == nil || in.Owner == nil {
+		return nil, errors.New("Identity or owner missing.")
+	}
+	id := in.Id.Id
+	if err := tcaa.tca.eca.checkAdmin(id); err != nil {
+		return nil, err
+	}
+
+	sig := in.Sig
+	in.Sig = nil
+	if err := tcaa.tca.eca.checkSignature(id, in, sig); err != nil {
+		return nil, err
+	}
+
+	if err := tcaa.tca.revokeCertificateSet(in.Owner.Id, in.Ts, in.Reason); err != nil {
+		return nil, err
+	}
+
+	return &pb.CAStatus{Status: pb.CAStatus_OK}, nil
 }
 
-// PublishCRL requests the creation of a certificate revocation list from the TCA.  Not yet implemented.
-func (tcaa *TCAA) PublishCRL(context.Context, *pb.TCertCRLReq) (*pb.CAStatus, error) {
+// PublishCRL requests the creation of a certificate revocation list from the TCA.
+func (tcaa *TCAA) PublishCRL(ctx context.Context, in *pb.TCertCRLReq) (*pb.CAStatus, error) {
 	Trace.Println("grpc TCAA:CreateCRL")
 
-	return nil, errors.New("not yet implemented")
+	if in.Id == nil {
+		return nil, errors.New("Identity missing.")
+	}
+	id := in.Id.Id
+	if err := tcaa.tca.eca.checkAdmin(id); err != nil {
+		return nil, err
+	}
+
+	sig := in.Sig
+	in.Sig = nil
+	if err := tcaa.tca.eca.checkSignature(id, in, sig); err != nil {
+		return nil, err
+	}
+
+	if _, err := tcaa.tca.publishCRL(); err != nil {
+		return nil, err
+	}
+
+	return &pb.CAStatus{Status: pb.CAStatus_OK}, nil
 }
 
 func isEnabledAttributesEncryption() bool {
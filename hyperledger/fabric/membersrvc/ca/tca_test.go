@@ -106,6 +106,86 @@ func TestCreateCertificateSet(t *testing.T) {
 	}
 }
 
+func TestRevokeTCertificates(t *testing.T) {
+	user := User{enrollID: "test_user5", enrollPwd: []byte("yg5DVhm0er1z")}
+	if err := enrollUser(&user); err != nil {
+		t.Fatalf("Failed enrolling user [%s]", err)
+	}
+
+	tcap := &TCAP{tca}
+	setReq, err := buildCertificateSetRequest(user.enrollID, user.enrollPrivKey, 3, -1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	set, err := tcap.CreateCertificateSet(context.Background(), setReq)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tcerts := set.Certs.Certs
+
+	// revoke a single TCert
+	req := &protos.TCertRevokeReq{Id: &protos.Identity{Id: user.enrollID}, Cert: &protos.Cert{Cert: tcerts[0].Cert}}
+	req.Sig, _ = signRequest(user.enrollPrivKey, req)
+	if _, err = tcap.RevokeCertificate(context.Background(), req); err != nil {
+		t.Fatalf("Failed revoking TCert [%s]", err)
+	}
+	if _, ok := isInCRL(t, tca.CA, tcerts[0].Cert); !ok {
+		t.Fatal("Revoked TCert is missing from the CRL")
+	}
+	if _, ok := isInCRL(t, tca.CA, tcerts[1].Cert); ok {
+		t.Fatal("TCert should not have been revoked")
+	}
+
+	// users can only revoke their own TCerts
+	req = &protos.TCertRevokeReq{Id: &protos.Identity{Id: testUser.enrollID}, Cert: &protos.Cert{Cert: tcerts[1].Cert}}
+	req.Sig, _ = signRequest(testUser.enrollPrivKey, req)
+	if _, err = tcap.RevokeCertificate(context.Background(), req); err != ErrCertificateNotFound {
+		t.Fatalf("Expected error was not returned: [%v]", err)
+	}
+
+	// revoke the remaining TCerts of the set
+	setRevokeReq := &protos.TCertRevokeSetReq{Id: &protos.Identity{Id: user.enrollID}, Ts: setReq.Ts, Reason: protos.CRLReason_SUPERSEDED}
+	setRevokeReq.Sig, _ = signRequest(user.enrollPrivKey, setRevokeReq)
+	if _, err = tcap.RevokeCertificateSet(context.Background(), setRevokeReq); err != nil {
+		t.Fatalf("Failed revoking TCert set [%s]", err)
+	}
+	for _, tcert := range tcerts {
+		if _, ok := isInCRL(t, tca.CA, tcert.Cert); !ok {
+			t.Fatal("Revoked TCert is missing from the CRL")
+		}
+	}
+
+	// admins revoke all the TCerts of a user
+	setReq, _ = buildCertificateSetRequest(user.enrollID, user.enrollPrivKey, 1, -1)
+	setReq.Ts.Seconds++
+	setReq.Sig = nil
+	setReq.Sig, _ = signRequest(user.enrollPrivKey, setReq)
+	if set, err = tcap.CreateCertificateSet(context.Background(), setReq); err != nil {
+		t.Fatal(err)
+	}
+	tcaa := &TCAA{tca}
+	setRevokeReq = &protos.TCertRevokeSetReq{Id: &protos.Identity{Id: testAuditor.enrollID}, Owner: &protos.Identity{Id: user.enrollID}}
+	setRevokeReq.Sig, _ = signRequest(testAuditor.enrollPrivKey, setRevokeReq)
+	if _, err = tcaa.RevokeCertificateSet(context.Background(), setRevokeReq); err != nil {
+		t.Fatalf("Failed revoking TCert sets [%s]", err)
+	}
+	if _, ok := isInCRL(t, tca.CA, set.Certs.Certs[0].Cert); !ok {
+		t.Fatal("Revoked TCert is missing from the CRL")
+	}
+
+	// no TCerts are issued for revoked ECerts
+	ecert, _ := tca.eca.readCertificateByKeyUsage(user.enrollID, x509.KeyUsageDigitalSignature)
+	revokeReq := &protos.ECertRevokeReq{Id: &protos.Identity{Id: testAuditor.enrollID}, Cert: &protos.Cert{Cert: ecert}}
+	revokeReq.Sig, _ = signRequest(testAuditor.enrollPrivKey, revokeReq)
+	if _, err = (&ECAA{tca.eca}).RevokeCertificate(context.Background(), revokeReq); err != nil {
+		t.Fatalf("Failed revoking ECert [%s]", err)
+	}
+	setReq, _ = buildCertificateSetRequest(user.enrollID, user.enrollPrivKey, 1, -1)
+	if _, err = tcap.CreateCertificateSet(context.Background(), setReq); err == nil {
+		t.Fatal("TCerts should not be issued for revoked ECerts")
+	}
+}
+
 func loadECertAndEnrollmentPrivateKey(enrollmentID string, password string) ([]byte, *ecdsa.PrivateKey, error) {
 	cooked, err := ioutil.ReadFile("./test_resources/key_" + enrollmentID + ".dump")
 	if err != nil {
@@ -149,7 +229,7 @@ func initTCA() (*TCA, error) {
 		return nil, fmt.Errorf("Could not create a new ECA")
 	}
 
-	aca := NewACA()
+	aca := NewACA(eca)
 	if aca == nil {
 		return nil, fmt.Errorf("Could not create a new ACA")
 	}
@@ -137,18 +137,89 @@ func (tlscap *TLSCAP) ReadCertificate(ctx context.Context, in *pb.TLSCertReadReq
 	return &pb.Cert{Cert: raw}, nil
 }
 
-// RevokeCertificate revokes a certificate from the TLSCA.  Not yet implemented.
+// RevokeCertificate revokes a certificate from the TLSCA.  The request has to be
+// signed with the key of the certificate to be revoked.
 //
-func (tlscap *TLSCAP) RevokeCertificate(context.Context, *pb.TLSCertRevokeReq) (*pb.CAStatus, error) {
+func (tlscap *TLSCAP) RevokeCertificate(ctx context.Context, in *pb.TLSCertRevokeReq) (*pb.CAStatus, error) {
 	Trace.Println("grpc TLSCAP:RevokeCertificate")
 
-	return nil, errors.New("not yet implemented")
+	if in.Id == nil || in.Cert == nil || in.Sig == nil {
+		return nil, errors.New("Identity, certificate or signature missing.")
+	}
+
+	cert, err := x509.ParseCertificate(in.Cert.Cert)
+	if err != nil {
+		return nil, err
+	}
+	pub, ok := cert.PublicKey.(*ecdsa.PublicKey)
+	if !ok {
+		return nil, errors.New("unsupported key type")
+	}
+
+	sig := in.Sig
+	in.Sig = nil
+
+	r, s := big.NewInt(0), big.NewInt(0)
+	r.UnmarshalText(sig.R)
+	s.UnmarshalText(sig.S)
+
+	hash := primitives.NewHash()
+	raw, _ := proto.Marshal(in)
+	hash.Write(raw)
+	if ecdsa.Verify(pub, hash.Sum(nil), r, s) == false {
+		return nil, errors.New("signature does not verify")
+	}
+
+	if err = tlscap.tlsca.revokeCertificate(in.Id.Id, in.Cert.Cert, in.Reason); err != nil {
+		return nil, err
+	}
+
+	if _, err = tlscap.tlsca.publishCRL(); err != nil {
+		return nil, err
+	}
+
+	return &pb.CAStatus{Status: pb.CAStatus_OK}, nil
 }
 
-// RevokeCertificate revokes a certificate from the TLSCA.  Not yet implemented.
+// ReadCRL returns the latest certificate revocation list published by the TLSCA.
 //
-func (tlscaa *TLSCAA) RevokeCertificate(context.Context, *pb.TLSCertRevokeReq) (*pb.CAStatus, error) {
+func (tlscap *TLSCAP) ReadCRL(ctx context.Context, in *pb.Empty) (*pb.CRL, error) {
+	Trace.Println("grpc TLSCAP:ReadCRL")
+
+	raw, err := tlscap.tlsca.readCRL()
+	if err != nil {
+		return nil, err
+	}
+
+	return &pb.CRL{Crl: raw}, nil
+}
+
+// RevokeCertificate revokes a certificate from the TLSCA.  An admin can revoke any certificate.
+//
+func (tlscaa *TLSCAA) RevokeCertificate(ctx context.Context, in *pb.TLSCertRevokeReq) (*pb.CAStatus, error) {
 	Trace.Println("grpc TLSCAA:RevokeCertificate")
 
-	return nil, errors.New("not yet implemented")
+	if in.Id == nil || in.Cert == nil {
+		return nil, errors.New("Identity or certificate missing.")
+	}
+	id := in.Id.Id
+	if err := tlscaa.tlsca.eca.checkAdmin(id); err != nil {
+		return nil, err
+	}
+
+	sig := in.Sig
+	in.Sig = nil
+	if err := tlscaa.tlsca.eca.checkSignature(id, in, sig); err != nil {
+		return nil, err
+	}
+
+	if err := tlscaa.tlsca.revokeCertificate("", in.Cert.Cert, in.Reason); err != nil {
+		return nil, err
+	}
+
+	if _, err := tlscaa.tlsca.publishCRL(); err != nil {
+		return nil, err
+	}
+
+	return &pb.CAStatus{Status: pb.CAStatus_OK}, nil
 }
@@ -62,6 +62,47 @@ func TestTLS(t *testing.T) {
 	stopTLSCA(t)
 }
 
+func TestRevokeTLSCertificate(t *testing.T) {
+	tlsca := NewTLSCA(eca)
+	defer tlsca.Close()
+	tlscap := &TLSCAP{tlsca}
+
+	priv, err := primitives.NewECDSAKey()
+	if err != nil {
+		t.Fatalf("Failed generating key: %s", err)
+	}
+	pubraw, _ := x509.MarshalPKIXPublicKey(&priv.PublicKey)
+
+	createReq := &membersrvc.TLSCertCreateReq{
+		Ts:  &google_protobuf.Timestamp{Seconds: time.Now().Unix()},
+		Id:  &membersrvc.Identity{Id: "peer-" + util.GenerateUUID()},
+		Pub: &membersrvc.PublicKey{Type: membersrvc.CryptoType_ECDSA, Key: pubraw}}
+	createReq.Sig, _ = signRequest(priv, createReq)
+	resp, err := tlscap.CreateCertificate(context.Background(), createReq)
+	if err != nil {
+		t.Fatalf("Failed requesting tls certificate: %s", err)
+	}
+
+	req := &membersrvc.TLSCertRevokeReq{Id: createReq.Id, Cert: resp.Cert, Reason: membersrvc.CRLReason_CESSATION_OF_OPERATION}
+	req.Sig, _ = signRequest(testUser.enrollPrivKey, req)
+	if _, err = tlscap.RevokeCertificate(context.Background(), req); err == nil {
+		t.Fatal("Revocation requests must be signed with the key of the certificate")
+	}
+
+	req.Sig, _ = signRequest(priv, req)
+	if _, err = tlscap.RevokeCertificate(context.Background(), req); err != nil {
+		t.Fatalf("Failed revoking tls certificate: %s", err)
+	}
+
+	crl, err := tlscap.ReadCRL(context.Background(), &membersrvc.Empty{})
+	if err != nil {
+		t.Fatalf("Failed reading CRL: %s", err)
+	}
+	if _, ok := isInCRL(t, tlsca.CA, resp.Cert.Cert); !ok || crl == nil {
+		t.Fatal("Revoked tls certificate is missing from the CRL")
+	}
+}
+
 func startTLSCA(t *testing.T) {
 	LogInit(ioutil.Discard, os.Stdout, os.Stdout, os.Stderr, os.Stdout)
 
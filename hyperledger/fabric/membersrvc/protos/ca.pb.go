@@ -41,6 +41,7 @@ It has these top-level messages:
 	TLSCertReadReq
 	TLSCertRevokeReq
 	Cert
+	CRL
 	TCert
 	CertSet
 	CertSets
@@ -51,6 +52,8 @@ It has these top-level messages:
 	ACAFetchAttrResp
 	FetchAttrsResult
 	ACAAttribute
+	ACAAttrEntry
+	ACAAttrAdminReq
 */
 package protos
 
@@ -127,6 +130,51 @@ func (x Role) String() string {
 	return proto.EnumName(Role_name, int32(x))
 }
 
+// Certificate revocation reasons (RFC 5280, 5.3.1).
+type CRLReason int32
+
+const (
+	CRLReason_UNSPECIFIED            CRLReason = 0
+	CRLReason_KEY_COMPROMISE         CRLReason = 1
+	CRLReason_CA_COMPROMISE          CRLReason = 2
+	CRLReason_AFFILIATION_CHANGED    CRLReason = 3
+	CRLReason_SUPERSEDED             CRLReason = 4
+	CRLReason_CESSATION_OF_OPERATION CRLReason = 5
+	CRLReason_CERTIFICATE_HOLD       CRLReason = 6
+	CRLReason_REMOVE_FROM_CRL        CRLReason = 8
+	CRLReason_PRIVILEGE_WITHDRAWN    CRLReason = 9
+	CRLReason_AA_COMPROMISE          CRLReason = 10
+)
+
+var CRLReason_name = map[int32]string{
+	0:  "UNSPECIFIED",
+	1:  "KEY_COMPROMISE",
+	2:  "CA_COMPROMISE",
+	3:  "AFFILIATION_CHANGED",
+	4:  "SUPERSEDED",
+	5:  "CESSATION_OF_OPERATION",
+	6:  "CERTIFICATE_HOLD",
+	8:  "REMOVE_FROM_CRL",
+	9:  "PRIVILEGE_WITHDRAWN",
+	10: "AA_COMPROMISE",
+}
+var CRLReason_value = map[string]int32{
+	"UNSPECIFIED":            0,
+	"KEY_COMPROMISE":         1,
+	"CA_COMPROMISE":          2,
+	"AFFILIATION_CHANGED":    3,
+	"SUPERSEDED":             4,
+	"CESSATION_OF_OPERATION": 5,
+	"CERTIFICATE_HOLD":       6,
+	"REMOVE_FROM_CRL":        8,
+	"PRIVILEGE_WITHDRAWN":    9,
+	"AA_COMPROMISE":          10,
+}
+
+func (x CRLReason) String() string {
+	return proto.EnumName(CRLReason_name, int32(x))
+}
+
 type CAStatus_StatusCode int32
 
 const (
@@ -537,9 +585,10 @@ func (m *ECertReadReq) GetId() *Identity {
 }
 
 type ECertRevokeReq struct {
-	Id   *Identity  `protobuf:"bytes,1,opt,name=id" json:"id,omitempty"`
-	Cert *Cert      `protobuf:"bytes,2,opt,name=cert" json:"cert,omitempty"`
-	Sig  *Signature `protobuf:"bytes,3,opt,name=sig" json:"sig,omitempty"`
+	Id     *Identity  `protobuf:"bytes,1,opt,name=id" json:"id,omitempty"`
+	Cert   *Cert      `protobuf:"bytes,2,opt,name=cert" json:"cert,omitempty"`
+	Sig    *Signature `protobuf:"bytes,3,opt,name=sig" json:"sig,omitempty"`
+	Reason CRLReason  `protobuf:"varint,4,opt,name=reason,enum=protos.CRLReason" json:"reason,omitempty"`
 }
 
 func (m *ECertRevokeReq) Reset()         { *m = ECertRevokeReq{} }
@@ -748,9 +797,10 @@ func (m *TCertReadSetsReq) GetSig() *Signature {
 }
 
 type TCertRevokeReq struct {
-	Id   *Identity  `protobuf:"bytes,1,opt,name=id" json:"id,omitempty"`
-	Cert *Cert      `protobuf:"bytes,2,opt,name=cert" json:"cert,omitempty"`
-	Sig  *Signature `protobuf:"bytes,3,opt,name=sig" json:"sig,omitempty"`
+	Id     *Identity  `protobuf:"bytes,1,opt,name=id" json:"id,omitempty"`
+	Cert   *Cert      `protobuf:"bytes,2,opt,name=cert" json:"cert,omitempty"`
+	Sig    *Signature `protobuf:"bytes,3,opt,name=sig" json:"sig,omitempty"`
+	Reason CRLReason  `protobuf:"varint,4,opt,name=reason,enum=protos.CRLReason" json:"reason,omitempty"`
 }
 
 func (m *TCertRevokeReq) Reset()         { *m = TCertRevokeReq{} }
@@ -779,9 +829,11 @@ func (m *TCertRevokeReq) GetSig() *Signature {
 }
 
 type TCertRevokeSetReq struct {
-	Id  *Identity                  `protobuf:"bytes,1,opt,name=id" json:"id,omitempty"`
-	Ts  *google_protobuf.Timestamp `protobuf:"bytes,2,opt,name=ts" json:"ts,omitempty"`
-	Sig *Signature                 `protobuf:"bytes,3,opt,name=sig" json:"sig,omitempty"`
+	Id     *Identity                  `protobuf:"bytes,1,opt,name=id" json:"id,omitempty"`
+	Ts     *google_protobuf.Timestamp `protobuf:"bytes,2,opt,name=ts" json:"ts,omitempty"`
+	Sig    *Signature                 `protobuf:"bytes,3,opt,name=sig" json:"sig,omitempty"`
+	Reason CRLReason                  `protobuf:"varint,4,opt,name=reason,enum=protos.CRLReason" json:"reason,omitempty"`
+	Owner  *Identity                  `protobuf:"bytes,5,opt,name=owner" json:"owner,omitempty"`
 }
 
 func (m *TCertRevokeSetReq) Reset()         { *m = TCertRevokeSetReq{} }
@@ -809,6 +861,13 @@ func (m *TCertRevokeSetReq) GetSig() *Signature {
 	return nil
 }
 
+func (m *TCertRevokeSetReq) GetOwner() *Identity {
+	if m != nil {
+		return m.Owner
+	}
+	return nil
+}
+
 type TCertCRLReq struct {
 	Id  *Identity  `protobuf:"bytes,1,opt,name=id" json:"id,omitempty"`
 	Sig *Signature `protobuf:"bytes,2,opt,name=sig" json:"sig,omitempty"`
@@ -910,9 +969,10 @@ func (m *TLSCertReadReq) GetId() *Identity {
 }
 
 type TLSCertRevokeReq struct {
-	Id   *Identity  `protobuf:"bytes,1,opt,name=id" json:"id,omitempty"`
-	Cert *Cert      `protobuf:"bytes,2,opt,name=cert" json:"cert,omitempty"`
-	Sig  *Signature `protobuf:"bytes,3,opt,name=sig" json:"sig,omitempty"`
+	Id     *Identity  `protobuf:"bytes,1,opt,name=id" json:"id,omitempty"`
+	Cert   *Cert      `protobuf:"bytes,2,opt,name=cert" json:"cert,omitempty"`
+	Sig    *Signature `protobuf:"bytes,3,opt,name=sig" json:"sig,omitempty"`
+	Reason CRLReason  `protobuf:"varint,4,opt,name=reason,enum=protos.CRLReason" json:"reason,omitempty"`
 }
 
 func (m *TLSCertRevokeReq) Reset()         { *m = TLSCertRevokeReq{} }
@@ -950,6 +1010,16 @@ func (m *Cert) Reset()         { *m = Cert{} }
 func (m *Cert) String() string { return proto.CompactTextString(m) }
 func (*Cert) ProtoMessage()    {}
 
+// Certificate revocation list issued by either the ECA, TCA or TLSCA.
+//
+type CRL struct {
+	Crl []byte `protobuf:"bytes,1,opt,name=crl,proto3" json:"crl,omitempty"`
+}
+
+func (m *CRL) Reset()         { *m = CRL{} }
+func (m *CRL) String() string { return proto.CompactTextString(m) }
+func (*CRL) ProtoMessage()    {}
+
 // TCert
 //
 type TCert struct {
@@ -1187,9 +1257,79 @@ func (m *ACAAttribute) GetValidTo() *google_protobuf.Timestamp {
 	return nil
 }
 
+// ACAAttrEntry is an attribute of a user as managed by the ACA administrators.
+type ACAAttrEntry struct {
+	// Enrollment ID of the owner of the attribute.
+	Id string `protobuf:"bytes,1,opt,name=id" json:"id,omitempty"`
+	// Affiliation of the owner of the attribute.
+	Affiliation string `protobuf:"bytes,2,opt,name=affiliation" json:"affiliation,omitempty"`
+	// Name of the attribute.
+	AttributeName string `protobuf:"bytes,3,opt,name=attributeName" json:"attributeName,omitempty"`
+	// Value of the attribute.
+	AttributeValue []byte `protobuf:"bytes,4,opt,name=attributeValue,proto3" json:"attributeValue,omitempty"`
+	// The timestamp which attribute is valid from.
+	ValidFrom *google_protobuf.Timestamp `protobuf:"bytes,5,opt,name=validFrom" json:"validFrom,omitempty"`
+	// The timestamp which attribute is valid to.
+	ValidTo *google_protobuf.Timestamp `protobuf:"bytes,6,opt,name=validTo" json:"validTo,omitempty"`
+}
+
+func (m *ACAAttrEntry) Reset()         { *m = ACAAttrEntry{} }
+func (m *ACAAttrEntry) String() string { return proto.CompactTextString(m) }
+func (*ACAAttrEntry) ProtoMessage()    {}
+
+func (m *ACAAttrEntry) GetValidFrom() *google_protobuf.Timestamp {
+	if m != nil {
+		return m.ValidFrom
+	}
+	return nil
+}
+
+func (m *ACAAttrEntry) GetValidTo() *google_protobuf.Timestamp {
+	if m != nil {
+		return m.ValidTo
+	}
+	return nil
+}
+
+// ACAAttrAdminReq is a request of an administrator to change attributes in the Attribute Certificate Authority (ACA).
+type ACAAttrAdminReq struct {
+	// Identity of the administrator.
+	Id *Identity `protobuf:"bytes,1,opt,name=id" json:"id,omitempty"`
+	// Attributes to add, update or expire.
+	Attributes []*ACAAttrEntry `protobuf:"bytes,2,rep,name=attributes" json:"attributes,omitempty"`
+	// The request is signed by the administrator.
+	Sig *Signature `protobuf:"bytes,3,opt,name=sig" json:"sig,omitempty"`
+}
+
+func (m *ACAAttrAdminReq) Reset()         { *m = ACAAttrAdminReq{} }
+func (m *ACAAttrAdminReq) String() string { return proto.CompactTextString(m) }
+func (*ACAAttrAdminReq) ProtoMessage()    {}
+
+func (m *ACAAttrAdminReq) GetId() *Identity {
+	if m != nil {
+		return m.Id
+	}
+	return nil
+}
+
+func (m *ACAAttrAdminReq) GetAttributes() []*ACAAttrEntry {
+	if m != nil {
+		return m.Attributes
+	}
+	return nil
+}
+
+func (m *ACAAttrAdminReq) GetSig() *Signature {
+	if m != nil {
+		return m.Sig
+	}
+	return nil
+}
+
 func init() {
 	proto.RegisterEnum("protos.CryptoType", CryptoType_name, CryptoType_value)
 	proto.RegisterEnum("protos.Role", Role_name, Role_value)
+	proto.RegisterEnum("protos.CRLReason", CRLReason_name, CRLReason_value)
 	proto.RegisterEnum("protos.CAStatus_StatusCode", CAStatus_StatusCode_name, CAStatus_StatusCode_value)
 	proto.RegisterEnum("protos.ACAAttrResp_StatusCode", ACAAttrResp_StatusCode_name, ACAAttrResp_StatusCode_value)
 	proto.RegisterEnum("protos.ACAFetchAttrResp_StatusCode", ACAFetchAttrResp_StatusCode_name, ACAFetchAttrResp_StatusCode_value)
@@ -1208,6 +1348,7 @@ type ECAPClient interface {
 	ReadCertificatePair(ctx context.Context, in *ECertReadReq, opts ...grpc.CallOption) (*CertPair, error)
 	ReadCertificateByHash(ctx context.Context, in *Hash, opts ...grpc.CallOption) (*Cert, error)
 	RevokeCertificatePair(ctx context.Context, in *ECertRevokeReq, opts ...grpc.CallOption) (*CAStatus, error)
+	ReadCRL(ctx context.Context, in *Empty, opts ...grpc.CallOption) (*CRL, error)
 }
 
 type eCAPClient struct {
@@ -1263,6 +1404,15 @@ func (c *eCAPClient) RevokeCertificatePair(ctx context.Context, in *ECertRevokeR
 	return out, nil
 }
 
+func (c *eCAPClient) ReadCRL(ctx context.Context, in *Empty, opts ...grpc.CallOption) (*CRL, error) {
+	out := new(CRL)
+	err := grpc.Invoke(ctx, "/protos.ECAP/ReadCRL", in, out, c.cc, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 // Server API for ECAP service
 
 type ECAPServer interface {
@@ -1271,6 +1421,7 @@ type ECAPServer interface {
 	ReadCertificatePair(context.Context, *ECertReadReq) (*CertPair, error)
 	ReadCertificateByHash(context.Context, *Hash) (*Cert, error)
 	RevokeCertificatePair(context.Context, *ECertRevokeReq) (*CAStatus, error)
+	ReadCRL(context.Context, *Empty) (*CRL, error)
 }
 
 func RegisterECAPServer(s *grpc.Server, srv ECAPServer) {
@@ -1337,6 +1488,18 @@ func _ECAP_RevokeCertificatePair_Handler(srv interface{}, ctx context.Context, d
 	return out, nil
 }
 
+func _ECAP_ReadCRL_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error) (interface{}, error) {
+	in := new(Empty)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	out, err := srv.(ECAPServer).ReadCRL(ctx, in)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 var _ECAP_serviceDesc = grpc.ServiceDesc{
 	ServiceName: "protos.ECAP",
 	HandlerType: (*ECAPServer)(nil),
@@ -1361,6 +1524,10 @@ var _ECAP_serviceDesc = grpc.ServiceDesc{
 			MethodName: "RevokeCertificatePair",
 			Handler:    _ECAP_RevokeCertificatePair_Handler,
 		},
+		{
+			MethodName: "ReadCRL",
+			Handler:    _ECAP_ReadCRL_Handler,
+		},
 	},
 	Streams: []grpc.StreamDesc{},
 }
@@ -1510,6 +1677,7 @@ type TCAPClient interface {
 	CreateCertificateSet(ctx context.Context, in *TCertCreateSetReq, opts ...grpc.CallOption) (*TCertCreateSetResp, error)
 	RevokeCertificate(ctx context.Context, in *TCertRevokeReq, opts ...grpc.CallOption) (*CAStatus, error)
 	RevokeCertificateSet(ctx context.Context, in *TCertRevokeSetReq, opts ...grpc.CallOption) (*CAStatus, error)
+	ReadCRL(ctx context.Context, in *Empty, opts ...grpc.CallOption) (*CRL, error)
 }
 
 type tCAPClient struct {
@@ -1556,6 +1724,15 @@ func (c *tCAPClient) RevokeCertificateSet(ctx context.Context, in *TCertRevokeSe
 	return out, nil
 }
 
+func (c *tCAPClient) ReadCRL(ctx context.Context, in *Empty, opts ...grpc.CallOption) (*CRL, error) {
+	out := new(CRL)
+	err := grpc.Invoke(ctx, "/protos.TCAP/ReadCRL", in, out, c.cc, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 // Server API for TCAP service
 
 type TCAPServer interface {
@@ -1563,6 +1740,7 @@ type TCAPServer interface {
 	CreateCertificateSet(context.Context, *TCertCreateSetReq) (*TCertCreateSetResp, error)
 	RevokeCertificate(context.Context, *TCertRevokeReq) (*CAStatus, error)
 	RevokeCertificateSet(context.Context, *TCertRevokeSetReq) (*CAStatus, error)
+	ReadCRL(context.Context, *Empty) (*CRL, error)
 }
 
 func RegisterTCAPServer(s *grpc.Server, srv TCAPServer) {
@@ -1617,6 +1795,18 @@ func _TCAP_RevokeCertificateSet_Handler(srv interface{}, ctx context.Context, de
 	return out, nil
 }
 
+func _TCAP_ReadCRL_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error) (interface{}, error) {
+	in := new(Empty)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	out, err := srv.(TCAPServer).ReadCRL(ctx, in)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 var _TCAP_serviceDesc = grpc.ServiceDesc{
 	ServiceName: "protos.TCAP",
 	HandlerType: (*TCAPServer)(nil),
@@ -1637,6 +1827,10 @@ var _TCAP_serviceDesc = grpc.ServiceDesc{
 			MethodName: "RevokeCertificateSet",
 			Handler:    _TCAP_RevokeCertificateSet_Handler,
 		},
+		{
+			MethodName: "ReadCRL",
+			Handler:    _TCAP_ReadCRL_Handler,
+		},
 	},
 	Streams: []grpc.StreamDesc{},
 }
@@ -1759,6 +1953,7 @@ type TLSCAPClient interface {
 	CreateCertificate(ctx context.Context, in *TLSCertCreateReq, opts ...grpc.CallOption) (*TLSCertCreateResp, error)
 	ReadCertificate(ctx context.Context, in *TLSCertReadReq, opts ...grpc.CallOption) (*Cert, error)
 	RevokeCertificate(ctx context.Context, in *TLSCertRevokeReq, opts ...grpc.CallOption) (*CAStatus, error)
+	ReadCRL(ctx context.Context, in *Empty, opts ...grpc.CallOption) (*CRL, error)
 }
 
 type tLSCAPClient struct {
@@ -1805,6 +2000,15 @@ func (c *tLSCAPClient) RevokeCertificate(ctx context.Context, in *TLSCertRevokeR
 	return out, nil
 }
 
+func (c *tLSCAPClient) ReadCRL(ctx context.Context, in *Empty, opts ...grpc.CallOption) (*CRL, error) {
+	out := new(CRL)
+	err := grpc.Invoke(ctx, "/protos.TLSCAP/ReadCRL", in, out, c.cc, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 // Server API for TLSCAP service
 
 type TLSCAPServer interface {
@@ -1812,6 +2016,7 @@ type TLSCAPServer interface {
 	CreateCertificate(context.Context, *TLSCertCreateReq) (*TLSCertCreateResp, error)
 	ReadCertificate(context.Context, *TLSCertReadReq) (*Cert, error)
 	RevokeCertificate(context.Context, *TLSCertRevokeReq) (*CAStatus, error)
+	ReadCRL(context.Context, *Empty) (*CRL, error)
 }
 
 func RegisterTLSCAPServer(s *grpc.Server, srv TLSCAPServer) {
@@ -1866,6 +2071,18 @@ func _TLSCAP_RevokeCertificate_Handler(srv interface{}, ctx context.Context, dec
 	return out, nil
 }
 
+func _TLSCAP_ReadCRL_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error) (interface{}, error) {
+	in := new(Empty)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	out, err := srv.(TLSCAPServer).ReadCRL(ctx, in)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 var _TLSCAP_serviceDesc = grpc.ServiceDesc{
 	ServiceName: "protos.TLSCAP",
 	HandlerType: (*TLSCAPServer)(nil),
@@ -1886,6 +2103,10 @@ var _TLSCAP_serviceDesc = grpc.ServiceDesc{
 			MethodName: "RevokeCertificate",
 			Handler:    _TLSCAP_RevokeCertificate_Handler,
 		},
+		{
+			MethodName: "ReadCRL",
+			Handler:    _TLSCAP_ReadCRL_Handler,
+		},
 	},
 	Streams: []grpc.StreamDesc{},
 }
@@ -2057,3 +2278,114 @@ var _ACAP_serviceDesc = grpc.ServiceDesc{
 	},
 	Streams: []grpc.StreamDesc{},
 }
+
+// Client API for ACAA service
+
+type ACAAClient interface {
+	AddAttributes(ctx context.Context, in *ACAAttrAdminReq, opts ...grpc.CallOption) (*CAStatus, error)
+	UpdateAttributes(ctx context.Context, in *ACAAttrAdminReq, opts ...grpc.CallOption) (*CAStatus, error)
+	ExpireAttributes(ctx context.Context, in *ACAAttrAdminReq, opts ...grpc.CallOption) (*CAStatus, error)
+}
+
+type aCAAClient struct {
+	cc *grpc.ClientConn
+}
+
+func NewACAAClient(cc *grpc.ClientConn) ACAAClient {
+	return &aCAAClient{cc}
+}
+
+func (c *aCAAClient) AddAttributes(ctx context.Context, in *ACAAttrAdminReq, opts ...grpc.CallOption) (*CAStatus, error) {
+	out := new(CAStatus)
+	err := grpc.Invoke(ctx, "/protos.ACAA/AddAttributes", in, out, c.cc, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *aCAAClient) UpdateAttributes(ctx context.Context, in *ACAAttrAdminReq, opts ...grpc.CallOption) (*CAStatus, error) {
+	out := new(CAStatus)
+	err := grpc.Invoke(ctx, "/protos.ACAA/UpdateAttributes", in, out, c.cc, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *aCAAClient) ExpireAttributes(ctx context.Context, in *ACAAttrAdminReq, opts ...grpc.CallOption) (*CAStatus, error) {
+	out := new(CAStatus)
+	err := grpc.Invoke(ctx, "/protos.ACAA/ExpireAttributes", in, out, c.cc, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// Server API for ACAA service
+
+type ACAAServer interface {
+	AddAttributes(context.Context, *ACAAttrAdminReq) (*CAStatus, error)
+	UpdateAttributes(context.Context, *ACAAttrAdminReq) (*CAStatus, error)
+	ExpireAttributes(context.Context, *ACAAttrAdminReq) (*CAStatus, error)
+}
+
+func RegisterACAAServer(s *grpc.Server, srv ACAAServer) {
+	s.RegisterService(&_ACAA_serviceDesc, srv)
+}
+
+func _ACAA_AddAttributes_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error) (interface{}, error) {
+	in := new(ACAAttrAdminReq)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	out, err := srv.(ACAAServer).AddAttributes(ctx, in)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func _ACAA_UpdateAttributes_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error) (interface{}, error) {
+	in := new(ACAAttrAdminReq)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	out, err := srv.(ACAAServer).UpdateAttributes(ctx, in)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func _ACAA_ExpireAttributes_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error) (interface{}, error) {
+	in := new(ACAAttrAdminReq)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	out, err := srv.(ACAAServer).ExpireAttributes(ctx, in)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+var _ACAA_serviceDesc = grpc.ServiceDesc{
+	ServiceName: "protos.ACAA",
+	HandlerType: (*ACAAServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "AddAttributes",
+			Handler:    _ACAA_AddAttributes_Handler,
+		},
+		{
+			MethodName: "UpdateAttributes",
+			Handler:    _ACAA_UpdateAttributes_Handler,
+		},
+		{
+			MethodName: "ExpireAttributes",
+			Handler:    _ACAA_ExpireAttributes_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{},
+}
@@ -90,12 +90,12 @@ func main() {
 	ca.LogInit(iotrace, ioinfo, iowarning, ioerror, iopanic)
 	ca.Info.Println("CA Server (" + viper.GetString("server.version") + ")")
 
-	aca := ca.NewACA()
-	defer aca.Close()
-
 	eca := ca.NewECA()
 	defer eca.Close()
 
+	aca := ca.NewACA(eca)
+	defer aca.Close()
+
 	tca := ca.NewTCA(eca)
 	defer tca.Close()
 
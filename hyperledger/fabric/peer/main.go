@@ -53,7 +53,9 @@ import (
 	"github.com/hyperledger/fabric/core/chaincode"
 	"github.com/hyperledger/fabric/core/comm"
 	"github.com/hyperledger/fabric/core/crypto"
+	"github.com/hyperledger/fabric/core/ledger"
 	"github.com/hyperledger/fabric/core/ledger/genesis"
+	"github.com/hyperledger/fabric/core/metrics"
 	"github.com/hyperledger/fabric/core/peer"
 	"github.com/hyperledger/fabric/core/rest"
 	"github.com/hyperledger/fabric/core/system_chaincode"
@@ -120,6 +122,42 @@ var nodeStopCmd = &cobra.Command{
 	},
 }
 
+var nodeExportCmd = &cobra.Command{
+	Use:   "export <path>",
+	Short: "Exports the ledger to an archive.",
+	Long: `Exports the blocks, the world state and the history of the ledger to an archive file.
+The running node writes the archive to its own file system, without stopping. When the node is not running the ledger is exported offline.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return exportLedger(args)
+	},
+}
+
+var nodeImportCmd = &cobra.Command{
+	Use:   "import <path>",
+	Short: "Imports the ledger from an archive.",
+	Long:  `Restores an archive written by 'node export' into the empty ledger of a node which is not running.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return importLedger(args)
+	},
+}
+
+var (
+	verifyStateSampleRate float64
+	verifyIndexSampleRate float64
+	verifyRepair          bool
+	verifyChainID         string
+)
+
+var nodeVerifyCmd = &cobra.Command{
+	Use:   "verify",
+	Short: "Verifies the ledger.",
+	Long: `Verifies the hash chain of the blocks, the state hash against the last block and the block indexes of the ledger of a node which is not running.
+With --repair the wrong index entries are rewritten and a state lagging behind the chain is brought up to date from the state deltas.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return verifyLedger()
+	},
+}
+
 var networkCmd = &cobra.Command{
 	Use:   networkFuncName,
 	Short: fmt.Sprintf("%s specific commands.", networkFuncName),
@@ -183,6 +221,9 @@ var (
 	chaincodeQueryHex       bool
 	chaincodeAttributesJSON string
 	customIDGenAlg          string
+	chaincodePurgeState     bool
+	chaincodeEffectiveDate  string
+	chaincodeChainID        string
 )
 
 var chaincodeCmd = &cobra.Command{
@@ -206,6 +247,16 @@ var chaincodeDeployCmd = &cobra.Command{
 	},
 }
 
+var chaincodeUpgradeCmd = &cobra.Command{
+	Use:       "upgrade",
+	Short:     fmt.Sprintf("Upgrade the specified %s to a new version.", chainFuncName),
+	Long:      fmt.Sprintf(`Deploy a new version of the specified %s, keeping its name and state.`, chainFuncName),
+	ValidArgs: []string{"1"},
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return chaincodeUpgrade(cmd, args)
+	},
+}
+
 var chaincodeInvokeCmd = &cobra.Command{
 	Use:       "invoke",
 	Short:     fmt.Sprintf("Invoke the specified %s.", chainFuncName),
@@ -226,6 +277,16 @@ var chaincodeQueryCmd = &cobra.Command{
 	},
 }
 
+var chaincodeTerminateCmd = &cobra.Command{
+	Use:       "terminate",
+	Short:     fmt.Sprintf("Terminate the specified %s.", chainFuncName),
+	Long:      fmt.Sprintf(`Terminate the specified %s, stopping it and rejecting any further invoke or query.`, chainFuncName),
+	ValidArgs: []string{"1"},
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return chaincodeTerminate(cmd, args)
+	},
+}
+
 func main() {
 	// For environment variables.
 	viper.SetEnvPrefix(cmdRoot)
@@ -277,6 +338,14 @@ func main() {
 
 	nodeStopCmd.Flags().StringVarP(&stopPidFile, "stop-peer-pid-file", "", viper.GetString("peer.fileSystemPath"), "Location of peer pid local file, for forces kill")
 	nodeCmd.AddCommand(nodeStopCmd)
+	nodeCmd.AddCommand(nodeExportCmd)
+	nodeCmd.AddCommand(nodeImportCmd)
+
+	nodeVerifyCmd.Flags().Float64VarP(&verifyStateSampleRate, "state-sample-rate", "", 1, "Fraction of the state buckets whose hash is recomputed, 1 to recompute the whole state hash")
+	nodeVerifyCmd.Flags().Float64VarP(&verifyIndexSampleRate, "index-sample-rate", "", 1, "Fraction of the blocks whose index entries are checked")
+	nodeVerifyCmd.Flags().BoolVarP(&verifyRepair, "repair", "", false, "Repair the index entries and the state from the state deltas")
+	nodeVerifyCmd.Flags().StringVarP(&verifyChainID, "chain", "", "", "ID of the chain of the ledger, the default chain if empty")
+	nodeCmd.AddCommand(nodeVerifyCmd)
 
 	mainCmd.AddCommand(nodeCmd)
 
@@ -298,14 +367,20 @@ func main() {
 	chaincodeCmd.PersistentFlags().StringVarP(&chaincodePath, "path", "p", undefinedParamValue, fmt.Sprintf("Path to %s", chainFuncName))
 	chaincodeCmd.PersistentFlags().StringVarP(&chaincodeName, "name", "n", undefinedParamValue, fmt.Sprintf("Name of the chaincode returned by the deploy transaction"))
 	chaincodeCmd.PersistentFlags().StringVarP(&chaincodeUsr, "username", "u", undefinedParamValue, fmt.Sprintf("Username for chaincode operations when security is enabled"))
+	chaincodeCmd.PersistentFlags().StringVarP(&chaincodeChainID, "chain", "", "", fmt.Sprintf("ID of the chain of the %s, the default chain if empty", chainFuncName))
 	chaincodeCmd.PersistentFlags().StringVarP(&customIDGenAlg, "tid", "t", undefinedParamValue, fmt.Sprintf("Name of a custom ID generation algorithm (hashing and decoding) e.g. sha256base64"))
 
 	chaincodeQueryCmd.Flags().BoolVarP(&chaincodeQueryRaw, "raw", "r", false, "If true, output the query value as raw bytes, otherwise format as a printable string")
 	chaincodeQueryCmd.Flags().BoolVarP(&chaincodeQueryHex, "hex", "x", false, "If true, output the query value byte array in hexadecimal. Incompatible with --raw")
+	chaincodeTerminateCmd.Flags().BoolVarP(&chaincodePurgeState, "purge", "", false, "If true, also delete all the keys of the chaincode from the state")
+	chaincodeDeployCmd.Flags().StringVarP(&chaincodeEffectiveDate, "effective-date", "", undefinedParamValue, fmt.Sprintf("Date from which the %s can be invoked, in RFC 3339 format", chainFuncName))
+	chaincodeUpgradeCmd.Flags().StringVarP(&chaincodeEffectiveDate, "effective-date", "", undefinedParamValue, fmt.Sprintf("Date from which the new version of the %s can be invoked, in RFC 3339 format", chainFuncName))
 
 	chaincodeCmd.AddCommand(chaincodeDeployCmd)
+	chaincodeCmd.AddCommand(chaincodeUpgradeCmd)
 	chaincodeCmd.AddCommand(chaincodeInvokeCmd)
 	chaincodeCmd.AddCommand(chaincodeQueryCmd)
+	chaincodeCmd.AddCommand(chaincodeTerminateCmd)
 
 	mainCmd.AddCommand(chaincodeCmd)
 
@@ -347,6 +422,24 @@ func createEventHubServer() (net.Listener, *grpc.Server, error) {
 		grpcServer = grpc.NewServer(opts...)
 		ehServer := producer.NewEventsServer(uint(viper.GetInt("peer.validator.events.buffersize")), viper.GetInt("peer.validator.events.timeout"))
 		pb.RegisterEventsServer(grpcServer, ehServer)
+
+		//events of the blocks of the ledger are replayed on request
+		lgr, err := ledger.GetLedger()
+		if err != nil {
+			return nil, nil, fmt.Errorf("Failed to get ledger: %s", err)
+		}
+		producer.SetBlockSource(lgr)
+		producer.SetChainBlockSources(func(chainID string) (producer.BlockSource, error) {
+			if !peer.HostsChain(chainID) {
+				return nil, fmt.Errorf("chain %s is not hosted by this peer", chainID)
+			}
+			chainLedger, err := ledger.GetChainLedger(chainID)
+			if err != nil {
+				return nil, err
+			}
+			return chainLedger, nil
+		})
+		producer.SetAckCursorStore(producer.NewDBAckCursorStore())
 	}
 	return lis, grpcServer, err
 }
@@ -465,7 +558,13 @@ func serve(args []string) error {
 
 	var peerServer *peer.PeerImpl
 
-	discInstance := core.NewStaticDiscovery(viper.GetString("peer.discovery.rootnode"))
+	if viper.GetBool("ledger.verify.onStartup") {
+		if err = verifyLedgersOnStartup(); err != nil {
+			return err
+		}
+	}
+
+	discInstance := core.NewGossipDiscovery(viper.GetString("peer.discovery.rootnode"), viper.GetDuration("peer.discovery.ttl"))
 
 	//create the peerServer....
 	if peer.ValidatorEnabled() {
@@ -474,6 +573,11 @@ func serve(args []string) error {
 		if makeGenesisError != nil {
 			return makeGenesisError
 		}
+		for _, chainID := range peer.Chains() {
+			if makeGenesisError = genesis.MakeChainGenesis(chainID); makeGenesisError != nil {
+				return fmt.Errorf("Error creating the genesis block of chain %s: %s", chainID, makeGenesisError)
+			}
+		}
 		logger.Debugf("Running as validating peer - installing consensus %s", viper.GetString("peer.validator.consensus"))
 		peerServer, err = peer.NewPeerWithEngine(secHelperFunc, helper.GetEngine, discInstance)
 	} else {
@@ -560,6 +664,18 @@ func serve(args []string) error {
 		}()
 	}
 
+	if viper.GetBool("peer.metrics.enabled") {
+		go func() {
+			metricsListenAddress := viper.GetString("peer.metrics.listenAddress")
+			logger.Infof("Starting metrics server with listenAddress = %s", metricsListenAddress)
+			mux := http.NewServeMux()
+			mux.Handle("/metrics", metrics.Handler())
+			if metricsErr := http.ListenAndServe(metricsListenAddress, mux); metricsErr != nil {
+				logger.Errorf("Error starting metrics server: %s", metricsErr)
+			}
+		}()
+	}
+
 	// Block until grpc server exits
 	return <-serve
 }
@@ -619,6 +735,121 @@ func stop() (err error) {
 	return err
 }
 
+func archivePath(args []string) (string, error) {
+	if len(args) != 1 {
+		return "", errors.New("Must supply the path of the archive as the 1st and only parameter")
+	}
+	return filepath.Abs(args[0])
+}
+
+func printArchiveInfo(blocks, stateEntries, historyEntries uint64) {
+	fmt.Printf("%d blocks, %d state key-values, %d history entries\n", blocks, stateEntries, historyEntries)
+}
+
+func exportLedger(args []string) error {
+	path, err := archivePath(args)
+	if err != nil {
+		return err
+	}
+
+	clientConn, err := peer.NewPeerClientConnection()
+	if err == nil {
+		logger.Infof("Exporting ledger of the running peer to %s", path)
+		serverClient := pb.NewAdminClient(clientConn)
+		response, err := serverClient.ExportLedger(context.Background(), &pb.ExportLedgerRequest{Path: path})
+		if err != nil {
+			return fmt.Errorf("Error exporting ledger of the running peer: %s", err)
+		}
+		printArchiveInfo(response.Blocks, response.StateEntries, response.HistoryEntries)
+		return nil
+	}
+
+	logger.Infof("Exporting ledger to %s offline, could not connect to local peer: %s", path, err)
+	ledger, err := ledger.GetLedger()
+	if err != nil {
+		return err
+	}
+	info, err := ledger.ExportToFile(path)
+	if err != nil {
+		return fmt.Errorf("Error exporting ledger: %s", err)
+	}
+	printArchiveInfo(info.Blocks, info.StateEntries, info.HistoryEntries)
+	return nil
+}
+
+func importLedger(args []string) error {
+	path, err := archivePath(args)
+	if err != nil {
+		return err
+	}
+	if clientConn, err := peer.NewPeerClientConnection(); err == nil {
+		clientConn.Close()
+		return errors.New("Cannot import the ledger while the local peer is running, stop the peer first")
+	}
+
+	logger.Infof("Importing ledger from %s", path)
+	ledger, err := ledger.GetLedger()
+	if err != nil {
+		return err
+	}
+	info, err := ledger.ImportFromFile(path)
+	if err != nil {
+		return fmt.Errorf("Error importing ledger: %s", err)
+	}
+	printArchiveInfo(info.Blocks, info.StateEntries, info.HistoryEntries)
+	return nil
+}
+
+func verifyLedger() error {
+	if clientConn, err := peer.NewPeerClientConnection(); err == nil {
+		clientConn.Close()
+		return errors.New("Cannot verify the ledger while the local peer is running, stop the peer first")
+	}
+
+	if !peer.HostsChain(verifyChainID) {
+		return fmt.Errorf("Chain %s is not hosted by this peer", verifyChainID)
+	}
+	lgr, err := ledger.GetChainLedger(verifyChainID)
+	if err != nil {
+		return err
+	}
+	report, err := lgr.Verify(ledger.VerifyOptions{StateSampleRate: verifyStateSampleRate,
+		IndexSampleRate: verifyIndexSampleRate, Repair: verifyRepair})
+	if err != nil {
+		return fmt.Errorf("Error verifying ledger: %s", err)
+	}
+	fmt.Println(report)
+	if !report.OK() {
+		return errors.New("The ledger is inconsistent")
+	}
+	return nil
+}
+
+// verifyLedgersOnStartup verifies the ledgers of the chains hosted by the
+// peer. A ledger whose state cannot be repaired locally is left flagged as
+// damaged, for the consensus to transfer the state once the peer is started.
+func verifyLedgersOnStartup() error {
+	opts := ledger.VerifyOptions{StateSampleRate: viper.GetFloat64("ledger.verify.stateSampleRate"),
+		IndexSampleRate: viper.GetFloat64("ledger.verify.indexSampleRate"),
+		Repair:          viper.GetBool("ledger.verify.repair")}
+	for _, chainID := range append([]string{""}, peer.Chains()...) {
+		lgr, err := ledger.GetChainLedger(chainID)
+		if err != nil {
+			return err
+		}
+		report, err := lgr.Verify(opts)
+		if err != nil {
+			return fmt.Errorf("Error verifying the ledger of chain %q: %s", chainID, err)
+		}
+		if report.OK() {
+			logger.Infof("Verified the ledger of chain %q at height %d", chainID, report.Height)
+		} else {
+			logger.Warningf("The ledger of chain %q is inconsistent:\n%s", chainID, report)
+		}
+	}
+	return nil
+}
+
 // login confirms the enrollmentID and secret password of the client with the
 // CA and stores the enrollment certificate and key in the Devops server.
 func networkLogin(args []string) (err error) {
@@ -739,6 +970,19 @@ func registerChaincodeSupport(chainname chaincode.ChainName, grpcServer *grpc.Se
 	pb.RegisterChaincodeSupportServer(grpcServer, ccSrv)
 }
 
+// getEffectiveDate parses the effective date given on the command line, nil if
+// none was given
+func getEffectiveDate() (*google_protobuf.Timestamp, error) {
+	if chaincodeEffectiveDate == undefinedParamValue {
+		return nil, nil
+	}
+	date, err := time.Parse(time.RFC3339, chaincodeEffectiveDate)
+	if err != nil {
+		return nil, fmt.Errorf("Effective date error: %s", err)
+	}
+	return &google_protobuf.Timestamp{Seconds: date.Unix(), Nanos: int32(date.Nanosecond())}, nil
+}
+
 func checkChaincodeCmdParams(cmd *cobra.Command) (err error) {
 
 	if chaincodeName == undefinedParamValue {
@@ -825,9 +1069,15 @@ func chaincodeDeploy(cmd *cobra.Command, args []string) (err error) {
 		return
 	}
 
+	effectiveDate, err := getEffectiveDate()
+	if err != nil {
+		return
+	}
+
 	chaincodeLang = strings.ToUpper(chaincodeLang)
 	spec := &pb.ChaincodeSpec{Type: pb.ChaincodeSpec_Type(pb.ChaincodeSpec_Type_value[chaincodeLang]),
-		ChaincodeID: &pb.ChaincodeID{Path: chaincodePath, Name: chaincodeName}, CtorMsg: input, Attributes: attributes}
+		ChaincodeID: &pb.ChaincodeID{Path: chaincodePath, Name: chaincodeName}, CtorMsg: input, Attributes: attributes,
+		EffectiveDate: effectiveDate, ChainID: chaincodeChainID}
 
 	// If security is enabled, add client login token
 	if core.SecurityEnabled() {
@@ -884,9 +1134,106 @@ func chaincodeDeploy(cmd *cobra.Command, args []string) (err error) {
 	}
 	logger.Infof("Deploy result: %s", chaincodeDeploymentSpec.ChaincodeSpec)
 	fmt.Println(chaincodeDeploymentSpec.ChaincodeSpec.ChaincodeID.Name)
+	printEffectiveDate(chaincodeDeploymentSpec)
 	return nil
 }
 
+// chaincodeUpgrade deploys a new version of the chaincode under its name. On
+// success, the chaincode name and its new version are printed to STDOUT.
+func chaincodeUpgrade(cmd *cobra.Command, args []string) (err error) {
+	if chaincodeName == undefinedParamValue {
+		err = errors.New("Name not given for upgrade")
+		return
+	}
+	if err = checkChaincodeCmdParams(cmd); err != nil {
+		return
+	}
+	devopsClient, err := getDevopsClient(cmd)
+	if err != nil {
+		err = fmt.Errorf("Error building %s: %s", chainFuncName, err)
+		return
+	}
+	// Build the spec
+	input := &pb.ChaincodeInput{}
+	if err = json.Unmarshal([]byte(chaincodeCtorJSON), &input); err != nil {
+		err = fmt.Errorf("Chaincode argument error: %s", err)
+		return
+	}
+
+	var attributes []string
+	if err = json.Unmarshal([]byte(chaincodeAttributesJSON), &attributes); err != nil {
+		err = fmt.Errorf("Chaincode argument error: %s", err)
+		return
+	}
+
+	effectiveDate, err := getEffectiveDate()
+	if err != nil {
+		return
+	}
+
+	chaincodeLang = strings.ToUpper(chaincodeLang)
+	spec := &pb.ChaincodeSpec{Type: pb.ChaincodeSpec_Type(pb.ChaincodeSpec_Type_value[chaincodeLang]),
+		ChaincodeID: &pb.ChaincodeID{Path: chaincodePath, Name: chaincodeName}, CtorMsg: input, Attributes: attributes,
+		EffectiveDate: effectiveDate, ChainID: chaincodeChainID}
+
+	// If security is enabled, add client login token
+	if core.SecurityEnabled() {
+		logger.Debug("Security is enabled. Include security context in upgrade spec")
+		if chaincodeUsr == undefinedParamValue {
+			err = errors.New("Must supply username for chaincode when security is enabled")
+			return
+		}
+
+		// Retrieve the CLI data storage path
+		// Returns /var/openchain/production/client/
+		localStore := getCliFilePath()
+
+		// Check if the user is logged in before sending transaction
+		if _, err = os.Stat(localStore + "loginToken_" + chaincodeUsr); err == nil {
+			logger.Infof("Local user '%s' is already logged in. Retrieving login token.\n", chaincodeUsr)
+
+			// Read in the login token
+			token, err := ioutil.ReadFile(localStore + "loginToken_" + chaincodeUsr)
+			if err != nil {
+				panic(fmt.Errorf("Fatal error when reading client login token: %s\n", err))
+			}
+
+			// Add the login token to the chaincodeSpec. Confidential chaincodes
+			// cannot be upgraded.
+			spec.SecureContext = string(token)
+		} else {
+			// Check if the token is not there and fail
+			if os.IsNotExist(err) {
+				err = fmt.Errorf("User '%s' not logged in. Use the 'login' command to obtain a security token.", chaincodeUsr)
+				return
+			}
+			// Unexpected error
+			panic(fmt.Errorf("Fatal error when checking for client login token: %s\n", err))
+		}
+	} else if chaincodeUsr != undefinedParamValue {
+		logger.Warning("Username supplied but security is disabled.")
+	}
+
+	chaincodeDeploymentSpec, err := devopsClient.Upgrade(context.Background(), spec)
+	if err != nil {
+		err = fmt.Errorf("Error upgrading %s: %s\n", chainFuncName, err)
+		return
+	}
+	logger.Infof("Upgrade result: %s", chaincodeDeploymentSpec.ChaincodeSpec)
+	fmt.Printf("%s %d\n", chaincodeDeploymentSpec.ChaincodeSpec.ChaincodeID.Name, chaincodeDeploymentSpec.ChaincodeSpec.ChaincodeID.Version)
+	printEffectiveDate(chaincodeDeploymentSpec)
+	return nil
+}
+
+// printEffectiveDate prints the pending activation of a deployed chaincode, if
+// any. Invokes are rejected until a block timestamped at or after the
+// effective date is committed.
+func printEffectiveDate(chaincodeDeploymentSpec *pb.ChaincodeDeploymentSpec) {
+	if chaincodeDeploymentSpec.EffectiveDate != nil {
+		fmt.Printf("Pending activation at %s\n", chaincode.FormatEffectiveDate(chaincodeDeploymentSpec.EffectiveDate))
+	}
+}
+
 func chaincodeInvoke(cmd *cobra.Command, args []string) error {
 	return chaincodeInvokeOrQuery(cmd, args, true)
 }
@@ -932,7 +1279,7 @@ func chaincodeInvokeOrQuery(cmd *cobra.Command, args []string, invoke bool) (err
 
 	chaincodeLang = strings.ToUpper(chaincodeLang)
 	spec := &pb.ChaincodeSpec{Type: pb.ChaincodeSpec_Type(pb.ChaincodeSpec_Type_value[chaincodeLang]),
-		ChaincodeID: &pb.ChaincodeID{Name: chaincodeName}, CtorMsg: input, Attributes: attributes}
+		ChaincodeID: &pb.ChaincodeID{Name: chaincodeName}, CtorMsg: input, Attributes: attributes, ChainID: chaincodeChainID}
 
 	// If security is enabled, add client login token
 	if core.SecurityEnabled() {
@@ -1027,6 +1374,74 @@ func chaincodeInvokeOrQuery(cmd *cobra.Command, args []string, invoke bool) (err
 	return nil
 }
 
+// chaincodeTerminate terminates the chaincode. If successful, the transaction
+// ID is printed on STDOUT. A command-line flag (--purge) determines whether the
+// state of the chaincode is deleted as well.
+func chaincodeTerminate(cmd *cobra.Command, args []string) (err error) {
+	if chaincodeName == undefinedParamValue {
+		err = errors.New("Name not given for terminate")
+		return
+	}
+
+	devopsClient, err := getDevopsClient(cmd)
+	if err != nil {
+		err = fmt.Errorf("Error building %s: %s", chainFuncName, err)
+		return
+	}
+
+	chaincodeLang = strings.ToUpper(chaincodeLang)
+	spec := &pb.ChaincodeSpec{Type: pb.ChaincodeSpec_Type(pb.ChaincodeSpec_Type_value[chaincodeLang]),
+		ChaincodeID: &pb.ChaincodeID{Name: chaincodeName}, ChainID: chaincodeChainID}
+
+	// If security is enabled, add client login token
+	if core.SecurityEnabled() {
+		if chaincodeUsr == undefinedParamValue {
+			err = errors.New("Must supply username for chaincode when security is enabled")
+			return
+		}
+
+		// Retrieve the CLI data storage path
+		// Returns /var/openchain/production/client/
+		localStore := getCliFilePath()
+
+		// Check if the user is logged in before sending transaction
+		if _, err = os.Stat(localStore + "loginToken_" + chaincodeUsr); err == nil {
+			logger.Infof("Local user '%s' is already logged in. Retrieving login token.\n", chaincodeUsr)
+
+			// Read in the login token
+			token, err := ioutil.ReadFile(localStore + "loginToken_" + chaincodeUsr)
+			if err != nil {
+				panic(fmt.Errorf("Fatal error when reading client login token: %s\n", err))
+			}
+
+			// Add the login token to the chaincodeSpec. Terminate transactions
+			// are never confidential.
+			spec.SecureContext = string(token)
+		} else {
+			// Check if the token is not there and fail
+			if os.IsNotExist(err) {
+				err = fmt.Errorf("User '%s' not logged in. Use the 'login' command to obtain a security token.", chaincodeUsr)
+				return
+			}
+			// Unexpected error
+			panic(fmt.Errorf("Fatal error when checking for client login token: %s\n", err))
+		}
+	} else if chaincodeUsr != undefinedParamValue {
+		logger.Warning("Username supplied but security is disabled.")
+	}
+
+	termination := &pb.ChaincodeTerminationSpec{ChaincodeSpec: spec, PurgeState: chaincodePurgeState}
+	resp, err := devopsClient.Terminate(context.Background(), termination)
+	if err != nil {
+		err = fmt.Errorf("Error terminating %s: %s\n", chainFuncName, err)
+		return
+	}
+	transactionID := string(resp.Msg)
+	logger.Infof("Successfully submitted terminate transaction: %s(%s)", termination, transactionID)
+	fmt.Println(transactionID)
+	return nil
+}
+
 // Show a list of all existing network connections for the target peer node,
 // includes both validating and non-validating peers
 func networkList() (err error) {
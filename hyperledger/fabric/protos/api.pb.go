@@ -17,12 +17,21 @@ It is generated from these files:
 It has these top-level messages:
 	BlockNumber
 	BlockCount
+	TransactionUUID
+	BlocksRange
+	StateKey
+	StateValue
+	StateRange
+	StateRangeResponse
+	ChaincodeInfoRequest
+	ChaincodeInfo
 	ChaincodeEvent
 	ChaincodeID
 	ChaincodeInput
 	ChaincodeSpec
 	ChaincodeDeploymentSpec
 	ChaincodeInvocationSpec
+	ChaincodeTerminationSpec
 	ChaincodeSecurityContext
 	ChaincodeMessage
 	PutStateInfo
@@ -31,6 +40,10 @@ It has these top-level messages:
 	RangeQueryStateClose
 	RangeQueryStateKeyValue
 	RangeQueryStateResponse
+	GetHistoryForKey
+	KeyModification
+	HistoryQueryResponse
+	GetQueryResult
 	Secret
 	SigmaInput
 	ExecuteWithBinding
@@ -40,6 +53,9 @@ It has these top-level messages:
 	ChaincodeReg
 	Interest
 	Register
+	Ack
+	FilteredTransaction
+	FilteredBlock
 	Event
 	Transaction
 	TransactionBlock
@@ -62,6 +78,8 @@ It has these top-level messages:
 	SyncStateDeltasRequest
 	SyncStateDeltas
 	ServerStatus
+	ExportLedgerRequest
+	ExportLedgerResponse
 */
 package protos
 
@@ -98,6 +116,121 @@ func (m *BlockCount) Reset()         { *m = BlockCount{} }
 func (m *BlockCount) String() string { return proto.CompactTextString(m) }
 func (*BlockCount) ProtoMessage()    {}
 
+// Specifies a transaction of the blockchain of a chain, the default chain if
+// chainID is empty.
+type TransactionUUID struct {
+	ChainID string `protobuf:"bytes,1,opt,name=chainID" json:"chainID,omitempty"`
+	Uuid    string `protobuf:"bytes,2,opt,name=uuid" json:"uuid,omitempty"`
+}
+
+func (m *TransactionUUID) Reset()         { *m = TransactionUUID{} }
+func (m *TransactionUUID) String() string { return proto.CompactTextString(m) }
+func (*TransactionUUID) ProtoMessage()    {}
+
+// Specifies the range of blocks to be returned from the blockchain of a chain.
+type BlocksRange struct {
+	ChainID string `protobuf:"bytes,1,opt,name=chainID" json:"chainID,omitempty"`
+	Start   uint64 `protobuf:"varint,2,opt,name=start" json:"start,omitempty"`
+	End     uint64 `protobuf:"varint,3,opt,name=end" json:"end,omitempty"`
+}
+
+func (m *BlocksRange) Reset()         { *m = BlocksRange{} }
+func (m *BlocksRange) String() string { return proto.CompactTextString(m) }
+func (*BlocksRange) ProtoMessage()    {}
+
+// Specifies a key in the state of a chaincode.
+type StateKey struct {
+	ChainID     string `protobuf:"bytes,1,opt,name=chainID" json:"chainID,omitempty"`
+	ChaincodeID string `protobuf:"bytes,2,opt,name=chaincodeID" json:"chaincodeID,omitempty"`
+	Key         string `protobuf:"bytes,3,opt,name=key" json:"key,omitempty"`
+}
+
+func (m *StateKey) Reset()         { *m = StateKey{} }
+func (m *StateKey) String() string { return proto.CompactTextString(m) }
+func (*StateKey) ProtoMessage()    {}
+
+// The value of a key, empty if the key is not in the state.
+type StateValue struct {
+	Value []byte `protobuf:"bytes,1,opt,name=value,proto3" json:"value,omitempty"`
+}
+
+func (m *StateValue) Reset()         { *m = StateValue{} }
+func (m *StateValue) String() string { return proto.CompactTextString(m) }
+func (*StateValue) ProtoMessage()    {}
+
+// Specifies a range of keys in the state of a chaincode. An empty endKey
+// ends the range with the last key. At most limit keys are returned, the
+// server maximum if zero.
+type StateRange struct {
+	ChainID     string `protobuf:"bytes,1,opt,name=chainID" json:"chainID,omitempty"`
+	ChaincodeID string `protobuf:"bytes,2,opt,name=chaincodeID" json:"chaincodeID,omitempty"`
+	StartKey    string `protobuf:"bytes,3,opt,name=startKey" json:"startKey,omitempty"`
+	EndKey      string `protobuf:"bytes,4,opt,name=endKey" json:"endKey,omitempty"`
+	Limit       uint32 `protobuf:"varint,5,opt,name=limit" json:"limit,omitempty"`
+}
+
+func (m *StateRange) Reset()         { *m = StateRange{} }
+func (m *StateRange) String() string { return proto.CompactTextString(m) }
+func (*StateRange) ProtoMessage()    {}
+
+// The keys of a range, hasMore being set if the range has more keys than
+// returned.
+type StateRangeResponse struct {
+	KeysAndValues []*RangeQueryStateKeyValue `protobuf:"bytes,1,rep,name=keysAndValues" json:"keysAndValues,omitempty"`
+	HasMore       bool                       `protobuf:"varint,2,opt,name=hasMore" json:"hasMore,omitempty"`
+}
+
+func (m *StateRangeResponse) Reset()         { *m = StateRangeResponse{} }
+func (m *StateRangeResponse) String() string { return proto.CompactTextString(m) }
+func (*StateRangeResponse) ProtoMessage()    {}
+
+func (m *StateRangeResponse) GetKeysAndValues() []*RangeQueryStateKeyValue {
+	if m != nil {
+		return m.KeysAndValues
+	}
+	return nil
+}
+
+// Specifies a deployed chaincode by its name.
+type ChaincodeInfoRequest struct {
+	ChainID string `protobuf:"bytes,1,opt,name=chainID" json:"chainID,omitempty"`
+	Name    string `protobuf:"bytes,2,opt,name=name" json:"name,omitempty"`
+}
+
+func (m *ChaincodeInfoRequest) Reset()         { *m = ChaincodeInfoRequest{} }
+func (m *ChaincodeInfoRequest) String() string { return proto.CompactTextString(m) }
+func (*ChaincodeInfoRequest) ProtoMessage()    {}
+
+// Describes the current version of a deployed chaincode. The path and type
+// of a confidential chaincode are not disclosed.
+type ChaincodeInfo struct {
+	ChaincodeID *ChaincodeID       `protobuf:"bytes,1,opt,name=chaincodeID" json:"chaincodeID,omitempty"`
+	Type        ChaincodeSpec_Type `protobuf:"varint,2,opt,name=type,enum=protos.ChaincodeSpec_Type" json:"type,omitempty"`
+	// UUID of the transaction which deployed the current version
+	TransactionUUID string                      `protobuf:"bytes,3,opt,name=transactionUUID" json:"transactionUUID,omitempty"`
+	EffectiveDate   *google_protobuf1.Timestamp `protobuf:"bytes,4,opt,name=effectiveDate" json:"effectiveDate,omitempty"`
+	Active          bool                        `protobuf:"varint,5,opt,name=active" json:"active,omitempty"`
+	Terminated      bool                        `protobuf:"varint,6,opt,name=terminated" json:"terminated,omitempty"`
+}
+
+func (m *ChaincodeInfo) Reset()         { *m = ChaincodeInfo{} }
+func (m *ChaincodeInfo) String() string { return proto.CompactTextString(m) }
+func (*ChaincodeInfo) ProtoMessage()    {}
+
+func (m *ChaincodeInfo) GetChaincodeID() *ChaincodeID {
+	if m != nil {
+		return m.ChaincodeID
+	}
+	return nil
+}
+
+func (m *ChaincodeInfo) GetEffectiveDate() *google_protobuf1.Timestamp {
+	if m != nil {
+		return m.EffectiveDate
+	}
+	return nil
+}
+
 // Reference imports to suppress errors if they are not otherwise used.
 var _ context.Context
 var _ grpc.ClientConn
@@ -117,6 +250,23 @@ type OpenchainClient interface {
 	// GetPeers returns a list of all peer nodes currently connected to the target
 	// peer.
 	GetPeers(ctx context.Context, in *google_protobuf1.Empty, opts ...grpc.CallOption) (*PeersMessage, error)
+	// GetTransactionByUUID returns a committed transaction.
+	GetTransactionByUUID(ctx context.Context, in *TransactionUUID, opts ...grpc.CallOption) (*Transaction, error)
+	// GetTransactionResult returns the result of a committed transaction.
+	GetTransactionResult(ctx context.Context, in *TransactionUUID, opts ...grpc.CallOption) (*TransactionResult, error)
+	// GetBlocksRange streams the blocks from start to end, both included, up to
+	// the last block. The code packages of the deploy transactions are left out.
+	GetBlocksRange(ctx context.Context, in *BlocksRange, opts ...grpc.CallOption) (Openchain_GetBlocksRangeClient, error)
+	// GetState returns the committed value of a key in the state of the
+	// current version of a chaincode. The peer must run with
+	// rest.stateQueries.enabled.
+	GetState(ctx context.Context, in *StateKey, opts ...grpc.CallOption) (*StateValue, error)
+	// GetStateRange returns the committed keys between startKey and endKey,
+	// both included, in the state of the current version of a chaincode,
+	// ordered by key. The peer must run with rest.stateQueries.enabled.
+	GetStateRange(ctx context.Context, in *StateRange, opts ...grpc.CallOption) (*StateRangeResponse, error)
+	// GetChaincodeInfo returns the current version of a deployed chaincode.
+	GetChaincodeInfo(ctx context.Context, in *ChaincodeInfoRequest, opts ...grpc.CallOption) (*ChaincodeInfo, error)
 }
 
 type openchainClient struct {
@@ -163,6 +313,83 @@ func (c *openchainClient) GetPeers(ctx context.Context, in *google_protobuf1.Emp
 	return out, nil
 }
 
+func (c *openchainClient) GetTransactionByUUID(ctx context.Context, in *TransactionUUID, opts ...grpc.CallOption) (*Transaction, error) {
+	out := new(Transaction)
+	err := grpc.Invoke(ctx, "/protos.Openchain/GetTransactionByUUID", in, out, c.cc, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *openchainClient) GetTransactionResult(ctx context.Context, in *TransactionUUID, opts ...grpc.CallOption) (*TransactionResult, error) {
+	out := new(TransactionResult)
+	err := grpc.Invoke(ctx, "/protos.Openchain/GetTransactionResult", in, out, c.cc, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *openchainClient) GetBlocksRange(ctx context.Context, in *BlocksRange, opts ...grpc.CallOption) (Openchain_GetBlocksRangeClient, error) {
+	stream, err := grpc.NewClientStream(ctx, &_Openchain_serviceDesc.Streams[0], c.cc, "/protos.Openchain/GetBlocksRange", opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &openchainGetBlocksRangeClient{stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+type Openchain_GetBlocksRangeClient interface {
+	Recv() (*Block, error)
+	grpc.ClientStream
+}
+
+type openchainGetBlocksRangeClient struct {
+	grpc.ClientStream
+}
+
+func (x *openchainGetBlocksRangeClient) Recv() (*Block, error) {
+	m := new(Block)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func (c *openchainClient) GetState(ctx context.Context, in *StateKey, opts ...grpc.CallOption) (*StateValue, error) {
+	out := new(StateValue)
+	err := grpc.Invoke(ctx, "/protos.Openchain/GetState", in, out, c.cc, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *openchainClient) GetStateRange(ctx context.Context, in *StateRange, opts ...grpc.CallOption) (*StateRangeResponse, error) {
+	out := new(StateRangeResponse)
+	err := grpc.Invoke(ctx, "/protos.Openchain/GetStateRange", in, out, c.cc, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *openchainClient) GetChaincodeInfo(ctx context.Context, in *ChaincodeInfoRequest, opts ...grpc.CallOption) (*ChaincodeInfo, error) {
+	out := new(ChaincodeInfo)
+	err := grpc.Invoke(ctx, "/protos.Openchain/GetChaincodeInfo", in, out, c.cc, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 // Server API for Openchain service
 
 type OpenchainServer interface {
@@ -178,6 +405,23 @@ type OpenchainServer interface {
 	// GetPeers returns a list of all peer nodes currently connected to the target
 	// peer.
 	GetPeers(context.Context, *google_protobuf1.Empty) (*PeersMessage, error)
+	// GetTransactionByUUID returns a committed transaction.
+	GetTransactionByUUID(context.Context, *TransactionUUID) (*Transaction, error)
+	// GetTransactionResult returns the result of a committed transaction.
+	GetTransactionResult(context.Context, *TransactionUUID) (*TransactionResult, error)
+	// GetBlocksRange streams the blocks from start to end, both included, up to
+	// the last block. The code packages of the deploy transactions are left out.
+	GetBlocksRange(*BlocksRange, Openchain_GetBlocksRangeServer) error
+	// GetState returns the committed value of a key in the state of the
+	// current version of a chaincode. The peer must run with
+	// rest.stateQueries.enabled.
+	GetState(context.Context, *StateKey) (*StateValue, error)
+	// GetStateRange returns the committed keys between startKey and endKey,
+	// both included, in the state of the current version of a chaincode,
+	// ordered by key. The peer must run with rest.stateQueries.enabled.
+	GetStateRange(context.Context, *StateRange) (*StateRangeResponse, error)
+	// GetChaincodeInfo returns the current version of a deployed chaincode.
+	GetChaincodeInfo(context.Context, *ChaincodeInfoRequest) (*ChaincodeInfo, error)
 }
 
 func RegisterOpenchainServer(s *grpc.Server, srv OpenchainServer) {
@@ -232,6 +476,87 @@ func _Openchain_GetPeers_Handler(srv interface{}, ctx context.Context, dec func(
 	return out, nil
 }
 
+func _Openchain_GetTransactionByUUID_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error) (interface{}, error) {
+	in := new(TransactionUUID)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	out, err := srv.(OpenchainServer).GetTransactionByUUID(ctx, in)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func _Openchain_GetTransactionResult_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error) (interface{}, error) {
+	in := new(TransactionUUID)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	out, err := srv.(OpenchainServer).GetTransactionResult(ctx, in)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func _Openchain_GetBlocksRange_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(BlocksRange)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(OpenchainServer).GetBlocksRange(m, &openchainGetBlocksRangeServer{stream})
+}
+
+type Openchain_GetBlocksRangeServer interface {
+	Send(*Block) error
+	grpc.ServerStream
+}
+
+type openchainGetBlocksRangeServer struct {
+	grpc.ServerStream
+}
+
+func (x *openchainGetBlocksRangeServer) Send(m *Block) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+func _Openchain_GetState_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error) (interface{}, error) {
+	in := new(StateKey)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	out, err := srv.(OpenchainServer).GetState(ctx, in)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func _Openchain_GetStateRange_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error) (interface{}, error) {
+	in := new(StateRange)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	out, err := srv.(OpenchainServer).GetStateRange(ctx, in)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func _Openchain_GetChaincodeInfo_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error) (interface{}, error) {
+	in := new(ChaincodeInfoRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	out, err := srv.(OpenchainServer).GetChaincodeInfo(ctx, in)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 var _Openchain_serviceDesc = grpc.ServiceDesc{
 	ServiceName: "protos.Openchain",
 	HandlerType: (*OpenchainServer)(nil),
@@ -252,6 +577,32 @@ var _Openchain_serviceDesc = grpc.ServiceDesc{
 			MethodName: "GetPeers",
 			Handler:    _Openchain_GetPeers_Handler,
 		},
+		{
+			MethodName: "GetTransactionByUUID",
+			Handler:    _Openchain_GetTransactionByUUID_Handler,
+		},
+		{
+			MethodName: "GetTransactionResult",
+			Handler:    _Openchain_GetTransactionResult_Handler,
+		},
+		{
+			MethodName: "GetState",
+			Handler:    _Openchain_GetState_Handler,
+		},
+		{
+			MethodName: "GetStateRange",
+			Handler:    _Openchain_GetStateRange_Handler,
+		},
+		{
+			MethodName: "GetChaincodeInfo",
+			Handler:    _Openchain_GetChaincodeInfo_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "GetBlocksRange",
+			Handler:       _Openchain_GetBlocksRange_Handler,
+			ServerStreams: true,
+		},
 	},
-	Streams: []grpc.StreamDesc{},
 }
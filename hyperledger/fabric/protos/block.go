@@ -20,6 +20,8 @@ import (
 	"fmt"
 
 	"github.com/golang/protobuf/proto"
+	google_protobuf "google/protobuf"
+
 	"github.com/hyperledger/fabric/core/util"
 )
 
@@ -58,6 +60,18 @@ func NewBlock(transactions []*Transaction, metadata []byte) *Block {
 	return block
 }
 
+// TimestampBefore returns true if timestamp t1 is before t2. A nil timestamp
+// is before any other.
+func TimestampBefore(t1, t2 *google_protobuf.Timestamp) bool {
+	if t2 == nil {
+		return false
+	}
+	if t1 == nil {
+		return true
+	}
+	return t1.Seconds < t2.Seconds || (t1.Seconds == t2.Seconds && t1.Nanos < t2.Nanos)
+}
+
 // GetHash returns the hash of this block.
 func (block *Block) GetHash() ([]byte, error) {
 
@@ -22,6 +22,8 @@ import (
 	"time"
 
 	"github.com/golang/protobuf/proto"
+	google_protobuf "google/protobuf"
+
 	"github.com/hyperledger/fabric/core/util"
 )
 
@@ -88,3 +90,15 @@ func TestBlockNonHashData(t *testing.T) {
 		t.Fatalf("Expected time2 and block2 times to be equal, but there were not")
 	}
 }
+
+func TestTimestampBefore(t *testing.T) {
+	t1 := &google_protobuf.Timestamp{Seconds: 20, Nanos: 1}
+	t2 := &google_protobuf.Timestamp{Seconds: 20, Nanos: 2}
+	t3 := &google_protobuf.Timestamp{Seconds: 10}
+	if !TimestampBefore(t1, t2) || TimestampBefore(t2, t1) || !TimestampBefore(t3, t1) || TimestampBefore(t1, t1) {
+		t.Fatalf("Expected %v < %v and %v < %v", t1, t2, t3, t1)
+	}
+	if !TimestampBefore(nil, t3) || TimestampBefore(t3, nil) || TimestampBefore(nil, nil) {
+		t.Fatalf("Expected a nil timestamp to be before any other")
+	}
+}
@@ -110,6 +110,9 @@ const (
 	ChaincodeMessage_RANGE_QUERY_STATE_NEXT  ChaincodeMessage_Type = 18
 	ChaincodeMessage_RANGE_QUERY_STATE_CLOSE ChaincodeMessage_Type = 19
 	ChaincodeMessage_KEEPALIVE               ChaincodeMessage_Type = 20
+	ChaincodeMessage_GET_HISTORY_FOR_KEY     ChaincodeMessage_Type = 21
+	ChaincodeMessage_UPGRADE                 ChaincodeMessage_Type = 22
+	ChaincodeMessage_GET_QUERY_RESULT        ChaincodeMessage_Type = 23
 )
 
 var ChaincodeMessage_Type_name = map[int32]string{
@@ -134,6 +137,9 @@ var ChaincodeMessage_Type_name = map[int32]string{
 	18: "RANGE_QUERY_STATE_NEXT",
 	19: "RANGE_QUERY_STATE_CLOSE",
 	20: "KEEPALIVE",
+	21: "GET_HISTORY_FOR_KEY",
+	22: "UPGRADE",
+	23: "GET_QUERY_RESULT",
 }
 var ChaincodeMessage_Type_value = map[string]int32{
 	"UNDEFINED":               0,
@@ -157,6 +163,9 @@ var ChaincodeMessage_Type_value = map[string]int32{
 	"RANGE_QUERY_STATE_NEXT":  18,
 	"RANGE_QUERY_STATE_CLOSE": 19,
 	"KEEPALIVE":               20,
+	"GET_HISTORY_FOR_KEY":     21,
+	"UPGRADE":                 22,
+	"GET_QUERY_RESULT":        23,
 }
 
 func (x ChaincodeMessage_Type) String() string {
@@ -176,6 +185,8 @@ type ChaincodeID struct {
 	// all other requests will use the name (really a hashcode) generated by
 	// the deploy transaction
 	Name string `protobuf:"bytes,2,opt,name=name" json:"name,omitempty"`
+	// version of the chaincode, incremented by every upgrade transaction
+	Version uint64 `protobuf:"varint,3,opt,name=version" json:"version,omitempty"`
 }
 
 func (m *ChaincodeID) Reset()         { *m = ChaincodeID{} }
@@ -203,6 +214,11 @@ type ChaincodeSpec struct {
 	ConfidentialityLevel ConfidentialityLevel `protobuf:"varint,6,opt,name=confidentialityLevel,enum=protos.ConfidentialityLevel" json:"confidentialityLevel,omitempty"`
 	Metadata             []byte               `protobuf:"bytes,7,opt,name=metadata,proto3" json:"metadata,omitempty"`
 	Attributes           []string             `protobuf:"bytes,8,rep,name=attributes" json:"attributes,omitempty"`
+	// Only used by deploy and upgrade requests: the effective date of the
+	// resulting deployment spec.
+	EffectiveDate *google_protobuf.Timestamp `protobuf:"bytes,9,opt,name=effectiveDate" json:"effectiveDate,omitempty"`
+	// the chain of the transaction, empty for the default chain
+	ChainID string `protobuf:"bytes,10,opt,name=chainID" json:"chainID,omitempty"`
 }
 
 func (m *ChaincodeSpec) Reset()         { *m = ChaincodeSpec{} }
@@ -223,6 +239,13 @@ func (m *ChaincodeSpec) GetCtorMsg() *ChaincodeInput {
 	return nil
 }
 
+func (m *ChaincodeSpec) GetEffectiveDate() *google_protobuf.Timestamp {
+	if m != nil {
+		return m.EffectiveDate
+	}
+	return nil
+}
+
 // Specify the deployment of a chaincode.
 // TODO: Define `codePackage`.
 type ChaincodeDeploymentSpec struct {
@@ -275,6 +298,24 @@ func (m *ChaincodeInvocationSpec) GetChaincodeSpec() *ChaincodeSpec {
 	return nil
 }
 
+// Specifies the chaincode to terminate. If purgeState is set, all the keys of
+// the chaincode are deleted from the state as well.
+type ChaincodeTerminationSpec struct {
+	ChaincodeSpec *ChaincodeSpec `protobuf:"bytes,1,opt,name=chaincodeSpec" json:"chaincodeSpec,omitempty"`
+	PurgeState    bool           `protobuf:"varint,2,opt,name=purgeState" json:"purgeState,omitempty"`
+}
+
+func (m *ChaincodeTerminationSpec) Reset()         { *m = ChaincodeTerminationSpec{} }
+func (m *ChaincodeTerminationSpec) String() string { return proto.CompactTextString(m) }
+func (*ChaincodeTerminationSpec) ProtoMessage()    {}
+
+func (m *ChaincodeTerminationSpec) GetChaincodeSpec() *ChaincodeSpec {
+	if m != nil {
+		return m.ChaincodeSpec
+	}
+	return nil
+}
+
 // This structure contain transaction data that we send to the chaincode
 // container shim and allow the chaincode to access through the shim interface.
 // TODO: Consider remove this message and just pass the transaction object
@@ -397,6 +438,51 @@ func (m *RangeQueryStateResponse) GetKeysAndValues() []*RangeQueryStateKeyValue
 	return nil
 }
 
+type GetHistoryForKey struct {
+	Key string `protobuf:"bytes,1,opt,name=key" json:"key,omitempty"`
+}
+
+func (m *GetHistoryForKey) Reset()         { *m = GetHistoryForKey{} }
+func (m *GetHistoryForKey) String() string { return proto.CompactTextString(m) }
+func (*GetHistoryForKey) ProtoMessage()    {}
+
+// A committed change of a key, as recorded by the ledger history index
+type KeyModification struct {
+	TxID        string `protobuf:"bytes,1,opt,name=txID" json:"txID,omitempty"`
+	BlockNumber uint64 `protobuf:"varint,2,opt,name=blockNumber" json:"blockNumber,omitempty"`
+	Value       []byte `protobuf:"bytes,3,opt,name=value,proto3" json:"value,omitempty"`
+	IsDelete    bool   `protobuf:"varint,4,opt,name=isDelete" json:"isDelete,omitempty"`
+}
+
+func (m *KeyModification) Reset()         { *m = KeyModification{} }
+func (m *KeyModification) String() string { return proto.CompactTextString(m) }
+func (*KeyModification) ProtoMessage()    {}
+
+type HistoryQueryResponse struct {
+	Modifications []*KeyModification `protobuf:"bytes,1,rep,name=modifications" json:"modifications,omitempty"`
+}
+
+func (m *HistoryQueryResponse) Reset()         { *m = HistoryQueryResponse{} }
+func (m *HistoryQueryResponse) String() string { return proto.CompactTextString(m) }
+func (*HistoryQueryResponse) ProtoMessage()    {}
+
+func (m *HistoryQueryResponse) GetModifications() []*KeyModification {
+	if m != nil {
+		return m.Modifications
+	}
+	return nil
+}
+
+// A rich query over the JSON values of the state of a chaincode. The matching
+// key-values are returned in a RangeQueryStateResponse.
+type GetQueryResult struct {
+	Query string `protobuf:"bytes,1,opt,name=query" json:"query,omitempty"`
+}
+
+func (m *GetQueryResult) Reset()         { *m = GetQueryResult{} }
+func (m *GetQueryResult) String() string { return proto.CompactTextString(m) }
+func (*GetQueryResult) ProtoMessage()    {}
+
 func init() {
 	proto.RegisterEnum("protos.ConfidentialityLevel", ConfidentialityLevel_name, ConfidentialityLevel_value)
 	proto.RegisterEnum("protos.ChaincodeSpec_Type", ChaincodeSpec_Type_name, ChaincodeSpec_Type_value)
@@ -142,6 +142,10 @@ type DevopsClient interface {
 	Invoke(ctx context.Context, in *ChaincodeInvocationSpec, opts ...grpc.CallOption) (*Response, error)
 	// Invoke chaincode.
 	Query(ctx context.Context, in *ChaincodeInvocationSpec, opts ...grpc.CallOption) (*Response, error)
+	// Terminate the chaincode, undeploying it from the chain.
+	Terminate(ctx context.Context, in *ChaincodeTerminationSpec, opts ...grpc.CallOption) (*Response, error)
+	// Upgrade the chaincode, deploying a new version of its package to the chain.
+	Upgrade(ctx context.Context, in *ChaincodeSpec, opts ...grpc.CallOption) (*ChaincodeDeploymentSpec, error)
 	// Request a TransactionResult.  The Response.Msg will contain the TransactionResult if successfully found the transaction in the chain.
 	GetTransactionResult(ctx context.Context, in *TransactionRequest, opts ...grpc.CallOption) (*Response, error)
 	// Retrieve a TCert.
@@ -207,6 +211,24 @@ func (c *devopsClient) Query(ctx context.Context, in *ChaincodeInvocationSpec, o
 	return out, nil
 }
 
+func (c *devopsClient) Terminate(ctx context.Context, in *ChaincodeTerminationSpec, opts ...grpc.CallOption) (*Response, error) {
+	out := new(Response)
+	err := grpc.Invoke(ctx, "/protos.Devops/Terminate", in, out, c.cc, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *devopsClient) Upgrade(ctx context.Context, in *ChaincodeSpec, opts ...grpc.CallOption) (*ChaincodeDeploymentSpec, error) {
+	out := new(ChaincodeDeploymentSpec)
+	err := grpc.Invoke(ctx, "/protos.Devops/Upgrade", in, out, c.cc, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 func (c *devopsClient) GetTransactionResult(ctx context.Context, in *TransactionRequest, opts ...grpc.CallOption) (*Response, error) {
 	out := new(Response)
 	err := grpc.Invoke(ctx, "/protos.Devops/GetTransactionResult", in, out, c.cc, opts...)
@@ -266,6 +288,10 @@ type DevopsServer interface {
 	Invoke(context.Context, *ChaincodeInvocationSpec) (*Response, error)
 	// Invoke chaincode.
 	Query(context.Context, *ChaincodeInvocationSpec) (*Response, error)
+	// Terminate the chaincode, undeploying it from the chain.
+	Terminate(context.Context, *ChaincodeTerminationSpec) (*Response, error)
+	// Upgrade the chaincode, deploying a new version of its package to the chain.
+	Upgrade(context.Context, *ChaincodeSpec) (*ChaincodeDeploymentSpec, error)
 	// Request a TransactionResult.  The Response.Msg will contain the TransactionResult if successfully found the transaction in the chain.
 	GetTransactionResult(context.Context, *TransactionRequest) (*Response, error)
 	// Retrieve a TCert.
@@ -342,6 +368,30 @@ func _Devops_Query_Handler(srv interface{}, ctx context.Context, dec func(interf
 	return out, nil
 }
 
+func _Devops_Terminate_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error) (interface{}, error) {
+	in := new(ChaincodeTerminationSpec)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	out, err := srv.(DevopsServer).Terminate(ctx, in)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func _Devops_Upgrade_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error) (interface{}, error) {
+	in := new(ChaincodeSpec)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	out, err := srv.(DevopsServer).Upgrade(ctx, in)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 func _Devops_GetTransactionResult_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error) (interface{}, error) {
 	in := new(TransactionRequest)
 	if err := dec(in); err != nil {
@@ -426,6 +476,14 @@ var _Devops_serviceDesc = grpc.ServiceDesc{
 			MethodName: "Query",
 			Handler:    _Devops_Query_Handler,
 		},
+		{
+			MethodName: "Terminate",
+			Handler:    _Devops_Terminate_Handler,
+		},
+		{
+			MethodName: "Upgrade",
+			Handler:    _Devops_Upgrade_Handler,
+		},
 		{
 			MethodName: "GetTransactionResult",
 			Handler:    _Devops_GetTransactionResult_Handler,
@@ -21,31 +21,59 @@ var _ = math.Inf
 type EventType int32
 
 const (
-	EventType_REGISTER  EventType = 0
-	EventType_BLOCK     EventType = 1
-	EventType_CHAINCODE EventType = 2
+	EventType_REGISTER      EventType = 0
+	EventType_BLOCK         EventType = 1
+	EventType_CHAINCODE     EventType = 2
+	EventType_FILTEREDBLOCK EventType = 3
 )
 
 var EventType_name = map[int32]string{
 	0: "REGISTER",
 	1: "BLOCK",
 	2: "CHAINCODE",
+	3: "FILTEREDBLOCK",
 }
 var EventType_value = map[string]int32{
-	"REGISTER":  0,
-	"BLOCK":     1,
-	"CHAINCODE": 2,
+	"REGISTER":      0,
+	"BLOCK":         1,
+	"CHAINCODE":     2,
+	"FILTEREDBLOCK": 3,
 }
 
 func (x EventType) String() string {
 	return proto.EnumName(EventType_name, int32(x))
 }
 
-// ChaincodeReg is used for registering chaincode Interests
-// when EventType is CHAINCODE
+type ChaincodeReg_MatchType int32
+
+const (
+	ChaincodeReg_EXACT  ChaincodeReg_MatchType = 0
+	ChaincodeReg_PREFIX ChaincodeReg_MatchType = 1
+	ChaincodeReg_REGEX  ChaincodeReg_MatchType = 2
+)
+
+var ChaincodeReg_MatchType_name = map[int32]string{
+	0: "EXACT",
+	1: "PREFIX",
+	2: "REGEX",
+}
+var ChaincodeReg_MatchType_value = map[string]int32{
+	"EXACT":  0,
+	"PREFIX": 1,
+	"REGEX":  2,
+}
+
+func (x ChaincodeReg_MatchType) String() string {
+	return proto.EnumName(ChaincodeReg_MatchType_name, int32(x))
+}
+
+// eventName is matched exactly, as a prefix or as a regular expression
+// depending on match. An empty eventName with EXACT match registers for
+// all the events of the chaincode
 type ChaincodeReg struct {
-	ChaincodeID string `protobuf:"bytes,1,opt,name=chaincodeID" json:"chaincodeID,omitempty"`
-	EventName   string `protobuf:"bytes,2,opt,name=eventName" json:"eventName,omitempty"`
+	ChaincodeID string                 `protobuf:"bytes,1,opt,name=chaincodeID" json:"chaincodeID,omitempty"`
+	EventName   string                 `protobuf:"bytes,2,opt,name=eventName" json:"eventName,omitempty"`
+	Match       ChaincodeReg_MatchType `protobuf:"varint,3,opt,name=match,enum=protos.ChaincodeReg_MatchType" json:"match,omitempty"`
 }
 
 func (m *ChaincodeReg) Reset()         { *m = ChaincodeReg{} }
@@ -134,8 +162,16 @@ func _Interest_OneofUnmarshaler(msg proto.Message, tag, wire int, b *proto.Buffe
 // ---------- consumer events ---------
 // Register is sent by consumers for registering events
 // string type - "register"
+// When replay is set, the events of the blocks from startBlock on are
+// replayed from the ledger before the live events are delivered. A
+// consumer identified by consumerID resumes the replay after the last
+// block it acknowledged, if that is later than startBlock
 type Register struct {
-	Events []*Interest `protobuf:"bytes,1,rep,name=events" json:"events,omitempty"`
+	Events     []*Interest `protobuf:"bytes,1,rep,name=events" json:"events,omitempty"`
+	Replay     bool        `protobuf:"varint,2,opt,name=replay" json:"replay,omitempty"`
+	StartBlock uint64      `protobuf:"varint,3,opt,name=startBlock" json:"startBlock,omitempty"`
+	ConsumerID string      `protobuf:"bytes,4,opt,name=consumerID" json:"consumerID,omitempty"`
+	ChainID    string      `protobuf:"bytes,5,opt,name=chainID" json:"chainID,omitempty"`
 }
 
 func (m *Register) Reset()         { *m = Register{} }
@@ -149,15 +185,61 @@ func (m *Register) GetEvents() []*Interest {
 	return nil
 }
 
+// Ack is sent by consumers registered with a consumerID to acknowledge
+// the events of the blocks up to blockNumber
+type Ack struct {
+	BlockNumber uint64 `protobuf:"varint,1,opt,name=blockNumber" json:"blockNumber,omitempty"`
+}
+
+func (m *Ack) Reset()         { *m = Ack{} }
+func (m *Ack) String() string { return proto.CompactTextString(m) }
+func (*Ack) ProtoMessage()    {}
+
+// FilteredTransaction carries the UUID and the validation result of a
+// transaction, errorCode is 0 for a valid transaction
+type FilteredTransaction struct {
+	Uuid      string `protobuf:"bytes,1,opt,name=uuid" json:"uuid,omitempty"`
+	ErrorCode uint32 `protobuf:"varint,2,opt,name=errorCode" json:"errorCode,omitempty"`
+	Error     string `protobuf:"bytes,3,opt,name=error" json:"error,omitempty"`
+}
+
+func (m *FilteredTransaction) Reset()         { *m = FilteredTransaction{} }
+func (m *FilteredTransaction) String() string { return proto.CompactTextString(m) }
+func (*FilteredTransaction) ProtoMessage()    {}
+
+// FilteredBlock is a lightweight block event carrying only the
+// transactions UUIDs and their validation results
+type FilteredBlock struct {
+	Transactions []*FilteredTransaction `protobuf:"bytes,1,rep,name=transactions" json:"transactions,omitempty"`
+}
+
+func (m *FilteredBlock) Reset()         { *m = FilteredBlock{} }
+func (m *FilteredBlock) String() string { return proto.CompactTextString(m) }
+func (*FilteredBlock) ProtoMessage()    {}
+
+func (m *FilteredBlock) GetTransactions() []*FilteredTransaction {
+	if m != nil {
+		return m.Transactions
+	}
+	return nil
+}
+
 // Event is used by
-//  - consumers (adapters) to send Register
+//  - consumers (adapters) to send Register and Ack
 //  - producer to advertise supported types and events
 type Event struct {
 	// Types that are valid to be assigned to Event:
 	//	*Event_Register
+	//	*Event_Ack
 	//	*Event_Block
 	//	*Event_ChaincodeEvent
+	//	*Event_FilteredBlock
 	Event isEvent_Event `protobuf_oneof:"Event"`
+	// number of the block producer events belong to
+	BlockNumber uint64 `protobuf:"varint,6,opt,name=blockNumber" json:"blockNumber,omitempty"`
+	// chain of the block producer events belong to, empty for the default
+	// chain
+	ChainID string `protobuf:"bytes,7,opt,name=chainID" json:"chainID,omitempty"`
 }
 
 func (m *Event) Reset()         { *m = Event{} }
@@ -171,16 +253,24 @@ type isEvent_Event interface {
 type Event_Register struct {
 	Register *Register `protobuf:"bytes,1,opt,name=register,oneof"`
 }
+type Event_Ack struct {
+	Ack *Ack `protobuf:"bytes,5,opt,name=ack,oneof"`
+}
 type Event_Block struct {
 	Block *Block `protobuf:"bytes,2,opt,name=block,oneof"`
 }
 type Event_ChaincodeEvent struct {
 	ChaincodeEvent *ChaincodeEvent `protobuf:"bytes,3,opt,name=chaincodeEvent,oneof"`
 }
+type Event_FilteredBlock struct {
+	FilteredBlock *FilteredBlock `protobuf:"bytes,4,opt,name=filteredBlock,oneof"`
+}
 
 func (*Event_Register) isEvent_Event()       {}
+func (*Event_Ack) isEvent_Event()            {}
 func (*Event_Block) isEvent_Event()          {}
 func (*Event_ChaincodeEvent) isEvent_Event() {}
+func (*Event_FilteredBlock) isEvent_Event()  {}
 
 func (m *Event) GetEvent() isEvent_Event {
 	if m != nil {
@@ -196,6 +286,13 @@ func (m *Event) GetRegister() *Register {
 	return nil
 }
 
+func (m *Event) GetAck() *Ack {
+	if x, ok := m.GetEvent().(*Event_Ack); ok {
+		return x.Ack
+	}
+	return nil
+}
+
 func (m *Event) GetBlock() *Block {
 	if x, ok := m.GetEvent().(*Event_Block); ok {
 		return x.Block
@@ -210,12 +307,21 @@ func (m *Event) GetChaincodeEvent() *ChaincodeEvent {
 	return nil
 }
 
+func (m *Event) GetFilteredBlock() *FilteredBlock {
+	if x, ok := m.GetEvent().(*Event_FilteredBlock); ok {
+		return x.FilteredBlock
+	}
+	return nil
+}
+
 // XXX_OneofFuncs is for the internal use of the proto package.
 func (*Event) XXX_OneofFuncs() (func(msg proto.Message, b *proto.Buffer) error, func(msg proto.Message, tag, wire int, b *proto.Buffer) (bool, error), []interface{}) {
 	return _Event_OneofMarshaler, _Event_OneofUnmarshaler, []interface{}{
 		(*Event_Register)(nil),
+		(*Event_Ack)(nil),
 		(*Event_Block)(nil),
 		(*Event_ChaincodeEvent)(nil),
+		(*Event_FilteredBlock)(nil),
 	}
 }
 
@@ -228,6 +334,11 @@ func _Event_OneofMarshaler(msg proto.Message, b *proto.Buffer) error {
 		if err := b.EncodeMessage(x.Register); err != nil {
 			return err
 		}
+	case *Event_Ack:
+		b.EncodeVarint(5<<3 | proto.WireBytes)
+		if err := b.EncodeMessage(x.Ack); err != nil {
+			return err
+		}
 	case *Event_Block:
 		b.EncodeVarint(2<<3 | proto.WireBytes)
 		if err := b.EncodeMessage(x.Block); err != nil {
@@ -238,6 +349,11 @@ func _Event_OneofMarshaler(msg proto.Message, b *proto.Buffer) error {
 		if err := b.EncodeMessage(x.ChaincodeEvent); err != nil {
 			return err
 		}
+	case *Event_FilteredBlock:
+		b.EncodeVarint(4<<3 | proto.WireBytes)
+		if err := b.EncodeMessage(x.FilteredBlock); err != nil {
+			return err
+		}
 	case nil:
 	default:
 		return fmt.Errorf("Event.Event has unexpected type %T", x)
@@ -272,6 +388,22 @@ func _Event_OneofUnmarshaler(msg proto.Message, tag, wire int, b *proto.Buffer)
 		err := b.DecodeMessage(msg)
 		m.Event = &Event_ChaincodeEvent{msg}
 		return true, err
+	case 4: // Event.filteredBlock
+		if wire != proto.WireBytes {
+			return true, proto.ErrInternalBadWireType
+		}
+		msg := new(FilteredBlock)
+		err := b.DecodeMessage(msg)
+		m.Event = &Event_FilteredBlock{msg}
+		return true, err
+	case 5: // Event.ack
+		if wire != proto.WireBytes {
+			return true, proto.ErrInternalBadWireType
+		}
+		msg := new(Ack)
+		err := b.DecodeMessage(msg)
+		m.Event = &Event_Ack{msg}
+		return true, err
 	default:
 		return false, nil
 	}
@@ -279,6 +411,7 @@ func _Event_OneofUnmarshaler(msg proto.Message, tag, wire int, b *proto.Buffer)
 
 func init() {
 	proto.RegisterEnum("protos.EventType", EventType_name, EventType_value)
+	proto.RegisterEnum("protos.ChaincodeReg_MatchType", ChaincodeReg_MatchType_name, ChaincodeReg_MatchType_value)
 }
 
 // Reference imports to suppress errors if they are not otherwise used.
@@ -29,8 +29,13 @@ const (
 	Transaction_CHAINCODE_INVOKE Transaction_Type = 2
 	// call a chaincode `query` function
 	Transaction_CHAINCODE_QUERY Transaction_Type = 3
-	// terminate a chaincode; not implemented yet
+	// terminate a chaincode
 	Transaction_CHAINCODE_TERMINATE Transaction_Type = 4
+	// deploy a new version of a chaincode and call its `Upgrade` function
+	Transaction_CHAINCODE_UPGRADE Transaction_Type = 5
+	// change the set of validators running the consensus, the payload
+	// is specific to the consensus plugin
+	Transaction_CONSENSUS_RECONFIGURE Transaction_Type = 6
 )
 
 var Transaction_Type_name = map[int32]string{
@@ -39,13 +44,17 @@ var Transaction_Type_name = map[int32]string{
 	2: "CHAINCODE_INVOKE",
 	3: "CHAINCODE_QUERY",
 	4: "CHAINCODE_TERMINATE",
+	5: "CHAINCODE_UPGRADE",
+	6: "CONSENSUS_RECONFIGURE",
 }
 var Transaction_Type_value = map[string]int32{
-	"UNDEFINED":           0,
-	"CHAINCODE_DEPLOY":    1,
-	"CHAINCODE_INVOKE":    2,
-	"CHAINCODE_QUERY":     3,
-	"CHAINCODE_TERMINATE": 4,
+	"UNDEFINED":             0,
+	"CHAINCODE_DEPLOY":      1,
+	"CHAINCODE_INVOKE":      2,
+	"CHAINCODE_QUERY":       3,
+	"CHAINCODE_TERMINATE":   4,
+	"CHAINCODE_UPGRADE":     5,
+	"CONSENSUS_RECONFIGURE": 6,
 }
 
 func (x Transaction_Type) String() string {
@@ -179,6 +188,8 @@ type Transaction struct {
 	ToValidators                   []byte                     `protobuf:"bytes,10,opt,name=toValidators,proto3" json:"toValidators,omitempty"`
 	Cert                           []byte                     `protobuf:"bytes,11,opt,name=cert,proto3" json:"cert,omitempty"`
 	Signature                      []byte                     `protobuf:"bytes,12,opt,name=signature,proto3" json:"signature,omitempty"`
+	// the chain the transaction belongs to, empty for the default chain
+	ChainID string `protobuf:"bytes,13,opt,name=chainID" json:"chainID,omitempty"`
 }
 
 func (m *Transaction) Reset()         { *m = Transaction{} }
@@ -357,7 +368,8 @@ func (m *PeerEndpoint) GetID() *PeerID {
 }
 
 type PeersMessage struct {
-	Peers []*PeerEndpoint `protobuf:"bytes,1,rep,name=peers" json:"peers,omitempty"`
+	Peers  []*PeerEndpoint `protobuf:"bytes,1,rep,name=peers" json:"peers,omitempty"`
+	Digest []byte          `protobuf:"bytes,2,opt,name=digest,proto3" json:"digest,omitempty"`
 }
 
 func (m *PeersMessage) Reset()         { *m = PeersMessage{} }
@@ -399,6 +411,9 @@ type Message struct {
 	Timestamp *google_protobuf.Timestamp `protobuf:"bytes,2,opt,name=timestamp" json:"timestamp,omitempty"`
 	Payload   []byte                     `protobuf:"bytes,3,opt,name=payload,proto3" json:"payload,omitempty"`
 	Signature []byte                     `protobuf:"bytes,4,opt,name=signature,proto3" json:"signature,omitempty"`
+	// the chain of the consensus and sync messages, empty for the default
+	// chain
+	ChainID string `protobuf:"bytes,5,opt,name=chainID" json:"chainID,omitempty"`
 }
 
 func (m *Message) Reset()         { *m = Message{} }
@@ -59,6 +59,24 @@ func (m *ServerStatus) Reset()         { *m = ServerStatus{} }
 func (m *ServerStatus) String() string { return proto.CompactTextString(m) }
 func (*ServerStatus) ProtoMessage()    {}
 
+type ExportLedgerRequest struct {
+	Path string `protobuf:"bytes,1,opt,name=path" json:"path,omitempty"`
+}
+
+func (m *ExportLedgerRequest) Reset()         { *m = ExportLedgerRequest{} }
+func (m *ExportLedgerRequest) String() string { return proto.CompactTextString(m) }
+func (*ExportLedgerRequest) ProtoMessage()    {}
+
+type ExportLedgerResponse struct {
+	Blocks         uint64 `protobuf:"varint,1,opt,name=blocks" json:"blocks,omitempty"`
+	StateEntries   uint64 `protobuf:"varint,2,opt,name=stateEntries" json:"stateEntries,omitempty"`
+	HistoryEntries uint64 `protobuf:"varint,3,opt,name=historyEntries" json:"historyEntries,omitempty"`
+}
+
+func (m *ExportLedgerResponse) Reset()         { *m = ExportLedgerResponse{} }
+func (m *ExportLedgerResponse) String() string { return proto.CompactTextString(m) }
+func (*ExportLedgerResponse) ProtoMessage()    {}
+
 func init() {
 	proto.RegisterEnum("protos.ServerStatus_StatusCode", ServerStatus_StatusCode_name, ServerStatus_StatusCode_value)
 }
@@ -74,6 +92,8 @@ type AdminClient interface {
 	GetStatus(ctx context.Context, in *google_protobuf1.Empty, opts ...grpc.CallOption) (*ServerStatus, error)
 	StartServer(ctx context.Context, in *google_protobuf1.Empty, opts ...grpc.CallOption) (*ServerStatus, error)
 	StopServer(ctx context.Context, in *google_protobuf1.Empty, opts ...grpc.CallOption) (*ServerStatus, error)
+	// Export the ledger to an archive file on the file system of the peer.
+	ExportLedger(ctx context.Context, in *ExportLedgerRequest, opts ...grpc.CallOption) (*ExportLedgerResponse, error)
 }
 
 type adminClient struct {
@@ -111,6 +131,15 @@ func (c *adminClient) StopServer(ctx context.Context, in *google_protobuf1.Empty
 	return out, nil
 }
 
+func (c *adminClient) ExportLedger(ctx context.Context, in *ExportLedgerRequest, opts ...grpc.CallOption) (*ExportLedgerResponse, error) {
+	out := new(ExportLedgerResponse)
+	err := grpc.Invoke(ctx, "/protos.Admin/ExportLedger", in, out, c.cc, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 // Server API for Admin service
 
 type AdminServer interface {
@@ -118,6 +147,8 @@ type AdminServer interface {
 	GetStatus(context.Context, *google_protobuf1.Empty) (*ServerStatus, error)
 	StartServer(context.Context, *google_protobuf1.Empty) (*ServerStatus, error)
 	StopServer(context.Context, *google_protobuf1.Empty) (*ServerStatus, error)
+	// Export the ledger to an archive file on the file system of the peer.
+	ExportLedger(context.Context, *ExportLedgerRequest) (*ExportLedgerResponse, error)
 }
 
 func RegisterAdminServer(s *grpc.Server, srv AdminServer) {
@@ -160,6 +191,18 @@ func _Admin_StopServer_Handler(srv interface{}, ctx context.Context, dec func(in
 	return out, nil
 }
 
+func _Admin_ExportLedger_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error) (interface{}, error) {
+	in := new(ExportLedgerRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	out, err := srv.(AdminServer).ExportLedger(ctx, in)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 var _Admin_serviceDesc = grpc.ServiceDesc{
 	ServiceName: "protos.Admin",
 	HandlerType: (*AdminServer)(nil),
@@ -176,6 +219,10 @@ var _Admin_serviceDesc = grpc.ServiceDesc{
 			MethodName: "StopServer",
 			Handler:    _Admin_StopServer_Handler,
 		},
+		{
+			MethodName: "ExportLedger",
+			Handler:    _Admin_ExportLedger_Handler,
+		},
 	},
 	Streams: []grpc.StreamDesc{},
 }
@@ -69,6 +69,9 @@ func NewChaincodeDeployTransaction(chaincodeDeploymentSpec *ChaincodeDeploymentS
 	transaction.Type = Transaction_CHAINCODE_DEPLOY
 	transaction.Uuid = uuid
 	transaction.Timestamp = util.CreateUtcTimestamp()
+	if chaincodeDeploymentSpec.ChaincodeSpec != nil {
+		transaction.ChainID = chaincodeDeploymentSpec.ChaincodeSpec.ChainID
+	}
 	cID := chaincodeDeploymentSpec.ChaincodeSpec.GetChaincodeID()
 	if cID != nil {
 		data, err := proto.Marshal(cID)
@@ -96,6 +99,9 @@ func NewChaincodeExecute(chaincodeInvocationSpec *ChaincodeInvocationSpec, uuid
 	transaction.Type = typ
 	transaction.Uuid = uuid
 	transaction.Timestamp = util.CreateUtcTimestamp()
+	if chaincodeInvocationSpec.ChaincodeSpec != nil {
+		transaction.ChainID = chaincodeInvocationSpec.ChaincodeSpec.ChainID
+	}
 	cID := chaincodeInvocationSpec.ChaincodeSpec.GetChaincodeID()
 	if cID != nil {
 		data, err := proto.Marshal(cID)
@@ -111,3 +117,38 @@ func NewChaincodeExecute(chaincodeInvocationSpec *ChaincodeInvocationSpec, uuid
 	transaction.Payload = data
 	return transaction, nil
 }
+
+// NewChaincodeUpgradeTransaction is used to upgrade chaincode.
+func NewChaincodeUpgradeTransaction(chaincodeDeploymentSpec *ChaincodeDeploymentSpec, uuid string) (*Transaction, error) {
+	transaction, err := NewChaincodeDeployTransaction(chaincodeDeploymentSpec, uuid)
+	if err != nil {
+		return nil, err
+	}
+	transaction.Type = Transaction_CHAINCODE_UPGRADE
+	return transaction, nil
+}
+
+// NewChaincodeTerminateTransaction is used to terminate chaincode.
+func NewChaincodeTerminateTransaction(chaincodeTerminationSpec *ChaincodeTerminationSpec, uuid string) (*Transaction, error) {
+	transaction := new(Transaction)
+	transaction.Type = Transaction_CHAINCODE_TERMINATE
+	transaction.Uuid = uuid
+	transaction.Timestamp = util.CreateUtcTimestamp()
+	if chaincodeTerminationSpec.ChaincodeSpec != nil {
+		transaction.ChainID = chaincodeTerminationSpec.ChaincodeSpec.ChainID
+	}
+	cID := chaincodeTerminationSpec.ChaincodeSpec.GetChaincodeID()
+	if cID != nil {
+		data, err := proto.Marshal(cID)
+		if err != nil {
+			return nil, fmt.Errorf("Could not marshal chaincode : %s", err)
+		}
+		transaction.ChaincodeID = data
+	}
+	data, err := proto.Marshal(chaincodeTerminationSpec)
+	if err != nil {
+		return nil, fmt.Errorf("Could not marshal payload for chaincode termination: %s", err)
+	}
+	transaction.Payload = data
+	return transaction, nil
+}
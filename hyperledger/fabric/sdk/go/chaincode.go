@@ -0,0 +1,197 @@
+/*
+Copyright IBM Corp. 2016 All Rights Reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+		 http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sdk
+
+import (
+	"fmt"
+
+	"github.com/hyperledger/fabric/core/chaincode/platforms"
+	"github.com/hyperledger/fabric/core/container"
+	"github.com/hyperledger/fabric/core/util"
+	pb "github.com/hyperledger/fabric/protos"
+)
+
+// ChaincodeRequest describes the deployment, invocation or query of a
+// chaincode
+type ChaincodeRequest struct {
+	// Type of the chaincode, golang if not set
+	Type pb.ChaincodeSpec_Type
+	// Path of the chaincode to deploy, from which its package is built and
+	// its name derived. Without a path, the chaincode is deployed in
+	// development mode under Name.
+	Path string
+	// Name of the chaincode to invoke or query
+	Name     string
+	Function string
+	Args     []string
+	// Metadata is passed to the chaincode along with the arguments
+	Metadata []byte
+	// Attributes of the user to include in the TCert of the transaction
+	Attributes []string
+	// Confidential encrypts the transaction for the validators, which
+	// requires a crypto client
+	Confidential bool
+}
+
+func (c *Client) newChaincodeSpec(req *ChaincodeRequest) (*pb.ChaincodeSpec, error) {
+	spec := &pb.ChaincodeSpec{
+		Type:        req.Type,
+		ChaincodeID: &pb.ChaincodeID{Path: req.Path, Name: req.Name},
+		CtorMsg:     &pb.ChaincodeInput{Function: req.Function, Args: req.Args},
+		Metadata:    req.Metadata,
+		Attributes:  req.Attributes,
+		ChainID:     c.config.ChainID,
+	}
+	if spec.Type == pb.ChaincodeSpec_UNDEFINED {
+		spec.Type = pb.ChaincodeSpec_GOLANG
+	}
+	if req.Confidential {
+		if c.cryptoClient == nil {
+			return nil, fmt.Errorf("Confidential transactions require a crypto client")
+		}
+		spec.ConfidentialityLevel = pb.ConfidentialityLevel_CONFIDENTIAL
+	}
+	return spec, nil
+}
+
+// NewDeployTransaction builds the transaction deploying a chaincode. Its UUID
+// is the name of the chaincode.
+func (c *Client) NewDeployTransaction(req *ChaincodeRequest) (*pb.Transaction, error) {
+	spec, err := c.newChaincodeSpec(req)
+	if err != nil {
+		return nil, err
+	}
+	var codePackage []byte
+	if req.Path != "" {
+		platform, err := platforms.Find(spec.Type)
+		if err != nil {
+			return nil, fmt.Errorf("Failed to determine platform type: %s", err)
+		}
+		if err = platform.ValidateSpec(spec); err != nil {
+			return nil, err
+		}
+		// sets the name of the chaincode to the hash of its package
+		if codePackage, err = container.GetChaincodePackageBytes(spec); err != nil {
+			return nil, fmt.Errorf("Error getting chaincode package bytes: %s", err)
+		}
+	} else if req.Name == "" {
+		return nil, fmt.Errorf("A chaincode path, or a name in development mode, is required")
+	}
+	cds := &pb.ChaincodeDeploymentSpec{ChaincodeSpec: spec, CodePackage: codePackage}
+	uuid := spec.ChaincodeID.Name
+	if c.cryptoClient != nil {
+		return c.cryptoClient.NewChaincodeDeployTransaction(cds, uuid, req.Attributes...)
+	}
+	return pb.NewChaincodeDeployTransaction(cds, uuid)
+}
+
+func (c *Client) newExecuteTransaction(req *ChaincodeRequest, txType pb.Transaction_Type) (*pb.Transaction, error) {
+	if req.Name == "" {
+		return nil, fmt.Errorf("The name of the chaincode is required")
+	}
+	spec, err := c.newChaincodeSpec(req)
+	if err != nil {
+		return nil, err
+	}
+	invocation := &pb.ChaincodeInvocationSpec{ChaincodeSpec: spec}
+	uuid := util.GenerateUUID()
+	if c.cryptoClient == nil {
+		return pb.NewChaincodeExecute(invocation, uuid, txType)
+	}
+	if txType == pb.Transaction_CHAINCODE_QUERY {
+		return c.cryptoClient.NewChaincodeQuery(invocation, uuid, req.Attributes...)
+	}
+	return c.cryptoClient.NewChaincodeExecute(invocation, uuid, req.Attributes...)
+}
+
+// NewInvokeTransaction builds the transaction invoking a chaincode
+func (c *Client) NewInvokeTransaction(req *ChaincodeRequest) (*pb.Transaction, error) {
+	return c.newExecuteTransaction(req, pb.Transaction_CHAINCODE_INVOKE)
+}
+
+// NewQueryTransaction builds the transaction querying a chaincode
+func (c *Client) NewQueryTransaction(req *ChaincodeRequest) (*pb.Transaction, error) {
+	return c.newExecuteTransaction(req, pb.Transaction_CHAINCODE_QUERY)
+}
+
+// Submit submits a transaction to the peers, without waiting for it to be
+// committed. An error is returned if no peer accepts the transaction.
+func (c *Client) Submit(tx *pb.Transaction) (*pb.Response, error) {
+	resp, err := c.submit(tx)
+	if err != nil {
+		return nil, err
+	}
+	if resp.Status != pb.Response_SUCCESS {
+		return resp, fmt.Errorf("Transaction %s was rejected: %s", tx.Uuid, resp.Msg)
+	}
+	return resp, nil
+}
+
+// SubmitAndWait submits a transaction to the peers and waits for it to be
+// committed. The transaction may be committed but have failed, which the
+// Err method of the result tells.
+func (c *Client) SubmitAndWait(tx *pb.Transaction) (*Result, error) {
+	commits, err := c.getCommitWaiter()
+	if err != nil {
+		return nil, err
+	}
+	ch, err := commits.register(tx.Uuid)
+	if err != nil {
+		return nil, err
+	}
+	if _, err = c.Submit(tx); err != nil {
+		commits.cancel(tx.Uuid)
+		return nil, err
+	}
+	return commits.wait(tx.Uuid, ch, c.config.CommitTimeout)
+}
+
+// Deploy deploys a chaincode and waits for the deployment to be committed.
+// The name of the deployed chaincode is the UUID of the result.
+func (c *Client) Deploy(req *ChaincodeRequest) (*Result, error) {
+	tx, err := c.NewDeployTransaction(req)
+	if err != nil {
+		return nil, err
+	}
+	return c.SubmitAndWait(tx)
+}
+
+// Invoke invokes a chaincode and waits for the invocation to be committed
+func (c *Client) Invoke(req *ChaincodeRequest) (*Result, error) {
+	tx, err := c.NewInvokeTransaction(req)
+	if err != nil {
+		return nil, err
+	}
+	return c.SubmitAndWait(tx)
+}
+
+// Query queries a chaincode and returns its result, decrypted if the query is
+// confidential
+func (c *Client) Query(req *ChaincodeRequest) ([]byte, error) {
+	tx, err := c.NewQueryTransaction(req)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := c.Submit(tx)
+	if err != nil {
+		return nil, err
+	}
+	if tx.ConfidentialityLevel == pb.ConfidentialityLevel_CONFIDENTIAL {
+		return c.cryptoClient.DecryptQueryResult(tx, resp.Msg)
+	}
+	return resp.Msg, nil
+}
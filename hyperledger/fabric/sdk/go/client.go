@@ -0,0 +1,193 @@
+/*
+Copyright IBM Corp. 2016 All Rights Reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+		 http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package sdk is a client SDK for Go applications. It enrolls users against
+// the ECA and TCA of the membership services, builds and submits deploy,
+// invoke and query transactions to the peers, and waits for the transactions
+// to be committed using the event hub of a peer.
+//
+// The security and TLS settings, and the addresses of the membership
+// services, are read from the configuration, as by the peer command.
+package sdk
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/op/go-logging"
+	"golang.org/x/net/context"
+	"google.golang.org/grpc"
+
+	"github.com/hyperledger/fabric/core/crypto"
+	"github.com/hyperledger/fabric/core/peer"
+	pb "github.com/hyperledger/fabric/protos"
+)
+
+var sdkLogger = logging.MustGetLogger("sdk")
+
+// Config is the configuration of a Client
+type Config struct {
+	// Peers are the addresses of the peers the transactions are submitted
+	// to, in order of preference
+	Peers []string
+	// EventsAddress is the address of the event hub of a validating peer,
+	// required to wait for the transactions to be committed
+	EventsAddress string
+	// ChainID is the chain the transactions belong to, empty for the default
+	// chain
+	ChainID string
+	// Retries is the number of times the submission of a transaction to all
+	// the peers is retried
+	Retries int
+	// RetryDelay is the time waited between two rounds of submission
+	RetryDelay time.Duration
+	// CommitTimeout bounds the time waited for a transaction to be committed,
+	// 0 for no timeout
+	CommitTimeout time.Duration
+}
+
+// Client submits transactions to the peers on behalf of a user. It is safe
+// for concurrent use.
+type Client struct {
+	config       Config
+	cryptoClient crypto.Client
+
+	lock        sync.Mutex
+	connections map[string]*grpc.ClientConn
+	next        int // the index of the peer tried first
+	commits     *commitWaiter
+}
+
+// Enroll enrolls a user against the ECA and TCA, unless it already is, and
+// returns its crypto client. name is the name under which the key material of
+// the user is stored locally.
+func Enroll(name, enrollID, enrollSecret string) (crypto.Client, error) {
+	if err := crypto.RegisterClient(name, nil, enrollID, enrollSecret); err != nil {
+		return nil, fmt.Errorf("Error enrolling %s: %s", enrollID, err)
+	}
+	return crypto.InitClient(name, nil)
+}
+
+// NewClient returns a client submitting transactions on behalf of the user of
+// cryptoClient. A nil cryptoClient submits unsigned transactions, as when
+// security is disabled.
+func NewClient(cryptoClient crypto.Client, config Config) (*Client, error) {
+	if len(config.Peers) == 0 {
+		return nil, fmt.Errorf("At least one peer address is required")
+	}
+	return &Client{config: config, cryptoClient: cryptoClient, connections: make(map[string]*grpc.ClientConn)}, nil
+}
+
+// Close closes the connections of the client to the peers and the event hub.
+// The crypto client is not closed.
+func (c *Client) Close() {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	for address, conn := range c.connections {
+		conn.Close()
+		delete(c.connections, address)
+	}
+	if c.commits != nil {
+		c.commits.stop()
+		c.commits = nil
+	}
+}
+
+func (c *Client) getConnection(address string) (*grpc.ClientConn, error) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	if conn, ok := c.connections[address]; ok {
+		return conn, nil
+	}
+	conn, err := peer.NewPeerClientConnectionWithAddress(address)
+	if err != nil {
+		return nil, err
+	}
+	c.connections[address] = conn
+	return conn, nil
+}
+
+func (c *Client) dropConnection(address string) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	if conn, ok := c.connections[address]; ok {
+		conn.Close()
+		delete(c.connections, address)
+	}
+}
+
+// submit sends the transaction to the peers in turn until one processes it,
+// retrying all the peers up to Retries times. A peer processing the
+// transaction but failing it is not retried.
+func (c *Client) submit(tx *pb.Transaction) (*pb.Response, error) {
+	c.lock.Lock()
+	first := c.next
+	c.lock.Unlock()
+
+	var lastErr error
+	for attempt := 0; attempt <= c.config.Retries; attempt++ {
+		if attempt > 0 {
+			time.Sleep(c.config.RetryDelay)
+		}
+		for i := range c.config.Peers {
+			index := (first + i) % len(c.config.Peers)
+			address := c.config.Peers[index]
+			resp, err := c.submitTo(address, tx)
+			if err == nil {
+				c.lock.Lock()
+				c.next = index
+				c.lock.Unlock()
+				return resp, nil
+			}
+			sdkLogger.Warningf("Error submitting transaction %s to peer %s: %s", tx.Uuid, address, err)
+			lastErr = err
+		}
+	}
+	return nil, fmt.Errorf("Error submitting transaction %s: %s", tx.Uuid, lastErr)
+}
+
+func (c *Client) submitTo(address string, tx *pb.Transaction) (*pb.Response, error) {
+	conn, err := c.getConnection(address)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := pb.NewPeerClient(conn).ProcessTransaction(context.Background(), tx)
+	if err != nil {
+		c.dropConnection(address)
+		return nil, err
+	}
+	return resp, nil
+}
+
+// getCommitWaiter returns the waiter of the commits, connecting to the event
+// hub if needed
+func (c *Client) getCommitWaiter() (*commitWaiter, error) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	if c.commits != nil && !c.commits.isDisconnected() {
+		return c.commits, nil
+	}
+	if c.config.EventsAddress == "" {
+		return nil, fmt.Errorf("No event hub address configured to wait for commits")
+	}
+	commits, err := startCommitWaiter(c.config.EventsAddress, c.config.ChainID)
+	if err != nil {
+		return nil, err
+	}
+	c.commits = commits
+	return commits, nil
+}
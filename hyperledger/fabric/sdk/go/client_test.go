@@ -0,0 +1,150 @@
+/*
+Copyright IBM Corp. 2016 All Rights Reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+		 http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sdk
+
+import (
+	"net"
+	"sync"
+	"testing"
+
+	"github.com/golang/protobuf/proto"
+	"golang.org/x/net/context"
+	"google.golang.org/grpc"
+
+	pb "github.com/hyperledger/fabric/protos"
+)
+
+// testPeer processes the transactions by recording them
+type testPeer struct {
+	sync.Mutex
+	txs []*pb.Transaction
+}
+
+func (p *testPeer) Chat(stream pb.Peer_ChatServer) error {
+	return nil
+}
+
+func (p *testPeer) ProcessTransaction(ctx context.Context, tx *pb.Transaction) (*pb.Response, error) {
+	p.Lock()
+	defer p.Unlock()
+	p.txs = append(p.txs, tx)
+	if tx.Type == pb.Transaction_CHAINCODE_QUERY {
+		return &pb.Response{Status: pb.Response_SUCCESS, Msg: []byte("42")}, nil
+	}
+	return &pb.Response{Status: pb.Response_SUCCESS, Msg: []byte(tx.Uuid)}, nil
+}
+
+func startTestPeer(t *testing.T) (*testPeer, string, func()) {
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Error listening: %s", err)
+	}
+	grpcServer := grpc.NewServer()
+	peer := &testPeer{}
+	pb.RegisterPeerServer(grpcServer, peer)
+	go grpcServer.Serve(lis)
+	return peer, lis.Addr().String(), grpcServer.Stop
+}
+
+func TestClientSubmit(t *testing.T) {
+	peer, address, stop := startTestPeer(t)
+	defer stop()
+
+	// The first peer cannot be reached, the transaction goes to the second
+	client, err := NewClient(nil, Config{Peers: []string{"127.0.0.1:1", address}, ChainID: "chain1"})
+	if err != nil {
+		t.Fatalf("Error creating client: %s", err)
+	}
+	defer client.Close()
+
+	tx, err := client.NewInvokeTransaction(&ChaincodeRequest{Name: "mycc", Function: "invoke", Args: []string{"a", "b", "10"}})
+	if err != nil {
+		t.Fatalf("Error building invoke transaction: %s", err)
+	}
+	if tx.Type != pb.Transaction_CHAINCODE_INVOKE || tx.ChainID != "chain1" {
+		t.Fatalf("Expected an invoke transaction on chain1, got %s on [%s]", tx.Type, tx.ChainID)
+	}
+	resp, err := client.Submit(tx)
+	if err != nil {
+		t.Fatalf("Error submitting transaction: %s", err)
+	}
+	if string(resp.Msg) != tx.Uuid {
+		t.Fatalf("Expected response %s but got %s", tx.Uuid, resp.Msg)
+	}
+
+	result, err := client.Query(&ChaincodeRequest{Name: "mycc", Function: "query", Args: []string{"a"}})
+	if err != nil {
+		t.Fatalf("Error querying chaincode: %s", err)
+	}
+	if string(result) != "42" {
+		t.Fatalf("Expected query result 42 but got %s", result)
+	}
+
+	peer.Lock()
+	defer peer.Unlock()
+	if len(peer.txs) != 2 {
+		t.Fatalf("Expected the peer to process 2 transactions, got %d", len(peer.txs))
+	}
+	spec := &pb.ChaincodeInvocationSpec{}
+	if err = proto.Unmarshal(peer.txs[1].Payload, spec); err != nil {
+		t.Fatalf("Error unmarshalling query payload: %s", err)
+	}
+	if spec.ChaincodeSpec.Type != pb.ChaincodeSpec_GOLANG || spec.ChaincodeSpec.CtorMsg.Function != "query" {
+		t.Fatalf("Unexpected query spec %v", spec.ChaincodeSpec)
+	}
+}
+
+func TestClientSubmitUnreachable(t *testing.T) {
+	client, err := NewClient(nil, Config{Peers: []string{"127.0.0.1:1"}})
+	if err != nil {
+		t.Fatalf("Error creating client: %s", err)
+	}
+	defer client.Close()
+	tx, err := client.NewInvokeTransaction(&ChaincodeRequest{Name: "mycc"})
+	if err != nil {
+		t.Fatalf("Error building invoke transaction: %s", err)
+	}
+	if _, err = client.Submit(tx); err == nil {
+		t.Fatalf("Expected an error submitting to an unreachable peer")
+	}
+	if _, err = client.SubmitAndWait(tx); err == nil {
+		t.Fatalf("Expected an error waiting for a commit without event hub")
+	}
+}
+
+func TestClientRequestErrors(t *testing.T) {
+	if _, err := NewClient(nil, Config{}); err == nil {
+		t.Fatalf("Expected an error creating a client without peers")
+	}
+	client, _ := NewClient(nil, Config{Peers: []string{"127.0.0.1:1"}})
+	if _, err := client.NewInvokeTransaction(&ChaincodeRequest{}); err == nil {
+		t.Fatalf("Expected an error invoking a chaincode without name")
+	}
+	if _, err := client.NewDeployTransaction(&ChaincodeRequest{}); err == nil {
+		t.Fatalf("Expected an error deploying a chaincode without path or name")
+	}
+	if _, err := client.NewQueryTransaction(&ChaincodeRequest{Name: "mycc", Confidential: true}); err == nil {
+		t.Fatalf("Expected an error building a confidential transaction without crypto client")
+	}
+	tx, err := client.NewDeployTransaction(&ChaincodeRequest{Name: "mycc", Function: "init"})
+	if err != nil {
+		t.Fatalf("Error building deploy transaction in development mode: %s", err)
+	}
+	if tx.Type != pb.Transaction_CHAINCODE_DEPLOY || tx.Uuid != "mycc" {
+		t.Fatalf("Expected a deploy transaction of mycc, got %s of %s", tx.Type, tx.Uuid)
+	}
+}
@@ -0,0 +1,172 @@
+/*
+Copyright IBM Corp. 2016 All Rights Reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+		 http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sdk
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/hyperledger/fabric/events/consumer"
+	pb "github.com/hyperledger/fabric/protos"
+)
+
+// Result is the outcome of a transaction committed to the ledger
+type Result struct {
+	UUID        string
+	BlockNumber uint64
+	// Payload is the result returned by the chaincode
+	Payload []byte
+	// ErrorCode and Error are set if the transaction failed
+	ErrorCode uint32
+	Error     string
+	// ChaincodeEvent is the event set by the chaincode, if any
+	ChaincodeEvent *pb.ChaincodeEvent
+}
+
+// Err returns an error if the transaction failed
+func (r *Result) Err() error {
+	if r.ErrorCode == 0 && r.Error == "" {
+		return nil
+	}
+	return fmt.Errorf("Transaction %s failed with error code %d: %s", r.UUID, r.ErrorCode, r.Error)
+}
+
+// commitWaiter correlates the transactions of the blocks received from the
+// event hub with the transactions waited for
+type commitWaiter struct {
+	eventsClient *consumer.EventsClient
+	lock         sync.Mutex
+	waiters      map[string]chan *Result // by transaction UUID
+	disconnected bool
+}
+
+func newCommitWaiter() *commitWaiter {
+	return &commitWaiter{waiters: make(map[string]chan *Result)}
+}
+
+func startCommitWaiter(eventsAddress string, chainID string) (*commitWaiter, error) {
+	waiter := newCommitWaiter()
+	waiter.eventsClient = consumer.NewEventsClient(eventsAddress, waiter)
+	waiter.eventsClient.SetChainID(chainID)
+	if err := waiter.eventsClient.Start(); err != nil {
+		return nil, fmt.Errorf("Error connecting to the event hub %s: %s", eventsAddress, err)
+	}
+	return waiter, nil
+}
+
+// GetInterestedEvents implements consumer.EventAdapter. The block events
+// carry the results and chaincode events of their transactions.
+func (waiter *commitWaiter) GetInterestedEvents() ([]*pb.Interest, error) {
+	return []*pb.Interest{{EventType: pb.EventType_BLOCK}}, nil
+}
+
+// Recv implements consumer.EventAdapter
+func (waiter *commitWaiter) Recv(msg *pb.Event) (bool, error) {
+	block := msg.GetBlock()
+	if block == nil {
+		return true, nil
+	}
+	results := make(map[string]*pb.TransactionResult)
+	for _, tr := range block.GetNonHashData().GetTransactionResults() {
+		results[tr.Uuid] = tr
+	}
+	waiter.lock.Lock()
+	defer waiter.lock.Unlock()
+	for _, tx := range block.Transactions {
+		ch, ok := waiter.waiters[tx.Uuid]
+		if !ok {
+			continue
+		}
+		result := &Result{UUID: tx.Uuid, BlockNumber: msg.BlockNumber}
+		if tr := results[tx.Uuid]; tr != nil {
+			result.Payload = tr.Result
+			result.ErrorCode = tr.ErrorCode
+			result.Error = tr.Error
+			result.ChaincodeEvent = tr.ChaincodeEvent
+		}
+		ch <- result
+		delete(waiter.waiters, tx.Uuid)
+	}
+	return true, nil
+}
+
+// Disconnected implements consumer.EventAdapter. The transactions waited for
+// cannot be known to be committed anymore.
+func (waiter *commitWaiter) Disconnected(err error) {
+	sdkLogger.Warningf("Disconnected from the event hub: %v", err)
+	waiter.lock.Lock()
+	defer waiter.lock.Unlock()
+	waiter.disconnected = true
+	for uuid, ch := range waiter.waiters {
+		close(ch)
+		delete(waiter.waiters, uuid)
+	}
+}
+
+func (waiter *commitWaiter) isDisconnected() bool {
+	waiter.lock.Lock()
+	defer waiter.lock.Unlock()
+	return waiter.disconnected
+}
+
+// register starts waiting for a transaction, before it is submitted so that
+// its commit cannot be missed
+func (waiter *commitWaiter) register(uuid string) (chan *Result, error) {
+	waiter.lock.Lock()
+	defer waiter.lock.Unlock()
+	if waiter.disconnected {
+		return nil, fmt.Errorf("Disconnected from the event hub")
+	}
+	if _, ok := waiter.waiters[uuid]; ok {
+		return nil, fmt.Errorf("Already waiting for transaction %s", uuid)
+	}
+	ch := make(chan *Result, 1)
+	waiter.waiters[uuid] = ch
+	return ch, nil
+}
+
+func (waiter *commitWaiter) cancel(uuid string) {
+	waiter.lock.Lock()
+	defer waiter.lock.Unlock()
+	delete(waiter.waiters, uuid)
+}
+
+// wait returns the result of a registered transaction once it is committed,
+// or an error after timeout if it is not 0
+func (waiter *commitWaiter) wait(uuid string, ch chan *Result, timeout time.Duration) (*Result, error) {
+	var timeoutChan <-chan time.Time
+	if timeout > 0 {
+		timeoutChan = time.After(timeout)
+	}
+	select {
+	case result, ok := <-ch:
+		if !ok {
+			return nil, fmt.Errorf("Disconnected from the event hub while waiting for transaction %s", uuid)
+		}
+		return result, nil
+	case <-timeoutChan:
+		waiter.cancel(uuid)
+		return nil, fmt.Errorf("Timeout waiting for transaction %s to be committed", uuid)
+	}
+}
+
+func (waiter *commitWaiter) stop() {
+	if waiter.eventsClient != nil {
+		waiter.eventsClient.Stop()
+	}
+}
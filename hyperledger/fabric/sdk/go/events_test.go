@@ -0,0 +1,87 @@
+/*
+Copyright IBM Corp. 2016 All Rights Reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+		 http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sdk
+
+import (
+	"testing"
+	"time"
+
+	pb "github.com/hyperledger/fabric/protos"
+)
+
+func TestCommitWaiter(t *testing.T) {
+	waiter := newCommitWaiter()
+	ch1, err := waiter.register("tx1")
+	if err != nil {
+		t.Fatalf("Error registering transaction: %s", err)
+	}
+	ch2, _ := waiter.register("tx2")
+	if _, err = waiter.register("tx1"); err == nil {
+		t.Fatalf("Expected an error registering a transaction twice")
+	}
+
+	event := &pb.ChaincodeEvent{ChaincodeID: "mycc", TxID: "tx1", EventName: "transfer", Payload: []byte("payload")}
+	block := &pb.Block{
+		Transactions: []*pb.Transaction{{Uuid: "other"}, {Uuid: "tx1"}},
+		NonHashData: &pb.NonHashData{TransactionResults: []*pb.TransactionResult{
+			{Uuid: "tx1", Result: []byte("ok"), ChaincodeEvent: event},
+		}},
+	}
+	if cont, err := waiter.Recv(&pb.Event{Event: &pb.Event_Block{Block: block}, BlockNumber: 7}); !cont || err != nil {
+		t.Fatalf("Expected to keep receiving events, got %t, %v", cont, err)
+	}
+	result, err := waiter.wait("tx1", ch1, time.Second)
+	if err != nil {
+		t.Fatalf("Error waiting for transaction: %s", err)
+	}
+	if result.UUID != "tx1" || result.BlockNumber != 7 || string(result.Payload) != "ok" || result.Err() != nil {
+		t.Fatalf("Unexpected result %+v", result)
+	}
+	if result.ChaincodeEvent == nil || result.ChaincodeEvent.EventName != "transfer" {
+		t.Fatalf("Expected the chaincode event of the transaction, got %v", result.ChaincodeEvent)
+	}
+
+	// A failed transaction
+	block = &pb.Block{
+		Transactions: []*pb.Transaction{{Uuid: "tx2"}},
+		NonHashData:  &pb.NonHashData{TransactionResults: []*pb.TransactionResult{{Uuid: "tx2", ErrorCode: 1, Error: "failed"}}},
+	}
+	waiter.Recv(&pb.Event{Event: &pb.Event_Block{Block: block}, BlockNumber: 8})
+	result, err = waiter.wait("tx2", ch2, time.Second)
+	if err != nil {
+		t.Fatalf("Error waiting for transaction: %s", err)
+	}
+	if result.Err() == nil {
+		t.Fatalf("Expected the transaction to have failed")
+	}
+
+	// A transaction never committed
+	ch3, _ := waiter.register("tx3")
+	if _, err = waiter.wait("tx3", ch3, 10*time.Millisecond); err == nil {
+		t.Fatalf("Expected a timeout waiting for a transaction never committed")
+	}
+
+	// Disconnection ends the waits
+	ch4, _ := waiter.register("tx4")
+	waiter.Disconnected(nil)
+	if _, err = waiter.wait("tx4", ch4, 0); err == nil {
+		t.Fatalf("Expected an error waiting for a transaction once disconnected")
+	}
+	if _, err = waiter.register("tx5"); err == nil {
+		t.Fatalf("Expected an error registering a transaction once disconnected")
+	}
+}
@@ -25,7 +25,6 @@ import (
 	"github.com/hyperledger/fabric/core/db"
 	"github.com/hyperledger/fabric/protos"
 	"github.com/spf13/viper"
-	"github.com/tecbot/gorocksdb"
 )
 
 const (
@@ -70,71 +69,72 @@ func main() {
 	fmt.Println()
 	scan(openchainDB, "persistCF", openchainDB.PersistCF, nil)
 	fmt.Println()
-	printLiveFilesMetaData(openchainDB)
-	fmt.Println()
-	printProperties(openchainDB)
+	if openchainDB.Engine() == "leveldb" {
+		printLevelDBProperties(openchainDB)
+	} else {
+		printLiveFilesMetaData(openchainDB)
+		fmt.Println()
+		printProperties(openchainDB)
+	}
 	fmt.Println()
 }
 
 func printLiveFilesMetaData(openchainDB *db.OpenchainDB) {
 	fmt.Println("------ Details of LiveFilesMetaData ---")
-	db := openchainDB.DB
-	liveFileMetadata := db.GetLiveFilesMetaData()
-	for _, file := range liveFileMetadata {
-		fmt.Printf("file.Name=[%s], file.Level=[%d], file.Size=[%d]\n",
-			file.Name, file.Level, file.Size)
-	}
+	fmt.Println(openchainDB.GetProperty("rocksdb.sstables"))
 }
 
 func printProperties(openchainDB *db.OpenchainDB) {
 	fmt.Println("------ Details of Properties ---")
-	db := openchainDB.DB
 	fmt.Printf("rocksdb.estimate-live-data-size:- BlockchainCF:%s, StateCF:%s, StateDeltaCF:%s, IndexesCF:%s, PersistCF:%s\n\n",
-		db.GetPropertyCF("rocksdb.estimate-live-data-size", openchainDB.BlockchainCF),
-		db.GetPropertyCF("rocksdb.estimate-live-data-size", openchainDB.StateCF),
-		db.GetPropertyCF("rocksdb.estimate-live-data-size", openchainDB.StateDeltaCF),
-		db.GetPropertyCF("rocksdb.estimate-live-data-size", openchainDB.IndexesCF),
-		db.GetPropertyCF("rocksdb.estimate-live-data-size", openchainDB.PersistCF))
-	fmt.Printf("Default:%s\n", db.GetProperty("rocksdb.estimate-live-data-size"))
+		openchainDB.GetPropertyCF("rocksdb.estimate-live-data-size", openchainDB.BlockchainCF),
+		openchainDB.GetPropertyCF("rocksdb.estimate-live-data-size", openchainDB.StateCF),
+		openchainDB.GetPropertyCF("rocksdb.estimate-live-data-size", openchainDB.StateDeltaCF),
+		openchainDB.GetPropertyCF("rocksdb.estimate-live-data-size", openchainDB.IndexesCF),
+		openchainDB.GetPropertyCF("rocksdb.estimate-live-data-size", openchainDB.PersistCF))
+	fmt.Printf("Default:%s\n", openchainDB.GetProperty("rocksdb.estimate-live-data-size"))
 
 	fmt.Printf("rocksdb.num-live-versions:- BlockchainCF:%s, StateCF:%s, StateDeltaCF:%s, IndexesCF:%s, PersistCF:%s\n\n",
-		db.GetPropertyCF("rocksdb.num-live-versions", openchainDB.BlockchainCF),
-		db.GetPropertyCF("rocksdb.num-live-versions", openchainDB.StateCF),
-		db.GetPropertyCF("rocksdb.num-live-versions", openchainDB.StateDeltaCF),
-		db.GetPropertyCF("rocksdb.num-live-versions", openchainDB.IndexesCF),
-		db.GetPropertyCF("rocksdb.num-live-versions", openchainDB.PersistCF))
+		openchainDB.GetPropertyCF("rocksdb.num-live-versions", openchainDB.BlockchainCF),
+		openchainDB.GetPropertyCF("rocksdb.num-live-versions", openchainDB.StateCF),
+		openchainDB.GetPropertyCF("rocksdb.num-live-versions", openchainDB.StateDeltaCF),
+		openchainDB.GetPropertyCF("rocksdb.num-live-versions", openchainDB.IndexesCF),
+		openchainDB.GetPropertyCF("rocksdb.num-live-versions", openchainDB.PersistCF))
 
 	fmt.Printf("rocksdb.cfstats:\n %s %s %s %s %s\n\n",
-		db.GetPropertyCF("rocksdb.cfstats", openchainDB.BlockchainCF),
-		db.GetPropertyCF("rocksdb.cfstats", openchainDB.StateCF),
-		db.GetPropertyCF("rocksdb.cfstats", openchainDB.StateDeltaCF),
-		db.GetPropertyCF("rocksdb.cfstats", openchainDB.IndexesCF),
-		db.GetPropertyCF("rocksdb.cfstats", openchainDB.PersistCF))
+		openchainDB.GetPropertyCF("rocksdb.cfstats", openchainDB.BlockchainCF),
+		openchainDB.GetPropertyCF("rocksdb.cfstats", openchainDB.StateCF),
+		openchainDB.GetPropertyCF("rocksdb.cfstats", openchainDB.StateDeltaCF),
+		openchainDB.GetPropertyCF("rocksdb.cfstats", openchainDB.IndexesCF),
+		openchainDB.GetPropertyCF("rocksdb.cfstats", openchainDB.PersistCF))
+}
+
+func printLevelDBProperties(openchainDB *db.OpenchainDB) {
+	fmt.Println("------ Details of Properties ---")
+	fmt.Printf("leveldb.stats:\n%s\n", openchainDB.GetProperty("leveldb.stats"))
+	fmt.Printf("leveldb.sstables:\n%s\n", openchainDB.GetProperty("leveldb.sstables"))
 }
 
-func scan(openchainDB *db.OpenchainDB, cfName string, cf *gorocksdb.ColumnFamilyHandle, printer detailPrinter) (int, int) {
+func scan(openchainDB *db.OpenchainDB, cfName string, cf db.ColumnFamily, printer detailPrinter) (int, int) {
 	fmt.Printf("------- Printing Key-values larger than [%d] bytes in Column family [%s]--------\n", MaxValueSize, cfName)
 	itr := openchainDB.GetIterator(cf)
 	totalKVs := 0
 	overSizeKVs := 0
 	itr.SeekToFirst()
 	for ; itr.Valid(); itr.Next() {
-		k := itr.Key()
-		v := itr.Value()
-		keyBytes := k.Data()
-		valueSize := v.Size()
+		keyBytes := itr.Key()
+		value := itr.Value()
+		valueSize := len(value)
 		totalKVs++
 		if valueSize >= MaxValueSize {
 			overSizeKVs++
 			fmt.Printf("key=[%x], valueSize=[%d]\n", keyBytes, valueSize)
 			if printer != nil {
 				fmt.Println("=== KV Details === ")
-				printer(v.Data())
+				printer(value)
 				fmt.Println("")
 			}
 		}
-		k.Free()
-		v.Free()
 	}
 	itr.Close()
 	fmt.Printf("totalKVs=[%d], overSizeKVs=[%d]\n", totalKVs, overSizeKVs)
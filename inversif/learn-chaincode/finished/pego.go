@@ -25,6 +25,7 @@ import (
 
 var projectIndexStr = "_projectindex" //name for the key that will store list of project index/project name
 var employeeIndexStr = "_employeeindex" //name for the key that will store list of employee index/employeeID
+var memberProjectIndex = "member~project" //object type of the composite keys indexing the projects of each member
 
 //same as employee
 type Member struct{
@@ -52,7 +53,7 @@ func main() {
 }
 
 // Init resets all the things
-func (t *SimpleChaincode) Init(stub *shim.ChaincodeStub, function string, args []string) ([]byte, error) {
+func (t *SimpleChaincode) Init(stub shim.ChaincodeStubInterface, function string, args []string) ([]byte, error) {
 	if len(args) != 1 {
 		return nil, errors.New("Incorrect number of arguments. Expecting 1")
 	}
@@ -65,8 +66,50 @@ func (t *SimpleChaincode) Init(stub *shim.ChaincodeStub, function string, args [
 return nil, nil
 }
 
+// Upgrade is called instead of Init when a new version of the chaincode is
+// deployed, the state of the previous version has been copied already
+func (t *SimpleChaincode) Upgrade(stub shim.ChaincodeStubInterface, function string, args []string) ([]byte, error) {
+	fmt.Println("upgrade is running " + function)
+
+	// Keep the project and employee indexes, only check they are still readable
+	for _, indexStr := range []string{projectIndexStr, employeeIndexStr} {
+		indexAsBytes, err := stub.GetState(indexStr)
+		if err != nil {
+			return nil, errors.New("Failed to get " + indexStr)
+		}
+		if indexAsBytes == nil {
+			continue
+		}
+		var indexList []string
+		if err = json.Unmarshal(indexAsBytes, &indexList); err != nil {
+			return nil, errors.New("Corrupted " + indexStr)
+		}
+	}
+
+	// Build the member~project index of the projects created by older versions
+	projectIndexAsBytes, err := stub.GetState(projectIndexStr)
+	if err != nil {
+		return nil, errors.New("Failed to get project index")
+	}
+	var projectList []string
+	json.Unmarshal(projectIndexAsBytes, &projectList)
+	for _, name := range projectList {
+		project, err := InquireProject(stub, name)
+		if err != nil {
+			return nil, err
+		}
+		for _, member := range project.Members {
+			if err = PutMemberProject(stub, member, name); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+return nil, nil
+}
+
 // Invoke isur entry point to invoke a chaincode function
-func (t *SimpleChaincode) Invoke(stub *shim.ChaincodeStub, 
+func (t *SimpleChaincode) Invoke(stub shim.ChaincodeStubInterface, 
 		function string, args []string) ([]byte, error) {
 	fmt.Println("invoke is running " + function)
 
@@ -90,12 +133,16 @@ func (t *SimpleChaincode) Invoke(stub *shim.ChaincodeStub,
 }
 
 // Query is our entry point for queries
-func (t *SimpleChaincode) Query(stub *shim.ChaincodeStub, function string, args []string) ([]byte, error) {
+func (t *SimpleChaincode) Query(stub shim.ChaincodeStubInterface, function string, args []string) ([]byte, error) {
 	fmt.Println("query is running " + function)
 
 	// Handle different functions
 	if function == "read" { //read a variable
 		return t.read(stub, args)
+	} else if function == "read_member_projects" { //list the projects of a member
+		return t.read_member_projects(stub, args)
+	} else if function == "query_documents" { //rich query over the members and projects
+		return t.query_documents(stub, args)
 	}
 	fmt.Println("query did not find func: " + function)
 
@@ -103,7 +150,7 @@ func (t *SimpleChaincode) Query(stub *shim.ChaincodeStub, function string, args
 }
 
 // read - query function to read key/value pair
-func (t *SimpleChaincode) read(stub *shim.ChaincodeStub, args []string) ([]byte, error) {
+func (t *SimpleChaincode) read(stub shim.ChaincodeStubInterface, args []string) ([]byte, error) {
 	var key, jsonResp string
 
 	if len(args) != 1 {
@@ -120,9 +167,63 @@ func (t *SimpleChaincode) read(stub *shim.ChaincodeStub, args []string) ([]byte,
 	return valAsbytes, nil
 }
 
+// read_member_projects - query function listing the projects of a member,
+// a prefix scan of the member~project index
+func (t *SimpleChaincode) read_member_projects(stub shim.ChaincodeStubInterface, args []string) ([]byte, error) {
+	if len(args) != 1 {
+		return nil, errors.New("Incorrect number of arguments. Expecting member id to query")
+	}
+
+	iter, err := stub.GetStateByPartialCompositeKey(memberProjectIndex, []string{args[0]})
+	if err != nil {
+		return nil, err
+	}
+	defer iter.Close()
+
+	projects := []string{}
+	for iter.HasNext() {
+		key, _, err := iter.Next()
+		if err != nil {
+			return nil, err
+		}
+		_, attributes, err := stub.SplitCompositeKey(key)
+		if err != nil {
+			return nil, err
+		}
+		projects = append(projects, attributes[1])
+	}
+
+	return json.Marshal(projects)
+}
+
+// query_documents - query function returning the members and projects matching
+// a rich query, e.g. {"selector":{"jobgroup":"dev","level":{"$gte":3}},"sort":["membername"]}
+func (t *SimpleChaincode) query_documents(stub shim.ChaincodeStubInterface, args []string) ([]byte, error) {
+	if len(args) != 1 {
+		return nil, errors.New("Incorrect number of arguments. Expecting the query")
+	}
+
+	iter, err := stub.GetQueryResult(args[0])
+	if err != nil {
+		return nil, err
+	}
+	defer iter.Close()
+
+	documents := []json.RawMessage{}
+	for iter.HasNext() {
+		_, value, err := iter.Next()
+		if err != nil {
+			return nil, err
+		}
+		documents = append(documents, json.RawMessage(value))
+	}
+
+	return json.Marshal(documents)
+}
+
 // Get Employee's information, and returns a Member struct and necessary error
 // if error exists.
-func InquireEmployee (stub *shim.ChaincodeStub, args []string) (Member, error) {
+func InquireEmployee (stub shim.ChaincodeStubInterface, args []string) (Member, error) {
 	//   0       1       2           3          4
 	// "id", "name", "job title", "level", "job group"
 	if len(args) != 5 {
@@ -158,7 +259,7 @@ func AssignToEmployee(id string, name string, title string, level string,
 }
 
 // Function to invoke Marshal & PutState consecutively.
-func PutBack(stub *shim.ChaincodeStub, employee Member, key string) ([]byte, error) {
+func PutBack(stub shim.ChaincodeStubInterface, employee Member, key string) ([]byte, error) {
 	employeeAsBytes, _ := json.Marshal(employee)
 
 	fmt.Println("Employee key CC: ", key, " data ", employee)
@@ -170,7 +271,7 @@ func PutBack(stub *shim.ChaincodeStub, employee Member, key string) ([]byte, err
 	return employeeAsBytes, nil
 }
 
-func (t *SimpleChaincode) update_employee(stub *shim.ChaincodeStub, args []string) ([]byte, error){
+func (t *SimpleChaincode) update_employee(stub shim.ChaincodeStubInterface, args []string) ([]byte, error){
 	employeeObj, err := InquireEmployee(stub, args)
 	if err != nil{
 		fmt.Println(err)
@@ -188,7 +289,7 @@ func (t *SimpleChaincode) update_employee(stub *shim.ChaincodeStub, args []strin
     return nil, nil
 }
 
-func (t *SimpleChaincode) add_employee(stub *shim.ChaincodeStub, args []string) ([]byte, error) {
+func (t *SimpleChaincode) add_employee(stub shim.ChaincodeStubInterface, args []string) ([]byte, error) {
 	//   0       1       2           3          4
 	// "id", "name", "job title", "level", "job group"
 	new_employee, err := InquireEmployee(stub, args)
@@ -229,7 +330,7 @@ func (t *SimpleChaincode) add_employee(stub *shim.ChaincodeStub, args []string)
 
 // Get Project's information, and returns a Project struct and necessary error
 // if error exists.
-func InquireProject (stub *shim.ChaincodeStub, argument string) (Project, error) {
+func InquireProject (stub shim.ChaincodeStubInterface, argument string) (Project, error) {
 	//get project from chaincode state
 	projectAsBytes, err := stub.GetState(argument);
 
@@ -244,7 +345,27 @@ func InquireProject (stub *shim.ChaincodeStub, argument string) (Project, error)
 	return project, nil
 }
 
-func GetIndex(somethingAsBytes []byte, stub *shim.ChaincodeStub, 
+// PutMemberProject records the member of the project in the member~project
+// index, the value is not used
+func PutMemberProject(stub shim.ChaincodeStubInterface, member string, project string) error {
+	key, err := stub.CreateCompositeKey(memberProjectIndex, []string{member, project})
+	if err != nil {
+		return err
+	}
+	return stub.PutState(key, []byte{0x00})
+}
+
+// DelMemberProject removes the member of the project from the member~project
+// index
+func DelMemberProject(stub shim.ChaincodeStubInterface, member string, project string) error {
+	key, err := stub.CreateCompositeKey(memberProjectIndex, []string{member, project})
+	if err != nil {
+		return err
+	}
+	return stub.DelState(key)
+}
+
+func GetIndex(somethingAsBytes []byte, stub shim.ChaincodeStubInterface, 
 		name string, whichone bool) error{
 	var indexList []string
 	var err error
@@ -265,7 +386,7 @@ func GetIndex(somethingAsBytes []byte, stub *shim.ChaincodeStub,
 }
 
 // Initiate a project
-func (t *SimpleChaincode) create_project(stub *shim.ChaincodeStub, args []string) ([]byte, error){
+func (t *SimpleChaincode) create_project(stub shim.ChaincodeStubInterface, args []string) ([]byte, error){
 
 	//input sanitation
 	fmt.Println("- start creating project")
@@ -313,7 +434,7 @@ func (t *SimpleChaincode) create_project(stub *shim.ChaincodeStub, args []string
 	return nil, nil
 }
 
-func (t *SimpleChaincode) add_project_member(stub *shim.ChaincodeStub, 
+func (t *SimpleChaincode) add_project_member(stub shim.ChaincodeStubInterface, 
 		args []string) ([]byte, error){
 	new_project, err := InquireProject(stub, args[0])
 
@@ -336,6 +457,11 @@ func (t *SimpleChaincode) add_project_member(stub *shim.ChaincodeStub,
 			new_project.Members = append(new_project.Members, args[i])	//append memberID/employeeID to project members array 
 			fmt.Println("! Success add new member: ", args[i])
 		}
+
+		//index the project of the member
+		if err = PutMemberProject(stub, args[i], args[0]); err != nil {
+			return nil, err
+		}
 	}
 
 	jsonAsBytes, _ := json.Marshal(new_project)	//equals to JSON.stringify
@@ -349,7 +475,7 @@ func (t *SimpleChaincode) add_project_member(stub *shim.ChaincodeStub,
 	return nil, nil
 }
 
-func (t *SimpleChaincode) delete_project_member(stub *shim.ChaincodeStub, 
+func (t *SimpleChaincode) delete_project_member(stub shim.ChaincodeStubInterface, 
 		args []string) ([]byte, error){
 	//   0                  1
 	// "project name", "member id"
@@ -369,6 +495,11 @@ func (t *SimpleChaincode) delete_project_member(stub *shim.ChaincodeStub,
 		}
 	}
 
+	//remove the project from the index of the member
+	if err = DelMemberProject(stub, args[1], args[0]); err != nil {
+		return nil, err
+	}
+
 	projectAsBytes, _ := json.Marshal(new_project)	//stringify
 	err = stub.PutState(args[0], projectAsBytes)	//rewrite project to the chaincode state
 
@@ -382,7 +513,7 @@ func (t *SimpleChaincode) delete_project_member(stub *shim.ChaincodeStub,
 // ============================================================================================================================
 // Write - write variable into chaincode state
 // ============================================================================================================================
-func (t *SimpleChaincode) Write(stub *shim.ChaincodeStub, args []string) ([]byte, error) {
+func (t *SimpleChaincode) Write(stub shim.ChaincodeStubInterface, args []string) ([]byte, error) {
 	var name, value string // Entities
 	var err error
 	fmt.Println("running write()")
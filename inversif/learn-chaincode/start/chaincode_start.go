@@ -31,7 +31,7 @@ type SimpleChaincode struct {
 // Main
 // ============================================================================================================================
 // Init resets all the things
-func (t *SimpleChaincode) Init(stub *shim.ChaincodeStub, function string, args []string) ([]byte, error) {
+func (t *SimpleChaincode) Init(stub shim.ChaincodeStubInterface, function string, args []string) ([]byte, error) {
 	if len(args) != 1 {
 		return nil, errors.New("Incorrect number of arguments. Expecting 1")
 	}
@@ -45,7 +45,7 @@ func (t *SimpleChaincode) Init(stub *shim.ChaincodeStub, function string, args [
 }
 
 // Invoke is our entry point to invoke a chaincode function
-func (t *SimpleChaincode) Invoke(stub *shim.ChaincodeStub, function string, args []string) ([]byte, error) {
+func (t *SimpleChaincode) Invoke(stub shim.ChaincodeStubInterface, function string, args []string) ([]byte, error) {
 	fmt.Println("invoke is running " + function)
 
 	// Handle different functions
@@ -59,7 +59,7 @@ func (t *SimpleChaincode) Invoke(stub *shim.ChaincodeStub, function string, args
 	return nil, errors.New("Received unknown function invocation")
 }
 
-func (t *SimpleChaincode) write (stub *shim.ChaincodeStub, args []string) ([]byte, error){
+func (t *SimpleChaincode) write (stub shim.ChaincodeStubInterface, args []string) ([]byte, error){
 	var key, value string
 	var err error
 	fmt.Println("Running write()")
@@ -78,7 +78,7 @@ func (t *SimpleChaincode) write (stub *shim.ChaincodeStub, args []string) ([]byt
 }
 
 // Query is our entry point for queries
-func (t *SimpleChaincode) Query(stub *shim.ChaincodeStub, function string, args []string) ([]byte, error) {
+func (t *SimpleChaincode) Query(stub shim.ChaincodeStubInterface, function string, args []string) ([]byte, error) {
 	fmt.Println("query is running " + function)
 
 	// Handle different functions
@@ -91,7 +91,7 @@ func (t *SimpleChaincode) Query(stub *shim.ChaincodeStub, function string, args
 }
 
 
-func (t * SimpleChaincode) read(stub *shim.ChaincodeStub, args []string)([]byte, error){
+func (t * SimpleChaincode) read(stub shim.ChaincodeStubInterface, args []string)([]byte, error){
 	var key, jsonResp string
 	var err error
 